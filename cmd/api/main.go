@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -16,12 +17,16 @@ import (
 	"linkbridge-backend/internal/config"
 	"linkbridge-backend/internal/httpserver"
 	"linkbridge-backend/internal/logging"
+	"linkbridge-backend/internal/metrics"
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/wechat"
 	"linkbridge-backend/internal/ws"
 )
 
 func main() {
+	migrateOnly := flag.Bool("migrate", false, "apply database migrations for DATABASE_URL and exit")
+	flag.Parse()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -37,9 +42,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *migrateOnly {
+		if err := storage.Migrate(ctx, cfg.DatabaseURL, logger); err != nil {
+			logger.Error("migration failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("migrations applied", "database", storage.RedactedDatabaseURL(cfg.DatabaseURL))
+		return
+	}
+
 	logger.Info("starting", "httpAddr", cfg.HTTPAddr, "database", storage.RedactedDatabaseURL(cfg.DatabaseURL))
 
-	store, err := storage.Open(ctx, cfg.DatabaseURL, logger)
+	var m *metrics.Metrics
+	storeOpts := storage.OpenOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.DBConnMaxLifetimeSeconds) * time.Second,
+		ConnMaxIdleTime: time.Duration(cfg.DBConnMaxIdleSeconds) * time.Second,
+	}
+	if cfg.MetricsEnabled {
+		m = metrics.New()
+		storeOpts.ObserveQuery = m.ObserveDBQuery
+	}
+
+	store, err := storage.OpenWithOptions(ctx, cfg.DatabaseURL, logger, storeOpts)
 	if err != nil {
 		logger.Error("failed to open database", "error", err)
 		os.Exit(1)
@@ -47,17 +73,60 @@ func main() {
 
 	tokenValidator := &storeTokenValidator{store: store}
 	callStore := &storeCallStore{store: store}
-	wsManager := ws.NewManager(logger, tokenValidator, callStore)
-	go runBurnMessageSweeper(ctx, logger, store, wsManager)
-	go runActivityReminderSweeper(ctx, logger, store, cfg.WeChatAppID, cfg.WeChatAppSecret, cfg.WeChatActivitySubscribeTemplateID, cfg.WeChatActivitySubscribePage)
-	handler := httpserver.NewHandler(logger, store, wsManager, cfg.UploadDir, httpserver.HandlerOptions{
+	wsManager := ws.NewManagerWithOptions(logger, tokenValidator, callStore, ws.ManagerOptions{
+		MaxInboundPerSec:      cfg.WSMaxInboundPerSec,
+		DisconnectOnRateLimit: cfg.WSDisconnectOnRateLimit,
+		MaxConnectionsPerUser: cfg.WSMaxConnectionsPerUser,
+		SendTimeout:           time.Duration(cfg.WSSendTimeoutMs) * time.Millisecond,
+		Presence:              &storePresenceAudience{store: store},
+		Sessions:              &storeSessionParticipantStore{store: store},
+	})
+	m.RegisterWSStats(wsManager.Stats)
+	go runBurnMessageSweeper(ctx, logger, store, wsManager, time.Duration(cfg.BurnSweepIntervalMs)*time.Millisecond, cfg.BurnSweepBatchSize)
+	go runCallTimeoutSweeper(ctx, logger, store, wsManager, time.Duration(cfg.CallRingingTimeoutSeconds)*time.Second, time.Duration(cfg.CallMaxDurationSeconds)*time.Second)
+	go runLocalFeedPostSweeper(ctx, logger, store)
+	go runRetentionSweeper(ctx, logger, store, cfg)
+
+	// One client for the API and the reminder sweeper so they share a single access token,
+	// persisted in the store to survive restarts.
+	var wechatClient *wechat.Client
+	if strings.TrimSpace(cfg.WeChatAppID) != "" && strings.TrimSpace(cfg.WeChatAppSecret) != "" {
+		wechatClient = wechat.NewClientWithTokenStore(logger, cfg.WeChatAppID, cfg.WeChatAppSecret, &storeWeChatTokenStore{store: store})
+	}
+	go runActivityReminderSweeper(ctx, logger, store, wechatClient, time.Duration(cfg.ActivityReminderIntervalSeconds)*time.Second, cfg.WeChatActivitySubscribeTemplateID, cfg.WeChatActivitySubscribePage)
+	handlerOpts := httpserver.HandlerOptions{
 		WeChatAppID:                       cfg.WeChatAppID,
 		WeChatAppSecret:                   cfg.WeChatAppSecret,
 		WeChatCallSubscribeTemplateID:     cfg.WeChatCallSubscribeTemplateID,
 		WeChatCallSubscribePage:           cfg.WeChatCallSubscribePage,
 		WeChatActivitySubscribeTemplateID: cfg.WeChatActivitySubscribeTemplateID,
 		WeChatActivitySubscribePage:       cfg.WeChatActivitySubscribePage,
-	})
+		AdminUserIDs:                      cfg.AdminUserIDs,
+		UploadAllowedExtensions:           cfg.UploadAllowedExtensions,
+		MinClientVersion:                  cfg.MinClientVersion,
+		BcryptCost:                        cfg.BcryptCost,
+		UploadQuotaBytes:                  cfg.UploadQuotaBytes,
+		UploadsRequireAuth:                cfg.UploadsRequireAuth,
+		DisableUploadEXIFStrip:            !cfg.UploadStripEXIF,
+		DisableCardViewTracking:           !cfg.CardViewTrackingEnabled,
+		MediaAllowedHosts:                 cfg.MediaAllowedHosts,
+		CORSAllowedOrigins:                cfg.CORSAllowedOrigins,
+		TURNSharedSecret:                  cfg.TURNSharedSecret,
+		TURNURIs:                          cfg.TURNURIs,
+		STUNURIs:                          cfg.STUNURIs,
+		TURNCredentialTTL:                 time.Duration(cfg.TURNCredentialTTLSeconds) * time.Second,
+		Metrics:                           m,
+		OpsToken:                          cfg.OpsToken,
+		SessionRequestLimits: storage.SessionRequestLimits{
+			WindowMs:         int64(cfg.SessionRequestWindowSeconds) * 1000,
+			MaxPerWindow:     cfg.SessionRequestMaxPerWindow,
+			RejectCooldownMs: int64(cfg.SessionRequestRejectCooldownHours) * 60 * 60 * 1000,
+		},
+	}
+	if wechatClient != nil {
+		handlerOpts.WeChatClient = wechatClient
+	}
+	handler := httpserver.NewHandler(logger, store, wsManager, cfg.UploadDir, handlerOpts)
 
 	srv := &http.Server{
 		Addr:              cfg.HTTPAddr,
@@ -83,7 +152,10 @@ func main() {
 		}
 	}
 
-	wsManager.CloseAll()
+	// Drain sockets first: the HTTP server's Shutdown does not wait for hijacked connections.
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(cfg.WSDrainGraceSeconds)*time.Second)
+	wsManager.Drain(drainCtx, time.Duration(cfg.WSDrainGraceSeconds)*time.Second)
+	cancelDrain()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -99,12 +171,12 @@ func main() {
 	logger.Info("stopped")
 }
 
-func runBurnMessageSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, wsManager *ws.Manager) {
-	if store == nil || wsManager == nil {
+func runBurnMessageSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, wsManager *ws.Manager, interval time.Duration, batchSize int) {
+	if store == nil || wsManager == nil || interval <= 0 || batchSize <= 0 {
 		return
 	}
 
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -112,43 +184,224 @@ func runBurnMessageSweeper(ctx context.Context, logger *slog.Logger, store *stor
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			nowMs := time.Now().UnixMilli()
-			due, err := store.ExpireBurnMessages(ctx, nowMs, 200)
+			sweepBurnMessages(ctx, logger, store, wsManager, time.Now().UnixMilli(), batchSize)
+		}
+	}
+}
+
+// sweepBurnMessages purges up to batchSize burn messages whose countdown ended by nowMs,
+// tells both parties, and returns how many were purged.
+func sweepBurnMessages(ctx context.Context, logger *slog.Logger, store *storage.Store, wsManager *ws.Manager, nowMs int64, batchSize int) int {
+	due, err := store.ExpireBurnMessages(ctx, nowMs, batchSize)
+	if err != nil {
+		logger.Warn("expire burn messages failed", "error", err)
+		return 0
+	}
+	for _, row := range due {
+		wsManager.SendToUsers(row.AudienceIDs(), ws.Envelope{
+			Type:      "message.burn.deleted",
+			SessionID: row.SessionID,
+			Payload: map[string]any{
+				"messageId":  row.MessageID,
+				"burnedAtMs": nowMs,
+			},
+		})
+	}
+	if len(due) > 0 {
+		logger.Info("burn messages expired", "count", len(due))
+	}
+	return len(due)
+}
+
+// runCallTimeoutSweeper marks calls left ringing past timeout as missed and, when maxDuration
+// is positive, ends accepted calls nobody hung up within it (e.g. because every client died)
+// so they stop blocking new calls between the same users or for the activity.
+func runCallTimeoutSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, wsManager *ws.Manager, timeout, maxDuration time.Duration) {
+	if store == nil || wsManager == nil || timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			timedOut, err := store.TimeoutStaleCalls(ctx, time.Now().UnixMilli(), timeout.Milliseconds())
+			if err != nil {
+				logger.Warn("timeout stale calls failed", "error", err)
+				continue
+			}
+			for _, call := range timedOut {
+				wsManager.SendToUsers([]string{call.CallerID, call.CalleeID}, ws.Envelope{
+					Type:      "call.timeout",
+					SessionID: "",
+					Payload: map[string]any{
+						"call": map[string]any{
+							"id":          call.ID,
+							"groupId":     call.GroupID,
+							"callerId":    call.CallerID,
+							"calleeId":    call.CalleeID,
+							"mediaType":   call.MediaType,
+							"status":      call.Status,
+							"createdAtMs": call.CreatedAtMs,
+							"updatedAtMs": call.UpdatedAtMs,
+						},
+					},
+				})
+				httpserver.PostCallSummary(ctx, logger, store, wsManager, call, call.UpdatedAtMs)
+			}
+
+			if maxDuration <= 0 {
+				continue
+			}
+			ended, err := store.EndStaleCalls(ctx, time.Now().UnixMilli(), maxDuration.Milliseconds())
 			if err != nil {
-				logger.Warn("expire burn messages failed", "error", err)
+				logger.Warn("end stale calls failed", "error", err)
 				continue
 			}
-			for _, row := range due {
-				wsManager.SendToUsers([]string{row.SenderID, row.RecipientID}, ws.Envelope{
-					Type:      "message.burn.deleted",
-					SessionID: row.SessionID,
+			for _, call := range ended {
+				recipients := []string{call.CallerID, call.CalleeID}
+				if call.IsGroup() {
+					members, err := store.ListActiveSessionParticipantIDs(ctx, call.SessionID)
+					if err != nil {
+						logger.Warn("list group call members failed", "error", err, "callID", call.ID)
+					}
+					recipients = members
+				}
+				wsManager.SendToUsers(recipients, ws.Envelope{
+					Type:      "call.ended",
+					SessionID: "",
 					Payload: map[string]any{
-						"messageId": row.MessageID,
+						"call": map[string]any{
+							"id":          call.ID,
+							"groupId":     call.GroupID,
+							"callerId":    call.CallerID,
+							"calleeId":    call.CalleeID,
+							"mediaType":   call.MediaType,
+							"status":      call.Status,
+							"createdAtMs": call.CreatedAtMs,
+							"updatedAtMs": call.UpdatedAtMs,
+							"sessionId":   call.SessionID,
+						},
 					},
 				})
+				httpserver.PostCallSummary(ctx, logger, store, wsManager, call, call.UpdatedAtMs)
 			}
 		}
 	}
 }
 
-func runActivityReminderSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, appID, appSecret, templateID, page string) {
+func runLocalFeedPostSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store) {
 	if store == nil || logger == nil {
 		return
 	}
 
-	appID = strings.TrimSpace(appID)
-	appSecret = strings.TrimSpace(appSecret)
+	const batch = 200
+	sweep := func() {
+		nowMs := time.Now().UnixMilli()
+		var total int64
+		for ctx.Err() == nil {
+			n, err := store.PurgeExpiredLocalFeedPosts(ctx, nowMs, batch)
+			if err != nil {
+				logger.Warn("purge expired local feed posts failed", "error", err)
+				break
+			}
+			total += n
+			if n < batch {
+				break
+			}
+		}
+		if total > 0 {
+			logger.Info("purged expired local feed posts", "purged", total)
+		}
+	}
+
+	sweep()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
+
+func runRetentionSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, cfg config.Config) {
+	if store == nil || logger == nil {
+		return
+	}
+
+	const day = 24 * time.Hour
+	type purgeFunc func(ctx context.Context, beforeMs int64) (int64, error)
+	tables := []struct {
+		name  string
+		days  int
+		purge purgeFunc
+	}{
+		{"auth_tokens", cfg.RetentionAuthTokensDays, store.CleanExpiredTokens},
+		{"activity_reminders", cfg.RetentionActivityRemindersDays, store.PurgeFinishedActivityReminders},
+		{"announcements", cfg.RetentionAnnouncementsDays, store.PurgeEndedAnnouncements},
+		// Replays only honor keys younger than a day, so older rows are dead weight.
+		{"idempotency_keys", 1, store.PurgeIdempotencyKeys},
+	}
+
+	sweep := func() {
+		now := time.Now()
+		for _, t := range tables {
+			if t.days <= 0 {
+				continue
+			}
+			beforeMs := now.Add(-time.Duration(t.days) * day).UnixMilli()
+			n, err := t.purge(ctx, beforeMs)
+			if err != nil {
+				logger.Warn("retention purge failed", "table", t.name, "error", err)
+				continue
+			}
+			if n > 0 {
+				logger.Info("retention purge", "table", t.name, "purged", n, "retentionDays", t.days)
+			}
+		}
+	}
+
+	sweep()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
+
+func runActivityReminderSweeper(ctx context.Context, logger *slog.Logger, store *storage.Store, wechatClient *wechat.Client, interval time.Duration, templateID, page string) {
+	if store == nil || logger == nil || wechatClient == nil {
+		return
+	}
+
 	templateID = strings.TrimSpace(templateID)
 	page = strings.TrimSpace(page)
-	if appID == "" || appSecret == "" || templateID == "" {
+	if templateID == "" {
 		return
 	}
 	if page == "" {
 		page = "pages/chat/index"
 	}
 
-	wechatClient := wechat.NewClient(logger, appID, appSecret)
-	ticker := time.NewTicker(2 * time.Second)
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -157,9 +410,9 @@ func runActivityReminderSweeper(ctx context.Context, logger *slog.Logger, store
 			return
 		case <-ticker.C:
 			nowMs := time.Now().UnixMilli()
-			due, err := store.ListDueActivityReminders(ctx, nowMs, 50)
+			due, err := store.ClaimDueActivityReminders(ctx, nowMs, 50)
 			if err != nil {
-				logger.Warn("list due activity reminders failed", "error", err)
+				logger.Warn("claim due activity reminders failed", "error", err)
 				continue
 			}
 			if len(due) == 0 {
@@ -168,6 +421,7 @@ func runActivityReminderSweeper(ctx context.Context, logger *slog.Logger, store
 
 			accessToken, err := wechatClient.GetAccessToken(ctx)
 			if err != nil {
+				// Claimed rows become claimable again once their lease expires.
 				logger.Warn("wechat get access token failed (activity reminder)", "error", err)
 				continue
 			}
@@ -265,10 +519,61 @@ type storeCallStore struct {
 	store *storage.Store
 }
 
-func (s *storeCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, err error) {
+func (s *storeCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, isGroup bool, err error) {
 	call, err := s.store.GetCallByID(ctx, callID)
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", false, err
 	}
-	return call.CallerID, call.CalleeID, call.Status, nil
+	return call.CallerID, call.CalleeID, call.Status, call.IsGroup(), nil
+}
+
+type storeSessionParticipantStore struct {
+	store *storage.Store
+}
+
+// ParticipantUserIDs returns the users of an active session: both sides of a direct chat,
+// or the active members of a group. Archived sessions have no live participants.
+func (s *storeSessionParticipantStore) ParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error) {
+	session, err := s.store.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != storage.SessionStatusActive {
+		return nil, nil
+	}
+	return s.store.ParticipantUserIDs(ctx, sessionID)
+}
+
+// storeWeChatTokenStore persists WeChat access tokens in the wechat_tokens table.
+type storeWeChatTokenStore struct {
+	store *storage.Store
+}
+
+func (t *storeWeChatTokenStore) LoadAccessToken(ctx context.Context, appID string) (string, time.Time, error) {
+	row, err := t.store.GetWeChatAccessToken(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", time.Time{}, nil
+		}
+		return "", time.Time{}, err
+	}
+	return row.AccessToken, time.UnixMilli(row.ExpiresAtMs), nil
+}
+
+func (t *storeWeChatTokenStore) SaveAccessToken(ctx context.Context, appID, token string, expiresAt time.Time) error {
+	return t.store.UpsertWeChatAccessToken(ctx, appID, token, expiresAt.UnixMilli(), time.Now().UnixMilli())
+}
+
+func (t *storeWeChatTokenStore) ClaimAccessTokenRefresh(ctx context.Context, appID string, lease time.Duration) (bool, error) {
+	return t.store.ClaimWeChatTokenRefresh(ctx, appID, time.Now().UnixMilli(), lease.Milliseconds())
+}
+
+// storePresenceAudience tells a user's direct-chat contacts when they come online or go
+// offline, leaving out blocked pairs.
+type storePresenceAudience struct {
+	store *storage.Store
+}
+
+func (a *storePresenceAudience) PresenceAudienceUserIDs(ctx context.Context, userID string) ([]string, error) {
+	return a.store.ListPresenceContactUserIDs(ctx, userID)
 }
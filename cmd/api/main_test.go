@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestSweepBurnMessages_PurgesDueMessages(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	wsManager := ws.NewManager(logger, &storeTokenValidator{store: store}, &storeCallStore{store: store})
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	msg, _, err := store.CreateBurnMessage(ctx, session.ID, alice.ID, []byte(`{"ciphertext":"abc"}`), 5000, now)
+	if err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+	burn, _, err := store.MarkBurnMessageRead(ctx, msg.ID, bob.ID, now+1000)
+	if err != nil {
+		t.Fatalf("MarkBurnMessageRead() error = %v", err)
+	}
+
+	if n := sweepBurnMessages(ctx, logger, store, wsManager, *burn.BurnAtMs-1, 200); n != 0 {
+		t.Fatalf("sweep before burnAt purged %d, want 0", n)
+	}
+	if n := sweepBurnMessages(ctx, logger, store, wsManager, *burn.BurnAtMs, 200); n != 1 {
+		t.Fatalf("sweep at burnAt purged %d, want 1", n)
+	}
+	if n := sweepBurnMessages(ctx, logger, store, wsManager, *burn.BurnAtMs+1000, 200); n != 0 {
+		t.Fatalf("second sweep purged %d, want 0", n)
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +14,97 @@ type Config struct {
 	LogLevel    string
 	UploadDir   string
 
+	// Database pool limits (Postgres only; SQLite always uses a single connection). 0 uses the
+	// storage defaults.
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeSeconds int
+	DBConnMaxIdleSeconds     int
+
+	// UploadAllowedExtensions overrides the extensions accepted by /v1/upload and served from /uploads/.
+	// Empty means the server's built-in media allow-list.
+	UploadAllowedExtensions []string
+
+	// MediaAllowedHosts lists hosts whose absolute image URLs may be attached to local feed posts.
+	// Relative /uploads/ paths are always accepted.
+	MediaAllowedHosts []string
+
+	// CORSAllowedOrigins lists the browser origins (e.g. https://admin.example.com) allowed to
+	// call the API cross-origin; "*" allows any origin. Empty sends no CORS headers.
+	CORSAllowedOrigins []string
+
+	// UploadQuotaBytes is the default per-user cap on stored upload bytes; 0 disables it.
+	UploadQuotaBytes int64
+
+	// UploadsRequireAuth makes /uploads/ serve files only to requests carrying a valid token.
+	UploadsRequireAuth bool
+
+	// UploadStripEXIF re-encodes JPEG uploads without metadata (GPS position included).
+	UploadStripEXIF bool
+
+	WSMaxInboundPerSec      int
+	WSDisconnectOnRateLimit bool
+	// WSMaxConnectionsPerUser caps open sockets per user; the oldest is evicted. 0 disables it.
+	WSMaxConnectionsPerUser int
+	// WSSendTimeoutMs is how long sending one event waits in total for room in slow clients'
+	// send buffers before those clients are dropped.
+	WSSendTimeoutMs int
+	// WSDrainGraceSeconds is how long shutdown waits for WebSocket clients to leave after the
+	// server.draining event before closing them. 0 closes them immediately.
+	WSDrainGraceSeconds int
+
+	AdminUserIDs []string
+
+	// MetricsEnabled serves Prometheus metrics at /metrics.
+	MetricsEnabled bool
+	// OpsToken is the bearer token /statsz and /metrics require; empty disables both.
+	OpsToken string
+
+	// CardViewTrackingEnabled records who opened a user's business card.
+	CardViewTrackingEnabled bool
+
+	// MinClientVersion is the oldest X-Client-Version (dotted numeric, e.g. "1.4.0") the API accepts.
+	// Empty disables the check.
+	MinClientVersion string
+
+	// BcryptCost is the bcrypt work factor for new password hashes. Stored hashes with a lower
+	// cost are re-hashed on the next successful login.
+	BcryptCost int
+
+	// ActivityReminderIntervalSeconds is how often the worker looks for due activity reminders.
+	ActivityReminderIntervalSeconds int
+
+	// CallRingingTimeoutSeconds is how long a call may stay unanswered before it is marked missed.
+	CallRingingTimeoutSeconds int
+
+	// CallMaxDurationSeconds is how long a call may stay accepted before the sweeper treats it
+	// as abandoned and ends it; 0 disables the cap.
+	CallMaxDurationSeconds int
+
+	// BurnSweepIntervalMs is how often opened burn messages are checked for expiry, and
+	// BurnSweepBatchSize caps how many are purged per pass.
+	BurnSweepIntervalMs int
+	BurnSweepBatchSize  int
+
+	// TURNSharedSecret signs time-limited TURN credentials (TURN REST API scheme); empty
+	// disables /v1/calls/{id}/turn. TURNURIs and STUNURIs are handed to clients as ICE servers.
+	TURNSharedSecret         string
+	TURNURIs                 []string
+	STUNURIs                 []string
+	TURNCredentialTTLSeconds int
+
+	// Session request throttling: at most SessionRequestMaxPerWindow requests per requester
+	// within SessionRequestWindowSeconds, and no re-request of a pair for
+	// SessionRequestRejectCooldownHours after a rejection.
+	SessionRequestWindowSeconds       int
+	SessionRequestMaxPerWindow        int
+	SessionRequestRejectCooldownHours int
+
+	// Retention windows (in days) for operational tables; 0 disables purging for that table.
+	RetentionAuthTokensDays        int
+	RetentionActivityRemindersDays int
+	RetentionAnnouncementsDays     int
+
 	WeChatAppID                       string
 	WeChatAppSecret                   string
 	WeChatCallSubscribeTemplateID     string
@@ -27,12 +120,25 @@ func Load() (Config, error) {
 		LogLevel:    strings.TrimSpace(getEnv("LOG_LEVEL", "info")),
 		UploadDir:   getEnv("UPLOAD_DIR", "./uploads"),
 
+		UploadAllowedExtensions: getEnvList("UPLOAD_ALLOWED_EXTENSIONS"),
+		MediaAllowedHosts:       getEnvList("MEDIA_ALLOWED_HOSTS"),
+		CORSAllowedOrigins:      getEnvList("CORS_ALLOWED_ORIGINS"),
+
 		WeChatAppID:                       strings.TrimSpace(getEnv("WECHAT_APPID", "")),
 		WeChatAppSecret:                   strings.TrimSpace(getEnv("WECHAT_APPSECRET", "")),
 		WeChatCallSubscribeTemplateID:     strings.TrimSpace(getEnv("WECHAT_CALL_SUBSCRIBE_TEMPLATE_ID", "")),
 		WeChatCallSubscribePage:           strings.TrimSpace(getEnv("WECHAT_CALL_SUBSCRIBE_PAGE", "pages/linkbridge/call/call")),
 		WeChatActivitySubscribeTemplateID: strings.TrimSpace(getEnv("WECHAT_ACTIVITY_SUBSCRIBE_TEMPLATE_ID", "")),
 		WeChatActivitySubscribePage:       strings.TrimSpace(getEnv("WECHAT_ACTIVITY_SUBSCRIBE_PAGE", "pages/chat/index")),
+
+		AdminUserIDs: getEnvList("ADMIN_USER_IDS"),
+		OpsToken:     strings.TrimSpace(getEnv("OPS_TOKEN", "")),
+
+		MinClientVersion: getEnv("MIN_CLIENT_VERSION", ""),
+
+		TURNSharedSecret: strings.TrimSpace(getEnv("TURN_SHARED_SECRET", "")),
+		TURNURIs:         getEnvList("TURN_URIS"),
+		STUNURIs:         getEnvList("STUN_URIS"),
 	}
 
 	if strings.TrimSpace(cfg.HTTPAddr) == "" {
@@ -45,6 +151,222 @@ func Load() (Config, error) {
 		cfg.LogLevel = "info"
 	}
 
+	if _, ok := ParseVersion(cfg.MinClientVersion); cfg.MinClientVersion != "" && !ok {
+		return Config{}, fmt.Errorf("MIN_CLIENT_VERSION must be a dotted numeric version like 1.4.0")
+	}
+
+	for _, origin := range cfg.CORSAllowedOrigins {
+		if origin != "*" && !isOrigin(strings.TrimSuffix(origin, "/")) {
+			return Config{}, fmt.Errorf("CORS_ALLOWED_ORIGINS entries must be \"*\" or scheme://host[:port], got %q", origin)
+		}
+	}
+
+	for _, v := range []struct {
+		key string
+		def int
+		dst *int
+	}{
+		{"DB_MAX_OPEN_CONNS", 25, &cfg.DBMaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", 10, &cfg.DBMaxIdleConns},
+		{"DB_CONN_MAX_LIFETIME_SECONDS", 1800, &cfg.DBConnMaxLifetimeSeconds},
+		{"DB_CONN_MAX_IDLE_SECONDS", 300, &cfg.DBConnMaxIdleSeconds},
+	} {
+		n, err := getEnvInt(v.key, v.def)
+		if err != nil {
+			return Config{}, err
+		}
+		if n < 0 {
+			return Config{}, fmt.Errorf("%s must not be negative", v.key)
+		}
+		*v.dst = n
+	}
+
+	uploadQuotaBytes, err := getEnvInt("UPLOAD_QUOTA_BYTES", 1<<30)
+	if err != nil {
+		return Config{}, err
+	}
+	if uploadQuotaBytes < 0 {
+		return Config{}, fmt.Errorf("UPLOAD_QUOTA_BYTES must not be negative")
+	}
+	cfg.UploadQuotaBytes = int64(uploadQuotaBytes)
+
+	uploadsRequireAuth, err := getEnvBool("UPLOADS_REQUIRE_AUTH", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.UploadsRequireAuth = uploadsRequireAuth
+
+	uploadStripEXIF, err := getEnvBool("UPLOAD_STRIP_EXIF", true)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.UploadStripEXIF = uploadStripEXIF
+
+	metricsEnabled, err := getEnvBool("METRICS_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.MetricsEnabled = metricsEnabled
+	if cfg.MetricsEnabled && cfg.OpsToken == "" {
+		return Config{}, fmt.Errorf("OPS_TOKEN is required when METRICS_ENABLED is set")
+	}
+
+	cardViewTrackingEnabled, err := getEnvBool("CARD_VIEW_TRACKING_ENABLED", true)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.CardViewTrackingEnabled = cardViewTrackingEnabled
+
+	wsMaxInboundPerSec, err := getEnvInt("WS_MAX_INBOUND_PER_SEC", 200)
+	if err != nil {
+		return Config{}, err
+	}
+	if wsMaxInboundPerSec < 0 {
+		return Config{}, fmt.Errorf("WS_MAX_INBOUND_PER_SEC must not be negative")
+	}
+	cfg.WSMaxInboundPerSec = wsMaxInboundPerSec
+
+	wsDisconnectOnRateLimit, err := getEnvBool("WS_DISCONNECT_ON_RATE_LIMIT", false)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.WSDisconnectOnRateLimit = wsDisconnectOnRateLimit
+
+	wsMaxConnectionsPerUser, err := getEnvInt("WS_MAX_CONNECTIONS_PER_USER", 5)
+	if err != nil {
+		return Config{}, err
+	}
+	if wsMaxConnectionsPerUser < 0 {
+		return Config{}, fmt.Errorf("WS_MAX_CONNECTIONS_PER_USER must not be negative")
+	}
+	cfg.WSMaxConnectionsPerUser = wsMaxConnectionsPerUser
+
+	wsSendTimeoutMs, err := getEnvInt("WS_SEND_TIMEOUT_MS", 200)
+	if err != nil {
+		return Config{}, err
+	}
+	if wsSendTimeoutMs <= 0 {
+		return Config{}, fmt.Errorf("WS_SEND_TIMEOUT_MS must be positive")
+	}
+	cfg.WSSendTimeoutMs = wsSendTimeoutMs
+
+	wsDrainGraceSeconds, err := getEnvInt("WS_DRAIN_GRACE_SECONDS", 10)
+	if err != nil {
+		return Config{}, err
+	}
+	if wsDrainGraceSeconds < 0 {
+		return Config{}, fmt.Errorf("WS_DRAIN_GRACE_SECONDS must not be negative")
+	}
+	cfg.WSDrainGraceSeconds = wsDrainGraceSeconds
+
+	bcryptCost, err := getEnvInt("BCRYPT_COST", 10)
+	if err != nil {
+		return Config{}, err
+	}
+	// bcrypt accepts costs 4 through 31.
+	if bcryptCost < 4 || bcryptCost > 31 {
+		return Config{}, fmt.Errorf("BCRYPT_COST must be between 4 and 31")
+	}
+	cfg.BcryptCost = bcryptCost
+
+	activityReminderIntervalSeconds, err := getEnvInt("ACTIVITY_REMINDER_INTERVAL_SECONDS", 2)
+	if err != nil {
+		return Config{}, err
+	}
+	if activityReminderIntervalSeconds <= 0 {
+		return Config{}, fmt.Errorf("ACTIVITY_REMINDER_INTERVAL_SECONDS must be positive")
+	}
+	cfg.ActivityReminderIntervalSeconds = activityReminderIntervalSeconds
+
+	callRingingTimeoutSeconds, err := getEnvInt("CALL_RINGING_TIMEOUT_SECONDS", 60)
+	if err != nil {
+		return Config{}, err
+	}
+	if callRingingTimeoutSeconds <= 0 {
+		return Config{}, fmt.Errorf("CALL_RINGING_TIMEOUT_SECONDS must be positive")
+	}
+	cfg.CallRingingTimeoutSeconds = callRingingTimeoutSeconds
+
+	callMaxDurationSeconds, err := getEnvInt("CALL_MAX_DURATION_SECONDS", 4*60*60)
+	if err != nil {
+		return Config{}, err
+	}
+	if callMaxDurationSeconds < 0 {
+		return Config{}, fmt.Errorf("CALL_MAX_DURATION_SECONDS must not be negative")
+	}
+	cfg.CallMaxDurationSeconds = callMaxDurationSeconds
+
+	burnSweep := []struct {
+		key          string
+		defaultValue int
+		dst          *int
+	}{
+		{"BURN_SWEEP_INTERVAL_MS", 500, &cfg.BurnSweepIntervalMs},
+		{"BURN_SWEEP_BATCH_SIZE", 200, &cfg.BurnSweepBatchSize},
+	}
+	for _, b := range burnSweep {
+		v, err := getEnvInt(b.key, b.defaultValue)
+		if err != nil {
+			return Config{}, err
+		}
+		if v <= 0 {
+			return Config{}, fmt.Errorf("%s must be positive", b.key)
+		}
+		*b.dst = v
+	}
+
+	if len(cfg.TURNURIs) > 0 && cfg.TURNSharedSecret == "" {
+		return Config{}, fmt.Errorf("TURN_SHARED_SECRET is required when TURN_URIS is set")
+	}
+	turnCredentialTTLSeconds, err := getEnvInt("TURN_CREDENTIAL_TTL_SECONDS", 3600)
+	if err != nil {
+		return Config{}, err
+	}
+	if turnCredentialTTLSeconds <= 0 {
+		return Config{}, fmt.Errorf("TURN_CREDENTIAL_TTL_SECONDS must be positive")
+	}
+	cfg.TURNCredentialTTLSeconds = turnCredentialTTLSeconds
+
+	sessionRequestLimits := []struct {
+		key          string
+		defaultValue int
+		dst          *int
+	}{
+		{"SESSION_REQUEST_WINDOW_SECONDS", 600, &cfg.SessionRequestWindowSeconds},
+		{"SESSION_REQUEST_MAX_PER_WINDOW", 20, &cfg.SessionRequestMaxPerWindow},
+		{"SESSION_REQUEST_REJECT_COOLDOWN_HOURS", 72, &cfg.SessionRequestRejectCooldownHours},
+	}
+	for _, l := range sessionRequestLimits {
+		v, err := getEnvInt(l.key, l.defaultValue)
+		if err != nil {
+			return Config{}, err
+		}
+		if v <= 0 {
+			return Config{}, fmt.Errorf("%s must be positive", l.key)
+		}
+		*l.dst = v
+	}
+
+	retention := []struct {
+		key          string
+		defaultValue int
+		dst          *int
+	}{
+		{"RETENTION_AUTH_TOKENS_DAYS", 7, &cfg.RetentionAuthTokensDays},
+		{"RETENTION_ACTIVITY_REMINDERS_DAYS", 30, &cfg.RetentionActivityRemindersDays},
+		{"RETENTION_ANNOUNCEMENTS_DAYS", 90, &cfg.RetentionAnnouncementsDays},
+	}
+	for _, r := range retention {
+		days, err := getEnvInt(r.key, r.defaultValue)
+		if err != nil {
+			return Config{}, err
+		}
+		if days < 0 {
+			return Config{}, fmt.Errorf("%s must not be negative", r.key)
+		}
+		*r.dst = days
+	}
+
 	return cfg, nil
 }
 
@@ -59,3 +381,73 @@ func getEnv(key, defaultValue string) string {
 	}
 	return v
 }
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+	return b, nil
+}
+
+func getEnvList(key string) []string {
+	v := getEnv(key, "")
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// ParseVersion parses a dotted numeric version such as "1.4.0". A leading "v" is tolerated.
+// The server uses it both to validate MIN_CLIENT_VERSION and to read X-Client-Version, so the
+// two always agree on what a version looks like.
+func ParseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if v == "" {
+		return nil, false
+	}
+	fields := strings.Split(v, ".")
+	out := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		out = append(out, n)
+	}
+	return out, true
+}
+
+// isOrigin reports whether v is a bare http(s) origin, without path, query or credentials.
+func isOrigin(v string) bool {
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" || u.User != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
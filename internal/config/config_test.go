@@ -6,6 +6,26 @@ func TestLoad_Defaults(t *testing.T) {
 	t.Setenv("HTTP_ADDR", "")
 	t.Setenv("DATABASE_URL", "")
 	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("DB_MAX_OPEN_CONNS", "")
+	t.Setenv("DB_MAX_IDLE_CONNS", "")
+	t.Setenv("DB_CONN_MAX_LIFETIME_SECONDS", "")
+	t.Setenv("DB_CONN_MAX_IDLE_SECONDS", "")
+	t.Setenv("WS_MAX_INBOUND_PER_SEC", "")
+	t.Setenv("WS_DISCONNECT_ON_RATE_LIMIT", "")
+	t.Setenv("WS_DRAIN_GRACE_SECONDS", "")
+	t.Setenv("WS_SEND_TIMEOUT_MS", "")
+	t.Setenv("UPLOAD_ALLOWED_EXTENSIONS", "")
+	t.Setenv("MEDIA_ALLOWED_HOSTS", "")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("MIN_CLIENT_VERSION", "")
+	t.Setenv("UPLOAD_QUOTA_BYTES", "")
+	t.Setenv("UPLOADS_REQUIRE_AUTH", "")
+	t.Setenv("UPLOAD_STRIP_EXIF", "")
+	t.Setenv("METRICS_ENABLED", "")
+	t.Setenv("OPS_TOKEN", "")
+	t.Setenv("CARD_VIEW_TRACKING_ENABLED", "")
+	t.Setenv("ACTIVITY_REMINDER_INTERVAL_SECONDS", "")
+	t.Setenv("BCRYPT_COST", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -21,4 +41,137 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.LogLevel != "info" {
 		t.Fatalf("LogLevel = %q, want %q", cfg.LogLevel, "info")
 	}
+	if cfg.DBMaxOpenConns != 25 || cfg.DBMaxIdleConns != 10 || cfg.DBConnMaxLifetimeSeconds != 1800 || cfg.DBConnMaxIdleSeconds != 300 {
+		t.Fatalf("DB pool = %d/%d/%d/%d, want 25/10/1800/300", cfg.DBMaxOpenConns, cfg.DBMaxIdleConns, cfg.DBConnMaxLifetimeSeconds, cfg.DBConnMaxIdleSeconds)
+	}
+	if cfg.WSMaxInboundPerSec != 200 {
+		t.Fatalf("WSMaxInboundPerSec = %d, want %d", cfg.WSMaxInboundPerSec, 200)
+	}
+	if cfg.WSDisconnectOnRateLimit {
+		t.Fatalf("WSDisconnectOnRateLimit = true, want false")
+	}
+	if cfg.WSMaxConnectionsPerUser != 5 {
+		t.Fatalf("WSMaxConnectionsPerUser = %d, want %d", cfg.WSMaxConnectionsPerUser, 5)
+	}
+	if cfg.WSSendTimeoutMs != 200 {
+		t.Fatalf("WSSendTimeoutMs = %d, want %d", cfg.WSSendTimeoutMs, 200)
+	}
+	if cfg.WSDrainGraceSeconds != 10 {
+		t.Fatalf("WSDrainGraceSeconds = %d, want %d", cfg.WSDrainGraceSeconds, 10)
+	}
+	if len(cfg.UploadAllowedExtensions) != 0 {
+		t.Fatalf("UploadAllowedExtensions = %v, want empty (built-in list)", cfg.UploadAllowedExtensions)
+	}
+	if len(cfg.MediaAllowedHosts) != 0 {
+		t.Fatalf("MediaAllowedHosts = %v, want empty", cfg.MediaAllowedHosts)
+	}
+	if len(cfg.CORSAllowedOrigins) != 0 {
+		t.Fatalf("CORSAllowedOrigins = %v, want empty", cfg.CORSAllowedOrigins)
+	}
+	if cfg.UploadQuotaBytes != 1<<30 {
+		t.Fatalf("UploadQuotaBytes = %d, want %d", cfg.UploadQuotaBytes, 1<<30)
+	}
+	if cfg.UploadsRequireAuth {
+		t.Fatalf("UploadsRequireAuth = true, want false")
+	}
+	if !cfg.UploadStripEXIF {
+		t.Fatalf("UploadStripEXIF = false, want true")
+	}
+	if cfg.MetricsEnabled {
+		t.Fatalf("MetricsEnabled = true, want false")
+	}
+	if cfg.OpsToken != "" {
+		t.Fatalf("OpsToken = %q, want empty", cfg.OpsToken)
+	}
+	if !cfg.CardViewTrackingEnabled {
+		t.Fatalf("CardViewTrackingEnabled = false, want true")
+	}
+	if cfg.MinClientVersion != "" {
+		t.Fatalf("MinClientVersion = %q, want empty", cfg.MinClientVersion)
+	}
+	if cfg.BcryptCost != 10 {
+		t.Fatalf("BcryptCost = %d, want %d", cfg.BcryptCost, 10)
+	}
+	if cfg.ActivityReminderIntervalSeconds != 2 {
+		t.Fatalf("ActivityReminderIntervalSeconds = %d, want %d", cfg.ActivityReminderIntervalSeconds, 2)
+	}
+	if cfg.CallRingingTimeoutSeconds != 60 {
+		t.Fatalf("CallRingingTimeoutSeconds = %d, want %d", cfg.CallRingingTimeoutSeconds, 60)
+	}
+	if cfg.CallMaxDurationSeconds != 4*60*60 {
+		t.Fatalf("CallMaxDurationSeconds = %d, want %d", cfg.CallMaxDurationSeconds, 4*60*60)
+	}
+	if cfg.BurnSweepIntervalMs != 500 || cfg.BurnSweepBatchSize != 200 {
+		t.Fatalf("BurnSweepIntervalMs/BatchSize = %d/%d, want 500/200", cfg.BurnSweepIntervalMs, cfg.BurnSweepBatchSize)
+	}
+	if cfg.TURNSharedSecret != "" || len(cfg.TURNURIs) != 0 || len(cfg.STUNURIs) != 0 {
+		t.Fatalf("TURN config = %q %v %v, want empty", cfg.TURNSharedSecret, cfg.TURNURIs, cfg.STUNURIs)
+	}
+	if cfg.TURNCredentialTTLSeconds != 3600 {
+		t.Fatalf("TURNCredentialTTLSeconds = %d, want %d", cfg.TURNCredentialTTLSeconds, 3600)
+	}
+	if cfg.SessionRequestWindowSeconds != 600 || cfg.SessionRequestMaxPerWindow != 20 || cfg.SessionRequestRejectCooldownHours != 72 {
+		t.Fatalf("session request limits = %d/%d/%d, want 600/20/72", cfg.SessionRequestWindowSeconds, cfg.SessionRequestMaxPerWindow, cfg.SessionRequestRejectCooldownHours)
+	}
+}
+
+func TestLoad_InvalidMinClientVersion(t *testing.T) {
+	t.Setenv("MIN_CLIENT_VERSION", "v1.2-beta")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_MinClientVersionAcceptsLeadingV(t *testing.T) {
+	t.Setenv("MIN_CLIENT_VERSION", "v1.4.0")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.MinClientVersion != "v1.4.0" {
+		t.Fatalf("MinClientVersion = %q, want %q", cfg.MinClientVersion, "v1.4.0")
+	}
+}
+
+func TestLoad_InvalidWSMaxInboundPerSec(t *testing.T) {
+	t.Setenv("WS_MAX_INBOUND_PER_SEC", "fast")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_NegativeCallMaxDuration(t *testing.T) {
+	t.Setenv("CALL_MAX_DURATION_SECONDS", "-1")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_TURNURIsRequireSecret(t *testing.T) {
+	t.Setenv("TURN_URIS", "turn:turn.example.com:3478")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_MetricsRequireOpsToken(t *testing.T) {
+	t.Setenv("METRICS_ENABLED", "true")
+	t.Setenv("OPS_TOKEN", "")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
+}
+
+func TestLoad_InvalidCORSAllowedOrigin(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://ok.example.com,example.com/app")
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("Load() error = nil, want error")
+	}
 }
@@ -36,11 +36,20 @@ const (
 	ErrCodeActivityInviteInvalid      ErrorCode = "ACTIVITY_INVITE_INVALID"
 	ErrCodeRateLimited                ErrorCode = "RATE_LIMITED"
 	ErrCodeCooldownActive             ErrorCode = "COOLDOWN_ACTIVE"
+	ErrCodeBlocked                    ErrorCode = "BLOCKED"
 	ErrCodeHomeBaseUpdateLimited      ErrorCode = "HOME_BASE_UPDATE_LIMITED"
 	ErrCodeLocalFeedPostNotFound      ErrorCode = "LOCAL_FEED_POST_NOT_FOUND"
 	ErrCodeWeChatNotConfigured        ErrorCode = "WECHAT_NOT_CONFIGURED"
 	ErrCodeWeChatNotBound             ErrorCode = "WECHAT_NOT_BOUND"
 	ErrCodeWeChatAPI                  ErrorCode = "WECHAT_API_ERROR"
+	ErrCodeWeChatDecryptFailed        ErrorCode = "WECHAT_DECRYPT_FAILED"
+	ErrCodeTURNNotConfigured          ErrorCode = "TURN_NOT_CONFIGURED"
+	ErrCodeAdminRequired              ErrorCode = "ADMIN_REQUIRED"
+	ErrCodeClientTooOld               ErrorCode = "CLIENT_TOO_OLD"
+	ErrCodeQuotaExceeded              ErrorCode = "QUOTA_EXCEEDED"
+	ErrCodeFileTooLarge               ErrorCode = "FILE_TOO_LARGE"
+	ErrCodeIdempotencyConflict        ErrorCode = "IDEMPOTENCY_CONFLICT"
+	ErrCodeExportConfirmationInvalid  ErrorCode = "EXPORT_CONFIRMATION_INVALID"
 	ErrCodeInternal                   ErrorCode = "INTERNAL_ERROR"
 	ErrCodeMethodNotAllowed           ErrorCode = "METHOD_NOT_ALLOWED"
 	ErrCodeNotFound                   ErrorCode = "NOT_FOUND"
@@ -76,11 +85,20 @@ var errorHTTPStatus = map[ErrorCode]int{
 	ErrCodeActivityInviteInvalid:      http.StatusNotFound,
 	ErrCodeRateLimited:                http.StatusTooManyRequests,
 	ErrCodeCooldownActive:             http.StatusTooManyRequests,
+	ErrCodeBlocked:                    http.StatusForbidden,
 	ErrCodeHomeBaseUpdateLimited:      http.StatusTooManyRequests,
 	ErrCodeLocalFeedPostNotFound:      http.StatusNotFound,
 	ErrCodeWeChatNotConfigured:        http.StatusNotImplemented,
 	ErrCodeWeChatNotBound:             http.StatusPreconditionFailed,
 	ErrCodeWeChatAPI:                  http.StatusBadGateway,
+	ErrCodeWeChatDecryptFailed:        http.StatusBadRequest,
+	ErrCodeTURNNotConfigured:          http.StatusNotImplemented,
+	ErrCodeAdminRequired:              http.StatusForbidden,
+	ErrCodeClientTooOld:               http.StatusUpgradeRequired,
+	ErrCodeQuotaExceeded:              http.StatusRequestEntityTooLarge,
+	ErrCodeFileTooLarge:               http.StatusRequestEntityTooLarge,
+	ErrCodeIdempotencyConflict:        http.StatusConflict,
+	ErrCodeExportConfirmationInvalid:  http.StatusForbidden,
 	ErrCodeInternal:                   http.StatusInternalServerError,
 	ErrCodeMethodNotAllowed:           http.StatusMethodNotAllowed,
 	ErrCodeNotFound:                   http.StatusNotFound,
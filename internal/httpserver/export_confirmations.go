@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// exportConfirmationTTL is how long a data export confirmation stays usable.
+	exportConfirmationTTL = 5 * time.Minute
+	// exportConfirmationPruneSize triggers dropping expired entries so the map cannot grow
+	// unbounded.
+	exportConfirmationPruneSize = 1000
+)
+
+type exportConfirmation struct {
+	userID    string
+	expiresAt time.Time
+}
+
+// exportConfirmations holds the single-use tokens handed out after a user re-enters their
+// password to export their data. They live in memory, so a confirmation only works on the
+// instance that issued it and is lost on restart.
+type exportConfirmations struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]exportConfirmation
+}
+
+func newExportConfirmations() *exportConfirmations {
+	return &exportConfirmations{
+		now:    time.Now,
+		tokens: make(map[string]exportConfirmation),
+	}
+}
+
+// issue returns a new confirmation token for userID and when it expires.
+func (c *exportConfirmations) issue(userID string) (string, time.Time, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b[:])
+	now := c.now()
+	expiresAt := now.Add(exportConfirmationTTL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.tokens) >= exportConfirmationPruneSize {
+		for t, conf := range c.tokens {
+			if !now.Before(conf.expiresAt) {
+				delete(c.tokens, t)
+			}
+		}
+	}
+	c.tokens[token] = exportConfirmation{userID: userID, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// consume reports whether token is an unexpired confirmation issued to userID. A token is
+// removed on first use, whether or not it matched.
+func (c *exportConfirmations) consume(token, userID string) bool {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conf, ok := c.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(c.tokens, token)
+	return conf.userID == userID && now.Before(conf.expiresAt)
+}
@@ -3,58 +3,100 @@ package httpserver
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"log/slog"
 
+	"linkbridge-backend/internal/metrics"
 	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/wechat"
 	"linkbridge-backend/internal/ws"
 )
 
 type Store interface {
 	Ready(ctx context.Context) error
+	PoolStats() storage.PoolStats
 
 	CreateUser(ctx context.Context, username, passwordHash, displayName string, nowMs int64) (storage.UserRow, error)
 	GetUserByID(ctx context.Context, userID string) (storage.UserRow, error)
+	GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]storage.UserRow, error)
 	GetUserByUsername(ctx context.Context, username string) (storage.UserRow, error)
 	SearchUsers(ctx context.Context, query string, limit int) ([]storage.UserRow, error)
 	UpdateUserDisplayName(ctx context.Context, userID, displayName string, nowMs int64) (storage.UserRow, error)
 	UpdateUserAvatarURL(ctx context.Context, userID string, avatarURL *string, nowMs int64) (storage.UserRow, error)
+	UpdateUserPasswordHash(ctx context.Context, userID, passwordHash string, nowMs int64) error
+	ReplaceUserPasswordHash(ctx context.Context, userID, oldHash, newHash string, nowMs int64) (bool, error)
+	DeleteUser(ctx context.Context, userID string, nowMs int64) ([]string, error)
+	ListExportSessions(ctx context.Context, userID string) ([]storage.SessionRow, error)
+	ForEachExportMessage(ctx context.Context, userID string, fn func(storage.MessageRow) error) error
 
 	CreateAuthToken(ctx context.Context, userID string, deviceInfo *string, nowMs, expiresAtMs int64) (storage.AuthTokenRow, error)
 	ValidateToken(ctx context.Context, token string, nowMs int64) (storage.AuthTokenRow, error)
+	RotateAuthToken(ctx context.Context, oldToken string, nowMs, newExpiryMs int64) (storage.AuthTokenRow, error)
 	DeleteToken(ctx context.Context, token string) error
+	DeleteTokensForUser(ctx context.Context, userID, keepToken string) (int64, error)
 
 	CreateSession(ctx context.Context, currentUserID, peerUserID string, nowMs int64) (storage.SessionRow, bool, error)
 	GetSessionByID(ctx context.Context, sessionID string) (storage.SessionRow, error)
-	ListSessionsForUser(ctx context.Context, userID, status string) ([]storage.SessionRow, error)
+	GetSessionByParticipants(ctx context.Context, user1ID, user2ID string) (storage.SessionRow, error)
+	ListSessionsForUserPage(ctx context.Context, userID, status string, opts storage.SessionListOptions) ([]storage.SessionRow, string, error)
 	ArchiveSession(ctx context.Context, sessionID, userID string, nowMs int64) (storage.SessionRow, error)
 	ReactivateSession(ctx context.Context, sessionID, userID string, nowMs int64) (storage.SessionRow, error)
 	ReactivateSessionByParticipants(ctx context.Context, user1ID, user2ID string, nowMs int64) (storage.SessionRow, error)
 	HideSession(ctx context.Context, sessionID, userID string) error
 	IsSessionParticipant(ctx context.Context, sessionID, userID string) (bool, error)
+	ParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error)
+	ListActiveSessionParticipantIDs(ctx context.Context, sessionID string) ([]string, error)
 	GetPeerUserID(session storage.SessionRow, currentUserID string) string
+	ListContactUserIDs(ctx context.Context, userID string) ([]string, error)
+	ListPresenceContactUserIDs(ctx context.Context, userID string) ([]string, error)
+	CreateGroupSession(ctx context.Context, creatorID string, memberIDs []string, title string, nowMs int64) (storage.SessionRow, storage.GroupSessionRow, error)
+	GetGroupSessions(ctx context.Context, sessionIDs []string) (map[string]storage.GroupSessionRow, error)
 
-	ListMessages(ctx context.Context, sessionID, userID string, limit int, beforeID string) ([]storage.MessageRow, bool, error)
+	ListMessages(ctx context.Context, sessionID, userID string, limit int, beforeID, afterID string) ([]storage.MessageRow, bool, error)
+	GetConversation(ctx context.Context, sessionID, userID string, messageLimit int) (storage.ConversationRow, error)
 	CreateMessage(ctx context.Context, sessionID, senderID, msgType string, text *string, meta *storage.MessageMeta, nowMs int64) (storage.MessageRow, error)
+	CreateReplyMessage(ctx context.Context, sessionID, senderID, msgType string, text *string, meta *storage.MessageMeta, replyToID string, nowMs int64) (storage.MessageRow, error)
+	CreateSystemMessage(ctx context.Context, sessionID, senderID, text string, nowMs int64) (storage.MessageRow, error)
+	EditMessage(ctx context.Context, sessionID, messageID, userID, newText string, nowMs int64) (storage.MessageRow, error)
+	MarkSessionRead(ctx context.Context, sessionID, userID string, nowMs int64) (bool, error)
+	CountUnreadMessages(ctx context.Context, sessionID, userID string) (int, error)
+	DeleteMessage(ctx context.Context, sessionID, messageID, userID string, nowMs int64) (storage.MessageRow, error)
+	AddReaction(ctx context.Context, sessionID, messageID, userID, emoji string, nowMs int64) (bool, error)
+	RemoveReaction(ctx context.Context, sessionID, messageID, userID, emoji string) (bool, error)
+	ListMessageReactions(ctx context.Context, messageIDs []string) (map[string][]storage.MessageReactionRow, error)
 	CreateBurnMessage(ctx context.Context, sessionID, senderID string, metaJSON []byte, burnAfterMs int64, nowMs int64) (storage.MessageRow, storage.BurnMessageRow, error)
 	GetBurnMessages(ctx context.Context, messageIDs []string) (map[string]storage.BurnMessageRow, error)
 	MarkBurnMessageRead(ctx context.Context, messageID, userID string, nowMs int64) (storage.BurnMessageRow, bool, error)
 
 	CreateCall(ctx context.Context, callerID, calleeID, mediaType, groupID string, nowMs int64) (storage.CallRow, error)
+	CreateGroupCall(ctx context.Context, activityID, initiatorID, mediaType, groupID string, nowMs int64) (storage.CallRow, error)
 	GetCallByID(ctx context.Context, callID string) (storage.CallRow, error)
 	AcceptCall(ctx context.Context, callID, userID string, nowMs int64) (storage.CallRow, error)
 	RejectCall(ctx context.Context, callID, userID string, nowMs int64) (storage.CallRow, error)
 	CancelCall(ctx context.Context, callID, userID string, nowMs int64) (storage.CallRow, error)
 	EndCall(ctx context.Context, callID, userID string, nowMs int64) (storage.CallRow, error)
+	UpdateCallMedia(ctx context.Context, callID, userID, mediaType string, nowMs int64) (storage.CallRow, error)
+	ListCallsForUser(ctx context.Context, userID string, limit int, beforeID string) ([]storage.CallRow, bool, error)
+
+	BlockUser(ctx context.Context, blockerID, blockedID string, nowMs int64) (bool, error)
+	UnblockUser(ctx context.Context, blockerID, blockedID string) (bool, error)
+	ListBlockedUsers(ctx context.Context, blockerID string) ([]storage.BlockedUserRow, error)
+
+	ReserveIdempotencyKey(ctx context.Context, userID, key, requestPath string, expiredBeforeMs, nowMs int64) (storage.IdempotencyKeyRow, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
 
 	UpsertWeChatBinding(ctx context.Context, userID, openID, sessionKey string, unionID *string, nowMs int64) (storage.WeChatBindingRow, error)
 	GetWeChatBindingByUserID(ctx context.Context, userID string) (storage.WeChatBindingRow, error)
+	SetWeChatBindingPhone(ctx context.Context, userID, phoneNumber string, nowMs int64) error
 
-	CreateSessionRequest(ctx context.Context, requesterID, addresseeID, source string, verificationMessage *string, nowMs int64) (storage.SessionRequestRow, bool, error)
+	CreateSessionRequestWithLimits(ctx context.Context, requesterID, addresseeID, source string, verificationMessage *string, limits storage.SessionRequestLimits, nowMs int64) (storage.SessionRequestRow, bool, error)
 	ListSessionRequests(ctx context.Context, userID, box, status string) ([]storage.SessionRequestRow, error)
 	AcceptSessionRequest(ctx context.Context, requestID, userID string, nowMs int64) (storage.SessionRequestRow, *storage.SessionRow, error)
 	RejectSessionRequest(ctx context.Context, requestID, userID string, nowMs int64) (storage.SessionRequestRow, error)
 	CancelSessionRequest(ctx context.Context, requestID, userID string, nowMs int64) (storage.SessionRequestRow, error)
+	MarkAllSessionRequestsSeen(ctx context.Context, userID string, nowMs int64) (int64, error)
 
 	GetOrCreateSessionInvite(ctx context.Context, inviterID string, nowMs int64) (storage.SessionInviteRow, bool, error)
 	ResolveSessionInvite(ctx context.Context, code string) (storage.SessionInviteRow, error)
@@ -63,10 +105,13 @@ type Store interface {
 
 	GetHomeBase(ctx context.Context, userID string) (storage.HomeBaseRow, error)
 	UpsertHomeBase(ctx context.Context, userID string, latE7, lngE7 int64, visibilityRadiusM *int, nowMs int64) (storage.HomeBaseRow, error)
+	SetHomeBaseAreaLabel(ctx context.Context, userID string, label *string) error
 
-	CreateLocalFeedPost(ctx context.Context, userID string, text *string, imageURLs []string, expiresAtMs int64, isPinned bool, nowMs int64) (storage.LocalFeedPostRow, []storage.LocalFeedPostImageRow, error)
+	CreateLocalFeedPost(ctx context.Context, userID string, text *string, imageURLs []string, radiusM *int, expiresAtMs int64, isPinned bool, nowMs int64) (storage.LocalFeedPostRow, []storage.LocalFeedPostImageRow, error)
+	UpdateLocalFeedPost(ctx context.Context, userID, postID string, text *string, isPinned *bool, nowMs int64) (storage.LocalFeedPostRow, []storage.LocalFeedPostImageRow, error)
 	DeleteLocalFeedPost(ctx context.Context, userID, postID string) error
-	ListLocalFeedPostsForSource(ctx context.Context, sourceUserID string, atLatE7, atLngE7 *int64, nowMs int64, limit int) ([]storage.LocalFeedPostWithImages, error)
+	ListLocalFeedPostsForSource(ctx context.Context, sourceUserID string, atLatE7, atLngE7 *int64, nowMs int64, opts storage.LocalFeedListOptions) ([]storage.LocalFeedPostWithImages, string, error)
+	ListNearbyLocalFeedPosts(ctx context.Context, viewerID string, viewerLatE7, viewerLngE7 int64, radiusM int, nowMs int64, limit int) ([]storage.LocalFeedPostWithImages, error)
 	ListLocalFeedPins(ctx context.Context, minLatE7, maxLatE7, minLngE7, maxLngE7, centerLatE7, centerLngE7 int64, limit int) ([]storage.LocalFeedPinRow, error)
 
 	GetUserCardProfile(ctx context.Context, userID string) (storage.UserProfileRow, error)
@@ -76,35 +121,125 @@ type Store interface {
 
 	ListRelationshipGroups(ctx context.Context, userID string) ([]storage.RelationshipGroupRow, error)
 	GetRelationshipGroupByID(ctx context.Context, userID, groupID string) (storage.RelationshipGroupRow, error)
+	CountSessionsPerGroup(ctx context.Context, userID string) (map[string]storage.RelationshipGroupStats, error)
 	CreateRelationshipGroup(ctx context.Context, userID, name string, nowMs int64) (storage.RelationshipGroupRow, bool, error)
 	RenameRelationshipGroup(ctx context.Context, userID, groupID, name string, nowMs int64) (storage.RelationshipGroupRow, error)
 	DeleteRelationshipGroup(ctx context.Context, userID, groupID string) error
 
 	GetSessionUserMeta(ctx context.Context, sessionID, userID string) (storage.SessionUserMetaRow, error)
+	ListSessionRelationshipsForUser(ctx context.Context, userID string, sessionIDs []string) (map[string]storage.SessionUserMetaRow, error)
+	ListUserTags(ctx context.Context, userID string) ([]storage.UserTagRow, error)
 	UpsertSessionUserMeta(ctx context.Context, sessionID, userID string, note *string, groupID *string, tags []string, nowMs int64) (storage.SessionUserMetaRow, error)
 
 	CreateActivity(ctx context.Context, creatorID, title string, description *string, startAtMs, endAtMs *int64, nowMs int64) (storage.ActivityRow, storage.ActivityInviteRow, error)
 	GetActivityByID(ctx context.Context, activityID string) (storage.ActivityRow, error)
 	GetOrCreateActivityInvite(ctx context.Context, activityID string, nowMs int64) (storage.ActivityInviteRow, bool, error)
-	UpdateActivityInviteSettings(ctx context.Context, activityID string, expiresAtMs *int64, geoFence *storage.GeoFence, nowMs int64) (storage.ActivityInviteRow, error)
+	UpdateActivityInviteSettings(ctx context.Context, activityID string, expiresAtMs *int64, geoFence *storage.GeoFence, maxUses *int, nowMs int64) (storage.ActivityInviteRow, error)
 	ConsumeActivityInvite(ctx context.Context, userID, code string, atLatE7, atLngE7 *int64, nowMs int64) (storage.ActivityRow, storage.SessionRow, bool, error)
+	PreviewActivityInvite(ctx context.Context, userID, code string, atLatE7, atLngE7 *int64, nowMs int64) (storage.ActivityInvitePreview, error)
 	ListActivityMembers(ctx context.Context, activityID string) ([]storage.SessionParticipantRow, error)
 	RemoveActivityMember(ctx context.Context, activityID, actorUserID, targetUserID string, nowMs int64) error
+	SetActivityMemberRole(ctx context.Context, activityID, actorUserID, targetUserID, role string, nowMs int64) error
+	CountActivityMembers(ctx context.Context, sessionID string) (int, error)
+	CountActivityMembersBySession(ctx context.Context, sessionIDs []string) (map[string]int, error)
+	LeaveActivity(ctx context.Context, activityID, userID string, nowMs int64) error
+	TransferActivityOwnership(ctx context.Context, activityID, actorUserID, newCreatorID string, nowMs int64) (storage.ActivityRow, error)
 	ExtendActivity(ctx context.Context, activityID, actorUserID string, newEndAtMs int64, nowMs int64) (storage.ActivityRow, error)
-	ListActivitiesForUser(ctx context.Context, userID, status string, nowMs int64, limit int) ([]storage.ActivityRow, error)
+	UpdateActivity(ctx context.Context, activityID, actorUserID string, upd storage.ActivityUpdate, nowMs int64) (storage.ActivityRow, error)
+	ListActivitiesForUser(ctx context.Context, userID, status string, nowMs int64, limit int, cursor, query string) ([]storage.ActivityRow, string, error)
+	ListActivitiesCreatedBy(ctx context.Context, creatorID, status string, limit int, cursor, query string) ([]storage.ActivityRow, string, error)
 	ArchiveExpiredActivitySessions(ctx context.Context, nowMs int64) (int64, error)
 	ArchiveActivitySessionIfExpired(ctx context.Context, activityID string, nowMs int64) (bool, error)
 
 	UpsertActivityReminder(ctx context.Context, activityID, userID string, remindAtMs, nowMs int64) (storage.ActivityReminderRow, error)
+	GetActivityReminder(ctx context.Context, activityID, userID string) (storage.ActivityReminderRow, error)
+	DeleteActivityReminder(ctx context.Context, activityID, userID string) error
+
+	CreateAnnouncement(ctx context.Context, createdBy, title, body string, startsAtMs int64, endsAtMs *int64, dismissible bool, nowMs int64) (storage.AnnouncementRow, error)
+	ListActiveAnnouncements(ctx context.Context, nowMs int64, limit int) ([]storage.AnnouncementRow, error)
+
+	RecordUpload(ctx context.Context, userID, name string, sizeBytes, defaultQuotaBytes, nowMs int64) (storage.UploadUsageRow, error)
+	ReleaseUpload(ctx context.Context, name string, nowMs int64) error
+	SetUploadQuota(ctx context.Context, userID string, quotaBytes *int64, nowMs int64) (storage.UploadUsageRow, error)
+
+	RecordCardView(ctx context.Context, viewerID, targetID string, nowMs int64) error
+	ListCardViewers(ctx context.Context, targetID string, limit int) ([]storage.CardViewerRow, error)
+}
+
+// WeChatClient is the part of *wechat.Client the handlers call.
+type WeChatClient interface {
+	ExchangeCode(ctx context.Context, code string) (wechat.CodeSession, error)
+	GetAccessToken(ctx context.Context) (string, error)
+	SendSubscribeMessage(ctx context.Context, accessToken string, req wechat.SubscribeSendRequest) error
+	GetWxaCodeUnlimit(ctx context.Context, accessToken string, req wechat.WxaCodeUnlimitRequest) ([]byte, error)
+	DecryptPhoneNumber(sessionKey, encryptedData, iv string) (wechat.PhoneNumber, error)
 }
 
 type HandlerOptions struct {
-	WeChatAppID                       string
-	WeChatAppSecret                   string
+	WeChatAppID     string
+	WeChatAppSecret string
+	// WeChatClient replaces the client built from WeChatAppID and WeChatAppSecret; WeChatAppID
+	// must still be set for the WeChat endpoints to be enabled.
+	WeChatClient WeChatClient
+
 	WeChatCallSubscribeTemplateID     string
 	WeChatCallSubscribePage           string
 	WeChatActivitySubscribeTemplateID string
 	WeChatActivitySubscribePage       string
+
+	// AdminUserIDs lists users allowed to call /v1/admin/* endpoints.
+	AdminUserIDs []string
+
+	// UploadAllowedExtensions restricts which file extensions may be uploaded and served.
+	// Empty uses defaultUploadExtensions.
+	UploadAllowedExtensions []string
+
+	// MinClientVersion rejects requests whose X-Client-Version is older with CLIENT_TOO_OLD (426).
+	// Empty disables the check.
+	MinClientVersion string
+
+	// BcryptCost is the work factor for new password hashes; zero uses bcrypt.DefaultCost.
+	// Logins re-hash stored passwords whose cost is lower.
+	BcryptCost int
+
+	// UploadQuotaBytes is the default per-user cap on stored upload bytes; admins can override it
+	// per user. Zero disables the quota.
+	UploadQuotaBytes int64
+
+	// DisableUploadEXIFStrip stores JPEG uploads byte for byte instead of re-encoding them
+	// without metadata. Meant for debugging only: EXIF can include the GPS position.
+	DisableUploadEXIFStrip bool
+
+	// UploadsRequireAuth serves /uploads/ files only to authenticated requests (Authorization
+	// header or ?token= for <image> tags).
+	UploadsRequireAuth bool
+
+	// DisableCardViewTracking stops recording card views; the viewers list then stays empty.
+	DisableCardViewTracking bool
+
+	// MediaAllowedHosts lists hosts whose absolute image URLs may be attached to posts.
+	// Relative /uploads/ paths are always accepted.
+	MediaAllowedHosts []string
+
+	// CORSAllowedOrigins lists the browser origins allowed to call the API cross-origin; "*"
+	// allows any. Empty disables CORS.
+	CORSAllowedOrigins []string
+
+	// TURNSharedSecret signs the credentials returned by /v1/calls/{id}/turn; empty disables
+	// the endpoint. TURNURIs and STUNURIs are returned as ICE servers.
+	TURNSharedSecret  string
+	TURNURIs          []string
+	STUNURIs          []string
+	TURNCredentialTTL time.Duration
+
+	// SessionRequestLimits throttles session request creation; zero fields use
+	// storage.DefaultSessionRequestLimits.
+	SessionRequestLimits storage.SessionRequestLimits
+
+	// Metrics, when set, instruments every request and is served at /metrics. Nil disables both.
+	Metrics *metrics.Metrics
+	// OpsToken is the bearer token /statsz and /metrics require. Empty disables both endpoints.
+	OpsToken string
 }
 
 func NewHandler(logger *slog.Logger, store Store, wsManager *ws.Manager, uploadDir string, opts HandlerOptions) http.Handler {
@@ -135,16 +270,41 @@ func NewHandler(logger *slog.Logger, store Store, wsManager *ws.Manager, uploadD
 		_, _ = w.Write([]byte("ready"))
 	})
 
+	// Operational endpoints expose connection and pool internals, so they are only served
+	// with an ops token and then require it on every request.
+	if opts.OpsToken != "" {
+		mux.Handle("/statsz", opsTokenMiddleware(opts.OpsToken)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"ws": wsManager.Stats(),
+				"db": store.PoolStats(),
+			})
+		})))
+
+		if opts.Metrics != nil {
+			mux.Handle("/metrics", opsTokenMiddleware(opts.OpsToken)(opts.Metrics.Handler()))
+		}
+	}
+
 	mux.Handle("/v1/ws", wsManager.Handler())
+	mux.HandleFunc("/v1/meta", api.handleMeta)
 	mux.HandleFunc("/v1/auth/", api.handleAuth)
 	mux.HandleFunc("/v1/users", api.handleUsers)
 	mux.HandleFunc("/v1/users/", api.handleUsers)
 	mux.HandleFunc("/v1/sessions", api.handleSessions)
 	mux.HandleFunc("/v1/sessions/", api.handleSessionSubroutes)
+	mux.HandleFunc("/v1/group-sessions", api.handleGroupSessions)
+	mux.HandleFunc("/v1/presence", api.handlePresence)
+	mux.HandleFunc("/v1/conversations/", api.handleConversations)
 	mux.HandleFunc("/v1/burn-messages/", api.handleBurnMessages)
 	mux.HandleFunc("/v1/calls", api.handleCalls)
 	mux.HandleFunc("/v1/calls/", api.handleCallSubroutes)
 	mux.HandleFunc("/v1/wechat/", api.handleWeChat)
+	mux.HandleFunc("/v1/blocks", api.handleBlocks)
+	mux.HandleFunc("/v1/blocks/", api.handleBlocks)
 	mux.HandleFunc("/v1/session-requests", api.handleSessionRequests)
 	mux.HandleFunc("/v1/session-requests/", api.handleSessionRequestSubroutes)
 	mux.HandleFunc("/v1/upload", api.handleUpload)
@@ -156,18 +316,23 @@ func NewHandler(logger *slog.Logger, store Store, wsManager *ws.Manager, uploadD
 	mux.HandleFunc("/v1/profiles/", api.handleProfiles)
 	mux.HandleFunc("/v1/relationship-groups", api.handleRelationshipGroups)
 	mux.HandleFunc("/v1/relationship-groups/", api.handleRelationshipGroups)
+	mux.HandleFunc("/v1/relationship-tags", api.handleListRelationshipTags)
+	mux.HandleFunc("/v1/announcements", api.handleAnnouncements)
+	mux.HandleFunc("/v1/admin/", api.handleAdmin)
 
 	// Serve uploaded files
 	if uploadDir != "" {
-		fs := http.FileServer(http.Dir(uploadDir))
-		mux.Handle("/uploads/", http.StripPrefix("/uploads/", fs))
+		mux.HandleFunc("/uploads/", api.handleServeUpload)
 	}
 
 	return chain(
 		mux,
+		requestIDMiddleware(logger),
+		metricsMiddleware(mux, opts.Metrics),
 		recoverMiddleware(logger),
 		requestLogMiddleware(logger),
-		corsMiddleware(),
+		corsMiddleware(opts.CORSAllowedOrigins),
+		clientVersionMiddleware(opts.MinClientVersion),
 		authMiddleware(store),
 	)
 }
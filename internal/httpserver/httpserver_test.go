@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/metrics"
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
 )
@@ -32,8 +33,28 @@ func (v tokenMapValidator) ValidateToken(ctx context.Context, token string) (str
 
 type noopCallStore struct{}
 
-func (noopCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, err error) {
-	return "", "", "", errors.New("not found")
+func (noopCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, isGroup bool, err error) {
+	return "", "", "", false, errors.New("not found")
+}
+
+// newTestUser creates username (also its display name) with a placeholder password hash and an
+// hour-long auth token. The token is added to tokenToUserID when the map is not nil.
+func newTestUser(t *testing.T, store *storage.Store, tokenToUserID map[string]string, username string, nowMs int64) (storage.UserRow, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	u, err := store.CreateUser(ctx, username, "hash", username, nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(%s) error = %v", username, err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken(%s) error = %v", username, err)
+	}
+	if tokenToUserID != nil {
+		tokenToUserID[tok.Token] = u.ID
+	}
+	return u, tok.Token
 }
 
 func TestHealthz(t *testing.T) {
@@ -278,3 +299,398 @@ func TestWebSocketBroadcast_SessionsAndMessages(t *testing.T) {
 
 	_ = user1ID
 }
+
+func TestWebSocket_SessionEventsSkipNonMembers(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	_, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bobby", nowMs)
+	_, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	dial := func(token string) *websocket.Conn {
+		c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		return c
+	}
+	bobConn := dial(bobToken)
+	defer bobConn.Close()
+	carolConn := dial(carolToken)
+	defer carolConn.Close()
+
+	res := postJSON(t, client, srv.URL+"/v1/sessions", map[string]any{"peerUserId": bob.ID}, aliceToken)
+	var created struct {
+		Session struct {
+			ID string `json:"id"`
+		} `json:"session"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatalf("decode create session response error = %v", err)
+	}
+	_ = res.Body.Close()
+	sessionID := created.Session.ID
+
+	res = postJSON(t, client, srv.URL+"/v1/sessions/"+sessionID+"/messages", map[string]any{
+		"type": "text",
+		"text": "private",
+	}, aliceToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("POST messages status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	for _, want := range []string{"session.created", "message.created"} {
+		env := readWSEvent(t, bobConn)
+		if env.Type != want || env.SessionID != sessionID {
+			t.Fatalf("bob event = %s/%s, want %s/%s", env.Type, env.SessionID, want, sessionID)
+		}
+	}
+
+	_ = carolConn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if _, msg, err := carolConn.ReadMessage(); err == nil {
+		t.Fatalf("non-member received event: %s", msg)
+	}
+}
+
+func TestClientVersion_MinimumEnforced(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{MinClientVersion: "1.4.0"})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	do := func(method, path, version string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		if version != "" {
+			req.Header.Set("X-Client-Version", version)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, path, err)
+		}
+		return res
+	}
+
+	// Old clients, and builds that predate the header, are turned away.
+	for _, v := range []string{"1.3.9", ""} {
+		res := do(http.MethodGet, "/v1/sessions?status=active", v)
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUpgradeRequired || body.Error.Code != string(ErrCodeClientTooOld) {
+			t.Fatalf("version %q status = %d code = %q, want %d %s", v, res.StatusCode, body.Error.Code, http.StatusUpgradeRequired, ErrCodeClientTooOld)
+		}
+	}
+
+	for _, v := range []string{"1.4", "v1.10.0"} {
+		res := do(http.MethodGet, "/v1/sessions?status=active", v)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("version %q status = %d, want %d", v, res.StatusCode, http.StatusUnauthorized)
+		}
+	}
+
+	// Meta and auth stay reachable for old clients.
+	metaRes := do(http.MethodGet, "/v1/meta", "0.1.0")
+	defer metaRes.Body.Close()
+	if metaRes.StatusCode != http.StatusOK {
+		t.Fatalf("GET /v1/meta status = %d, want %d", metaRes.StatusCode, http.StatusOK)
+	}
+	var meta struct {
+		MinClientVersion string `json:"minClientVersion"`
+	}
+	if err := json.NewDecoder(metaRes.Body).Decode(&meta); err != nil {
+		t.Fatalf("decode meta error = %v", err)
+	}
+	if meta.MinClientVersion != "1.4.0" {
+		t.Fatalf("minClientVersion = %q, want %q", meta.MinClientVersion, "1.4.0")
+	}
+
+	authRes := do(http.MethodPost, "/v1/auth/login", "0.1.0")
+	_ = authRes.Body.Close()
+	if authRes.StatusCode == http.StatusUpgradeRequired {
+		t.Fatalf("POST /v1/auth/login rejected for old client, want exempt")
+	}
+
+	// WebSocket clients that cannot set upgrade headers pass the version in the query.
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=nope"
+	dialStatus := func(url string, header http.Header) int {
+		t.Helper()
+		conn, res, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			_ = conn.Close()
+			t.Fatalf("ws dial %s succeeded with a bogus token", url)
+		}
+		if res == nil {
+			t.Fatalf("ws dial %s error = %v", url, err)
+		}
+		return res.StatusCode
+	}
+	for _, url := range []string{wsURL, wsURL + "&clientVersion=1.3.0"} {
+		if got := dialStatus(url, nil); got != http.StatusUpgradeRequired {
+			t.Fatalf("ws dial %s status = %d, want %d", url, got, http.StatusUpgradeRequired)
+		}
+	}
+	// A current version gets past the gate; the bogus token is then refused by the upgrade.
+	header := http.Header{}
+	header.Set("X-Client-Version", "1.4.0")
+	if got := dialStatus(wsURL+"&clientVersion=1.4.0", nil); got == http.StatusUpgradeRequired {
+		t.Fatalf("ws dial with clientVersion=1.4.0 status = %d, want past the version gate", got)
+	}
+	if got := dialStatus(wsURL, header); got == http.StatusUpgradeRequired {
+		t.Fatalf("ws dial with version header status = %d, want past the version gate", got)
+	}
+}
+
+func TestRequestID_RoundTripsAndIsGenerated(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/sessions", nil)
+	if err != nil {
+		t.Fatalf("NewRequest error = %v", err)
+	}
+	req.Header.Set("X-Request-ID", "trace-abc-123")
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/sessions error = %v", err)
+	}
+	var body apiErrorEnvelope
+	_ = json.NewDecoder(res.Body).Decode(&body)
+	_ = res.Body.Close()
+	if got := res.Header.Get("X-Request-ID"); got != "trace-abc-123" {
+		t.Fatalf("X-Request-ID = %q, want %q", got, "trace-abc-123")
+	}
+	if body.Error.RequestID != "trace-abc-123" {
+		t.Fatalf("error.requestId = %q, want %q", body.Error.RequestID, "trace-abc-123")
+	}
+	if !strings.Contains(logs.String(), `"requestId":"trace-abc-123"`) {
+		t.Fatalf("request log does not carry the request id: %s", logs.String())
+	}
+
+	// Without (or with an unusable) header a fresh id is generated per request.
+	seen := map[string]bool{}
+	for _, header := range []string{"", "bad id with spaces"} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/healthz", nil)
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET /healthz error = %v", err)
+		}
+		_ = res.Body.Close()
+		id := res.Header.Get("X-Request-ID")
+		if len(id) != 32 || seen[id] {
+			t.Fatalf("generated X-Request-ID = %q, want a fresh 32-char id", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestMetrics_ServedWhenEnabled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	const opsToken = "ops-secret"
+	m := metrics.New()
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	m.RegisterWSStats(wsManager.Stats)
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{Metrics: m, OpsToken: opsToken})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res := get(t, srv.Client(), srv.URL+"/healthz", "")
+	_ = res.Body.Close()
+
+	res = get(t, srv.Client(), srv.URL+"/metrics", opsToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	b, _ := io.ReadAll(res.Body)
+	body := string(b)
+	for _, want := range []string{
+		`linkbridge_http_requests_total{method="GET",route="/healthz",status="200"} 1`,
+		"linkbridge_ws_connections 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("GET /metrics missing %q", want)
+		}
+	}
+
+	// Without metrics the endpoint does not exist.
+	plain := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{OpsToken: opsToken}))
+	defer plain.Close()
+	res = get(t, plain.Client(), plain.URL+"/metrics", opsToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /metrics (disabled) status = %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestOpsEndpoints_RequireOpsToken(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	_, userToken := newTestUser(t, store, nil, "alice", nowMs)
+
+	const opsToken = "ops-secret"
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{Metrics: metrics.New(), OpsToken: opsToken}))
+	defer srv.Close()
+
+	for _, path := range []string{"/statsz", "/metrics"} {
+		for token, want := range map[string]int{
+			"":        http.StatusUnauthorized,
+			userToken: http.StatusUnauthorized,
+			"wrong":   http.StatusUnauthorized,
+			opsToken:  http.StatusOK,
+		} {
+			res := get(t, srv.Client(), srv.URL+path, token)
+			_ = res.Body.Close()
+			if res.StatusCode != want {
+				t.Fatalf("GET %s with token %q status = %d, want %d", path, token, res.StatusCode, want)
+			}
+		}
+	}
+
+	// Without an ops token neither endpoint is served.
+	closed := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{Metrics: metrics.New()}))
+	defer closed.Close()
+	for _, path := range []string{"/statsz", "/metrics"} {
+		res := get(t, closed.Client(), closed.URL+path, "")
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Fatalf("GET %s (no ops token) status = %d, want %d", path, res.StatusCode, http.StatusNotFound)
+		}
+	}
+}
+
+func TestCORS_AllowsConfiguredOriginsOnly(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{
+		CORSAllowedOrigins: []string{"https://admin.example.com"},
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	preflight := func(origin string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodOptions, srv.URL+"/v1/sessions", nil)
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("OPTIONS /v1/sessions error = %v", err)
+		}
+		_ = res.Body.Close()
+		return res
+	}
+
+	allowed := preflight("https://admin.example.com")
+	if allowed.StatusCode != http.StatusNoContent {
+		t.Fatalf("allowed preflight status = %d, want 204", allowed.StatusCode)
+	}
+	if got := allowed.Header.Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if got := allowed.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") || !strings.Contains(got, "Content-Type") || !strings.Contains(got, "Idempotency-Key") {
+		t.Fatalf("Access-Control-Allow-Headers = %q, want Authorization, Content-Type and Idempotency-Key", got)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/healthz", nil)
+	if err != nil {
+		t.Fatalf("NewRequest error = %v", err)
+	}
+	req.Header.Set("Origin", "https://admin.example.com")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET /healthz error = %v", err)
+	}
+	_ = res.Body.Close()
+	if got := res.Header.Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Fatalf("GET Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+
+	rejected := preflight("https://evil.example.com")
+	if rejected.StatusCode == http.StatusNoContent {
+		t.Fatalf("rejected preflight status = 204, want it not to be answered")
+	}
+	if got := rejected.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("rejected Access-Control-Allow-Origin = %q, want empty", got)
+	}
+}
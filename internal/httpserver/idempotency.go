@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+)
+
+// idempotencyKeyTTL is how long a stored response is replayed for a repeated Idempotency-Key.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyRecorder forwards the response to the client while keeping a copy to store.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotencyRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// withIdempotency runs handle at most once per (user, Idempotency-Key). A repeated key
+// replays the stored response with Idempotency-Replayed: true; failed (non-2xx) responses are
+// not stored so the client can retry with the same key. Requests without the header are
+// handled as usual.
+func (api *v1API) withIdempotency(w http.ResponseWriter, r *http.Request, handle http.HandlerFunc) {
+	key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	userID := getUserIDFromContext(r.Context())
+	if key == "" || userID == "" {
+		handle(w, r)
+		return
+	}
+	if len(key) > storage.MaxIdempotencyKeyLen {
+		writeAPIError(w, ErrCodeValidation, "Idempotency-Key too long")
+		return
+	}
+
+	now := time.Now()
+	row, reserved, err := api.store.ReserveIdempotencyKey(r.Context(), userID, key, r.URL.Path, now.Add(-idempotencyKeyTTL).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		api.log(r.Context()).Error("reserve idempotency key failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if !reserved {
+		switch {
+		case row.RequestPath != r.URL.Path:
+			writeAPIError(w, ErrCodeIdempotencyConflict, "Idempotency-Key was used for a different request")
+		case row.StatusCode == 0:
+			writeAPIError(w, ErrCodeIdempotencyConflict, "a request with this Idempotency-Key is in progress")
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Idempotency-Replayed", "true")
+			w.WriteHeader(row.StatusCode)
+			_, _ = w.Write(row.ResponseBody)
+		}
+		return
+	}
+
+	// The client may have given up on this attempt; the outcome must still be recorded for
+	// its retry.
+	ctx := context.WithoutCancel(r.Context())
+	rec := &idempotencyRecorder{ResponseWriter: w}
+	completed := false
+	defer func() {
+		if !completed {
+			if err := api.store.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+				api.log(r.Context()).Warn("release idempotency key failed", "error", err)
+			}
+		}
+	}()
+
+	handle(rec, r)
+
+	if rec.status >= 200 && rec.status < 300 {
+		if err := api.store.CompleteIdempotencyKey(ctx, userID, key, rec.status, rec.body.Bytes()); err != nil {
+			api.log(r.Context()).Warn("store idempotent response failed", "error", err)
+			return
+		}
+		completed = true
+	}
+}
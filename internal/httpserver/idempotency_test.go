@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestCreateMessage_IdempotencyKeyReplaysResponse(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	post := func(url, key string, body any) (*http.Response, []byte) {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+aliceToken)
+		req.Header.Set("Idempotency-Key", key)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do error = %v", err)
+		}
+		defer res.Body.Close()
+		out, _ := io.ReadAll(res.Body)
+		return res, out
+	}
+
+	messagesURL := srv.URL + "/v1/sessions/" + session.ID + "/messages"
+	first, firstBody := post(messagesURL, "retry-1", map[string]any{"type": "text", "text": "hello"})
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("first POST status = %d, body=%s", first.StatusCode, string(firstBody))
+	}
+	second, secondBody := post(messagesURL, "retry-1", map[string]any{"type": "text", "text": "hello"})
+	if second.StatusCode != http.StatusOK {
+		t.Fatalf("replayed POST status = %d, body=%s", second.StatusCode, string(secondBody))
+	}
+	if second.Header.Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("replayed POST missing Idempotency-Replayed header")
+	}
+	if !bytes.Equal(firstBody, secondBody) {
+		t.Fatalf("replayed body = %s, want %s", string(secondBody), string(firstBody))
+	}
+
+	msgs, _, err := store.ListMessages(ctx, session.ID, alice.ID, 50, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("len(messages) = %d, want 1", len(msgs))
+	}
+
+	conflict, conflictBody := post(srv.URL+"/v1/session-requests", "retry-1", map[string]any{"addresseeId": bob.ID})
+	if conflict.StatusCode != http.StatusConflict {
+		t.Fatalf("reused key on another endpoint status = %d, body=%s", conflict.StatusCode, string(conflictBody))
+	}
+}
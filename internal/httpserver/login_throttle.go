@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// loginFailureWindow is how long failed attempts count towards a lockout.
+	loginFailureWindow = 15 * time.Minute
+	// loginMaxFailuresPerUser and loginMaxFailuresPerIP are the failures allowed within the
+	// window before further attempts are refused. The IP limit is looser because several
+	// users can share an address.
+	loginMaxFailuresPerUser = 5
+	loginMaxFailuresPerIP   = 20
+	// The first lockout lasts loginBaseLockout and doubles with every further failure, up to
+	// loginMaxLockout.
+	loginBaseLockout = 30 * time.Second
+	loginMaxLockout  = 15 * time.Minute
+
+	// loginThrottlePruneSize triggers dropping stale entries so the map cannot grow unbounded.
+	loginThrottlePruneSize = 10000
+)
+
+type loginFailures struct {
+	count       int
+	firstAt     time.Time
+	lockedUntil time.Time
+}
+
+// loginThrottle counts failed logins per key (username or client IP) in memory and locks a key
+// out with exponential backoff once it exceeds its limit.
+type loginThrottle struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+func newLoginThrottle() *loginThrottle {
+	return &loginThrottle{
+		now:      time.Now,
+		failures: make(map[string]*loginFailures),
+	}
+}
+
+// retryAfter returns how long the longest active lockout among keys still lasts, or 0.
+func (t *loginThrottle) retryAfter(keys ...string) time.Duration {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var wait time.Duration
+	for _, key := range keys {
+		if f, ok := t.failures[key]; ok {
+			if d := f.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// recordFailure counts a failed attempt for key and locks it out once limit is reached.
+func (t *loginThrottle) recordFailure(key string, limit int) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.failures) >= loginThrottlePruneSize {
+		t.pruneLocked(now)
+	}
+
+	f, ok := t.failures[key]
+	if !ok || (now.Sub(f.firstAt) > loginFailureWindow && !now.Before(f.lockedUntil)) {
+		f = &loginFailures{firstAt: now}
+		t.failures[key] = f
+	}
+	f.count++
+	if f.count < limit {
+		return
+	}
+
+	lockout := loginMaxLockout
+	if over := f.count - limit; over < 16 {
+		if d := loginBaseLockout << over; d < loginMaxLockout {
+			lockout = d
+		}
+	}
+	f.lockedUntil = now.Add(lockout)
+}
+
+// reset forgets the failures recorded for key.
+func (t *loginThrottle) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+func (t *loginThrottle) pruneLocked(now time.Time) {
+	for key, f := range t.failures {
+		if now.Sub(f.firstAt) > loginFailureWindow && !now.Before(f.lockedUntil) {
+			delete(t.failures, key)
+		}
+	}
+}
+
+func loginUserThrottleKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func loginIPThrottleKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottle_ExponentialBackoff(t *testing.T) {
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.UTC)
+	th := newLoginThrottle()
+	th.now = func() time.Time { return now }
+
+	key := loginUserThrottleKey("Alice")
+	for i := 0; i < loginMaxFailuresPerUser-1; i++ {
+		th.recordFailure(key, loginMaxFailuresPerUser)
+	}
+	if wait := th.retryAfter(key); wait != 0 {
+		t.Fatalf("retryAfter() below limit = %v, want 0", wait)
+	}
+
+	th.recordFailure(key, loginMaxFailuresPerUser)
+	if wait := th.retryAfter(loginUserThrottleKey("alice")); wait != loginBaseLockout {
+		t.Fatalf("retryAfter() at limit = %v, want %v", wait, loginBaseLockout)
+	}
+
+	now = now.Add(loginBaseLockout)
+	th.recordFailure(key, loginMaxFailuresPerUser)
+	if wait := th.retryAfter(key); wait != 2*loginBaseLockout {
+		t.Fatalf("retryAfter() after another failure = %v, want %v", wait, 2*loginBaseLockout)
+	}
+
+	for i := 0; i < 20; i++ {
+		th.recordFailure(key, loginMaxFailuresPerUser)
+	}
+	if wait := th.retryAfter(key); wait != loginMaxLockout {
+		t.Fatalf("retryAfter() capped = %v, want %v", wait, loginMaxLockout)
+	}
+
+	th.reset(key)
+	if wait := th.retryAfter(key); wait != 0 {
+		t.Fatalf("retryAfter() after reset = %v, want 0", wait)
+	}
+
+	// Failures older than the window no longer count.
+	for i := 0; i < loginMaxFailuresPerUser-1; i++ {
+		th.recordFailure(key, loginMaxFailuresPerUser)
+	}
+	now = now.Add(loginFailureWindow + time.Second)
+	th.recordFailure(key, loginMaxFailuresPerUser)
+	if wait := th.retryAfter(key); wait != 0 {
+		t.Fatalf("retryAfter() after window = %v, want 0", wait)
+	}
+}
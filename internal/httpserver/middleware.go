@@ -3,14 +3,20 @@ package httpserver
 import (
 	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"net"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
 	"log/slog"
 
+	"linkbridge-backend/internal/config"
+	"linkbridge-backend/internal/metrics"
 	"linkbridge-backend/internal/storage"
 )
 
@@ -67,6 +73,64 @@ func (w *statusResponseWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied request ids; longer or non-printable ones are replaced.
+const maxRequestIDLen = 128
+
+// requestIDMiddleware adopts the caller's X-Request-ID (e.g. from a proxy) or generates one,
+// echoes it on the response and stores it, with a logger carrying it, in the request context.
+func requestIDMiddleware(logger *slog.Logger) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimSpace(r.Header.Get(requestIDHeader))
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+			ctx = context.WithValue(ctx, loggerContextKey, logger.With("requestId", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+func getRequestIDFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(requestIDContextKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// loggerFromContext returns the request-scoped logger set by requestIDMiddleware, or fallback.
+func loggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return fallback
+}
+
 func requestLogMiddleware(logger *slog.Logger) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -75,7 +139,7 @@ func requestLogMiddleware(logger *slog.Logger) middleware {
 
 			next.ServeHTTP(srw, r)
 
-			logger.Info("http request",
+			loggerFromContext(r.Context(), logger).Info("http request",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", srw.status,
@@ -87,12 +151,39 @@ func requestLogMiddleware(logger *slog.Logger) middleware {
 	}
 }
 
+// metricsMiddleware records request counts and latency per mux pattern. The pattern, not the
+// raw path, is the route label so ids in paths cannot blow up label cardinality.
+func metricsMiddleware(mux *http.ServeMux, m *metrics.Metrics) middleware {
+	return func(next http.Handler) http.Handler {
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			srw := &statusResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(srw, r)
+
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+			status := srw.status
+			if status == 0 {
+				// Hijacked (WebSocket) or empty responses.
+				status = http.StatusOK
+			}
+			m.ObserveHTTPRequest(r.Method, route, status, time.Since(start))
+		})
+	}
+}
+
 func recoverMiddleware(logger *slog.Logger) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if v := recover(); v != nil {
-					logger.Error("panic", "error", v, "stack", string(debug.Stack()))
+					loggerFromContext(r.Context(), logger).Error("panic", "error", v, "stack", string(debug.Stack()))
 					w.WriteHeader(http.StatusInternalServerError)
 				}
 			}()
@@ -105,6 +196,8 @@ type contextKey string
 
 const userIDContextKey contextKey = "userID"
 const authTokenContextKey contextKey = "authToken"
+const requestIDContextKey contextKey = "requestID"
+const loggerContextKey contextKey = "logger"
 
 func getUserIDFromContext(ctx context.Context) string {
 	if v := ctx.Value(userIDContextKey); v != nil {
@@ -168,6 +261,7 @@ func isPublicPath(path string) bool {
 	publicPaths := []string{
 		"/healthz",
 		"/readyz",
+		"/v1/meta",
 		"/v1/auth/register",
 		"/v1/auth/login",
 	}
@@ -179,15 +273,65 @@ func isPublicPath(path string) bool {
 	return false
 }
 
-func corsMiddleware() middleware {
+// opsTokenMiddleware only lets requests through that carry token as a bearer token. It
+// guards operational endpoints, which are not tied to a user account.
+func opsTokenMiddleware(token string) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			got := extractTokenFromHeader(r)
+			if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeAPIError(w, ErrCodeTokenInvalid, "ops token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsPreflightMaxAge is how long browsers may cache a preflight response, in seconds.
+const corsPreflightMaxAge = "600"
+
+// corsMiddleware lets browsers on allowedOrigins call the API cross-origin by echoing their
+// Origin and answering OPTIONS preflights. A "*" entry allows any origin. With no origins
+// configured it adds nothing, so browsers keep the same-origin policy. Requests from other
+// origins are still served (CORS is enforced by the browser) but without CORS headers.
+func corsMiddleware(allowedOrigins []string) middleware {
+	allowed := make(map[string]struct{}, len(allowedOrigins))
+	allowAny := false
+	for _, origin := range allowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch origin {
+		case "":
+		case "*":
+			allowAny = true
+		default:
+			allowed[origin] = struct{}{}
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		if !allowAny && len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			if _, ok := allowed[strings.ToLower(origin)]; !ok && !allowAny {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Client-Version, X-Request-ID, Idempotency-Key")
+				w.Header().Set("Access-Control-Max-Age", corsPreflightMaxAge)
+				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 
@@ -196,6 +340,70 @@ func corsMiddleware() middleware {
 	}
 }
 
+const (
+	clientVersionHeader = "X-Client-Version"
+	// clientVersionQueryParam carries the version on the WebSocket upgrade, which browsers and
+	// some mini-program runtimes cannot add custom headers to; the token travels the same way.
+	clientVersionQueryParam = "clientVersion"
+)
+
+// clientVersionMiddleware rejects /v1 requests from clients older than minVersion. Auth and meta
+// endpoints stay reachable so an outdated client can still log in and learn it must upgrade.
+// Requests without the header are treated as too old: builds that predate the header are the
+// ones the minimum exists to turn away. /v1/ws also accepts the version as a query parameter.
+func clientVersionMiddleware(minVersion string) middleware {
+	minParts, ok := config.ParseVersion(minVersion)
+	return func(next http.Handler) http.Handler {
+		if !ok {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isClientVersionExemptPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			version := r.Header.Get(clientVersionHeader)
+			if version == "" && r.URL.Path == "/v1/ws" {
+				version = r.URL.Query().Get(clientVersionQueryParam)
+			}
+			parts, valid := config.ParseVersion(version)
+			if !valid || compareClientVersions(parts, minParts) < 0 {
+				writeAPIError(w, ErrCodeClientTooOld, "客户端版本过低，请升级到 "+minVersion+" 或更高版本")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isClientVersionExemptPath(path string) bool {
+	if !strings.HasPrefix(path, "/v1/") {
+		return true
+	}
+	return path == "/v1/meta" || strings.HasPrefix(path, "/v1/auth/")
+}
+
+// compareClientVersions compares element-wise, treating missing trailing parts as zero.
+func compareClientVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
 func extractTokenFromHeader(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
 	if strings.HasPrefix(auth, "Bearer ") {
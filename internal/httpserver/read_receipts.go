@@ -0,0 +1,102 @@
+package httpserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+// readReceiptDebounce is how long a read receipt waits for newer marks from the same reader
+// before it is sent, so scrolling through a chat produces one message.read event.
+const readReceiptDebounce = 300 * time.Millisecond
+
+type pendingReadReceipt struct {
+	recipients   []string
+	lastReadAtMs int64
+}
+
+// readReceiptDebouncer collapses successive read marks per (session, reader) into a single
+// delivery carrying the latest mark.
+type readReceiptDebouncer struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending map[[2]string]*pendingReadReceipt
+}
+
+func newReadReceiptDebouncer(delay time.Duration) *readReceiptDebouncer {
+	return &readReceiptDebouncer{
+		delay:   delay,
+		pending: make(map[[2]string]*pendingReadReceipt),
+	}
+}
+
+// schedule queues a receipt; send runs once per window with the newest mark seen in it.
+func (d *readReceiptDebouncer) schedule(sessionID, readerID string, recipients []string, lastReadAtMs int64, send func(recipients []string, lastReadAtMs int64)) {
+	key := [2]string{sessionID, readerID}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p, ok := d.pending[key]; ok {
+		p.recipients = recipients
+		if lastReadAtMs > p.lastReadAtMs {
+			p.lastReadAtMs = lastReadAtMs
+		}
+		return
+	}
+	d.pending[key] = &pendingReadReceipt{recipients: recipients, lastReadAtMs: lastReadAtMs}
+
+	time.AfterFunc(d.delay, func() {
+		d.mu.Lock()
+		p := d.pending[key]
+		delete(d.pending, key)
+		d.mu.Unlock()
+		if p != nil {
+			send(p.recipients, p.lastReadAtMs)
+		}
+	})
+}
+
+// queueReadReceipt tells the other participants of sessionID that readerID has read up to
+// lastReadAtMs. The reader's own connections are not notified.
+func (api *v1API) queueReadReceipt(ctx context.Context, sessionID, readerID string, lastReadAtMs int64) {
+	if api.wsManager == nil || api.readReceipts == nil {
+		return
+	}
+
+	sess, err := api.store.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		api.log(ctx).Warn("get session for read receipt failed", "error", err, "sessionID", sessionID)
+		return
+	}
+	var candidates []string
+	if sess.Kind == storage.SessionKindGroup {
+		candidates = api.activeParticipantIDs(ctx, sessionID)
+	} else {
+		candidates = []string{api.store.GetPeerUserID(sess, readerID)}
+	}
+	recipients := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		if id != "" && id != readerID {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	api.readReceipts.schedule(sessionID, readerID, recipients, lastReadAtMs, func(recipients []string, lastReadAtMs int64) {
+		api.sendToUsers(recipients, ws.Envelope{
+			Type:      "message.read",
+			SessionID: sessionID,
+			Payload: map[string]any{
+				"sessionId":    sessionID,
+				"userId":       readerID,
+				"lastReadAtMs": lastReadAtMs,
+			},
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestMarkSessionRead_SendsDebouncedReceiptToPeer(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	alice, aliceToken := newTestUser(t, store, nil, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, nil, "bob", nowMs)
+
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	text := "hi"
+	if _, err := store.CreateMessage(ctx, session.ID, alice.ID, storage.MessageTypeText, &text, nil, nowMs); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+
+	tokenToUserID := map[string]string{aliceToken: alice.ID, bobToken: bob.ID}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	dial := func(token string) *websocket.Conn {
+		c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		return c
+	}
+	aliceConn := dial(aliceToken)
+	defer aliceConn.Close()
+	bobConn := dial(bobToken)
+	defer bobConn.Close()
+
+	for i := 0; i < 3; i++ {
+		res := postJSON(t, client, srv.URL+"/v1/sessions/"+session.ID+"/read", map[string]any{}, bobToken)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("POST read status = %d, want %d", res.StatusCode, http.StatusOK)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	env := readWSEvent(t, aliceConn)
+	if env.Type != "message.read" || env.SessionID != session.ID {
+		t.Fatalf("event = %s/%s, want message.read/%s", env.Type, env.SessionID, session.ID)
+	}
+	var payload struct {
+		SessionID    string `json:"sessionId"`
+		UserID       string `json:"userId"`
+		LastReadAtMs int64  `json:"lastReadAtMs"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode payload error = %v", err)
+	}
+	if payload.SessionID != session.ID || payload.UserID != bob.ID || payload.LastReadAtMs < nowMs {
+		t.Fatalf("payload = %+v, want bob's read mark for %s", payload, session.ID)
+	}
+
+	// The burst of reads collapses into one receipt, and the reader is never notified.
+	_ = aliceConn.SetReadDeadline(time.Now().Add(readReceiptDebounce + 200*time.Millisecond))
+	if _, msg, err := aliceConn.ReadMessage(); err == nil {
+		t.Fatalf("unexpected extra event for alice: %s", msg)
+	}
+	_ = bobConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, msg, err := bobConn.ReadMessage(); err == nil {
+		t.Fatalf("unexpected event for reader: %s", msg)
+	}
+}
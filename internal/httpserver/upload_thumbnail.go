@@ -0,0 +1,111 @@
+package httpserver
+
+import (
+	"image"
+	"image/color"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// thumbnailMaxSide is the longest side of a generated thumbnail, in pixels.
+	thumbnailMaxSide     = 128
+	thumbnailJPEGQuality = 80
+
+	thumbnailSuffix = "_thumb.jpg"
+)
+
+// thumbnailExtensions are the uploads the standard library can decode.
+var thumbnailExtensions = map[string]struct{}{
+	".jpg":  {},
+	".jpeg": {},
+	".png":  {},
+	".gif":  {},
+}
+
+// thumbnailName returns the stored name of the thumbnail for upload name.
+func thumbnailName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + thumbnailSuffix
+}
+
+// writeUploadThumbnail decodes the image at srcPath and writes a JPEG no larger than
+// thumbnailMaxSide on either side to destPath. Images already that small are re-encoded as is.
+func writeUploadThumbnail(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	cfg, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return err
+	}
+	if err := checkDecodeDimensions(cfg); err != nil {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return err
+	}
+
+	thumb := scaleDownImage(img, thumbnailMaxSide)
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(dest, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+		dest.Close()
+		os.Remove(destPath)
+		return err
+	}
+	return dest.Close()
+}
+
+// scaleDownImage box-averages img so that neither side exceeds maxSide, flattening
+// transparency onto white since JPEG has no alpha channel. Source pixels are read straight
+// from img so no full-size copy is made.
+func scaleDownImage(img image.Image, maxSide int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > maxSide || srcH > maxSide {
+		if srcW >= srcH {
+			dstW, dstH = maxSide, max(1, srcH*maxSide/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*maxSide/srcH), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// RGBA returns alpha-premultiplied 16-bit channels; compositing onto white
+					// adds the uncovered share of white to each.
+					pr, pg, pb, pa := img.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					white := uint64(0xffff - pa)
+					r += uint64(pr) + white
+					g += uint64(pg) + white
+					bl += uint64(pb) + white
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: 0xff})
+		}
+	}
+	return dst
+}
@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
 )
 
 type activityItem struct {
@@ -21,6 +23,7 @@ type activityItem struct {
 	SessionStatus    string  `json:"sessionStatus"`
 	Expired          bool    `json:"expired"`
 	NeedsRenewPrompt bool    `json:"needsRenewPrompt"`
+	MemberCount      int     `json:"memberCount"`
 	CreatedAtMs      int64   `json:"createdAtMs"`
 	UpdatedAtMs      int64   `json:"updatedAtMs"`
 }
@@ -32,17 +35,24 @@ type createActivityRequest struct {
 	EndAtMs     *int64  `json:"endAtMs,omitempty"`
 }
 
+// ServerTimeMs on activity responses is the nowMs that `expired` was computed against,
+// so clients can anchor countdowns to server time instead of the device clock.
 type createActivityResponse struct {
-	Activity   activityItem `json:"activity"`
-	InviteCode string       `json:"inviteCode"`
+	Activity     activityItem `json:"activity"`
+	InviteCode   string       `json:"inviteCode"`
+	ServerTimeMs int64        `json:"serverTimeMs"`
 }
 
 type getActivityResponse struct {
-	Activity activityItem `json:"activity"`
+	Activity     activityItem `json:"activity"`
+	ServerTimeMs int64        `json:"serverTimeMs"`
 }
 
 type listActivitiesResponse struct {
-	Activities []activityItem `json:"activities"`
+	Activities   []activityItem `json:"activities"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+	HasMore      bool           `json:"hasMore"`
+	ServerTimeMs int64          `json:"serverTimeMs"`
 }
 
 type consumeActivityInviteRequest struct {
@@ -52,8 +62,26 @@ type consumeActivityInviteRequest struct {
 }
 
 type consumeActivityInviteResponse struct {
-	Activity activityItem `json:"activity"`
-	Joined   bool         `json:"joined"`
+	Activity     activityItem `json:"activity"`
+	Joined       bool         `json:"joined"`
+	ServerTimeMs int64        `json:"serverTimeMs"`
+}
+
+type activityPreviewItem struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description *string `json:"description,omitempty"`
+	StartAtMs   *int64  `json:"startAtMs,omitempty"`
+	EndAtMs     *int64  `json:"endAtMs,omitempty"`
+	Expired     bool    `json:"expired"`
+}
+
+type previewActivityInviteResponse struct {
+	OK            bool                 `json:"ok"`
+	Error         *apiError            `json:"error,omitempty"`
+	AlreadyMember bool                 `json:"alreadyMember"`
+	Activity      *activityPreviewItem `json:"activity,omitempty"`
+	ServerTimeMs  int64                `json:"serverTimeMs"`
 }
 
 type listActivityMembersResponse struct {
@@ -70,6 +98,21 @@ type activityMemberItem struct {
 	UpdatedAtMs int64   `json:"updatedAtMs"`
 }
 
+type updateActivityRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	StartAtMs   *int64  `json:"startAtMs,omitempty"`
+	EndAtMs     *int64  `json:"endAtMs,omitempty"`
+}
+
+type setActivityMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type transferActivityRequest struct {
+	UserID string `json:"userId"`
+}
+
 type extendActivityRequest struct {
 	EndAtMs int64 `json:"endAtMs"`
 }
@@ -130,19 +173,32 @@ func (api *v1API) handleActivities(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// POST /v1/activities/invites/preview
+	if len(parts) == 2 && parts[0] == "invites" && parts[1] == "preview" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handlePreviewActivityInvite(w, r, userID)
+		return
+	}
+
 	activityID := strings.TrimSpace(parts[0])
 	if activityID == "" {
 		writeAPIError(w, ErrCodeValidation, "activityId is required")
 		return
 	}
 
-	// GET /v1/activities/{id}
+	// GET/PATCH /v1/activities/{id}
 	if len(parts) == 1 {
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			api.handleGetActivity(w, r, userID, activityID)
+		case http.MethodPatch:
+			api.handleUpdateActivity(w, r, userID, activityID)
+		default:
 			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
-			return
 		}
-		api.handleGetActivity(w, r, userID, activityID)
 		return
 	}
 
@@ -156,13 +212,18 @@ func (api *v1API) handleActivities(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// POST /v1/activities/{id}/reminders
+	// GET/POST/DELETE /v1/activities/{id}/reminders
 	if len(parts) == 2 && parts[1] == "reminders" {
-		if r.Method != http.MethodPost {
+		switch r.Method {
+		case http.MethodGet:
+			api.handleGetActivityReminder(w, r, userID, activityID)
+		case http.MethodPost:
+			api.handleUpsertActivityReminder(w, r, userID, activityID)
+		case http.MethodDelete:
+			api.handleDeleteActivityReminder(w, r, userID, activityID)
+		default:
 			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
-			return
 		}
-		api.handleUpsertActivityReminder(w, r, userID, activityID)
 		return
 	}
 
@@ -177,6 +238,57 @@ func (api *v1API) handleActivities(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// POST /v1/activities/{id}/members/{userId}/role
+	if len(parts) == 4 && parts[1] == "members" && parts[3] == "role" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		targetUserID := strings.TrimSpace(parts[2])
+		api.handleSetActivityMemberRole(w, r, userID, activityID, targetUserID)
+		return
+	}
+
+	// GET /v1/activities/{id}/invite/qr
+	if len(parts) == 3 && parts[1] == "invite" && parts[2] == "qr" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleActivityInviteQRCode(w, r, userID, activityID)
+		return
+	}
+
+	// POST /v1/activities/{id}/transfer
+	if len(parts) == 2 && parts[1] == "transfer" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleTransferActivity(w, r, userID, activityID)
+		return
+	}
+
+	// POST /v1/activities/{id}/leave
+	if len(parts) == 2 && parts[1] == "leave" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleLeaveActivity(w, r, userID, activityID)
+		return
+	}
+
+	// POST /v1/activities/{id}/call
+	if len(parts) == 2 && parts[1] == "call" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleCreateGroupCall(w, r, userID, activityID)
+		return
+	}
+
 	// POST /v1/activities/{id}/extend
 	if len(parts) == 2 && parts[1] == "extend" {
 		if r.Method != http.MethodPost {
@@ -231,9 +343,44 @@ func (api *v1API) handleListActivities(w http.ResponseWriter, r *http.Request, u
 		_, _ = api.store.ArchiveExpiredActivitySessions(r.Context(), nowMs)
 	}
 
-	activities, err := api.store.ListActivitiesForUser(r.Context(), userID, status, nowMs, limit)
+	cursor := strings.TrimSpace(r.URL.Query().Get("cursor"))
+	if cursor == "" {
+		cursor = strings.TrimSpace(r.URL.Query().Get("before"))
+	}
+
+	query := r.URL.Query().Get("q")
+
+	var (
+		activities []storage.ActivityRow
+		nextCursor string
+		err        error
+	)
+	switch strings.TrimSpace(r.URL.Query().Get("role")) {
+	case "":
+		activities, nextCursor, err = api.store.ListActivitiesForUser(r.Context(), userID, status, nowMs, limit, cursor, query)
+	case storage.SessionParticipantRoleCreator:
+		activities, nextCursor, err = api.store.ListActivitiesCreatedBy(r.Context(), userID, status, limit, cursor, query)
+	default:
+		writeAPIError(w, ErrCodeValidation, "role must be creator")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			writeAPIError(w, ErrCodeValidation, "invalid cursor")
+			return
+		}
+		api.log(r.Context()).Error("list activities failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	sessionIDs := make([]string, 0, len(activities))
+	for _, a := range activities {
+		sessionIDs = append(sessionIDs, a.SessionID)
+	}
+	memberCounts, err := api.store.CountActivityMembersBySession(r.Context(), sessionIDs)
 	if err != nil {
-		api.logger.Error("list activities failed", "error", err)
+		api.log(r.Context()).Error("count activity members failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -244,10 +391,15 @@ func (api *v1API) handleListActivities(w http.ResponseWriter, r *http.Request, u
 		if err != nil {
 			continue
 		}
-		items = append(items, activityItemFromRows(a, sess, userID, nowMs))
+		items = append(items, activityItemFromRows(a, sess, userID, memberCounts[a.SessionID], nowMs))
 	}
 
-	writeJSON(w, http.StatusOK, listActivitiesResponse{Activities: items})
+	writeJSON(w, http.StatusOK, listActivitiesResponse{
+		Activities:   items,
+		NextCursor:   nextCursor,
+		HasMore:      nextCursor != "",
+		ServerTimeMs: nowMs,
+	})
 }
 
 func (api *v1API) handleGetActivity(w http.ResponseWriter, r *http.Request, userID, activityID string) {
@@ -264,7 +416,7 @@ func (api *v1API) handleGetActivityWithInvite(w http.ResponseWriter, r *http.Req
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity failed", "error", err)
+		api.log(r.Context()).Error("get activity failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -275,14 +427,14 @@ func (api *v1API) handleGetActivityWithInvite(w http.ResponseWriter, r *http.Req
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity session failed", "error", err)
+		api.log(r.Context()).Error("get activity session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	ok, err := api.store.IsSessionParticipant(r.Context(), sess.ID, userID)
 	if err != nil {
-		api.logger.Error("check activity participant failed", "error", err)
+		api.log(r.Context()).Error("check activity participant failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -291,14 +443,21 @@ func (api *v1API) handleGetActivityWithInvite(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	item := activityItemFromRows(activity, sess, userID, nowMs)
+	memberCount, err := api.store.CountActivityMembers(r.Context(), sess.ID)
+	if err != nil {
+		api.log(r.Context()).Error("count activity members failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	item := activityItemFromRows(activity, sess, userID, memberCount, nowMs)
 
 	if inviteCode != nil {
-		writeJSON(w, http.StatusOK, createActivityResponse{Activity: item, InviteCode: *inviteCode})
+		writeJSON(w, http.StatusOK, createActivityResponse{Activity: item, InviteCode: *inviteCode, ServerTimeMs: nowMs})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, getActivityResponse{Activity: item})
+	writeJSON(w, http.StatusOK, getActivityResponse{Activity: item, ServerTimeMs: nowMs})
 }
 
 func (api *v1API) handleConsumeActivityInvite(w http.ResponseWriter, r *http.Request, userID string) {
@@ -313,67 +472,140 @@ func (api *v1API) handleConsumeActivityInvite(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	var (
-		atLatE7 *int64
-		atLngE7 *int64
-	)
-	if req.AtLat != nil {
-		if *req.AtLat < -90 || *req.AtLat > 90 {
-			writeAPIError(w, ErrCodeValidation, "invalid atLat range")
-			return
-		}
-		v := floatToE7(*req.AtLat)
-		atLatE7 = &v
-	}
-	if req.AtLng != nil {
-		if *req.AtLng < -180 || *req.AtLng > 180 {
-			writeAPIError(w, ErrCodeValidation, "invalid atLng range")
-			return
-		}
-		v := floatToE7(*req.AtLng)
-		atLngE7 = &v
+	atLatE7, atLngE7, ok := parseInviteLocation(w, req.AtLat, req.AtLng)
+	if !ok {
+		return
 	}
 
 	nowMs := time.Now().UnixMilli()
 	activity, session, joined, err := api.store.ConsumeActivityInvite(r.Context(), userID, req.Code, atLatE7, atLngE7, nowMs)
 	if err != nil {
-		if errors.Is(err, storage.ErrInviteInvalid) {
-			writeAPIError(w, ErrCodeActivityInviteInvalid, "invalid invite")
+		if code, msg, ok := activityInviteErrorCode(err); ok {
+			writeAPIError(w, code, msg)
 			return
 		}
-		if errors.Is(err, storage.ErrInviteExpired) {
-			writeAPIError(w, ErrCodeInviteExpired, "invite expired")
-			return
-		}
-		if errors.Is(err, storage.ErrGeoFenceRequired) {
-			writeAPIError(w, ErrCodeGeoFenceRequired, "location required")
-			return
+		api.log(r.Context()).Error("consume activity invite failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, consumeActivityInviteResponse{
+		Activity:     activityItemFromRows(activity, session, userID, api.activityMemberCount(r.Context(), session.ID), nowMs),
+		Joined:       joined,
+		ServerTimeMs: nowMs,
+	})
+
+	if !joined {
+		return
+	}
+	member, err := api.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		api.log(r.Context()).Warn("get joined member failed", "error", err, "activityID", activity.ID)
+		return
+	}
+	// Scoped to the roster rather than api.broadcast, which would reach every connected client.
+	recipients := make([]string, 0)
+	for _, id := range api.activeParticipantIDs(r.Context(), session.ID) {
+		if id != userID {
+			recipients = append(recipients, id)
 		}
-		if errors.Is(err, storage.ErrGeoFenceForbidden) {
-			writeAPIError(w, ErrCodeGeoFenceForbidden, "outside allowed area")
+	}
+	api.sendToUsers(recipients, ws.Envelope{
+		Type:      "activity.member.joined",
+		SessionID: session.ID,
+		Payload: map[string]any{
+			"activityId":  activity.ID,
+			"userId":      member.ID,
+			"displayName": member.DisplayName,
+			"avatarUrl":   member.AvatarURL,
+		},
+	})
+}
+
+func (api *v1API) handlePreviewActivityInvite(w http.ResponseWriter, r *http.Request, userID string) {
+	var req consumeActivityInviteRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		writeAPIError(w, ErrCodeValidation, "code is required")
+		return
+	}
+
+	atLatE7, atLngE7, ok := parseInviteLocation(w, req.AtLat, req.AtLng)
+	if !ok {
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	preview, err := api.store.PreviewActivityInvite(r.Context(), userID, req.Code, atLatE7, atLngE7, nowMs)
+	resp := previewActivityInviteResponse{OK: err == nil, AlreadyMember: preview.AlreadyMember, ServerTimeMs: nowMs}
+	if err != nil {
+		code, msg, ok := activityInviteErrorCode(err)
+		if !ok {
+			api.log(r.Context()).Error("preview activity invite failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
-		if errors.Is(err, storage.ErrNotFound) {
-			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
-			return
+		resp.Error = &apiError{Code: string(code), Message: msg}
+	}
+	if preview.Activity.ID != "" {
+		a := preview.Activity
+		resp.Activity = &activityPreviewItem{
+			ID:          a.ID,
+			Title:       a.Title,
+			Description: a.Description,
+			StartAtMs:   a.StartAtMs,
+			EndAtMs:     a.EndAtMs,
+			Expired:     a.EndAtMs != nil && nowMs > *a.EndAtMs,
 		}
-		if errors.Is(err, storage.ErrInvalidState) {
-			writeAPIError(w, ErrCodeActivityInvalidState, "activity ended")
-			return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// activityInviteErrorCode maps the storage errors shared by invite consume/preview to API codes.
+func activityInviteErrorCode(err error) (ErrorCode, string, bool) {
+	switch {
+	case errors.Is(err, storage.ErrInviteInvalid):
+		return ErrCodeActivityInviteInvalid, "invalid invite", true
+	case errors.Is(err, storage.ErrInviteExpired):
+		return ErrCodeInviteExpired, "invite expired", true
+	case errors.Is(err, storage.ErrGeoFenceRequired):
+		return ErrCodeGeoFenceRequired, "location required", true
+	case errors.Is(err, storage.ErrGeoFenceForbidden):
+		return ErrCodeGeoFenceForbidden, "outside allowed area", true
+	case errors.Is(err, storage.ErrNotFound):
+		return ErrCodeActivityNotFound, "activity not found", true
+	case errors.Is(err, storage.ErrInvalidState):
+		return ErrCodeActivityInvalidState, "activity ended", true
+	case errors.Is(err, storage.ErrSessionArchived):
+		return ErrCodeSessionArchived, "session is archived", true
+	default:
+		return "", "", false
+	}
+}
+
+func parseInviteLocation(w http.ResponseWriter, atLat, atLng *float64) (atLatE7, atLngE7 *int64, ok bool) {
+	if atLat != nil {
+		if *atLat < -90 || *atLat > 90 {
+			writeAPIError(w, ErrCodeValidation, "invalid atLat range")
+			return nil, nil, false
 		}
-		if errors.Is(err, storage.ErrSessionArchived) {
-			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
-			return
+		v := floatToE7(*atLat)
+		atLatE7 = &v
+	}
+	if atLng != nil {
+		if *atLng < -180 || *atLng > 180 {
+			writeAPIError(w, ErrCodeValidation, "invalid atLng range")
+			return nil, nil, false
 		}
-		api.logger.Error("consume activity invite failed", "error", err)
-		writeAPIError(w, ErrCodeInternal, "internal error")
-		return
+		v := floatToE7(*atLng)
+		atLngE7 = &v
 	}
-
-	writeJSON(w, http.StatusOK, consumeActivityInviteResponse{
-		Activity: activityItemFromRows(activity, session, userID, nowMs),
-		Joined:   joined,
-	})
+	return atLatE7, atLngE7, true
 }
 
 func (api *v1API) handleListActivityMembers(w http.ResponseWriter, r *http.Request, userID, activityID string) {
@@ -386,14 +618,14 @@ func (api *v1API) handleListActivityMembers(w http.ResponseWriter, r *http.Reque
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity failed", "error", err)
+		api.log(r.Context()).Error("get activity failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	ok, err := api.store.IsSessionParticipant(r.Context(), activity.SessionID, userID)
 	if err != nil {
-		api.logger.Error("check activity participant failed", "error", err)
+		api.log(r.Context()).Error("check activity participant failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -404,15 +636,26 @@ func (api *v1API) handleListActivityMembers(w http.ResponseWriter, r *http.Reque
 
 	members, err := api.store.ListActivityMembers(r.Context(), activityID)
 	if err != nil {
-		api.logger.Error("list activity members failed", "error", err)
+		api.log(r.Context()).Error("list activity members failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	memberIDs := make([]string, 0, len(members))
+	for _, m := range members {
+		memberIDs = append(memberIDs, m.UserID)
+	}
+	users, err := api.store.GetUsersByIDs(r.Context(), memberIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get activity member users failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	items := make([]activityMemberItem, 0, len(members))
 	for _, m := range members {
-		u, err := api.store.GetUserByID(r.Context(), m.UserID)
-		if err != nil {
+		u, ok := users[m.UserID]
+		if !ok {
 			continue
 		}
 		items = append(items, activityMemberItem{
@@ -437,6 +680,16 @@ func (api *v1API) handleRemoveActivityMember(w http.ResponseWriter, r *http.Requ
 	}
 
 	nowMs := time.Now().UnixMilli()
+	activity, err := api.store.GetActivityByID(r.Context(), activityID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity/member not found")
+			return
+		}
+		api.log(r.Context()).Error("get activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
 	if err := api.store.RemoveActivityMember(r.Context(), activityID, userID, targetUserID, nowMs); err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			writeAPIError(w, ErrCodeActivityNotFound, "activity/member not found")
@@ -446,12 +699,195 @@ func (api *v1API) handleRemoveActivityMember(w http.ResponseWriter, r *http.Requ
 			writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
 			return
 		}
-		api.logger.Error("remove activity member failed", "error", err)
+		api.log(r.Context()).Error("remove activity member failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{"removed": true})
+
+	// The removed user gets the event too so their client drops the activity;
+	// remaining members use it to update the roster.
+	remaining := api.activeParticipantIDs(r.Context(), activity.SessionID)
+	api.sendToUsers(append(remaining, targetUserID), ws.Envelope{
+		Type:      "activity.member.removed",
+		SessionID: activity.SessionID,
+		Payload: map[string]any{
+			"activityId": activity.ID,
+			"userId":     targetUserID,
+		},
+	})
+
+	name := targetUserID
+	if u, err := api.store.GetUserByID(r.Context(), targetUserID); err == nil {
+		name = u.DisplayName
+	}
+	text := name + " 已被移出活动"
+	msg, err := api.store.CreateMessage(r.Context(), activity.SessionID, userID, storage.MessageTypeSystem, &text, nil, nowMs)
+	if err != nil {
+		api.log(r.Context()).Warn("create member removed system message failed", "error", err, "activityID", activity.ID)
+		return
+	}
+	api.sendToUsers(remaining, ws.Envelope{
+		Type:      "message.created",
+		SessionID: msg.SessionID,
+		Payload: map[string]any{
+			"message": messageItem{
+				ID:          msg.ID,
+				SessionID:   msg.SessionID,
+				Sender:      "me",
+				SenderID:    msg.SenderID,
+				Type:        msg.Type,
+				Text:        text,
+				CreatedAtMs: msg.CreatedAtMs,
+			},
+		},
+	})
+}
+
+func (api *v1API) handleSetActivityMemberRole(w http.ResponseWriter, r *http.Request, userID, activityID, targetUserID string) {
+	if targetUserID == "" {
+		writeAPIError(w, ErrCodeValidation, "target userId is required")
+		return
+	}
+	var req setActivityMemberRoleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.Role = strings.TrimSpace(req.Role)
+	if req.Role != storage.SessionParticipantRoleAdmin && req.Role != storage.SessionParticipantRoleMember {
+		writeAPIError(w, ErrCodeValidation, "role must be admin or member")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	activity, err := api.store.GetActivityByID(r.Context(), activityID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return
+		}
+		api.log(r.Context()).Error("get activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if err := api.store.SetActivityMemberRole(r.Context(), activityID, userID, targetUserID, req.Role, nowMs); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity/member not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeActivityInvalidState, "creator role cannot be changed")
+			return
+		}
+		api.log(r.Context()).Error("set activity member role failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"userId": targetUserID, "role": req.Role})
+
+	api.sendToUsers(api.activeParticipantIDs(r.Context(), activity.SessionID), ws.Envelope{
+		Type:      "activity.member.role",
+		SessionID: activity.SessionID,
+		Payload: map[string]any{
+			"activityId": activity.ID,
+			"userId":     targetUserID,
+			"role":       req.Role,
+		},
+	})
+}
+
+func (api *v1API) handleLeaveActivity(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	nowMs := time.Now().UnixMilli()
+	activity, err := api.store.GetActivityByID(r.Context(), activityID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return
+		}
+		api.log(r.Context()).Error("get activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if err := api.store.LeaveActivity(r.Context(), activityID, userID, nowMs); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity/member not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeActivityInvalidState, "creator must transfer or delete the activity before leaving")
+			return
+		}
+		api.log(r.Context()).Error("leave activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"left": true})
+
+	remaining := api.activeParticipantIDs(r.Context(), activity.SessionID)
+	api.sendToUsers(append(remaining, userID), ws.Envelope{
+		Type:      "activity.member.left",
+		SessionID: activity.SessionID,
+		Payload: map[string]any{
+			"activityId": activity.ID,
+			"userId":     userID,
+		},
+	})
+
+	name := userID
+	if u, err := api.store.GetUserByID(r.Context(), userID); err == nil {
+		name = u.DisplayName
+	}
+	text := name + " 已退出活动"
+	// The leaver is no longer an active participant, so the notice goes through the system path.
+	msg, err := api.store.CreateSystemMessage(r.Context(), activity.SessionID, userID, text, nowMs)
+	if err != nil {
+		api.log(r.Context()).Warn("create member left system message failed", "error", err, "activityID", activity.ID)
+		return
+	}
+	api.sendToUsers(remaining, ws.Envelope{
+		Type:      "message.created",
+		SessionID: msg.SessionID,
+		Payload: map[string]any{
+			"message": messageItem{
+				ID:          msg.ID,
+				SessionID:   msg.SessionID,
+				Sender:      "peer",
+				SenderID:    msg.SenderID,
+				Type:        msg.Type,
+				Text:        text,
+				CreatedAtMs: msg.CreatedAtMs,
+			},
+		},
+	})
+}
+
+// activityMemberCount is a best-effort count for responses where the activity itself is the point;
+// a failed count is logged and reported as zero rather than failing the request.
+func (api *v1API) activityMemberCount(ctx context.Context, sessionID string) int {
+	n, err := api.store.CountActivityMembers(ctx, sessionID)
+	if err != nil {
+		api.log(ctx).Warn("count activity members failed", "error", err, "sessionID", sessionID)
+		return 0
+	}
+	return n
+}
+
+// activeParticipantIDs lists the users currently in a group session for targeted WS delivery.
+func (api *v1API) activeParticipantIDs(ctx context.Context, sessionID string) []string {
+	ids, err := api.store.ListActiveSessionParticipantIDs(ctx, sessionID)
+	if err != nil {
+		api.log(ctx).Warn("list session participants failed", "error", err, "sessionID", sessionID)
+		return nil
+	}
+	return ids
 }
 
 func (api *v1API) handleExtendActivity(w http.ResponseWriter, r *http.Request, userID, activityID string) {
@@ -487,11 +923,117 @@ func (api *v1API) handleExtendActivity(w http.ResponseWriter, r *http.Request, u
 	}
 
 	writeJSON(w, http.StatusOK, getActivityResponse{
-		Activity: activityItemFromRows(activity, sess, userID, nowMs),
+		Activity:     activityItemFromRows(activity, sess, userID, api.activityMemberCount(r.Context(), sess.ID), nowMs),
+		ServerTimeMs: nowMs,
 	})
 }
 
-func activityItemFromRows(a storage.ActivityRow, sess storage.SessionRow, viewerID string, nowMs int64) activityItem {
+func (api *v1API) handleTransferActivity(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	var req transferActivityRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		writeAPIError(w, ErrCodeValidation, "userId is required")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	activity, err := api.store.TransferActivityOwnership(r.Context(), activityID, userID, req.UserID, nowMs)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeActivityInvalidState, "new owner must be an active member")
+			return
+		}
+		api.log(r.Context()).Error("transfer activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	sess, err := api.store.GetSessionByID(r.Context(), activity.SessionID)
+	if err != nil {
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	memberCount := api.activityMemberCount(r.Context(), sess.ID)
+	writeJSON(w, http.StatusOK, getActivityResponse{
+		Activity:     activityItemFromRows(activity, sess, userID, memberCount, nowMs),
+		ServerTimeMs: nowMs,
+	})
+
+	api.sendToUsers(api.activeParticipantIDs(r.Context(), activity.SessionID), ws.Envelope{
+		Type:      "activity.updated",
+		SessionID: activity.SessionID,
+		Payload: map[string]any{
+			"activity": activityItemFromRows(activity, sess, "", memberCount, nowMs),
+		},
+	})
+}
+
+func (api *v1API) handleUpdateActivity(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	var req updateActivityRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.Title == nil && req.Description == nil && req.StartAtMs == nil && req.EndAtMs == nil {
+		writeAPIError(w, ErrCodeValidation, "no fields to update")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	activity, err := api.store.UpdateActivity(r.Context(), activityID, userID, storage.ActivityUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		StartAtMs:   req.StartAtMs,
+		EndAtMs:     req.EndAtMs,
+	}, nowMs)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+			return
+		}
+		writeAPIError(w, ErrCodeValidation, "invalid activity fields")
+		return
+	}
+
+	sess, err := api.store.GetSessionByID(r.Context(), activity.SessionID)
+	if err != nil {
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	memberCount := api.activityMemberCount(r.Context(), sess.ID)
+	writeJSON(w, http.StatusOK, getActivityResponse{
+		Activity:     activityItemFromRows(activity, sess, userID, memberCount, nowMs),
+		ServerTimeMs: nowMs,
+	})
+
+	api.sendToUsers(api.activeParticipantIDs(r.Context(), activity.SessionID), ws.Envelope{
+		Type:      "activity.updated",
+		SessionID: activity.SessionID,
+		Payload: map[string]any{
+			"activity": activityItemFromRows(activity, sess, "", memberCount, nowMs),
+		},
+	})
+}
+
+func activityItemFromRows(a storage.ActivityRow, sess storage.SessionRow, viewerID string, memberCount int, nowMs int64) activityItem {
 	expired := a.EndAtMs != nil && nowMs > *a.EndAtMs
 	return activityItem{
 		ID:               a.ID,
@@ -504,6 +1046,7 @@ func activityItemFromRows(a storage.ActivityRow, sess storage.SessionRow, viewer
 		SessionStatus:    sess.Status,
 		Expired:          expired,
 		NeedsRenewPrompt: expired && viewerID == a.CreatorID,
+		MemberCount:      memberCount,
 		CreatedAtMs:      a.CreatedAtMs,
 		UpdatedAtMs:      a.UpdatedAtMs,
 	}
@@ -532,25 +1075,8 @@ func (api *v1API) handleUpsertActivityReminder(w http.ResponseWriter, r *http.Re
 	nowMs := time.Now().UnixMilli()
 	_, _ = api.store.ArchiveActivitySessionIfExpired(r.Context(), activityID, nowMs)
 
-	activity, err := api.store.GetActivityByID(r.Context(), activityID)
-	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
-			return
-		}
-		api.logger.Error("get activity failed", "error", err)
-		writeAPIError(w, ErrCodeInternal, "internal error")
-		return
-	}
-
-	ok, err := api.store.IsSessionParticipant(r.Context(), activity.SessionID, userID)
-	if err != nil {
-		api.logger.Error("check activity participant failed", "error", err)
-		writeAPIError(w, ErrCodeInternal, "internal error")
-		return
-	}
+	activity, ok := api.requireActivityParticipant(w, r, userID, activityID)
 	if !ok {
-		writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
 		return
 	}
 
@@ -578,10 +1104,74 @@ func (api *v1API) handleUpsertActivityReminder(w http.ResponseWriter, r *http.Re
 
 	row, err := api.store.UpsertActivityReminder(r.Context(), activityID, userID, remindAtMs, nowMs)
 	if err != nil {
-		api.logger.Error("upsert activity reminder failed", "error", err)
+		api.log(r.Context()).Error("upsert activity reminder failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, upsertActivityReminderResponse{Reminder: activityReminderItemFromRow(row)})
 }
+
+func (api *v1API) handleGetActivityReminder(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	if _, ok := api.requireActivityParticipant(w, r, userID, activityID); !ok {
+		return
+	}
+
+	row, err := api.store.GetActivityReminder(r.Context(), activityID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeNotFound, "reminder not found")
+			return
+		}
+		api.log(r.Context()).Error("get activity reminder failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, upsertActivityReminderResponse{Reminder: activityReminderItemFromRow(row)})
+}
+
+func (api *v1API) handleDeleteActivityReminder(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	if _, ok := api.requireActivityParticipant(w, r, userID, activityID); !ok {
+		return
+	}
+
+	if err := api.store.DeleteActivityReminder(r.Context(), activityID, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeNotFound, "reminder not found")
+			return
+		}
+		api.log(r.Context()).Error("delete activity reminder failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
+}
+
+// requireActivityParticipant loads the activity and checks the caller is an active member,
+// writing the error response itself when either fails.
+func (api *v1API) requireActivityParticipant(w http.ResponseWriter, r *http.Request, userID, activityID string) (storage.ActivityRow, bool) {
+	activity, err := api.store.GetActivityByID(r.Context(), activityID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return storage.ActivityRow{}, false
+		}
+		api.log(r.Context()).Error("get activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return storage.ActivityRow{}, false
+	}
+
+	ok, err := api.store.IsSessionParticipant(r.Context(), activity.SessionID, userID)
+	if err != nil {
+		api.log(r.Context()).Error("check activity participant failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return storage.ActivityRow{}, false
+	}
+	if !ok {
+		writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+		return storage.ActivityRow{}, false
+	}
+	return activity, true
+}
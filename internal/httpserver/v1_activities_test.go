@@ -1,15 +1,18 @@
 package httpserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
 )
@@ -90,6 +93,12 @@ func TestActivities_CreateJoinMembersRemove_Smoke(t *testing.T) {
 		t.Fatalf("expected inviteCode to be non-empty")
 	}
 
+	creatorConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws?token="+creatorToken, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer creatorConn.Close()
+
 	consumeRes := postJSON(t, client, srv.URL+"/v1/activities/invites/consume", map[string]any{
 		"code": created.InviteCode,
 	}, memberToken)
@@ -108,6 +117,21 @@ func TestActivities_CreateJoinMembersRemove_Smoke(t *testing.T) {
 		t.Fatalf("expected joined=true on first join")
 	}
 
+	joinedEnv := readWSEvent(t, creatorConn)
+	if joinedEnv.Type != "activity.member.joined" || joinedEnv.SessionID != created.Activity.SessionID {
+		t.Fatalf("ws event = %s/%s, want activity.member.joined/%s", joinedEnv.Type, joinedEnv.SessionID, created.Activity.SessionID)
+	}
+	var joinedPayload struct {
+		UserID      string `json:"userId"`
+		DisplayName string `json:"displayName"`
+	}
+	if err := json.Unmarshal(joinedEnv.Payload, &joinedPayload); err != nil {
+		t.Fatalf("decode joined payload error = %v", err)
+	}
+	if joinedPayload.UserID != memberID || joinedPayload.DisplayName != "member" {
+		t.Fatalf("joined payload = %+v, want member %s", joinedPayload, memberID)
+	}
+
 	membersRes := get(t, client, srv.URL+"/v1/activities/"+created.Activity.ID+"/members", memberToken)
 	defer membersRes.Body.Close()
 	if membersRes.StatusCode != http.StatusOK {
@@ -162,3 +186,376 @@ func TestActivities_CreateJoinMembersRemove_Smoke(t *testing.T) {
 		t.Fatalf("POST remove creator status = %d, want %d, body=%s", removeCreatorRes.StatusCode, http.StatusForbidden, string(b))
 	}
 }
+
+func TestActivities_PreviewInvite_DoesNotJoin(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	tokenToUserID := map[string]string{}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	register := func(username string) (userID string, token string) {
+		res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+			"username":    username,
+			"password":    "P@ssw0rd1",
+			"displayName": username,
+		}, "")
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("register status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+		}
+		var body struct {
+			User struct {
+				ID string `json:"id"`
+			} `json:"user"`
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode register response error = %v", err)
+		}
+		tokenToUserID[body.Token] = body.User.ID
+		return body.User.ID, body.Token
+	}
+
+	_, creatorToken := register("creator")
+	_, memberToken := register("member")
+
+	createRes := postJSON(t, client, srv.URL+"/v1/activities", map[string]any{
+		"title":   "Geo Activity",
+		"endAtMs": time.Now().Add(2 * time.Hour).UnixMilli(),
+	}, creatorToken)
+	defer createRes.Body.Close()
+	var created struct {
+		Activity struct {
+			ID string `json:"id"`
+		} `json:"activity"`
+		InviteCode string `json:"inviteCode"`
+	}
+	if err := json.NewDecoder(createRes.Body).Decode(&created); err != nil {
+		t.Fatalf("decode create activity response error = %v", err)
+	}
+
+	fence := &storage.GeoFence{LatE7: 310000000, LngE7: 1210000000, RadiusM: 500}
+	if _, err := store.UpdateActivityInviteSettings(ctx, created.Activity.ID, nil, fence, nil, time.Now().UnixMilli()); err != nil {
+		t.Fatalf("UpdateActivityInviteSettings() error = %v", err)
+	}
+
+	preview := func(body map[string]any) previewActivityInviteResponse {
+		t.Helper()
+		res := postJSON(t, client, srv.URL+"/v1/activities/invites/preview", body, memberToken)
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("POST /v1/activities/invites/preview status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+		}
+		var out previewActivityInviteResponse
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			t.Fatalf("decode preview response error = %v", err)
+		}
+		return out
+	}
+
+	noLocation := preview(map[string]any{"code": created.InviteCode})
+	if noLocation.OK || noLocation.Error == nil || noLocation.Error.Code != string(ErrCodeGeoFenceRequired) {
+		t.Fatalf("preview without location = %+v, want GEOFENCE_REQUIRED", noLocation)
+	}
+	if noLocation.Activity == nil || noLocation.Activity.Title != "Geo Activity" {
+		t.Fatalf("preview activity = %+v, want summary of Geo Activity", noLocation.Activity)
+	}
+
+	inside := preview(map[string]any{"code": created.InviteCode, "atLat": 31.0, "atLng": 121.0})
+	if !inside.OK || inside.Error != nil || inside.AlreadyMember {
+		t.Fatalf("preview inside fence = %+v, want ok", inside)
+	}
+
+	members, err := store.ListActivityMembers(ctx, created.Activity.ID)
+	if err != nil {
+		t.Fatalf("ListActivityMembers() error = %v", err)
+	}
+	if len(members) != 1 {
+		t.Fatalf("members = %d, want 1 (preview must not join)", len(members))
+	}
+}
+
+func patchJSON(t *testing.T, client *http.Client, url string, body any, token string) *http.Response {
+	t.Helper()
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal error = %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("NewRequest error = %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do error = %v", err)
+	}
+	return res
+}
+
+func TestActivities_PartialUpdate_NotifiesMembers(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	creator, creatorToken := newTestUser(t, store, nil, "creator", nowMs)
+	member, memberToken := newTestUser(t, store, nil, "member", nowMs)
+
+	tokenToUserID := map[string]string{creatorToken: creator.ID, memberToken: member.ID}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	desc := "original"
+	endAt := nowMs + 2*time.Hour.Milliseconds()
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Tpyo", &desc, nil, &endAt, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, member.ID, invite.Code, nil, nil, nowMs); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + memberToken
+	c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	res := patchJSON(t, client, srv.URL+"/v1/activities/"+activity.ID, map[string]any{"title": "Hijack"}, memberToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("PATCH (member) status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = patchJSON(t, client, srv.URL+"/v1/activities/"+activity.ID, map[string]any{"title": "Typo", "startAtMs": endAt + 1}, creatorToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("PATCH (start after end) status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	res = patchJSON(t, client, srv.URL+"/v1/activities/"+activity.ID, map[string]any{"title": "Typo"}, creatorToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("PATCH status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+	var body getActivityResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode PATCH response error = %v", err)
+	}
+	if body.Activity.Title != "Typo" {
+		t.Fatalf("title = %q, want %q", body.Activity.Title, "Typo")
+	}
+	if body.ServerTimeMs < nowMs {
+		t.Fatalf("serverTimeMs = %d, want >= %d", body.ServerTimeMs, nowMs)
+	}
+	if body.Activity.Description == nil || *body.Activity.Description != "original" {
+		t.Fatalf("description = %v, want unchanged", body.Activity.Description)
+	}
+	if body.Activity.EndAtMs == nil || *body.Activity.EndAtMs != endAt {
+		t.Fatalf("endAtMs = %v, want unchanged %d", body.Activity.EndAtMs, endAt)
+	}
+
+	env := readWSEvent(t, c)
+	if env.Type != "activity.updated" || env.SessionID != activity.SessionID {
+		t.Fatalf("ws event = %s/%s, want activity.updated/%s", env.Type, env.SessionID, activity.SessionID)
+	}
+	var payload struct {
+		Activity activityItem `json:"activity"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode ws payload error = %v", err)
+	}
+	if payload.Activity.Title != "Typo" {
+		t.Fatalf("ws activity title = %q, want %q", payload.Activity.Title, "Typo")
+	}
+}
+
+func TestActivities_RemoveMember_NotifiesAndStopsRelay(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	creator, creatorToken := newTestUser(t, store, nil, "creator", nowMs)
+	removed, removedToken := newTestUser(t, store, nil, "removed", nowMs)
+	staying, stayingToken := newTestUser(t, store, nil, "staying", nowMs)
+
+	tokenToUserID := map[string]string{creatorToken: creator.ID, removedToken: removed.ID, stayingToken: staying.ID}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	endAt := nowMs + 2*time.Hour.Milliseconds()
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, &endAt, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	for _, id := range []string{removed.ID, staying.ID} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, id, invite.Code, nil, nil, nowMs); err != nil {
+			t.Fatalf("ConsumeActivityInvite() error = %v", err)
+		}
+	}
+
+	dial := func(token string) *websocket.Conn {
+		wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + token
+		c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		return c
+	}
+	removedConn := dial(removedToken)
+	defer removedConn.Close()
+	stayingConn := dial(stayingToken)
+	defer stayingConn.Close()
+
+	res := postJSON(t, client, srv.URL+"/v1/activities/"+activity.ID+"/members/"+removed.ID+"/remove", map[string]any{}, creatorToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("remove member status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	for _, c := range []*websocket.Conn{removedConn, stayingConn} {
+		env := readWSEvent(t, c)
+		if env.Type != "activity.member.removed" || env.SessionID != activity.SessionID {
+			t.Fatalf("ws event = %s/%s, want activity.member.removed/%s", env.Type, env.SessionID, activity.SessionID)
+		}
+	}
+	if env := readWSEvent(t, stayingConn); env.Type != "message.created" {
+		t.Fatalf("ws event = %s, want system message.created", env.Type)
+	}
+
+	res = postJSON(t, client, srv.URL+"/v1/sessions/"+activity.SessionID+"/messages", map[string]any{
+		"type": "text",
+		"text": "still here?",
+	}, creatorToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("POST messages status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if env := readWSEvent(t, stayingConn); env.Type != "message.created" {
+		t.Fatalf("ws event = %s, want message.created", env.Type)
+	}
+
+	_ = removedConn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if _, msg, err := removedConn.ReadMessage(); err == nil {
+		t.Fatalf("removed member received %s, want no further session events", string(msg))
+	}
+}
+
+func TestActivities_Leave_PostsSystemMessageToRemainingMembers(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	creator, creatorToken := newTestUser(t, store, tokenToUserID, "creator", nowMs)
+	leaver, leaverToken := newTestUser(t, store, tokenToUserID, "leaver", nowMs)
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{}))
+	defer srv.Close()
+	client := srv.Client()
+
+	endAt := nowMs + 2*time.Hour.Milliseconds()
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, &endAt, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, leaver.ID, invite.Code, nil, nil, nowMs); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + creatorToken
+	creatorConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer creatorConn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res := postJSON(t, client, srv.URL+"/v1/activities/"+activity.ID+"/leave", map[string]any{}, leaverToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("leave status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	if env := readWSEvent(t, creatorConn); env.Type != "activity.member.left" {
+		t.Fatalf("ws event = %s, want activity.member.left", env.Type)
+	}
+	env := readWSEvent(t, creatorConn)
+	if env.Type != "message.created" || env.SessionID != activity.SessionID {
+		t.Fatalf("ws event = %s/%s, want message.created/%s", env.Type, env.SessionID, activity.SessionID)
+	}
+	var payload struct {
+		Message messageItem `json:"message"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode message payload: %v", err)
+	}
+	if got := payload.Message; got.Type != storage.MessageTypeSystem || got.SenderID != leaver.ID || got.Sender != "peer" || got.Text != "leaver 已退出活动" {
+		t.Fatalf("message = %+v, want a system notice from the leaver", got)
+	}
+
+	msgRes := get(t, client, srv.URL+"/v1/sessions/"+activity.SessionID+"/messages", creatorToken)
+	defer msgRes.Body.Close()
+	var list listMessagesResponse
+	if err := json.NewDecoder(msgRes.Body).Decode(&list); err != nil {
+		t.Fatalf("decode messages: %v", err)
+	}
+	found := false
+	for _, m := range list.Messages {
+		if m.ID == payload.Message.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("messages = %+v, want the leave notice stored", list.Messages)
+	}
+}
@@ -0,0 +1,87 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+type createGroupCallRequest struct {
+	MediaType string `json:"mediaType"` // voice|video
+}
+
+// handleCreateGroupCall opens a WeChat VoIP group room for an activity chat and invites the
+// other active members with call.group.invite. Members then fetch their own VoIP signature
+// from /v1/calls/{id}/voip.
+func (api *v1API) handleCreateGroupCall(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	var req createGroupCallRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.MediaType = strings.TrimSpace(req.MediaType)
+	if req.MediaType == "" {
+		req.MediaType = storage.CallMediaTypeVoice
+	}
+	if req.MediaType != storage.CallMediaTypeVoice && req.MediaType != storage.CallMediaTypeVideo {
+		writeAPIError(w, ErrCodeValidation, "invalid mediaType")
+		return
+	}
+
+	groupID, err := newNumericGroupID(18)
+	if err != nil {
+		api.log(r.Context()).Error("generate call groupId failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	call, err := api.store.CreateGroupCall(r.Context(), activityID, userID, req.MediaType, groupID, time.Now().UnixMilli())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+		case errors.Is(err, storage.ErrAccessDenied):
+			writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+		case errors.Is(err, storage.ErrSessionArchived):
+			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
+		case errors.Is(err, storage.ErrInvalidState):
+			writeAPIError(w, ErrCodeCallInvalidState, "a group call is already in progress")
+		default:
+			api.log(r.Context()).Error("create group call failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+		}
+		return
+	}
+
+	item := callItemFromRow(call)
+	writeJSON(w, http.StatusOK, createCallResponse{Call: item})
+
+	payload := map[string]any{
+		"call":       item,
+		"activityId": activityID,
+	}
+	if initiator, err := api.store.GetUserByID(r.Context(), userID); err == nil {
+		payload["initiator"] = map[string]any{
+			"id":          initiator.ID,
+			"displayName": initiator.DisplayName,
+			"avatarUrl":   initiator.AvatarURL,
+		}
+	}
+
+	members := api.activeParticipantIDs(r.Context(), call.SessionID)
+	invitees := make([]string, 0, len(members))
+	for _, id := range members {
+		if id != userID {
+			invitees = append(invitees, id)
+		}
+	}
+	api.sendToUsers(invitees, ws.Envelope{
+		Type:      "call.group.invite",
+		SessionID: call.SessionID,
+		Payload:   payload,
+	})
+}
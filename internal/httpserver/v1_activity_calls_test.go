@@ -0,0 +1,250 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestActivityGroupCall_InvitesMembersOnly(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	_, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	activity, invite, err := store.CreateActivity(ctx, alice.ID, "Picnic", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, bob.ID, invite.Code, nil, nil, nowMs); err != nil {
+		t.Fatalf("ConsumeActivityInvite(bob) error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	bobWS, _, err := websocket.DefaultDialer.Dial(wsURL+bobToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial bob error = %v", err)
+	}
+	defer bobWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	callURL := srv.URL + "/v1/activities/" + activity.ID + "/call"
+	res := postJSON(t, client, callURL, map[string]any{"mediaType": "video"}, carolToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider group call status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = postJSON(t, client, callURL, map[string]any{"mediaType": "video"}, aliceToken)
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("group call status = %d, body=%s", res.StatusCode, string(b))
+	}
+	_ = res.Body.Close()
+
+	env := readWSEvent(t, bobWS)
+	if env.Type != "call.group.invite" || env.SessionID != activity.SessionID {
+		t.Fatalf("bob ws event = %+v, want call.group.invite for the activity chat", env)
+	}
+	var payload struct {
+		Call callItem `json:"call"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode invite payload: %v", err)
+	}
+	callID := payload.Call.ID
+	if callID == "" || payload.Call.GroupID == "" || payload.Call.Status != storage.CallStatusAccepted {
+		t.Fatalf("invite call = %+v, want an accepted call with a groupId", payload.Call)
+	}
+
+	res = postJSON(t, client, callURL, map[string]any{}, bobToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("second group call status = %d, want %d", res.StatusCode, http.StatusConflict)
+	}
+
+	for token, want := range map[string]int{bobToken: http.StatusOK, carolToken: http.StatusForbidden} {
+		res := get(t, client, srv.URL+"/v1/calls/"+callID, token)
+		_ = res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("GET call status = %d, want %d", res.StatusCode, want)
+		}
+	}
+}
+
+func TestActivityGroupCall_SummaryPostedAfterInitiatorLeft(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+
+	activity, invite, err := store.CreateActivity(ctx, alice.ID, "Picnic", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, bob.ID, invite.Code, nil, nil, nowMs); err != nil {
+		t.Fatalf("ConsumeActivityInvite(bob) error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{}))
+	defer srv.Close()
+	client := srv.Client()
+
+	call, err := store.CreateGroupCall(ctx, activity.ID, bob.ID, storage.CallMediaTypeVoice, "g1", nowMs)
+	if err != nil {
+		t.Fatalf("CreateGroupCall() error = %v", err)
+	}
+	res := postJSON(t, client, srv.URL+"/v1/activities/"+activity.ID+"/leave", map[string]any{}, bobToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("leave status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + aliceToken
+	aliceWS, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("ws dial alice error = %v", err)
+	}
+	defer aliceWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res = postJSON(t, client, srv.URL+"/v1/calls/"+call.ID+"/end", map[string]any{}, aliceToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("end call status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	if env := readWSEvent(t, aliceWS); env.Type != "call.ended" {
+		t.Fatalf("first ws event type = %q, want call.ended", env.Type)
+	}
+	env := readWSEvent(t, aliceWS)
+	if env.Type != "message.created" || env.SessionID != activity.SessionID {
+		t.Fatalf("second ws event = %+v, want message.created for the activity chat", env)
+	}
+	var payload struct {
+		Message messageItem `json:"message"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode message payload: %v", err)
+	}
+	if got := payload.Message; got.Type != storage.MessageTypeSystem || got.SenderID != bob.ID || got.Sender != "peer" || !strings.HasPrefix(got.Text, "语音通话") {
+		t.Fatalf("message = %+v, want the call summary attributed to the initiator", got)
+	}
+}
+
+func TestActivityGroupCall_MediaSwitchByMemberReachesEveryone(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+	_, daveToken := newTestUser(t, store, tokenToUserID, "dave", nowMs)
+
+	activity, invite, err := store.CreateActivity(ctx, alice.ID, "Picnic", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	for _, u := range []storage.UserRow{bob, carol} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, nowMs); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s) error = %v", u.Username, err)
+		}
+	}
+	call, err := store.CreateGroupCall(ctx, activity.ID, alice.ID, storage.CallMediaTypeVoice, "g1", nowMs)
+	if err != nil {
+		t.Fatalf("CreateGroupCall() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{}))
+	defer srv.Close()
+	client := srv.Client()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	aliceWS, _, err := websocket.DefaultDialer.Dial(wsURL+aliceToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial alice error = %v", err)
+	}
+	defer aliceWS.Close()
+	carolWS, _, err := websocket.DefaultDialer.Dial(wsURL+carolToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial carol error = %v", err)
+	}
+	defer carolWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	mediaURL := srv.URL + "/v1/calls/" + call.ID + "/media"
+	res := postJSON(t, client, mediaURL, map[string]any{"mediaType": "video"}, daveToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider media switch status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = postJSON(t, client, mediaURL, map[string]any{"mediaType": "video"}, bobToken)
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("member media switch status = %d, body=%s", res.StatusCode, string(b))
+	}
+	_ = res.Body.Close()
+
+	for name, conn := range map[string]*websocket.Conn{"alice": aliceWS, "carol": carolWS} {
+		env := readWSEvent(t, conn)
+		if env.Type != "call.media.changed" {
+			t.Fatalf("%s ws event type = %q, want call.media.changed", name, env.Type)
+		}
+		var payload struct {
+			Call      callItem `json:"call"`
+			ChangedBy string   `json:"changedBy"`
+		}
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			t.Fatalf("decode media payload: %v", err)
+		}
+		if payload.Call.MediaType != storage.CallMediaTypeVideo || payload.ChangedBy != bob.ID {
+			t.Fatalf("%s media payload = %+v, want video changed by bob", name, payload)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+type announcementItem struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	StartsAtMs  int64  `json:"startsAtMs"`
+	EndsAtMs    *int64 `json:"endsAtMs,omitempty"`
+	Dismissible bool   `json:"dismissible"`
+	CreatedAtMs int64  `json:"createdAtMs"`
+}
+
+type listAnnouncementsResponse struct {
+	Announcements []announcementItem `json:"announcements"`
+}
+
+type createAnnouncementRequest struct {
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	StartsAtMs  *int64 `json:"startsAtMs,omitempty"`
+	EndsAtMs    *int64 `json:"endsAtMs,omitempty"`
+	Dismissible *bool  `json:"dismissible,omitempty"`
+}
+
+type createAnnouncementResponse struct {
+	Announcement announcementItem `json:"announcement"`
+}
+
+func toAnnouncementItem(a storage.AnnouncementRow) announcementItem {
+	return announcementItem{
+		ID:          a.ID,
+		Title:       a.Title,
+		Body:        a.Body,
+		StartsAtMs:  a.StartsAtMs,
+		EndsAtMs:    a.EndsAtMs,
+		Dismissible: a.Dismissible,
+		CreatedAtMs: a.CreatedAtMs,
+	}
+}
+
+func (api *v1API) isAdmin(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	_, ok := api.adminUserIDs[userID]
+	return ok
+}
+
+func (api *v1API) handleAnnouncements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	rows, err := api.store.ListActiveAnnouncements(r.Context(), time.Now().UnixMilli(), 20)
+	if err != nil {
+		api.log(r.Context()).Error("list announcements failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]announcementItem, 0, len(rows))
+	for _, a := range rows {
+		items = append(items, toAnnouncementItem(a))
+	}
+	writeJSON(w, http.StatusOK, listAnnouncementsResponse{Announcements: items})
+}
+
+func (api *v1API) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	if !api.isAdmin(userID) {
+		writeAPIError(w, ErrCodeAdminRequired, "admin required")
+		return
+	}
+
+	parts := splitPath(strings.TrimPrefix(r.URL.Path, "/v1/admin/"))
+	switch {
+	case len(parts) == 1 && parts[0] == "announcements":
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleCreateAnnouncement(w, r, userID)
+	case len(parts) == 3 && parts[0] == "users" && parts[2] == "upload-quota":
+		if r.Method != http.MethodPut {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleSetUploadQuota(w, r, parts[1])
+	default:
+		writeAPIError(w, ErrCodeNotFound, "not found")
+	}
+}
+
+func (api *v1API) handleCreateAnnouncement(w http.ResponseWriter, r *http.Request, userID string) {
+	var req createAnnouncementRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	body := strings.TrimSpace(req.Body)
+	if title == "" && body == "" {
+		writeAPIError(w, ErrCodeValidation, "title or body is required")
+		return
+	}
+	if len([]rune(title)) > 100 {
+		writeAPIError(w, ErrCodeValidation, "title too long")
+		return
+	}
+	if len([]rune(body)) > 2000 {
+		writeAPIError(w, ErrCodeValidation, "body too long")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	startsAtMs := nowMs
+	if req.StartsAtMs != nil && *req.StartsAtMs > nowMs {
+		startsAtMs = *req.StartsAtMs
+	}
+	if req.EndsAtMs != nil && *req.EndsAtMs <= startsAtMs {
+		writeAPIError(w, ErrCodeValidation, "endsAtMs must be after startsAtMs")
+		return
+	}
+	dismissible := true
+	if req.Dismissible != nil {
+		dismissible = *req.Dismissible
+	}
+
+	row, err := api.store.CreateAnnouncement(r.Context(), userID, title, body, startsAtMs, req.EndsAtMs, dismissible, nowMs)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeValidation, "invalid announcement window")
+			return
+		}
+		api.log(r.Context()).Error("create announcement failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	item := toAnnouncementItem(row)
+	writeJSON(w, http.StatusOK, createAnnouncementResponse{Announcement: item})
+
+	// Scheduled announcements are picked up by clients via GET /v1/announcements once they start.
+	if row.StartsAtMs <= nowMs {
+		api.broadcast(ws.Envelope{
+			Type: "announcement",
+			Payload: map[string]any{
+				"announcement": item,
+			},
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestAnnouncements_AdminBroadcastAndList(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	admin, adminToken := newTestUser(t, store, nil, "admin", nowMs)
+	user, userToken := newTestUser(t, store, nil, "alice", nowMs)
+
+	tokenToUserID := map[string]string{adminToken: admin.ID, userToken: user.ID}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{AdminUserIDs: []string{admin.ID}})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + userToken
+	c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	// Non-admins are rejected.
+	res := postJSON(t, client, srv.URL+"/v1/admin/announcements", map[string]any{"title": "hi"}, userToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("POST /v1/admin/announcements (non-admin) status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = postJSON(t, client, srv.URL+"/v1/admin/announcements", map[string]any{
+		"title":       "维护通知",
+		"body":        "今晚 23:00 停机维护",
+		"dismissible": false,
+	}, adminToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/admin/announcements status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+
+	env := readWSEvent(t, c)
+	if env.Type != "announcement" {
+		t.Fatalf("ws event type = %q, want %q", env.Type, "announcement")
+	}
+
+	listRes := get(t, client, srv.URL+"/v1/announcements", userToken)
+	defer listRes.Body.Close()
+	if listRes.StatusCode != http.StatusOK {
+		t.Fatalf("GET /v1/announcements status = %d, want %d", listRes.StatusCode, http.StatusOK)
+	}
+	var listBody listAnnouncementsResponse
+	if err := json.NewDecoder(listRes.Body).Decode(&listBody); err != nil {
+		t.Fatalf("decode list announcements response error = %v", err)
+	}
+	if len(listBody.Announcements) != 1 {
+		t.Fatalf("len(announcements) = %d, want 1", len(listBody.Announcements))
+	}
+	if got := listBody.Announcements[0]; got.Title != "维护通知" || got.Dismissible {
+		t.Fatalf("announcement = %+v, want title 维护通知 and dismissible=false", got)
+	}
+}
@@ -1,16 +1,21 @@
 package httpserver
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"log/slog"
 
@@ -25,19 +30,64 @@ type v1API struct {
 	wsManager *ws.Manager
 	uploadDir string
 
-	wechatClient                      *wechat.Client
+	wechatClient                      WeChatClient
 	wechatAppID                       string
 	wechatCallSubscribeTemplateID     string
 	wechatCallSubscribePage           string
 	wechatActivitySubscribeTemplateID string
 	wechatActivitySubscribePage       string
+
+	adminUserIDs map[string]struct{}
+
+	uploadAllowedExts map[string]struct{}
+
+	minClientVersion string
+
+	bcryptCost int
+
+	uploadQuotaBytes        int64
+	uploadsRequireAuth      bool
+	uploadEXIFStripDisabled bool
+
+	cardViewTrackingDisabled bool
+
+	mediaAllowedHosts map[string]struct{}
+
+	turnSharedSecret  string
+	turnURIs          []string
+	stunURIs          []string
+	turnCredentialTTL time.Duration
+
+	sessionRequestLimits storage.SessionRequestLimits
+
+	readReceipts *readReceiptDebouncer
+
+	loginThrottle *loginThrottle
+
+	exportConfirmations *exportConfirmations
+
+	qrCodes *qrCodeCache
 }
 
 func newV1API(logger *slog.Logger, store Store, wsManager *ws.Manager, uploadDir string, opts HandlerOptions) *v1API {
-	var wc *wechat.Client
-	if strings.TrimSpace(opts.WeChatAppID) != "" && strings.TrimSpace(opts.WeChatAppSecret) != "" {
+	var wc WeChatClient
+	if opts.WeChatClient != nil {
+		wc = opts.WeChatClient
+	} else if strings.TrimSpace(opts.WeChatAppID) != "" && strings.TrimSpace(opts.WeChatAppSecret) != "" {
 		wc = wechat.NewClient(logger, opts.WeChatAppID, opts.WeChatAppSecret)
 	}
+	adminUserIDs := make(map[string]struct{}, len(opts.AdminUserIDs))
+	for _, id := range opts.AdminUserIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			adminUserIDs[id] = struct{}{}
+		}
+	}
+	mediaAllowedHosts := make(map[string]struct{}, len(opts.MediaAllowedHosts))
+	for _, h := range opts.MediaAllowedHosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			mediaAllowedHosts[h] = struct{}{}
+		}
+	}
 	return &v1API{
 		logger:                            logger.With("component", "v1"),
 		store:                             store,
@@ -49,7 +99,39 @@ func newV1API(logger *slog.Logger, store Store, wsManager *ws.Manager, uploadDir
 		wechatCallSubscribePage:           strings.TrimSpace(opts.WeChatCallSubscribePage),
 		wechatActivitySubscribeTemplateID: strings.TrimSpace(opts.WeChatActivitySubscribeTemplateID),
 		wechatActivitySubscribePage:       strings.TrimSpace(opts.WeChatActivitySubscribePage),
+		adminUserIDs:                      adminUserIDs,
+		uploadAllowedExts:                 newUploadExtensionSet(opts.UploadAllowedExtensions),
+		minClientVersion:                  strings.TrimSpace(opts.MinClientVersion),
+		bcryptCost:                        bcryptCostOrDefault(opts.BcryptCost),
+		uploadQuotaBytes:                  opts.UploadQuotaBytes,
+		uploadsRequireAuth:                opts.UploadsRequireAuth,
+		uploadEXIFStripDisabled:           opts.DisableUploadEXIFStrip,
+		cardViewTrackingDisabled:          opts.DisableCardViewTracking,
+		mediaAllowedHosts:                 mediaAllowedHosts,
+		turnSharedSecret:                  strings.TrimSpace(opts.TURNSharedSecret),
+		turnURIs:                          opts.TURNURIs,
+		stunURIs:                          opts.STUNURIs,
+		turnCredentialTTL:                 opts.TURNCredentialTTL,
+		sessionRequestLimits:              sessionRequestLimitsOrDefault(opts.SessionRequestLimits),
+		readReceipts:                      newReadReceiptDebouncer(readReceiptDebounce),
+		loginThrottle:                     newLoginThrottle(),
+		exportConfirmations:               newExportConfirmations(),
+		qrCodes:                           newQRCodeCache(),
+	}
+}
+
+func sessionRequestLimitsOrDefault(l storage.SessionRequestLimits) storage.SessionRequestLimits {
+	def := storage.DefaultSessionRequestLimits
+	if l.WindowMs <= 0 {
+		l.WindowMs = def.WindowMs
+	}
+	if l.MaxPerWindow <= 0 {
+		l.MaxPerWindow = def.MaxPerWindow
 	}
+	if l.RejectCooldownMs <= 0 {
+		l.RejectCooldownMs = def.RejectCooldownMs
+	}
+	return l
 }
 
 type apiErrorEnvelope struct {
@@ -59,6 +141,14 @@ type apiErrorEnvelope struct {
 type apiError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+	// RequestID repeats the X-Request-ID response header so clients can quote it in reports.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// log returns the request-scoped logger (tagged with the request id) for ctx.
+func (api *v1API) log(ctx context.Context) *slog.Logger {
+	return loggerFromContext(ctx, api.logger)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -69,11 +159,63 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = enc.Encode(v)
 }
 
+// writeJSONConditional writes v as a 200 with a weak ETag derived from the encoded body, or
+// an empty 304 when the request's If-None-Match already names that ETag. It suits small
+// responses clients poll; the body is always built, only the transfer is saved.
+func writeJSONConditional(w http.ResponseWriter, r *http.Request, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// etagMatches applies If-None-Match's weak comparison: W/ prefixes are ignored and "*"
+// matches any current representation.
+func etagMatches(ifNoneMatch, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
 func writeAPIError(w http.ResponseWriter, code ErrorCode, message string) {
 	writeJSON(w, httpStatusForCode(code), apiErrorEnvelope{
 		Error: apiError{
-			Code:    string(code),
-			Message: message,
+			Code:      string(code),
+			Message:   message,
+			RequestID: w.Header().Get(requestIDHeader),
+		},
+	})
+}
+
+// writeAPIErrorDetails is writeAPIError plus machine-readable context the client can show,
+// such as the usage and limit behind a quota error.
+func writeAPIErrorDetails(w http.ResponseWriter, code ErrorCode, message string, details any) {
+	writeJSON(w, httpStatusForCode(code), apiErrorEnvelope{
+		Error: apiError{
+			Code:      string(code),
+			Message:   message,
+			Details:   details,
+			RequestID: w.Header().Get(requestIDHeader),
 		},
 	})
 }
@@ -112,6 +254,33 @@ func (api *v1API) handleSessions(w http.ResponseWriter, r *http.Request) {
 func (api *v1API) handleSessionSubroutes(w http.ResponseWriter, r *http.Request) {
 	rest := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
 	parts := splitPath(rest)
+	if len(parts) == 4 && parts[1] == "messages" && parts[3] == "reactions" {
+		api.handleMessageReactions(w, r, parts[0], parts[2])
+		return
+	}
+	if len(parts) == 4 && parts[1] == "messages" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		switch parts[3] {
+		case "edit":
+			api.handleEditMessage(w, r, parts[0], parts[2])
+		case "delete":
+			api.handleDeleteMessage(w, r, parts[0], parts[2])
+		default:
+			writeAPIError(w, ErrCodeNotFound, "not found")
+		}
+		return
+	}
+	if len(parts) == 3 && parts[1] == "relationship" && parts[2] == "ungroup" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleUngroupSessionRelationship(w, r, parts[0])
+		return
+	}
 	if len(parts) != 2 {
 		writeAPIError(w, ErrCodeNotFound, "not found")
 		return
@@ -137,6 +306,12 @@ func (api *v1API) handleSessionSubroutes(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		api.handleHideSession(w, r, sessionID)
+	case "read":
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleMarkSessionRead(w, r, sessionID)
 	case "relationship":
 		switch r.Method {
 		case http.MethodGet:
@@ -151,7 +326,9 @@ func (api *v1API) handleSessionSubroutes(w http.ResponseWriter, r *http.Request)
 		case http.MethodGet:
 			api.handleListMessages(w, r, sessionID)
 		case http.MethodPost:
-			api.handleCreateMessage(w, r, sessionID)
+			api.withIdempotency(w, r, func(w http.ResponseWriter, r *http.Request) {
+				api.handleCreateMessage(w, r, sessionID)
+			})
 		default:
 			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
 		}
@@ -167,13 +344,19 @@ type peerItem struct {
 	AvatarURL   *string `json:"avatarUrl,omitempty"`
 }
 
+// sessionListItem describes a session in lists. Direct sessions carry Peer; ad-hoc group
+// chats carry Group instead.
 type sessionListItem struct {
 	ID              string                   `json:"id"`
-	Peer            peerItem                 `json:"peer"`
+	Kind            string                   `json:"kind"`
+	Peer            *peerItem                `json:"peer,omitempty"`
+	Group           *groupSessionItem        `json:"group,omitempty"`
+	PeerOnline      bool                     `json:"peerOnline"`
 	Status          string                   `json:"status"`
 	Source          string                   `json:"source"`
 	LastMessageText *string                  `json:"lastMessageText,omitempty"`
 	LastMessageAtMs *int64                   `json:"lastMessageAtMs,omitempty"`
+	UnreadCount     int                      `json:"unreadCount"`
 	UpdatedAtMs     int64                    `json:"updatedAtMs"`
 	Relationship    *relationshipSummaryItem `json:"relationship,omitempty"`
 }
@@ -187,7 +370,9 @@ type relationshipSummaryItem struct {
 }
 
 type listSessionsResponse struct {
-	Sessions []sessionListItem `json:"sessions"`
+	Sessions     []sessionListItem `json:"sessions"`
+	NextCursor   string            `json:"nextCursor,omitempty"`
+	ServerTimeMs int64             `json:"serverTimeMs"`
 }
 
 func (api *v1API) handleListSessions(w http.ResponseWriter, r *http.Request) {
@@ -203,38 +388,113 @@ func (api *v1API) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessions, err := api.store.ListSessionsForUser(r.Context(), userID, status)
+	opts := storage.SessionListOptions{Cursor: strings.TrimSpace(r.URL.Query().Get("cursor"))}
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 100 {
+			writeAPIError(w, ErrCodeValidation, "limit must be between 1 and 100")
+			return
+		}
+		opts.Limit = limit
+	}
+	if groupID := strings.TrimSpace(r.URL.Query().Get("groupId")); groupID != "" {
+		if _, err := api.store.GetRelationshipGroupByID(r.Context(), userID, groupID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeAPIError(w, ErrCodeValidation, "group not found")
+				return
+			}
+			api.log(r.Context()).Error("get relationship group failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+			return
+		}
+		opts.GroupID = groupID
+	}
+	opts.Tag = storage.NormalizeTag(r.URL.Query().Get("tag"))
+
+	sessions, nextCursor, err := api.store.ListSessionsForUserPage(r.Context(), userID, status, opts)
 	if err != nil {
-		api.logger.Error("list sessions failed", "error", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			writeAPIError(w, ErrCodeValidation, "invalid cursor")
+			return
+		}
+		api.log(r.Context()).Error("list sessions failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
-	items := make([]sessionListItem, 0, len(sessions))
+	sessionIDs := make([]string, 0, len(sessions))
+	peerIDs := make([]string, 0, len(sessions))
+	groupIDs := make([]string, 0)
 	for _, s := range sessions {
-		peerUserID := api.store.GetPeerUserID(s, userID)
-		peerUser, err := api.store.GetUserByID(r.Context(), peerUserID)
-		if err != nil {
-			api.logger.Warn("get peer user failed", "error", err, "peerUserID", peerUserID)
+		sessionIDs = append(sessionIDs, s.ID)
+		if s.Kind == storage.SessionKindGroup {
+			groupIDs = append(groupIDs, s.ID)
 			continue
 		}
+		peerIDs = append(peerIDs, api.store.GetPeerUserID(s, userID))
+	}
+	online, err := api.onlineStatus(r.Context(), userID, peerIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get peer presence failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	peers, err := api.store.GetUsersByIDs(r.Context(), peerIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get session peers failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	groups, err := api.store.GetGroupSessions(r.Context(), groupIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get group sessions failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	relationships, err := api.store.ListSessionRelationshipsForUser(r.Context(), userID, sessionIDs)
+	if err != nil {
+		api.log(r.Context()).Error("list session relationships failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
 
+	items := make([]sessionListItem, 0, len(sessions))
+	for _, s := range sessions {
 		item := sessionListItem{
-			ID: s.ID,
-			Peer: peerItem{
-				ID:          peerUser.ID,
-				Username:    peerUser.Username,
-				DisplayName: peerUser.DisplayName,
-				AvatarURL:   peerUser.AvatarURL,
-			},
+			ID:              s.ID,
+			Kind:            s.Kind,
 			Status:          s.Status,
 			Source:          s.Source,
 			LastMessageText: s.LastMessageText,
 			LastMessageAtMs: s.LastMessageAtMs,
+			UnreadCount:     s.UnreadCount,
 			UpdatedAtMs:     s.UpdatedAtMs,
 		}
 
-		if meta, err := api.store.GetSessionUserMeta(r.Context(), s.ID, userID); err == nil {
+		if s.Kind == storage.SessionKindGroup {
+			group, ok := groups[s.ID]
+			if !ok {
+				api.log(r.Context()).Warn("group session details missing", "sessionID", s.ID)
+				continue
+			}
+			item.Group = groupSessionItemFromRow(group)
+		} else {
+			peerUserID := api.store.GetPeerUserID(s, userID)
+			peerUser, ok := peers[peerUserID]
+			if !ok {
+				api.log(r.Context()).Warn("peer user missing", "peerUserID", peerUserID)
+				continue
+			}
+			item.Peer = &peerItem{
+				ID:          peerUser.ID,
+				Username:    peerUser.Username,
+				DisplayName: peerUser.DisplayName,
+				AvatarURL:   peerUser.AvatarURL,
+			}
+			item.PeerOnline = online[peerUser.ID]
+		}
+
+		if meta, ok := relationships[s.ID]; ok {
 			item.Relationship = &relationshipSummaryItem{
 				Note:        meta.Note,
 				GroupID:     meta.GroupID,
@@ -247,7 +507,7 @@ func (api *v1API) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		items = append(items, item)
 	}
 
-	writeJSON(w, http.StatusOK, listSessionsResponse{Sessions: items})
+	writeJSON(w, http.StatusOK, listSessionsResponse{Sessions: items, NextCursor: nextCursor, ServerTimeMs: time.Now().UnixMilli()})
 }
 
 type createSessionRequest struct {
@@ -285,7 +545,7 @@ func (api *v1API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeUserNotFound, "peer user not found")
 			return
 		}
-		api.logger.Error("get peer user failed", "error", err)
+		api.log(r.Context()).Error("get peer user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -297,7 +557,7 @@ func (api *v1API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeCannotChatSelf, "cannot create session with yourself")
 			return
 		}
-		api.logger.Error("create session failed", "error", err)
+		api.log(r.Context()).Error("create session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -306,7 +566,7 @@ func (api *v1API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	if !created && session.Status == storage.SessionStatusArchived {
 		session, err = api.store.ReactivateSession(r.Context(), session.ID, userID, nowMs)
 		if err != nil {
-			api.logger.Error("reactivate session failed", "error", err)
+			api.log(r.Context()).Error("reactivate session failed", "error", err)
 			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
@@ -314,8 +574,9 @@ func (api *v1API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 
 	resp := createSessionResponse{
 		Session: sessionListItem{
-			ID: session.ID,
-			Peer: peerItem{
+			ID:   session.ID,
+			Kind: session.Kind,
+			Peer: &peerItem{
 				ID:          peerUser.ID,
 				Username:    peerUser.Username,
 				DisplayName: peerUser.DisplayName,
@@ -331,12 +592,17 @@ func (api *v1API) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	}
 	if !created {
 		resp.Hint = "会话已存在"
+		if n, err := api.store.CountUnreadMessages(r.Context(), session.ID, userID); err == nil {
+			resp.Session.UnreadCount = n
+		} else {
+			api.log(r.Context()).Warn("count unread messages failed", "error", err, "sessionID", session.ID)
+		}
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 
 	if created {
-		api.broadcast(ws.Envelope{
+		api.relayMessageEvent(r.Context(), ws.Envelope{
 			Type:      "session.created",
 			SessionID: session.ID,
 			Payload: map[string]any{
@@ -380,7 +646,7 @@ func (api *v1API) handleArchiveSession(w http.ResponseWriter, r *http.Request, s
 			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
 			return
 		}
-		api.logger.Error("archive session failed", "error", err)
+		api.log(r.Context()).Error("archive session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -393,7 +659,7 @@ func (api *v1API) handleArchiveSession(w http.ResponseWriter, r *http.Request, s
 		},
 	})
 
-	api.broadcast(ws.Envelope{
+	api.relayMessageEvent(r.Context(), ws.Envelope{
 		Type:      "session.archived",
 		SessionID: session.ID,
 		Payload: map[string]any{
@@ -434,7 +700,7 @@ func (api *v1API) handleReactivateSession(w http.ResponseWriter, r *http.Request
 			writeAPIError(w, ErrCodeValidation, "session is not archived")
 			return
 		}
-		api.logger.Error("reactivate session failed", "error", err)
+		api.log(r.Context()).Error("reactivate session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -448,7 +714,7 @@ func (api *v1API) handleReactivateSession(w http.ResponseWriter, r *http.Request
 		},
 	})
 
-	api.broadcast(ws.Envelope{
+	api.relayMessageEvent(r.Context(), ws.Envelope{
 		Type:      "session.reactivated",
 		SessionID: session.ID,
 		Payload: map[string]any{
@@ -485,7 +751,7 @@ func (api *v1API) handleHideSession(w http.ResponseWriter, r *http.Request, sess
 			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
 			return
 		}
-		api.logger.Error("hide session failed", "error", err)
+		api.log(r.Context()).Error("hide session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -495,22 +761,120 @@ func (api *v1API) handleHideSession(w http.ResponseWriter, r *http.Request, sess
 	})
 }
 
+func (api *v1API) handleMarkSessionRead(w http.ResponseWriter, r *http.Request, sessionID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		writeAPIError(w, ErrCodeValidation, "invalid sessionId")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	advanced, err := api.store.MarkSessionRead(r.Context(), sessionID, userID, nowMs)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeSessionNotFound, "session not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+			return
+		}
+		api.log(r.Context()).Error("mark session read failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success": true,
+	})
+
+	if advanced {
+		api.queueReadReceipt(r.Context(), sessionID, userID, nowMs)
+	}
+}
+
 type listMessagesResponse struct {
 	Messages []messageItem `json:"messages"`
 	HasMore  bool          `json:"hasMore"`
 }
 
 type messageItem struct {
-	ID          string               `json:"id"`
-	SessionID   string               `json:"sessionId"`
-	Sender      string               `json:"sender"`
-	SenderID    string               `json:"senderId"`
-	Type        string               `json:"type"`
-	Text        string               `json:"text,omitempty"`
-	Meta        *storage.MessageMeta `json:"meta,omitempty"`
-	MetaJSON    json.RawMessage      `json:"metaJson,omitempty"`
-	Burn        *burnStateItem       `json:"burn,omitempty"`
-	CreatedAtMs int64                `json:"createdAtMs"`
+	ID                string               `json:"id"`
+	SessionID         string               `json:"sessionId"`
+	Sender            string               `json:"sender"`
+	SenderID          string               `json:"senderId"`
+	SenderDisplayName string               `json:"senderDisplayName,omitempty"`
+	SenderAvatarURL   *string              `json:"senderAvatarUrl,omitempty"`
+	Type              string               `json:"type"`
+	Text              string               `json:"text,omitempty"`
+	Meta              *storage.MessageMeta `json:"meta,omitempty"`
+	MetaJSON          json.RawMessage      `json:"metaJson,omitempty"`
+	Burn              *burnStateItem       `json:"burn,omitempty"`
+	CreatedAtMs       int64                `json:"createdAtMs"`
+	EditedAtMs        *int64               `json:"editedAtMs,omitempty"`
+	DeletedAtMs       *int64               `json:"deletedAtMs,omitempty"`
+	Reactions         map[string]int       `json:"reactions,omitempty"`
+	MyReactions       []string             `json:"myReactions,omitempty"`
+
+	ReplyToMessageID *string           `json:"replyToMessageId,omitempty"`
+	ReplyTo          *replyPreviewItem `json:"replyTo,omitempty"`
+}
+
+// attachSenders fills the sender display name and avatar of items with one user lookup.
+// Group chats need them because "me"/"peer" does not identify the sender.
+func (api *v1API) attachSenders(ctx context.Context, items []messageItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	senderIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		senderIDs = append(senderIDs, item.SenderID)
+	}
+	users, err := api.store.GetUsersByIDs(ctx, senderIDs)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if u, ok := users[items[i].SenderID]; ok {
+			items[i].SenderDisplayName = u.DisplayName
+			items[i].SenderAvatarURL = u.AvatarURL
+		}
+	}
+	return nil
+}
+
+// replyPreviewTextLen caps the quoted text carried inside a reply, in runes.
+const replyPreviewTextLen = 100
+
+type replyPreviewItem struct {
+	ID       string `json:"id"`
+	SenderID string `json:"senderId"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+}
+
+func replyPreviewFromRow(p *storage.MessageReplyPreview) *replyPreviewItem {
+	if p == nil {
+		return nil
+	}
+	item := &replyPreviewItem{ID: p.ID, SenderID: p.SenderID, Type: p.Type}
+	if p.Text != nil {
+		item.Text = truncateRunes(*p.Text, replyPreviewTextLen)
+	}
+	return item
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
 }
 
 func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, sessionID string) {
@@ -527,10 +891,19 @@ func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, ses
 	}
 
 	beforeID := r.URL.Query().Get("before")
+	afterID := strings.TrimSpace(r.URL.Query().Get("after"))
+	if beforeID != "" && afterID != "" {
+		writeAPIError(w, ErrCodeValidation, "before and after cannot be combined")
+		return
+	}
 	limit := 50
 
-	messages, hasMore, err := api.store.ListMessages(r.Context(), sessionID, userID, limit, beforeID)
+	messages, hasMore, err := api.store.ListMessages(r.Context(), sessionID, userID, limit, beforeID, afterID)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			writeAPIError(w, ErrCodeValidation, "invalid after cursor")
+			return
+		}
 		if errors.Is(err, storage.ErrNotFound) {
 			writeAPIError(w, ErrCodeSessionNotFound, "session not found")
 			return
@@ -539,13 +912,27 @@ func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, ses
 			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
 			return
 		}
-		api.logger.Error("list messages failed", "error", err)
+		api.log(r.Context()).Error("list messages failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
+	items, err := api.messageItemsFromRows(r.Context(), messages, userID)
+	if err != nil {
+		api.log(r.Context()).Error("get burn messages failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listMessagesResponse{Messages: items, HasMore: hasMore})
+}
+
+// messageItemsFromRows renders stored messages for userID. Burn messages sent before the
+// current token was issued are hidden, and burn state, reactions and sender profiles are
+// attached to the rest.
+func (api *v1API) messageItemsFromRows(ctx context.Context, messages []storage.MessageRow, userID string) ([]messageItem, error) {
 	var burnMinCreatedAtMs int64
-	if tokenRow, ok := getAuthTokenFromContext(r.Context()); ok {
+	if tokenRow, ok := getAuthTokenFromContext(ctx); ok {
 		burnMinCreatedAtMs = tokenRow.CreatedAtMs
 	}
 
@@ -565,16 +952,27 @@ func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, ses
 	}
 	burnByID := map[string]storage.BurnMessageRow{}
 	if len(burnIDs) > 0 {
-		burnByID, err = api.store.GetBurnMessages(r.Context(), burnIDs)
+		var err error
+		burnByID, err = api.store.GetBurnMessages(ctx, burnIDs)
 		if err != nil {
-			api.logger.Error("get burn messages failed", "error", err)
-			writeAPIError(w, ErrCodeInternal, "internal error")
-			return
+			return nil, err
 		}
 	}
 
+	nowMs := time.Now().UnixMilli()
 	items := make([]messageItem, 0, len(filtered))
 	for _, m := range filtered {
+		var burnState *burnStateItem
+		if m.Type == storage.MessageTypeBurn {
+			if burn, ok := burnByID[m.ID]; ok {
+				state, visible := burnStateForViewer(burn, userID, nowMs)
+				if !visible {
+					continue
+				}
+				burnState = &state
+			}
+		}
+
 		sender := "peer"
 		if m.SenderID == userID {
 			sender = "me"
@@ -587,6 +985,11 @@ func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, ses
 			SenderID:    m.SenderID,
 			Type:        m.Type,
 			CreatedAtMs: m.CreatedAtMs,
+			EditedAtMs:  m.EditedAtMs,
+			DeletedAtMs: m.DeletedAtMs,
+
+			ReplyToMessageID: m.ReplyToMessageID,
+			ReplyTo:          replyPreviewFromRow(m.ReplyTo),
 		}
 		if m.Text != nil {
 			item.Text = *m.Text
@@ -597,19 +1000,17 @@ func (api *v1API) handleListMessages(w http.ResponseWriter, r *http.Request, ses
 		if meta := parseMeta(m.MetaJSON); meta != nil {
 			item.Meta = meta
 		}
-		if m.Type == storage.MessageTypeBurn {
-			if burn, ok := burnByID[m.ID]; ok {
-				item.Burn = &burnStateItem{
-					BurnAfterMs: burn.BurnAfterMs,
-					OpenedAtMs:  burn.OpenedAtMs,
-					BurnAtMs:    burn.BurnAtMs,
-				}
-			}
-		}
+		item.Burn = burnState
 		items = append(items, item)
 	}
 
-	writeJSON(w, http.StatusOK, listMessagesResponse{Messages: items, HasMore: hasMore})
+	if err := api.attachReactions(ctx, items, userID); err != nil {
+		return nil, err
+	}
+	if err := api.attachSenders(ctx, items); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 type createMessageRequest struct {
@@ -618,6 +1019,8 @@ type createMessageRequest struct {
 	Meta        *storage.MessageMeta `json:"meta,omitempty"`
 	MetaJSON    json.RawMessage      `json:"metaJson,omitempty"`
 	BurnAfterMs *int64               `json:"burnAfterMs,omitempty"`
+
+	ReplyToMessageID string `json:"replyToMessageId,omitempty"`
 }
 
 type createMessageResponse struct {
@@ -645,8 +1048,9 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 
 	req.Type = strings.TrimSpace(req.Type)
 	switch req.Type {
-	case storage.MessageTypeText, storage.MessageTypeImage, storage.MessageTypeFile, storage.MessageTypeSystem, storage.MessageTypeBurn:
+	case storage.MessageTypeText, storage.MessageTypeImage, storage.MessageTypeFile, storage.MessageTypeBurn:
 	default:
+		// System notices are server-posted only.
 		writeAPIError(w, ErrCodeValidation, "invalid message type")
 		return
 	}
@@ -658,8 +1062,17 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 			writeAPIError(w, ErrCodeValidation, "text is required for type text")
 			return
 		}
+		if utf8.RuneCountInString(req.Text) > storage.MaxMessageTextLen {
+			writeAPIError(w, ErrCodeValidation, fmt.Sprintf("text must be at most %d characters", storage.MaxMessageTextLen))
+			return
+		}
 		text = &req.Text
 	}
+	req.ReplyToMessageID = strings.TrimSpace(req.ReplyToMessageID)
+	if req.ReplyToMessageID != "" && req.Type == storage.MessageTypeBurn {
+		writeAPIError(w, ErrCodeValidation, "burn messages cannot reply to other messages")
+		return
+	}
 
 	nowMs := time.Now().UnixMilli()
 	var (
@@ -672,6 +1085,12 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 			writeAPIError(w, ErrCodeValidation, "burnAfterMs is required for type burn")
 			return
 		}
+		if !storage.ValidBurnAfterMs(*req.BurnAfterMs) {
+			writeAPIErrorDetails(w, ErrCodeValidation,
+				fmt.Sprintf("burnAfterMs must be between %d and %d", storage.MinBurnAfterMs, storage.MaxBurnAfterMs),
+				burnAfterBoundsItem{MinBurnAfterMs: storage.MinBurnAfterMs, MaxBurnAfterMs: storage.MaxBurnAfterMs})
+			return
+		}
 		meta := []byte(strings.TrimSpace(string(req.MetaJSON)))
 		if len(meta) == 0 {
 			writeAPIError(w, ErrCodeValidation, "metaJson is required for type burn")
@@ -689,9 +1108,17 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 
 		msg, burnRow, err = api.store.CreateBurnMessage(r.Context(), sessionID, userID, meta, *req.BurnAfterMs, nowMs)
 	} else {
-		msg, err = api.store.CreateMessage(r.Context(), sessionID, userID, req.Type, text, req.Meta, nowMs)
+		msg, err = api.store.CreateReplyMessage(r.Context(), sessionID, userID, req.Type, text, req.Meta, req.ReplyToMessageID, nowMs)
 	}
 	if err != nil {
+		if errors.Is(err, storage.ErrReplyTargetInvalid) {
+			writeAPIError(w, ErrCodeValidation, "invalid replyToMessageId")
+			return
+		}
+		if errors.Is(err, storage.ErrBlocked) {
+			writeAPIError(w, ErrCodeBlocked, "blocked by this user")
+			return
+		}
 		if errors.Is(err, storage.ErrNotFound) {
 			writeAPIError(w, ErrCodeSessionNotFound, "session not found")
 			return
@@ -708,7 +1135,7 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 			writeAPIError(w, ErrCodeValidation, "invalid session state")
 			return
 		}
-		api.logger.Error("create message failed", "error", err)
+		api.log(r.Context()).Error("create message failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -720,6 +1147,9 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 		SenderID:    msg.SenderID,
 		Type:        msg.Type,
 		CreatedAtMs: msg.CreatedAtMs,
+
+		ReplyToMessageID: msg.ReplyToMessageID,
+		ReplyTo:          replyPreviewFromRow(msg.ReplyTo),
 	}
 	if msg.Text != nil {
 		item.Text = *msg.Text
@@ -738,7 +1168,7 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 
 	writeJSON(w, http.StatusOK, createMessageResponse{Message: item})
 
-	api.broadcast(ws.Envelope{
+	api.relayMessageEvent(r.Context(), ws.Envelope{
 		Type:      "message.created",
 		SessionID: msg.SessionID,
 		Payload: map[string]any{
@@ -747,6 +1177,158 @@ func (api *v1API) handleCreateMessage(w http.ResponseWriter, r *http.Request, se
 	})
 }
 
+type editMessageRequest struct {
+	Text string `json:"text"`
+}
+
+func (api *v1API) handleEditMessage(w http.ResponseWriter, r *http.Request, sessionID, messageID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	messageID = strings.TrimSpace(messageID)
+	if sessionID == "" || messageID == "" {
+		writeAPIError(w, ErrCodeValidation, "invalid sessionId or messageId")
+		return
+	}
+
+	var req editMessageRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		writeAPIError(w, ErrCodeValidation, "text is required")
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > storage.MaxMessageTextLen {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("text must be at most %d characters", storage.MaxMessageTextLen))
+		return
+	}
+
+	msg, err := api.store.EditMessage(r.Context(), sessionID, messageID, userID, req.Text, time.Now().UnixMilli())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeNotFound, "message not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+			return
+		}
+		if errors.Is(err, storage.ErrSessionArchived) {
+			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeValidation, "only undeleted text messages can be edited")
+			return
+		}
+		api.log(r.Context()).Error("edit message failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	item := messageItem{
+		ID:          msg.ID,
+		SessionID:   msg.SessionID,
+		Sender:      "me",
+		SenderID:    msg.SenderID,
+		Type:        msg.Type,
+		CreatedAtMs: msg.CreatedAtMs,
+		EditedAtMs:  msg.EditedAtMs,
+	}
+	if msg.Text != nil {
+		item.Text = *msg.Text
+	}
+	if meta := parseMeta(msg.MetaJSON); meta != nil {
+		item.Meta = meta
+	}
+
+	writeJSON(w, http.StatusOK, createMessageResponse{Message: item})
+
+	api.relayMessageEvent(r.Context(), ws.Envelope{
+		Type:      "message.edited",
+		SessionID: msg.SessionID,
+		Payload: map[string]any{
+			"message": item,
+		},
+	})
+}
+
+func (api *v1API) handleDeleteMessage(w http.ResponseWriter, r *http.Request, sessionID, messageID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	messageID = strings.TrimSpace(messageID)
+	if sessionID == "" || messageID == "" {
+		writeAPIError(w, ErrCodeValidation, "invalid sessionId or messageId")
+		return
+	}
+
+	msg, err := api.store.DeleteMessage(r.Context(), sessionID, messageID, userID, time.Now().UnixMilli())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeNotFound, "message not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+			return
+		}
+		if errors.Is(err, storage.ErrSessionArchived) {
+			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
+			return
+		}
+		api.log(r.Context()).Error("delete message failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, createMessageResponse{Message: messageItem{
+		ID:          msg.ID,
+		SessionID:   msg.SessionID,
+		Sender:      "me",
+		SenderID:    msg.SenderID,
+		Type:        msg.Type,
+		CreatedAtMs: msg.CreatedAtMs,
+		DeletedAtMs: msg.DeletedAtMs,
+	}})
+
+	api.relayMessageEvent(r.Context(), ws.Envelope{
+		Type:      "message.deleted",
+		SessionID: msg.SessionID,
+		Payload: map[string]any{
+			"messageId":   msg.ID,
+			"deletedAtMs": msg.DeletedAtMs,
+		},
+	})
+}
+
+// relayMessageEvent delivers a session or message envelope to the participants of
+// env.SessionID only. Group chats reach current members, so removed or departed members
+// stop receiving the live relay as soon as their participant row changes.
+func (api *v1API) relayMessageEvent(ctx context.Context, env ws.Envelope) {
+	api.sendToUsers(api.participantUserIDs(ctx, env.SessionID), env)
+}
+
+func (api *v1API) participantUserIDs(ctx context.Context, sessionID string) []string {
+	ids, err := api.store.ParticipantUserIDs(ctx, sessionID)
+	if err != nil {
+		api.log(ctx).Warn("list session participants failed", "error", err, "sessionID", sessionID)
+		return nil
+	}
+	return ids
+}
+
 func parseMeta(b []byte) *storage.MessageMeta {
 	if len(b) == 0 {
 		return nil
@@ -768,6 +1350,31 @@ func (api *v1API) broadcast(env ws.Envelope) {
 	api.wsManager.Broadcast(env)
 }
 
+// onlineStatus reports which of userIDs hold an open WebSocket as viewerID may see it. Only
+// viewerID's presence contacts are looked up, matching who receives presence events; anyone
+// else, including blocked pairs, is left out and reads as offline.
+func (api *v1API) onlineStatus(ctx context.Context, viewerID string, userIDs []string) (map[string]bool, error) {
+	if api.wsManager == nil || len(userIDs) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	contactIDs, err := api.store.ListPresenceContactUserIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	isContact := make(map[string]bool, len(contactIDs))
+	for _, id := range contactIDs {
+		isContact[id] = true
+	}
+	visible := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if isContact[id] {
+			visible = append(visible, id)
+		}
+	}
+	return api.wsManager.OnlineStatus(visible), nil
+}
+
 func (api *v1API) sendToUser(userID string, env ws.Envelope) {
 	if api.wsManager == nil || strings.TrimSpace(userID) == "" {
 		return
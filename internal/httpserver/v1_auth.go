@@ -1,9 +1,12 @@
 package httpserver
 
 import (
+	"context"
 	"errors"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -49,6 +52,23 @@ type logoutResponse struct {
 	Success bool `json:"success"`
 }
 
+type refreshTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+	// SignOutCurrent also revokes the token used for this request; by default it stays valid.
+	SignOutCurrent bool `json:"signOutCurrent,omitempty"`
+}
+
+type changePasswordResponse struct {
+	Success       bool  `json:"success"`
+	RevokedTokens int64 `json:"revokedTokens"`
+}
+
 func (api *v1API) handleAuth(w http.ResponseWriter, r *http.Request) {
 	rest := strings.TrimPrefix(r.URL.Path, "/v1/auth/")
 	switch rest {
@@ -70,6 +90,18 @@ func (api *v1API) handleAuth(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		api.handleLogout(w, r)
+	case "refresh":
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleRefreshToken(w, r)
+	case "change-password":
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleChangePassword(w, r)
 	case "me":
 		if r.Method != http.MethodGet {
 			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
@@ -106,9 +138,9 @@ func (api *v1API) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), api.bcryptCost)
 	if err != nil {
-		api.logger.Error("bcrypt hash failed", "error", err)
+		api.log(r.Context()).Error("bcrypt hash failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -120,7 +152,7 @@ func (api *v1API) handleRegister(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeUsernameExists, "username already exists")
 			return
 		}
-		api.logger.Error("create user failed", "error", err)
+		api.log(r.Context()).Error("create user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -128,7 +160,7 @@ func (api *v1API) handleRegister(w http.ResponseWriter, r *http.Request) {
 	expiresAtMs := nowMs + tokenDuration.Milliseconds()
 	tokenRow, err := api.store.CreateAuthToken(r.Context(), user.ID, nil, nowMs, expiresAtMs)
 	if err != nil {
-		api.logger.Error("create token failed", "error", err)
+		api.log(r.Context()).Error("create token failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -158,27 +190,45 @@ func (api *v1API) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Locked-out attempts are refused before any lookup or bcrypt work. Unknown usernames count
+	// as failures too, so the throttle does not reveal which accounts exist.
+	userKey, ipKey := loginUserThrottleKey(req.Username), loginIPThrottleKey(r)
+	if wait := api.loginThrottle.retryAfter(userKey, ipKey); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeAPIError(w, ErrCodeRateLimited, "too many failed login attempts, try again later")
+		return
+	}
+	loginFailed := func() {
+		api.loginThrottle.recordFailure(userKey, loginMaxFailuresPerUser)
+		api.loginThrottle.recordFailure(ipKey, loginMaxFailuresPerIP)
+		writeAPIError(w, ErrCodeInvalidCredentials, "invalid username or password")
+	}
+
 	user, err := api.store.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
-			writeAPIError(w, ErrCodeInvalidCredentials, "invalid username or password")
+			loginFailed()
 			return
 		}
-		api.logger.Error("get user failed", "error", err)
+		api.log(r.Context()).Error("get user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		writeAPIError(w, ErrCodeInvalidCredentials, "invalid username or password")
+		loginFailed()
 		return
 	}
+	// Only the account's counter is cleared; a successful login must not let one address
+	// keep guessing other accounts.
+	api.loginThrottle.reset(userKey)
+	api.upgradePasswordHash(r.Context(), user, req.Password)
 
 	nowMs := time.Now().UnixMilli()
 	expiresAtMs := nowMs + tokenDuration.Milliseconds()
 	tokenRow, err := api.store.CreateAuthToken(r.Context(), user.ID, nil, nowMs, expiresAtMs)
 	if err != nil {
-		api.logger.Error("create token failed", "error", err)
+		api.log(r.Context()).Error("create token failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -206,6 +256,105 @@ func (api *v1API) handleLogout(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, logoutResponse{Success: true})
 }
 
+// handleRefreshToken swaps the caller's still-valid token for a new one with a full
+// tokenDuration, so active clients are not logged out weekly. The old token stops working.
+func (api *v1API) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "token required")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	tokenRow, err := api.store.RotateAuthToken(r.Context(), token, nowMs, nowMs+tokenDuration.Milliseconds())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTokenExpired):
+			writeAPIError(w, ErrCodeTokenExpired, "token expired")
+		case errors.Is(err, storage.ErrTokenInvalid):
+			writeAPIError(w, ErrCodeTokenInvalid, "invalid token")
+		default:
+			api.log(r.Context()).Error("rotate token failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, refreshTokenResponse{Token: tokenRow.Token, ExpiresAt: tokenRow.ExpiresAtMs})
+}
+
+// handleChangePassword replaces the caller's password and revokes their other tokens, so every
+// other device has to log in again. WebSocket connections opened with a revoked token are
+// closed too.
+func (api *v1API) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req changePasswordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.OldPassword == "" {
+		writeAPIError(w, ErrCodeValidation, "oldPassword is required")
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		writeAPIError(w, ErrCodeValidation, err.Error())
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		writeAPIError(w, ErrCodeValidation, "newPassword must differ from oldPassword")
+		return
+	}
+
+	user, err := api.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(r.Context()).Error("get user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if !api.verifyPasswordThrottled(w, user, req.OldPassword) {
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), api.bcryptCost)
+	if err != nil {
+		api.log(r.Context()).Error("bcrypt hash failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if err := api.store.UpdateUserPasswordHash(r.Context(), userID, string(passwordHash), time.Now().UnixMilli()); err != nil {
+		api.log(r.Context()).Error("update password failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	keepToken := extractToken(r)
+	if req.SignOutCurrent {
+		keepToken = ""
+	}
+	revoked, err := api.store.DeleteTokensForUser(r.Context(), userID, keepToken)
+	if err != nil {
+		api.log(r.Context()).Error("revoke tokens failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, changePasswordResponse{Success: true, RevokedTokens: revoked})
+
+	if api.wsManager != nil {
+		api.wsManager.CloseUserExcept(userID, keepToken, "password changed")
+	}
+}
+
 func (api *v1API) handleMe(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == "" {
@@ -219,7 +368,7 @@ func (api *v1API) handleMe(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeUserNotFound, "user not found")
 			return
 		}
-		api.logger.Error("get user failed", "error", err)
+		api.log(r.Context()).Error("get user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -272,3 +421,49 @@ func extractToken(r *http.Request) string {
 	}
 	return ""
 }
+
+func bcryptCostOrDefault(cost int) int {
+	if cost <= 0 {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+// upgradePasswordHash re-hashes a just-verified password when its stored hash uses a lower
+// cost than configured, so raising BcryptCost takes effect without forcing resets. Failures
+// are logged and never fail the login. The write only applies while the stored hash is still
+// the one just verified, so a concurrent password change wins.
+func (api *v1API) upgradePasswordHash(ctx context.Context, user storage.UserRow, password string) {
+	cost, err := bcrypt.Cost([]byte(user.PasswordHash))
+	if err != nil || cost >= api.bcryptCost {
+		return
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), api.bcryptCost)
+	if err != nil {
+		api.log(ctx).Warn("rehash password failed", "error", err, "userID", user.ID)
+		return
+	}
+	if _, err := api.store.ReplaceUserPasswordHash(ctx, user.ID, user.PasswordHash, string(passwordHash), time.Now().UnixMilli()); err != nil {
+		api.log(ctx).Warn("store rehashed password failed", "error", err, "userID", user.ID)
+	}
+}
+
+// verifyPasswordThrottled checks password for an already-authenticated user, writing the
+// error response and returning false when it is wrong or the account is locked out. Wrong
+// passwords count against the same per-account lockout as logins, so a session token is not
+// an unthrottled way to guess the password.
+func (api *v1API) verifyPasswordThrottled(w http.ResponseWriter, user storage.UserRow, password string) bool {
+	userKey := loginUserThrottleKey(user.Username)
+	if wait := api.loginThrottle.retryAfter(userKey); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeAPIError(w, ErrCodeRateLimited, "too many failed password attempts, try again later")
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		api.loginThrottle.recordFailure(userKey, loginMaxFailuresPerUser)
+		writeAPIError(w, ErrCodeInvalidCredentials, "invalid password")
+		return false
+	}
+	api.loginThrottle.reset(userKey)
+	return true
+}
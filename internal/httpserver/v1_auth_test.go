@@ -0,0 +1,351 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestChangePassword_RevokesOtherTokens(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	tokenToUserID := map[string]string{}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	decodeToken := func(res *http.Response) string {
+		t.Helper()
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("auth status = %d, body=%s", res.StatusCode, string(b))
+		}
+		var body authResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode auth response error = %v", err)
+		}
+		return body.Token
+	}
+	login := func(password string) *http.Response {
+		return postJSON(t, client, srv.URL+"/v1/auth/login", map[string]any{"username": "alice", "password": password}, "")
+	}
+
+	oldToken := decodeToken(postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+		"username":    "alice",
+		"password":    "OldP@ss123",
+		"displayName": "Alice",
+	}, ""))
+	currentToken := decodeToken(login("OldP@ss123"))
+
+	alice, err := store.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername() error = %v", err)
+	}
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	conns := map[string]*websocket.Conn{}
+	for _, token := range []string{oldToken, currentToken} {
+		tokenToUserID[token] = alice.ID
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+token, nil)
+		if err != nil {
+			t.Fatalf("ws dial error = %v", err)
+		}
+		defer conn.Close()
+		conns[token] = conn
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	wrong := postJSON(t, client, srv.URL+"/v1/auth/change-password", map[string]any{
+		"oldPassword": "not-it-123",
+		"newPassword": "NewP@ss456",
+	}, currentToken)
+	_ = wrong.Body.Close()
+	if wrong.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("change-password with wrong oldPassword status = %d, want 401", wrong.StatusCode)
+	}
+
+	res := postJSON(t, client, srv.URL+"/v1/auth/change-password", map[string]any{
+		"oldPassword": "OldP@ss123",
+		"newPassword": "NewP@ss456",
+	}, currentToken)
+	var changed changePasswordResponse
+	if err := json.NewDecoder(res.Body).Decode(&changed); err != nil {
+		t.Fatalf("decode change-password response error = %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || changed.RevokedTokens != 1 {
+		t.Fatalf("change-password = %d %+v, want 200 with 1 revoked token", res.StatusCode, changed)
+	}
+
+	// The revoked token's socket is closed; the current one stays open.
+	_ = conns[oldToken].SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conns[oldToken].ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("old token ws read error = %v, want a normal close", err)
+	}
+	_ = conns[currentToken].SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := conns[currentToken].ReadMessage(); websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("current token ws closed: %v", err)
+	}
+
+	for _, c := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"old token", oldToken, http.StatusUnauthorized},
+		{"current token", currentToken, http.StatusOK},
+	} {
+		me := get(t, client, srv.URL+"/v1/auth/me", c.token)
+		_ = me.Body.Close()
+		if me.StatusCode != c.want {
+			t.Fatalf("GET /v1/auth/me with %s status = %d, want %d", c.name, me.StatusCode, c.want)
+		}
+	}
+
+	stale := login("OldP@ss123")
+	_ = stale.Body.Close()
+	if stale.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("login with old password status = %d, want 401", stale.StatusCode)
+	}
+	_ = decodeToken(login("NewP@ss456"))
+}
+
+func TestChangePassword_WrongOldPasswordsAreThrottled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	hash, err := bcrypt.GenerateFromPassword([]byte("OldP@ss123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	alice, err := store.CreateUser(ctx, "alice", string(hash), "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, alice.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: alice.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	changePassword := func(oldPassword string) *http.Response {
+		return postJSON(t, client, srv.URL+"/v1/auth/change-password", map[string]any{
+			"oldPassword": oldPassword,
+			"newPassword": "NewP@ss456",
+		}, tok.Token)
+	}
+
+	for i := 0; i < loginMaxFailuresPerUser; i++ {
+		res := changePassword("nope")
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("change-password wrong oldPassword #%d status = %d, want 401", i+1, res.StatusCode)
+		}
+	}
+
+	// Locked out: even the right old password is refused without being checked.
+	locked := changePassword("OldP@ss123")
+	var errEnv struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(locked.Body).Decode(&errEnv)
+	_ = locked.Body.Close()
+	if locked.StatusCode != http.StatusTooManyRequests || errEnv.Error.Code != string(ErrCodeRateLimited) {
+		t.Fatalf("change-password while locked = %d %q, want 429 %s", locked.StatusCode, errEnv.Error.Code, ErrCodeRateLimited)
+	}
+	if locked.Header.Get("Retry-After") == "" {
+		t.Fatalf("change-password while locked missing Retry-After header")
+	}
+}
+
+func TestRefreshToken_RotatesAndInvalidatesOldToken(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+		"username":    "alice",
+		"password":    "P@ssw0rd1",
+		"displayName": "Alice",
+	}, "")
+	var registered authResponse
+	if err := json.NewDecoder(res.Body).Decode(&registered); err != nil {
+		t.Fatalf("decode register response error = %v", err)
+	}
+	_ = res.Body.Close()
+
+	res = postJSON(t, client, srv.URL+"/v1/auth/refresh", map[string]any{}, registered.Token)
+	var refreshed refreshTokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("decode refresh response error = %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || refreshed.Token == "" || refreshed.Token == registered.Token {
+		t.Fatalf("refresh = %d %+v, want a new token", res.StatusCode, refreshed)
+	}
+	if refreshed.ExpiresAt < registered.ExpiresAt {
+		t.Fatalf("refreshed expiresAt = %d, want >= %d", refreshed.ExpiresAt, registered.ExpiresAt)
+	}
+
+	for _, c := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"old token", registered.Token, http.StatusUnauthorized},
+		{"new token", refreshed.Token, http.StatusOK},
+	} {
+		me := get(t, client, srv.URL+"/v1/auth/me", c.token)
+		_ = me.Body.Close()
+		if me.StatusCode != c.want {
+			t.Fatalf("GET /v1/auth/me with %s status = %d, want %d", c.name, me.StatusCode, c.want)
+		}
+	}
+
+	again := postJSON(t, client, srv.URL+"/v1/auth/refresh", map[string]any{}, registered.Token)
+	_ = again.Body.Close()
+	if again.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("refresh with rotated-out token status = %d, want 401", again.StatusCode)
+	}
+}
+
+func TestLogin_ThrottledAfterRepeatedFailures(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+		"username":    "alice",
+		"password":    "P@ssw0rd1",
+		"displayName": "Alice",
+	}, "")
+	_ = res.Body.Close()
+
+	login := func(password string) *http.Response {
+		return postJSON(t, client, srv.URL+"/v1/auth/login", map[string]any{"username": "alice", "password": password}, "")
+	}
+
+	for i := 0; i < loginMaxFailuresPerUser; i++ {
+		res := login("wrong-password")
+		var body apiErrorEnvelope
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized || body.Error.Message != "invalid username or password" {
+			t.Fatalf("attempt %d = %d %+v, want generic 401", i+1, res.StatusCode, body.Error)
+		}
+	}
+
+	// Even the right password is refused while locked out.
+	locked := login("P@ssw0rd1")
+	_ = locked.Body.Close()
+	if locked.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("login while locked status = %d, want 429", locked.StatusCode)
+	}
+	if locked.Header.Get("Retry-After") == "" {
+		t.Fatalf("login while locked missing Retry-After header")
+	}
+}
+
+func TestLogin_UpgradesLowCostPasswordHash(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	lowHash, err := bcrypt.GenerateFromPassword([]byte("P@ssw0rd1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	user, err := store.CreateUser(ctx, "alice", string(lowHash), "Alice", time.Now().UnixMilli())
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	const configuredCost = bcrypt.MinCost + 2
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{BcryptCost: configuredCost})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	login := func() int {
+		res := postJSON(t, client, srv.URL+"/v1/auth/login", map[string]any{"username": "alice", "password": "P@ssw0rd1"}, "")
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+
+	if status := login(); status != http.StatusOK {
+		t.Fatalf("login status = %d, want 200", status)
+	}
+	stored, err := store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(stored.PasswordHash)); err != nil || cost != configuredCost {
+		t.Fatalf("stored hash cost = %d (%v), want %d", cost, err, configuredCost)
+	}
+	if status := login(); status != http.StatusOK {
+		t.Fatalf("login with upgraded hash status = %d, want 200", status)
+	}
+}
@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+)
+
+type blockRequest struct {
+	UserID string `json:"userId"`
+}
+
+type blockedUserItem struct {
+	UserID      string  `json:"userId"`
+	DisplayName string  `json:"displayName"`
+	AvatarURL   *string `json:"avatarUrl,omitempty"`
+	CreatedAtMs int64   `json:"createdAtMs"`
+}
+
+// handleBlocks serves /v1/blocks: GET lists the caller's blocks, POST blocks the user in the
+// body, and DELETE unblocks the user given as /v1/blocks/{userId}, ?userId= or in the body.
+func (api *v1API) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/blocks"), "/")
+	parts := splitPath(rest)
+	if len(parts) > 1 || (len(parts) == 1 && r.Method != http.MethodDelete) {
+		writeAPIError(w, ErrCodeNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		api.handleListBlocks(w, r, userID)
+	case http.MethodPost:
+		var req blockRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+			return
+		}
+		api.handleBlockUser(w, r, userID, strings.TrimSpace(req.UserID))
+	case http.MethodDelete:
+		var req blockRequest
+		switch {
+		case len(parts) == 1:
+			req.UserID = parts[0]
+		case r.ContentLength <= 0:
+			req.UserID = r.URL.Query().Get("userId")
+		default:
+			if err := decodeJSON(w, r, &req); err != nil {
+				writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+				return
+			}
+		}
+		api.handleUnblockUser(w, r, userID, strings.TrimSpace(req.UserID))
+	default:
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (api *v1API) handleListBlocks(w http.ResponseWriter, r *http.Request, userID string) {
+	rows, err := api.store.ListBlockedUsers(r.Context(), userID)
+	if err != nil {
+		api.log(r.Context()).Error("list blocked users failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]blockedUserItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, blockedUserItem{
+			UserID:      row.UserID,
+			DisplayName: row.DisplayName,
+			AvatarURL:   row.AvatarURL,
+			CreatedAtMs: row.CreatedAtMs,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"blocks": items})
+}
+
+func (api *v1API) handleBlockUser(w http.ResponseWriter, r *http.Request, userID, targetID string) {
+	if targetID == "" {
+		writeAPIError(w, ErrCodeValidation, "userId is required")
+		return
+	}
+
+	created, err := api.store.BlockUser(r.Context(), userID, targetID, time.Now().UnixMilli())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrCannotChatSelf):
+			writeAPIError(w, ErrCodeCannotChatSelf, "cannot block self")
+		case errors.Is(err, storage.ErrNotFound):
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+		default:
+			api.log(r.Context()).Error("block user failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"userId": targetID, "blocked": true, "created": created})
+}
+
+func (api *v1API) handleUnblockUser(w http.ResponseWriter, r *http.Request, userID, targetID string) {
+	if targetID == "" {
+		writeAPIError(w, ErrCodeValidation, "userId is required")
+		return
+	}
+
+	removed, err := api.store.UnblockUser(r.Context(), userID, targetID)
+	if err != nil {
+		api.log(r.Context()).Error("unblock user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"userId": targetID, "blocked": false, "removed": removed})
+}
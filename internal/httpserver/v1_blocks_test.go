@@ -0,0 +1,105 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestBlocks_BlockedUserCannotCall(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/blocks", map[string]any{"userId": bob.ID}, aliceToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("POST /v1/blocks status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res = get(t, client, srv.URL+"/v1/blocks", aliceToken)
+	var list struct {
+		Blocks []blockedUserItem `json:"blocks"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&list)
+	_ = res.Body.Close()
+	if len(list.Blocks) != 1 || list.Blocks[0].UserID != bob.ID {
+		t.Fatalf("GET /v1/blocks = %+v, want bob only", list.Blocks)
+	}
+
+	res = postJSON(t, client, srv.URL+"/v1/calls", map[string]any{"calleeUserId": alice.ID, "mediaType": "voice"}, bobToken)
+	var errEnv struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&errEnv)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusForbidden || errEnv.Error.Code != string(ErrCodeBlocked) {
+		t.Fatalf("blocked call status = %d code = %q, want %d %s", res.StatusCode, errEnv.Error.Code, http.StatusForbidden, ErrCodeBlocked)
+	}
+
+	// A client cannot dodge the block by posting a forged system notice.
+	res = postJSON(t, client, srv.URL+"/v1/sessions/"+session.ID+"/messages", map[string]any{"type": "system", "text": "语音通话 1分0秒"}, bobToken)
+	errEnv.Error.Code = ""
+	_ = json.NewDecoder(res.Body).Decode(&errEnv)
+	_ = res.Body.Close()
+	if res.StatusCode == http.StatusOK || errEnv.Error.Code != string(ErrCodeValidation) {
+		t.Fatalf("blocked system message status = %d code = %q, want rejected %s", res.StatusCode, errEnv.Error.Code, ErrCodeValidation)
+	}
+	msgs, _, err := store.ListMessages(ctx, session.ID, alice.ID, 50, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("messages after blocked system post = %d, want 0", len(msgs))
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/blocks/"+bob.ID, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /v1/blocks error = %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE /v1/blocks status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res = postJSON(t, client, srv.URL+"/v1/calls", map[string]any{"calleeUserId": alice.ID, "mediaType": "voice"}, bobToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("call after unblock status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+}
@@ -23,9 +23,27 @@ type burnMessageReadResponse struct {
 	Started   bool          `json:"started"`
 }
 
+type burnAfterBoundsItem struct {
+	MinBurnAfterMs int64 `json:"minBurnAfterMs"`
+	MaxBurnAfterMs int64 `json:"maxBurnAfterMs"`
+}
+
+type burnPresetsResponse struct {
+	burnAfterBoundsItem
+	PresetsMs []int64 `json:"presetsMs"`
+}
+
 func (api *v1API) handleBurnMessages(w http.ResponseWriter, r *http.Request) {
 	rest := strings.TrimPrefix(r.URL.Path, "/v1/burn-messages/")
 	parts := splitPath(rest)
+	if len(parts) == 1 && parts[0] == "presets" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleBurnPresets(w, r)
+		return
+	}
 	if len(parts) != 2 {
 		writeAPIError(w, ErrCodeNotFound, "not found")
 		return
@@ -68,7 +86,7 @@ func (api *v1API) handleReadBurnMessage(w http.ResponseWriter, r *http.Request,
 			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
 			return
 		}
-		api.logger.Error("mark burn message read failed", "error", err)
+		api.log(r.Context()).Error("mark burn message read failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -85,6 +103,7 @@ func (api *v1API) handleReadBurnMessage(w http.ResponseWriter, r *http.Request,
 	}
 	writeJSON(w, http.StatusOK, resp)
 
+	// Only the first read starts the countdown, so the sender hears about it exactly once.
 	if started {
 		api.sendToUsers([]string{row.SenderID, row.RecipientID}, ws.Envelope{
 			Type:      "message.burn.read",
@@ -92,8 +111,44 @@ func (api *v1API) handleReadBurnMessage(w http.ResponseWriter, r *http.Request,
 			Payload: map[string]any{
 				"messageId":    row.MessageID,
 				"burn":         resp.Burn,
+				"burnAtMs":     row.BurnAtMs,
 				"readerUserId": userID,
 			},
 		})
 	}
 }
+
+// handleBurnPresets tells clients which burn-after durations they may offer. The bounds come
+// straight from storage so the picker and CreateBurnMessage can't drift apart.
+func (api *v1API) handleBurnPresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, burnPresetsResponse{
+		burnAfterBoundsItem: burnAfterBoundsItem{
+			MinBurnAfterMs: storage.MinBurnAfterMs,
+			MaxBurnAfterMs: storage.MaxBurnAfterMs,
+		},
+		PresetsMs: storage.BurnAfterPresetsMs,
+	})
+}
+
+// burnStateForViewer renders burn for userID. Group members see their own countdown and lose
+// the message once it ends, even though it is only purged after every member's countdown.
+func burnStateForViewer(burn storage.BurnMessageRow, userID string, nowMs int64) (burnStateItem, bool) {
+	state := burnStateItem{
+		BurnAfterMs: burn.BurnAfterMs,
+		OpenedAtMs:  burn.OpenedAtMs,
+		BurnAtMs:    burn.BurnAtMs,
+	}
+	if !burn.IsGroup() || burn.SenderID == userID {
+		return state, true
+	}
+	rec, ok := burn.Recipient(userID)
+	if !ok {
+		return burnStateItem{}, false
+	}
+	if rec.BurnAtMs != nil && *rec.BurnAtMs <= nowMs {
+		return burnStateItem{}, false
+	}
+	state.OpenedAtMs = rec.OpenedAtMs
+	state.BurnAtMs = rec.BurnAtMs
+	return state, true
+}
@@ -7,9 +7,11 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
 )
@@ -330,3 +332,124 @@ func TestBurnMessages_NewDevice_NoHistoryForBurn(t *testing.T) {
 		t.Fatalf("expected new device to NOT see historical burn messages (Option A)")
 	}
 }
+
+func TestBurnMessages_PresetsAndOutOfRangeFeedback(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := get(t, client, srv.URL+"/v1/burn-messages/presets", aliceToken)
+	var presets burnPresetsResponse
+	_ = json.NewDecoder(res.Body).Decode(&presets)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET presets status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if presets.MinBurnAfterMs != storage.MinBurnAfterMs || presets.MaxBurnAfterMs != storage.MaxBurnAfterMs || len(presets.PresetsMs) == 0 {
+		t.Fatalf("presets = %+v, want storage bounds and presets", presets)
+	}
+	for _, p := range presets.PresetsMs {
+		if !storage.ValidBurnAfterMs(p) {
+			t.Fatalf("preset %d outside bounds", p)
+		}
+	}
+
+	res = postJSON(t, client, srv.URL+"/v1/sessions/"+session.ID+"/messages", map[string]any{
+		"type":        "burn",
+		"burnAfterMs": int64(500),
+		"metaJson":    map[string]any{"text": "hi"},
+	}, aliceToken)
+	var errEnv struct {
+		Error struct {
+			Code    string              `json:"code"`
+			Details burnAfterBoundsItem `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&errEnv)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest || errEnv.Error.Code != string(ErrCodeValidation) {
+		t.Fatalf("out-of-range burn status = %d code = %q, want %d %s", res.StatusCode, errEnv.Error.Code, http.StatusBadRequest, ErrCodeValidation)
+	}
+	if errEnv.Error.Details.MinBurnAfterMs != storage.MinBurnAfterMs || errEnv.Error.Details.MaxBurnAfterMs != storage.MaxBurnAfterMs {
+		t.Fatalf("error details = %+v, want storage bounds", errEnv.Error.Details)
+	}
+}
+
+func TestBurnMessages_SenderNotifiedOnFirstRead(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	msg, _, err := store.CreateBurnMessage(ctx, session.ID, alice.ID, []byte(`{"ciphertext":"abc"}`), 60_000, nowMs)
+	if err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	aliceWS, _, err := websocket.DefaultDialer.Dial(wsURL+aliceToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial alice error = %v", err)
+	}
+	defer aliceWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res := postJSON(t, srv.Client(), srv.URL+"/v1/burn-messages/"+msg.ID+"/read", map[string]any{}, bobToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("read status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	env := readWSEvent(t, aliceWS)
+	if env.Type != "message.burn.read" || env.SessionID != session.ID {
+		t.Fatalf("alice event = %s/%s, want message.burn.read/%s", env.Type, env.SessionID, session.ID)
+	}
+	var payload struct {
+		MessageID    string `json:"messageId"`
+		BurnAtMs     *int64 `json:"burnAtMs"`
+		ReaderUserID string `json:"readerUserId"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode burn.read payload error = %v", err)
+	}
+	if payload.MessageID != msg.ID || payload.BurnAtMs == nil || payload.ReaderUserID != bob.ID {
+		t.Fatalf("burn.read payload = %+v, want message, burnAtMs and reader", payload)
+	}
+}
@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"linkbridge-backend/internal/storage"
+)
+
+// callHistoryItem is a call as seen by one participant: Direction is "outgoing" for the
+// caller and "incoming" for the callee, and Peer is the other party. Group calls carry the
+// activity SessionID instead of a peer.
+type callHistoryItem struct {
+	ID           string    `json:"id"`
+	Direction    string    `json:"direction"`
+	Peer         *peerItem `json:"peer,omitempty"`
+	SessionID    string    `json:"sessionId,omitempty"`
+	MediaType    string    `json:"mediaType"`
+	Status       string    `json:"status"`
+	CreatedAtMs  int64     `json:"createdAtMs"`
+	UpdatedAtMs  int64     `json:"updatedAtMs"`
+	AcceptedAtMs *int64    `json:"acceptedAtMs,omitempty"`
+	EndedAtMs    *int64    `json:"endedAtMs,omitempty"`
+	DurationMs   int64     `json:"durationMs"`
+}
+
+type listCallHistoryResponse struct {
+	Calls   []callHistoryItem `json:"calls"`
+	HasMore bool              `json:"hasMore"`
+}
+
+func (api *v1API) handleListCallHistory(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	limit := 20
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 100 {
+			writeAPIError(w, ErrCodeValidation, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	beforeID := strings.TrimSpace(r.URL.Query().Get("before"))
+
+	calls, hasMore, err := api.store.ListCallsForUser(r.Context(), userID, limit, beforeID)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+
+	peerIDs := make([]string, 0, len(calls))
+	for _, call := range calls {
+		peerIDs = append(peerIDs, callPeerID(call, userID))
+	}
+	users, err := api.store.GetUsersByIDs(r.Context(), peerIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get call peers failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]callHistoryItem, 0, len(calls))
+	for _, call := range calls {
+		item := callHistoryItem{
+			ID:           call.ID,
+			Direction:    "outgoing",
+			MediaType:    call.MediaType,
+			Status:       call.Status,
+			CreatedAtMs:  call.CreatedAtMs,
+			UpdatedAtMs:  call.UpdatedAtMs,
+			AcceptedAtMs: call.AcceptedAtMs,
+			EndedAtMs:    call.EndedAtMs,
+			DurationMs:   call.DurationMs(),
+			SessionID:    call.SessionID,
+		}
+		if call.CalleeID == userID && !call.IsGroup() {
+			item.Direction = "incoming"
+		}
+		if peer, ok := users[callPeerID(call, userID)]; ok && !call.IsGroup() {
+			item.Peer = &peerItem{
+				ID:          peer.ID,
+				Username:    peer.Username,
+				DisplayName: peer.DisplayName,
+				AvatarURL:   peer.AvatarURL,
+			}
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, listCallHistoryResponse{Calls: items, HasMore: hasMore})
+}
+
+func callPeerID(call storage.CallRow, userID string) string {
+	if call.CallerID == userID {
+		return call.CalleeID
+	}
+	return call.CallerID
+}
@@ -0,0 +1,89 @@
+package httpserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultTURNCredentialTTL applies when HandlerOptions.TURNCredentialTTL is unset.
+const defaultTURNCredentialTTL = time.Hour
+
+type iceServerItem struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+type turnCredentialsResponse struct {
+	Username    string          `json:"username"`
+	Credential  string          `json:"credential"`
+	TTLSeconds  int64           `json:"ttlSeconds"`
+	ExpiresAtMs int64           `json:"expiresAtMs"`
+	ICEServers  []iceServerItem `json:"iceServers"`
+}
+
+// handleGetTURNCredentials issues short-lived TURN credentials to a participant of callID so
+// the two clients can relay media when a direct path fails.
+func (api *v1API) handleGetTURNCredentials(w http.ResponseWriter, r *http.Request, callID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	if api.turnSharedSecret == "" {
+		writeAPIError(w, ErrCodeTURNNotConfigured, "turn integration not configured")
+		return
+	}
+
+	call, err := api.store.GetCallByID(r.Context(), callID)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+	isParticipant, err := api.isCallParticipant(r.Context(), call, userID)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+	if !isParticipant {
+		writeAPIError(w, ErrCodeCallAccessDenied, "access denied")
+		return
+	}
+
+	ttl := api.turnCredentialTTL
+	if ttl <= 0 {
+		ttl = defaultTURNCredentialTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+	username, credential := computeTURNCredentials(api.turnSharedSecret, userID, expiresAt)
+
+	servers := make([]iceServerItem, 0, 2)
+	if len(api.stunURIs) > 0 {
+		servers = append(servers, iceServerItem{URLs: api.stunURIs})
+	}
+	if len(api.turnURIs) > 0 {
+		servers = append(servers, iceServerItem{URLs: api.turnURIs, Username: username, Credential: credential})
+	}
+
+	writeJSON(w, http.StatusOK, turnCredentialsResponse{
+		Username:    username,
+		Credential:  credential,
+		TTLSeconds:  int64(ttl / time.Second),
+		ExpiresAtMs: expiresAt.UnixMilli(),
+		ICEServers:  servers,
+	})
+}
+
+// computeTURNCredentials follows the TURN REST API scheme understood by coturn's
+// use-auth-secret: the username is "<expiry unix seconds>:<userId>" and the password is the
+// base64 HMAC-SHA1 of the username keyed with the shared secret.
+func computeTURNCredentials(secret, userID string, expiresAt time.Time) (string, string) {
+	username := fmt.Sprintf("%d:%s", expiresAt.Unix(), userID)
+	mac := hmac.New(sha1.New, []byte(secret))
+	_, _ = mac.Write([]byte(username))
+	return username, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
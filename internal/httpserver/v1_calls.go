@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"net/url"
@@ -31,6 +32,12 @@ type callItem struct {
 	Status      string `json:"status"`
 	CreatedAtMs int64  `json:"createdAtMs"`
 	UpdatedAtMs int64  `json:"updatedAtMs"`
+	// SessionID is the activity chat of a group call.
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+type updateCallMediaRequest struct {
+	MediaType string `json:"mediaType"` // voice|video
 }
 
 type createCallResponse struct {
@@ -39,6 +46,8 @@ type createCallResponse struct {
 
 func (api *v1API) handleCalls(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
+	case http.MethodGet:
+		api.handleListCallHistory(w, r)
 	case http.MethodPost:
 		api.handleCreateCall(w, r)
 	default:
@@ -88,12 +97,24 @@ func (api *v1API) handleCallSubroutes(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		api.handleEndCall(w, r, callID)
+	case "media":
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleUpdateCallMedia(w, r, callID)
 	case "voip":
 		if r.Method != http.MethodGet {
 			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
 			return
 		}
 		api.handleGetVoipSign(w, r, callID)
+	case "turn":
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleGetTURNCredentials(w, r, callID)
 	default:
 		writeAPIError(w, ErrCodeNotFound, "not found")
 	}
@@ -108,10 +129,15 @@ func (api *v1API) handleGetCall(w http.ResponseWriter, r *http.Request, callID s
 
 	call, err := api.store.GetCallByID(r.Context(), callID)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
-	if call.CallerID != userID && call.CalleeID != userID {
+	isParticipant, err := api.isCallParticipant(r.Context(), call, userID)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+	if !isParticipant {
 		writeAPIError(w, ErrCodeCallAccessDenied, "access denied")
 		return
 	}
@@ -161,7 +187,7 @@ func (api *v1API) handleCreateCall(w http.ResponseWriter, r *http.Request) {
 	nowMs := time.Now().UnixMilli()
 	groupID, err := newNumericGroupID(18)
 	if err != nil {
-		api.logger.Error("generate call groupId failed", "error", err)
+		api.log(r.Context()).Error("generate call groupId failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -180,7 +206,15 @@ func (api *v1API) handleCreateCall(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
 			return
 		}
-		api.logger.Error("create call failed", "error", err)
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeCallInvalidState, "a call is already in progress")
+			return
+		}
+		if errors.Is(err, storage.ErrBlocked) {
+			writeAPIError(w, ErrCodeBlocked, "blocked by this user")
+			return
+		}
+		api.log(r.Context()).Error("create call failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -190,7 +224,7 @@ func (api *v1API) handleCreateCall(w http.ResponseWriter, r *http.Request) {
 
 	caller, err := api.store.GetUserByID(r.Context(), call.CallerID)
 	if err != nil {
-		api.logger.Warn("get caller user failed", "error", err, "callerID", call.CallerID)
+		api.log(r.Context()).Warn("get caller user failed", "error", err, "callerID", call.CallerID)
 	}
 
 	payload := map[string]any{
@@ -216,7 +250,7 @@ func (api *v1API) handleCreateCall(w http.ResponseWriter, r *http.Request) {
 		Payload:   payload,
 	})
 
-	go api.bestEffortOfflineCallNotify(call)
+	go api.bestEffortOfflineCallNotify(context.WithoutCancel(r.Context()), call)
 }
 
 func (api *v1API) handleAcceptCall(w http.ResponseWriter, r *http.Request, callID string) {
@@ -229,7 +263,7 @@ func (api *v1API) handleAcceptCall(w http.ResponseWriter, r *http.Request, callI
 	nowMs := time.Now().UnixMilli()
 	call, err := api.store.AcceptCall(r.Context(), callID, userID, nowMs)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
 
@@ -254,7 +288,7 @@ func (api *v1API) handleRejectCall(w http.ResponseWriter, r *http.Request, callI
 	nowMs := time.Now().UnixMilli()
 	call, err := api.store.RejectCall(r.Context(), callID, userID, nowMs)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
 
@@ -267,6 +301,8 @@ func (api *v1API) handleRejectCall(w http.ResponseWriter, r *http.Request, callI
 			"call": item,
 		},
 	})
+
+	api.postCallSummary(r.Context(), call, nowMs)
 }
 
 func (api *v1API) handleCancelCall(w http.ResponseWriter, r *http.Request, callID string) {
@@ -279,7 +315,7 @@ func (api *v1API) handleCancelCall(w http.ResponseWriter, r *http.Request, callI
 	nowMs := time.Now().UnixMilli()
 	call, err := api.store.CancelCall(r.Context(), callID, userID, nowMs)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
 
@@ -292,6 +328,8 @@ func (api *v1API) handleCancelCall(w http.ResponseWriter, r *http.Request, callI
 			"call": item,
 		},
 	})
+
+	api.postCallSummary(r.Context(), call, nowMs)
 }
 
 func (api *v1API) handleEndCall(w http.ResponseWriter, r *http.Request, callID string) {
@@ -304,19 +342,60 @@ func (api *v1API) handleEndCall(w http.ResponseWriter, r *http.Request, callID s
 	nowMs := time.Now().UnixMilli()
 	call, err := api.store.EndCall(r.Context(), callID, userID, nowMs)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
 
 	item := callItemFromRow(call)
 	writeJSON(w, http.StatusOK, map[string]any{"call": item})
-	api.sendToUsers([]string{call.CallerID, call.CalleeID}, ws.Envelope{
+	api.sendToUsers(api.callAudience(r.Context(), call), ws.Envelope{
 		Type:      "call.ended",
 		SessionID: "",
 		Payload: map[string]any{
 			"call": item,
 		},
 	})
+
+	api.postCallSummary(r.Context(), call, nowMs)
+}
+
+// handleUpdateCallMedia switches an accepted call between voice and video. Everyone in the
+// call's audience gets call.media.changed so they can renegotiate; the VoIP sign response follows the new roomType.
+func (api *v1API) handleUpdateCallMedia(w http.ResponseWriter, r *http.Request, callID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req updateCallMediaRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.MediaType = strings.TrimSpace(req.MediaType)
+	if req.MediaType != storage.CallMediaTypeVoice && req.MediaType != storage.CallMediaTypeVideo {
+		writeAPIError(w, ErrCodeValidation, "invalid mediaType")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	call, err := api.store.UpdateCallMedia(r.Context(), callID, userID, req.MediaType, nowMs)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+
+	item := callItemFromRow(call)
+	writeJSON(w, http.StatusOK, map[string]any{"call": item})
+	api.sendToUsers(api.callAudience(r.Context(), call), ws.Envelope{
+		Type:      "call.media.changed",
+		SessionID: "",
+		Payload: map[string]any{
+			"call":      item,
+			"changedBy": userID,
+		},
+	})
 }
 
 func (api *v1API) handleGetVoipSign(w http.ResponseWriter, r *http.Request, callID string) {
@@ -332,10 +411,15 @@ func (api *v1API) handleGetVoipSign(w http.ResponseWriter, r *http.Request, call
 
 	call, err := api.store.GetCallByID(r.Context(), callID)
 	if err != nil {
-		api.writeCallError(w, err)
+		api.writeCallError(w, r, err)
 		return
 	}
-	if call.CallerID != userID && call.CalleeID != userID {
+	isParticipant, err := api.isCallParticipant(r.Context(), call, userID)
+	if err != nil {
+		api.writeCallError(w, r, err)
+		return
+	}
+	if !isParticipant {
 		writeAPIError(w, ErrCodeCallAccessDenied, "access denied")
 		return
 	}
@@ -346,14 +430,14 @@ func (api *v1API) handleGetVoipSign(w http.ResponseWriter, r *http.Request, call
 			writeAPIError(w, ErrCodeWeChatNotBound, "wechat not bound")
 			return
 		}
-		api.logger.Error("get wechat binding failed", "error", err)
+		api.log(r.Context()).Error("get wechat binding failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	nonceStr, err := randomHex(16)
 	if err != nil {
-		api.logger.Error("generate nonce failed", "error", err)
+		api.log(r.Context()).Error("generate nonce failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -369,7 +453,7 @@ func (api *v1API) handleGetVoipSign(w http.ResponseWriter, r *http.Request, call
 	})
 }
 
-func (api *v1API) writeCallError(w http.ResponseWriter, err error) {
+func (api *v1API) writeCallError(w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
@@ -386,10 +470,116 @@ func (api *v1API) writeCallError(w http.ResponseWriter, err error) {
 		writeAPIError(w, ErrCodeCallInvalidState, "invalid call state")
 		return
 	}
-	api.logger.Error("call operation failed", "error", err)
+	api.log(r.Context()).Error("call operation failed", "error", err)
 	writeAPIError(w, ErrCodeInternal, "internal error")
 }
 
+// postCallSummary records a finished call as a system message; see PostCallSummary.
+func (api *v1API) postCallSummary(ctx context.Context, call storage.CallRow, nowMs int64) {
+	PostCallSummary(ctx, api.log(ctx), api.store, api.wsManager, call, nowMs)
+}
+
+// PostCallSummary records a finished or missed call as a system message in the direct session
+// of its two parties, or in the activity chat for group calls, and sends message.created to
+// the session's participants. The message is attributed to the caller, so the caller gets it
+// with sender "me" and everyone else with "peer"; it goes through the system message path
+// since the caller may have left the activity or been blocked by the time the call ends. A
+// direct session archived because a party deleted their account gets no summary. It is
+// best-effort: the call has already changed state when this runs. The call timeout sweeper
+// uses it for missed calls.
+func PostCallSummary(ctx context.Context, logger *slog.Logger, store Store, wsManager *ws.Manager, call storage.CallRow, nowMs int64) {
+	text := callSummaryText(call)
+	if text == "" {
+		return
+	}
+	sessionID := call.SessionID
+	if !call.IsGroup() {
+		session, err := store.GetSessionByParticipants(ctx, call.CallerID, call.CalleeID)
+		if err != nil {
+			logger.Warn("resolve call session failed", "error", err, "callID", call.ID)
+			return
+		}
+		sessionID = session.ID
+	}
+	msg, err := store.CreateSystemMessage(ctx, sessionID, call.CallerID, text, nowMs)
+	if err != nil {
+		logger.Warn("create call summary message failed", "error", err, "callID", call.ID)
+		return
+	}
+	if wsManager == nil {
+		return
+	}
+	userIDs, err := store.ParticipantUserIDs(ctx, msg.SessionID)
+	if err != nil {
+		logger.Warn("list session participants failed", "error", err, "sessionID", msg.SessionID)
+		return
+	}
+	for _, userID := range userIDs {
+		sender := "peer"
+		if userID == msg.SenderID {
+			sender = "me"
+		}
+		wsManager.SendToUser(userID, ws.Envelope{
+			Type:      "message.created",
+			SessionID: msg.SessionID,
+			Payload: map[string]any{
+				"message": messageItem{
+					ID:          msg.ID,
+					SessionID:   msg.SessionID,
+					Sender:      sender,
+					SenderID:    msg.SenderID,
+					Type:        msg.Type,
+					Text:        text,
+					CreatedAtMs: msg.CreatedAtMs,
+				},
+			},
+		})
+	}
+}
+
+func callSummaryText(call storage.CallRow) string {
+	kind := "语音通话"
+	if call.MediaType == storage.CallMediaTypeVideo {
+		kind = "视频通话"
+	}
+	switch call.Status {
+	case storage.CallStatusEnded:
+		secs := call.DurationMs() / 1000
+		if secs < 60 {
+			return fmt.Sprintf("%s %d秒", kind, secs)
+		}
+		return fmt.Sprintf("%s %d分%d秒", kind, secs/60, secs%60)
+	case storage.CallStatusRejected:
+		return kind + "已拒绝"
+	case storage.CallStatusCanceled:
+		return kind + "已取消"
+	case storage.CallStatusMissed:
+		return kind + "未接听"
+	default:
+		return ""
+	}
+}
+
+// isCallParticipant reports whether userID may see callID: the two parties of a direct call,
+// or any active member of the activity chat for a group call.
+func (api *v1API) isCallParticipant(ctx context.Context, call storage.CallRow, userID string) (bool, error) {
+	if call.CallerID == userID || call.CalleeID == userID {
+		return true, nil
+	}
+	if !call.IsGroup() {
+		return false, nil
+	}
+	return api.store.IsSessionParticipant(ctx, call.SessionID, userID)
+}
+
+// callAudience lists who gets events about call.
+func (api *v1API) callAudience(ctx context.Context, call storage.CallRow) []string {
+	if call.IsGroup() {
+		return api.activeParticipantIDs(ctx, call.SessionID)
+	}
+	return []string{call.CallerID, call.CalleeID}
+}
+
 func callItemFromRow(call storage.CallRow) callItem {
 	return callItem{
 		ID:          call.ID,
@@ -400,6 +590,7 @@ func callItemFromRow(call storage.CallRow) callItem {
 		Status:      call.Status,
 		CreatedAtMs: call.CreatedAtMs,
 		UpdatedAtMs: call.UpdatedAtMs,
+		SessionID:   call.SessionID,
 	}
 }
 
@@ -437,14 +628,21 @@ func newNumericGroupID(length int) (string, error) {
 	return id, nil
 }
 
-func (api *v1API) bestEffortOfflineCallNotify(call storage.CallRow) {
+// bestEffortOfflineCallNotify falls back to a WeChat subscribe message when the callee has no
+// live WebSocket; connected callees already got call.invite. ctx carries the request's logger
+// but must not be canceled with the request.
+func (api *v1API) bestEffortOfflineCallNotify(ctx context.Context, call storage.CallRow) {
 	if api.wechatClient == nil || api.wechatCallSubscribeTemplateID == "" {
 		return
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 8*time.Second)
 	defer cancel()
 
+	online, err := api.onlineStatus(ctx, call.CallerID, []string{call.CalleeID})
+	if err != nil || online[call.CalleeID] {
+		return
+	}
+
 	binding, err := api.store.GetWeChatBindingByUserID(ctx, call.CalleeID)
 	if err != nil {
 		return
@@ -452,7 +650,7 @@ func (api *v1API) bestEffortOfflineCallNotify(call storage.CallRow) {
 
 	accessToken, err := api.wechatClient.GetAccessToken(ctx)
 	if err != nil {
-		api.logger.Warn("wechat get access token failed", "error", err)
+		api.log(ctx).Warn("wechat get access token failed", "error", err)
 		return
 	}
 
@@ -493,6 +691,6 @@ func (api *v1API) bestEffortOfflineCallNotify(call storage.CallRow) {
 		Data:       data,
 	})
 	if err != nil {
-		api.logger.Warn("wechat subscribe send failed", "error", err)
+		api.log(ctx).Warn("wechat subscribe send failed", "error", err)
 	}
 }
@@ -0,0 +1,487 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestCalls_UpdateMedia_AcceptedOnlyAndNotifiesBoth(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	caller, callerToken := newTestUser(t, store, tokenToUserID, "caller", nowMs)
+	callee, calleeToken := newTestUser(t, store, tokenToUserID, "callee", nowMs)
+	_, outsiderToken := newTestUser(t, store, tokenToUserID, "outsider", nowMs)
+
+	if _, _, err := store.CreateSession(ctx, caller.ID, callee.ID, nowMs); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	call, err := store.CreateCall(ctx, caller.ID, callee.ID, storage.CallMediaTypeVoice, "123456789012345678", nowMs)
+	if err != nil {
+		t.Fatalf("CreateCall() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	mediaURL := srv.URL + "/v1/calls/" + call.ID + "/media"
+
+	// Not yet accepted.
+	res := postJSON(t, client, mediaURL, map[string]any{"mediaType": "video"}, callerToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("media before accept status = %d, want %d", res.StatusCode, http.StatusConflict)
+	}
+
+	if _, err := store.AcceptCall(ctx, call.ID, callee.ID, nowMs); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+
+	res = postJSON(t, client, mediaURL, map[string]any{"mediaType": "video"}, outsiderToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider media status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = postJSON(t, client, mediaURL, map[string]any{"mediaType": "hologram"}, callerToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid mediaType status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	callerWS, _, err := websocket.DefaultDialer.Dial(wsURL+callerToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial caller error = %v", err)
+	}
+	defer callerWS.Close()
+	calleeWS, _, err := websocket.DefaultDialer.Dial(wsURL+calleeToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial callee error = %v", err)
+	}
+	defer calleeWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res = postJSON(t, client, mediaURL, map[string]any{"mediaType": "video"}, callerToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("media status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body struct {
+		Call struct {
+			MediaType string `json:"mediaType"`
+		} `json:"call"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode media response error = %v", err)
+	}
+	if body.Call.MediaType != storage.CallMediaTypeVideo {
+		t.Fatalf("mediaType = %q, want video", body.Call.MediaType)
+	}
+
+	for name, c := range map[string]*websocket.Conn{"caller": callerWS, "callee": calleeWS} {
+		if env := readWSEvent(t, c); env.Type != "call.media.changed" {
+			t.Fatalf("%s ws event type = %q, want call.media.changed", name, env.Type)
+		}
+	}
+
+	stored, err := store.GetCallByID(ctx, call.ID)
+	if err != nil {
+		t.Fatalf("GetCallByID() error = %v", err)
+	}
+	if stored.MediaType != storage.CallMediaTypeVideo {
+		t.Fatalf("stored media_type = %q, want video", stored.MediaType)
+	}
+}
+
+func TestCalls_FinishedCallsPostSystemMessage(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	caller, callerToken := newTestUser(t, store, tokenToUserID, "caller", nowMs)
+	callee, calleeToken := newTestUser(t, store, tokenToUserID, "callee", nowMs)
+
+	session, _, err := store.CreateSession(ctx, caller.ID, callee.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	ended, err := store.CreateCall(ctx, caller.ID, callee.ID, storage.CallMediaTypeVoice, "123456789012345678", nowMs-140_000)
+	if err != nil {
+		t.Fatalf("CreateCall(ended) error = %v", err)
+	}
+	if _, err := store.AcceptCall(ctx, ended.ID, callee.ID, time.Now().UnixMilli()-133_000); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	calleeWS, _, err := websocket.DefaultDialer.Dial(wsURL+calleeToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial callee error = %v", err)
+	}
+	defer calleeWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res := postJSON(t, client, srv.URL+"/v1/calls/"+ended.ID+"/end", map[string]any{}, callerToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("end call status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if env := readWSEvent(t, calleeWS); env.Type != "call.ended" {
+		t.Fatalf("first ws event type = %q, want call.ended", env.Type)
+	}
+	if env := readWSEvent(t, calleeWS); env.Type != "message.created" || env.SessionID != session.ID {
+		t.Fatalf("second ws event = %+v, want message.created for the session", env)
+	}
+
+	rejected, err := store.CreateCall(ctx, callee.ID, caller.ID, storage.CallMediaTypeVideo, "123456789012345679", nowMs)
+	if err != nil {
+		t.Fatalf("CreateCall(rejected) error = %v", err)
+	}
+	res = postJSON(t, client, srv.URL+"/v1/calls/"+rejected.ID+"/reject", map[string]any{}, callerToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("reject call status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	msgRes := get(t, client, srv.URL+"/v1/sessions/"+session.ID+"/messages", calleeToken)
+	defer msgRes.Body.Close()
+	var list listMessagesResponse
+	if err := json.NewDecoder(msgRes.Body).Decode(&list); err != nil {
+		t.Fatalf("decode messages: %v", err)
+	}
+	texts := map[string]bool{}
+	for _, m := range list.Messages {
+		if m.Type != storage.MessageTypeSystem {
+			t.Fatalf("message %s type = %q, want system", m.ID, m.Type)
+		}
+		texts[m.Text] = true
+	}
+	if len(list.Messages) != 2 || !texts["语音通话 2分13秒"] || !texts["视频通话已拒绝"] {
+		t.Fatalf("call summaries = %v, want the ended and rejected calls", texts)
+	}
+}
+
+func TestCalls_MissedCallSummaryRenderedPerViewer(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	caller, callerToken := newTestUser(t, store, tokenToUserID, "caller", nowMs)
+	callee, calleeToken := newTestUser(t, store, tokenToUserID, "callee", nowMs)
+
+	session, _, err := store.CreateSession(ctx, caller.ID, callee.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, caller.ID, callee.ID, storage.CallMediaTypeVideo, "123456789012345678", nowMs-60_000); err != nil {
+		t.Fatalf("CreateCall() error = %v", err)
+	}
+	missed, err := store.TimeoutStaleCalls(ctx, nowMs, 30_000)
+	if err != nil || len(missed) != 1 {
+		t.Fatalf("TimeoutStaleCalls() = %v, %v, want one call", missed, err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	callerWS, _, err := websocket.DefaultDialer.Dial(wsURL+callerToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial caller error = %v", err)
+	}
+	defer callerWS.Close()
+	calleeWS, _, err := websocket.DefaultDialer.Dial(wsURL+calleeToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial callee error = %v", err)
+	}
+	defer calleeWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	PostCallSummary(ctx, logger, store, wsManager, missed[0], nowMs)
+
+	for conn, want := range map[*websocket.Conn]string{callerWS: "me", calleeWS: "peer"} {
+		env := readWSEvent(t, conn)
+		if env.Type != "message.created" || env.SessionID != session.ID {
+			t.Fatalf("ws event = %+v, want message.created for the session", env)
+		}
+		var payload struct {
+			Message messageItem `json:"message"`
+		}
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			t.Fatalf("decode message payload: %v", err)
+		}
+		if payload.Message.Sender != want || payload.Message.Text != "视频通话未接听" {
+			t.Fatalf("message = %+v, want sender %q and the missed call text", payload.Message, want)
+		}
+	}
+}
+
+func TestCalls_MissedCallSummaryAfterCallerBlocked(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	caller, _ := newTestUser(t, store, tokenToUserID, "caller", nowMs)
+	callee, calleeToken := newTestUser(t, store, tokenToUserID, "callee", nowMs)
+
+	session, _, err := store.CreateSession(ctx, caller.ID, callee.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, caller.ID, callee.ID, storage.CallMediaTypeVoice, "123456789012345678", nowMs-60_000); err != nil {
+		t.Fatalf("CreateCall() error = %v", err)
+	}
+	// The callee blocks the caller while the call is still ringing.
+	if _, err := store.BlockUser(ctx, callee.ID, caller.ID, nowMs-30_000); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	missed, err := store.TimeoutStaleCalls(ctx, nowMs, 30_000)
+	if err != nil || len(missed) != 1 {
+		t.Fatalf("TimeoutStaleCalls() = %v, %v, want one call", missed, err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token=" + calleeToken
+	calleeWS, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("ws dial callee error = %v", err)
+	}
+	defer calleeWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	PostCallSummary(ctx, logger, store, wsManager, missed[0], nowMs)
+
+	env := readWSEvent(t, calleeWS)
+	if env.Type != "message.created" || env.SessionID != session.ID {
+		t.Fatalf("ws event = %+v, want message.created for the session", env)
+	}
+	var payload struct {
+		Message messageItem `json:"message"`
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		t.Fatalf("decode message payload: %v", err)
+	}
+	if got := payload.Message; got.Sender != "peer" || got.SenderID != caller.ID || got.Text != "语音通话未接听" {
+		t.Fatalf("message = %+v, want the missed call summary from the caller", got)
+	}
+}
+
+func TestCalls_TURNCredentials_ParticipantsOnly(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	caller, _ := newTestUser(t, store, tokenToUserID, "caller", nowMs)
+	callee, calleeToken := newTestUser(t, store, tokenToUserID, "callee", nowMs)
+	_, outsiderToken := newTestUser(t, store, tokenToUserID, "outsider", nowMs)
+
+	if _, _, err := store.CreateSession(ctx, caller.ID, callee.ID, nowMs); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	call, err := store.CreateCall(ctx, caller.ID, callee.ID, storage.CallMediaTypeVideo, "123456789012345678", nowMs)
+	if err != nil {
+		t.Fatalf("CreateCall() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	turnURL := "/v1/calls/" + call.ID + "/turn"
+
+	unconfigured := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{}))
+	defer unconfigured.Close()
+	res := get(t, unconfigured.Client(), unconfigured.URL+turnURL, calleeToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("unconfigured status = %d, want %d", res.StatusCode, http.StatusNotImplemented)
+	}
+
+	srv := httptest.NewServer(NewHandler(logger, store, wsManager, "", HandlerOptions{
+		TURNSharedSecret:  "s3cret",
+		TURNURIs:          []string{"turn:turn.example.com:3478"},
+		STUNURIs:          []string{"stun:stun.example.com:3478"},
+		TURNCredentialTTL: 10 * time.Minute,
+	}))
+	defer srv.Close()
+	client := srv.Client()
+
+	res = get(t, client, srv.URL+turnURL, outsiderToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res = get(t, client, srv.URL+turnURL, calleeToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("turn status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body turnCredentialsResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode turn response error = %v", err)
+	}
+	if !strings.HasSuffix(body.Username, ":"+callee.ID) || body.TTLSeconds != 600 {
+		t.Fatalf("username = %q, ttl = %d; want <expiry>:%s and 600", body.Username, body.TTLSeconds, callee.ID)
+	}
+	expiresAt := time.UnixMilli(body.ExpiresAtMs)
+	wantUsername, wantCredential := computeTURNCredentials("s3cret", callee.ID, expiresAt)
+	if body.Username != wantUsername || body.Credential != wantCredential {
+		t.Fatalf("credentials = %q/%q, want %q/%q", body.Username, body.Credential, wantUsername, wantCredential)
+	}
+	if len(body.ICEServers) != 2 || body.ICEServers[0].Credential != "" || body.ICEServers[1].Credential != body.Credential {
+		t.Fatalf("iceServers = %+v, want STUN without and TURN with credentials", body.ICEServers)
+	}
+}
+
+// countingTransport answers every request with a WeChat error and counts the calls, so the
+// tests never reach the real WeChat API.
+type countingTransport struct {
+	n atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n.Add(1)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"errcode":40001,"errmsg":"test"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestCalls_OfflineWeChatNotifySkipsOnlineCallee(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	wechatTransport := &countingTransport{}
+	origTransport := http.DefaultTransport
+	http.DefaultTransport = wechatTransport
+	defer func() { http.DefaultTransport = origTransport }()
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, _ := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+	for _, u := range []storage.UserRow{alice, bob, carol} {
+		if _, err := store.UpsertWeChatBinding(ctx, u.ID, "openid-"+u.Username, "sk", nil, nowMs); err != nil {
+			t.Fatalf("UpsertWeChatBinding(%s) error = %v", u.Username, err)
+		}
+	}
+	for _, peer := range []string{bob.ID, carol.ID} {
+		if _, _, err := store.CreateSession(ctx, alice.ID, peer, nowMs); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{
+		WeChatAppID:                   "wx-app",
+		WeChatAppSecret:               "wx-secret",
+		WeChatCallSubscribeTemplateID: "tmpl-call",
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	bobWS, _, err := websocket.DefaultDialer.Dial(wsURL+bobToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial bob error = %v", err)
+	}
+	defer bobWS.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	res := postJSON(t, client, srv.URL+"/v1/calls", map[string]any{"calleeUserId": bob.ID, "mediaType": "voice"}, aliceToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("call bob status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := wechatTransport.n.Load(); n != 0 {
+		t.Fatalf("wechat requests for online callee = %d, want 0", n)
+	}
+
+	// Carol has no socket, so the fallback must reach WeChat.
+	res = postJSON(t, client, srv.URL+"/v1/calls", map[string]any{"calleeUserId": carol.ID, "mediaType": "voice"}, aliceToken)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("call carol status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for wechatTransport.n.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a wechat request for offline callee")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+type cardViewerItem struct {
+	UserID         string  `json:"userId"`
+	DisplayName    string  `json:"displayName"`
+	AvatarURL      *string `json:"avatarUrl,omitempty"`
+	LastViewedAtMs int64   `json:"lastViewedAtMs"`
+	ViewDays       int     `json:"viewDays"`
+}
+
+type listCardViewersResponse struct {
+	TrackingEnabled bool             `json:"trackingEnabled"`
+	Viewers         []cardViewerItem `json:"viewers"`
+}
+
+// handleGetUserCard serves another user's business card and, unless tracking is disabled,
+// records the view for the card owner's viewer list.
+func (api *v1API) handleGetUserCard(w http.ResponseWriter, r *http.Request, targetUserID string) {
+	viewerID := getUserIDFromContext(r.Context())
+	if viewerID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	targetUserID = strings.TrimSpace(targetUserID)
+	if targetUserID == "" {
+		writeAPIError(w, ErrCodeValidation, "user ID is required")
+		return
+	}
+
+	if !api.writeProfile(w, r, "card", targetUserID) {
+		return
+	}
+
+	if api.cardViewTrackingDisabled {
+		return
+	}
+	if err := api.store.RecordCardView(r.Context(), viewerID, targetUserID, time.Now().UnixMilli()); err != nil {
+		api.log(r.Context()).Warn("record card view failed", "error", err, "targetUserID", targetUserID)
+	}
+}
+
+func (api *v1API) handleListCardViewers(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	if api.cardViewTrackingDisabled {
+		writeJSON(w, http.StatusOK, listCardViewersResponse{TrackingEnabled: false, Viewers: []cardViewerItem{}})
+		return
+	}
+
+	rows, err := api.store.ListCardViewers(r.Context(), userID, 50)
+	if err != nil {
+		api.log(r.Context()).Error("list card viewers failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]cardViewerItem, 0, len(rows))
+	for _, v := range rows {
+		items = append(items, cardViewerItem{
+			UserID:         v.ViewerID,
+			DisplayName:    v.DisplayName,
+			AvatarURL:      v.AvatarURL,
+			LastViewedAtMs: v.LastViewedAtMs,
+			ViewDays:       v.ViewDays,
+		})
+	}
+	writeJSON(w, http.StatusOK, listCardViewersResponse{TrackingEnabled: true, Viewers: items})
+}
@@ -0,0 +1,129 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+)
+
+type getConversationResponse struct {
+	Session      sessionListItem `json:"session"`
+	Messages     []messageItem   `json:"messages"`
+	HasMore      bool            `json:"hasMore"`
+	ServerTimeMs int64           `json:"serverTimeMs"`
+}
+
+func (api *v1API) handleConversations(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/conversations/")
+	parts := splitPath(rest)
+	if len(parts) != 1 {
+		writeAPIError(w, ErrCodeNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		api.handleGetConversation(w, r, parts[0])
+	default:
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleGetConversation returns everything needed to render a direct chat on first open.
+// Clients should switch to the granular session and message endpoints for later updates.
+func (api *v1API) handleGetConversation(w http.ResponseWriter, r *http.Request, sessionID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		writeAPIError(w, ErrCodeValidation, "invalid sessionId")
+		return
+	}
+
+	limit := 0
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 50 {
+			writeAPIError(w, ErrCodeValidation, "limit must be between 1 and 50")
+			return
+		}
+		limit = n
+	}
+
+	conv, err := api.store.GetConversation(r.Context(), sessionID, userID, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			writeAPIError(w, ErrCodeSessionNotFound, "session not found")
+		case errors.Is(err, storage.ErrAccessDenied):
+			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+		case errors.Is(err, storage.ErrInvalidState):
+			writeAPIError(w, ErrCodeValidation, "not a direct session")
+		default:
+			api.log(r.Context()).Error("get conversation failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+		}
+		return
+	}
+
+	items, err := api.messageItemsFromRows(r.Context(), conv.Messages, userID)
+	if err != nil {
+		api.log(r.Context()).Error("get burn messages failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	online, err := api.onlineStatus(r.Context(), userID, []string{conv.Peer.ID})
+	if err != nil {
+		api.log(r.Context()).Error("get peer presence failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	s := conv.Session
+	session := sessionListItem{
+		ID:   s.ID,
+		Kind: s.Kind,
+		Peer: &peerItem{
+			ID:          conv.Peer.ID,
+			Username:    conv.Peer.Username,
+			DisplayName: conv.Peer.DisplayName,
+			AvatarURL:   conv.Peer.AvatarURL,
+		},
+		PeerOnline:      online[conv.Peer.ID],
+		Status:          s.Status,
+		Source:          s.Source,
+		LastMessageText: s.LastMessageText,
+		LastMessageAtMs: s.LastMessageAtMs,
+		UpdatedAtMs:     s.UpdatedAtMs,
+	}
+	if n, err := api.store.CountUnreadMessages(r.Context(), s.ID, userID); err == nil {
+		session.UnreadCount = n
+	} else {
+		api.log(r.Context()).Warn("count unread messages failed", "error", err, "sessionID", s.ID)
+	}
+	if meta := conv.Meta; meta != nil {
+		session.Relationship = &relationshipSummaryItem{
+			Note:        meta.Note,
+			GroupID:     meta.GroupID,
+			GroupName:   meta.GroupName,
+			Tags:        storage.ParseTagsJSON(meta.TagsJSON),
+			UpdatedAtMs: meta.UpdatedAtMs,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, getConversationResponse{
+		Session:      session,
+		Messages:     items,
+		HasMore:      conv.HasMore,
+		ServerTimeMs: time.Now().UnixMilli(),
+	})
+}
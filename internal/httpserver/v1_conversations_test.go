@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestConversations_GetAggregatesSessionPeerMetaAndMessages(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bobby", nowMs)
+	_, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	for _, text := range []string{"one", "two", "three"} {
+		res := postJSON(t, client, srv.URL+"/v1/sessions/"+session.ID+"/messages", map[string]any{
+			"type": "text",
+			"text": text,
+		}, aliceToken)
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("POST messages status = %d, body=%s", res.StatusCode, string(b))
+		}
+		_ = res.Body.Close()
+	}
+
+	relRes := putJSON(t, client, srv.URL+"/v1/sessions/"+session.ID+"/relationship", map[string]any{
+		"note": "met at the park",
+	}, aliceToken)
+	if relRes.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(relRes.Body)
+		t.Fatalf("PUT relationship status = %d, body=%s", relRes.StatusCode, string(b))
+	}
+	_ = relRes.Body.Close()
+
+	res := get(t, client, srv.URL+"/v1/conversations/"+session.ID+"?limit=2", aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("GET conversation status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body struct {
+		Session struct {
+			ID   string `json:"id"`
+			Peer struct {
+				ID string `json:"id"`
+			} `json:"peer"`
+			Relationship *struct {
+				Note *string `json:"note"`
+			} `json:"relationship"`
+		} `json:"session"`
+		Messages []struct {
+			Text   string `json:"text"`
+			Sender string `json:"sender"`
+		} `json:"messages"`
+		HasMore      bool  `json:"hasMore"`
+		ServerTimeMs int64 `json:"serverTimeMs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode conversation response error = %v", err)
+	}
+	if body.Session.ID != session.ID || body.Session.Peer.ID != bob.ID {
+		t.Fatalf("session = %+v, want id %s with peer %s", body.Session, session.ID, bob.ID)
+	}
+	if body.Session.Relationship == nil || body.Session.Relationship.Note == nil || *body.Session.Relationship.Note != "met at the park" {
+		t.Fatalf("relationship = %+v, want note", body.Session.Relationship)
+	}
+	if len(body.Messages) != 2 || body.Messages[0].Text != "two" || body.Messages[1].Text != "three" {
+		t.Fatalf("messages = %+v, want [two three]", body.Messages)
+	}
+	if body.Messages[0].Sender != "me" {
+		t.Fatalf("sender = %q, want me", body.Messages[0].Sender)
+	}
+	if !body.HasMore {
+		t.Fatalf("hasMore = false, want true")
+	}
+	if body.ServerTimeMs == 0 {
+		t.Fatalf("serverTimeMs missing")
+	}
+
+	outsider := get(t, client, srv.URL+"/v1/conversations/"+session.ID, carolToken)
+	defer outsider.Body.Close()
+	if outsider.StatusCode != http.StatusForbidden {
+		t.Fatalf("outsider status = %d, want %d", outsider.StatusCode, http.StatusForbidden)
+	}
+
+	missing := get(t, client, srv.URL+"/v1/conversations/nope", aliceToken)
+	defer missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing status = %d, want %d", missing.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,250 @@
+package httpserver
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+)
+
+type exportTokenRequest struct {
+	Password string `json:"password"`
+}
+
+type exportTokenResponse struct {
+	Token       string `json:"token"`
+	ExpiresAtMs int64  `json:"expiresAtMs"`
+}
+
+type exportProfile struct {
+	ID          string  `json:"id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"displayName"`
+	AvatarURL   *string `json:"avatarUrl,omitempty"`
+	CreatedAtMs int64   `json:"createdAtMs"`
+	UpdatedAtMs int64   `json:"updatedAtMs"`
+}
+
+type exportSession struct {
+	ID              string `json:"id"`
+	Kind            string `json:"kind"`
+	Source          string `json:"source"`
+	Status          string `json:"status"`
+	PeerUserID      string `json:"peerUserId,omitempty"`
+	Title           string `json:"title,omitempty"`
+	CreatedAtMs     int64  `json:"createdAtMs"`
+	UpdatedAtMs     int64  `json:"updatedAtMs"`
+	LastMessageAtMs *int64 `json:"lastMessageAtMs,omitempty"`
+}
+
+type exportMessage struct {
+	ID               string          `json:"id"`
+	SessionID        string          `json:"sessionId"`
+	SenderID         string          `json:"senderId"`
+	Type             string          `json:"type"`
+	Text             *string         `json:"text,omitempty"`
+	Meta             json.RawMessage `json:"meta,omitempty"`
+	CreatedAtMs      int64           `json:"createdAtMs"`
+	EditedAtMs       *int64          `json:"editedAtMs,omitempty"`
+	ReplyToMessageID *string         `json:"replyToMessageId,omitempty"`
+}
+
+// handleCreateExportToken re-checks the caller's password and returns a short-lived,
+// single-use token that authorizes one GET /v1/users/me/export.
+func (api *v1API) handleCreateExportToken(w http.ResponseWriter, r *http.Request) {
+	currentUserID := getUserIDFromContext(r.Context())
+	if currentUserID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req exportTokenRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.Password == "" {
+		writeAPIError(w, ErrCodeValidation, "password is required")
+		return
+	}
+
+	user, err := api.store.GetUserByID(r.Context(), currentUserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(r.Context()).Error("get user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	if !api.verifyPasswordThrottled(w, user, req.Password) {
+		return
+	}
+
+	token, expiresAt, err := api.exportConfirmations.issue(currentUserID)
+	if err != nil {
+		api.log(r.Context()).Error("issue export confirmation failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, exportTokenResponse{Token: token, ExpiresAtMs: expiresAt.UnixMilli()})
+}
+
+// handleExportMe streams a zip of the caller's data: their profile, their contacts, the
+// metadata of the sessions they can read and the messages in those sessions. Burn-after-
+// reading messages, deleted messages and other users' settings are left out.
+//
+// Everything except the messages is loaded before the response starts so those failures
+// still get a JSON error. A failure while streaming messages can only abort the response,
+// which leaves the zip without its central directory and therefore unreadable.
+func (api *v1API) handleExportMe(w http.ResponseWriter, r *http.Request) {
+	currentUserID := getUserIDFromContext(r.Context())
+	if currentUserID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	confirm := strings.TrimSpace(r.URL.Query().Get("confirm"))
+	if confirm == "" || !api.exportConfirmations.consume(confirm, currentUserID) {
+		writeAPIError(w, ErrCodeExportConfirmationInvalid, "export confirmation is missing, expired or already used")
+		return
+	}
+
+	ctx := r.Context()
+	user, err := api.store.GetUserByID(ctx, currentUserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(ctx).Error("get user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	contactIDs, err := api.store.ListContactUserIDs(ctx, currentUserID)
+	if err != nil {
+		api.log(ctx).Error("list contacts failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	contacts, err := api.store.GetUsersByIDs(ctx, contactIDs)
+	if err != nil {
+		api.log(ctx).Error("get contacts failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	friends := make([]userItem, 0, len(contactIDs))
+	for _, id := range contactIDs {
+		if u, ok := contacts[id]; ok {
+			friends = append(friends, userItem{ID: u.ID, Username: u.Username, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL})
+		}
+	}
+
+	sessionRows, err := api.store.ListExportSessions(ctx, currentUserID)
+	if err != nil {
+		api.log(ctx).Error("list export sessions failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	var groupIDs []string
+	for _, s := range sessionRows {
+		if s.Kind == storage.SessionKindGroup {
+			groupIDs = append(groupIDs, s.ID)
+		}
+	}
+	groups, err := api.store.GetGroupSessions(ctx, groupIDs)
+	if err != nil {
+		api.log(ctx).Error("get group sessions failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	sessions := make([]exportSession, 0, len(sessionRows))
+	for _, s := range sessionRows {
+		item := exportSession{
+			ID:              s.ID,
+			Kind:            s.Kind,
+			Source:          s.Source,
+			Status:          s.Status,
+			PeerUserID:      api.store.GetPeerUserID(s, currentUserID),
+			CreatedAtMs:     s.CreatedAtMs,
+			UpdatedAtMs:     s.UpdatedAtMs,
+			LastMessageAtMs: s.LastMessageAtMs,
+		}
+		if g, ok := groups[s.ID]; ok {
+			item.Title = g.Title
+		}
+		sessions = append(sessions, item)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="linkbridge-export-%s.zip"`, time.Now().Format("20060102")))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	err = writeZipJSON(zw, "profile.json", exportProfile{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		CreatedAtMs: user.CreatedAtMs,
+		UpdatedAtMs: user.UpdatedAtMs,
+	})
+	if err == nil {
+		err = writeZipJSON(zw, "friends.json", friends)
+	}
+	if err == nil {
+		err = writeZipJSON(zw, "sessions.json", sessions)
+	}
+	if err == nil {
+		err = writeZipMessages(ctx, zw, api.store, currentUserID)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		api.log(ctx).Error("write user export failed", "error", err)
+	}
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeZipMessages writes messages.ndjson, one JSON object per line, as the store pages
+// through them.
+func writeZipMessages(ctx context.Context, zw *zip.Writer, store Store, userID string) error {
+	f, err := zw.Create("messages.ndjson")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	return store.ForEachExportMessage(ctx, userID, func(m storage.MessageRow) error {
+		return enc.Encode(exportMessage{
+			ID:               m.ID,
+			SessionID:        m.SessionID,
+			SenderID:         m.SenderID,
+			Type:             m.Type,
+			Text:             m.Text,
+			Meta:             json.RawMessage(m.MetaJSON),
+			CreatedAtMs:      m.CreatedAtMs,
+			EditedAtMs:       m.EditedAtMs,
+			ReplyToMessageID: m.ReplyToMessageID,
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+type groupSessionItem struct {
+	Title       string `json:"title"`
+	CreatorID   string `json:"creatorId"`
+	MemberCount int    `json:"memberCount"`
+}
+
+func groupSessionItemFromRow(row storage.GroupSessionRow) *groupSessionItem {
+	return &groupSessionItem{
+		Title:       row.Title,
+		CreatorID:   row.CreatorID,
+		MemberCount: row.MemberCount,
+	}
+}
+
+type createGroupSessionRequest struct {
+	Title     string   `json:"title"`
+	MemberIDs []string `json:"memberIds"`
+}
+
+func (api *v1API) handleGroupSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req createGroupSessionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		writeAPIError(w, ErrCodeValidation, "title is required")
+		return
+	}
+	if utf8.RuneCountInString(req.Title) > storage.MaxGroupSessionTitleLen {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("title must be at most %d characters", storage.MaxGroupSessionTitleLen))
+		return
+	}
+
+	session, group, err := api.store.CreateGroupSession(r.Context(), userID, req.MemberIDs, req.Title, time.Now().UnixMilli())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "member user not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeValidation, fmt.Sprintf("a group needs 2 to %d other members", storage.MaxGroupSessionMembers-1))
+			return
+		}
+		if errors.Is(err, storage.ErrBlocked) {
+			writeAPIError(w, ErrCodeBlocked, "a member has blocked you or is blocked by you")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeSessionAccessDenied, "members must be your contacts")
+			return
+		}
+		api.log(r.Context()).Error("create group session failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	resp := createSessionResponse{
+		Session: sessionListItem{
+			ID:          session.ID,
+			Kind:        session.Kind,
+			Group:       groupSessionItemFromRow(group),
+			Status:      session.Status,
+			Source:      session.Source,
+			UpdatedAtMs: session.UpdatedAtMs,
+		},
+		Created: true,
+	}
+	writeJSON(w, http.StatusOK, resp)
+
+	api.sendToUsers(api.activeParticipantIDs(r.Context(), session.ID), ws.Envelope{
+		Type:      "session.created",
+		SessionID: session.ID,
+		Payload: map[string]any{
+			"session": resp.Session,
+		},
+	})
+}
@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestGroupSessions_MessagesCarrySenderNames(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+	for _, peer := range []storage.UserRow{bob, carol} {
+		if _, _, err := store.CreateSession(ctx, alice.ID, peer.ID, nowMs); err != nil {
+			t.Fatalf("CreateSession(alice, %s) error = %v", peer.Username, err)
+		}
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/group-sessions", map[string]any{
+		"title":     "Weekend",
+		"memberIds": []string{bob.ID, carol.ID},
+	}, aliceToken)
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST group-sessions status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var created createSessionResponse
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatalf("decode create response: %v", err)
+	}
+	_ = res.Body.Close()
+	if created.Session.Kind != storage.SessionKindGroup || created.Session.Group == nil || created.Session.Group.MemberCount != 3 {
+		t.Fatalf("created session = %+v, want group with 3 members", created.Session)
+	}
+	sessionID := created.Session.ID
+
+	for _, m := range []struct{ token, text string }{{aliceToken, "hi all"}, {bobToken, "hey"}} {
+		res := postJSON(t, client, srv.URL+"/v1/sessions/"+sessionID+"/messages", map[string]any{
+			"type": "text",
+			"text": m.text,
+		}, m.token)
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("POST messages status = %d, body=%s", res.StatusCode, string(b))
+		}
+		_ = res.Body.Close()
+	}
+
+	listRes := get(t, client, srv.URL+"/v1/sessions?status=active", carolToken)
+	var sessions listSessionsResponse
+	if err := json.NewDecoder(listRes.Body).Decode(&sessions); err != nil {
+		t.Fatalf("decode sessions: %v", err)
+	}
+	_ = listRes.Body.Close()
+	if len(sessions.Sessions) != 2 || sessions.Sessions[0].ID != sessionID || sessions.Sessions[0].Peer != nil || sessions.Sessions[0].Group == nil {
+		t.Fatalf("carol sessions = %+v, want the group without a peer ahead of the direct session", sessions.Sessions)
+	}
+
+	msgRes := get(t, client, srv.URL+"/v1/sessions/"+sessionID+"/messages", carolToken)
+	defer msgRes.Body.Close()
+	if msgRes.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(msgRes.Body)
+		t.Fatalf("GET messages status = %d, body=%s", msgRes.StatusCode, string(b))
+	}
+	var list listMessagesResponse
+	if err := json.NewDecoder(msgRes.Body).Decode(&list); err != nil {
+		t.Fatalf("decode messages: %v", err)
+	}
+	want := map[string]string{alice.ID: "alice", bob.ID: "bob"}
+	if len(list.Messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(list.Messages))
+	}
+	for _, m := range list.Messages {
+		if m.SenderDisplayName != want[m.SenderID] {
+			t.Fatalf("message %s senderDisplayName = %q, want %q", m.ID, m.SenderDisplayName, want[m.SenderID])
+		}
+	}
+}
@@ -14,6 +14,7 @@ type homeBaseItem struct {
 	Lat            float64 `json:"lat"`
 	Lng            float64 `json:"lng"`
 	RadiusM        int     `json:"radiusM"`
+	AreaLabel      *string `json:"areaLabel,omitempty"`
 	LastUpdatedYMD int     `json:"lastUpdatedYmd"`
 	UpdatedAtMs    int64   `json:"updatedAtMs"`
 }
@@ -22,12 +23,17 @@ type getHomeBaseResponse struct {
 	HomeBase *homeBaseItem `json:"homeBase,omitempty"`
 }
 
+// upsertHomeBaseRequest.AreaLabel is a client-resolved place name; an empty string clears it.
 type upsertHomeBaseRequest struct {
-	Lat     float64 `json:"lat"`
-	Lng     float64 `json:"lng"`
-	RadiusM *int    `json:"radiusM,omitempty"`
+	Lat       float64 `json:"lat"`
+	Lng       float64 `json:"lng"`
+	RadiusM   *int    `json:"radiusM,omitempty"`
+	AreaLabel *string `json:"areaLabel,omitempty"`
 }
 
+// maxHomeBaseAreaLabelLen caps the client-supplied area label, in characters.
+const maxHomeBaseAreaLabelLen = 64
+
 func (api *v1API) handleHomeBase(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -52,7 +58,7 @@ func (api *v1API) handleGetHomeBase(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusOK, getHomeBaseResponse{HomeBase: nil})
 			return
 		}
-		api.logger.Error("get home base failed", "error", err)
+		api.log(r.Context()).Error("get home base failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -62,6 +68,7 @@ func (api *v1API) handleGetHomeBase(w http.ResponseWriter, r *http.Request) {
 			Lat:            e7ToFloat(hb.LatE7),
 			Lng:            e7ToFloat(hb.LngE7),
 			RadiusM:        hb.VisibilityRadiusM,
+			AreaLabel:      hb.AreaLabel,
 			LastUpdatedYMD: hb.LastUpdatedYMD,
 			UpdatedAtMs:    hb.UpdatedAtMs,
 		},
@@ -91,6 +98,10 @@ func (api *v1API) handleUpsertHomeBase(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if req.AreaLabel != nil && len([]rune(strings.TrimSpace(*req.AreaLabel))) > maxHomeBaseAreaLabelLen {
+		writeAPIError(w, ErrCodeValidation, "areaLabel too long")
+		return
+	}
 
 	nowMs := time.Now().UnixMilli()
 	hb, err := api.store.UpsertHomeBase(r.Context(), userID, floatToE7(req.Lat), floatToE7(req.Lng), req.RadiusM, nowMs)
@@ -99,16 +110,28 @@ func (api *v1API) handleUpsertHomeBase(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeHomeBaseUpdateLimited, "home base can only be updated 3 times per day (0:00 reset)")
 			return
 		}
-		api.logger.Error("upsert home base failed", "error", err)
+		api.log(r.Context()).Error("upsert home base failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
+	if req.AreaLabel != nil {
+		if err := api.store.SetHomeBaseAreaLabel(r.Context(), userID, req.AreaLabel); err != nil {
+			api.log(r.Context()).Error("set home base area label failed", "error", err)
+			writeAPIError(w, ErrCodeInternal, "internal error")
+			return
+		}
+		hb.AreaLabel = nil
+		if label := strings.TrimSpace(*req.AreaLabel); label != "" {
+			hb.AreaLabel = &label
+		}
+	}
 
 	writeJSON(w, http.StatusOK, getHomeBaseResponse{
 		HomeBase: &homeBaseItem{
 			Lat:            e7ToFloat(hb.LatE7),
 			Lng:            e7ToFloat(hb.LngE7),
 			RadiusM:        hb.VisibilityRadiusM,
+			AreaLabel:      hb.AreaLabel,
 			LastUpdatedYMD: hb.LastUpdatedYMD,
 			UpdatedAtMs:    hb.UpdatedAtMs,
 		},
@@ -2,7 +2,9 @@ package httpserver
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -35,12 +37,18 @@ type createLocalFeedPostRequest struct {
 	IsPinned    *bool    `json:"isPinned,omitempty"`
 }
 
+type updateLocalFeedPostRequest struct {
+	Text     *string `json:"text,omitempty"`
+	IsPinned *bool   `json:"isPinned,omitempty"`
+}
+
 type createLocalFeedPostResponse struct {
 	Post localFeedPostItem `json:"post"`
 }
 
 type listLocalFeedPostsResponse struct {
-	Posts []localFeedPostItem `json:"posts"`
+	Posts      []localFeedPostItem `json:"posts"`
+	NextCursor string              `json:"nextCursor,omitempty"`
 }
 
 type localFeedPinItem struct {
@@ -49,6 +57,7 @@ type localFeedPinItem struct {
 	Lng         float64 `json:"lng"`
 	DisplayName string  `json:"displayName"`
 	AvatarURL   *string `json:"avatarUrl,omitempty"`
+	AreaLabel   *string `json:"areaLabel,omitempty"`
 	UpdatedAtMs int64   `json:"updatedAtMs"`
 }
 
@@ -77,6 +86,16 @@ func (api *v1API) handleLocalFeed(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		api.handleListLocalFeedPins(w, r)
+	case "nearby":
+		if len(parts) != 1 {
+			writeAPIError(w, ErrCodeNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleListNearbyLocalFeedPosts(w, r)
 	case "posts":
 		if len(parts) == 1 {
 			switch r.Method {
@@ -94,6 +113,10 @@ func (api *v1API) handleLocalFeed(w http.ResponseWriter, r *http.Request) {
 			api.handleDeleteLocalFeedPost(w, r, parts[1])
 			return
 		}
+		if len(parts) == 3 && parts[2] == "update" && r.Method == http.MethodPost {
+			api.handleUpdateLocalFeedPost(w, r, parts[1])
+			return
+		}
 		writeAPIError(w, ErrCodeNotFound, "not found")
 	case "users":
 		if len(parts) == 3 && parts[2] == "posts" && r.Method == http.MethodGet {
@@ -130,27 +153,38 @@ func (api *v1API) handleCreateLocalFeedPost(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	radiusM := req.RadiusM
+	if radiusM != nil {
+		if *radiusM <= 0 {
+			writeAPIError(w, ErrCodeValidation, "radiusM must be positive")
+			return
+		}
+		if *radiusM > storage.MaxLocalFeedPostRadiusM {
+			clamped := storage.MaxLocalFeedPostRadiusM
+			radiusM = &clamped
+		}
+	}
+
 	isPinned := false
 	if req.IsPinned != nil {
 		isPinned = *req.IsPinned
 	}
 
-	hasText := req.Text != nil && strings.TrimSpace(*req.Text) != ""
-	hasImage := false
-	for _, u := range req.ImageURLs {
-		if strings.TrimSpace(u) != "" {
-			hasImage = true
-			break
-		}
+	imageURLs, err := api.validateLocalFeedImageURLs(req.ImageURLs)
+	if err != nil {
+		writeAPIError(w, ErrCodeValidation, err.Error())
+		return
 	}
-	if !hasText && !hasImage {
+
+	hasText := req.Text != nil && strings.TrimSpace(*req.Text) != ""
+	if !hasText && len(imageURLs) == 0 {
 		writeAPIError(w, ErrCodeValidation, "text or imageUrls is required")
 		return
 	}
 
-	post, images, err := api.store.CreateLocalFeedPost(r.Context(), userID, req.Text, req.ImageURLs, expiresAtMs, isPinned, nowMs)
+	post, images, err := api.store.CreateLocalFeedPost(r.Context(), userID, req.Text, imageURLs, radiusM, expiresAtMs, isPinned, nowMs)
 	if err != nil {
-		api.logger.Error("create local feed post failed", "error", err)
+		api.log(r.Context()).Error("create local feed post failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -159,6 +193,85 @@ func (api *v1API) handleCreateLocalFeedPost(w http.ResponseWriter, r *http.Reque
 	writeJSON(w, http.StatusOK, createLocalFeedPostResponse{Post: item})
 }
 
+// validateLocalFeedImageURLs drops blank entries and accepts only our own /uploads/ paths
+// or absolute http(s) URLs on a configured media host.
+func (api *v1API) validateLocalFeedImageURLs(raw []string) ([]string, error) {
+	out := make([]string, 0, len(raw))
+	for _, u := range raw {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	if len(out) > storage.MaxLocalFeedPostImages {
+		return nil, fmt.Errorf("at most %d images are allowed", storage.MaxLocalFeedPostImages)
+	}
+
+	for i, raw := range out {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("imageUrls[%d] is not a valid URL", i)
+		}
+		if !u.IsAbs() {
+			if u.Host != "" || !strings.HasPrefix(u.Path, "/uploads/") || strings.Contains(u.Path, "..") {
+				return nil, fmt.Errorf("imageUrls[%d] must be an /uploads/ path", i)
+			}
+			continue
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("imageUrls[%d] must use http or https", i)
+		}
+		if _, ok := api.mediaAllowedHosts[strings.ToLower(u.Hostname())]; !ok {
+			return nil, fmt.Errorf("imageUrls[%d] host is not allowed", i)
+		}
+	}
+	return out, nil
+}
+
+func (api *v1API) handleUpdateLocalFeedPost(w http.ResponseWriter, r *http.Request, postID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	postID = strings.TrimSpace(postID)
+	if postID == "" {
+		writeAPIError(w, ErrCodeValidation, "postId is required")
+		return
+	}
+
+	var req updateLocalFeedPostRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.Text == nil && req.IsPinned == nil {
+		writeAPIError(w, ErrCodeValidation, "text or isPinned is required")
+		return
+	}
+	if req.Text != nil && strings.TrimSpace(*req.Text) == "" {
+		writeAPIError(w, ErrCodeValidation, "text must not be empty")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	post, images, err := api.store.UpdateLocalFeedPost(r.Context(), userID, postID, req.Text, req.IsPinned, nowMs)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeLocalFeedPostNotFound, "post not found")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeValidation, "post has expired")
+			return
+		}
+		api.log(r.Context()).Error("update local feed post failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, createLocalFeedPostResponse{Post: localFeedPostItemFromStorage(post, images)})
+}
+
 func (api *v1API) handleDeleteLocalFeedPost(w http.ResponseWriter, r *http.Request, postID string) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == "" {
@@ -176,7 +289,7 @@ func (api *v1API) handleDeleteLocalFeedPost(w http.ResponseWriter, r *http.Reque
 			writeAPIError(w, ErrCodeLocalFeedPostNotFound, "post not found")
 			return
 		}
-		api.logger.Error("delete local feed post failed", "error", err)
+		api.log(r.Context()).Error("delete local feed post failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -191,10 +304,19 @@ func (api *v1API) handleListMyLocalFeedPosts(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	opts, ok := parseLocalFeedListOptions(w, r)
+	if !ok {
+		return
+	}
+
 	nowMs := time.Now().UnixMilli()
-	posts, err := api.store.ListLocalFeedPostsForSource(r.Context(), userID, nil, nil, nowMs, 50)
+	posts, nextCursor, err := api.store.ListLocalFeedPostsForSource(r.Context(), userID, nil, nil, nowMs, opts)
 	if err != nil {
-		api.logger.Error("list local feed posts failed", "error", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			writeAPIError(w, ErrCodeValidation, "invalid cursor")
+			return
+		}
+		api.log(r.Context()).Error("list local feed posts failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -203,11 +325,38 @@ func (api *v1API) handleListMyLocalFeedPosts(w http.ResponseWriter, r *http.Requ
 	for _, p := range posts {
 		items = append(items, localFeedPostItemFromStorage(p.Post, p.Images))
 	}
-	writeJSON(w, http.StatusOK, listLocalFeedPostsResponse{Posts: items})
+	writeJSON(w, http.StatusOK, listLocalFeedPostsResponse{Posts: items, NextCursor: nextCursor})
+}
+
+// parseLocalFeedListOptions reads ?limit=&cursor=&includeExpired= from the query.
+// Callers decide whether includeExpired is honored for the current viewer.
+func parseLocalFeedListOptions(w http.ResponseWriter, r *http.Request) (storage.LocalFeedListOptions, bool) {
+	query := r.URL.Query()
+	opts := storage.LocalFeedListOptions{
+		Cursor: strings.TrimSpace(query.Get("cursor")),
+	}
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 100 {
+			writeAPIError(w, ErrCodeValidation, "limit must be between 1 and 100")
+			return storage.LocalFeedListOptions{}, false
+		}
+		opts.Limit = limit
+	}
+	if raw := strings.TrimSpace(query.Get("includeExpired")); raw != "" {
+		includeExpired, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeAPIError(w, ErrCodeValidation, "invalid includeExpired")
+			return storage.LocalFeedListOptions{}, false
+		}
+		opts.IncludeExpired = includeExpired
+	}
+	return opts, true
 }
 
 func (api *v1API) handleListLocalFeedPostsForUser(w http.ResponseWriter, r *http.Request, userID string) {
-	if getUserIDFromContext(r.Context()) == "" {
+	viewerID := getUserIDFromContext(r.Context())
+	if viewerID == "" {
 		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
 		return
 	}
@@ -240,14 +389,85 @@ func (api *v1API) handleListLocalFeedPostsForUser(w http.ResponseWriter, r *http
 		atLngE7 = &v
 	}
 
+	opts, ok := parseLocalFeedListOptions(w, r)
+	if !ok {
+		return
+	}
+	// Expired posts are only ever shown back to their author.
+	if viewerID != userID {
+		opts.IncludeExpired = false
+	}
+
 	nowMs := time.Now().UnixMilli()
-	posts, err := api.store.ListLocalFeedPostsForSource(r.Context(), userID, atLatE7, atLngE7, nowMs, 50)
+	posts, nextCursor, err := api.store.ListLocalFeedPostsForSource(r.Context(), userID, atLatE7, atLngE7, nowMs, opts)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			writeJSON(w, http.StatusOK, listLocalFeedPostsResponse{Posts: nil})
 			return
 		}
-		api.logger.Error("list local feed user posts failed", "error", err)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			writeAPIError(w, ErrCodeValidation, "invalid cursor")
+			return
+		}
+		api.log(r.Context()).Error("list local feed user posts failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]localFeedPostItem, 0, len(posts))
+	for _, p := range posts {
+		items = append(items, localFeedPostItemFromStorage(p.Post, p.Images))
+	}
+	writeJSON(w, http.StatusOK, listLocalFeedPostsResponse{Posts: items, NextCursor: nextCursor})
+}
+
+// handleListNearbyLocalFeedPosts serves GET /v1/local-feed/nearby?atLat=&atLng=&radiusM=&limit=,
+// aggregating other users' posts that are visible at the viewer's position.
+func (api *v1API) handleListNearbyLocalFeedPosts(w http.ResponseWriter, r *http.Request) {
+	viewerID := getUserIDFromContext(r.Context())
+	if viewerID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	query := r.URL.Query()
+	lat, err := strconv.ParseFloat(strings.TrimSpace(query.Get("atLat")), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		writeAPIError(w, ErrCodeValidation, "invalid atLat")
+		return
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(query.Get("atLng")), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		writeAPIError(w, ErrCodeValidation, "invalid atLng")
+		return
+	}
+
+	radiusM := storage.MaxLocalFeedPostRadiusM
+	if raw := strings.TrimSpace(query.Get("radiusM")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeAPIError(w, ErrCodeValidation, "invalid radiusM")
+			return
+		}
+		if n < radiusM {
+			radiusM = n
+		}
+	}
+
+	limit := 0
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 100 {
+			writeAPIError(w, ErrCodeValidation, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	nowMs := time.Now().UnixMilli()
+	posts, err := api.store.ListNearbyLocalFeedPosts(r.Context(), viewerID, floatToE7(lat), floatToE7(lng), radiusM, nowMs, limit)
+	if err != nil {
+		api.log(r.Context()).Error("list nearby local feed posts failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -317,7 +537,7 @@ func (api *v1API) handleListLocalFeedPins(w http.ResponseWriter, r *http.Request
 
 	pins, err := api.store.ListLocalFeedPins(r.Context(), minLat, maxLat, minLng, maxLng, centerLat, centerLng, limit)
 	if err != nil {
-		api.logger.Error("list local feed pins failed", "error", err)
+		api.log(r.Context()).Error("list local feed pins failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -330,6 +550,7 @@ func (api *v1API) handleListLocalFeedPins(w http.ResponseWriter, r *http.Request
 			Lng:         e7ToFloat(p.LngE7),
 			DisplayName: p.DisplayName,
 			AvatarURL:   p.AvatarURL,
+			AreaLabel:   p.AreaLabel,
 			UpdatedAtMs: p.UpdatedAtMs,
 		})
 	}
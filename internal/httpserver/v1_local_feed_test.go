@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
@@ -160,3 +161,126 @@ func TestHomeBaseAndLocalFeedEndpoints_Smoke(t *testing.T) {
 		t.Fatalf("GET /v1/local-feed/users/{id}/posts status = %d, want %d, body=%s", listUserRes.StatusCode, http.StatusOK, string(b))
 	}
 }
+
+func TestLocalFeed_CreatePostValidatesImageURLs(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{MediaAllowedHosts: []string{"CDN.example.com"}})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	tooMany := make([]string, 10)
+	for i := range tooMany {
+		tooMany[i] = "/uploads/img" + strconv.Itoa(i) + ".jpg"
+	}
+
+	cases := []struct {
+		name       string
+		imageURLs  []string
+		wantStatus int
+	}{
+		{"ten images", tooMany, http.StatusBadRequest},
+		{"disallowed host", []string{"https://evil.example.net/a.jpg"}, http.StatusBadRequest},
+		{"non-upload path", []string{"/etc/passwd"}, http.StatusBadRequest},
+		{"nine images", tooMany[:9], http.StatusOK},
+		{"allowed host", []string{"https://cdn.example.com/a.jpg", "/uploads/b.png"}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		res := postJSON(t, client, srv.URL+"/v1/local-feed/posts", map[string]any{
+			"imageUrls": tc.imageURLs,
+		}, tok.Token)
+		b, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != tc.wantStatus {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tc.name, res.StatusCode, tc.wantStatus, string(b))
+		}
+	}
+}
+
+func TestLocalFeed_CreatePostUsesRequestedRadius(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	createPost := func(body map[string]any) (int, createLocalFeedPostResponse) {
+		t.Helper()
+		res := postJSON(t, client, srv.URL+"/v1/local-feed/posts", body, tok.Token)
+		defer res.Body.Close()
+		var out createLocalFeedPostResponse
+		if res.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+				t.Fatalf("decode create post response error = %v", err)
+			}
+		}
+		return res.StatusCode, out
+	}
+
+	status, created := createPost(map[string]any{"text": "hello", "radiusM": 500})
+	if status != http.StatusOK {
+		t.Fatalf("create (radiusM=500) status = %d, want %d", status, http.StatusOK)
+	}
+	if created.Post.RadiusM != 500 {
+		t.Fatalf("radiusM = %d, want 500", created.Post.RadiusM)
+	}
+	posts, _, err := store.ListLocalFeedPostsForSource(ctx, u.ID, nil, nil, nowMs, storage.LocalFeedListOptions{})
+	if err != nil {
+		t.Fatalf("ListLocalFeedPostsForSource() error = %v", err)
+	}
+	if len(posts) != 1 || posts[0].Post.RadiusM != 500 {
+		t.Fatalf("stored posts = %+v, want one with radiusM 500", posts)
+	}
+
+	if status, _ := createPost(map[string]any{"text": "bad", "radiusM": -1}); status != http.StatusBadRequest {
+		t.Fatalf("create (radiusM=-1) status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, created = createPost(map[string]any{"text": "far", "radiusM": 1000000})
+	if status != http.StatusOK || created.Post.RadiusM != storage.MaxLocalFeedPostRadiusM {
+		t.Fatalf("create (radiusM=1000000) = %d/%d, want clamped to %d", status, created.Post.RadiusM, storage.MaxLocalFeedPostRadiusM)
+	}
+
+	status, created = createPost(map[string]any{"text": "default"})
+	if status != http.StatusOK || created.Post.RadiusM != 1100 {
+		t.Fatalf("create (no radius) = %d/%d, want default 1100", status, created.Post.RadiusM)
+	}
+}
@@ -0,0 +1,168 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+type messageReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+type messageReactionsResponse struct {
+	MessageID   string         `json:"messageId"`
+	Reactions   map[string]int `json:"reactions"`
+	MyReactions []string       `json:"myReactions"`
+}
+
+// handleMessageReactions adds (POST, emoji in the body) or removes (DELETE, emoji in the
+// body or ?emoji=) the caller's reaction on a message.
+func (api *v1API) handleMessageReactions(w http.ResponseWriter, r *http.Request, sessionID, messageID string) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	sessionID = strings.TrimSpace(sessionID)
+	messageID = strings.TrimSpace(messageID)
+	if sessionID == "" || messageID == "" {
+		writeAPIError(w, ErrCodeValidation, "invalid sessionId or messageId")
+		return
+	}
+
+	var req messageReactionRequest
+	if r.Method == http.MethodDelete && r.ContentLength <= 0 {
+		req.Emoji = r.URL.Query().Get("emoji")
+	} else if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	emoji, ok := storage.NormalizeReactionEmoji(req.Emoji)
+	if !ok {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("emoji must be 1-%d characters without spaces", storage.MaxReactionEmojiLen))
+		return
+	}
+
+	var (
+		changed bool
+		err     error
+	)
+	action := "added"
+	if r.Method == http.MethodPost {
+		changed, err = api.store.AddReaction(r.Context(), sessionID, messageID, userID, emoji, time.Now().UnixMilli())
+	} else {
+		action = "removed"
+		changed, err = api.store.RemoveReaction(r.Context(), sessionID, messageID, userID, emoji)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeMessageNotFound, "message not found")
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+			return
+		}
+		if errors.Is(err, storage.ErrSessionArchived) {
+			writeAPIError(w, ErrCodeSessionArchived, "session is archived")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidState) {
+			writeAPIError(w, ErrCodeValidation, "deleted messages cannot be reacted to")
+			return
+		}
+		if errors.Is(err, storage.ErrReactionLimit) {
+			writeAPIError(w, ErrCodeValidation, fmt.Sprintf("at most %d reactions per message", storage.MaxReactionsPerUserPerMessage))
+			return
+		}
+		api.log(r.Context()).Error("update message reaction failed", "error", err, "action", action)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	byMessage, err := api.store.ListMessageReactions(r.Context(), []string{messageID})
+	if err != nil {
+		api.log(r.Context()).Error("list message reactions failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	counts, mine := aggregateReactions(byMessage[messageID], userID)
+	if counts == nil {
+		counts = map[string]int{}
+	}
+	if mine == nil {
+		mine = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, messageReactionsResponse{
+		MessageID:   messageID,
+		Reactions:   counts,
+		MyReactions: mine,
+	})
+
+	if !changed {
+		return
+	}
+	api.relayMessageEvent(r.Context(), ws.Envelope{
+		Type:      "message.reaction",
+		SessionID: sessionID,
+		Payload: map[string]any{
+			"messageId": messageID,
+			"userId":    userID,
+			"emoji":     emoji,
+			"action":    action,
+			"reactions": counts,
+		},
+	})
+}
+
+// aggregateReactions counts reactions per emoji and collects the ones left by userID.
+// Both results are nil when there are no reactions.
+func aggregateReactions(rows []storage.MessageReactionRow, userID string) (map[string]int, []string) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	counts := make(map[string]int, len(rows))
+	var mine []string
+	for _, row := range rows {
+		counts[row.Emoji]++
+		if row.UserID == userID {
+			mine = append(mine, row.Emoji)
+		}
+	}
+	return counts, mine
+}
+
+// attachReactions fills the reaction summary of items from the stored reactions.
+func (api *v1API) attachReactions(ctx context.Context, items []messageItem, userID string) error {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.DeletedAtMs == nil {
+			ids = append(ids, item.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	byMessage, err := api.store.ListMessageReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Reactions, items[i].MyReactions = aggregateReactions(byMessage[items[i].ID], userID)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+)
+
+type metaResponse struct {
+	MinClientVersion string `json:"minClientVersion,omitempty"`
+	ServerTimeMs     int64  `json:"serverTimeMs"`
+}
+
+// handleMeta is unauthenticated and exempt from the client version check, so clients can
+// always discover whether they need to upgrade.
+func (api *v1API) handleMeta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, metaResponse{
+		MinClientVersion: api.minClientVersion,
+		ServerTimeMs:     time.Now().UnixMilli(),
+	})
+}
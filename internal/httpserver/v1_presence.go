@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// maxPresenceQueryUsers bounds how many users one presence lookup may ask about.
+const maxPresenceQueryUsers = 100
+
+type presenceResponse struct {
+	Online map[string]bool `json:"online"`
+}
+
+// handlePresence reports which of ?userIds=a,b,c currently hold an open WebSocket. Only the
+// caller's contacts are looked up, matching who receives presence events; anyone else,
+// including blocked pairs, is reported offline.
+func (api *v1API) handlePresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	currentUserID := getUserIDFromContext(r.Context())
+	if currentUserID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var userIDs []string
+	for _, id := range strings.Split(r.URL.Query().Get("userIds"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		writeAPIError(w, ErrCodeValidation, "userIds is required")
+		return
+	}
+	if len(userIDs) > maxPresenceQueryUsers {
+		writeAPIError(w, ErrCodeValidation, "too many userIds")
+		return
+	}
+
+	online, err := api.onlineStatus(r.Context(), currentUserID, userIDs)
+	if err != nil {
+		api.log(r.Context()).Error("list presence contacts failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	for _, id := range userIDs {
+		if _, ok := online[id]; !ok {
+			online[id] = false
+		}
+	}
+	writeJSON(w, http.StatusOK, presenceResponse{Online: online})
+}
@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestPresence_OnlyContactsWithoutBlocks(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+	dave, daveToken := newTestUser(t, store, tokenToUserID, "dave", nowMs)
+
+	for _, peer := range []storage.UserRow{bob, dave} {
+		if _, _, err := store.CreateSession(ctx, alice.ID, peer.ID, nowMs); err != nil {
+			t.Fatalf("CreateSession(alice, %s) error = %v", peer.Username, err)
+		}
+	}
+	if _, err := store.BlockUser(ctx, dave.ID, alice.ID, nowMs); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws?token="
+	for _, token := range []string{bobToken, carolToken, daveToken} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+token, nil)
+		if err != nil {
+			t.Fatalf("ws dial error = %v", err)
+		}
+		defer conn.Close()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	res := get(t, srv.Client(), srv.URL+"/v1/presence?userIds="+strings.Join([]string{bob.ID, carol.ID, dave.ID}, ","), aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET presence status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	var body presenceResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode presence: %v", err)
+	}
+	want := map[string]bool{bob.ID: true, carol.ID: false, dave.ID: false}
+	if len(body.Online) != len(want) {
+		t.Fatalf("online = %v, want %v", body.Online, want)
+	}
+	for id, online := range want {
+		if body.Online[id] != online {
+			t.Fatalf("online = %v, want %v", body.Online, want)
+		}
+	}
+}
+
+func TestPresence_BlockedPeerOfflineInSessionListAndConversation(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws?token="+bobToken, nil)
+	if err != nil {
+		t.Fatalf("ws dial error = %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	peerOnline := func() (inList, inConversation bool) {
+		t.Helper()
+		res := get(t, srv.Client(), srv.URL+"/v1/sessions?status=active", aliceToken)
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET sessions status = %d, want %d", res.StatusCode, http.StatusOK)
+		}
+		var list listSessionsResponse
+		if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+			t.Fatalf("decode sessions: %v", err)
+		}
+		if len(list.Sessions) != 1 {
+			t.Fatalf("sessions = %+v, want the session with bob", list.Sessions)
+		}
+
+		convRes := get(t, srv.Client(), srv.URL+"/v1/conversations/"+session.ID, aliceToken)
+		defer convRes.Body.Close()
+		if convRes.StatusCode != http.StatusOK {
+			t.Fatalf("GET conversation status = %d, want %d", convRes.StatusCode, http.StatusOK)
+		}
+		var conv getConversationResponse
+		if err := json.NewDecoder(convRes.Body).Decode(&conv); err != nil {
+			t.Fatalf("decode conversation: %v", err)
+		}
+		return list.Sessions[0].PeerOnline, conv.Session.PeerOnline
+	}
+
+	if inList, inConversation := peerOnline(); !inList || !inConversation {
+		t.Fatalf("peerOnline = %v (list), %v (conversation); want bob online before the block", inList, inConversation)
+	}
+
+	if _, err := store.BlockUser(ctx, bob.ID, alice.ID, nowMs); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	if inList, inConversation := peerOnline(); inList || inConversation {
+		t.Fatalf("peerOnline = %v (list), %v (conversation); want false across a block", inList, inConversation)
+	}
+}
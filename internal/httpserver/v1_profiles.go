@@ -33,6 +33,14 @@ type getProfileResponse struct {
 func (api *v1API) handleProfiles(w http.ResponseWriter, r *http.Request) {
 	rest := strings.TrimPrefix(r.URL.Path, "/v1/profiles/")
 	parts := splitPath(rest)
+	if len(parts) == 2 && parts[0] == "card" && parts[1] == "viewers" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleListCardViewers(w, r)
+		return
+	}
 	if len(parts) != 1 {
 		writeAPIError(w, ErrCodeNotFound, "not found")
 		return
@@ -66,22 +74,28 @@ func (api *v1API) handleGetProfile(w http.ResponseWriter, r *http.Request, kind
 		return
 	}
 
+	api.writeProfile(w, r, kind, userID)
+}
+
+// writeProfile renders userID's profile of the given kind. It is shared by the owner's own
+// view and the cross-user card view. Both are polled, so they honor If-None-Match.
+func (api *v1API) writeProfile(w http.ResponseWriter, r *http.Request, kind, userID string) bool {
 	user, err := api.store.GetUserByID(r.Context(), userID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			writeAPIError(w, ErrCodeUserNotFound, "user not found")
-			return
+			return false
 		}
-		api.logger.Error("get user failed", "error", err)
+		api.log(r.Context()).Error("get user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
-		return
+		return false
 	}
 
 	profile, err := api.getProfileRow(r, kind, userID)
 	if err != nil {
-		api.logger.Error("get profile failed", "error", err, "kind", kind)
+		api.log(r.Context()).Error("get profile failed", "error", err, "kind", kind)
 		writeAPIError(w, ErrCodeInternal, "internal error")
-		return
+		return false
 	}
 
 	core := profileCoreItem{
@@ -101,7 +115,7 @@ func (api *v1API) handleGetProfile(w http.ResponseWriter, r *http.Request, kind
 	}
 
 	fields := normalizeRawJSONObject(profile.ProfileJSON)
-	writeJSON(w, http.StatusOK, getProfileResponse{
+	writeJSONConditional(w, r, getProfileResponse{
 		Core: core,
 		Profile: profileItem{
 			Nickname:          resolvedNickname,
@@ -112,6 +126,7 @@ func (api *v1API) handleGetProfile(w http.ResponseWriter, r *http.Request, kind
 			UpdatedAtMs:       profile.UpdatedAtMs,
 		},
 	})
+	return true
 }
 
 func (api *v1API) handleUpsertProfile(w http.ResponseWriter, r *http.Request, kind string) {
@@ -129,7 +144,7 @@ func (api *v1API) handleUpsertProfile(w http.ResponseWriter, r *http.Request, ki
 
 	existing, err := api.getProfileRow(r, kind, userID)
 	if err != nil {
-		api.logger.Error("get existing profile failed", "error", err, "kind", kind)
+		api.log(r.Context()).Error("get existing profile failed", "error", err, "kind", kind)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -167,13 +182,13 @@ func (api *v1API) handleUpsertProfile(w http.ResponseWriter, r *http.Request, ki
 	switch kind {
 	case "card":
 		if _, err := api.store.UpsertUserCardProfile(r.Context(), userID, nicknameOverride, avatarOverride, profileJSON, nowMs); err != nil {
-			api.logger.Error("upsert card profile failed", "error", err)
+			api.log(r.Context()).Error("upsert card profile failed", "error", err)
 			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
 	case "map":
 		if _, err := api.store.UpsertUserMapProfile(r.Context(), userID, nicknameOverride, avatarOverride, profileJSON, nowMs); err != nil {
-			api.logger.Error("upsert map profile failed", "error", err)
+			api.log(r.Context()).Error("upsert map profile failed", "error", err)
 			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
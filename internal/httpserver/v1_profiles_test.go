@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
@@ -227,3 +228,133 @@ func TestProfiles_CardAndMap_PatchSemantics(t *testing.T) {
 		t.Fatalf("map profile.nickname = %q, want %q", mapProfile.Profile.Nickname, "MapNick")
 	}
 }
+
+func TestProfiles_CardViewers_PeersOnlyDedupedPerDay(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bobby", nowMs)
+	_, carolToken := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	if _, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	cardURL := srv.URL + "/v1/users/" + alice.ID + "/profiles/card"
+	for _, tok := range []string{bobToken, bobToken, carolToken, aliceToken} {
+		res := get(t, client, cardURL, tok)
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("GET card status = %d, body=%s", res.StatusCode, string(b))
+		}
+		_ = res.Body.Close()
+	}
+
+	res := get(t, client, srv.URL+"/v1/profiles/card/viewers", aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("GET viewers status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body listCardViewersResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode viewers error = %v", err)
+	}
+	if !body.TrackingEnabled {
+		t.Fatalf("trackingEnabled = false, want true")
+	}
+	// Carol has no session with Alice and Alice's own view is not counted.
+	if len(body.Viewers) != 1 || body.Viewers[0].UserID != bob.ID || body.Viewers[0].ViewDays != 1 {
+		t.Fatalf("viewers = %+v, want only bob once", body.Viewers)
+	}
+}
+
+func TestProfiles_ConditionalGetReturnsNotModified(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	tokenToUserID := map[string]string{}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+		"username":    "alice",
+		"password":    "P@ssw0rd1",
+		"displayName": "Alice",
+	}, "")
+	var registered authResponse
+	if err := json.NewDecoder(res.Body).Decode(&registered); err != nil {
+		t.Fatalf("decode register response error = %v", err)
+	}
+	_ = res.Body.Close()
+	token := registered.Token
+
+	getCard := func(ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/profiles/card", nil)
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET /v1/profiles/card error = %v", err)
+		}
+		_ = res.Body.Close()
+		return res
+	}
+
+	first := getCard("")
+	etag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("GET /v1/profiles/card status = %d, ETag = %q, want 200 with an ETag", first.StatusCode, etag)
+	}
+
+	unchanged := getCard(etag)
+	if unchanged.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional GET status = %d, want 304", unchanged.StatusCode)
+	}
+
+	putRes := putJSON(t, client, srv.URL+"/v1/profiles/card", map[string]any{
+		"nicknameOverride": "Ally",
+	}, token)
+	_ = putRes.Body.Close()
+	if putRes.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /v1/profiles/card status = %d, want 200", putRes.StatusCode)
+	}
+
+	changed := getCard(etag)
+	if changed.StatusCode != http.StatusOK {
+		t.Fatalf("conditional GET after update status = %d, want 200", changed.StatusCode)
+	}
+	if got := changed.Header.Get("ETag"); got == etag {
+		t.Fatalf("ETag after update = %q, want it to change", got)
+	}
+}
@@ -14,6 +14,9 @@ type relationshipGroupItem struct {
 	Name        string `json:"name"`
 	CreatedAtMs int64  `json:"createdAtMs"`
 	UpdatedAtMs int64  `json:"updatedAtMs"`
+	// SessionCount and LastActivityAtMs are only filled in by the group listing.
+	SessionCount     int    `json:"sessionCount"`
+	LastActivityAtMs *int64 `json:"lastActivityAtMs,omitempty"`
 }
 
 type listRelationshipGroupsResponse struct {
@@ -33,6 +36,15 @@ type renameRelationshipGroupRequest struct {
 	Name string `json:"name"`
 }
 
+type relationshipTagItem struct {
+	Tag          string `json:"tag"`
+	SessionCount int    `json:"sessionCount"`
+}
+
+type listRelationshipTagsResponse struct {
+	Tags []relationshipTagItem `json:"tags"`
+}
+
 func (api *v1API) handleRelationshipGroups(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == "" {
@@ -80,19 +92,30 @@ func (api *v1API) handleRelationshipGroups(w http.ResponseWriter, r *http.Reques
 func (api *v1API) handleListRelationshipGroups(w http.ResponseWriter, r *http.Request, userID string) {
 	groups, err := api.store.ListRelationshipGroups(r.Context(), userID)
 	if err != nil {
-		api.logger.Error("list relationship groups failed", "error", err)
+		api.log(r.Context()).Error("list relationship groups failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	stats, err := api.store.CountSessionsPerGroup(r.Context(), userID)
+	if err != nil {
+		api.log(r.Context()).Error("count sessions per group failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	items := make([]relationshipGroupItem, 0, len(groups))
 	for _, g := range groups {
-		items = append(items, relationshipGroupItem{
+		item := relationshipGroupItem{
 			ID:          g.ID,
 			Name:        g.Name,
 			CreatedAtMs: g.CreatedAtMs,
 			UpdatedAtMs: g.UpdatedAtMs,
-		})
+		}
+		if st, ok := stats[g.ID]; ok {
+			item.SessionCount = st.SessionCount
+			item.LastActivityAtMs = &st.LastActivityAtMs
+		}
+		items = append(items, item)
 	}
 	writeJSON(w, http.StatusOK, listRelationshipGroupsResponse{Groups: items})
 }
@@ -113,7 +136,7 @@ func (api *v1API) handleCreateRelationshipGroup(w http.ResponseWriter, r *http.R
 	nowMs := time.Now().UnixMilli()
 	group, created, err := api.store.CreateRelationshipGroup(r.Context(), userID, name, nowMs)
 	if err != nil {
-		api.logger.Error("create relationship group failed", "error", err)
+		api.log(r.Context()).Error("create relationship group failed", "error", err)
 		writeAPIError(w, ErrCodeValidation, "invalid group name")
 		return
 	}
@@ -152,7 +175,7 @@ func (api *v1API) handleRenameRelationshipGroup(w http.ResponseWriter, r *http.R
 			writeAPIError(w, ErrCodeValidation, "group name exists")
 			return
 		}
-		api.logger.Error("rename relationship group failed", "error", err)
+		api.log(r.Context()).Error("rename relationship group failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -173,9 +196,35 @@ func (api *v1API) handleDeleteRelationshipGroup(w http.ResponseWriter, r *http.R
 			writeAPIError(w, ErrCodeNotFound, "group not found")
 			return
 		}
-		api.logger.Error("delete relationship group failed", "error", err)
+		api.log(r.Context()).Error("delete relationship group failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
 }
+
+// handleListRelationshipTags lists the tags the caller put on their sessions, most used first.
+func (api *v1API) handleListRelationshipTags(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tags, err := api.store.ListUserTags(r.Context(), userID)
+	if err != nil {
+		api.log(r.Context()).Error("list relationship tags failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	items := make([]relationshipTagItem, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, relationshipTagItem{Tag: t.Tag, SessionCount: t.SessionCount})
+	}
+	writeJSON(w, http.StatusOK, listRelationshipTagsResponse{Tags: items})
+}
@@ -7,7 +7,11 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"linkbridge-backend/internal/storage"
 	"linkbridge-backend/internal/ws"
@@ -208,3 +212,376 @@ func TestRelationshipGroupsAndSessionRelationship_Smoke(t *testing.T) {
 	}
 
 }
+
+func TestListSessions_IncludesRelationshipSummary(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, _ := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	withMeta, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession(bob) error = %v", err)
+	}
+	withoutMeta, _, err := store.CreateSession(ctx, alice.ID, carol.ID, nowMs+1)
+	if err != nil {
+		t.Fatalf("CreateSession(carol) error = %v", err)
+	}
+	group, _, err := store.CreateRelationshipGroup(ctx, alice.ID, "Friends", nowMs)
+	if err != nil {
+		t.Fatalf("CreateRelationshipGroup() error = %v", err)
+	}
+	note := "met at the expo"
+	if _, err := store.UpsertSessionUserMeta(ctx, withMeta.ID, alice.ID, &note, &group.ID, []string{"work"}, nowMs); err != nil {
+		t.Fatalf("UpsertSessionUserMeta() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res := get(t, srv.Client(), srv.URL+"/v1/sessions?status=active", aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("GET /v1/sessions status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body listSessionsResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode sessions response error = %v", err)
+	}
+
+	byID := map[string]sessionListItem{}
+	for _, s := range body.Sessions {
+		byID[s.ID] = s
+	}
+	rel := byID[withMeta.ID].Relationship
+	if rel == nil {
+		t.Fatalf("session %s relationship = nil, want note and group", withMeta.ID)
+	}
+	if rel.Note == nil || *rel.Note != note {
+		t.Fatalf("relationship.note = %v, want %q", rel.Note, note)
+	}
+	if rel.GroupName == nil || *rel.GroupName != "Friends" {
+		t.Fatalf("relationship.groupName = %v, want %q", rel.GroupName, "Friends")
+	}
+	if len(rel.Tags) != 1 || rel.Tags[0] != "work" {
+		t.Fatalf("relationship.tags = %v, want [work]", rel.Tags)
+	}
+	if other, ok := byID[withoutMeta.ID]; !ok || other.Relationship != nil {
+		t.Fatalf("session without meta = %+v, want it listed without relationship", other)
+	}
+}
+
+func TestListSessions_FiltersByRelationshipGroup(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, _ := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	grouped, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession(bob) error = %v", err)
+	}
+	if _, _, err := store.CreateSession(ctx, alice.ID, carol.ID, nowMs+1); err != nil {
+		t.Fatalf("CreateSession(carol) error = %v", err)
+	}
+	group, _, err := store.CreateRelationshipGroup(ctx, alice.ID, "Work", nowMs)
+	if err != nil {
+		t.Fatalf("CreateRelationshipGroup(alice) error = %v", err)
+	}
+	if _, err := store.UpsertSessionUserMeta(ctx, grouped.ID, alice.ID, nil, &group.ID, nil, nowMs); err != nil {
+		t.Fatalf("UpsertSessionUserMeta() error = %v", err)
+	}
+	bobsGroup, _, err := store.CreateRelationshipGroup(ctx, bob.ID, "Bob's", nowMs)
+	if err != nil {
+		t.Fatalf("CreateRelationshipGroup(bob) error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := get(t, client, srv.URL+"/v1/sessions?status=active&groupId="+group.ID, aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("GET /v1/sessions?groupId status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body listSessionsResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode sessions response error = %v", err)
+	}
+	if len(body.Sessions) != 1 || body.Sessions[0].ID != grouped.ID {
+		t.Fatalf("filtered sessions = %+v, want only %s", body.Sessions, grouped.ID)
+	}
+
+	foreign := get(t, client, srv.URL+"/v1/sessions?status=active&groupId="+bobsGroup.ID, aliceToken)
+	_ = foreign.Body.Close()
+	if foreign.StatusCode != http.StatusBadRequest {
+		t.Fatalf("GET /v1/sessions with another user's group status = %d, want 400", foreign.StatusCode)
+	}
+}
+
+func TestRelationshipTags_ListAndFilterSessions(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	tagged := map[string][]string{
+		"bob":   {"Work", "vip"},
+		"carol": {"work", "axb"},
+		"dave":  {"a_b"},
+	}
+	sessionIDs := map[string]string{}
+	for i, name := range []string{"bob", "carol", "dave"} {
+		peer, _ := newTestUser(t, store, tokenToUserID, name, nowMs)
+		session, _, err := store.CreateSession(ctx, alice.ID, peer.ID, nowMs+int64(i))
+		if err != nil {
+			t.Fatalf("CreateSession(%s) error = %v", name, err)
+		}
+		if _, err := store.UpsertSessionUserMeta(ctx, session.ID, alice.ID, nil, nil, tagged[name], nowMs+int64(i)); err != nil {
+			t.Fatalf("UpsertSessionUserMeta(%s) error = %v", name, err)
+		}
+		sessionIDs[name] = session.ID
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	tagsRes := get(t, client, srv.URL+"/v1/relationship-tags", aliceToken)
+	defer tagsRes.Body.Close()
+	if tagsRes.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(tagsRes.Body)
+		t.Fatalf("GET /v1/relationship-tags status = %d, body=%s", tagsRes.StatusCode, string(b))
+	}
+	var tags listRelationshipTagsResponse
+	if err := json.NewDecoder(tagsRes.Body).Decode(&tags); err != nil {
+		t.Fatalf("decode tags response error = %v", err)
+	}
+	if len(tags.Tags) != 4 || !strings.EqualFold(tags.Tags[0].Tag, "work") || tags.Tags[0].SessionCount != 2 {
+		t.Fatalf("tags = %+v, want work (2) first and 4 distinct tags", tags.Tags)
+	}
+
+	listByTag := func(tag string) []string {
+		t.Helper()
+		res := get(t, client, srv.URL+"/v1/sessions?status=active&tag="+url.QueryEscape(tag), aliceToken)
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("GET /v1/sessions?tag=%s status = %d, body=%s", tag, res.StatusCode, string(b))
+		}
+		var body listSessionsResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode sessions response error = %v", err)
+		}
+		ids := make([]string, 0, len(body.Sessions))
+		for _, s := range body.Sessions {
+			ids = append(ids, s.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	want := []string{sessionIDs["bob"], sessionIDs["carol"]}
+	sort.Strings(want)
+	if got := listByTag("WORK"); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("sessions tagged work = %v, want %v", got, want)
+	}
+	if got := listByTag("a_b"); len(got) != 1 || got[0] != sessionIDs["dave"] {
+		t.Fatalf("sessions tagged a_b = %v, want only %s", got, sessionIDs["dave"])
+	}
+	if got := listByTag("wor"); len(got) != 0 {
+		t.Fatalf("sessions tagged wor = %v, want none", got)
+	}
+}
+
+func TestRelationshipGroups_ListIncludesSessionCounts(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, _ := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, _ := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+
+	s1, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession(bob) error = %v", err)
+	}
+	s2, _, err := store.CreateSession(ctx, alice.ID, carol.ID, nowMs+5)
+	if err != nil {
+		t.Fatalf("CreateSession(carol) error = %v", err)
+	}
+	group, _, err := store.CreateRelationshipGroup(ctx, alice.ID, "Work", nowMs)
+	if err != nil {
+		t.Fatalf("CreateRelationshipGroup() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	listGroup := func() relationshipGroupItem {
+		t.Helper()
+		res := get(t, client, srv.URL+"/v1/relationship-groups", aliceToken)
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("GET /v1/relationship-groups status = %d, body=%s", res.StatusCode, string(b))
+		}
+		var body listRelationshipGroupsResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode groups response error = %v", err)
+		}
+		if len(body.Groups) != 1 {
+			t.Fatalf("groups = %+v, want exactly one", body.Groups)
+		}
+		return body.Groups[0]
+	}
+	assign := func(sessionID string, groupID *string) {
+		t.Helper()
+		if _, err := store.UpsertSessionUserMeta(ctx, sessionID, alice.ID, nil, groupID, nil, time.Now().UnixMilli()); err != nil {
+			t.Fatalf("UpsertSessionUserMeta() error = %v", err)
+		}
+	}
+
+	if g := listGroup(); g.SessionCount != 0 || g.LastActivityAtMs != nil {
+		t.Fatalf("empty group = %+v, want sessionCount 0 and no lastActivityAtMs", g)
+	}
+
+	assign(s1.ID, &group.ID)
+	assign(s2.ID, &group.ID)
+	g := listGroup()
+	if g.SessionCount != 2 {
+		t.Fatalf("sessionCount = %d, want 2", g.SessionCount)
+	}
+	if g.LastActivityAtMs == nil || *g.LastActivityAtMs != s2.UpdatedAtMs {
+		t.Fatalf("lastActivityAtMs = %v, want %d", g.LastActivityAtMs, s2.UpdatedAtMs)
+	}
+
+	assign(s2.ID, nil)
+	g = listGroup()
+	if g.SessionCount != 1 {
+		t.Fatalf("sessionCount after unassigning = %d, want 1", g.SessionCount)
+	}
+	if g.LastActivityAtMs == nil || *g.LastActivityAtMs != s1.UpdatedAtMs {
+		t.Fatalf("lastActivityAtMs after unassigning = %v, want %d", g.LastActivityAtMs, s1.UpdatedAtMs)
+	}
+}
+
+func TestSessionRelationship_UngroupClearsAndStaysCleared(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+
+	// Creating and joining an activity files its chat under the default "活动" group.
+	activity, invite, err := store.CreateActivity(ctx, alice.ID, "Hike", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, bob.ID, invite.Code, nil, nil, nowMs); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	relURL := srv.URL + "/v1/sessions/" + activity.SessionID + "/relationship"
+	decodeRel := func(res *http.Response) sessionRelationshipItem {
+		t.Helper()
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("relationship status = %d, body=%s", res.StatusCode, string(b))
+		}
+		var body getSessionRelationshipResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode relationship response error = %v", err)
+		}
+		return body.Relationship
+	}
+
+	if rel := decodeRel(get(t, client, relURL, bobToken)); rel.GroupID == nil {
+		t.Fatalf("bob's relationship before ungroup = %+v, want the default group", rel)
+	}
+	if rel := decodeRel(postJSON(t, client, relURL+"/ungroup", map[string]any{}, bobToken)); rel.GroupID != nil || rel.GroupName != nil {
+		t.Fatalf("bob's relationship after ungroup = %+v, want groupId and groupName null", rel)
+	}
+
+	// Joining again must not re-apply the default group.
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, bob.ID, invite.Code, nil, nil, nowMs+1); err != nil {
+		t.Fatalf("ConsumeActivityInvite() again error = %v", err)
+	}
+	if rel := decodeRel(get(t, client, relURL, bobToken)); rel.GroupID != nil {
+		t.Fatalf("bob's relationship after rejoining = %+v, want it to stay ungrouped", rel)
+	}
+
+	// PUT with an explicit null clears the assignment too and keeps the note.
+	if rel := decodeRel(putJSON(t, client, relURL, map[string]any{"note": "organizer"}, aliceToken)); rel.GroupID == nil {
+		t.Fatalf("alice's relationship = %+v, want the default group", rel)
+	}
+	rel := decodeRel(putJSON(t, client, relURL, map[string]any{"groupId": nil}, aliceToken))
+	if rel.GroupID != nil || rel.GroupName != nil {
+		t.Fatalf("alice's relationship after groupId null = %+v, want groupId and groupName null", rel)
+	}
+	if rel.Note == nil || *rel.Note != "organizer" {
+		t.Fatalf("alice's note after groupId null = %v, want %q", rel.Note, "organizer")
+	}
+}
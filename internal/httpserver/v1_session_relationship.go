@@ -38,7 +38,7 @@ func (api *v1API) handleGetSessionRelationship(w http.ResponseWriter, r *http.Re
 
 	ok, err := api.store.IsSessionParticipant(r.Context(), sessionID, userID)
 	if err != nil {
-		api.logger.Error("check session participant failed", "error", err)
+		api.log(r.Context()).Error("check session participant failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -53,14 +53,14 @@ func (api *v1API) handleGetSessionRelationship(w http.ResponseWriter, r *http.Re
 			writeAPIError(w, ErrCodeSessionNotFound, "session not found")
 			return
 		}
-		api.logger.Error("get session failed", "error", err)
+		api.log(r.Context()).Error("get session failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	meta, err := api.store.GetSessionUserMeta(r.Context(), sessionID, userID)
 	if err != nil && !errors.Is(err, storage.ErrNotFound) {
-		api.logger.Error("get session meta failed", "error", err)
+		api.log(r.Context()).Error("get session meta failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -96,7 +96,7 @@ func (api *v1API) handleUpsertSessionRelationship(w http.ResponseWriter, r *http
 
 	ok, err := api.store.IsSessionParticipant(r.Context(), sessionID, userID)
 	if err != nil {
-		api.logger.Error("check session participant failed", "error", err)
+		api.log(r.Context()).Error("check session participant failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -108,7 +108,7 @@ func (api *v1API) handleUpsertSessionRelationship(w http.ResponseWriter, r *http
 	// Load existing meta for patch semantics.
 	existing, err := api.store.GetSessionUserMeta(r.Context(), sessionID, userID)
 	if err != nil && !errors.Is(err, storage.ErrNotFound) {
-		api.logger.Error("get existing session meta failed", "error", err)
+		api.log(r.Context()).Error("get existing session meta failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -148,7 +148,7 @@ func (api *v1API) handleUpsertSessionRelationship(w http.ResponseWriter, r *http
 					writeAPIError(w, ErrCodeValidation, "group not found")
 					return
 				}
-				api.logger.Error("get relationship group failed", "error", err)
+				api.log(r.Context()).Error("get relationship group failed", "error", err)
 				writeAPIError(w, ErrCodeInternal, "internal error")
 				return
 			}
@@ -167,7 +167,51 @@ func (api *v1API) handleUpsertSessionRelationship(w http.ResponseWriter, r *http
 
 	nowMs := time.Now().UnixMilli()
 	if _, err := api.store.UpsertSessionUserMeta(r.Context(), sessionID, userID, note, groupID, tags, nowMs); err != nil {
-		api.logger.Error("upsert session relationship failed", "error", err)
+		api.log(r.Context()).Error("upsert session relationship failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	api.handleGetSessionRelationship(w, r, sessionID)
+}
+
+// handleUngroupSessionRelationship takes the session out of the caller's relationship group,
+// keeping the note and tags; it is the same as PUT with "groupId": null. The meta row is
+// written even when none existed, so the default group an activity or accepted request would
+// assign is not applied later.
+func (api *v1API) handleUngroupSessionRelationship(w http.ResponseWriter, r *http.Request, sessionID string) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		writeAPIError(w, ErrCodeValidation, "sessionId is required")
+		return
+	}
+
+	ok, err := api.store.IsSessionParticipant(r.Context(), sessionID, userID)
+	if err != nil {
+		api.log(r.Context()).Error("check session participant failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if !ok {
+		writeAPIError(w, ErrCodeSessionAccessDenied, "access denied")
+		return
+	}
+
+	existing, err := api.store.GetSessionUserMeta(r.Context(), sessionID, userID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		api.log(r.Context()).Error("get existing session meta failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	if _, err := api.store.UpsertSessionUserMeta(r.Context(), sessionID, userID, existing.Note, nil, storage.ParseTagsJSON(existing.TagsJSON), nowMs); err != nil {
+		api.log(r.Context()).Error("ungroup session relationship failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -1,7 +1,9 @@
 package httpserver
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -26,6 +28,10 @@ type sessionRequestItem struct {
 	CreatedAtMs         int64   `json:"createdAtMs"`
 	UpdatedAtMs         int64   `json:"updatedAtMs"`
 	LastOpenedAtMs      int64   `json:"lastOpenedAtMs"`
+	SeenAtMs            *int64  `json:"seenAtMs,omitempty"`
+	// Peer is the other side of the request: the requester for incoming requests and the
+	// addressee for outgoing ones. It is only filled in request listings.
+	Peer *peerItem `json:"peer,omitempty"`
 }
 
 type createSessionRequestResponse struct {
@@ -61,7 +67,7 @@ func (api *v1API) handleSessionRequests(w http.ResponseWriter, r *http.Request)
 	case http.MethodGet:
 		api.handleListSessionRequests(w, r)
 	case http.MethodPost:
-		api.handleCreateSessionRequest(w, r)
+		api.withIdempotency(w, r, api.handleCreateSessionRequest)
 	default:
 		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
 	}
@@ -84,6 +90,24 @@ func (api *v1API) handleSessionRequestSubroutes(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if len(parts) == 1 && parts[0] == "batch" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleBatchSessionRequests(w, r)
+		return
+	}
+
+	if len(parts) == 1 && parts[0] == "seen-all" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleMarkAllSessionRequestsSeen(w, r)
+		return
+	}
+
 	if len(parts) != 2 {
 		writeAPIError(w, ErrCodeNotFound, "not found")
 		return
@@ -108,6 +132,26 @@ func (api *v1API) handleSessionRequestSubroutes(w http.ResponseWriter, r *http.R
 	}
 }
 
+func (api *v1API) handleMarkAllSessionRequestsSeen(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	updated, err := api.store.MarkAllSessionRequestsSeen(r.Context(), userID, time.Now().UnixMilli())
+	if err != nil {
+		api.log(r.Context()).Error("mark session requests seen failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	// Read state is private to the user, so nothing is pushed over WS.
+	writeJSON(w, http.StatusOK, map[string]any{
+		"updated": updated,
+	})
+}
+
 func (api *v1API) handleConsumeSessionInvite(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == "" {
@@ -166,12 +210,12 @@ func (api *v1API) handleConsumeSessionInvite(w http.ResponseWriter, r *http.Requ
 			writeAPIError(w, ErrCodeGeoFenceForbidden, "outside allowed area")
 			return
 		}
-		api.logger.Error("consume session invite failed", "error", err)
+		api.log(r.Context()).Error("consume session invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
-	sr, created, err := api.store.CreateSessionRequest(r.Context(), userID, invite.InviterID, storage.SessionRequestSourceWeChatCode, nil, nowMs)
+	sr, created, err := api.store.CreateSessionRequestWithLimits(r.Context(), userID, invite.InviterID, storage.SessionRequestSourceWeChatCode, nil, api.sessionRequestLimits, nowMs)
 	if err != nil {
 		if errors.Is(err, storage.ErrCannotChatSelf) {
 			writeAPIError(w, ErrCodeValidation, "cannot add self")
@@ -193,7 +237,11 @@ func (api *v1API) handleConsumeSessionInvite(w http.ResponseWriter, r *http.Requ
 			writeAPIError(w, ErrCodeCooldownActive, "cooldown active")
 			return
 		}
-		api.logger.Error("create session request from invite failed", "error", err)
+		if errors.Is(err, storage.ErrBlocked) {
+			writeAPIError(w, ErrCodeBlocked, "blocked by this user")
+			return
+		}
+		api.log(r.Context()).Error("create session request from invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -236,17 +284,14 @@ func (api *v1API) handleCreateSessionRequest(w http.ResponseWriter, r *http.Requ
 		writeAPIError(w, ErrCodeValidation, "addresseeId is required")
 		return
 	}
-	if req.VerificationMessage != nil {
-		msg := strings.TrimSpace(*req.VerificationMessage)
-		if msg == "" {
-			req.VerificationMessage = nil
-		} else {
-			req.VerificationMessage = &msg
-		}
+	verificationMessage, ok := storage.NormalizeVerificationMessage(req.VerificationMessage)
+	if !ok {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("verificationMessage must be at most %d characters", storage.MaxVerificationMessageLen))
+		return
 	}
 
 	nowMs := time.Now().UnixMilli()
-	sr, created, err := api.store.CreateSessionRequest(r.Context(), userID, req.AddresseeID, storage.SessionRequestSourceMap, req.VerificationMessage, nowMs)
+	sr, created, err := api.store.CreateSessionRequestWithLimits(r.Context(), userID, req.AddresseeID, storage.SessionRequestSourceMap, verificationMessage, api.sessionRequestLimits, nowMs)
 	if err != nil {
 		if errors.Is(err, storage.ErrCannotChatSelf) {
 			writeAPIError(w, ErrCodeValidation, "cannot add self")
@@ -268,7 +313,11 @@ func (api *v1API) handleCreateSessionRequest(w http.ResponseWriter, r *http.Requ
 			writeAPIError(w, ErrCodeCooldownActive, "cooldown active")
 			return
 		}
-		api.logger.Error("create session request failed", "error", err)
+		if errors.Is(err, storage.ErrBlocked) {
+			writeAPIError(w, ErrCodeBlocked, "blocked by this user")
+			return
+		}
+		api.log(r.Context()).Error("create session request failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -301,18 +350,46 @@ func (api *v1API) handleListSessionRequests(w http.ResponseWriter, r *http.Reque
 
 	requests, err := api.store.ListSessionRequests(r.Context(), userID, box, status)
 	if err != nil {
-		api.logger.Error("list session requests failed", "error", err)
+		api.log(r.Context()).Error("list session requests failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	peerIDs := make([]string, 0, len(requests))
+	for _, rr := range requests {
+		peerIDs = append(peerIDs, sessionRequestPeerID(rr, userID))
+	}
+	users, err := api.store.GetUsersByIDs(r.Context(), peerIDs)
+	if err != nil {
+		api.log(r.Context()).Error("get session request peers failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	items := make([]sessionRequestItem, 0, len(requests))
 	for _, rr := range requests {
-		items = append(items, sessionRequestItemFromRow(rr))
+		item := sessionRequestItemFromRow(rr)
+		if peer, ok := users[sessionRequestPeerID(rr, userID)]; ok {
+			item.Peer = &peerItem{
+				ID:          peer.ID,
+				Username:    peer.Username,
+				DisplayName: peer.DisplayName,
+				AvatarURL:   peer.AvatarURL,
+			}
+		}
+		items = append(items, item)
 	}
 	writeJSON(w, http.StatusOK, listSessionRequestsResponse{Requests: items})
 }
 
+// sessionRequestPeerID returns the counterparty of sr from userID's point of view.
+func sessionRequestPeerID(sr storage.SessionRequestRow, userID string) string {
+	if sr.RequesterID == userID {
+		return sr.AddresseeID
+	}
+	return sr.RequesterID
+}
+
 func (api *v1API) handleAcceptSessionRequest(w http.ResponseWriter, r *http.Request, requestID string) {
 	api.handleMutateSessionRequest(w, r, requestID, "accept")
 }
@@ -337,40 +414,13 @@ func (api *v1API) handleMutateSessionRequest(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	nowMs := time.Now().UnixMilli()
-
-	var (
-		sr      storage.SessionRequestRow
-		session *storage.SessionRow
-		err     error
-	)
-	switch action {
-	case "accept":
-		sr, session, err = api.store.AcceptSessionRequest(r.Context(), requestID, userID, nowMs)
-	case "reject":
-		sr, err = api.store.RejectSessionRequest(r.Context(), requestID, userID, nowMs)
-	case "cancel":
-		sr, err = api.store.CancelSessionRequest(r.Context(), requestID, userID, nowMs)
-	default:
-		writeAPIError(w, ErrCodeNotFound, "not found")
-		return
-	}
-
+	sr, session, err := api.mutateSessionRequest(r.Context(), requestID, userID, action, time.Now().UnixMilli())
 	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			writeAPIError(w, ErrCodeSessionRequestNotFound, "session request not found")
-			return
-		}
-		if errors.Is(err, storage.ErrAccessDenied) {
-			writeAPIError(w, ErrCodeSessionRequestAccessDenied, "access denied")
-			return
-		}
-		if errors.Is(err, storage.ErrInvalidState) {
-			writeAPIError(w, ErrCodeSessionRequestInvalidState, "invalid request state")
-			return
+		code, msg := sessionRequestMutationError(err)
+		if code == ErrCodeInternal {
+			api.log(r.Context()).Error("mutate session request failed", "error", err, "action", action)
 		}
-		api.logger.Error("mutate session request failed", "error", err, "action", action)
-		writeAPIError(w, ErrCodeInternal, "internal error")
+		writeAPIError(w, code, msg)
 		return
 	}
 
@@ -381,13 +431,50 @@ func (api *v1API) handleMutateSessionRequest(w http.ResponseWriter, r *http.Requ
 	}
 	writeJSON(w, http.StatusOK, resp)
 
+	api.notifySessionRequestMutation(sr, session, action)
+}
+
+// mutateSessionRequest applies action ("accept", "reject" or "cancel") to requestID on behalf
+// of userID. The storage layer checks that userID is allowed to take the action.
+func (api *v1API) mutateSessionRequest(ctx context.Context, requestID, userID, action string, nowMs int64) (storage.SessionRequestRow, *storage.SessionRow, error) {
+	switch action {
+	case "accept":
+		return api.store.AcceptSessionRequest(ctx, requestID, userID, nowMs)
+	case "reject":
+		sr, err := api.store.RejectSessionRequest(ctx, requestID, userID, nowMs)
+		return sr, nil, err
+	case "cancel":
+		sr, err := api.store.CancelSessionRequest(ctx, requestID, userID, nowMs)
+		return sr, nil, err
+	default:
+		return storage.SessionRequestRow{}, nil, fmt.Errorf("unknown session request action %q", action)
+	}
+}
+
+// sessionRequestMutationError maps a mutateSessionRequest error to an API error.
+func sessionRequestMutationError(err error) (ErrorCode, string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return ErrCodeSessionRequestNotFound, "session request not found"
+	case errors.Is(err, storage.ErrAccessDenied):
+		return ErrCodeSessionRequestAccessDenied, "access denied"
+	case errors.Is(err, storage.ErrInvalidState):
+		return ErrCodeSessionRequestInvalidState, "invalid request state"
+	default:
+		return ErrCodeInternal, "internal error"
+	}
+}
+
+// notifySessionRequestMutation tells both sides of sr that it was accepted, rejected or
+// canceled.
+func (api *v1API) notifySessionRequestMutation(sr storage.SessionRequestRow, session *storage.SessionRow, action string) {
 	eventType := map[string]string{
 		"accept": "session.request.accepted",
 		"reject": "session.request.rejected",
 		"cancel": "session.request.canceled",
 	}[action]
 
-	payload := map[string]any{"request": item}
+	payload := map[string]any{"request": sessionRequestItemFromRow(sr)}
 	if session != nil {
 		payload["session"] = sessionItemFromRow(*session)
 	}
@@ -410,5 +497,87 @@ func sessionRequestItemFromRow(sr storage.SessionRequestRow) sessionRequestItem
 		CreatedAtMs:         sr.CreatedAtMs,
 		UpdatedAtMs:         sr.UpdatedAtMs,
 		LastOpenedAtMs:      sr.LastOpenedAtMs,
+		SeenAtMs:            sr.SeenAtMs,
 	}
 }
+
+// maxSessionRequestBatch caps how many ids one batch call may touch.
+const maxSessionRequestBatch = 50
+
+type batchSessionRequestsRequest struct {
+	Accept []string `json:"accept"`
+	Reject []string `json:"reject"`
+}
+
+type batchSessionRequestResult struct {
+	ID      string              `json:"id"`
+	Action  string              `json:"action"`
+	OK      bool                `json:"ok"`
+	Error   *apiError           `json:"error,omitempty"`
+	Request *sessionRequestItem `json:"request,omitempty"`
+}
+
+// handleBatchSessionRequests accepts and rejects several requests at once. Each id goes
+// through the same checks as the single-request endpoints, so ids the caller may not act on
+// fail individually without affecting the rest.
+func (api *v1API) handleBatchSessionRequests(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req batchSessionRequestsRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	total := len(req.Accept) + len(req.Reject)
+	if total == 0 {
+		writeAPIError(w, ErrCodeValidation, "accept or reject is required")
+		return
+	}
+	if total > maxSessionRequestBatch {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("at most %d ids per batch", maxSessionRequestBatch))
+		return
+	}
+
+	type op struct{ id, action string }
+	ops := make([]op, 0, total)
+	for _, id := range req.Accept {
+		ops = append(ops, op{strings.TrimSpace(id), "accept"})
+	}
+	for _, id := range req.Reject {
+		ops = append(ops, op{strings.TrimSpace(id), "reject"})
+	}
+
+	nowMs := time.Now().UnixMilli()
+	results := make([]batchSessionRequestResult, 0, len(ops))
+	for _, o := range ops {
+		result := batchSessionRequestResult{ID: o.id, Action: o.action}
+		if o.id == "" {
+			result.Error = &apiError{Code: string(ErrCodeValidation), Message: "invalid request id"}
+			results = append(results, result)
+			continue
+		}
+
+		sr, session, err := api.mutateSessionRequest(r.Context(), o.id, userID, o.action, nowMs)
+		if err != nil {
+			code, msg := sessionRequestMutationError(err)
+			if code == ErrCodeInternal {
+				api.log(r.Context()).Error("batch mutate session request failed", "error", err, "action", o.action)
+			}
+			result.Error = &apiError{Code: string(code), Message: msg}
+			results = append(results, result)
+			continue
+		}
+
+		item := sessionRequestItemFromRow(sr)
+		result.OK = true
+		result.Request = &item
+		results = append(results, result)
+		api.notifySessionRequestMutation(sr, session, o.action)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
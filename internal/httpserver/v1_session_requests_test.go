@@ -0,0 +1,133 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestListSessionRequests_IncludesPeerSummary(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/session-requests", map[string]any{"addresseeId": bob.ID}, aliceToken)
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/session-requests status = %d, body=%s", res.StatusCode, string(b))
+	}
+	_ = res.Body.Close()
+
+	list := func(box, token string) []sessionRequestItem {
+		t.Helper()
+		res := get(t, client, srv.URL+"/v1/session-requests?box="+box, token)
+		defer res.Body.Close()
+		var body listSessionRequestsResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode %s list error = %v", box, err)
+		}
+		return body.Requests
+	}
+
+	incoming := list("incoming", bobToken)
+	if len(incoming) != 1 || incoming[0].Peer == nil {
+		t.Fatalf("incoming = %+v, want one request with peer", incoming)
+	}
+	if incoming[0].Peer.ID != alice.ID || incoming[0].Peer.DisplayName != "alice" {
+		t.Fatalf("incoming peer = %+v, want Alice", incoming[0].Peer)
+	}
+
+	outgoing := list("outgoing", aliceToken)
+	if len(outgoing) != 1 || outgoing[0].Peer == nil || outgoing[0].Peer.DisplayName != "bob" {
+		t.Fatalf("outgoing = %+v, want one request with peer Bob", outgoing)
+	}
+}
+
+func TestBatchSessionRequests_SkipsRequestsOfOthers(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, _ := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	bob, bobToken := newTestUser(t, store, tokenToUserID, "bob", nowMs)
+	carol, _ := newTestUser(t, store, tokenToUserID, "carol", nowMs)
+	dave, _ := newTestUser(t, store, tokenToUserID, "dave", nowMs)
+
+	mine, _, err := store.CreateSessionRequest(ctx, alice.ID, bob.ID, storage.SessionRequestSourceMap, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSessionRequest(alice->bob) error = %v", err)
+	}
+	others, _, err := store.CreateSessionRequest(ctx, carol.ID, dave.ID, storage.SessionRequestSourceMap, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSessionRequest(carol->dave) error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res := postJSON(t, srv.Client(), srv.URL+"/v1/session-requests/batch", map[string]any{
+		"accept": []string{mine.ID},
+		"reject": []string{others.ID},
+	}, bobToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("batch status = %d, body=%s", res.StatusCode, string(b))
+	}
+	var body struct {
+		Results []batchSessionRequestResult `json:"results"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode batch response error = %v", err)
+	}
+	if len(body.Results) != 2 {
+		t.Fatalf("results len = %d, want 2", len(body.Results))
+	}
+	if r := body.Results[0]; r.ID != mine.ID || !r.OK || r.Request == nil || r.Request.Status != storage.SessionRequestStatusAccepted {
+		t.Fatalf("results[0] = %+v, want accepted", r)
+	}
+	if r := body.Results[1]; r.ID != others.ID || r.OK || r.Error == nil || r.Error.Code != string(ErrCodeSessionRequestAccessDenied) {
+		t.Fatalf("results[1] = %+v, want access denied", r)
+	}
+
+	pending, err := store.ListSessionRequests(ctx, dave.ID, "incoming", storage.SessionRequestStatusPending)
+	if err != nil {
+		t.Fatalf("ListSessionRequests(dave) error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != others.ID {
+		t.Fatalf("dave pending = %+v, want carol's request untouched", pending)
+	}
+}
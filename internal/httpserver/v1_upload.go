@@ -1,8 +1,10 @@
 package httpserver
 
 import (
-	"crypto/sha256"
+	"context"
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,14 +12,143 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"linkbridge-backend/internal/storage"
+)
+
+const (
+	maxUploadSize       = 50 << 20 // 50MB
+	maxAvatarUploadSize = 5 << 20  // 5MB
 )
 
-const maxUploadSize = 50 << 20 // 50MB
+// uploadPurposeAvatar restricts an upload to a small still image (?purpose=avatar).
+const uploadPurposeAvatar = "avatar"
+
+// avatarUploadExtensions are the only formats accepted for avatars.
+var avatarUploadExtensions = map[string]struct{}{
+	".jpg":  {},
+	".jpeg": {},
+	".png":  {},
+	".webp": {},
+}
+
+// uploadContentTypes pins the Content-Type served for each known extension. Uploaded bytes are
+// never sniffed, so an HTML document saved as ".png" is still delivered as an image.
+var uploadContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".wav":  "audio/wav",
+	".amr":  "audio/amr",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain; charset=utf-8",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".zip":  "application/zip",
+}
+
+// uploadSniffedTypes lists, per extension, the http.DetectContentType results accepted for the
+// uploaded bytes. Formats the sniffer has no signature for are only accepted as
+// application/octet-stream, so text such as HTML can never pass as a binary format.
+var uploadSniffedTypes = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".mp3":  {"audio/mpeg", "application/octet-stream"},
+	".m4a":  {"video/mp4", "application/octet-stream"},
+	".aac":  {"application/octet-stream"},
+	".wav":  {"audio/wave"},
+	".amr":  {"application/octet-stream"},
+	".mp4":  {"video/mp4", "application/octet-stream"},
+	".mov":  {"video/mp4", "application/octet-stream"},
+	".pdf":  {"application/pdf"},
+	".txt":  {"text/plain"},
+	".doc":  {"application/octet-stream"},
+	".docx": {"application/zip"},
+	".xls":  {"application/octet-stream"},
+	".xlsx": {"application/zip"},
+	".ppt":  {"application/octet-stream"},
+	".pptx": {"application/zip"},
+	".zip":  {"application/zip"},
+}
+
+// uploadContentMatchesExtension reports whether sniffed (from http.DetectContentType) is an
+// accepted type for ext. Extensions added through UPLOAD_ALLOWED_EXTENSIONS without an entry
+// in uploadSniffedTypes are accepted unless the content sniffs as markup.
+func uploadContentMatchesExtension(ext, sniffed string) bool {
+	sniffed, _, _ = strings.Cut(sniffed, ";")
+	accepted, ok := uploadSniffedTypes[ext]
+	if !ok {
+		return sniffed != "text/html" && sniffed != "text/xml"
+	}
+	for _, t := range accepted {
+		if sniffed == t {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultUploadExtensions() []string {
+	exts := make([]string, 0, len(uploadContentTypes))
+	for ext := range uploadContentTypes {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+func newUploadExtensionSet(exts []string) map[string]struct{} {
+	if len(exts) == 0 {
+		exts = defaultUploadExtensions()
+	}
+	set := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = struct{}{}
+	}
+	return set
+}
+
+func (api *v1API) uploadExtensionAllowed(ext string) bool {
+	_, ok := api.uploadAllowedExts[strings.ToLower(ext)]
+	return ok
+}
+
+type uploadQuotaDetails struct {
+	UsedBytes  int64 `json:"usedBytes"`
+	LimitBytes int64 `json:"limitBytes"`
+	FileBytes  int64 `json:"fileBytes"`
+}
+
+type uploadSizeDetails struct {
+	MaxBytes int64 `json:"maxBytes"`
+}
 
 type uploadResponse struct {
 	URL       string `json:"url"`
 	Name      string `json:"name"`
 	SizeBytes int64  `json:"sizeBytes"`
+	// ThumbnailURL is set for images a thumbnail could be generated for.
+	ThumbnailURL *string `json:"thumbnailUrl,omitempty"`
 }
 
 func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
@@ -32,9 +163,29 @@ func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		writeAPIError(w, ErrCodeValidation, "file too large or invalid form")
+	limit := int64(maxUploadSize)
+	extensionAllowed := api.uploadExtensionAllowed
+	switch purpose := strings.TrimSpace(r.URL.Query().Get("purpose")); purpose {
+	case "":
+	case uploadPurposeAvatar:
+		limit = maxAvatarUploadSize
+		extensionAllowed = func(ext string) bool {
+			_, ok := avatarUploadExtensions[ext]
+			return ok
+		}
+	default:
+		writeAPIError(w, ErrCodeValidation, "invalid purpose")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIErrorDetails(w, ErrCodeFileTooLarge, "file too large", uploadSizeDetails{MaxBytes: limit})
+			return
+		}
+		writeAPIError(w, ErrCodeValidation, "invalid form")
 		return
 	}
 
@@ -46,12 +197,61 @@ func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	originalName := header.Filename
-	ext := filepath.Ext(originalName)
+	ext := strings.ToLower(filepath.Ext(originalName))
+	if !extensionAllowed(ext) {
+		writeAPIError(w, ErrCodeValidation, "file type not allowed")
+		return
+	}
+
+	// The extension decides how the file is served, so the bytes must actually be that format.
+	sniffBuf := make([]byte, 512)
+	n, err := io.ReadFull(file, sniffBuf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		writeAPIError(w, ErrCodeValidation, "invalid file")
+		return
+	}
+	if !uploadContentMatchesExtension(ext, http.DetectContentType(sniffBuf[:n])) {
+		writeAPIError(w, ErrCodeValidation, "file content does not match its extension")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		api.log(r.Context()).Error("rewind upload failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	// Random names keep stored files unguessable and free of client-supplied path parts.
+	nameBytes := make([]byte, 16)
+	if _, err := rand.Read(nameBytes); err != nil {
+		api.log(r.Context()).Error("generate upload name failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	uniqueName := hex.EncodeToString(nameBytes) + ext
 
-	// Generate unique filename using timestamp and hash
-	hash := sha256.New()
-	hash.Write([]byte(fmt.Sprintf("%s-%d-%s", userID, time.Now().UnixNano(), originalName)))
-	uniqueName := hex.EncodeToString(hash.Sum(nil))[:16] + ext
+	// Charge the quota before touching disk; the record is released again if the write fails.
+	if usage, err := api.store.RecordUpload(r.Context(), userID, uniqueName, header.Size, api.uploadQuotaBytes, time.Now().UnixMilli()); err != nil {
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			writeAPIErrorDetails(w, ErrCodeQuotaExceeded, "upload quota exceeded", uploadQuotaDetails{
+				UsedBytes:  usage.UsedBytes,
+				LimitBytes: usage.LimitBytes(api.uploadQuotaBytes),
+				FileBytes:  header.Size,
+			})
+			return
+		}
+		api.log(r.Context()).Error("record upload failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := api.store.ReleaseUpload(context.Background(), uniqueName, time.Now().UnixMilli()); err != nil {
+			api.log(r.Context()).Warn("release upload failed", "error", err, "name", uniqueName)
+		}
+	}()
 
 	// Create upload directory if not exists
 	uploadDir := api.uploadDir
@@ -59,7 +259,7 @@ func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
 		uploadDir = "./uploads"
 	}
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		api.logger.Error("failed to create upload dir", "error", err)
+		api.log(r.Context()).Error("failed to create upload dir", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -68,7 +268,7 @@ func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
 	destPath := filepath.Join(uploadDir, uniqueName)
 	dest, err := os.Create(destPath)
 	if err != nil {
-		api.logger.Error("failed to create file", "error", err)
+		api.log(r.Context()).Error("failed to create file", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -76,20 +276,135 @@ func (api *v1API) handleUpload(w http.ResponseWriter, r *http.Request) {
 
 	written, err := io.Copy(dest, file)
 	if err != nil {
-		api.logger.Error("failed to write file", "error", err)
+		api.log(r.Context()).Error("failed to write file", "error", err)
+		os.Remove(destPath)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if err := dest.Close(); err != nil {
+		api.log(r.Context()).Error("failed to write file", "error", err)
 		os.Remove(destPath)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
+	// Photos can carry the GPS position they were taken at; JPEGs are re-encoded without
+	// metadata before anyone can fetch them. The quota stays charged at the uploaded size.
+	if !api.uploadEXIFStripDisabled && (ext == ".jpg" || ext == ".jpeg") {
+		if err := stripJPEGMetadata(destPath); err != nil {
+			os.Remove(destPath)
+			writeAPIError(w, ErrCodeValidation, "invalid image")
+			return
+		}
+		if info, err := os.Stat(destPath); err == nil {
+			written = info.Size()
+		}
+	}
+
+	committed = true
+
 	// Return file URL
 	fileURL := "/uploads/" + uniqueName
-
-	writeJSON(w, http.StatusOK, uploadResponse{
+	resp := uploadResponse{
 		URL:       fileURL,
 		Name:      sanitizeFilename(originalName),
 		SizeBytes: written,
-	})
+	}
+
+	// Thumbnails are best effort: an image the decoder rejects is still a valid upload.
+	if _, ok := thumbnailExtensions[ext]; ok {
+		thumbName := thumbnailName(uniqueName)
+		if err := writeUploadThumbnail(destPath, filepath.Join(uploadDir, thumbName)); err != nil {
+			api.log(r.Context()).Warn("generate upload thumbnail failed", "error", err, "name", uniqueName)
+		} else {
+			thumbURL := "/uploads/" + thumbName
+			resp.ThumbnailURL = &thumbURL
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleServeUpload serves a single file from uploadDir. Only flat names with an allow-listed
+// extension are served; anything else, including traversal attempts, is a plain 404.
+//
+// Stored names are random and never reused, so responses are cacheable for a year; ETag,
+// Last-Modified, conditional requests and byte ranges come from http.ServeContent.
+// ?size=thumb serves the generated thumbnail instead, falling back to the original when the
+// upload has none.
+func (api *v1API) handleServeUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if api.uploadsRequireAuth && getUserIDFromContext(r.Context()) == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/uploads/")
+	if name == "" || name != filepath.Base(name) || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if !api.uploadExtensionAllowed(ext) {
+		http.NotFound(w, r)
+		return
+	}
+
+	root, err := filepath.Abs(api.uploadDir)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	path := filepath.Join(root, name)
+	if filepath.Dir(path) != root {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("size") == "thumb" {
+		thumbName := thumbnailName(name)
+		thumbPath := filepath.Join(root, thumbName)
+		if info, err := os.Stat(thumbPath); err == nil && info.Mode().IsRegular() {
+			name, path, ext = thumbName, thumbPath, ".jpg"
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType, ok := uploadContentTypes[ext]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, name))
+	if api.uploadsRequireAuth {
+		// Shared caches must not hand a private file to someone without a token.
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	if stem := strings.TrimSuffix(name, ext); !strings.Contains(stem, `"`) {
+		w.Header().Set("ETag", `"`+stem+`"`)
+	}
+	http.ServeContent(w, r, name, info.ModTime(), f)
 }
 
 func sanitizeFilename(name string) string {
@@ -101,3 +416,52 @@ func sanitizeFilename(name string) string {
 	}
 	return name
 }
+
+type setUploadQuotaRequest struct {
+	// QuotaBytes overrides the server default for this user; null restores the default and 0 means unlimited.
+	QuotaBytes *int64 `json:"quotaBytes"`
+}
+
+type uploadUsageResponse struct {
+	UserID     string `json:"userId"`
+	UsedBytes  int64  `json:"usedBytes"`
+	QuotaBytes *int64 `json:"quotaBytes"`
+	LimitBytes int64  `json:"limitBytes"`
+}
+
+func (api *v1API) handleSetUploadQuota(w http.ResponseWriter, r *http.Request, targetUserID string) {
+	var req setUploadQuotaRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.QuotaBytes != nil && *req.QuotaBytes < 0 {
+		writeAPIError(w, ErrCodeValidation, "quotaBytes must not be negative")
+		return
+	}
+
+	targetUserID = strings.TrimSpace(targetUserID)
+	if _, err := api.store.GetUserByID(r.Context(), targetUserID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(r.Context()).Error("get user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	usage, err := api.store.SetUploadQuota(r.Context(), targetUserID, req.QuotaBytes, time.Now().UnixMilli())
+	if err != nil {
+		api.log(r.Context()).Error("set upload quota failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, uploadUsageResponse{
+		UserID:     usage.UserID,
+		UsedBytes:  usage.UsedBytes,
+		QuotaBytes: usage.QuotaBytes,
+		LimitBytes: usage.LimitBytes(api.uploadQuotaBytes),
+	})
+}
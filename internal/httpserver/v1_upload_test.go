@@ -0,0 +1,601 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func uploadFile(t *testing.T, client *http.Client, url, filename string, content []byte, token string) *http.Response {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile error = %v", err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatalf("write form file error = %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("multipart close error = %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, &buf)
+	if err != nil {
+		t.Fatalf("NewRequest error = %v", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do error = %v", err)
+	}
+	return res
+}
+
+func TestUploads_ServeOnlyAllowListedFlatNames(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	root := t.TempDir()
+	uploadDir := filepath.Join(root, "uploads")
+	if err := os.WriteFile(filepath.Join(root, "secret.png"), []byte("outside"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, uploadDir, HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	// HTML disguised as an image is rejected by content sniffing.
+	html := []byte("<html><script>alert(1)</script></html>")
+	res := uploadFile(t, client, srv.URL+"/v1/upload", "evil.png", html, tok.Token)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST /v1/upload (html as .png) status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	// A real PNG is stored and served strictly as image/png.
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	res = uploadFile(t, client, srv.URL+"/v1/upload", "pic.png", png, tok.Token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/upload status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+	var uploaded uploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode upload response error = %v", err)
+	}
+
+	fileRes := get(t, client, srv.URL+uploaded.URL, "")
+	fileRes.Body.Close()
+	if fileRes.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", uploaded.URL, fileRes.StatusCode, http.StatusOK)
+	}
+	if ct := fileRes.Header.Get("Content-Type"); ct != "image/png" {
+		t.Fatalf("Content-Type = %q, want %q", ct, "image/png")
+	}
+	if v := fileRes.Header.Get("X-Content-Type-Options"); v != "nosniff" {
+		t.Fatalf("X-Content-Type-Options = %q, want nosniff", v)
+	}
+
+	// Extensions outside the allow-list are rejected at upload time.
+	res = uploadFile(t, client, srv.URL+"/v1/upload", "page.html", html, tok.Token)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST /v1/upload (.html) status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	// ...and never served even if they land in the directory some other way.
+	if err := os.WriteFile(filepath.Join(uploadDir, "planted.html"), html, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	for _, path := range []string{
+		"/uploads/planted.html",
+		"/uploads/..%2fsecret.png",
+		"/uploads/%2e%2e/secret.png",
+		"/uploads/..%5csecret.png",
+	} {
+		res := get(t, client, srv.URL+path, "")
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			t.Fatalf("GET %s status = %d, want non-200", path, res.StatusCode)
+		}
+	}
+}
+
+func TestUploads_QuotaEnforcedWithAdminOverride(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	alice, aliceToken := newTestUser(t, store, tokenToUserID, "alice", nowMs)
+	admin, adminToken := newTestUser(t, store, tokenToUserID, "admin", nowMs)
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{
+		AdminUserIDs:     []string{admin.ID},
+		UploadQuotaBytes: 10,
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := uploadFile(t, client, srv.URL+"/v1/upload", "a.txt", []byte("12345678"), aliceToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("first upload status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res = uploadFile(t, client, srv.URL+"/v1/upload", "b.txt", []byte("12345"), aliceToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("over-quota upload status = %d, want %d", res.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+	var body struct {
+		Error struct {
+			Code    string             `json:"code"`
+			Details uploadQuotaDetails `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode quota error = %v", err)
+	}
+	if body.Error.Code != string(ErrCodeQuotaExceeded) || body.Error.Details.UsedBytes != 8 || body.Error.Details.LimitBytes != 10 {
+		t.Fatalf("quota error = %+v, want %s used=8 limit=10", body.Error, ErrCodeQuotaExceeded)
+	}
+
+	quotaURL := srv.URL + "/v1/admin/users/" + alice.ID + "/upload-quota"
+	res = putJSON(t, client, quotaURL, map[string]any{"quotaBytes": 100}, aliceToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("non-admin quota status = %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+	res = putJSON(t, client, quotaURL, map[string]any{"quotaBytes": 100}, adminToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("admin quota status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res = uploadFile(t, client, srv.URL+"/v1/upload", "b.txt", []byte("12345"), aliceToken)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("upload after override status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestUploads_AvatarPurposeLimitsSizeAndType(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+	avatarURL := srv.URL + "/v1/upload?purpose=avatar"
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+	res := uploadFile(t, client, avatarURL, "me.png", png, tok.Token)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("POST avatar status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	// Allowed for general uploads, but not as an avatar.
+	res = uploadFile(t, client, avatarURL, "notes.txt", []byte("hello"), tok.Token)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST avatar (.txt) status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	res = uploadFile(t, client, srv.URL+"/v1/upload?purpose=banner", "me.png", png, tok.Token)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST unknown purpose status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	big := append(append([]byte{}, png...), make([]byte, maxAvatarUploadSize)...)
+	res = uploadFile(t, client, avatarURL, "big.png", big, tok.Token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("POST oversized avatar status = %d, want %d", res.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+	var env apiErrorEnvelope
+	if err := json.NewDecoder(res.Body).Decode(&env); err != nil {
+		t.Fatalf("decode error response error = %v", err)
+	}
+	if env.Error.Code != string(ErrCodeFileTooLarge) {
+		t.Fatalf("error code = %q, want %q", env.Error.Code, ErrCodeFileTooLarge)
+	}
+}
+
+func TestUploads_ServeRangeCachingAndPrivateAuth(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{UploadsRequireAuth: true})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+	content := []byte("0123456789abcdef")
+	res := uploadFile(t, client, srv.URL+"/v1/upload", "notes.txt", content, tok.Token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/upload status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+	var uploaded uploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode upload response error = %v", err)
+	}
+
+	anon := get(t, client, srv.URL+uploaded.URL, "")
+	anon.Body.Close()
+	if anon.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GET without token status = %d, want %d", anon.StatusCode, http.StatusUnauthorized)
+	}
+
+	// <image> tags cannot set headers, so the token may come as a query param.
+	req, err := http.NewRequest(http.MethodGet, srv.URL+uploaded.URL+"?token="+tok.Token, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Range", "bytes=4-7")
+	ranged, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET range error = %v", err)
+	}
+	defer ranged.Body.Close()
+	if ranged.StatusCode != http.StatusPartialContent {
+		t.Fatalf("GET range status = %d, want %d", ranged.StatusCode, http.StatusPartialContent)
+	}
+	body, _ := io.ReadAll(ranged.Body)
+	if string(body) != "4567" {
+		t.Fatalf("GET range body = %q, want %q", string(body), "4567")
+	}
+	if cr := ranged.Header.Get("Content-Range"); cr != "bytes 4-7/16" {
+		t.Fatalf("Content-Range = %q, want %q", cr, "bytes 4-7/16")
+	}
+	if cc := ranged.Header.Get("Cache-Control"); !strings.HasPrefix(cc, "private,") {
+		t.Fatalf("Cache-Control = %q, want private", cc)
+	}
+	etag := ranged.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("ETag missing")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, srv.URL+uploaded.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tok.Token)
+	req.Header.Set("If-None-Match", etag)
+	cached, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET conditional error = %v", err)
+	}
+	cached.Body.Close()
+	if cached.StatusCode != http.StatusNotModified {
+		t.Fatalf("GET If-None-Match status = %d, want %d", cached.StatusCode, http.StatusNotModified)
+	}
+}
+
+func TestUploads_GeneratesThumbnailForImages(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	uploadDir := t.TempDir()
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, uploadDir, HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{R: 200, A: 0xff}), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	res := uploadFile(t, client, srv.URL+"/v1/upload", "photo.png", buf.Bytes(), tok.Token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/upload status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+	var uploaded uploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode upload response error = %v", err)
+	}
+	if uploaded.ThumbnailURL == nil {
+		t.Fatalf("thumbnailUrl missing")
+	}
+
+	thumbPath := filepath.Join(uploadDir, strings.TrimPrefix(*uploaded.ThumbnailURL, "/uploads/"))
+	f, err := os.Open(thumbPath)
+	if err != nil {
+		t.Fatalf("open thumbnail error = %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("jpeg.DecodeConfig() error = %v", err)
+	}
+	if cfg.Width != 128 || cfg.Height != 64 {
+		t.Fatalf("thumbnail size = %dx%d, want 128x64", cfg.Width, cfg.Height)
+	}
+
+	thumbRes := get(t, client, srv.URL+uploaded.URL+"?size=thumb", "")
+	thumbRes.Body.Close()
+	if thumbRes.StatusCode != http.StatusOK {
+		t.Fatalf("GET ?size=thumb status = %d, want %d", thumbRes.StatusCode, http.StatusOK)
+	}
+	if ct := thumbRes.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Fatalf("GET ?size=thumb Content-Type = %q, want image/jpeg", ct)
+	}
+
+	// An image the decoder cannot read is still accepted, just without a thumbnail.
+	broken := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	res2 := uploadFile(t, client, srv.URL+"/v1/upload", "broken.png", broken, tok.Token)
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusOK {
+		t.Fatalf("POST /v1/upload (undecodable) status = %d, want %d", res2.StatusCode, http.StatusOK)
+	}
+	var brokenUpload uploadResponse
+	if err := json.NewDecoder(res2.Body).Decode(&brokenUpload); err != nil {
+		t.Fatalf("decode upload response error = %v", err)
+	}
+	if brokenUpload.ThumbnailURL != nil {
+		t.Fatalf("thumbnailUrl = %q, want none for undecodable image", *brokenUpload.ThumbnailURL)
+	}
+}
+
+// jpegWithEXIF encodes img and inserts an APP1 EXIF segment holding the given orientation and
+// a GPS IFD pointer right after the SOI marker.
+func jpegWithEXIF(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(42))
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(8))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(2))
+	for _, e := range []struct {
+		tag, typ uint16
+		value    uint32
+	}{
+		{0x0112, 3, uint32(orientation) << 16}, // Orientation (SHORT, left-justified)
+		{0x8825, 4, 38},                        // GPSInfo IFD pointer
+	} {
+		_ = binary.Write(&tiff, binary.BigEndian, e.tag)
+		_ = binary.Write(&tiff, binary.BigEndian, e.typ)
+		_ = binary.Write(&tiff, binary.BigEndian, uint32(1))
+		_ = binary.Write(&tiff, binary.BigEndian, e.value)
+	}
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GPS 31.2304N 121.4737E")
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(enc.Bytes()[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(enc.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestUploads_StripsJPEGMetadataKeepingOrientation(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	u, err := store.CreateUser(ctx, "alice", "hash", "alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, u.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	uploadDir := t.TempDir()
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: u.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, uploadDir, HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	// A 64x32 landscape photo tagged "rotate 90° clockwise" (orientation 6).
+	src := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{G: 180, A: 0xff}), image.Point{}, draw.Src)
+	content := jpegWithEXIF(t, src, 6)
+	if !bytes.Contains(content, []byte("Exif\x00\x00")) {
+		t.Fatalf("test fixture has no EXIF segment")
+	}
+
+	res := uploadFile(t, client, srv.URL+"/v1/upload", "photo.jpg", content, tok.Token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		t.Fatalf("POST /v1/upload status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+	}
+	var uploaded uploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode upload response error = %v", err)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(uploadDir, strings.TrimPrefix(uploaded.URL, "/uploads/")))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if bytes.Contains(stored, []byte("Exif")) || bytes.Contains(stored, []byte("GPS")) {
+		t.Fatalf("stored file still carries EXIF metadata")
+	}
+	if uploaded.SizeBytes != int64(len(stored)) {
+		t.Fatalf("sizeBytes = %d, want stored size %d", uploaded.SizeBytes, len(stored))
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatalf("jpeg.DecodeConfig() error = %v", err)
+	}
+	if cfg.Width != 32 || cfg.Height != 64 {
+		t.Fatalf("stored size = %dx%d, want 32x64 after applying orientation", cfg.Width, cfg.Height)
+	}
+}
+
+func TestStripJPEGMetadata_RejectsOversizedFrame(t *testing.T) {
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	data := enc.Bytes()
+
+	// Rewrite the SOF0 frame header to declare 30000x30000 without adding any pixel data.
+	sof := bytes.Index(data, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatalf("test fixture has no SOF0 segment")
+	}
+	binary.BigEndian.PutUint16(data[sof+5:], 30000)
+	binary.BigEndian.PutUint16(data[sof+7:], 30000)
+
+	path := filepath.Join(t.TempDir(), "bomb.jpg")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := stripJPEGMetadata(path); err == nil {
+		t.Fatalf("stripJPEGMetadata() error = nil, want dimension error")
+	}
+}
+
+func TestScaleDownImage_FlattensAlphaOntoWhite(t *testing.T) {
+	// Fully transparent left half, opaque red right half.
+	src := image.NewNRGBA(image.Rect(0, 0, 256, 128))
+	draw.Draw(src, image.Rect(128, 0, 256, 128), image.NewUniform(color.NRGBA{R: 200, A: 0xff}), image.Point{}, draw.Src)
+
+	thumb := scaleDownImage(src, 128)
+	if got := thumb.Bounds().Size(); got != (image.Point{X: 128, Y: 64}) {
+		t.Fatalf("thumbnail size = %v, want 128x64", got)
+	}
+	if got := thumb.RGBAAt(10, 10); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("transparent area = %+v, want white", got)
+	}
+	if got := thumb.RGBAAt(100, 10); got != (color.RGBA{R: 200, A: 0xff}) {
+		t.Fatalf("opaque area = %+v, want red", got)
+	}
+}
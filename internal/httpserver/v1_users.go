@@ -2,9 +2,14 @@ package httpserver
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"linkbridge-backend/internal/storage"
 )
@@ -27,6 +32,10 @@ type updateMeResponse struct {
 	User userItem `json:"user"`
 }
 
+type deleteMeRequest struct {
+	Password string `json:"password"`
+}
+
 func (api *v1API) handleUsers(w http.ResponseWriter, r *http.Request) {
 	rest := strings.TrimPrefix(r.URL.Path, "/v1/users")
 	if rest == "" || rest == "/" {
@@ -38,15 +47,55 @@ func (api *v1API) handleUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rest == "/search" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleSearchUsers(w, r)
+		return
+	}
+
 	if rest == "/me" {
-		if r.Method == http.MethodPut {
+		switch r.Method {
+		case http.MethodPut:
 			api.handleUpdateMe(w, r)
 			return
+		case http.MethodDelete:
+			api.handleDeleteMe(w, r)
+			return
 		}
 		writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
+	if rest == "/me/export-token" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleCreateExportToken(w, r)
+		return
+	}
+
+	if rest == "/me/export" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleExportMe(w, r)
+		return
+	}
+
+	if parts := splitPath(rest); len(parts) == 3 && parts[1] == "profiles" && parts[2] == "card" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		api.handleGetUserCard(w, r, parts[0])
+		return
+	}
+
 	if strings.HasPrefix(rest, "/") {
 		userID := strings.TrimPrefix(rest, "/")
 		if r.Method != http.MethodGet {
@@ -72,17 +121,32 @@ func (api *v1API) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, ErrCodeValidation, "query parameter 'q' is required")
 		return
 	}
+	if utf8.RuneCountInString(query) > storage.MaxUserSearchQueryLen {
+		writeAPIError(w, ErrCodeValidation, fmt.Sprintf("query must be at most %d characters", storage.MaxUserSearchQueryLen))
+		return
+	}
+
+	limit := 20
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > storage.MaxUserSearchLimit {
+			writeAPIError(w, ErrCodeValidation, fmt.Sprintf("limit must be between 1 and %d", storage.MaxUserSearchLimit))
+			return
+		}
+		limit = n
+	}
 
-	users, err := api.store.SearchUsers(r.Context(), query, 20)
+	// One extra row leaves room for the caller, who is filtered out below.
+	users, err := api.store.SearchUsers(r.Context(), query, limit+1)
 	if err != nil {
-		api.logger.Error("search users failed", "error", err)
+		api.log(r.Context()).Error("search users failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
 	items := make([]userItem, 0, len(users))
 	for _, u := range users {
-		if u.ID == currentUserID {
+		if u.ID == currentUserID || len(items) == limit {
 			continue
 		}
 		items = append(items, userItem{
@@ -120,7 +184,7 @@ func (api *v1API) handleGetUser(w http.ResponseWriter, r *http.Request, userID s
 			writeAPIError(w, ErrCodeUserNotFound, "user not found")
 			return
 		}
-		api.logger.Error("get user failed", "error", err)
+		api.log(r.Context()).Error("get user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -188,7 +252,7 @@ func (api *v1API) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
 			writeAPIError(w, ErrCodeUserNotFound, "user not found")
 			return
 		}
-		api.logger.Error("get current user failed", "error", err)
+		api.log(r.Context()).Error("get current user failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -200,7 +264,7 @@ func (api *v1API) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
 				writeAPIError(w, ErrCodeUserNotFound, "user not found")
 				return
 			}
-			api.logger.Error("update user display name failed", "error", err)
+			api.log(r.Context()).Error("update user display name failed", "error", err)
 			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
@@ -213,7 +277,7 @@ func (api *v1API) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
 				writeAPIError(w, ErrCodeUserNotFound, "user not found")
 				return
 			}
-			api.logger.Error("update user avatar failed", "error", err)
+			api.log(r.Context()).Error("update user avatar failed", "error", err)
 			writeAPIError(w, ErrCodeInternal, "internal error")
 			return
 		}
@@ -228,3 +292,67 @@ func (api *v1API) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// handleDeleteMe erases the caller's account after re-checking their password. See
+// storage.DeleteUser for what is deleted and what is anonymized.
+func (api *v1API) handleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	currentUserID := getUserIDFromContext(r.Context())
+	if currentUserID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+
+	var req deleteMeRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	if req.Password == "" {
+		writeAPIError(w, ErrCodeValidation, "password is required")
+		return
+	}
+
+	user, err := api.store.GetUserByID(r.Context(), currentUserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(r.Context()).Error("get user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if !api.verifyPasswordThrottled(w, user, req.Password) {
+		return
+	}
+
+	uploads, err := api.store.DeleteUser(r.Context(), currentUserID, time.Now().UnixMilli())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeUserNotFound, "user not found")
+			return
+		}
+		api.log(r.Context()).Error("delete user failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	uploadDir := api.uploadDir
+	if uploadDir == "" {
+		uploadDir = "./uploads"
+	}
+	for _, name := range uploads {
+		name = filepath.Base(name)
+		for _, file := range []string{name, thumbnailName(name)} {
+			if err := os.Remove(filepath.Join(uploadDir, file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				api.log(r.Context()).Warn("remove deleted user upload failed", "error", err, "name", file)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
+
+	if api.wsManager != nil {
+		api.wsManager.CloseUser(currentUserID, "account deleted")
+	}
+}
@@ -0,0 +1,380 @@
+package httpserver
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/ws"
+)
+
+func TestDeleteMe_RequiresPasswordAndRevokesAccess(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	tokenToUserID := map[string]string{}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+		"username":    "alice",
+		"password":    "P@ssw0rd1",
+		"displayName": "Alice",
+	}, "")
+	var registered authResponse
+	if err := json.NewDecoder(res.Body).Decode(&registered); err != nil {
+		t.Fatalf("decode register response error = %v", err)
+	}
+	_ = res.Body.Close()
+	token := registered.Token
+
+	deleteMe := func(password string) *http.Response {
+		t.Helper()
+		b, _ := json.Marshal(map[string]any{"password": password})
+		req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/users/me", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do error = %v", err)
+		}
+		return res
+	}
+
+	wrong := deleteMe("not-the-password")
+	_ = wrong.Body.Close()
+	if wrong.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("DELETE /v1/users/me with wrong password status = %d, want 401", wrong.StatusCode)
+	}
+
+	ok := deleteMe("P@ssw0rd1")
+	if ok.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(ok.Body)
+		t.Fatalf("DELETE /v1/users/me status = %d, body=%s", ok.StatusCode, string(b))
+	}
+	_ = ok.Body.Close()
+
+	me := get(t, client, srv.URL+"/v1/auth/me", token)
+	_ = me.Body.Close()
+	if me.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GET /v1/auth/me after deletion status = %d, want 401", me.StatusCode)
+	}
+
+	login := postJSON(t, client, srv.URL+"/v1/auth/login", map[string]any{
+		"username": "alice",
+		"password": "P@ssw0rd1",
+	}, "")
+	_ = login.Body.Close()
+	if login.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("login after deletion status = %d, want 401", login.StatusCode)
+	}
+}
+
+func TestDeleteMe_WrongPasswordsAreThrottled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	hash, err := bcrypt.GenerateFromPassword([]byte("P@ssw0rd1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	alice, err := store.CreateUser(ctx, "alice", string(hash), "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, alice.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: alice.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	deleteMe := func(password string) *http.Response {
+		t.Helper()
+		b, _ := json.Marshal(map[string]any{"password": password})
+		req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/users/me", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tok.Token)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do error = %v", err)
+		}
+		return res
+	}
+
+	for i := 0; i < loginMaxFailuresPerUser; i++ {
+		res := deleteMe("nope")
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("DELETE /v1/users/me wrong password #%d status = %d, want 401", i+1, res.StatusCode)
+		}
+	}
+
+	// Locked out: even the right password is refused and the account survives.
+	locked := deleteMe("P@ssw0rd1")
+	var errEnv struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(locked.Body).Decode(&errEnv)
+	_ = locked.Body.Close()
+	if locked.StatusCode != http.StatusTooManyRequests || errEnv.Error.Code != string(ErrCodeRateLimited) {
+		t.Fatalf("DELETE /v1/users/me while locked = %d %q, want 429 %s", locked.StatusCode, errEnv.Error.Code, ErrCodeRateLimited)
+	}
+	if locked.Header.Get("Retry-After") == "" {
+		t.Fatalf("DELETE /v1/users/me while locked missing Retry-After header")
+	}
+	if _, err := store.GetUserByID(ctx, alice.ID); err != nil {
+		t.Fatalf("GetUserByID() after locked delete error = %v", err)
+	}
+}
+
+func TestExportMe_RequiresConfirmationAndStreamsZip(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	hash, err := bcrypt.GenerateFromPassword([]byte("P@ssw0rd1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	alice, err := store.CreateUser(ctx, "alice", string(hash), "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, alice.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+	token := tok.Token
+
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, nowMs)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	text := "hello bob"
+	if _, err := store.CreateMessage(ctx, session.ID, alice.ID, storage.MessageTypeText, &text, nil, nowMs); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	if _, _, err := store.CreateBurnMessage(ctx, session.ID, bob.ID, []byte(`{"ciphertext":"abc"}`), 60_000, nowMs+1); err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{token: alice.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	wrong := postJSON(t, client, srv.URL+"/v1/users/me/export-token", map[string]any{"password": "nope"}, token)
+	_ = wrong.Body.Close()
+	if wrong.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("export-token with wrong password status = %d, want 401", wrong.StatusCode)
+	}
+
+	unconfirmed := get(t, client, srv.URL+"/v1/users/me/export", token)
+	_ = unconfirmed.Body.Close()
+	if unconfirmed.StatusCode != http.StatusForbidden {
+		t.Fatalf("export without confirmation status = %d, want 403", unconfirmed.StatusCode)
+	}
+
+	res := postJSON(t, client, srv.URL+"/v1/users/me/export-token", map[string]any{"password": "P@ssw0rd1"}, token)
+	var confirm exportTokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&confirm); err != nil {
+		t.Fatalf("decode export-token response error = %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || confirm.Token == "" {
+		t.Fatalf("export-token status = %d, token = %q", res.StatusCode, confirm.Token)
+	}
+
+	export := get(t, client, srv.URL+"/v1/users/me/export?confirm="+confirm.Token, token)
+	body, err := io.ReadAll(export.Body)
+	_ = export.Body.Close()
+	if err != nil {
+		t.Fatalf("read export body error = %v", err)
+	}
+	if export.StatusCode != http.StatusOK {
+		t.Fatalf("export status = %d, body=%s", export.StatusCode, string(body))
+	}
+	if ct := export.Header.Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("export Content-Type = %q, want application/zip", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"profile.json", "friends.json", "sessions.json", "messages.ndjson"} {
+		if files[name] == nil {
+			t.Fatalf("export is missing %s", name)
+		}
+	}
+
+	rc, err := files["messages.ndjson"].Open()
+	if err != nil {
+		t.Fatalf("open messages.ndjson error = %v", err)
+	}
+	var messages []exportMessage
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		var m exportMessage
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("decode message line %q error = %v", scanner.Text(), err)
+		}
+		messages = append(messages, m)
+	}
+	_ = rc.Close()
+	if len(messages) != 1 || messages[0].Text == nil || *messages[0].Text != text {
+		t.Fatalf("exported messages = %+v, want only the text message", messages)
+	}
+
+	reused := get(t, client, srv.URL+"/v1/users/me/export?confirm="+confirm.Token, token)
+	_ = reused.Body.Close()
+	if reused.StatusCode != http.StatusForbidden {
+		t.Fatalf("export with a used confirmation status = %d, want 403", reused.StatusCode)
+	}
+}
+
+func TestExportToken_WrongPasswordsAreThrottled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	hash, err := bcrypt.GenerateFromPassword([]byte("P@ssw0rd1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	alice, err := store.CreateUser(ctx, "alice", string(hash), "Alice", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	tok, err := store.CreateAuthToken(ctx, alice.ID, nil, nowMs, nowMs+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: map[string]string{tok.Token: alice.ID}}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	client := srv.Client()
+
+	for i := 0; i < loginMaxFailuresPerUser; i++ {
+		res := postJSON(t, client, srv.URL+"/v1/users/me/export-token", map[string]any{"password": "nope"}, tok.Token)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("export-token wrong password #%d status = %d, want 401", i+1, res.StatusCode)
+		}
+	}
+
+	// Locked out: even the right password is refused without being checked.
+	locked := postJSON(t, client, srv.URL+"/v1/users/me/export-token", map[string]any{"password": "P@ssw0rd1"}, tok.Token)
+	_ = locked.Body.Close()
+	if locked.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("export-token while locked status = %d, want 429", locked.StatusCode)
+	}
+	if locked.Header.Get("Retry-After") == "" {
+		t.Fatalf("export-token while locked missing Retry-After header")
+	}
+}
+
+func TestSearchUsers_FullPageExcludesCaller(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Now().UnixMilli()
+	tokenToUserID := map[string]string{}
+	// The caller sorts first for the query, so it takes the row that must be dropped.
+	_, token := newTestUser(t, store, tokenToUserID, "user", nowMs)
+	for i := 0; i < storage.MaxUserSearchLimit; i++ {
+		newTestUser(t, store, nil, "user"+string(rune('a'+i/26))+string(rune('a'+i%26)), nowMs)
+	}
+
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, t.TempDir(), HandlerOptions{})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res := get(t, srv.Client(), srv.URL+"/v1/users/search?q=user&limit=50", token)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("search status = %d, want 200", res.StatusCode)
+	}
+	var body searchUsersResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode search response error = %v", err)
+	}
+	if len(body.Users) != storage.MaxUserSearchLimit {
+		t.Fatalf("search returned %d users, want %d", len(body.Users), storage.MaxUserSearchLimit)
+	}
+	for _, u := range body.Users {
+		if u.Username == "user" {
+			t.Fatalf("search returned the caller")
+		}
+	}
+}
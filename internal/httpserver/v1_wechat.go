@@ -21,6 +21,17 @@ type bindWeChatResponse struct {
 	Bound bool `json:"bound"`
 }
 
+type bindWeChatPhoneRequest struct {
+	EncryptedData string `json:"encryptedData"`
+	IV            string `json:"iv"`
+}
+
+type bindWeChatPhoneResponse struct {
+	PhoneNumber     string `json:"phoneNumber"`
+	PurePhoneNumber string `json:"purePhoneNumber"`
+	CountryCode     string `json:"countryCode"`
+}
+
 type geoFenceItem struct {
 	Lat     float64 `json:"lat"`
 	Lng     float64 `json:"lng"`
@@ -31,9 +42,14 @@ type inviteSettingsItem struct {
 	Code        string        `json:"code"`
 	ExpiresAtMs *int64        `json:"expiresAtMs,omitempty"`
 	GeoFence    *geoFenceItem `json:"geoFence,omitempty"`
+	MaxUses     *int          `json:"maxUses,omitempty"`
+	UsedCount   int           `json:"usedCount,omitempty"`
 	UpdatedAtMs int64         `json:"updatedAtMs"`
 }
 
+// maxActivityInviteUses bounds the per-invite usage cap; larger groups should leave it unset.
+const maxActivityInviteUses = 10000
+
 type inviteSettingsResponse struct {
 	Invite inviteSettingsItem `json:"invite"`
 }
@@ -60,6 +76,13 @@ func (api *v1API) handleWeChat(w http.ResponseWriter, r *http.Request) {
 			}
 			api.handleWeChatBind(w, r)
 			return
+		case "phone":
+			if r.Method != http.MethodPost {
+				writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
+				return
+			}
+			api.handleWeChatBindPhone(w, r)
+			return
 		case "subscribe-templates":
 			if r.Method != http.MethodGet {
 				writeAPIError(w, ErrCodeMethodNotAllowed, "method not allowed")
@@ -199,6 +222,8 @@ func inviteSettingsItemFromActivityInviteRow(row storage.ActivityInviteRow) invi
 		Code:        row.Code,
 		ExpiresAtMs: row.ExpiresAtMs,
 		GeoFence:    gf,
+		MaxUses:     row.MaxUses,
+		UsedCount:   row.UsedCount,
 		UpdatedAtMs: row.UpdatedAtMs,
 	}
 }
@@ -276,7 +301,7 @@ func (api *v1API) handleWeChatSessionInviteSettings(w http.ResponseWriter, r *ht
 	nowMs := time.Now().UnixMilli()
 	invite, _, err := api.store.GetOrCreateSessionInvite(r.Context(), userID, nowMs)
 	if err != nil {
-		api.logger.Error("get session invite failed", "error", err)
+		api.log(r.Context()).Error("get session invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -300,7 +325,7 @@ func (api *v1API) handleWeChatUpdateSessionInviteSettings(w http.ResponseWriter,
 	nowMs := time.Now().UnixMilli()
 	current, _, err := api.store.GetOrCreateSessionInvite(r.Context(), userID, nowMs)
 	if err != nil {
-		api.logger.Error("get session invite failed", "error", err)
+		api.log(r.Context()).Error("get session invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -317,7 +342,11 @@ func (api *v1API) handleWeChatUpdateSessionInviteSettings(w http.ResponseWriter,
 
 	updated, err := api.store.UpdateSessionInviteSettings(r.Context(), userID, expiresAtMs, geoFence, nowMs)
 	if err != nil {
-		api.logger.Error("update session invite settings failed", "error", err)
+		if errors.Is(err, storage.ErrRateLimited) {
+			writeAPIError(w, ErrCodeRateLimited, "too many invite settings updates today")
+			return
+		}
+		api.log(r.Context()).Error("update session invite settings failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -344,7 +373,7 @@ func (api *v1API) handleWeChatActivityInviteSettings(w http.ResponseWriter, r *h
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity failed", "error", err)
+		api.log(r.Context()).Error("get activity failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -356,7 +385,7 @@ func (api *v1API) handleWeChatActivityInviteSettings(w http.ResponseWriter, r *h
 	nowMs := time.Now().UnixMilli()
 	invite, _, err := api.store.GetOrCreateActivityInvite(r.Context(), activityID, nowMs)
 	if err != nil {
-		api.logger.Error("get activity invite failed", "error", err)
+		api.log(r.Context()).Error("get activity invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -383,7 +412,7 @@ func (api *v1API) handleWeChatUpdateActivityInviteSettings(w http.ResponseWriter
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity failed", "error", err)
+		api.log(r.Context()).Error("get activity failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -401,7 +430,7 @@ func (api *v1API) handleWeChatUpdateActivityInviteSettings(w http.ResponseWriter
 	nowMs := time.Now().UnixMilli()
 	current, _, err := api.store.GetOrCreateActivityInvite(r.Context(), activityID, nowMs)
 	if err != nil {
-		api.logger.Error("get activity invite failed", "error", err)
+		api.log(r.Context()).Error("get activity invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -411,14 +440,42 @@ func (api *v1API) handleWeChatUpdateActivityInviteSettings(w http.ResponseWriter
 		writeAPIError(w, ErrCodeValidation, err.Error())
 		return
 	}
+	maxUses := current.MaxUses
+	if raw, exists := patch["maxUses"]; exists {
+		ok = true
+		// Only an explicit null lifts the cap; zero or a negative count is a mistake, not
+		// "unlimited", so parseNullableInt64 (which maps them to nil) is not used here.
+		var v *int64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			writeAPIError(w, ErrCodeValidation, "invalid maxUses")
+			return
+		}
+		maxUses = nil
+		if v != nil {
+			if *v < 1 {
+				writeAPIError(w, ErrCodeValidation, "maxUses must be at least 1")
+				return
+			}
+			if *v > maxActivityInviteUses {
+				writeAPIError(w, ErrCodeValidation, "maxUses too large")
+				return
+			}
+			n := int(*v)
+			maxUses = &n
+		}
+	}
 	if !ok {
-		writeAPIError(w, ErrCodeValidation, "expiresAtMs or geoFence is required")
+		writeAPIError(w, ErrCodeValidation, "expiresAtMs, geoFence or maxUses is required")
 		return
 	}
 
-	updated, err := api.store.UpdateActivityInviteSettings(r.Context(), activityID, expiresAtMs, geoFence, nowMs)
+	updated, err := api.store.UpdateActivityInviteSettings(r.Context(), activityID, expiresAtMs, geoFence, maxUses, nowMs)
 	if err != nil {
-		api.logger.Error("update activity invite settings failed", "error", err)
+		if errors.Is(err, storage.ErrRateLimited) {
+			writeAPIError(w, ErrCodeRateLimited, "too many invite settings updates today")
+			return
+		}
+		api.log(r.Context()).Error("update activity invite settings failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -453,14 +510,14 @@ func (api *v1API) handleWeChatBind(w http.ResponseWriter, r *http.Request) {
 
 	cs, err := api.wechatClient.ExchangeCode(ctx, req.Code)
 	if err != nil {
-		api.logger.Warn("wechat bind exchange code failed", "error", err)
+		api.log(r.Context()).Warn("wechat bind exchange code failed", "error", err)
 		writeAPIError(w, ErrCodeWeChatAPI, "wechat API error")
 		return
 	}
 
 	nowMs := time.Now().UnixMilli()
 	if _, err := api.store.UpsertWeChatBinding(r.Context(), userID, cs.OpenID, cs.SessionKey, cs.UnionID, nowMs); err != nil {
-		api.logger.Error("save wechat binding failed", "error", err)
+		api.log(r.Context()).Error("save wechat binding failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -468,6 +525,69 @@ func (api *v1API) handleWeChatBind(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, bindWeChatResponse{Bound: true})
 }
 
+// handleWeChatBindPhone decrypts the payload of wx.getPhoneNumber with the session_key saved
+// by POST /v1/wechat/bind and stores the phone number on the binding.
+func (api *v1API) handleWeChatBindPhone(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == "" {
+		writeAPIError(w, ErrCodeTokenInvalid, "authentication required")
+		return
+	}
+	if api.wechatClient == nil || strings.TrimSpace(api.wechatAppID) == "" {
+		writeAPIError(w, ErrCodeWeChatNotConfigured, "wechat integration not configured")
+		return
+	}
+
+	var req bindWeChatPhoneRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeAPIError(w, ErrCodeValidation, "invalid JSON body")
+		return
+	}
+	req.EncryptedData = strings.TrimSpace(req.EncryptedData)
+	req.IV = strings.TrimSpace(req.IV)
+	if req.EncryptedData == "" || req.IV == "" {
+		writeAPIError(w, ErrCodeValidation, "encryptedData and iv are required")
+		return
+	}
+
+	binding, err := api.store.GetWeChatBindingByUserID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeWeChatNotBound, "wechat not bound")
+			return
+		}
+		api.log(r.Context()).Error("get wechat binding failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	phone, err := api.wechatClient.DecryptPhoneNumber(binding.SessionKey, req.EncryptedData, req.IV)
+	if err != nil {
+		if errors.Is(err, wechat.ErrDecryptFailed) || errors.Is(err, wechat.ErrWatermarkMismatch) {
+			// A stale session_key is the usual cause; the client should call wx.login and
+			// POST /v1/wechat/bind again before retrying.
+			api.log(r.Context()).Info("wechat phone decrypt failed", "error", err)
+			writeAPIError(w, ErrCodeWeChatDecryptFailed, "could not decrypt phone number")
+			return
+		}
+		api.log(r.Context()).Error("wechat phone decrypt failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	if err := api.store.SetWeChatBindingPhone(r.Context(), userID, phone.PhoneNumber, time.Now().UnixMilli()); err != nil {
+		api.log(r.Context()).Error("save wechat phone failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bindWeChatPhoneResponse{
+		PhoneNumber:     phone.PhoneNumber,
+		PurePhoneNumber: phone.PurePhoneNumber,
+		CountryCode:     phone.CountryCode,
+	})
+}
+
 func (api *v1API) handleWeChatSessionQRCode(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == "" {
@@ -482,7 +602,7 @@ func (api *v1API) handleWeChatSessionQRCode(w http.ResponseWriter, r *http.Reque
 	nowMs := time.Now().UnixMilli()
 	invite, _, err := api.store.GetOrCreateSessionInvite(r.Context(), userID, nowMs)
 	if err != nil {
-		api.logger.Error("create session invite failed", "error", err)
+		api.log(r.Context()).Error("create session invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -492,20 +612,20 @@ func (api *v1API) handleWeChatSessionQRCode(w http.ResponseWriter, r *http.Reque
 
 	accessToken, err := api.wechatClient.GetAccessToken(ctx)
 	if err != nil {
-		api.logger.Warn("wechat get access token failed", "error", err)
+		api.log(r.Context()).Warn("wechat get access token failed", "error", err)
 		writeAPIError(w, ErrCodeWeChatAPI, "wechat API error")
 		return
 	}
 
 	png, err := api.wechatClient.GetWxaCodeUnlimit(ctx, accessToken, wechat.WxaCodeUnlimitRequest{
 		Scene:      "c=" + invite.Code,
-		Page:       "pages/linkbridge/add-friend/add-friend",
+		Page:       wechatInviteQRPage,
 		CheckPath:  false,
 		EnvVersion: "develop",
 		Width:      430,
 	})
 	if err != nil {
-		api.logger.Warn("wechat getwxacodeunlimit failed", "error", err)
+		api.log(r.Context()).Warn("wechat getwxacodeunlimit failed", "error", err)
 		writeAPIError(w, ErrCodeWeChatAPI, "wechat API error")
 		return
 	}
@@ -539,7 +659,7 @@ func (api *v1API) handleWeChatActivityQRCode(w http.ResponseWriter, r *http.Requ
 			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
 			return
 		}
-		api.logger.Error("get activity failed", "error", err)
+		api.log(r.Context()).Error("get activity failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
@@ -551,30 +671,62 @@ func (api *v1API) handleWeChatActivityQRCode(w http.ResponseWriter, r *http.Requ
 	nowMs := time.Now().UnixMilli()
 	invite, _, err := api.store.GetOrCreateActivityInvite(r.Context(), activityID, nowMs)
 	if err != nil {
-		api.logger.Error("create activity invite failed", "error", err)
+		api.log(r.Context()).Error("create activity invite failed", "error", err)
 		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
-	defer cancel()
+	api.writeActivityInviteQRCode(w, r, invite.Code)
+}
 
-	accessToken, err := api.wechatClient.GetAccessToken(ctx)
+// handleActivityInviteQRCode serves the mini program code for the activity's stable invite
+// code to any participant, unlike the creator-only /v1/wechat/qrcode/activity.
+func (api *v1API) handleActivityInviteQRCode(w http.ResponseWriter, r *http.Request, userID, activityID string) {
+	if api.wechatClient == nil || strings.TrimSpace(api.wechatAppID) == "" {
+		writeAPIError(w, ErrCodeWeChatNotConfigured, "wechat integration not configured")
+		return
+	}
+
+	activity, err := api.store.GetActivityByID(r.Context(), activityID)
 	if err != nil {
-		api.logger.Warn("wechat get access token failed", "error", err)
-		writeAPIError(w, ErrCodeWeChatAPI, "wechat API error")
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAPIError(w, ErrCodeActivityNotFound, "activity not found")
+			return
+		}
+		api.log(r.Context()).Error("get activity failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
 		return
 	}
 
-	png, err := api.wechatClient.GetWxaCodeUnlimit(ctx, accessToken, wechat.WxaCodeUnlimitRequest{
-		Scene:      "a=" + invite.Code,
-		Page:       "pages/linkbridge/add-friend/add-friend",
-		CheckPath:  false,
-		EnvVersion: "develop",
-		Width:      430,
-	})
+	ok, err := api.store.IsSessionParticipant(r.Context(), activity.SessionID, userID)
+	if err != nil {
+		api.log(r.Context()).Error("check activity participant failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+	if !ok {
+		writeAPIError(w, ErrCodeActivityAccessDenied, "access denied")
+		return
+	}
+
+	invite, _, err := api.store.GetOrCreateActivityInvite(r.Context(), activityID, time.Now().UnixMilli())
+	if err != nil {
+		api.log(r.Context()).Error("create activity invite failed", "error", err)
+		writeAPIError(w, ErrCodeInternal, "internal error")
+		return
+	}
+
+	api.writeActivityInviteQRCode(w, r, invite.Code)
+}
+
+// writeActivityInviteQRCode responds with the mini program code for an activity invite code.
+func (api *v1API) writeActivityInviteQRCode(w http.ResponseWriter, r *http.Request, code string) {
+	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
+	defer cancel()
+
+	png, err := api.activityInviteQRCode(ctx, code)
 	if err != nil {
-		api.logger.Warn("wechat getwxacodeunlimit failed", "error", err)
+		api.log(r.Context()).Warn("wechat activity qrcode failed", "error", err)
 		writeAPIError(w, ErrCodeWeChatAPI, "wechat API error")
 		return
 	}
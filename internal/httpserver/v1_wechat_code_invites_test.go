@@ -7,13 +7,52 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"linkbridge-backend/internal/storage"
+	"linkbridge-backend/internal/wechat"
 	"linkbridge-backend/internal/ws"
 )
 
+var fakePNG = []byte("\x89PNG\r\n\x1a\nfake")
+
+// fakeWeChatClient returns fakePNG for every mini program code and records the requests.
+type fakeWeChatClient struct {
+	mu       sync.Mutex
+	requests []wechat.WxaCodeUnlimitRequest
+}
+
+func (c *fakeWeChatClient) ExchangeCode(ctx context.Context, code string) (wechat.CodeSession, error) {
+	return wechat.CodeSession{}, nil
+}
+
+func (c *fakeWeChatClient) GetAccessToken(ctx context.Context) (string, error) {
+	return "fake-access-token", nil
+}
+
+func (c *fakeWeChatClient) SendSubscribeMessage(ctx context.Context, accessToken string, req wechat.SubscribeSendRequest) error {
+	return nil
+}
+
+func (c *fakeWeChatClient) GetWxaCodeUnlimit(ctx context.Context, accessToken string, req wechat.WxaCodeUnlimitRequest) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	return fakePNG, nil
+}
+
+func (c *fakeWeChatClient) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (wechat.PhoneNumber, error) {
+	return wechat.PhoneNumber{}, wechat.ErrDecryptFailed
+}
+
+func (c *fakeWeChatClient) wxaCodeRequests() []wechat.WxaCodeUnlimitRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]wechat.WxaCodeUnlimitRequest(nil), c.requests...)
+}
+
 func TestWeChatCode_SessionInviteSettings_ExpiryAndGeoFence(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 
@@ -247,6 +286,17 @@ func TestWeChatCode_ActivityInviteSettings_GeoFence(t *testing.T) {
 		t.Fatalf("PUT activity invite status = %d, want %d, body=%s", putRes.StatusCode, http.StatusOK, string(b))
 	}
 
+	// A zero or negative maxUses must not silently lift the cap; only null does.
+	for _, maxUses := range []int{0, -1} {
+		res := putJSON(t, client, srv.URL+"/v1/wechat/code/activity/invite?activityId="+created.Activity.ID, map[string]any{
+			"maxUses": maxUses,
+		}, creatorToken)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("PUT activity invite maxUses=%d status = %d, want %d", maxUses, res.StatusCode, http.StatusBadRequest)
+		}
+	}
+
 	// Member consume invite without location -> GEOFENCE_REQUIRED.
 	missingLocRes := postJSON(t, client, srv.URL+"/v1/activities/invites/consume", map[string]any{
 		"code": created.InviteCode,
@@ -278,3 +328,113 @@ func TestWeChatCode_ActivityInviteSettings_GeoFence(t *testing.T) {
 		t.Fatalf("consume ok status = %d, want %d, body=%s", okRes.StatusCode, http.StatusOK, string(b))
 	}
 }
+
+func TestActivityInviteQR_ParticipantsOnlyAndCached(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ctx := context.Background()
+	store, err := storage.Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("storage.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	wc := &fakeWeChatClient{}
+	tokenToUserID := map[string]string{}
+	wsManager := ws.NewManager(logger, tokenMapValidator{tokenToUserID: tokenToUserID}, noopCallStore{})
+	handler := NewHandler(logger, store, wsManager, "", HandlerOptions{WeChatAppID: "wx-test", WeChatClient: wc})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := srv.Client()
+
+	register := func(username string) string {
+		res := postJSON(t, client, srv.URL+"/v1/auth/register", map[string]any{
+			"username":    username,
+			"password":    "P@ssw0rd1",
+			"displayName": username,
+		}, "")
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(res.Body)
+			t.Fatalf("register status = %d, want %d, body=%s", res.StatusCode, http.StatusOK, string(b))
+		}
+		var body struct {
+			User struct {
+				ID string `json:"id"`
+			} `json:"user"`
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("decode register response error = %v", err)
+		}
+		tokenToUserID[body.Token] = body.User.ID
+		return body.Token
+	}
+
+	creatorToken := register("creator")
+	memberToken := register("member")
+	outsiderToken := register("outsider")
+
+	createRes := postJSON(t, client, srv.URL+"/v1/activities", map[string]any{
+		"title":   "Test Activity",
+		"endAtMs": time.Now().Add(2 * time.Hour).UnixMilli(),
+	}, creatorToken)
+	defer createRes.Body.Close()
+	if createRes.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(createRes.Body)
+		t.Fatalf("POST /v1/activities status = %d, want %d, body=%s", createRes.StatusCode, http.StatusOK, string(b))
+	}
+	var created struct {
+		Activity struct {
+			ID string `json:"id"`
+		} `json:"activity"`
+		InviteCode string `json:"inviteCode"`
+	}
+	if err := json.NewDecoder(createRes.Body).Decode(&created); err != nil {
+		t.Fatalf("decode create activity response error = %v", err)
+	}
+	qrURL := srv.URL + "/v1/activities/" + created.Activity.ID + "/invite/qr"
+
+	outsiderRes := get(t, client, qrURL, outsiderToken)
+	defer outsiderRes.Body.Close()
+	if outsiderRes.StatusCode != http.StatusForbidden {
+		b, _ := io.ReadAll(outsiderRes.Body)
+		t.Fatalf("outsider GET invite qr status = %d, want %d, body=%s", outsiderRes.StatusCode, http.StatusForbidden, string(b))
+	}
+
+	consumeRes := postJSON(t, client, srv.URL+"/v1/activities/invites/consume", map[string]any{
+		"code": created.InviteCode,
+	}, memberToken)
+	defer consumeRes.Body.Close()
+	if consumeRes.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(consumeRes.Body)
+		t.Fatalf("consume invite status = %d, want %d, body=%s", consumeRes.StatusCode, http.StatusOK, string(b))
+	}
+
+	for i := 0; i < 2; i++ {
+		res := get(t, client, qrURL, memberToken)
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("member GET invite qr #%d status = %d, want %d, body=%s", i+1, res.StatusCode, http.StatusOK, string(b))
+		}
+		if ct := res.Header.Get("Content-Type"); ct != "image/png" {
+			t.Fatalf("Content-Type = %q, want %q", ct, "image/png")
+		}
+		if string(b) != string(fakePNG) {
+			t.Fatalf("body = %q, want %q", b, fakePNG)
+		}
+	}
+
+	requests := wc.wxaCodeRequests()
+	if len(requests) != 1 {
+		t.Fatalf("GetWxaCodeUnlimit calls = %d, want 1", len(requests))
+	}
+	if requests[0].Scene != "a="+created.InviteCode {
+		t.Fatalf("scene = %q, want %q", requests[0].Scene, "a="+created.InviteCode)
+	}
+	if requests[0].Page != wechatInviteQRPage {
+		t.Fatalf("page = %q, want %q", requests[0].Page, wechatInviteQRPage)
+	}
+}
@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"context"
+	"sync"
+
+	"linkbridge-backend/internal/wechat"
+)
+
+const (
+	// wechatInviteQRPage is the mini program page that decodes invite scenes ("c=" for
+	// session invites, "a=" for activity invites).
+	wechatInviteQRPage = "pages/linkbridge/add-friend/add-friend"
+
+	// qrCodeCacheSize bounds the cached PNGs (roughly 30-60KB each).
+	qrCodeCacheSize = 256
+)
+
+// qrCodeCache keeps generated mini program codes by scene. getwxacodeunlimit is rate-limited
+// by WeChat, and a scene built from a stable invite code always yields the same image.
+type qrCodeCache struct {
+	mu    sync.Mutex
+	pngs  map[string][]byte
+	order []string
+}
+
+func newQRCodeCache() *qrCodeCache {
+	return &qrCodeCache{pngs: make(map[string][]byte)}
+}
+
+func (c *qrCodeCache) get(scene string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	png, ok := c.pngs[scene]
+	return png, ok
+}
+
+// put stores png for scene, evicting the oldest entry once the cache is full.
+func (c *qrCodeCache) put(scene string, png []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.pngs[scene]; ok {
+		return
+	}
+	if len(c.order) >= qrCodeCacheSize {
+		delete(c.pngs, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.pngs[scene] = png
+	c.order = append(c.order, scene)
+}
+
+// activityInviteQRCode returns the mini program code for an activity invite code, asking
+// WeChat only on a cache miss.
+func (api *v1API) activityInviteQRCode(ctx context.Context, code string) ([]byte, error) {
+	scene := "a=" + code
+	if png, ok := api.qrCodes.get(scene); ok {
+		return png, nil
+	}
+
+	accessToken, err := api.wechatClient.GetAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	png, err := api.wechatClient.GetWxaCodeUnlimit(ctx, accessToken, wechat.WxaCodeUnlimitRequest{
+		Scene:      scene,
+		Page:       wechatInviteQRPage,
+		CheckPath:  false,
+		EnvVersion: "develop",
+		Width:      430,
+	})
+	if err != nil {
+		return nil, err
+	}
+	api.qrCodes.put(scene, png)
+	return png, nil
+}
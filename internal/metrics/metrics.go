@@ -0,0 +1,107 @@
+// Package metrics exposes Prometheus metrics for the API server. A nil *Metrics is valid and
+// records nothing, so callers never need to check whether metrics are enabled.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"linkbridge-backend/internal/ws"
+)
+
+const namespace = "linkbridge"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	dbQueryDuration *prometheus.HistogramVec
+}
+
+// New creates a private registry with the process/Go collectors and the server's metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database call latency by operation (exec, query, begin).",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"op"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbQueryDuration,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveHTTPRequest records one finished request. route must be a mux pattern, never the raw
+// path, to keep label cardinality bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveDBQuery records the duration of one database call.
+func (m *Metrics) ObserveDBQuery(op string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.dbQueryDuration.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// RegisterWSStats exports the WebSocket manager's counters, read on every scrape.
+func (m *Metrics) RegisterWSStats(stats func() ws.Stats) {
+	if m == nil || stats == nil {
+		return
+	}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ws_connections",
+			Help:      "Open WebSocket connections.",
+		}, func() float64 { return float64(stats().Connections) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ws_events_sent_total",
+			Help:      "Server events queued to WebSocket connections (broadcasts and targeted sends).",
+		}, func() float64 { return float64(stats().SentEvents) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ws_rate_limited_messages_total",
+			Help:      "Inbound WebSocket frames dropped by the per-connection rate limit.",
+		}, func() float64 { return float64(stats().RateLimitedMessages) }),
+	)
+}
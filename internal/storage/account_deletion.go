@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeletedUserDisplayName replaces the display name of a deleted account.
+const DeletedUserDisplayName = "已注销用户"
+
+// DeletedUsernamePrefix prefixes the placeholder username of a deleted account, which frees
+// the original username for new registrations.
+const DeletedUsernamePrefix = "deleted_"
+
+// DeleteUser erases userID's account in one transaction.
+//
+// The users row itself is kept as an anonymized tombstone because messages, sessions and
+// calls in conversations shared with other people still reference it. Everything the user
+// authored is scrubbed instead: their messages keep their place in shared threads but lose
+// text and attachments (as if unsent), direct sessions and activities they created are
+// archived, and they leave every group. Personal rows (tokens, bindings, requests, invites,
+// profiles, home base, local feed posts, reminders, blocks, uploads, ...) are deleted.
+//
+// It returns the names of the user's uploaded files so the caller can remove them from disk.
+func (s *Store) DeleteUser(ctx context.Context, userID string, nowMs int64) ([]string, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, fmt.Errorf("missing required fields")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists string
+	existsQ := `SELECT id FROM users WHERE id = ?;`
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, existsQ), userID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: user", ErrNotFound)
+		}
+		return nil, err
+	}
+
+	uploadsQ := `SELECT name FROM uploads WHERE user_id = ? ORDER BY name;`
+	rows, err := tx.QueryContext(txCtx, rebindQuery(s.driver, uploadsQ), userID)
+	if err != nil {
+		return nil, err
+	}
+	var uploads []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		uploads = append(uploads, name)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	deletedPreview := buildLastMessageText(MessageTypeDeleted, nil, nil)
+	steps := []struct {
+		q    string
+		args []any
+	}{
+		// Session previews showing one of the user's messages must not keep its text.
+		{`UPDATE sessions SET last_message_text = ?
+			WHERE EXISTS (
+				SELECT 1 FROM messages m
+				WHERE m.session_id = sessions.id AND m.sender_id = ? AND m.created_at_ms = sessions.last_message_at_ms
+			);`, []any{deletedPreview, userID}},
+		{`UPDATE messages SET text = NULL, meta_json = NULL, deleted_at_ms = ?
+			WHERE deleted_at_ms IS NULL AND (
+				sender_id = ? OR id IN (SELECT message_id FROM burn_messages WHERE recipient_id = ?)
+			);`, []any{nowMs, userID, userID}},
+		{`DELETE FROM burn_messages WHERE sender_id = ? OR recipient_id = ?;`, []any{userID, userID}},
+		{`DELETE FROM burn_message_recipients WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM message_reactions WHERE user_id = ?;`, []any{userID}},
+
+		{`UPDATE sessions SET status = ?, updated_at_ms = ?
+			WHERE status = ? AND (
+				(kind = ? AND (user1_id = ? OR user2_id = ?))
+				OR id IN (SELECT session_id FROM activities WHERE creator_id = ?)
+			);`, []any{SessionStatusArchived, nowMs, SessionStatusActive, SessionKindDirect, userID, userID, userID}},
+		{`UPDATE session_participants SET status = ?, updated_at_ms = ? WHERE user_id = ? AND status = ?;`,
+			[]any{SessionParticipantStatusLeft, nowMs, userID, SessionParticipantStatusActive}},
+
+		{`DELETE FROM auth_tokens WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM wechat_bindings WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM session_requests WHERE requester_id = ? OR addressee_id = ?;`, []any{userID, userID}},
+		{`DELETE FROM session_invites WHERE inviter_id = ?;`, []any{userID}},
+		{`DELETE FROM session_user_meta WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM session_read_marks WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM relationship_groups WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM home_bases WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM user_card_profiles WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM user_map_profiles WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM local_feed_posts WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM activity_reminders WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM card_views WHERE target_id = ? OR viewer_id = ?;`, []any{userID, userID}},
+		{`DELETE FROM blocks WHERE blocker_id = ? OR blocked_id = ?;`, []any{userID, userID}},
+		{`DELETE FROM uploads WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM upload_usage WHERE user_id = ?;`, []any{userID}},
+		{`DELETE FROM idempotency_keys WHERE user_id = ?;`, []any{userID}},
+
+		// An empty password hash never matches, so the tombstone cannot log in.
+		{`UPDATE users SET username = ?, password_hash = '', display_name = ?, avatar_url = NULL, updated_at_ms = ?
+			WHERE id = ?;`, []any{DeletedUsernamePrefix + userID, DeletedUserDisplayName, nowMs, userID}},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, step.q), step.args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
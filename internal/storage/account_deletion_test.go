@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteUser_RemovesPersonalDataAndScrubsSharedHistory(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	users := map[string]UserRow{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		users[name] = u
+	}
+	alice, bob, carol := users["alice"], users["bob"], users["carol"]
+
+	token, err := store.CreateAuthToken(ctx, alice.ID, nil, now, now+time.Hour.Milliseconds())
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+	if _, err := store.UpsertWeChatBinding(ctx, alice.ID, "openid-alice", "session-key", nil, now); err != nil {
+		t.Fatalf("UpsertWeChatBinding() error = %v", err)
+	}
+
+	direct, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	aliceText, bobText := "from alice", "from bob"
+	bobMsg, err := store.CreateMessage(ctx, direct.ID, bob.ID, MessageTypeText, &bobText, nil, now+1)
+	if err != nil {
+		t.Fatalf("CreateMessage(bob) error = %v", err)
+	}
+	aliceMsg, err := store.CreateMessage(ctx, direct.ID, alice.ID, MessageTypeText, &aliceText, nil, now+2)
+	if err != nil {
+		t.Fatalf("CreateMessage(alice) error = %v", err)
+	}
+	if _, err := store.AddReaction(ctx, direct.ID, bobMsg.ID, alice.ID, "👍", now+3); err != nil {
+		t.Fatalf("AddReaction() error = %v", err)
+	}
+	if _, err := store.MarkSessionRead(ctx, direct.ID, alice.ID, now+3); err != nil {
+		t.Fatalf("MarkSessionRead() error = %v", err)
+	}
+	group, _, err := store.CreateRelationshipGroup(ctx, alice.ID, "friends", now)
+	if err != nil {
+		t.Fatalf("CreateRelationshipGroup() error = %v", err)
+	}
+	if _, err := store.UpsertSessionUserMeta(ctx, direct.ID, alice.ID, nil, &group.ID, []string{"school"}, now); err != nil {
+		t.Fatalf("UpsertSessionUserMeta() error = %v", err)
+	}
+
+	if _, _, err := store.CreateSession(ctx, bob.ID, carol.ID, now); err != nil {
+		t.Fatalf("CreateSession(bob, carol) error = %v", err)
+	}
+	groupChat, _, err := store.CreateGroupSession(ctx, bob.ID, []string{alice.ID, carol.ID}, "trip", now)
+	if err != nil {
+		t.Fatalf("CreateGroupSession() error = %v", err)
+	}
+	activity, _, err := store.CreateActivity(ctx, alice.ID, "hike", nil, nil, nil, now)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	if _, _, err := store.CreateSessionRequestWithLimits(ctx, alice.ID, carol.ID, SessionRequestSourceMap, nil, DefaultSessionRequestLimits, now); err != nil {
+		t.Fatalf("CreateSessionRequestWithLimits() error = %v", err)
+	}
+	if _, _, err := store.GetOrCreateSessionInvite(ctx, alice.ID, now); err != nil {
+		t.Fatalf("GetOrCreateSessionInvite() error = %v", err)
+	}
+	if _, err := store.UpsertHomeBase(ctx, alice.ID, 311234567, 1211234567, nil, now); err != nil {
+		t.Fatalf("UpsertHomeBase() error = %v", err)
+	}
+	if _, err := store.UpsertUserCardProfile(ctx, alice.ID, nil, nil, `{"bio":"hi"}`, now); err != nil {
+		t.Fatalf("UpsertUserCardProfile() error = %v", err)
+	}
+	if _, err := store.UpsertUserMapProfile(ctx, alice.ID, nil, nil, `{"bio":"hi"}`, now); err != nil {
+		t.Fatalf("UpsertUserMapProfile() error = %v", err)
+	}
+	postText := "hello neighbours"
+	if _, _, err := store.CreateLocalFeedPost(ctx, alice.ID, &postText, []string{"/uploads/a.jpg"}, nil, now+time.Hour.Milliseconds(), false, now); err != nil {
+		t.Fatalf("CreateLocalFeedPost() error = %v", err)
+	}
+	if err := store.RecordCardView(ctx, bob.ID, alice.ID, now); err != nil {
+		t.Fatalf("RecordCardView() error = %v", err)
+	}
+	if _, err := store.BlockUser(ctx, carol.ID, alice.ID, now); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	if _, err := store.RecordUpload(ctx, alice.ID, "a.jpg", 100, 0, now); err != nil {
+		t.Fatalf("RecordUpload() error = %v", err)
+	}
+
+	uploads, err := store.DeleteUser(ctx, alice.ID, now+10)
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if len(uploads) != 1 || uploads[0] != "a.jpg" {
+		t.Fatalf("DeleteUser() uploads = %v, want [a.jpg]", uploads)
+	}
+
+	// The account is an anonymized tombstone that cannot log in.
+	if _, err := store.GetUserByUsername(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetUserByUsername(alice) error = %v, want ErrNotFound", err)
+	}
+	tomb, err := store.GetUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if tomb.DisplayName != DeletedUserDisplayName || tomb.PasswordHash != "" || tomb.AvatarURL != nil {
+		t.Fatalf("tombstone = %+v, want anonymized", tomb)
+	}
+	if _, err := store.ValidateToken(ctx, token.Token, now+20); err == nil {
+		t.Fatalf("ValidateToken() succeeded after deletion")
+	}
+	if _, err := store.CreateUser(ctx, "alice", "hash", "New Alice", now+20); err != nil {
+		t.Fatalf("CreateUser(alice) after deletion error = %v", err)
+	}
+
+	for _, c := range []struct {
+		table, where string
+	}{
+		{"auth_tokens", "user_id = ?"},
+		{"wechat_bindings", "user_id = ?"},
+		{"session_requests", "requester_id = ? OR addressee_id = ?"},
+		{"session_invites", "inviter_id = ?"},
+		{"session_user_meta", "user_id = ?"},
+		{"session_read_marks", "user_id = ?"},
+		{"relationship_groups", "user_id = ?"},
+		{"home_bases", "user_id = ?"},
+		{"user_card_profiles", "user_id = ?"},
+		{"user_map_profiles", "user_id = ?"},
+		{"local_feed_posts", "user_id = ?"},
+		{"card_views", "target_id = ? OR viewer_id = ?"},
+		{"blocks", "blocker_id = ? OR blocked_id = ?"},
+		{"uploads", "user_id = ?"},
+		{"upload_usage", "user_id = ?"},
+		{"message_reactions", "user_id = ?"},
+		{"session_participants", "user_id = ? AND status = 'active'"},
+		{"messages", "sender_id = ? AND (text IS NOT NULL OR deleted_at_ms IS NULL)"},
+	} {
+		args := make([]any, strings.Count(c.where, "?"))
+		for i := range args {
+			args[i] = alice.ID
+		}
+		var n int
+		q := `SELECT COUNT(*) FROM ` + c.table + ` WHERE ` + c.where + `;`
+		if err := store.db.QueryRowContext(ctx, store.rebind(q), args...).Scan(&n); err != nil {
+			t.Fatalf("count %s error = %v", c.table, err)
+		}
+		if n != 0 {
+			t.Fatalf("%s still has %d rows for the deleted user", c.table, n)
+		}
+	}
+
+	// Shared history stays for the other side, without the deleted user's content.
+	msgs, _, err := store.ListMessages(ctx, direct.ID, bob.ID, 50, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("len(messages) = %d, want 2", len(msgs))
+	}
+	for _, m := range msgs {
+		switch m.ID {
+		case bobMsg.ID:
+			if m.Text == nil || *m.Text != bobText {
+				t.Fatalf("bob's message = %+v, want untouched", m)
+			}
+		case aliceMsg.ID:
+			if m.Text != nil || m.DeletedAtMs == nil {
+				t.Fatalf("alice's message = %+v, want scrubbed", m)
+			}
+		}
+	}
+	session, err := store.GetSessionByID(ctx, direct.ID)
+	if err != nil {
+		t.Fatalf("GetSessionByID() error = %v", err)
+	}
+	if session.Status != SessionStatusArchived {
+		t.Fatalf("direct session status = %q, want archived", session.Status)
+	}
+	if session.LastMessageText != nil && *session.LastMessageText == aliceText {
+		t.Fatalf("direct session preview still shows the deleted user's message")
+	}
+	activitySession, err := store.GetSessionByID(ctx, activity.SessionID)
+	if err != nil {
+		t.Fatalf("GetSessionByID(activity) error = %v", err)
+	}
+	if activitySession.Status != SessionStatusArchived {
+		t.Fatalf("activity session status = %q, want archived", activitySession.Status)
+	}
+	members, err := store.ListActiveSessionParticipantIDs(ctx, groupChat.ID)
+	if err != nil {
+		t.Fatalf("ListActiveSessionParticipantIDs() error = %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("group members = %v, want bob and carol", members)
+	}
+
+	if _, err := store.DeleteUser(ctx, "missing", now); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteUser(missing) error = %v, want ErrNotFound", err)
+	}
+}
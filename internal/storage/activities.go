@@ -193,6 +193,8 @@ func (s *Store) GetOrCreateActivityInvite(ctx context.Context, activityID string
 			geo_fence_lat_e7,
 			geo_fence_lng_e7,
 			geo_fence_radius_m,
+			max_uses,
+			used_count,
 			created_at_ms,
 			updated_at_ms
 		FROM activity_invites WHERE activity_id = ?;`
@@ -202,9 +204,10 @@ func (s *Store) GetOrCreateActivityInvite(ctx context.Context, activityID string
 		gfLat   sql.NullInt64
 		gfLng   sql.NullInt64
 		gfRad   sql.NullInt64
+		maxUses sql.NullInt64
 	)
 	if err := s.db.QueryRowContext(ctx, s.rebind(selectQ), activityID).Scan(
-		&existing.Code, &existing.ActivityID, &expires, &gfLat, &gfLng, &gfRad, &existing.CreatedAtMs, &existing.UpdatedAtMs,
+		&existing.Code, &existing.ActivityID, &expires, &gfLat, &gfLng, &gfRad, &maxUses, &existing.UsedCount, &existing.CreatedAtMs, &existing.UpdatedAtMs,
 	); err == nil {
 		if expires.Valid && expires.Int64 > 0 {
 			existing.ExpiresAtMs = &expires.Int64
@@ -212,6 +215,10 @@ func (s *Store) GetOrCreateActivityInvite(ctx context.Context, activityID string
 		if gfLat.Valid && gfLng.Valid && gfRad.Valid && gfRad.Int64 > 0 {
 			existing.GeoFence = &GeoFence{LatE7: gfLat.Int64, LngE7: gfLng.Int64, RadiusM: int(gfRad.Int64)}
 		}
+		if maxUses.Valid && maxUses.Int64 > 0 {
+			n := int(maxUses.Int64)
+			existing.MaxUses = &n
+		}
 		return existing, false, nil
 	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return ActivityInviteRow{}, false, err
@@ -244,7 +251,9 @@ func (s *Store) GetOrCreateActivityInvite(ctx context.Context, activityID string
 	return ActivityInviteRow{}, false, fmt.Errorf("failed to create invite code")
 }
 
-func (s *Store) UpdateActivityInviteSettings(ctx context.Context, activityID string, expiresAtMs *int64, geoFence *GeoFence, nowMs int64) (ActivityInviteRow, error) {
+// UpdateActivityInviteSettings replaces the invite's expiry, geo-fence and usage cap.
+// A nil maxUses lifts the cap; used_count keeps counting across changes.
+func (s *Store) UpdateActivityInviteSettings(ctx context.Context, activityID string, expiresAtMs *int64, geoFence *GeoFence, maxUses *int, nowMs int64) (ActivityInviteRow, error) {
 	if s == nil || s.db == nil {
 		return ActivityInviteRow{}, fmt.Errorf("db not initialized")
 	}
@@ -258,6 +267,37 @@ func (s *Store) UpdateActivityInviteSettings(ctx context.Context, activityID str
 		return ActivityInviteRow{}, err
 	}
 
+	todayYMD := ymdInResetTZ(nowMs)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ActivityInviteRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The per-creator total spans several rows, so concurrent updates across the creator's
+	// activities are serialized on the creator's user row. SQLite runs on a single
+	// connection, where the transaction alone does that.
+	if s.driver == "pgx" {
+		lockQ := `SELECT id FROM users WHERE id = (SELECT creator_id FROM activities WHERE id = ?) FOR UPDATE;`
+		var creatorID string
+		if err := tx.QueryRowContext(ctx, s.rebind(lockQ), activityID).Scan(&creatorID); err != nil {
+			return ActivityInviteRow{}, err
+		}
+	}
+
+	creatorQ := `SELECT COALESCE(SUM(ai.settings_update_count), 0)
+		FROM activity_invites ai
+		JOIN activities a ON a.id = ai.activity_id
+		WHERE ai.settings_update_ymd = ? AND a.creator_id = (SELECT creator_id FROM activities WHERE id = ?);`
+	var creatorTotal int
+	if err := tx.QueryRowContext(ctx, s.rebind(creatorQ), todayYMD, activityID).Scan(&creatorTotal); err != nil {
+		return ActivityInviteRow{}, err
+	}
+	if creatorTotal >= maxActivityInviteSettingsUpdatesPerCreator {
+		return ActivityInviteRow{}, ErrRateLimited
+	}
+
 	var exp any
 	if expiresAtMs != nil && *expiresAtMs > 0 {
 		exp = *expiresAtMs
@@ -272,10 +312,23 @@ func (s *Store) UpdateActivityInviteSettings(ctx context.Context, activityID str
 		rad = geoFence.RadiusM
 	}
 
+	var uses any
+	if maxUses != nil && *maxUses > 0 {
+		uses = *maxUses
+	}
+
 	q := `UPDATE activity_invites
-		SET expires_at_ms = ?, geo_fence_lat_e7 = ?, geo_fence_lng_e7 = ?, geo_fence_radius_m = ?, updated_at_ms = ?
-		WHERE activity_id = ?;`
-	if _, err := s.db.ExecContext(ctx, s.rebind(q), exp, lat, lng, rad, nowMs, activityID); err != nil {
+		SET expires_at_ms = ?, geo_fence_lat_e7 = ?, geo_fence_lng_e7 = ?, geo_fence_radius_m = ?, max_uses = ?,
+			` + inviteSettingsCountSet + `, updated_at_ms = ?
+		WHERE activity_id = ?` + inviteSettingsCountWhere + `;`
+	res, err := tx.ExecContext(ctx, s.rebind(q), exp, lat, lng, rad, uses, todayYMD, todayYMD, nowMs, activityID, todayYMD, maxInviteSettingsUpdatesPerDay)
+	if err != nil {
+		return ActivityInviteRow{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ActivityInviteRow{}, ErrRateLimited
+	}
+	if err := tx.Commit(); err != nil {
 		return ActivityInviteRow{}, err
 	}
 
@@ -299,6 +352,8 @@ func (s *Store) ResolveActivityInvite(ctx context.Context, code string) (Activit
 			geo_fence_lat_e7,
 			geo_fence_lng_e7,
 			geo_fence_radius_m,
+			max_uses,
+			used_count,
 			created_at_ms,
 			updated_at_ms
 		FROM activity_invites WHERE code = ?;`
@@ -308,9 +363,10 @@ func (s *Store) ResolveActivityInvite(ctx context.Context, code string) (Activit
 		gfLat   sql.NullInt64
 		gfLng   sql.NullInt64
 		gfRad   sql.NullInt64
+		maxUses sql.NullInt64
 	)
 	if err := s.db.QueryRowContext(ctx, s.rebind(q), code).Scan(
-		&row.Code, &row.ActivityID, &expires, &gfLat, &gfLng, &gfRad, &row.CreatedAtMs, &row.UpdatedAtMs,
+		&row.Code, &row.ActivityID, &expires, &gfLat, &gfLng, &gfRad, &maxUses, &row.UsedCount, &row.CreatedAtMs, &row.UpdatedAtMs,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ActivityInviteRow{}, ErrInviteInvalid
@@ -323,6 +379,10 @@ func (s *Store) ResolveActivityInvite(ctx context.Context, code string) (Activit
 	if gfLat.Valid && gfLng.Valid && gfRad.Valid && gfRad.Int64 > 0 {
 		row.GeoFence = &GeoFence{LatE7: gfLat.Int64, LngE7: gfLng.Int64, RadiusM: int(gfRad.Int64)}
 	}
+	if maxUses.Valid && maxUses.Int64 > 0 {
+		n := int(maxUses.Int64)
+		row.MaxUses = &n
+	}
 	return row, nil
 }
 
@@ -341,17 +401,8 @@ func (s *Store) ConsumeActivityInvite(ctx context.Context, userID, code string,
 		return ActivityRow{}, SessionRow{}, false, err
 	}
 
-	if invite.ExpiresAtMs != nil && nowMs > *invite.ExpiresAtMs {
-		return ActivityRow{}, SessionRow{}, false, ErrInviteExpired
-	}
-	if invite.GeoFence != nil && invite.GeoFence.RadiusM > 0 {
-		if atLatE7 == nil || atLngE7 == nil {
-			return ActivityRow{}, SessionRow{}, false, ErrGeoFenceRequired
-		}
-		dist := distanceMetersE7(invite.GeoFence.LatE7, invite.GeoFence.LngE7, *atLatE7, *atLngE7)
-		if dist > float64(invite.GeoFence.RadiusM) {
-			return ActivityRow{}, SessionRow{}, false, ErrGeoFenceForbidden
-		}
+	if err := checkActivityInvite(invite, atLatE7, atLngE7, nowMs); err != nil {
+		return ActivityRow{}, SessionRow{}, false, err
 	}
 
 	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
@@ -368,17 +419,32 @@ func (s *Store) ConsumeActivityInvite(ctx context.Context, userID, code string,
 		return ActivityRow{}, SessionRow{}, false, err
 	}
 
-	// Reject joining expired activities.
-	if activity.EndAtMs != nil && nowMs > *activity.EndAtMs {
-		return ActivityRow{}, SessionRow{}, false, ErrInvalidState
-	}
-
 	session, err := getSessionByIDInTx(txCtx, tx, s.driver, activity.SessionID)
 	if err != nil {
 		return ActivityRow{}, SessionRow{}, false, err
 	}
-	if session.Status != SessionStatusActive {
-		return ActivityRow{}, SessionRow{}, false, ErrSessionArchived
+	if err := checkActivityJoinable(activity, session, nowMs); err != nil {
+		return ActivityRow{}, SessionRow{}, false, err
+	}
+
+	// Only new or returning members count against the invite's cap, so re-consuming
+	// while already active never burns a use.
+	var existingStatus string
+	statusQ := `SELECT status FROM session_participants WHERE session_id = ? AND user_id = ?;`
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, statusQ), session.ID, userID).Scan(&existingStatus); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ActivityRow{}, SessionRow{}, false, err
+	}
+	if existingStatus != SessionParticipantStatusActive {
+		useQ := `UPDATE activity_invites
+			SET used_count = used_count + 1
+			WHERE code = ? AND (max_uses IS NULL OR used_count < max_uses);`
+		res, err := tx.ExecContext(txCtx, rebindQuery(s.driver, useQ), invite.Code)
+		if err != nil {
+			return ActivityRow{}, SessionRow{}, false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ActivityRow{}, SessionRow{}, false, ErrInviteExpired
+		}
 	}
 
 	created, err := upsertSessionParticipantInTx(txCtx, tx, s.driver, session.ID, userID, SessionParticipantRoleMember, SessionParticipantStatusActive, nowMs)
@@ -403,6 +469,142 @@ func (s *Store) ConsumeActivityInvite(ctx context.Context, userID, code string,
 	return activity, session, created, nil
 }
 
+// PreviewActivityInvite runs the same checks as ConsumeActivityInvite without joining.
+// When the code resolves, the returned preview carries the activity even if a later check fails,
+// so callers can show what the user would be joining alongside the reason they can't.
+func (s *Store) PreviewActivityInvite(ctx context.Context, userID, code string, atLatE7, atLngE7 *int64, nowMs int64) (ActivityInvitePreview, error) {
+	if s == nil || s.db == nil {
+		return ActivityInvitePreview{}, fmt.Errorf("db not initialized")
+	}
+	userID = strings.TrimSpace(userID)
+	code = strings.TrimSpace(code)
+	if userID == "" || code == "" {
+		return ActivityInvitePreview{}, fmt.Errorf("missing required fields")
+	}
+
+	invite, err := s.ResolveActivityInvite(ctx, code)
+	if err != nil {
+		return ActivityInvitePreview{}, err
+	}
+
+	activity, err := s.GetActivityByID(ctx, invite.ActivityID)
+	if err != nil {
+		return ActivityInvitePreview{}, err
+	}
+	session, err := s.GetSessionByID(ctx, activity.SessionID)
+	if err != nil {
+		return ActivityInvitePreview{}, err
+	}
+	member, err := s.IsSessionParticipant(ctx, session.ID, userID)
+	if err != nil {
+		return ActivityInvitePreview{}, err
+	}
+
+	preview := ActivityInvitePreview{Activity: activity, Session: session, AlreadyMember: member}
+	if err := checkActivityInvite(invite, atLatE7, atLngE7, nowMs); err != nil {
+		return preview, err
+	}
+	if !member && invite.MaxUses != nil && invite.UsedCount >= *invite.MaxUses {
+		return preview, ErrInviteExpired
+	}
+	if err := checkActivityJoinable(activity, session, nowMs); err != nil {
+		return preview, err
+	}
+	return preview, nil
+}
+
+func checkActivityInvite(invite ActivityInviteRow, atLatE7, atLngE7 *int64, nowMs int64) error {
+	if invite.ExpiresAtMs != nil && nowMs > *invite.ExpiresAtMs {
+		return ErrInviteExpired
+	}
+	if invite.GeoFence != nil && invite.GeoFence.RadiusM > 0 {
+		if atLatE7 == nil || atLngE7 == nil {
+			return ErrGeoFenceRequired
+		}
+		dist := distanceMetersE7(invite.GeoFence.LatE7, invite.GeoFence.LngE7, *atLatE7, *atLngE7)
+		if dist > float64(invite.GeoFence.RadiusM) {
+			return ErrGeoFenceForbidden
+		}
+	}
+	return nil
+}
+
+func checkActivityJoinable(activity ActivityRow, session SessionRow, nowMs int64) error {
+	// Reject joining expired activities.
+	if activity.EndAtMs != nil && nowMs > *activity.EndAtMs {
+		return ErrInvalidState
+	}
+	if session.Status != SessionStatusActive {
+		return ErrSessionArchived
+	}
+	return nil
+}
+
+// CountActivityMembers returns how many users are currently active in the activity's session.
+func (s *Store) CountActivityMembers(ctx context.Context, sessionID string) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return 0, fmt.Errorf("missing sessionID")
+	}
+
+	q := `SELECT COUNT(1) FROM session_participants WHERE session_id = ? AND status = ?;`
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), sessionID, SessionParticipantStatusActive).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CountActivityMembersBySession is the batched form of CountActivityMembers for list views.
+// Sessions without active members are absent from the result.
+func (s *Store) CountActivityMembersBySession(ctx context.Context, sessionIDs []string) (map[string]int, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	args := make([]any, 0, len(sessionIDs)+1)
+	args = append(args, SessionParticipantStatusActive)
+	for _, id := range sessionIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		args = append(args, id)
+	}
+	if len(args) == 1 {
+		return map[string]int{}, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)-1), ",")
+	q := fmt.Sprintf(`SELECT session_id, COUNT(1)
+		FROM session_participants
+		WHERE status = ? AND session_id IN (%s)
+		GROUP BY session_id;`, placeholders)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]int, len(args)-1)
+	for rows.Next() {
+		var sessionID string
+		var n int
+		if err := rows.Scan(&sessionID, &n); err != nil {
+			return nil, err
+		}
+		out[sessionID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (s *Store) ListActivityMembers(ctx context.Context, activityID string) ([]SessionParticipantRow, error) {
 	if s == nil || s.db == nil {
 		return nil, fmt.Errorf("db not initialized")
@@ -457,12 +659,29 @@ func (s *Store) RemoveActivityMember(ctx context.Context, activityID, actorUserI
 	if err != nil {
 		return err
 	}
-	if activity.CreatorID != actorUserID {
-		return ErrAccessDenied
-	}
 	if targetUserID == activity.CreatorID {
 		return ErrAccessDenied
 	}
+	if activity.CreatorID != actorUserID {
+		// Admins may remove plain members, but not other admins.
+		actorRole, actorStatus, err := s.getSessionParticipantRole(ctx, activity.SessionID, actorUserID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrAccessDenied
+			}
+			return err
+		}
+		if actorRole != SessionParticipantRoleAdmin || actorStatus != SessionParticipantStatusActive {
+			return ErrAccessDenied
+		}
+		targetRole, _, err := s.getSessionParticipantRole(ctx, activity.SessionID, targetUserID)
+		if err != nil {
+			return err
+		}
+		if targetRole != SessionParticipantRoleMember {
+			return ErrAccessDenied
+		}
+	}
 
 	q := `UPDATE session_participants
 		SET status = ?, updated_at_ms = ?
@@ -478,6 +697,161 @@ func (s *Store) RemoveActivityMember(ctx context.Context, activityID, actorUserI
 	return nil
 }
 
+// SetActivityMemberRole promotes an active member to admin or demotes an admin back to member.
+// Only the creator may change roles, and the creator's own role is fixed.
+func (s *Store) SetActivityMemberRole(ctx context.Context, activityID, actorUserID, targetUserID, role string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	activityID = strings.TrimSpace(activityID)
+	actorUserID = strings.TrimSpace(actorUserID)
+	targetUserID = strings.TrimSpace(targetUserID)
+	role = strings.TrimSpace(role)
+	if activityID == "" || actorUserID == "" || targetUserID == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	if role != SessionParticipantRoleAdmin && role != SessionParticipantRoleMember {
+		return ErrInvalidState
+	}
+
+	activity, err := s.GetActivityByID(ctx, activityID)
+	if err != nil {
+		return err
+	}
+	if activity.CreatorID != actorUserID {
+		return ErrAccessDenied
+	}
+	if targetUserID == activity.CreatorID {
+		return ErrInvalidState
+	}
+
+	q := `UPDATE session_participants
+		SET role = ?, updated_at_ms = ?
+		WHERE session_id = ? AND user_id = ? AND status = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), role, nowMs, activity.SessionID, targetUserID, SessionParticipantStatusActive)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("%w: session participant", ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) getSessionParticipantRole(ctx context.Context, sessionID, userID string) (role, status string, _ error) {
+	q := `SELECT role, status FROM session_participants WHERE session_id = ? AND user_id = ?;`
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), sessionID, userID).Scan(&role, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", fmt.Errorf("%w: session participant", ErrNotFound)
+		}
+		return "", "", err
+	}
+	return role, status, nil
+}
+
+// LeaveActivity marks the caller as having left the activity. The creator cannot leave;
+// they must transfer ownership or delete the activity first.
+func (s *Store) LeaveActivity(ctx context.Context, activityID, userID string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	activityID = strings.TrimSpace(activityID)
+	userID = strings.TrimSpace(userID)
+	if activityID == "" || userID == "" {
+		return fmt.Errorf("missing required fields")
+	}
+
+	activity, err := s.GetActivityByID(ctx, activityID)
+	if err != nil {
+		return err
+	}
+	if activity.CreatorID == userID {
+		return ErrInvalidState
+	}
+
+	q := `UPDATE session_participants
+		SET status = ?, updated_at_ms = ?
+		WHERE session_id = ? AND user_id = ? AND status = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), SessionParticipantStatusLeft, nowMs, activity.SessionID, userID, SessionParticipantStatusActive)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("%w: session participant", ErrNotFound)
+	}
+	return nil
+}
+
+// TransferActivityOwnership hands the activity to newCreatorID, who must be an active participant.
+// The new owner gets the creator role and the previous creator stays on as an admin.
+func (s *Store) TransferActivityOwnership(ctx context.Context, activityID, actorUserID, newCreatorID string, nowMs int64) (ActivityRow, error) {
+	if s == nil || s.db == nil {
+		return ActivityRow{}, fmt.Errorf("db not initialized")
+	}
+	activityID = strings.TrimSpace(activityID)
+	actorUserID = strings.TrimSpace(actorUserID)
+	newCreatorID = strings.TrimSpace(newCreatorID)
+	if activityID == "" || actorUserID == "" || newCreatorID == "" {
+		return ActivityRow{}, fmt.Errorf("missing required fields")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return ActivityRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	activity, err := getActivityByIDInTx(txCtx, tx, s.driver, activityID)
+	if err != nil {
+		return ActivityRow{}, err
+	}
+	if activity.CreatorID != actorUserID {
+		return ActivityRow{}, ErrAccessDenied
+	}
+	if newCreatorID == actorUserID {
+		return ActivityRow{}, ErrInvalidState
+	}
+
+	var status string
+	statusQ := `SELECT status FROM session_participants WHERE session_id = ? AND user_id = ?;`
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, statusQ), activity.SessionID, newCreatorID).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ActivityRow{}, ErrInvalidState
+		}
+		return ActivityRow{}, err
+	}
+	if status != SessionParticipantStatusActive {
+		return ActivityRow{}, ErrInvalidState
+	}
+
+	updateActivityQ := `UPDATE activities SET creator_id = ?, updated_at_ms = ? WHERE id = ? AND creator_id = ?;`
+	res, err := tx.ExecContext(txCtx, rebindQuery(s.driver, updateActivityQ), newCreatorID, nowMs, activityID, actorUserID)
+	if err != nil {
+		return ActivityRow{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ActivityRow{}, ErrInvalidState
+	}
+
+	roleQ := rebindQuery(s.driver, `UPDATE session_participants SET role = ?, updated_at_ms = ? WHERE session_id = ? AND user_id = ?;`)
+	if _, err := tx.ExecContext(txCtx, roleQ, SessionParticipantRoleCreator, nowMs, activity.SessionID, newCreatorID); err != nil {
+		return ActivityRow{}, err
+	}
+	if _, err := tx.ExecContext(txCtx, roleQ, SessionParticipantRoleAdmin, nowMs, activity.SessionID, actorUserID); err != nil {
+		return ActivityRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ActivityRow{}, err
+	}
+	return s.GetActivityByID(ctx, activityID)
+}
+
 func (s *Store) ExtendActivity(ctx context.Context, activityID, actorUserID string, newEndAtMs int64, nowMs int64) (ActivityRow, error) {
 	if s == nil || s.db == nil {
 		return ActivityRow{}, fmt.Errorf("db not initialized")
@@ -526,24 +900,162 @@ func (s *Store) ExtendActivity(ctx context.Context, activityID, actorUserID stri
 	return s.GetActivityByID(ctx, activityID)
 }
 
-func (s *Store) ListActivitiesForUser(ctx context.Context, userID, status string, nowMs int64, limit int) ([]ActivityRow, error) {
+// ActivityUpdate carries the fields of a partial activity edit. Nil fields are left untouched.
+// An empty Description clears it; a non-positive StartAtMs/EndAtMs clears that bound.
+type ActivityUpdate struct {
+	Title       *string
+	Description *string
+	StartAtMs   *int64
+	EndAtMs     *int64
+}
+
+func (s *Store) UpdateActivity(ctx context.Context, activityID, actorUserID string, upd ActivityUpdate, nowMs int64) (ActivityRow, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("db not initialized")
+		return ActivityRow{}, fmt.Errorf("db not initialized")
+	}
+	activityID = strings.TrimSpace(activityID)
+	actorUserID = strings.TrimSpace(actorUserID)
+	if activityID == "" || actorUserID == "" {
+		return ActivityRow{}, fmt.Errorf("missing required fields")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return ActivityRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	activity, err := getActivityByIDInTx(txCtx, tx, s.driver, activityID)
+	if err != nil {
+		return ActivityRow{}, err
+	}
+	if activity.CreatorID != actorUserID {
+		return ActivityRow{}, ErrAccessDenied
+	}
+
+	if upd.Title != nil {
+		title := strings.TrimSpace(*upd.Title)
+		if title == "" {
+			return ActivityRow{}, fmt.Errorf("missing title")
+		}
+		if len(title) > 50 {
+			return ActivityRow{}, fmt.Errorf("title too long")
+		}
+		activity.Title = title
+	}
+	if upd.Description != nil {
+		activity.Description = normalizeOptionalText(upd.Description, 500)
+	}
+	if upd.StartAtMs != nil {
+		activity.StartAtMs = nil
+		if *upd.StartAtMs > 0 {
+			v := *upd.StartAtMs
+			activity.StartAtMs = &v
+		}
+	}
+	endChanged := false
+	if upd.EndAtMs != nil {
+		activity.EndAtMs = nil
+		if *upd.EndAtMs > 0 {
+			if *upd.EndAtMs <= nowMs {
+				return ActivityRow{}, fmt.Errorf("endAtMs must be in the future")
+			}
+			v := *upd.EndAtMs
+			activity.EndAtMs = &v
+		}
+		endChanged = true
+	}
+	if activity.StartAtMs != nil && activity.EndAtMs != nil && *activity.EndAtMs <= *activity.StartAtMs {
+		return ActivityRow{}, fmt.Errorf("endAtMs must be greater than startAtMs")
+	}
+
+	updateActivityQ := `UPDATE activities
+		SET title = ?, description = ?, start_at_ms = ?, end_at_ms = ?, updated_at_ms = ?
+		WHERE id = ?;`
+	var descVal any
+	if activity.Description != nil {
+		descVal = *activity.Description
+	}
+	var startVal any
+	if activity.StartAtMs != nil {
+		startVal = *activity.StartAtMs
+	}
+	var endVal any
+	if activity.EndAtMs != nil {
+		endVal = *activity.EndAtMs
+	}
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, updateActivityQ),
+		activity.Title, descVal, startVal, endVal, nowMs, activityID,
+	); err != nil {
+		return ActivityRow{}, err
+	}
+
+	// Rescheduling past the old end behaves like ExtendActivity: reopen the group chat.
+	if endChanged {
+		updateSessionQ := `UPDATE sessions SET status = ?, updated_at_ms = ? WHERE id = ?;`
+		if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, updateSessionQ), SessionStatusActive, nowMs, activity.SessionID); err != nil {
+			return ActivityRow{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ActivityRow{}, err
+	}
+
+	return s.GetActivityByID(ctx, activityID)
+}
+
+// maxActivitySearchQueryLen caps the title filter accepted by ListActivitiesForUser, in characters.
+const maxActivitySearchQueryLen = 50
+
+// ListActivitiesForUser pages through the activities userID takes part in, most recently updated
+// first with id as the tie-breaker. cursor is the nextCursor of the previous page; an empty
+// nextCursor means there are no more rows.
+func (s *Store) ListActivitiesForUser(ctx context.Context, userID, status string, nowMs int64, limit int, cursor, query string) ([]ActivityRow, string, error) {
+	if s == nil || s.db == nil {
+		return nil, "", fmt.Errorf("db not initialized")
 	}
 	_ = nowMs
 	userID = strings.TrimSpace(userID)
 	status = strings.TrimSpace(status)
 	if userID == "" {
-		return nil, fmt.Errorf("missing userID")
+		return nil, "", fmt.Errorf("missing userID")
 	}
 	if status != SessionStatusActive && status != SessionStatusArchived {
 		status = SessionStatusActive
 	}
-	if limit <= 0 || limit > 200 {
-		limit = 50
+
+	q := activityListSelect + `
+		JOIN session_participants p ON p.session_id = a.session_id AND p.user_id = ? AND p.status = ?
+		WHERE s.status = ?`
+	args := []any{userID, SessionParticipantStatusActive, status}
+	return s.listActivitiesPage(ctx, q, args, limit, cursor, query)
+}
+
+// ListActivitiesCreatedBy lists the activities creatorID currently owns, paged like ListActivitiesForUser.
+func (s *Store) ListActivitiesCreatedBy(ctx context.Context, creatorID, status string, limit int, cursor, query string) ([]ActivityRow, string, error) {
+	if s == nil || s.db == nil {
+		return nil, "", fmt.Errorf("db not initialized")
+	}
+	creatorID = strings.TrimSpace(creatorID)
+	status = strings.TrimSpace(status)
+	if creatorID == "" {
+		return nil, "", fmt.Errorf("missing creatorID")
+	}
+	if status != SessionStatusActive && status != SessionStatusArchived {
+		status = SessionStatusActive
 	}
 
-	q := `SELECT
+	q := activityListSelect + `
+		WHERE a.creator_id = ? AND s.status = ?`
+	args := []any{creatorID, status}
+	return s.listActivitiesPage(ctx, q, args, limit, cursor, query)
+}
+
+const activityListSelect = `SELECT
 			a.id,
 			a.session_id,
 			a.creator_id,
@@ -554,19 +1066,49 @@ func (s *Store) ListActivitiesForUser(ctx context.Context, userID, status string
 			a.created_at_ms,
 			a.updated_at_ms
 		FROM activities a
-		JOIN sessions s ON s.id = a.session_id
-		JOIN session_participants p ON p.session_id = a.session_id AND p.user_id = ? AND p.status = ?
-		WHERE s.status = ?
-		ORDER BY a.updated_at_ms DESC
-		LIMIT ?;`
+		JOIN sessions s ON s.id = a.session_id`
 
-	rows, err := s.db.QueryContext(ctx, s.rebind(q), userID, SessionParticipantStatusActive, status, limit)
+// listActivitiesPage appends the title filter and keyset cursor to a base activity query
+// and returns one page ordered by (updated_at_ms DESC, id DESC).
+func (s *Store) listActivitiesPage(ctx context.Context, q string, args []any, limit int, cursor, query string) ([]ActivityRow, string, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	if query = strings.TrimSpace(query); query != "" {
+		if r := []rune(query); len(r) > maxActivitySearchQueryLen {
+			query = string(r[:maxActivitySearchQueryLen])
+		}
+		if s.driver == "pgx" {
+			q += ` AND a.title ILIKE ? ESCAPE '\'`
+		} else {
+			// SQLite's LIKE is already case-insensitive for ASCII.
+			q += ` AND a.title LIKE ? ESCAPE '\'`
+		}
+		args = append(args, "%"+escapeLikePattern(query)+"%")
+	}
+
+	if cursor = strings.TrimSpace(cursor); cursor != "" {
+		cursorUpdatedAt, cursorID, ok := parseUpdatedAtCursor(cursor)
+		if !ok {
+			return nil, "", ErrInvalidCursor
+		}
+		q += ` AND (a.updated_at_ms < ? OR (a.updated_at_ms = ? AND a.id < ?))`
+		args = append(args, cursorUpdatedAt, cursorUpdatedAt, cursorID)
+	}
+	q += ` ORDER BY a.updated_at_ms DESC, a.id DESC LIMIT ?;`
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
-	var out []ActivityRow
+	var (
+		out     []ActivityRow
+		hasMore bool
+	)
 	for rows.Next() {
 		var (
 			row   ActivityRow
@@ -577,7 +1119,12 @@ func (s *Store) ListActivitiesForUser(ctx context.Context, userID, status string
 		if err := rows.Scan(
 			&row.ID, &row.SessionID, &row.CreatorID, &row.Title, &desc, &start, &end, &row.CreatedAtMs, &row.UpdatedAtMs,
 		); err != nil {
-			return nil, err
+			return nil, "", err
+		}
+		if len(out) == limit {
+			// The extra row only signals that another page exists.
+			hasMore = true
+			continue
 		}
 		if desc.Valid {
 			row.Description = &desc.String
@@ -591,9 +1138,15 @@ func (s *Store) ListActivitiesForUser(ctx context.Context, userID, status string
 		out = append(out, row)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return out, nil
+
+	var nextCursor string
+	if hasMore {
+		last := out[len(out)-1]
+		nextCursor = formatUpdatedAtCursor(last.UpdatedAtMs, last.ID)
+	}
+	return out, nextCursor, nil
 }
 
 func (s *Store) ArchiveExpiredActivitySessions(ctx context.Context, nowMs int64) (int64, error) {
@@ -750,6 +1303,11 @@ func upsertSessionParticipantInTx(ctx context.Context, tx *sql.Tx, driver, sessi
 	selectQ := rebindQuery(driver, `SELECT status FROM session_participants WHERE session_id = ? AND user_id = ?;`)
 	var existingStatus string
 	if err := tx.QueryRowContext(ctx, selectQ, sessionID, userID).Scan(&existingStatus); err == nil {
+		// An active participant keeps their row as is, so re-joining never demotes an admin
+		// or the creator. Only left or removed users are reset to the given role.
+		if existingStatus == SessionParticipantStatusActive {
+			return false, nil
+		}
 		updateQ := rebindQuery(driver, `UPDATE session_participants
 			SET role = ?, status = ?, updated_at_ms = ?
 			WHERE session_id = ? AND user_id = ?;`)
@@ -57,3 +57,53 @@ func TestCreateMessage_ActivityEnded_AutoArchivesSession(t *testing.T) {
 		t.Fatalf("session.Status = %q, want %q", sess2.Status, SessionStatusArchived)
 	}
 }
+
+func TestMessages_RemovedActivityMember_Denied(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", base)
+	if err != nil {
+		t.Fatalf("CreateUser(creator) error = %v", err)
+	}
+	member, err := store.CreateUser(ctx, "member", "hash", "Member", base)
+	if err != nil {
+		t.Fatalf("CreateUser(member) error = %v", err)
+	}
+
+	endAt := base + 60*60*1000
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Test Activity", nil, nil, &endAt, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, member.ID, invite.Code, nil, nil, base+1000); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	text := "hi"
+	if _, err := store.CreateMessage(ctx, activity.SessionID, member.ID, MessageTypeText, &text, nil, base+2000); err != nil {
+		t.Fatalf("CreateMessage(active member) error = %v", err)
+	}
+
+	if err := store.RemoveActivityMember(ctx, activity.ID, creator.ID, member.ID, base+3000); err != nil {
+		t.Fatalf("RemoveActivityMember() error = %v", err)
+	}
+
+	if _, err := store.CreateMessage(ctx, activity.SessionID, member.ID, MessageTypeText, &text, nil, base+4000); err != ErrAccessDenied {
+		t.Fatalf("CreateMessage(removed member) error = %v, want ErrAccessDenied", err)
+	}
+	if _, _, err := store.ListMessages(ctx, activity.SessionID, member.ID, 50, "", ""); err != ErrAccessDenied {
+		t.Fatalf("ListMessages(removed member) error = %v, want ErrAccessDenied", err)
+	}
+	if _, err := store.CreateMessage(ctx, activity.SessionID, creator.ID, MessageTypeText, &text, nil, base+5000); err != nil {
+		t.Fatalf("CreateMessage(creator) error = %v", err)
+	}
+}
@@ -0,0 +1,624 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListActivitiesForUser_CursorPaging(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", base)
+	if err != nil {
+		t.Fatalf("CreateUser(creator) error = %v", err)
+	}
+
+	// Four activities, all sharing one updated_at_ms so only the id tie-breaker orders them.
+	for i := 0; i < 4; i++ {
+		if _, _, err := store.CreateActivity(ctx, creator.ID, "Activity", nil, nil, nil, base); err != nil {
+			t.Fatalf("CreateActivity(%d) error = %v", i, err)
+		}
+	}
+
+	list := func(limit int, cursor string) ([]ActivityRow, string) {
+		t.Helper()
+		rows, next, err := store.ListActivitiesForUser(ctx, creator.ID, SessionStatusActive, base, limit, cursor, "")
+		if err != nil {
+			t.Fatalf("ListActivitiesForUser(limit=%d, cursor=%q) error = %v", limit, cursor, err)
+		}
+		return rows, next
+	}
+
+	// Limit exactly matching the remaining rows: one full page and no cursor.
+	all, next := list(4, "")
+	if len(all) != 4 || next != "" {
+		t.Fatalf("limit=4 got %d rows, next=%q; want 4 rows and no cursor", len(all), next)
+	}
+
+	first, next := list(2, "")
+	if len(first) != 2 || next == "" {
+		t.Fatalf("page 1 got %d rows, next=%q; want 2 rows and a cursor", len(first), next)
+	}
+	second, next := list(2, next)
+	if len(second) != 2 || next != "" {
+		t.Fatalf("page 2 got %d rows, next=%q; want the last 2 rows and no cursor", len(second), next)
+	}
+
+	paged := append(first, second...)
+	for i := range all {
+		if paged[i].ID != all[i].ID {
+			t.Fatalf("paged[%d] = %s, want %s", i, paged[i].ID, all[i].ID)
+		}
+	}
+
+	// Cursor past the last row yields an empty page.
+	last := all[len(all)-1]
+	empty, after := list(2, formatUpdatedAtCursor(last.UpdatedAtMs, last.ID))
+	if len(empty) != 0 || after != "" {
+		t.Fatalf("empty page got %d rows, next=%q; want none", len(empty), after)
+	}
+
+	if _, _, err := store.ListActivitiesForUser(ctx, creator.ID, SessionStatusActive, base, 2, "nope", ""); err != ErrInvalidCursor {
+		t.Fatalf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestUpdateActivity_PartialFieldsAndCreatorOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", base)
+	if err != nil {
+		t.Fatalf("CreateUser(creator) error = %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "hash", "Other", base)
+	if err != nil {
+		t.Fatalf("CreateUser(other) error = %v", err)
+	}
+
+	desc := "bring snacks"
+	activity, _, err := store.CreateActivity(ctx, creator.ID, "Picnic", &desc, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	newTitle := "Picnic v2"
+	if _, err := store.UpdateActivity(ctx, activity.ID, other.ID, ActivityUpdate{Title: &newTitle}, base+1); err != ErrAccessDenied {
+		t.Fatalf("UpdateActivity(non-creator) error = %v, want ErrAccessDenied", err)
+	}
+
+	long := strings.Repeat("x", 51)
+	if _, err := store.UpdateActivity(ctx, activity.ID, creator.ID, ActivityUpdate{Title: &long}, base+1); err == nil {
+		t.Fatalf("UpdateActivity(long title) error = nil, want error")
+	}
+
+	updated, err := store.UpdateActivity(ctx, activity.ID, creator.ID, ActivityUpdate{Title: &newTitle}, base+2)
+	if err != nil {
+		t.Fatalf("UpdateActivity(title) error = %v", err)
+	}
+	if updated.Title != newTitle || updated.Description == nil || *updated.Description != desc {
+		t.Fatalf("after title update = %+v, want new title and unchanged description", updated)
+	}
+	if updated.UpdatedAtMs != base+2 {
+		t.Fatalf("updatedAtMs = %d, want %d", updated.UpdatedAtMs, base+2)
+	}
+
+	empty := ""
+	cleared, err := store.UpdateActivity(ctx, activity.ID, creator.ID, ActivityUpdate{Description: &empty}, base+3)
+	if err != nil {
+		t.Fatalf("UpdateActivity(clear description) error = %v", err)
+	}
+	if cleared.Description != nil || cleared.Title != newTitle {
+		t.Fatalf("after clearing description = %+v, want nil description and kept title", cleared)
+	}
+}
+
+func TestTransferActivityOwnership_ActiveMembersOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator := newTestUser(t, store, "creator", base)
+	member := newTestUser(t, store, "member", base)
+	removed := newTestUser(t, store, "removed", base)
+	stranger := newTestUser(t, store, "stranger", base)
+
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	for _, u := range []UserRow{member, removed} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, base+1); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s) error = %v", u.Username, err)
+		}
+	}
+	if err := store.RemoveActivityMember(ctx, activity.ID, creator.ID, removed.ID, base+2); err != nil {
+		t.Fatalf("RemoveActivityMember() error = %v", err)
+	}
+
+	if _, err := store.TransferActivityOwnership(ctx, activity.ID, member.ID, member.ID, base+3); err != ErrAccessDenied {
+		t.Fatalf("transfer by non-creator error = %v, want ErrAccessDenied", err)
+	}
+	for _, target := range []UserRow{removed, stranger} {
+		if _, err := store.TransferActivityOwnership(ctx, activity.ID, creator.ID, target.ID, base+3); err != ErrInvalidState {
+			t.Fatalf("transfer to %s error = %v, want ErrInvalidState", target.Username, err)
+		}
+	}
+
+	updated, err := store.TransferActivityOwnership(ctx, activity.ID, creator.ID, member.ID, base+4)
+	if err != nil {
+		t.Fatalf("TransferActivityOwnership() error = %v", err)
+	}
+	if updated.CreatorID != member.ID {
+		t.Fatalf("creatorId = %q, want %q", updated.CreatorID, member.ID)
+	}
+
+	members, err := store.ListActivityMembers(ctx, activity.ID)
+	if err != nil {
+		t.Fatalf("ListActivityMembers() error = %v", err)
+	}
+	roles := map[string]string{}
+	for _, m := range members {
+		roles[m.UserID] = m.Role
+	}
+	if roles[member.ID] != SessionParticipantRoleCreator || roles[creator.ID] != SessionParticipantRoleAdmin {
+		t.Fatalf("roles = %v, want new owner creator and old owner admin", roles)
+	}
+
+	// The previous owner has lost creator-only powers.
+	if _, err := store.TransferActivityOwnership(ctx, activity.ID, creator.ID, member.ID, base+5); err != ErrAccessDenied {
+		t.Fatalf("transfer by previous owner error = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestLeaveActivity_MemberLeavesAndCanRejoin(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "creator", base)
+	if err != nil {
+		t.Fatalf("CreateUser(creator) error = %v", err)
+	}
+	member, err := store.CreateUser(ctx, "member", "hash", "member", base)
+	if err != nil {
+		t.Fatalf("CreateUser(member) error = %v", err)
+	}
+
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, member.ID, invite.Code, nil, nil, base+1); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	if err := store.LeaveActivity(ctx, activity.ID, creator.ID, base+2); err != ErrInvalidState {
+		t.Fatalf("creator leave error = %v, want ErrInvalidState", err)
+	}
+	if err := store.LeaveActivity(ctx, activity.ID, member.ID, base+2); err != nil {
+		t.Fatalf("LeaveActivity() error = %v", err)
+	}
+	if err := store.LeaveActivity(ctx, activity.ID, member.ID, base+3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second leave error = %v, want ErrNotFound", err)
+	}
+
+	memberStatus := func() string {
+		members, err := store.ListActivityMembers(ctx, activity.ID)
+		if err != nil {
+			t.Fatalf("ListActivityMembers() error = %v", err)
+		}
+		for _, m := range members {
+			if m.UserID == member.ID {
+				return m.Status
+			}
+		}
+		return ""
+	}
+	if got := memberStatus(); got == SessionParticipantStatusActive {
+		t.Fatalf("left member status = %q, want not active", got)
+	}
+
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, member.ID, invite.Code, nil, nil, base+4); err != nil {
+		t.Fatalf("rejoin ConsumeActivityInvite() error = %v", err)
+	}
+	if got := memberStatus(); got != SessionParticipantStatusActive {
+		t.Fatalf("rejoined member status = %q, want active", got)
+	}
+}
+
+func TestSetActivityMemberRole_AdminsRemovePlainMembers(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator := newTestUser(t, store, "creator", base)
+	admin := newTestUser(t, store, "admin", base)
+	peer := newTestUser(t, store, "peer", base)
+	member := newTestUser(t, store, "member", base)
+
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	for _, u := range []UserRow{admin, peer, member} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, base+1); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s) error = %v", u.Username, err)
+		}
+	}
+
+	if err := store.SetActivityMemberRole(ctx, activity.ID, creator.ID, admin.ID, "owner", base+2); err != ErrInvalidState {
+		t.Fatalf("invalid role error = %v, want ErrInvalidState", err)
+	}
+	if err := store.SetActivityMemberRole(ctx, activity.ID, creator.ID, creator.ID, SessionParticipantRoleMember, base+2); err != ErrInvalidState {
+		t.Fatalf("demote creator error = %v, want ErrInvalidState", err)
+	}
+	if err := store.SetActivityMemberRole(ctx, activity.ID, member.ID, member.ID, SessionParticipantRoleAdmin, base+2); err != ErrAccessDenied {
+		t.Fatalf("non-creator promote error = %v, want ErrAccessDenied", err)
+	}
+	for _, u := range []UserRow{admin, peer} {
+		if err := store.SetActivityMemberRole(ctx, activity.ID, creator.ID, u.ID, SessionParticipantRoleAdmin, base+2); err != nil {
+			t.Fatalf("SetActivityMemberRole(%s) error = %v", u.Username, err)
+		}
+	}
+
+	// Admins cannot remove the creator or each other.
+	if err := store.RemoveActivityMember(ctx, activity.ID, admin.ID, creator.ID, base+3); err != ErrAccessDenied {
+		t.Fatalf("admin removing creator error = %v, want ErrAccessDenied", err)
+	}
+	if err := store.RemoveActivityMember(ctx, activity.ID, admin.ID, peer.ID, base+3); err != ErrAccessDenied {
+		t.Fatalf("admin removing admin error = %v, want ErrAccessDenied", err)
+	}
+	if err := store.RemoveActivityMember(ctx, activity.ID, admin.ID, member.ID, base+3); err != nil {
+		t.Fatalf("admin removing member error = %v", err)
+	}
+
+	// A demoted admin loses the ability to remove members.
+	if err := store.SetActivityMemberRole(ctx, activity.ID, creator.ID, peer.ID, SessionParticipantRoleMember, base+4); err != nil {
+		t.Fatalf("demote admin error = %v", err)
+	}
+	if err := store.RemoveActivityMember(ctx, activity.ID, peer.ID, admin.ID, base+5); err != ErrAccessDenied {
+		t.Fatalf("demoted admin removing error = %v, want ErrAccessDenied", err)
+	}
+
+	members, err := store.ListActivityMembers(ctx, activity.ID)
+	if err != nil {
+		t.Fatalf("ListActivityMembers() error = %v", err)
+	}
+	got := map[string]SessionParticipantRow{}
+	for _, m := range members {
+		got[m.UserID] = m
+	}
+	if got[member.ID].Status != SessionParticipantStatusRemoved {
+		t.Fatalf("member status = %q, want removed", got[member.ID].Status)
+	}
+	if got[admin.ID].Role != SessionParticipantRoleAdmin || got[peer.ID].Role != SessionParticipantRoleMember {
+		t.Fatalf("roles = admin:%q peer:%q, want admin/member", got[admin.ID].Role, got[peer.ID].Role)
+	}
+}
+
+func TestConsumeActivityInvite_MaxUses(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator := newTestUser(t, store, "creator", base)
+	first := newTestUser(t, store, "first", base)
+	second := newTestUser(t, store, "second", base)
+
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Dinner", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	one := 1
+	updated, err := store.UpdateActivityInviteSettings(ctx, activity.ID, nil, nil, &one, base+1)
+	if err != nil {
+		t.Fatalf("UpdateActivityInviteSettings() error = %v", err)
+	}
+	if updated.MaxUses == nil || *updated.MaxUses != 1 || updated.UsedCount != 0 {
+		t.Fatalf("invite = %+v, want maxUses 1 and usedCount 0", updated)
+	}
+
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, first.ID, invite.Code, nil, nil, base+2); err != nil {
+		t.Fatalf("ConsumeActivityInvite(first) error = %v", err)
+	}
+	// Re-consuming while already a member must not burn another use.
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, first.ID, invite.Code, nil, nil, base+3); err != nil {
+		t.Fatalf("re-consume error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, second.ID, invite.Code, nil, nil, base+4); err != ErrInviteExpired {
+		t.Fatalf("ConsumeActivityInvite(second) error = %v, want ErrInviteExpired", err)
+	}
+	if _, err := store.PreviewActivityInvite(ctx, second.ID, invite.Code, nil, nil, base+4); err != ErrInviteExpired {
+		t.Fatalf("PreviewActivityInvite(second) error = %v, want ErrInviteExpired", err)
+	}
+
+	resolved, err := store.ResolveActivityInvite(ctx, invite.Code)
+	if err != nil {
+		t.Fatalf("ResolveActivityInvite() error = %v", err)
+	}
+	if resolved.UsedCount != 1 {
+		t.Fatalf("usedCount = %d, want 1", resolved.UsedCount)
+	}
+
+	// Lifting the cap reopens the invite.
+	if _, err := store.UpdateActivityInviteSettings(ctx, activity.ID, nil, nil, nil, base+5); err != nil {
+		t.Fatalf("UpdateActivityInviteSettings(clear) error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, second.ID, invite.Code, nil, nil, base+6); err != nil {
+		t.Fatalf("ConsumeActivityInvite(second, uncapped) error = %v", err)
+	}
+}
+
+func TestConsumeActivityInvite_KeepsActiveMemberRoles(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator := newTestUser(t, store, "creator", base)
+	admin := newTestUser(t, store, "admin", base)
+	member := newTestUser(t, store, "member", base)
+
+	activity, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	for _, u := range []UserRow{admin, member} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, base+1); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s) error = %v", u.Username, err)
+		}
+	}
+	if err := store.SetActivityMemberRole(ctx, activity.ID, creator.ID, admin.ID, SessionParticipantRoleAdmin, base+2); err != nil {
+		t.Fatalf("SetActivityMemberRole() error = %v", err)
+	}
+	if err := store.RemoveActivityMember(ctx, activity.ID, creator.ID, member.ID, base+3); err != nil {
+		t.Fatalf("RemoveActivityMember() error = %v", err)
+	}
+
+	// Re-opening the invite link must not demote the creator or an admin; a removed member
+	// comes back as a plain member.
+	for _, u := range []UserRow{creator, admin, member} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, base+4); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s, again) error = %v", u.Username, err)
+		}
+	}
+
+	members, err := store.ListActivityMembers(ctx, activity.ID)
+	if err != nil {
+		t.Fatalf("ListActivityMembers() error = %v", err)
+	}
+	want := map[string]string{
+		creator.ID: SessionParticipantRoleCreator,
+		admin.ID:   SessionParticipantRoleAdmin,
+		member.ID:  SessionParticipantRoleMember,
+	}
+	for _, m := range members {
+		if m.Role != want[m.UserID] || m.Status != SessionParticipantStatusActive {
+			t.Fatalf("member %s = %s/%s, want %s/active", m.UserID, m.Role, m.Status, want[m.UserID])
+		}
+	}
+	if len(members) != len(want) {
+		t.Fatalf("members = %d, want %d", len(members), len(want))
+	}
+}
+
+func TestCountActivityMembers_ActiveOnlyAndBatched(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator := newTestUser(t, store, "creator", base)
+	stays := newTestUser(t, store, "stays", base)
+	leaves := newTestUser(t, store, "leaves", base)
+
+	hike, invite, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity(hike) error = %v", err)
+	}
+	solo, _, err := store.CreateActivity(ctx, creator.ID, "Solo", nil, nil, nil, base)
+	if err != nil {
+		t.Fatalf("CreateActivity(solo) error = %v", err)
+	}
+	for _, u := range []UserRow{stays, leaves} {
+		if _, _, _, err := store.ConsumeActivityInvite(ctx, u.ID, invite.Code, nil, nil, base+1); err != nil {
+			t.Fatalf("ConsumeActivityInvite(%s) error = %v", u.Username, err)
+		}
+	}
+	if err := store.LeaveActivity(ctx, hike.ID, leaves.ID, base+2); err != nil {
+		t.Fatalf("LeaveActivity() error = %v", err)
+	}
+
+	n, err := store.CountActivityMembers(ctx, hike.SessionID)
+	if err != nil {
+		t.Fatalf("CountActivityMembers() error = %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("CountActivityMembers() = %d, want 2", n)
+	}
+
+	counts, err := store.CountActivityMembersBySession(ctx, []string{hike.SessionID, solo.SessionID, "missing"})
+	if err != nil {
+		t.Fatalf("CountActivityMembersBySession() error = %v", err)
+	}
+	if counts[hike.SessionID] != 2 || counts[solo.SessionID] != 1 || len(counts) != 2 {
+		t.Fatalf("counts = %v, want hike 2 and solo 1", counts)
+	}
+}
+
+func TestListActivitiesForUser_TitleQuery(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "creator", base)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	for i, title := range []string{"Morning Hike", "Board games", "100% fun_night"} {
+		if _, _, err := store.CreateActivity(ctx, creator.ID, title, nil, nil, nil, base+int64(i)); err != nil {
+			t.Fatalf("CreateActivity(%s) error = %v", title, err)
+		}
+	}
+
+	rows, _, err := store.ListActivitiesForUser(ctx, creator.ID, SessionStatusActive, base, 10, "", "  hike ")
+	if err != nil {
+		t.Fatalf("ListActivitiesForUser(hike) error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "Morning Hike" {
+		t.Fatalf("rows = %+v, want only Morning Hike", rows)
+	}
+
+	// Wildcards in the query match literally.
+	rows, _, err = store.ListActivitiesForUser(ctx, creator.ID, SessionStatusActive, base, 10, "", "%")
+	if err != nil {
+		t.Fatalf("ListActivitiesForUser(%%) error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "100% fun_night" {
+		t.Fatalf("rows = %+v, want only the title containing %%", rows)
+	}
+
+	rows, _, err = store.ListActivitiesForUser(ctx, creator.ID, SessionStatusActive, base, 10, "", "")
+	if err != nil {
+		t.Fatalf("ListActivitiesForUser(all) error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("len(rows) = %d, want 3", len(rows))
+	}
+}
+
+func TestListActivitiesCreatedBy_OnlyOwnedActivities(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	host, err := store.CreateUser(ctx, "host", "hash", "host", base)
+	if err != nil {
+		t.Fatalf("CreateUser(host) error = %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "hash", "other", base)
+	if err != nil {
+		t.Fatalf("CreateUser(other) error = %v", err)
+	}
+
+	var hosted []string
+	for i := 0; i < 3; i++ {
+		a, _, err := store.CreateActivity(ctx, host.ID, "Hosted", nil, nil, nil, base)
+		if err != nil {
+			t.Fatalf("CreateActivity(hosted) error = %v", err)
+		}
+		hosted = append(hosted, a.ID)
+	}
+	_, invite, err := store.CreateActivity(ctx, other.ID, "Joined", nil, nil, nil, base+1)
+	if err != nil {
+		t.Fatalf("CreateActivity(joined) error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, host.ID, invite.Code, nil, nil, base+2); err != nil {
+		t.Fatalf("ConsumeActivityInvite() error = %v", err)
+	}
+
+	first, next, err := store.ListActivitiesCreatedBy(ctx, host.ID, SessionStatusActive, 2, "", "")
+	if err != nil {
+		t.Fatalf("ListActivitiesCreatedBy(page 1) error = %v", err)
+	}
+	if len(first) != 2 || next == "" {
+		t.Fatalf("page 1 = %d rows, next %q; want 2 rows and a cursor", len(first), next)
+	}
+	second, next, err := store.ListActivitiesCreatedBy(ctx, host.ID, SessionStatusActive, 2, next, "")
+	if err != nil {
+		t.Fatalf("ListActivitiesCreatedBy(page 2) error = %v", err)
+	}
+	if len(second) != 1 || next != "" {
+		t.Fatalf("page 2 = %d rows, next %q; want 1 row and no cursor", len(second), next)
+	}
+
+	// Same timestamps, so order falls back to id DESC.
+	got := []string{first[0].ID, first[1].ID, second[0].ID}
+	for i := 1; i < len(got); i++ {
+		if got[i-1] < got[i] {
+			t.Fatalf("ids %v not in descending order", got)
+		}
+	}
+	for _, id := range got {
+		if !strings.Contains(strings.Join(hosted, ","), id) {
+			t.Fatalf("listed activity %s was not created by host", id)
+		}
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
+// activityReminderClaimLeaseMs is how long a claimed reminder stays reserved. A claim older than this
+// is assumed to belong to an instance that died mid-send and becomes claimable again.
+const activityReminderClaimLeaseMs = 5 * 60 * 1000
+
 func (s *Store) UpsertActivityReminder(ctx context.Context, activityID, userID string, remindAtMs, nowMs int64) (ActivityReminderRow, error) {
 	if s == nil || s.db == nil {
 		return ActivityReminderRow{}, fmt.Errorf("db not initialized")
@@ -19,6 +24,9 @@ func (s *Store) UpsertActivityReminder(ctx context.Context, activityID, userID s
 	if remindAtMs <= 0 {
 		return ActivityReminderRow{}, fmt.Errorf("invalid remindAtMs")
 	}
+	if remindAtMs <= nowMs {
+		return ActivityReminderRow{}, fmt.Errorf("remindAtMs must be in the future")
+	}
 
 	q := `INSERT INTO activity_reminders (
 			activity_id, user_id, remind_at_ms, status, last_error, sent_at_ms, created_at_ms, updated_at_ms
@@ -78,27 +86,59 @@ func (s *Store) GetActivityReminder(ctx context.Context, activityID, userID stri
 	return row, nil
 }
 
-func (s *Store) ListDueActivityReminders(ctx context.Context, nowMs int64, limit int) ([]ActivityReminderRow, error) {
+func (s *Store) DeleteActivityReminder(ctx context.Context, activityID, userID string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	activityID = strings.TrimSpace(activityID)
+	userID = strings.TrimSpace(userID)
+	if activityID == "" || userID == "" {
+		return fmt.Errorf("missing required fields")
+	}
+
+	q := `DELETE FROM activity_reminders WHERE activity_id = ? AND user_id = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), activityID, userID)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("%w: activity reminder", ErrNotFound)
+	}
+	return nil
+}
+
+// ClaimDueActivityReminders moves up to limit due reminders to the sending status and returns them.
+// Each row is claimed with a conditional update, so concurrent instances never dispatch the same reminder.
+func (s *Store) ClaimDueActivityReminders(ctx context.Context, nowMs int64, limit int) ([]ActivityReminderRow, error) {
 	if s == nil || s.db == nil {
 		return nil, fmt.Errorf("db not initialized")
 	}
 	if limit <= 0 || limit > 500 {
 		limit = 100
 	}
+	staleBeforeMs := nowMs - activityReminderClaimLeaseMs
 
-	q := `SELECT activity_id, user_id, remind_at_ms, status, last_error, sent_at_ms, created_at_ms, updated_at_ms
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	selectQ := `SELECT activity_id, user_id, remind_at_ms, status, last_error, sent_at_ms, created_at_ms, updated_at_ms
 		FROM activity_reminders
-		WHERE status = ? AND remind_at_ms <= ?
+		WHERE remind_at_ms <= ? AND (status = ? OR (status = ? AND updated_at_ms < ?))
 		ORDER BY remind_at_ms ASC
 		LIMIT ?;`
-
-	rows, err := s.db.QueryContext(ctx, s.rebind(q), ActivityReminderStatusPending, nowMs, limit)
+	rows, err := tx.QueryContext(txCtx, rebindQuery(s.driver, selectQ),
+		nowMs, ActivityReminderStatusPending, ActivityReminderStatusSending, staleBeforeMs, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	out := make([]ActivityReminderRow, 0, 8)
+	candidates := make([]ActivityReminderRow, 0, 8)
 	for rows.Next() {
 		var row ActivityReminderRow
 		var lastErr sql.NullString
@@ -113,6 +153,7 @@ func (s *Store) ListDueActivityReminders(ctx context.Context, nowMs int64, limit
 			&row.CreatedAtMs,
 			&row.UpdatedAtMs,
 		); err != nil {
+			_ = rows.Close()
 			return nil, err
 		}
 		if lastErr.Valid {
@@ -121,12 +162,38 @@ func (s *Store) ListDueActivityReminders(ctx context.Context, nowMs int64, limit
 		if sentAt.Valid {
 			row.SentAtMs = &sentAt.Int64
 		}
-		out = append(out, row)
+		candidates = append(candidates, row)
 	}
 	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	claimQ := rebindQuery(s.driver, `UPDATE activity_reminders
+		SET status = ?, updated_at_ms = ?
+		WHERE activity_id = ? AND user_id = ? AND (status = ? OR (status = ? AND updated_at_ms < ?));`)
+	claimed := make([]ActivityReminderRow, 0, len(candidates))
+	for _, row := range candidates {
+		res, err := tx.ExecContext(txCtx, claimQ,
+			ActivityReminderStatusSending, nowMs, row.ActivityID, row.UserID,
+			ActivityReminderStatusPending, ActivityReminderStatusSending, staleBeforeMs)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// Another instance claimed it first.
+			continue
+		}
+		row.Status = ActivityReminderStatusSending
+		row.UpdatedAtMs = nowMs
+		claimed = append(claimed, row)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return claimed, nil
 }
 
 func (s *Store) MarkActivityReminderSent(ctx context.Context, activityID, userID string, nowMs int64) error {
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestUpsertActivityReminder_RejectsPastRemindAt(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	endAt := nowMs + 60*60*1000
+	activity, _, err := store.CreateActivity(ctx, creator.ID, "Test Activity", nil, nil, &endAt, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	if _, err := store.UpsertActivityReminder(ctx, activity.ID, creator.ID, nowMs-1000, nowMs); err == nil {
+		t.Fatalf("UpsertActivityReminder(past) error = nil, want error")
+	}
+
+	row, err := store.UpsertActivityReminder(ctx, activity.ID, creator.ID, nowMs+1000, nowMs)
+	if err != nil {
+		t.Fatalf("UpsertActivityReminder(future) error = %v", err)
+	}
+	if row.CreatedAtMs != nowMs || row.UpdatedAtMs != nowMs {
+		t.Fatalf("reminder timestamps = (%d, %d), want server nowMs %d", row.CreatedAtMs, row.UpdatedAtMs, nowMs)
+	}
+}
+
+func TestDeleteActivityReminder(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	activity, _, err := store.CreateActivity(ctx, creator.ID, "Test Activity", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	if err := store.DeleteActivityReminder(ctx, activity.ID, creator.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteActivityReminder(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.UpsertActivityReminder(ctx, activity.ID, creator.ID, nowMs+1000, nowMs); err != nil {
+		t.Fatalf("UpsertActivityReminder() error = %v", err)
+	}
+	if err := store.DeleteActivityReminder(ctx, activity.ID, creator.ID); err != nil {
+		t.Fatalf("DeleteActivityReminder() error = %v", err)
+	}
+	if _, err := store.GetActivityReminder(ctx, activity.ID, creator.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetActivityReminder(after delete) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClaimDueActivityReminders_ClaimsOnceAndReclaimsStale(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	activity, _, err := store.CreateActivity(ctx, creator.ID, "Test Activity", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, err := store.UpsertActivityReminder(ctx, activity.ID, creator.ID, nowMs+1000, nowMs); err != nil {
+		t.Fatalf("UpsertActivityReminder() error = %v", err)
+	}
+
+	if got, err := store.ClaimDueActivityReminders(ctx, nowMs, 10); err != nil || len(got) != 0 {
+		t.Fatalf("ClaimDueActivityReminders(before due) = %d rows, %v; want none", len(got), err)
+	}
+
+	dueMs := nowMs + 2000
+	claimed, err := store.ClaimDueActivityReminders(ctx, dueMs, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueActivityReminders() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Status != ActivityReminderStatusSending {
+		t.Fatalf("claimed = %+v, want one sending reminder", claimed)
+	}
+	if again, err := store.ClaimDueActivityReminders(ctx, dueMs+1, 10); err != nil || len(again) != 0 {
+		t.Fatalf("second claim = %d rows, %v; want none", len(again), err)
+	}
+
+	// A claim that outlives its lease is handed out again.
+	staleMs := dueMs + activityReminderClaimLeaseMs + 1
+	reclaimed, err := store.ClaimDueActivityReminders(ctx, staleMs, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueActivityReminders(stale) error = %v", err)
+	}
+	if len(reclaimed) != 1 {
+		t.Fatalf("reclaimed = %d rows, want 1", len(reclaimed))
+	}
+
+	if err := store.MarkActivityReminderSent(ctx, activity.ID, creator.ID, staleMs); err != nil {
+		t.Fatalf("MarkActivityReminderSent() error = %v", err)
+	}
+	if after, err := store.ClaimDueActivityReminders(ctx, staleMs+activityReminderClaimLeaseMs+1, 10); err != nil || len(after) != 0 {
+		t.Fatalf("claim after sent = %d rows, %v; want none", len(after), err)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateAnnouncement(ctx context.Context, createdBy, title, body string, startsAtMs int64, endsAtMs *int64, dismissible bool, nowMs int64) (AnnouncementRow, error) {
+	if s == nil || s.db == nil {
+		return AnnouncementRow{}, fmt.Errorf("db not initialized")
+	}
+	if createdBy == "" {
+		return AnnouncementRow{}, fmt.Errorf("missing createdBy")
+	}
+	title = strings.TrimSpace(title)
+	body = strings.TrimSpace(body)
+	if title == "" && body == "" {
+		return AnnouncementRow{}, fmt.Errorf("missing title and body")
+	}
+	// starts_at_ms is client-scheduled but may not predate the record itself.
+	if startsAtMs < nowMs {
+		startsAtMs = nowMs
+	}
+	if endsAtMs != nil && *endsAtMs <= startsAtMs {
+		return AnnouncementRow{}, ErrInvalidState
+	}
+
+	a := AnnouncementRow{
+		ID:          uuid.NewString(),
+		CreatedBy:   createdBy,
+		Title:       title,
+		Body:        body,
+		StartsAtMs:  startsAtMs,
+		EndsAtMs:    endsAtMs,
+		Dismissible: dismissible,
+		CreatedAtMs: nowMs,
+	}
+
+	dismissibleInt := 0
+	if dismissible {
+		dismissibleInt = 1
+	}
+
+	q := `INSERT INTO announcements (id, created_by, title, body, starts_at_ms, ends_at_ms, dismissible, created_at_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := s.db.ExecContext(ctx, s.rebind(q), a.ID, a.CreatedBy, a.Title, a.Body, a.StartsAtMs, a.EndsAtMs, dismissibleInt, a.CreatedAtMs); err != nil {
+		return AnnouncementRow{}, err
+	}
+	return a, nil
+}
+
+// ListActiveAnnouncements returns announcements whose window contains nowMs, newest first.
+func (s *Store) ListActiveAnnouncements(ctx context.Context, nowMs int64, limit int) ([]AnnouncementRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	q := `SELECT id, created_by, title, body, starts_at_ms, ends_at_ms, dismissible, created_at_ms
+		FROM announcements
+		WHERE starts_at_ms <= ? AND (ends_at_ms IS NULL OR ends_at_ms > ?)
+		ORDER BY starts_at_ms DESC, id DESC
+		LIMIT ?;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), nowMs, nowMs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AnnouncementRow
+	for rows.Next() {
+		var (
+			a           AnnouncementRow
+			ends        sql.NullInt64
+			dismissible int
+		)
+		if err := rows.Scan(&a.ID, &a.CreatedBy, &a.Title, &a.Body, &a.StartsAtMs, &ends, &dismissible, &a.CreatedAtMs); err != nil {
+			return nil, err
+		}
+		if ends.Valid {
+			a.EndsAtMs = &ends.Int64
+		}
+		a.Dismissible = dismissible != 0
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
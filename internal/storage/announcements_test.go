@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCreateAnnouncement_ClampsBackdatedStart(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	admin, err := store.CreateUser(ctx, "admin", "hash", "Admin", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	row, err := store.CreateAnnouncement(ctx, admin.ID, "Title", "", nowMs-24*60*60*1000, nil, true, nowMs)
+	if err != nil {
+		t.Fatalf("CreateAnnouncement() error = %v", err)
+	}
+	if row.StartsAtMs != nowMs {
+		t.Fatalf("StartsAtMs = %d, want %d", row.StartsAtMs, nowMs)
+	}
+}
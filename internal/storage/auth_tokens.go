@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"time"
 )
 
 func (s *Store) CreateAuthToken(ctx context.Context, userID string, deviceInfo *string, nowMs, expiresAtMs int64) (AuthTokenRow, error) {
@@ -72,6 +73,87 @@ func (s *Store) ValidateToken(ctx context.Context, token string, nowMs int64) (A
 	return row, nil
 }
 
+// RotateAuthToken replaces a still-valid oldToken with a new token expiring at newExpiryMs.
+// The old token stops working immediately. The new token keeps the device info and original
+// CreatedAtMs of the login it continues, since burn message visibility is keyed on when the
+// login happened rather than when the token was last rotated.
+func (s *Store) RotateAuthToken(ctx context.Context, oldToken string, nowMs, newExpiryMs int64) (AuthTokenRow, error) {
+	if s == nil || s.db == nil {
+		return AuthTokenRow{}, fmt.Errorf("db not initialized")
+	}
+	if oldToken == "" {
+		return AuthTokenRow{}, ErrTokenInvalid
+	}
+	if newExpiryMs <= nowMs {
+		return AuthTokenRow{}, fmt.Errorf("newExpiryMs must be in the future")
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return AuthTokenRow{}, err
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return AuthTokenRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old AuthTokenRow
+	var device sql.NullString
+	selectQ := `SELECT token, user_id, device_info, created_at_ms, expires_at_ms
+		FROM auth_tokens WHERE token = ?;`
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, selectQ), oldToken).Scan(
+		&old.Token, &old.UserID, &device, &old.CreatedAtMs, &old.ExpiresAtMs,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return AuthTokenRow{}, ErrTokenInvalid
+		}
+		return AuthTokenRow{}, err
+	}
+	if nowMs > old.ExpiresAtMs {
+		return AuthTokenRow{}, ErrTokenExpired
+	}
+
+	// A concurrent refresh of the same token may have won; only one rotation succeeds.
+	deleteQ := `DELETE FROM auth_tokens WHERE token = ?;`
+	result, err := tx.ExecContext(txCtx, rebindQuery(s.driver, deleteQ), oldToken)
+	if err != nil {
+		return AuthTokenRow{}, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return AuthTokenRow{}, ErrTokenInvalid
+	}
+
+	row := AuthTokenRow{
+		Token:       token,
+		UserID:      old.UserID,
+		CreatedAtMs: old.CreatedAtMs,
+		ExpiresAtMs: newExpiryMs,
+	}
+	var deviceVal any
+	if device.Valid {
+		row.DeviceInfo = &device.String
+		deviceVal = device.String
+	}
+
+	insertQ := `INSERT INTO auth_tokens (token, user_id, device_info, created_at_ms, expires_at_ms)
+		VALUES (?, ?, ?, ?, ?);`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertQ),
+		row.Token, row.UserID, deviceVal, row.CreatedAtMs, row.ExpiresAtMs,
+	); err != nil {
+		return AuthTokenRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return AuthTokenRow{}, err
+	}
+	return row, nil
+}
+
 func (s *Store) DeleteToken(ctx context.Context, token string) error {
 	if s == nil || s.db == nil {
 		return fmt.Errorf("db not initialized")
@@ -82,14 +164,19 @@ func (s *Store) DeleteToken(ctx context.Context, token string) error {
 	return err
 }
 
-func (s *Store) DeleteUserTokens(ctx context.Context, userID string) error {
+// DeleteTokensForUser signs userID out everywhere except keepToken (which may be empty) and
+// returns how many tokens were deleted.
+func (s *Store) DeleteTokensForUser(ctx context.Context, userID, keepToken string) (int64, error) {
 	if s == nil || s.db == nil {
-		return fmt.Errorf("db not initialized")
+		return 0, fmt.Errorf("db not initialized")
 	}
 
-	q := `DELETE FROM auth_tokens WHERE user_id = ?;`
-	_, err := s.db.ExecContext(ctx, s.rebind(q), userID)
-	return err
+	q := `DELETE FROM auth_tokens WHERE user_id = ? AND token <> ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), userID, keepToken)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (s *Store) CleanExpiredTokens(ctx context.Context, nowMs int64) (int64, error) {
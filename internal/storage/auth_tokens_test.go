@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRotateAuthToken_ReplacesValidTokenOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	day := (24 * time.Hour).Milliseconds()
+
+	user, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	device := "iPhone"
+	old, err := store.CreateAuthToken(ctx, user.ID, &device, now, now+7*day)
+	if err != nil {
+		t.Fatalf("CreateAuthToken() error = %v", err)
+	}
+
+	rotated, err := store.RotateAuthToken(ctx, old.Token, now+6*day, now+13*day)
+	if err != nil {
+		t.Fatalf("RotateAuthToken() error = %v", err)
+	}
+	if rotated.Token == old.Token || rotated.UserID != user.ID || rotated.ExpiresAtMs != now+13*day {
+		t.Fatalf("rotated = %+v, want a new token for alice expiring in 13 days", rotated)
+	}
+	if rotated.CreatedAtMs != old.CreatedAtMs || rotated.DeviceInfo == nil || *rotated.DeviceInfo != device {
+		t.Fatalf("rotated = %+v, want original login time and device", rotated)
+	}
+
+	if _, err := store.ValidateToken(ctx, old.Token, now+6*day); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("ValidateToken(old) error = %v, want ErrTokenInvalid", err)
+	}
+	if _, err := store.ValidateToken(ctx, rotated.Token, now+10*day); err != nil {
+		t.Fatalf("ValidateToken(rotated) error = %v", err)
+	}
+	if _, err := store.RotateAuthToken(ctx, old.Token, now+6*day, now+13*day); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("RotateAuthToken(old again) error = %v, want ErrTokenInvalid", err)
+	}
+	if _, err := store.RotateAuthToken(ctx, rotated.Token, now+14*day, now+21*day); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("RotateAuthToken(expired) error = %v, want ErrTokenExpired", err)
+	}
+}
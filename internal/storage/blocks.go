@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// BlockUser records blockerID blocking blockedID and reports whether a new block was stored.
+// Blocks are directional: blockedID can no longer reach blockerID, but blockerID can still
+// reach blockedID unless the reverse block exists too.
+func (s *Store) BlockUser(ctx context.Context, blockerID, blockedID string, nowMs int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	blockerID = strings.TrimSpace(blockerID)
+	blockedID = strings.TrimSpace(blockedID)
+	if blockerID == "" || blockedID == "" {
+		return false, fmt.Errorf("missing user ids")
+	}
+	if blockerID == blockedID {
+		return false, ErrCannotChatSelf
+	}
+	if _, err := s.GetUserByID(ctx, blockedID); err != nil {
+		return false, err
+	}
+
+	q := `INSERT INTO blocks (blocker_id, blocked_id, created_at_ms)
+		VALUES (?, ?, ?)
+		ON CONFLICT(blocker_id, blocked_id) DO NOTHING;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), blockerID, blockedID, nowMs)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// UnblockUser lifts blockerID's block on blockedID and reports whether a block was removed.
+func (s *Store) UnblockUser(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	blockerID = strings.TrimSpace(blockerID)
+	blockedID = strings.TrimSpace(blockedID)
+	if blockerID == "" || blockedID == "" {
+		return false, fmt.Errorf("missing user ids")
+	}
+
+	q := `DELETE FROM blocks WHERE blocker_id = ? AND blocked_id = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), blockerID, blockedID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// IsBlocked reports whether blockerID has blocked blockedID.
+func (s *Store) IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	if blockerID == "" || blockedID == "" {
+		return false, nil
+	}
+
+	q := `SELECT 1 FROM blocks WHERE blocker_id = ? AND blocked_id = ?;`
+	var one int
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), blockerID, blockedID).Scan(&one); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// checkNotBlocked returns ErrBlocked when targetID has blocked actorID.
+func (s *Store) checkNotBlocked(ctx context.Context, targetID, actorID string) error {
+	blocked, err := s.IsBlocked(ctx, targetID, actorID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// ListBlockedUsers returns the users blockerID has blocked, most recent first.
+func (s *Store) ListBlockedUsers(ctx context.Context, blockerID string) ([]BlockedUserRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT u.id, u.display_name, u.avatar_url, b.created_at_ms
+		FROM blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at_ms DESC, u.id ASC;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BlockedUserRow
+	for rows.Next() {
+		var (
+			row    BlockedUserRow
+			avatar sql.NullString
+		)
+		if err := rows.Scan(&row.UserID, &row.DisplayName, &avatar, &row.CreatedAtMs); err != nil {
+			return nil, err
+		}
+		if avatar.Valid {
+			v := avatar.String
+			row.AvatarURL = &v
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestBlocks_EnforcedAgainstBlockedUserOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	if _, err := store.BlockUser(ctx, alice.ID, alice.ID, now); !errors.Is(err, ErrCannotChatSelf) {
+		t.Fatalf("BlockUser(self) error = %v, want ErrCannotChatSelf", err)
+	}
+	for _, target := range []string{bob.ID, carol.ID} {
+		created, err := store.BlockUser(ctx, alice.ID, target, now)
+		if err != nil || !created {
+			t.Fatalf("BlockUser(%s) = %v, %v; want true, nil", target, created, err)
+		}
+	}
+	if created, err := store.BlockUser(ctx, alice.ID, bob.ID, now+1); err != nil || created {
+		t.Fatalf("BlockUser(repeat) = %v, %v; want false, nil", created, err)
+	}
+
+	// Session requests: carol cannot reach alice, but alice can still reach carol.
+	if _, _, err := store.CreateSessionRequest(ctx, carol.ID, alice.ID, SessionRequestSourceMap, nil, now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateSessionRequest(carol->alice) error = %v, want ErrBlocked", err)
+	}
+	if _, _, err := store.CreateSessionRequest(ctx, alice.ID, carol.ID, SessionRequestSourceMap, nil, now); err != nil {
+		t.Fatalf("CreateSessionRequest(alice->carol) error = %v", err)
+	}
+
+	// Calls.
+	if _, err := store.CreateCall(ctx, bob.ID, alice.ID, CallMediaTypeVoice, "g1", now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateCall(bob->alice) error = %v, want ErrBlocked", err)
+	}
+	call, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g2", now)
+	if err != nil {
+		t.Fatalf("CreateCall(alice->bob) error = %v", err)
+	}
+	if _, err := store.CancelCall(ctx, call.ID, alice.ID, now+1000); err != nil {
+		t.Fatalf("CancelCall() error = %v", err)
+	}
+
+	// Messages, including burn-after-reading ones.
+	text := "hi"
+	if _, err := store.CreateMessage(ctx, session.ID, bob.ID, MessageTypeText, &text, nil, now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateMessage(bob) error = %v, want ErrBlocked", err)
+	}
+	if _, _, err := store.CreateBurnMessage(ctx, session.ID, bob.ID, []byte(`{"kind":"text"}`), 10_000, now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateBurnMessage(bob) error = %v, want ErrBlocked", err)
+	}
+	if _, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now); err != nil {
+		t.Fatalf("CreateMessage(alice) error = %v", err)
+	}
+	if _, err := store.CreateMessage(ctx, session.ID, bob.ID, MessageTypeSystem, &text, nil, now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateMessage(bob system) error = %v, want ErrBlocked", err)
+	}
+	if _, err := store.CreateSystemMessage(ctx, session.ID, bob.ID, text, now); err != nil {
+		t.Fatalf("CreateSystemMessage(bob) error = %v", err)
+	}
+
+	blocked, err := store.ListBlockedUsers(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListBlockedUsers() error = %v", err)
+	}
+	if len(blocked) != 2 {
+		t.Fatalf("ListBlockedUsers() len = %d, want 2", len(blocked))
+	}
+
+	removed, err := store.UnblockUser(ctx, alice.ID, bob.ID)
+	if err != nil || !removed {
+		t.Fatalf("UnblockUser() = %v, %v; want true, nil", removed, err)
+	}
+	if isBlocked, err := store.IsBlocked(ctx, alice.ID, bob.ID); err != nil || isBlocked {
+		t.Fatalf("IsBlocked() after unblock = %v, %v; want false, nil", isBlocked, err)
+	}
+	if _, err := store.CreateMessage(ctx, session.ID, bob.ID, MessageTypeText, &text, nil, now+2000); err != nil {
+		t.Fatalf("CreateMessage(bob) after unblock error = %v", err)
+	}
+}
@@ -13,10 +13,32 @@ import (
 )
 
 const (
-	minBurnAfterMs = int64(1000)                     // 1s
-	maxBurnAfterMs = int64(30 * 24 * 60 * 60 * 1000) // 30d
+	// MinBurnAfterMs and MaxBurnAfterMs bound how long a burn message stays readable after
+	// it is opened.
+	MinBurnAfterMs = int64(1000)                     // 1s
+	MaxBurnAfterMs = int64(30 * 24 * 60 * 60 * 1000) // 30d
+
+	// GroupBurnOpenWindowMs is how long group members have to open a burn message. After
+	// that (plus BurnAfterMs) the message burns even if some members never opened it.
+	GroupBurnOpenWindowMs = int64(7 * 24 * 60 * 60 * 1000) // 7d
+	// MaxBurnGroupRecipients keeps group burn messages to small groups.
+	MaxBurnGroupRecipients = 50
 )
 
+// BurnAfterPresetsMs are the durations clients offer in their burn-after picker, shortest
+// first. Every preset lies within [MinBurnAfterMs, MaxBurnAfterMs].
+var BurnAfterPresetsMs = []int64{
+	5 * 1000,            // 5s
+	60 * 1000,           // 1m
+	60 * 60 * 1000,      // 1h
+	24 * 60 * 60 * 1000, // 1d
+}
+
+// ValidBurnAfterMs reports whether burnAfterMs is within the allowed bounds.
+func ValidBurnAfterMs(burnAfterMs int64) bool {
+	return burnAfterMs >= MinBurnAfterMs && burnAfterMs <= MaxBurnAfterMs
+}
+
 func (s *Store) CreateBurnMessage(ctx context.Context, sessionID, senderID string, metaJSON []byte, burnAfterMs int64, nowMs int64) (MessageRow, BurnMessageRow, error) {
 	if s == nil || s.db == nil {
 		return MessageRow{}, BurnMessageRow{}, fmt.Errorf("db not initialized")
@@ -27,7 +49,7 @@ func (s *Store) CreateBurnMessage(ctx context.Context, sessionID, senderID strin
 		return MessageRow{}, BurnMessageRow{}, fmt.Errorf("missing required fields")
 	}
 
-	if burnAfterMs < minBurnAfterMs || burnAfterMs > maxBurnAfterMs {
+	if !ValidBurnAfterMs(burnAfterMs) {
 		return MessageRow{}, BurnMessageRow{}, fmt.Errorf("invalid burnAfterMs")
 	}
 
@@ -43,19 +65,55 @@ func (s *Store) CreateBurnMessage(ctx context.Context, sessionID, senderID strin
 	if err != nil {
 		return MessageRow{}, BurnMessageRow{}, err
 	}
-	if session.Kind != SessionKindDirect {
-		return MessageRow{}, BurnMessageRow{}, ErrInvalidState
-	}
-	if session.User1ID != senderID && session.User2ID != senderID {
-		return MessageRow{}, BurnMessageRow{}, ErrAccessDenied
-	}
-	if session.Status == SessionStatusArchived {
-		return MessageRow{}, BurnMessageRow{}, ErrSessionArchived
-	}
 
-	recipientID := s.GetPeerUserID(session, senderID)
-	if strings.TrimSpace(recipientID) == "" {
-		return MessageRow{}, BurnMessageRow{}, ErrAccessDenied
+	var (
+		recipientID  string
+		groupMembers []string
+		burnAtMs     *int64
+	)
+	switch session.Kind {
+	case SessionKindDirect:
+		if session.User1ID != senderID && session.User2ID != senderID {
+			return MessageRow{}, BurnMessageRow{}, ErrAccessDenied
+		}
+		if session.Status == SessionStatusArchived {
+			return MessageRow{}, BurnMessageRow{}, ErrSessionArchived
+		}
+		recipientID = s.GetPeerUserID(session, senderID)
+		if strings.TrimSpace(recipientID) == "" {
+			return MessageRow{}, BurnMessageRow{}, ErrAccessDenied
+		}
+		if err := s.checkNotBlocked(ctx, recipientID, senderID); err != nil {
+			return MessageRow{}, BurnMessageRow{}, err
+		}
+	case SessionKindGroup:
+		isParticipant, err := s.IsSessionParticipant(ctx, sessionID, senderID)
+		if err != nil {
+			return MessageRow{}, BurnMessageRow{}, err
+		}
+		if !isParticipant {
+			return MessageRow{}, BurnMessageRow{}, ErrAccessDenied
+		}
+		if session.Status == SessionStatusArchived {
+			return MessageRow{}, BurnMessageRow{}, ErrSessionArchived
+		}
+		members, err := s.ListActiveSessionParticipantIDs(ctx, sessionID)
+		if err != nil {
+			return MessageRow{}, BurnMessageRow{}, err
+		}
+		for _, id := range members {
+			if id != senderID {
+				groupMembers = append(groupMembers, id)
+			}
+		}
+		if len(groupMembers) == 0 || len(groupMembers) > MaxBurnGroupRecipients {
+			return MessageRow{}, BurnMessageRow{}, ErrInvalidState
+		}
+		// Members who never open the message must not keep it alive forever.
+		purgeAtMs := nowMs + GroupBurnOpenWindowMs + burnAfterMs
+		burnAtMs = &purgeAtMs
+	default:
+		return MessageRow{}, BurnMessageRow{}, ErrInvalidState
 	}
 
 	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
@@ -80,22 +138,36 @@ func (s *Store) CreateBurnMessage(ctx context.Context, sessionID, senderID strin
 		MessageID:   messageID,
 		SessionID:   sessionID,
 		SenderID:    senderID,
+		SessionKind: session.Kind,
 		RecipientID: recipientID,
 		BurnAfterMs: burnAfterMs,
+		BurnAtMs:    burnAtMs,
 		CreatedAtMs: nowMs,
 		UpdatedAtMs: nowMs,
 	}
 
+	var recipientVal any
+	if recipientID != "" {
+		recipientVal = recipientID
+	}
 	insertBurnQ := `INSERT INTO burn_messages (
-			message_id, session_id, sender_id, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+			message_id, session_id, sender_id, session_kind, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertBurnQ),
-		burnRow.MessageID, burnRow.SessionID, burnRow.SenderID, burnRow.RecipientID,
-		burnRow.BurnAfterMs, nil, nil, burnRow.CreatedAtMs, burnRow.UpdatedAtMs,
+		burnRow.MessageID, burnRow.SessionID, burnRow.SenderID, burnRow.SessionKind, recipientVal,
+		burnRow.BurnAfterMs, nil, burnRow.BurnAtMs, burnRow.CreatedAtMs, burnRow.UpdatedAtMs,
 	); err != nil {
 		return MessageRow{}, BurnMessageRow{}, err
 	}
 
+	insertRecipientQ := rebindQuery(s.driver, `INSERT INTO burn_message_recipients (message_id, user_id) VALUES (?, ?);`)
+	for _, userID := range groupMembers {
+		if _, err := tx.ExecContext(txCtx, insertRecipientQ, messageID, userID); err != nil {
+			return MessageRow{}, BurnMessageRow{}, err
+		}
+		burnRow.Recipients = append(burnRow.Recipients, BurnRecipientRow{UserID: userID})
+	}
+
 	// Server can't preview encrypted content.
 	lastMessageText := "[阅后即焚]"
 	updateSessQ := `UPDATE sessions SET last_message_text = ?, last_message_at_ms = ?, updated_at_ms = ? WHERE id = ?;`
@@ -143,7 +215,7 @@ func (s *Store) GetBurnMessages(ctx context.Context, messageIDs []string) (map[s
 
 	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
 	q := fmt.Sprintf(`SELECT
-			message_id, session_id, sender_id, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
+			message_id, session_id, sender_id, session_kind, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
 		FROM burn_messages
 		WHERE message_id IN (%s);`, placeholders)
 
@@ -156,14 +228,16 @@ func (s *Store) GetBurnMessages(ctx context.Context, messageIDs []string) (map[s
 	out := make(map[string]BurnMessageRow, len(args))
 	for rows.Next() {
 		var row BurnMessageRow
+		var recipient sql.NullString
 		var opened sql.NullInt64
 		var burnAt sql.NullInt64
 		if err := rows.Scan(
-			&row.MessageID, &row.SessionID, &row.SenderID, &row.RecipientID,
+			&row.MessageID, &row.SessionID, &row.SenderID, &row.SessionKind, &recipient,
 			&row.BurnAfterMs, &opened, &burnAt, &row.CreatedAtMs, &row.UpdatedAtMs,
 		); err != nil {
 			return nil, err
 		}
+		row.RecipientID = recipient.String
 		if opened.Valid {
 			row.OpenedAtMs = &opened.Int64
 		}
@@ -175,6 +249,11 @@ func (s *Store) GetBurnMessages(ctx context.Context, messageIDs []string) (map[s
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	_ = rows.Close()
+
+	if err := attachBurnRecipients(ctx, s.db, s.driver, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
@@ -201,6 +280,16 @@ func (s *Store) MarkBurnMessageRead(ctx context.Context, messageID, userID strin
 	if err != nil {
 		return BurnMessageRow{}, false, err
 	}
+	if row.IsGroup() {
+		view, started, err := markGroupBurnRecipientRead(txCtx, tx, s.driver, row, userID, nowMs)
+		if err != nil {
+			return BurnMessageRow{}, false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return BurnMessageRow{}, false, err
+		}
+		return view, started, nil
+	}
 	if row.RecipientID != userID {
 		return BurnMessageRow{}, false, ErrAccessDenied
 	}
@@ -256,7 +345,7 @@ func (s *Store) ExpireBurnMessages(ctx context.Context, nowMs int64, limit int)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	selectQ := `SELECT message_id, session_id, sender_id, recipient_id
+	selectQ := `SELECT message_id, session_id, sender_id, session_kind, recipient_id
 		FROM burn_messages
 		WHERE burn_at_ms IS NOT NULL AND burn_at_ms <= ?
 		ORDER BY burn_at_ms ASC
@@ -271,9 +360,11 @@ func (s *Store) ExpireBurnMessages(ctx context.Context, nowMs int64, limit int)
 	var msgIDs []any
 	for rows.Next() {
 		var row BurnMessageRow
-		if err := rows.Scan(&row.MessageID, &row.SessionID, &row.SenderID, &row.RecipientID); err != nil {
+		var recipient sql.NullString
+		if err := rows.Scan(&row.MessageID, &row.SessionID, &row.SenderID, &row.SessionKind, &recipient); err != nil {
 			return nil, err
 		}
+		row.RecipientID = recipient.String
 		due = append(due, row)
 		msgIDs = append(msgIDs, row.MessageID)
 	}
@@ -283,6 +374,20 @@ func (s *Store) ExpireBurnMessages(ctx context.Context, nowMs int64, limit int)
 	if len(due) == 0 {
 		return nil, nil
 	}
+	_ = rows.Close()
+
+	// Load group recipients before the delete cascades them away so callers can notify
+	// everyone.
+	byID := make(map[string]BurnMessageRow, len(due))
+	for _, row := range due {
+		byID[row.MessageID] = row
+	}
+	if err := attachBurnRecipients(txCtx, tx, s.driver, byID); err != nil {
+		return nil, err
+	}
+	for i := range due {
+		due[i] = byID[due[i].MessageID]
+	}
 
 	placeholders := strings.TrimRight(strings.Repeat("?,", len(msgIDs)), ",")
 	deleteQ := fmt.Sprintf(`DELETE FROM messages WHERE id IN (%s);`, placeholders)
@@ -299,13 +404,14 @@ func (s *Store) ExpireBurnMessages(ctx context.Context, nowMs int64, limit int)
 
 func getBurnMessageInTx(ctx context.Context, tx *sql.Tx, driver, messageID string) (BurnMessageRow, error) {
 	q := rebindQuery(driver, `SELECT
-			message_id, session_id, sender_id, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
+			message_id, session_id, sender_id, session_kind, recipient_id, burn_after_ms, opened_at_ms, burn_at_ms, created_at_ms, updated_at_ms
 		FROM burn_messages WHERE message_id = ?;`)
 	var row BurnMessageRow
+	var recipient sql.NullString
 	var opened sql.NullInt64
 	var burnAt sql.NullInt64
 	if err := tx.QueryRowContext(ctx, q, messageID).Scan(
-		&row.MessageID, &row.SessionID, &row.SenderID, &row.RecipientID,
+		&row.MessageID, &row.SessionID, &row.SenderID, &row.SessionKind, &recipient,
 		&row.BurnAfterMs, &opened, &burnAt, &row.CreatedAtMs, &row.UpdatedAtMs,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -313,6 +419,7 @@ func getBurnMessageInTx(ctx context.Context, tx *sql.Tx, driver, messageID strin
 		}
 		return BurnMessageRow{}, err
 	}
+	row.RecipientID = recipient.String
 	if opened.Valid {
 		row.OpenedAtMs = &opened.Int64
 	}
@@ -322,6 +429,134 @@ func getBurnMessageInTx(ctx context.Context, tx *sql.Tx, driver, messageID strin
 	return row, nil
 }
 
+// markGroupBurnRecipientRead starts userID's own countdown on a group burn message and
+// returns the message as userID sees it. The first open stamps the message's OpenedAtMs; once
+// every member has opened it, the message is rescheduled to burn when the last countdown ends.
+func markGroupBurnRecipientRead(ctx context.Context, tx *sql.Tx, driver string, row BurnMessageRow, userID string, nowMs int64) (BurnMessageRow, bool, error) {
+	readRecipient := func() (BurnRecipientRow, error) {
+		q := rebindQuery(driver, `SELECT opened_at_ms, burn_at_ms FROM burn_message_recipients WHERE message_id = ? AND user_id = ?;`)
+		rec := BurnRecipientRow{UserID: userID}
+		var opened, burnAt sql.NullInt64
+		if err := tx.QueryRowContext(ctx, q, row.MessageID, userID).Scan(&opened, &burnAt); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return BurnRecipientRow{}, ErrAccessDenied
+			}
+			return BurnRecipientRow{}, err
+		}
+		if opened.Valid {
+			rec.OpenedAtMs = &opened.Int64
+		}
+		if burnAt.Valid {
+			rec.BurnAtMs = &burnAt.Int64
+		}
+		return rec, nil
+	}
+
+	view := row
+	view.RecipientID = userID
+	rec, err := readRecipient()
+	if err != nil {
+		return BurnMessageRow{}, false, err
+	}
+	if rec.OpenedAtMs != nil {
+		view.OpenedAtMs, view.BurnAtMs = rec.OpenedAtMs, rec.BurnAtMs
+		return view, false, nil
+	}
+
+	burnAtMs := nowMs + row.BurnAfterMs
+	updateQ := `UPDATE burn_message_recipients SET opened_at_ms = ?, burn_at_ms = ?
+		WHERE message_id = ? AND user_id = ? AND opened_at_ms IS NULL;`
+	res, err := tx.ExecContext(ctx, rebindQuery(driver, updateQ), nowMs, burnAtMs, row.MessageID, userID)
+	if err != nil {
+		return BurnMessageRow{}, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		rec, err := readRecipient()
+		if err != nil {
+			return BurnMessageRow{}, false, err
+		}
+		view.OpenedAtMs, view.BurnAtMs = rec.OpenedAtMs, rec.BurnAtMs
+		return view, false, nil
+	}
+
+	var (
+		unopened int
+		latest   sql.NullInt64
+	)
+	countQ := `SELECT COALESCE(SUM(CASE WHEN opened_at_ms IS NULL THEN 1 ELSE 0 END), 0), MAX(burn_at_ms)
+		FROM burn_message_recipients WHERE message_id = ?;`
+	if err := tx.QueryRowContext(ctx, rebindQuery(driver, countQ), row.MessageID).Scan(&unopened, &latest); err != nil {
+		return BurnMessageRow{}, false, err
+	}
+
+	openedAtMs := nowMs
+	if row.OpenedAtMs != nil {
+		openedAtMs = *row.OpenedAtMs
+	}
+	purgeAtMs := row.BurnAtMs
+	if unopened == 0 && latest.Valid && (purgeAtMs == nil || latest.Int64 < *purgeAtMs) {
+		purgeAtMs = &latest.Int64
+	}
+	updateMsgQ := `UPDATE burn_messages SET opened_at_ms = ?, burn_at_ms = ?, updated_at_ms = ? WHERE message_id = ?;`
+	if _, err := tx.ExecContext(ctx, rebindQuery(driver, updateMsgQ), openedAtMs, purgeAtMs, nowMs, row.MessageID); err != nil {
+		return BurnMessageRow{}, false, err
+	}
+
+	view.OpenedAtMs = &nowMs
+	view.BurnAtMs = &burnAtMs
+	view.UpdatedAtMs = nowMs
+	return view, true, nil
+}
+
+type sqlRowsQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// attachBurnRecipients fills Recipients for the group messages in rows.
+func attachBurnRecipients(ctx context.Context, q sqlRowsQueryer, driver string, rows map[string]BurnMessageRow) error {
+	args := make([]any, 0, len(rows))
+	for id, row := range rows {
+		if row.IsGroup() {
+			args = append(args, id)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
+	query := fmt.Sprintf(`SELECT message_id, user_id, opened_at_ms, burn_at_ms
+		FROM burn_message_recipients
+		WHERE message_id IN (%s)
+		ORDER BY message_id, user_id;`, placeholders)
+	rs, err := q.QueryContext(ctx, rebindQuery(driver, query), args...)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	for rs.Next() {
+		var (
+			messageID      string
+			rec            BurnRecipientRow
+			opened, burnAt sql.NullInt64
+		)
+		if err := rs.Scan(&messageID, &rec.UserID, &opened, &burnAt); err != nil {
+			return err
+		}
+		if opened.Valid {
+			rec.OpenedAtMs = &opened.Int64
+		}
+		if burnAt.Valid {
+			rec.BurnAtMs = &burnAt.Int64
+		}
+		row := rows[messageID]
+		row.Recipients = append(row.Recipients, rec)
+		rows[messageID] = row
+	}
+	return rs.Err()
+}
+
 func validateJSONObject(raw []byte) error {
 	var v any
 	if err := json.Unmarshal(raw, &v); err != nil {
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestGroupBurnMessages_PurgedAfterEveryRecipientCountdown(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	users := map[string]UserRow{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		users[name] = u
+	}
+	alice, bob, carol, dave := users["alice"], users["bob"], users["carol"], users["dave"]
+
+	for _, peer := range []UserRow{bob, carol} {
+		if _, _, err := store.CreateSession(ctx, alice.ID, peer.ID, now); err != nil {
+			t.Fatalf("CreateSession(alice, %s) error = %v", peer.Username, err)
+		}
+	}
+	session, _, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, carol.ID}, "trip", now)
+	if err != nil {
+		t.Fatalf("CreateGroupSession() error = %v", err)
+	}
+
+	const burnAfterMs = int64(10_000)
+	msg, burn, err := store.CreateBurnMessage(ctx, session.ID, alice.ID, []byte(`{"ciphertext":"abc"}`), burnAfterMs, now)
+	if err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+	if !burn.IsGroup() || burn.RecipientID != "" || len(burn.Recipients) != 2 {
+		t.Fatalf("burn = %+v, want group message with 2 recipients and no single recipient", burn)
+	}
+	if _, _, err := store.CreateBurnMessage(ctx, session.ID, dave.ID, []byte(`{"ciphertext":"abc"}`), burnAfterMs, now); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("CreateBurnMessage(outsider) error = %v, want ErrAccessDenied", err)
+	}
+
+	for _, id := range []string{alice.ID, dave.ID} {
+		if _, _, err := store.MarkBurnMessageRead(ctx, msg.ID, id, now+500); !errors.Is(err, ErrAccessDenied) {
+			t.Fatalf("MarkBurnMessageRead(%s) error = %v, want ErrAccessDenied", id, err)
+		}
+	}
+
+	view, started, err := store.MarkBurnMessageRead(ctx, msg.ID, bob.ID, now+1000)
+	if err != nil || !started {
+		t.Fatalf("MarkBurnMessageRead(bob) = %v, %v; want started", started, err)
+	}
+	if view.RecipientID != bob.ID || view.BurnAtMs == nil || *view.BurnAtMs != now+1000+burnAfterMs {
+		t.Fatalf("bob view = %+v, want his own countdown", view)
+	}
+	if _, started, err := store.MarkBurnMessageRead(ctx, msg.ID, bob.ID, now+2000); err != nil || started {
+		t.Fatalf("MarkBurnMessageRead(bob again) = %v, %v; want not started", started, err)
+	}
+
+	// Bob's countdown is over but Carol hasn't opened the message yet.
+	if due, err := store.ExpireBurnMessages(ctx, now+20_000, 200); err != nil || len(due) != 0 {
+		t.Fatalf("ExpireBurnMessages(carol unopened) = %d, %v; want none", len(due), err)
+	}
+
+	if _, _, err := store.MarkBurnMessageRead(ctx, msg.ID, carol.ID, now+30_000); err != nil {
+		t.Fatalf("MarkBurnMessageRead(carol) error = %v", err)
+	}
+	byID, err := store.GetBurnMessages(ctx, []string{msg.ID})
+	if err != nil {
+		t.Fatalf("GetBurnMessages() error = %v", err)
+	}
+	got := byID[msg.ID]
+	if got.OpenedAtMs == nil || *got.OpenedAtMs != now+1000 {
+		t.Fatalf("message OpenedAtMs = %v, want first open", got.OpenedAtMs)
+	}
+	if got.BurnAtMs == nil || *got.BurnAtMs != now+30_000+burnAfterMs {
+		t.Fatalf("message BurnAtMs = %v, want carol's countdown end", got.BurnAtMs)
+	}
+	if rec, ok := got.Recipient(carol.ID); !ok || rec.OpenedAtMs == nil {
+		t.Fatalf("carol recipient = %+v, %v; want opened", rec, ok)
+	}
+
+	if due, err := store.ExpireBurnMessages(ctx, now+30_000+burnAfterMs-1, 200); err != nil || len(due) != 0 {
+		t.Fatalf("ExpireBurnMessages(before last countdown) = %d, %v; want none", len(due), err)
+	}
+	due, err := store.ExpireBurnMessages(ctx, now+30_000+burnAfterMs, 200)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("ExpireBurnMessages() = %d, %v; want 1", len(due), err)
+	}
+	if audience := due[0].AudienceIDs(); len(audience) != 3 {
+		t.Fatalf("AudienceIDs() = %v, want sender and both recipients", audience)
+	}
+}
+
+func TestGroupBurnMessages_PurgedAfterOpenWindow(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	var ids []string
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		ids = append(ids, u.ID)
+	}
+	for _, peer := range ids[1:] {
+		if _, _, err := store.CreateSession(ctx, ids[0], peer, now); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+	}
+	session, _, err := store.CreateGroupSession(ctx, ids[0], ids[1:], "trip", now)
+	if err != nil {
+		t.Fatalf("CreateGroupSession() error = %v", err)
+	}
+
+	const burnAfterMs = int64(60_000)
+	msg, _, err := store.CreateBurnMessage(ctx, session.ID, ids[0], []byte(`{"ciphertext":"abc"}`), burnAfterMs, now)
+	if err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+	if _, _, err := store.MarkBurnMessageRead(ctx, msg.ID, ids[1], now+1000); err != nil {
+		t.Fatalf("MarkBurnMessageRead() error = %v", err)
+	}
+
+	capMs := now + GroupBurnOpenWindowMs + burnAfterMs
+	if due, err := store.ExpireBurnMessages(ctx, capMs-1, 200); err != nil || len(due) != 0 {
+		t.Fatalf("ExpireBurnMessages(before cap) = %d, %v; want none", len(due), err)
+	}
+	if due, err := store.ExpireBurnMessages(ctx, capMs, 200); err != nil || len(due) != 1 || due[0].MessageID != msg.ID {
+		t.Fatalf("ExpireBurnMessages(at cap) = %+v, %v; want the message", due, err)
+	}
+}
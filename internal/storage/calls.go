@@ -18,6 +18,9 @@ func (s *Store) CreateCall(ctx context.Context, callerID, calleeID, mediaType, g
 	if callerID == calleeID {
 		return CallRow{}, ErrCannotChatSelf
 	}
+	if err := s.checkNotBlocked(ctx, calleeID, callerID); err != nil {
+		return CallRow{}, err
+	}
 
 	// Check for active session between caller and callee
 	session, err := s.getSessionByParticipants(ctx, callerID, calleeID)
@@ -40,45 +43,131 @@ func (s *Store) CreateCall(ctx context.Context, callerID, calleeID, mediaType, g
 		UpdatedAtMs: nowMs,
 	}
 
+	// idx_calls_active_pair rejects the insert while a call between the same two users is
+	// still ringing or connected, so repeated taps cannot open a second one.
 	q := `INSERT INTO calls (id, group_id, caller_id, callee_id, media_type, status, created_at_ms, updated_at_ms)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
 	if _, err := s.db.ExecContext(ctx, s.rebind(q),
 		call.ID, call.GroupID, call.CallerID, call.CalleeID, call.MediaType, call.Status, call.CreatedAtMs, call.UpdatedAtMs,
 	); err != nil {
+		if isUniqueViolation(err) {
+			return CallRow{}, fmt.Errorf("%w: call already in progress", ErrInvalidState)
+		}
 		return CallRow{}, err
 	}
 
 	return call, nil
 }
 
+// CreateGroupCall opens a WeChat VoIP room for an activity chat. The initiator must be an
+// active member. Group calls start accepted since members join the room on their own; the
+// initiator is recorded as the caller and there is no callee, and any active member can end
+// the call for everyone. Only one group call can be active per activity.
+func (s *Store) CreateGroupCall(ctx context.Context, activityID, initiatorID, mediaType, groupID string, nowMs int64) (CallRow, error) {
+	if s == nil || s.db == nil {
+		return CallRow{}, fmt.Errorf("db not initialized")
+	}
+	if activityID == "" || initiatorID == "" || groupID == "" {
+		return CallRow{}, fmt.Errorf("missing required fields")
+	}
+
+	activity, err := s.GetActivityByID(ctx, activityID)
+	if err != nil {
+		return CallRow{}, err
+	}
+	session, err := s.GetSessionByID(ctx, activity.SessionID)
+	if err != nil {
+		return CallRow{}, err
+	}
+	if session.Status == SessionStatusArchived {
+		return CallRow{}, ErrSessionArchived
+	}
+	ok, err := s.IsSessionParticipant(ctx, session.ID, initiatorID)
+	if err != nil {
+		return CallRow{}, err
+	}
+	if !ok {
+		return CallRow{}, ErrAccessDenied
+	}
+
+	call := CallRow{
+		ID:           uuid.NewString(),
+		GroupID:      groupID,
+		CallerID:     initiatorID,
+		MediaType:    mediaType,
+		Status:       CallStatusAccepted,
+		CreatedAtMs:  nowMs,
+		UpdatedAtMs:  nowMs,
+		AcceptedAtMs: &nowMs,
+		SessionID:    session.ID,
+	}
+
+	q := `INSERT INTO calls (id, group_id, caller_id, media_type, status, created_at_ms, updated_at_ms, accepted_at_ms, session_id)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM calls WHERE session_id = ? AND status IN (?, ?)
+		);`
+	res, err := s.db.ExecContext(ctx, s.rebind(q),
+		call.ID, call.GroupID, call.CallerID, call.MediaType, call.Status, call.CreatedAtMs, call.UpdatedAtMs, nowMs, call.SessionID,
+		call.SessionID, CallStatusInviting, CallStatusAccepted,
+	)
+	if err != nil {
+		return CallRow{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return CallRow{}, fmt.Errorf("%w: group call already in progress", ErrInvalidState)
+	}
+	return call, nil
+}
+
 func (s *Store) GetCallByID(ctx context.Context, callID string) (CallRow, error) {
 	if s == nil || s.db == nil {
 		return CallRow{}, fmt.Errorf("db not initialized")
 	}
 
-	q := `SELECT id, group_id, caller_id, callee_id, media_type, status, created_at_ms, updated_at_ms
-		FROM calls WHERE id = ?;`
+	q := callSelect + ` WHERE id = ?;`
 
 	var call CallRow
+	var acceptedAt, endedAt sql.NullInt64
+	var calleeID, sessionID sql.NullString
 	if err := s.db.QueryRowContext(ctx, s.rebind(q), callID).Scan(
 		&call.ID,
 		&call.GroupID,
 		&call.CallerID,
-		&call.CalleeID,
+		&calleeID,
 		&call.MediaType,
 		&call.Status,
 		&call.CreatedAtMs,
 		&call.UpdatedAtMs,
+		&acceptedAt,
+		&endedAt,
+		&sessionID,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return CallRow{}, fmt.Errorf("%w: call", ErrNotFound)
 		}
 		return CallRow{}, err
 	}
+	setCallNullables(&call, calleeID, acceptedAt, endedAt, sessionID)
 
 	return call, nil
 }
 
+const callSelect = `SELECT id, group_id, caller_id, callee_id, media_type, status, created_at_ms, updated_at_ms,
+		accepted_at_ms, ended_at_ms, session_id
+	FROM calls`
+
+func setCallNullables(call *CallRow, calleeID sql.NullString, acceptedAt, endedAt sql.NullInt64, sessionID sql.NullString) {
+	call.CalleeID = calleeID.String
+	call.SessionID = sessionID.String
+	if acceptedAt.Valid {
+		call.AcceptedAtMs = &acceptedAt.Int64
+	}
+	if endedAt.Valid {
+		call.EndedAtMs = &endedAt.Int64
+	}
+}
+
 func (s *Store) AcceptCall(ctx context.Context, callID, userID string, nowMs int64) (CallRow, error) {
 	call, err := s.GetCallByID(ctx, callID)
 	if err != nil {
@@ -91,8 +180,8 @@ func (s *Store) AcceptCall(ctx context.Context, callID, userID string, nowMs int
 		return CallRow{}, ErrInvalidState
 	}
 
-	q := `UPDATE calls SET status = ?, updated_at_ms = ? WHERE id = ? AND status = ?;`
-	res, err := s.db.ExecContext(ctx, s.rebind(q), CallStatusAccepted, nowMs, callID, CallStatusInviting)
+	q := `UPDATE calls SET status = ?, updated_at_ms = ?, accepted_at_ms = ? WHERE id = ? AND status = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), CallStatusAccepted, nowMs, nowMs, callID, CallStatusInviting)
 	if err != nil {
 		return CallRow{}, err
 	}
@@ -103,6 +192,7 @@ func (s *Store) AcceptCall(ctx context.Context, callID, userID string, nowMs int
 
 	call.Status = CallStatusAccepted
 	call.UpdatedAtMs = nowMs
+	call.AcceptedAtMs = &nowMs
 	return call, nil
 }
 
@@ -160,20 +250,30 @@ func (s *Store) CancelCall(ctx context.Context, callID, userID string, nowMs int
 	return call, nil
 }
 
+// EndCall hangs up an accepted call. A direct call can be ended by either party; a group call
+// by any active member of its activity chat, so it does not depend on the initiator staying.
 func (s *Store) EndCall(ctx context.Context, callID, userID string, nowMs int64) (CallRow, error) {
 	call, err := s.GetCallByID(ctx, callID)
 	if err != nil {
 		return CallRow{}, err
 	}
-	if call.CallerID != userID && call.CalleeID != userID {
+	if call.IsGroup() {
+		ok, err := s.IsSessionParticipant(ctx, call.SessionID, userID)
+		if err != nil {
+			return CallRow{}, err
+		}
+		if !ok {
+			return CallRow{}, ErrAccessDenied
+		}
+	} else if call.CallerID != userID && call.CalleeID != userID {
 		return CallRow{}, ErrAccessDenied
 	}
 	if call.Status != CallStatusAccepted {
 		return CallRow{}, ErrInvalidState
 	}
 
-	q := `UPDATE calls SET status = ?, updated_at_ms = ? WHERE id = ? AND status = ?;`
-	res, err := s.db.ExecContext(ctx, s.rebind(q), CallStatusEnded, nowMs, callID, CallStatusAccepted)
+	q := `UPDATE calls SET status = ?, updated_at_ms = ?, ended_at_ms = ? WHERE id = ? AND status = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), CallStatusEnded, nowMs, nowMs, callID, CallStatusAccepted)
 	if err != nil {
 		return CallRow{}, err
 	}
@@ -184,5 +284,231 @@ func (s *Store) EndCall(ctx context.Context, callID, userID string, nowMs int64)
 
 	call.Status = CallStatusEnded
 	call.UpdatedAtMs = nowMs
+	call.EndedAtMs = &nowMs
+	return call, nil
+}
+
+// UpdateCallMedia switches an accepted call between voice and video. Either party of a direct
+// call may renegotiate, as may any active member of a group call's activity chat; setting the
+// current media type again is a no-op.
+func (s *Store) UpdateCallMedia(ctx context.Context, callID, userID, mediaType string, nowMs int64) (CallRow, error) {
+	if mediaType != CallMediaTypeVoice && mediaType != CallMediaTypeVideo {
+		return CallRow{}, fmt.Errorf("invalid media type")
+	}
+	call, err := s.GetCallByID(ctx, callID)
+	if err != nil {
+		return CallRow{}, err
+	}
+	if call.IsGroup() {
+		ok, err := s.IsSessionParticipant(ctx, call.SessionID, userID)
+		if err != nil {
+			return CallRow{}, err
+		}
+		if !ok {
+			return CallRow{}, ErrAccessDenied
+		}
+	} else if call.CallerID != userID && call.CalleeID != userID {
+		return CallRow{}, ErrAccessDenied
+	}
+	if call.Status != CallStatusAccepted {
+		return CallRow{}, ErrInvalidState
+	}
+	if call.MediaType == mediaType {
+		return call, nil
+	}
+
+	q := `UPDATE calls SET media_type = ?, updated_at_ms = ? WHERE id = ? AND status = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), mediaType, nowMs, callID, CallStatusAccepted)
+	if err != nil {
+		return CallRow{}, err
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return CallRow{}, ErrInvalidState
+	}
+
+	call.MediaType = mediaType
+	call.UpdatedAtMs = nowMs
 	return call, nil
 }
+
+// TimeoutStaleCalls marks calls still inviting after ringingTimeoutMs as missed and returns
+// them. Each update is conditional on the inviting status, so a call accepted, rejected or
+// canceled concurrently is left alone and not reported.
+func (s *Store) TimeoutStaleCalls(ctx context.Context, nowMs, ringingTimeoutMs int64) ([]CallRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if ringingTimeoutMs <= 0 {
+		return nil, fmt.Errorf("invalid ringing timeout")
+	}
+
+	selectQ := callSelect + `
+		WHERE status = ? AND created_at_ms <= ?
+		ORDER BY created_at_ms ASC
+		LIMIT 200;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(selectQ), CallStatusInviting, nowMs-ringingTimeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	var stale []CallRow
+	for rows.Next() {
+		var call CallRow
+		var acceptedAt, endedAt sql.NullInt64
+		var calleeID, sessionID sql.NullString
+		if err := rows.Scan(&call.ID, &call.GroupID, &call.CallerID, &calleeID, &call.MediaType, &call.Status, &call.CreatedAtMs, &call.UpdatedAtMs, &acceptedAt, &endedAt, &sessionID); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		setCallNullables(&call, calleeID, acceptedAt, endedAt, sessionID)
+		stale = append(stale, call)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	updateQ := `UPDATE calls SET status = ?, updated_at_ms = ? WHERE id = ? AND status = ?;`
+	out := make([]CallRow, 0, len(stale))
+	for _, call := range stale {
+		res, err := s.db.ExecContext(ctx, s.rebind(updateQ), CallStatusMissed, nowMs, call.ID, CallStatusInviting)
+		if err != nil {
+			return out, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		call.Status = CallStatusMissed
+		call.UpdatedAtMs = nowMs
+		out = append(out, call)
+	}
+	return out, nil
+}
+
+// EndStaleCalls ends direct and group calls that have been accepted for longer than
+// maxDurationMs and returns them. Nothing else ends a call whose clients all died without
+// hanging up, and an open call blocks new ones between the same two users or for the
+// activity. Such a call is assumed abandoned, so its end is stamped at its last recorded
+// activity (acceptance or a media switch) rather than nowMs, keeping call history from
+// reporting the whole cap as talk time.
+func (s *Store) EndStaleCalls(ctx context.Context, nowMs, maxDurationMs int64) ([]CallRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if maxDurationMs <= 0 {
+		return nil, fmt.Errorf("invalid max duration")
+	}
+
+	selectQ := callSelect + `
+		WHERE status = ? AND accepted_at_ms <= ?
+		ORDER BY accepted_at_ms ASC
+		LIMIT 200;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(selectQ), CallStatusAccepted, nowMs-maxDurationMs)
+	if err != nil {
+		return nil, err
+	}
+	var stale []CallRow
+	for rows.Next() {
+		var call CallRow
+		var acceptedAt, endedAt sql.NullInt64
+		var calleeID, sessionID sql.NullString
+		if err := rows.Scan(&call.ID, &call.GroupID, &call.CallerID, &calleeID, &call.MediaType, &call.Status, &call.CreatedAtMs, &call.UpdatedAtMs, &acceptedAt, &endedAt, &sessionID); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		setCallNullables(&call, calleeID, acceptedAt, endedAt, sessionID)
+		stale = append(stale, call)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	// Conditional on updated_at_ms too, so a media switch that lands meanwhile leaves the
+	// call for the next pass instead of being stamped with a stale end time.
+	updateQ := `UPDATE calls SET status = ?, updated_at_ms = ?, ended_at_ms = ? WHERE id = ? AND status = ? AND updated_at_ms = ?;`
+	out := make([]CallRow, 0, len(stale))
+	for _, call := range stale {
+		endedAt := call.UpdatedAtMs
+		res, err := s.db.ExecContext(ctx, s.rebind(updateQ), CallStatusEnded, nowMs, endedAt, call.ID, CallStatusAccepted, call.UpdatedAtMs)
+		if err != nil {
+			return out, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		call.Status = CallStatusEnded
+		call.UpdatedAtMs = nowMs
+		call.EndedAtMs = &endedAt
+		out = append(out, call)
+	}
+	return out, nil
+}
+
+// ListCallsForUser returns the calls userID placed or received, plus the group calls of the
+// activity chats they are an active member of, newest first, paging backwards from beforeID
+// when set. The bool reports whether older calls remain.
+func (s *Store) ListCallsForUser(ctx context.Context, userID string, limit int, beforeID string) ([]CallRow, bool, error) {
+	if s == nil || s.db == nil {
+		return nil, false, fmt.Errorf("db not initialized")
+	}
+	if userID == "" {
+		return nil, false, fmt.Errorf("missing required fields")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	visible := `(caller_id = ? OR callee_id = ? OR session_id IN (
+		SELECT session_id FROM session_participants WHERE user_id = ? AND status = ?))`
+	visibleArgs := []any{userID, userID, userID, SessionParticipantStatusActive}
+
+	q := callSelect + ` WHERE ` + visible
+	args := append([]any{}, visibleArgs...)
+	if beforeID != "" {
+		var beforeCreatedAt int64
+		subQ := `SELECT created_at_ms FROM calls WHERE id = ? AND ` + visible + `;`
+		if err := s.db.QueryRowContext(ctx, s.rebind(subQ), append([]any{beforeID}, visibleArgs...)...).Scan(&beforeCreatedAt); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, false, fmt.Errorf("%w: call", ErrNotFound)
+			}
+			return nil, false, err
+		}
+		// Ties on created_at_ms are broken by id so no call is skipped or repeated.
+		q += ` AND (created_at_ms < ? OR (created_at_ms = ? AND id < ?))`
+		args = append(args, beforeCreatedAt, beforeCreatedAt, beforeID)
+	}
+	q += `
+		ORDER BY created_at_ms DESC, id DESC
+		LIMIT ?;`
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var calls []CallRow
+	for rows.Next() {
+		var call CallRow
+		var acceptedAt, endedAt sql.NullInt64
+		var calleeID, sessionID sql.NullString
+		if err := rows.Scan(&call.ID, &call.GroupID, &call.CallerID, &calleeID, &call.MediaType, &call.Status, &call.CreatedAtMs, &call.UpdatedAtMs, &acceptedAt, &endedAt, &sessionID); err != nil {
+			return nil, false, err
+		}
+		setCallNullables(&call, calleeID, acceptedAt, endedAt, sessionID)
+		calls = append(calls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(calls) > limit
+	if hasMore {
+		calls = calls[:limit]
+	}
+	return calls, hasMore, nil
+}
@@ -0,0 +1,412 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTimeoutStaleCalls_MarksUnansweredCallsMissed(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	const timeoutMs = int64(60_000)
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+	for _, pair := range [][2]string{{alice.ID, bob.ID}, {alice.ID, carol.ID}, {bob.ID, carol.ID}} {
+		if _, _, err := store.CreateSession(ctx, pair[0], pair[1], now); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+	}
+
+	stale, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g1", now)
+	if err != nil {
+		t.Fatalf("CreateCall(stale) error = %v", err)
+	}
+	answered, err := store.CreateCall(ctx, alice.ID, carol.ID, CallMediaTypeVoice, "g2", now)
+	if err != nil {
+		t.Fatalf("CreateCall(answered) error = %v", err)
+	}
+	if _, err := store.AcceptCall(ctx, answered.ID, carol.ID, now+1000); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+	fresh, err := store.CreateCall(ctx, bob.ID, carol.ID, CallMediaTypeVideo, "g3", now+30_000)
+	if err != nil {
+		t.Fatalf("CreateCall(fresh) error = %v", err)
+	}
+
+	sweepAt := now + timeoutMs
+	timedOut, err := store.TimeoutStaleCalls(ctx, sweepAt, timeoutMs)
+	if err != nil {
+		t.Fatalf("TimeoutStaleCalls() error = %v", err)
+	}
+	if len(timedOut) != 1 || timedOut[0].ID != stale.ID || timedOut[0].Status != CallStatusMissed || timedOut[0].UpdatedAtMs != sweepAt {
+		t.Fatalf("timed out = %+v, want only the stale call as missed", timedOut)
+	}
+
+	for _, c := range []struct {
+		id   string
+		want string
+	}{
+		{stale.ID, CallStatusMissed},
+		{answered.ID, CallStatusAccepted},
+		{fresh.ID, CallStatusInviting},
+	} {
+		got, err := store.GetCallByID(ctx, c.id)
+		if err != nil {
+			t.Fatalf("GetCallByID(%s) error = %v", c.id, err)
+		}
+		if got.Status != c.want {
+			t.Fatalf("call %s status = %q, want %q", c.id, got.Status, c.want)
+		}
+	}
+
+	if _, err := store.AcceptCall(ctx, stale.ID, bob.ID, sweepAt+1); err == nil {
+		t.Fatalf("AcceptCall(missed) error = nil, want error")
+	}
+	again, err := store.TimeoutStaleCalls(ctx, sweepAt+1, timeoutMs)
+	if err != nil {
+		t.Fatalf("TimeoutStaleCalls(again) error = %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("second sweep = %+v, want none", again)
+	}
+}
+
+func TestListCallsForUser_HistoryWithDuration(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	users := map[string]UserRow{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		users[name] = u
+	}
+	alice, bob, carol := users["alice"], users["bob"], users["carol"]
+	if _, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now); err != nil {
+		t.Fatalf("CreateSession(alice, bob) error = %v", err)
+	}
+	if _, _, err := store.CreateSession(ctx, bob.ID, carol.ID, now); err != nil {
+		t.Fatalf("CreateSession(bob, carol) error = %v", err)
+	}
+
+	ended, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g1", now)
+	if err != nil {
+		t.Fatalf("CreateCall(ended) error = %v", err)
+	}
+	if _, err := store.AcceptCall(ctx, ended.ID, bob.ID, now+2_000); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+	if _, err := store.EndCall(ctx, ended.ID, alice.ID, now+65_000); err != nil {
+		t.Fatalf("EndCall() error = %v", err)
+	}
+	rejected, err := store.CreateCall(ctx, bob.ID, alice.ID, CallMediaTypeVideo, "g2", now+100_000)
+	if err != nil {
+		t.Fatalf("CreateCall(rejected) error = %v", err)
+	}
+	if _, err := store.RejectCall(ctx, rejected.ID, alice.ID, now+101_000); err != nil {
+		t.Fatalf("RejectCall() error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, bob.ID, carol.ID, CallMediaTypeVoice, "g3", now+200_000); err != nil {
+		t.Fatalf("CreateCall(bob, carol) error = %v", err)
+	}
+
+	calls, hasMore, err := store.ListCallsForUser(ctx, alice.ID, 1, "")
+	if err != nil {
+		t.Fatalf("ListCallsForUser() error = %v", err)
+	}
+	if len(calls) != 1 || !hasMore || calls[0].ID != rejected.ID || calls[0].Status != CallStatusRejected || calls[0].DurationMs() != 0 {
+		t.Fatalf("first page = %+v (hasMore=%v), want the rejected call with more to come", calls, hasMore)
+	}
+
+	calls, hasMore, err = store.ListCallsForUser(ctx, alice.ID, 1, calls[0].ID)
+	if err != nil {
+		t.Fatalf("ListCallsForUser(before) error = %v", err)
+	}
+	if len(calls) != 1 || hasMore || calls[0].ID != ended.ID || calls[0].Status != CallStatusEnded {
+		t.Fatalf("second page = %+v (hasMore=%v), want only the ended call", calls, hasMore)
+	}
+	if got := calls[0].DurationMs(); got != 63_000 {
+		t.Fatalf("DurationMs() = %d, want %d", got, 63_000)
+	}
+
+	if _, _, err := store.ListCallsForUser(ctx, carol.ID, 10, ended.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ListCallsForUser(foreign cursor) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateCall_RejectsWhileAnotherIsActive(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	if _, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	first, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g1", now)
+	if err != nil {
+		t.Fatalf("CreateCall(first) error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g2", now+100); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("CreateCall(repeat tap) error = %v, want ErrInvalidState", err)
+	}
+	if _, err := store.AcceptCall(ctx, first.ID, bob.ID, now+1_000); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, bob.ID, alice.ID, CallMediaTypeVideo, "g3", now+2_000); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("CreateCall(during accepted call) error = %v, want ErrInvalidState", err)
+	}
+	if _, err := store.EndCall(ctx, first.ID, alice.ID, now+3_000); err != nil {
+		t.Fatalf("EndCall() error = %v", err)
+	}
+	if _, err := store.CreateCall(ctx, bob.ID, alice.ID, CallMediaTypeVideo, "g4", now+4_000); err != nil {
+		t.Fatalf("CreateCall(after end) error = %v", err)
+	}
+
+	calls, _, err := store.ListCallsForUser(ctx, alice.ID, 10, "")
+	if err != nil {
+		t.Fatalf("ListCallsForUser() error = %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want 2", len(calls))
+	}
+}
+
+func TestActiveCallPairIndex_EndsLegacyDuplicatesAndRejectsNewOnes(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice := newTestUser(t, store, "alice", now)
+	bob := newTestUser(t, store, "bob", now)
+
+	// A database from before the index may hold concurrent calls for the same pair.
+	if _, err := store.db.ExecContext(ctx, `DROP INDEX idx_calls_active_pair;`); err != nil {
+		t.Fatalf("DROP INDEX error = %v", err)
+	}
+	insertQ := store.rebind(`INSERT INTO calls (id, group_id, caller_id, callee_id, media_type, status, created_at_ms, updated_at_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	insert := func(id, callerID, calleeID string, atMs int64) error {
+		_, err := store.db.ExecContext(ctx, insertQ, id, "g-"+id, callerID, calleeID, CallMediaTypeVoice, CallStatusInviting, atMs, atMs)
+		return err
+	}
+	if err := insert("older", alice.ID, bob.ID, now); err != nil {
+		t.Fatalf("insert(older) error = %v", err)
+	}
+	if err := insert("newer", bob.ID, alice.ID, now+100); err != nil {
+		t.Fatalf("insert(newer) error = %v", err)
+	}
+
+	if err := ensureActiveCallPairIndex(ctx, store.db, store.driver); err != nil {
+		t.Fatalf("ensureActiveCallPairIndex() error = %v", err)
+	}
+	if older, err := store.GetCallByID(ctx, "older"); err != nil || older.Status != CallStatusEnded {
+		t.Fatalf("older call = %+v, %v; want ended", older, err)
+	}
+	if newer, err := store.GetCallByID(ctx, "newer"); err != nil || newer.Status != CallStatusInviting {
+		t.Fatalf("newer call = %+v, %v; want still inviting", newer, err)
+	}
+
+	// The index holds even for writers that skip CreateCall's error mapping.
+	if err := insert("third", alice.ID, bob.ID, now+200); !isUniqueViolation(err) {
+		t.Fatalf("insert(third) error = %v, want a unique violation", err)
+	}
+}
+
+func TestEndStaleCalls_UnblocksAbandonedDirectCall(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	const maxDurationMs = int64(4 * 60 * 60 * 1000)
+
+	alice := newTestUser(t, store, "alice", now)
+	bob := newTestUser(t, store, "bob", now)
+	if _, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	// Both clients die mid-call, so nobody ever ends it.
+	call, err := store.CreateCall(ctx, alice.ID, bob.ID, CallMediaTypeVoice, "g1", now)
+	if err != nil {
+		t.Fatalf("CreateCall() error = %v", err)
+	}
+	if _, err := store.AcceptCall(ctx, call.ID, bob.ID, now+1_000); err != nil {
+		t.Fatalf("AcceptCall() error = %v", err)
+	}
+	if _, err := store.UpdateCallMedia(ctx, call.ID, alice.ID, CallMediaTypeVideo, now+61_000); err != nil {
+		t.Fatalf("UpdateCallMedia() error = %v", err)
+	}
+
+	if got, err := store.EndStaleCalls(ctx, now+1_000+maxDurationMs-1, maxDurationMs); err != nil || len(got) != 0 {
+		t.Fatalf("EndStaleCalls(before max) = %+v, %v, want none", got, err)
+	}
+	got, err := store.EndStaleCalls(ctx, now+1_000+maxDurationMs, maxDurationMs)
+	if err != nil {
+		t.Fatalf("EndStaleCalls() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != call.ID || got[0].Status != CallStatusEnded || got[0].IsGroup() {
+		t.Fatalf("EndStaleCalls() = %+v, want the direct call ended", got)
+	}
+	// The end is stamped at the last recorded activity, not at the sweep.
+	if got[0].EndedAtMs == nil || *got[0].EndedAtMs != now+61_000 || got[0].DurationMs() != 60_000 {
+		t.Fatalf("EndStaleCalls() ended at %v duration %d, want %d and 60000", got[0].EndedAtMs, got[0].DurationMs(), now+61_000)
+	}
+	stored, err := store.GetCallByID(ctx, call.ID)
+	if err != nil {
+		t.Fatalf("GetCallByID() error = %v", err)
+	}
+	if stored.EndedAtMs == nil || *stored.EndedAtMs != now+61_000 {
+		t.Fatalf("stored EndedAtMs = %v, want %d", stored.EndedAtMs, now+61_000)
+	}
+	if _, err := store.CreateCall(ctx, bob.ID, alice.ID, CallMediaTypeVoice, "g2", now+1_000+maxDurationMs+1); err != nil {
+		t.Fatalf("CreateCall(after sweep) error = %v", err)
+	}
+}
+
+func TestGroupCall_EndableByMembersAndListedForAll(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	const maxDurationMs = int64(4 * 60 * 60 * 1000)
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+
+	activity, invite, err := store.CreateActivity(ctx, alice.ID, "Picnic", nil, nil, nil, now)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	if _, _, _, err := store.ConsumeActivityInvite(ctx, bob.ID, invite.Code, nil, nil, now); err != nil {
+		t.Fatalf("ConsumeActivityInvite(bob) error = %v", err)
+	}
+
+	call, err := store.CreateGroupCall(ctx, activity.ID, alice.ID, CallMediaTypeVoice, "g1", now)
+	if err != nil {
+		t.Fatalf("CreateGroupCall() error = %v", err)
+	}
+	if stored, err := store.GetCallByID(ctx, call.ID); err != nil || !stored.IsGroup() || stored.CallerID != alice.ID || stored.CalleeID != "" {
+		t.Fatalf("GetCallByID() = %+v, %v; want a group call started by alice with no callee", stored, err)
+	}
+
+	for _, u := range []UserRow{alice, bob} {
+		calls, _, err := store.ListCallsForUser(ctx, u.ID, 10, "")
+		if err != nil {
+			t.Fatalf("ListCallsForUser(%s) error = %v", u.Username, err)
+		}
+		if len(calls) != 1 || calls[0].ID != call.ID {
+			t.Fatalf("ListCallsForUser(%s) = %+v, want the group call", u.Username, calls)
+		}
+	}
+	if calls, _, err := store.ListCallsForUser(ctx, carol.ID, 10, ""); err != nil || len(calls) != 0 {
+		t.Fatalf("ListCallsForUser(carol) = %+v, %v, want none", calls, err)
+	}
+
+	if _, err := store.EndCall(ctx, call.ID, carol.ID, now+1000); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("EndCall(outsider) error = %v, want ErrAccessDenied", err)
+	}
+	ended, err := store.EndCall(ctx, call.ID, bob.ID, now+1000)
+	if err != nil {
+		t.Fatalf("EndCall(member) error = %v", err)
+	}
+	if ended.Status != CallStatusEnded {
+		t.Fatalf("EndCall(member) status = %s, want ended", ended.Status)
+	}
+
+	// A call nobody hung up is ended by the sweeper once it exceeds the max duration.
+	stale, err := store.CreateGroupCall(ctx, activity.ID, alice.ID, CallMediaTypeVoice, "g2", now+2000)
+	if err != nil {
+		t.Fatalf("CreateGroupCall(second) error = %v", err)
+	}
+	if got, err := store.EndStaleCalls(ctx, now+2000+maxDurationMs-1, maxDurationMs); err != nil || len(got) != 0 {
+		t.Fatalf("EndStaleCalls(before max) = %+v, %v, want none", got, err)
+	}
+	got, err := store.EndStaleCalls(ctx, now+2000+maxDurationMs, maxDurationMs)
+	if err != nil {
+		t.Fatalf("EndStaleCalls() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != stale.ID || got[0].Status != CallStatusEnded {
+		t.Fatalf("EndStaleCalls() = %+v, want the second call ended", got)
+	}
+	if _, err := store.CreateGroupCall(ctx, activity.ID, bob.ID, CallMediaTypeVoice, "g3", now+2000+maxDurationMs+1); err != nil {
+		t.Fatalf("CreateGroupCall(after sweep) error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// CardViewerRow summarizes one user who viewed a card.
+type CardViewerRow struct {
+	ViewerID       string
+	DisplayName    string
+	AvatarURL      *string
+	LastViewedAtMs int64
+	// ViewDays counts the distinct days (in the reset time zone) the viewer looked at the card.
+	ViewDays int
+}
+
+// RecordCardView notes that viewerID opened targetID's card. Views are kept at most once per
+// viewer per day; repeat views on the same day are ignored. Viewing your own card is not recorded.
+func (s *Store) RecordCardView(ctx context.Context, viewerID, targetID string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if viewerID == "" || targetID == "" {
+		return fmt.Errorf("missing ids")
+	}
+	if viewerID == targetID {
+		return nil
+	}
+
+	q := `INSERT INTO card_views (target_id, viewer_id, view_ymd, viewed_at_ms)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(target_id, viewer_id, view_ymd) DO NOTHING;`
+	_, err := s.db.ExecContext(ctx, s.rebind(q), targetID, viewerID, ymdInResetTZ(nowMs), nowMs)
+	return err
+}
+
+// ListCardViewers returns the most recent viewers of targetID's card. Only viewers who share a
+// direct session with the target are included, so strangers who opened the card stay anonymous.
+func (s *Store) ListCardViewers(ctx context.Context, targetID string, limit int) ([]CardViewerRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	q := `SELECT cv.viewer_id, u.display_name, u.avatar_url, MAX(cv.viewed_at_ms) AS last_viewed_at_ms, COUNT(*)
+		FROM card_views cv
+		JOIN users u ON u.id = cv.viewer_id
+		WHERE cv.target_id = ?
+		AND EXISTS (
+			SELECT 1 FROM sessions s
+			WHERE s.kind = ?
+			AND ((s.user1_id = cv.target_id AND s.user2_id = cv.viewer_id) OR (s.user1_id = cv.viewer_id AND s.user2_id = cv.target_id))
+		)
+		GROUP BY cv.viewer_id, u.display_name, u.avatar_url
+		ORDER BY last_viewed_at_ms DESC, cv.viewer_id ASC
+		LIMIT ?;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), targetID, SessionKindDirect, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CardViewerRow
+	for rows.Next() {
+		var (
+			v      CardViewerRow
+			avatar sql.NullString
+		)
+		if err := rows.Scan(&v.ViewerID, &v.DisplayName, &avatar, &v.LastViewedAtMs, &v.ViewDays); err != nil {
+			return nil, err
+		}
+		if avatar.Valid {
+			v.AvatarURL = &avatar.String
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ConversationRow bundles what a client needs to render a direct chat on first open.
+type ConversationRow struct {
+	Session  SessionRow
+	Peer     UserRow
+	Meta     *SessionUserMetaRow
+	Messages []MessageRow
+	HasMore  bool
+}
+
+// GetConversation loads a direct session for userID together with the peer, the viewer's
+// relationship meta and the newest messageLimit messages (oldest first).
+func (s *Store) GetConversation(ctx context.Context, sessionID, userID string, messageLimit int) (ConversationRow, error) {
+	if s == nil || s.db == nil {
+		return ConversationRow{}, fmt.Errorf("db not initialized")
+	}
+	if sessionID == "" || userID == "" {
+		return ConversationRow{}, fmt.Errorf("missing ids")
+	}
+	if messageLimit <= 0 || messageLimit > 50 {
+		messageLimit = 20
+	}
+
+	session, err := s.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return ConversationRow{}, err
+	}
+	if session.Kind == SessionKindGroup {
+		return ConversationRow{}, ErrInvalidState
+	}
+	if session.User1ID != userID && session.User2ID != userID {
+		return ConversationRow{}, ErrAccessDenied
+	}
+
+	peer, err := s.GetUserByID(ctx, s.GetPeerUserID(session, userID))
+	if err != nil {
+		return ConversationRow{}, err
+	}
+
+	out := ConversationRow{Session: session, Peer: peer}
+
+	meta, err := s.GetSessionUserMeta(ctx, sessionID, userID)
+	if err == nil {
+		out.Meta = &meta
+	} else if !errors.Is(err, ErrNotFound) {
+		return ConversationRow{}, err
+	}
+
+	out.Messages, out.HasMore, err = s.ListMessages(ctx, sessionID, userID, messageLimit, "", "")
+	if err != nil {
+		return ConversationRow{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// exportSessionFilter matches the sessions userID can read: direct sessions they are part of
+// and group sessions (ad-hoc or activity chats) they are an active member of. Sessions the
+// user hid are included; hiding only affects the session list.
+const exportSessionFilter = `(
+		(sessions.kind = ? AND (sessions.user1_id = ? OR sessions.user2_id = ?))
+		OR (sessions.kind = ? AND EXISTS (
+			SELECT 1 FROM session_participants sp
+			WHERE sp.session_id = sessions.id AND sp.user_id = ? AND sp.status = ?))
+	)`
+
+func exportSessionArgs(userID string) []any {
+	return []any{
+		SessionKindDirect, userID, userID,
+		SessionKindGroup, userID, SessionParticipantStatusActive,
+	}
+}
+
+// ListExportSessions returns every session userID can read, active or archived, oldest first.
+// HiddenByUsers is left empty: it records other participants' choices too.
+func (s *Store) ListExportSessions(ctx context.Context, userID string) ([]SessionRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT id, participants_hash, user1_id, user2_id, source, kind, status, last_message_text, last_message_at_ms, created_at_ms, updated_at_ms, reactivated_at_ms
+		FROM sessions
+		WHERE ` + exportSessionFilter + `
+		ORDER BY created_at_ms ASC, id ASC;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), exportSessionArgs(userID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []SessionRow
+	for rows.Next() {
+		var session SessionRow
+		var lastText sql.NullString
+		var lastAtMs sql.NullInt64
+		var reactivatedAt sql.NullInt64
+		if err := rows.Scan(
+			&session.ID, &session.ParticipantsHash, &session.User1ID, &session.User2ID,
+			&session.Source, &session.Kind, &session.Status, &lastText, &lastAtMs, &session.CreatedAtMs, &session.UpdatedAtMs,
+			&reactivatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if lastText.Valid {
+			session.LastMessageText = &lastText.String
+		}
+		if lastAtMs.Valid {
+			session.LastMessageAtMs = &lastAtMs.Int64
+		}
+		if reactivatedAt.Valid {
+			session.ReactivatedAtMs = &reactivatedAt.Int64
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// exportMessageBatchSize bounds how many messages ForEachExportMessage reads per query.
+const exportMessageBatchSize = 500
+
+// ForEachExportMessage calls fn for every message in the sessions userID can read, ordered by
+// session and then by time, without loading them all into memory. Deleted and
+// burn-after-reading messages are skipped. ReplyTo is not filled in.
+//
+// Messages are read in keyset-paginated batches and each batch's rows are closed before fn
+// runs, so a slow consumer (such as a client downloading the export) does not hold a database
+// connection. Returning an error from fn stops the iteration and is returned as is.
+func (s *Store) ForEachExportMessage(ctx context.Context, userID string, fn func(MessageRow) error) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+
+	var last *MessageRow
+	for {
+		batch, err := s.listExportMessagesAfter(ctx, userID, last, exportMessageBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range batch {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportMessageBatchSize {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
+// listExportMessagesAfter returns up to limit export messages ordered by
+// (session_id, created_at_ms, id), starting after the position of after when it is not nil.
+func (s *Store) listExportMessagesAfter(ctx context.Context, userID string, after *MessageRow, limit int) ([]MessageRow, error) {
+	q := `SELECT m.id, m.session_id, m.sender_id, m.type, m.text, m.meta_json, m.created_at_ms, m.edited_at_ms, m.reply_to_message_id
+		FROM messages m
+		JOIN sessions ON sessions.id = m.session_id
+		WHERE m.deleted_at_ms IS NULL AND m.type <> ? AND ` + exportSessionFilter
+	args := append([]any{MessageTypeBurn}, exportSessionArgs(userID)...)
+	if after != nil {
+		q += ` AND (m.session_id > ? OR (m.session_id = ? AND (m.created_at_ms > ? OR (m.created_at_ms = ? AND m.id > ?))))`
+		args = append(args, after.SessionID, after.SessionID, after.CreatedAtMs, after.CreatedAtMs, after.ID)
+	}
+	q += `
+		ORDER BY m.session_id ASC, m.created_at_ms ASC, m.id ASC
+		LIMIT ?;`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]MessageRow, 0, limit)
+	for rows.Next() {
+		var text, meta, replyID sql.NullString
+		var editedAt sql.NullInt64
+		var mrow MessageRow
+		if err := rows.Scan(
+			&mrow.ID, &mrow.SessionID, &mrow.SenderID, &mrow.Type, &text, &meta, &mrow.CreatedAtMs, &editedAt, &replyID,
+		); err != nil {
+			return nil, err
+		}
+		if text.Valid {
+			mrow.Text = &text.String
+		}
+		if meta.Valid && meta.String != "" {
+			mrow.MetaJSON = []byte(meta.String)
+		}
+		if editedAt.Valid {
+			mrow.EditedAtMs = &editedAt.Int64
+		}
+		if replyID.Valid {
+			mrow.ReplyToMessageID = &replyID.String
+		}
+		out = append(out, mrow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
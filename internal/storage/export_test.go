@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestForEachExportMessage_PagesWithoutHoldingConnection(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+
+	var want []string
+	for _, peer := range []string{bob.ID, carol.ID} {
+		session, _, err := store.CreateSession(ctx, alice.ID, peer, now)
+		if err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		// Several messages share a timestamp so the id tie-breaker is exercised.
+		for i := 0; i < 3; i++ {
+			text := "hi"
+			msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now+int64(i/2))
+			if err != nil {
+				t.Fatalf("CreateMessage() error = %v", err)
+			}
+			want = append(want, msg.ID)
+		}
+	}
+
+	// Walk the keyset pages by hand with a batch smaller than the result.
+	seen := make(map[string]bool)
+	var paged []MessageRow
+	var last *MessageRow
+	for {
+		batch, err := store.listExportMessagesAfter(ctx, alice.ID, last, 2)
+		if err != nil {
+			t.Fatalf("listExportMessagesAfter() error = %v", err)
+		}
+		for _, m := range batch {
+			if seen[m.ID] {
+				t.Fatalf("message %s returned twice", m.ID)
+			}
+			seen[m.ID] = true
+		}
+		paged = append(paged, batch...)
+		if len(batch) < 2 {
+			break
+		}
+		last = &batch[len(batch)-1]
+	}
+	if len(paged) != len(want) {
+		t.Fatalf("paged %d messages, want %d", len(paged), len(want))
+	}
+
+	// fn may query the store: the batch's rows are closed before it runs.
+	var streamed []MessageRow
+	err = store.ForEachExportMessage(ctx, alice.ID, func(m MessageRow) error {
+		if _, err := store.GetUserByID(ctx, m.SenderID); err != nil {
+			return err
+		}
+		streamed = append(streamed, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachExportMessage() error = %v", err)
+	}
+	if len(streamed) != len(paged) {
+		t.Fatalf("streamed %d messages, want %d", len(streamed), len(paged))
+	}
+	for i := range streamed {
+		if streamed[i].ID != paged[i].ID {
+			t.Fatalf("streamed[%d] = %s, want %s", i, streamed[i].ID, paged[i].ID)
+		}
+	}
+}
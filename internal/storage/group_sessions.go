@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxGroupSessionMembers caps an ad-hoc group chat, creator included.
+	MaxGroupSessionMembers = 50
+	// MaxGroupSessionTitleLen bounds a group chat title in runes.
+	MaxGroupSessionTitleLen = 50
+)
+
+// CreateGroupSession starts an ad-hoc group chat owned by creatorID with memberIDs as
+// members. Duplicate ids and the creator are dropped from memberIDs; at least two other
+// users are required, since a pair belongs in a direct session.
+//
+// Members are added without asking them, so each must be a contact of the creator (an
+// active direct session) and neither side may have blocked the other. ErrBlocked and
+// ErrAccessDenied report the two cases.
+func (s *Store) CreateGroupSession(ctx context.Context, creatorID string, memberIDs []string, title string, nowMs int64) (SessionRow, GroupSessionRow, error) {
+	if s == nil || s.db == nil {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("db not initialized")
+	}
+	creatorID = strings.TrimSpace(creatorID)
+	title = strings.TrimSpace(title)
+	if creatorID == "" || title == "" {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("missing required fields")
+	}
+	if utf8.RuneCountInString(title) > MaxGroupSessionTitleLen {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("title too long")
+	}
+
+	seen := map[string]bool{creatorID: true}
+	members := make([]string, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		members = append(members, id)
+	}
+	if len(members) < 2 {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("%w: group needs at least two other members", ErrInvalidState)
+	}
+	if len(members)+1 > MaxGroupSessionMembers {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("%w: group exceeds %d members", ErrInvalidState, MaxGroupSessionMembers)
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	args := make([]any, 0, len(members))
+	for _, id := range members {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
+	countQ := fmt.Sprintf(`SELECT COUNT(*) FROM users WHERE id IN (%s);`, placeholders)
+	var found int
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, countQ), args...).Scan(&found); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	if found != len(members) {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("%w: user", ErrNotFound)
+	}
+
+	blockQ := fmt.Sprintf(`SELECT COUNT(*) FROM blocks
+		WHERE (blocker_id = ? AND blocked_id IN (%s)) OR (blocked_id = ? AND blocker_id IN (%s));`, placeholders, placeholders)
+	blockArgs := append(append(append([]any{creatorID}, args...), creatorID), args...)
+	var blocked int
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, blockQ), blockArgs...).Scan(&blocked); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	if blocked > 0 {
+		return SessionRow{}, GroupSessionRow{}, ErrBlocked
+	}
+
+	contactQ := fmt.Sprintf(`SELECT COUNT(DISTINCT CASE WHEN user1_id = ? THEN user2_id ELSE user1_id END)
+		FROM sessions
+		WHERE kind = ? AND status = ?
+			AND ((user1_id = ? AND user2_id IN (%s)) OR (user2_id = ? AND user1_id IN (%s)));`, placeholders, placeholders)
+	contactArgs := append([]any{creatorID, SessionKindDirect, SessionStatusActive, creatorID}, args...)
+	contactArgs = append(append(contactArgs, creatorID), args...)
+	var contacts int
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, contactQ), contactArgs...).Scan(&contacts); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	if contacts != len(members) {
+		return SessionRow{}, GroupSessionRow{}, fmt.Errorf("%w: members must be contacts", ErrAccessDenied)
+	}
+
+	// Like activity chats, user1/user2 only record the creator; membership lives in
+	// session_participants.
+	session := SessionRow{
+		ID:               uuid.NewString(),
+		ParticipantsHash: uuid.NewString(),
+		User1ID:          creatorID,
+		User2ID:          creatorID,
+		Source:           SessionSourceGroup,
+		Kind:             SessionKindGroup,
+		Status:           SessionStatusActive,
+		CreatedAtMs:      nowMs,
+		UpdatedAtMs:      nowMs,
+	}
+	insertSessionQ := `INSERT INTO sessions (
+			id, participants_hash, user1_id, user2_id, source, kind, status, created_at_ms, updated_at_ms
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertSessionQ),
+		session.ID, session.ParticipantsHash, session.User1ID, session.User2ID,
+		session.Source, session.Kind, session.Status, session.CreatedAtMs, session.UpdatedAtMs,
+	); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+
+	if _, err := upsertSessionParticipantInTx(txCtx, tx, s.driver, session.ID, creatorID, SessionParticipantRoleCreator, SessionParticipantStatusActive, nowMs); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	for _, id := range members {
+		if _, err := upsertSessionParticipantInTx(txCtx, tx, s.driver, session.ID, id, SessionParticipantRoleMember, SessionParticipantStatusActive, nowMs); err != nil {
+			return SessionRow{}, GroupSessionRow{}, err
+		}
+	}
+
+	group := GroupSessionRow{
+		SessionID:   session.ID,
+		CreatorID:   creatorID,
+		Title:       title,
+		MemberCount: len(members) + 1,
+		CreatedAtMs: nowMs,
+		UpdatedAtMs: nowMs,
+	}
+	insertGroupQ := `INSERT INTO group_sessions (session_id, creator_id, title, created_at_ms, updated_at_ms)
+		VALUES (?, ?, ?, ?, ?);`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertGroupQ),
+		group.SessionID, group.CreatorID, group.Title, group.CreatedAtMs, group.UpdatedAtMs,
+	); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SessionRow{}, GroupSessionRow{}, err
+	}
+	return session, group, nil
+}
+
+// GetGroupSessions returns the group details of sessionIDs keyed by session id. Sessions
+// that are not ad-hoc group chats are absent from the result.
+func (s *Store) GetGroupSessions(ctx context.Context, sessionIDs []string) (map[string]GroupSessionRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	args := make([]any, 0, len(sessionIDs)+1)
+	args = append(args, SessionParticipantStatusActive)
+	for _, id := range sessionIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		args = append(args, id)
+	}
+	if len(args) == 1 {
+		return map[string]GroupSessionRow{}, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)-1), ",")
+	q := fmt.Sprintf(`SELECT g.session_id, g.creator_id, g.title, g.created_at_ms, g.updated_at_ms,
+			(SELECT COUNT(*) FROM session_participants sp WHERE sp.session_id = g.session_id AND sp.status = ?)
+		FROM group_sessions g
+		WHERE g.session_id IN (%s);`, placeholders)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]GroupSessionRow, len(args)-1)
+	for rows.Next() {
+		var row GroupSessionRow
+		if err := rows.Scan(&row.SessionID, &row.CreatorID, &row.Title, &row.CreatedAtMs, &row.UpdatedAtMs, &row.MemberCount); err != nil {
+			return nil, err
+		}
+		out[row.SessionID] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCreateGroupSession_ListedForMembersOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	users := map[string]UserRow{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		users[name] = u
+	}
+	alice, bob, carol, dave := users["alice"], users["bob"], users["carol"], users["dave"]
+
+	if _, _, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, alice.ID, bob.ID}, "Trip", now); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("CreateGroupSession(one other member) error = %v, want ErrInvalidState", err)
+	}
+	if _, _, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, "ghost"}, "Trip", now); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CreateGroupSession(unknown member) error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, carol.ID}, "Trip", now); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("CreateGroupSession(non-contacts) error = %v, want ErrAccessDenied", err)
+	}
+	var contactSessions []SessionRow
+	for _, peer := range []UserRow{bob, carol} {
+		s, _, err := store.CreateSession(ctx, alice.ID, peer.ID, now)
+		if err != nil {
+			t.Fatalf("CreateSession(alice, %s) error = %v", peer.Username, err)
+		}
+		contactSessions = append(contactSessions, s)
+	}
+	if _, err := store.BlockUser(ctx, carol.ID, alice.ID, now); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	if _, _, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, carol.ID}, "Trip", now); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CreateGroupSession(blocked member) error = %v, want ErrBlocked", err)
+	}
+	if _, err := store.UnblockUser(ctx, carol.ID, alice.ID); err != nil {
+		t.Fatalf("UnblockUser() error = %v", err)
+	}
+
+	session, group, err := store.CreateGroupSession(ctx, alice.ID, []string{bob.ID, carol.ID}, " Trip ", now+1)
+	if err != nil {
+		t.Fatalf("CreateGroupSession() error = %v", err)
+	}
+	if session.Kind != SessionKindGroup || session.Source != SessionSourceGroup || group.Title != "Trip" || group.MemberCount != 3 {
+		t.Fatalf("session = %+v, group = %+v", session, group)
+	}
+	if _, _, err := store.CreateActivity(ctx, bob.ID, "Picnic", nil, nil, nil, now+2); err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+	direct, _, err := store.CreateSession(ctx, bob.ID, dave.ID, now+3)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if got := store.GetPeerUserID(session, bob.ID); got != "" {
+		t.Fatalf("GetPeerUserID(group) = %q, want empty", got)
+	}
+
+	sessions, err := store.ListSessionsForUser(ctx, bob.ID, SessionStatusActive)
+	if err != nil {
+		t.Fatalf("ListSessionsForUser(bob) error = %v", err)
+	}
+	if len(sessions) != 3 || sessions[0].ID != direct.ID || sessions[1].ID != session.ID || sessions[2].ID != contactSessions[0].ID {
+		t.Fatalf("bob sessions = %+v, want both direct sessions and the group (activity chat excluded)", sessions)
+	}
+	sessions, err = store.ListSessionsForUser(ctx, dave.ID, SessionStatusActive)
+	if err != nil {
+		t.Fatalf("ListSessionsForUser(dave) error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != direct.ID {
+		t.Fatalf("dave sessions = %+v, want only the direct session", sessions)
+	}
+
+	groups, err := store.GetGroupSessions(ctx, []string{session.ID, direct.ID})
+	if err != nil {
+		t.Fatalf("GetGroupSessions() error = %v", err)
+	}
+	if len(groups) != 1 || groups[session.ID].Title != "Trip" || groups[session.ID].MemberCount != 3 {
+		t.Fatalf("groups = %+v, want only the trip group with 3 members", groups)
+	}
+}
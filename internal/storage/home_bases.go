@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 func (s *Store) GetHomeBase(ctx context.Context, userID string) (HomeBaseRow, error) {
@@ -15,20 +16,56 @@ func (s *Store) GetHomeBase(ctx context.Context, userID string) (HomeBaseRow, er
 		return HomeBaseRow{}, fmt.Errorf("missing userID")
 	}
 
-	q := `SELECT user_id, lat_e7, lng_e7, last_updated_ymd, daily_update_count, visibility_radius_m, created_at_ms, updated_at_ms
+	q := `SELECT user_id, lat_e7, lng_e7, last_updated_ymd, daily_update_count, visibility_radius_m, area_label, created_at_ms, updated_at_ms
 		FROM home_bases WHERE user_id = ?;`
-	var hb HomeBaseRow
+	var (
+		hb        HomeBaseRow
+		areaLabel sql.NullString
+	)
 	if err := s.db.QueryRowContext(ctx, s.rebind(q), userID).Scan(
-		&hb.UserID, &hb.LatE7, &hb.LngE7, &hb.LastUpdatedYMD, &hb.DailyUpdateCount, &hb.VisibilityRadiusM, &hb.CreatedAtMs, &hb.UpdatedAtMs,
+		&hb.UserID, &hb.LatE7, &hb.LngE7, &hb.LastUpdatedYMD, &hb.DailyUpdateCount, &hb.VisibilityRadiusM, &areaLabel, &hb.CreatedAtMs, &hb.UpdatedAtMs,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return HomeBaseRow{}, fmt.Errorf("%w: home base", ErrNotFound)
 		}
 		return HomeBaseRow{}, err
 	}
+	if areaLabel.Valid {
+		hb.AreaLabel = &areaLabel.String
+	}
 	return hb, nil
 }
 
+// SetHomeBaseAreaLabel stores a client-supplied, human-readable name for the user's
+// home base area (the server does not geocode). A nil or blank label clears it.
+// Changing the label does not count against the daily location update limit.
+func (s *Store) SetHomeBaseAreaLabel(ctx context.Context, userID string, label *string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if userID == "" {
+		return fmt.Errorf("missing userID")
+	}
+
+	var value any
+	if label != nil {
+		if trimmed := strings.TrimSpace(*label); trimmed != "" {
+			value = trimmed
+		}
+	}
+
+	q := `UPDATE home_bases SET area_label = ? WHERE user_id = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), value, userID)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("%w: home base", ErrNotFound)
+	}
+	return nil
+}
+
 func (s *Store) UpsertHomeBase(ctx context.Context, userID string, latE7, lngE7 int64, visibilityRadiusM *int, nowMs int64) (HomeBaseRow, error) {
 	if s == nil || s.db == nil {
 		return HomeBaseRow{}, fmt.Errorf("db not initialized")
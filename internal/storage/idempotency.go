@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxIdempotencyKeyLen bounds client-supplied Idempotency-Key values.
+const MaxIdempotencyKeyLen = 128
+
+// ReserveIdempotencyKey claims key for userID. It returns (row, true) when the caller should
+// handle the request and later call CompleteIdempotencyKey or ReleaseIdempotencyKey, and
+// (existing, false) when the key is already taken. Keys created before expiredBeforeMs are
+// treated as free and replaced.
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, userID, key, requestPath string, expiredBeforeMs, nowMs int64) (IdempotencyKeyRow, bool, error) {
+	if s == nil || s.db == nil {
+		return IdempotencyKeyRow{}, false, fmt.Errorf("db not initialized")
+	}
+	userID = strings.TrimSpace(userID)
+	key = strings.TrimSpace(key)
+	if userID == "" || key == "" {
+		return IdempotencyKeyRow{}, false, fmt.Errorf("missing required fields")
+	}
+	if len(key) > MaxIdempotencyKeyLen {
+		return IdempotencyKeyRow{}, false, fmt.Errorf("idempotency key too long")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return IdempotencyKeyRow{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deleteQ := `DELETE FROM idempotency_keys WHERE user_id = ? AND idem_key = ? AND created_at_ms < ?;`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, deleteQ), userID, key, expiredBeforeMs); err != nil {
+		return IdempotencyKeyRow{}, false, err
+	}
+
+	insertQ := `INSERT INTO idempotency_keys (user_id, idem_key, request_path, status_code, response_body, created_at_ms)
+		VALUES (?, ?, ?, 0, '', ?)
+		ON CONFLICT(user_id, idem_key) DO NOTHING;`
+	result, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertQ), userID, key, requestPath, nowMs)
+	if err != nil {
+		return IdempotencyKeyRow{}, false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return IdempotencyKeyRow{}, false, err
+	}
+
+	row := IdempotencyKeyRow{UserID: userID, Key: key, RequestPath: requestPath, CreatedAtMs: nowMs}
+	if inserted == 0 {
+		var body string
+		selectQ := `SELECT request_path, status_code, response_body, created_at_ms
+			FROM idempotency_keys
+			WHERE user_id = ? AND idem_key = ?;`
+		if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, selectQ), userID, key).Scan(
+			&row.RequestPath,
+			&row.StatusCode,
+			&body,
+			&row.CreatedAtMs,
+		); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return IdempotencyKeyRow{}, false, fmt.Errorf("%w: idempotency key", ErrNotFound)
+			}
+			return IdempotencyKeyRow{}, false, err
+		}
+		row.ResponseBody = []byte(body)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return IdempotencyKeyRow{}, false, err
+	}
+	return row, inserted > 0, nil
+}
+
+// CompleteIdempotencyKey stores the response for a key reserved by ReserveIdempotencyKey so
+// replays can return it.
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, body []byte) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if statusCode <= 0 {
+		return fmt.Errorf("invalid status code")
+	}
+
+	q := `UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE user_id = ? AND idem_key = ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), statusCode, string(body), userID, key)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: idempotency key", ErrNotFound)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a reserved key so the client can retry a request that failed.
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+
+	q := `DELETE FROM idempotency_keys WHERE user_id = ? AND idem_key = ?;`
+	_, err := s.db.ExecContext(ctx, s.rebind(q), userID, key)
+	return err
+}
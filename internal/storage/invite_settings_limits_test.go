@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestUpdateInviteSettings_DailyLimit(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	tomorrow := now + 24*60*60*1000
+
+	u, err := store.CreateUser(ctx, "inviter", "hash", "Inviter", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	endAt := now + 7*24*60*60*1000
+	activity, _, err := store.CreateActivity(ctx, u.ID, "Activity", nil, nil, &endAt, now)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	for i := 0; i < maxInviteSettingsUpdatesPerDay; i++ {
+		exp := now + int64(i+1)*60*1000
+		if _, err := store.UpdateSessionInviteSettings(ctx, u.ID, &exp, nil, now); err != nil {
+			t.Fatalf("UpdateSessionInviteSettings(%d) error = %v", i, err)
+		}
+		if _, err := store.UpdateActivityInviteSettings(ctx, activity.ID, &exp, nil, nil, now); err != nil {
+			t.Fatalf("UpdateActivityInviteSettings(%d) error = %v", i, err)
+		}
+	}
+	if _, err := store.UpdateSessionInviteSettings(ctx, u.ID, nil, nil, now); err != ErrRateLimited {
+		t.Fatalf("UpdateSessionInviteSettings(over limit) error = %v, want ErrRateLimited", err)
+	}
+	if _, err := store.UpdateActivityInviteSettings(ctx, activity.ID, nil, nil, nil, now); err != ErrRateLimited {
+		t.Fatalf("UpdateActivityInviteSettings(over limit) error = %v, want ErrRateLimited", err)
+	}
+
+	if _, err := store.UpdateSessionInviteSettings(ctx, u.ID, nil, nil, tomorrow); err != nil {
+		t.Fatalf("UpdateSessionInviteSettings(next day) error = %v", err)
+	}
+	if _, err := store.UpdateActivityInviteSettings(ctx, activity.ID, nil, nil, nil, tomorrow); err != nil {
+		t.Fatalf("UpdateActivityInviteSettings(next day) error = %v", err)
+	}
+}
+
+func TestUpdateActivityInviteSettings_PerCreatorLimit(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	u, err := store.CreateUser(ctx, "creator", "hash", "Creator", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	endAt := now + 7*24*60*60*1000
+
+	updates := 0
+	for updates < maxActivityInviteSettingsUpdatesPerCreator {
+		activity, _, err := store.CreateActivity(ctx, u.ID, fmt.Sprintf("Activity %d", updates), nil, nil, &endAt, now)
+		if err != nil {
+			t.Fatalf("CreateActivity() error = %v", err)
+		}
+		for i := 0; i < maxInviteSettingsUpdatesPerDay && updates < maxActivityInviteSettingsUpdatesPerCreator; i++ {
+			if _, err := store.UpdateActivityInviteSettings(ctx, activity.ID, nil, nil, nil, now); err != nil {
+				t.Fatalf("UpdateActivityInviteSettings(%d) error = %v", updates, err)
+			}
+			updates++
+		}
+	}
+
+	fresh, _, err := store.CreateActivity(ctx, u.ID, "Fresh", nil, nil, &endAt, now)
+	if err != nil {
+		t.Fatalf("CreateActivity(fresh) error = %v", err)
+	}
+	if _, err := store.UpdateActivityInviteSettings(ctx, fresh.ID, nil, nil, nil, now); err != ErrRateLimited {
+		t.Fatalf("UpdateActivityInviteSettings(fresh) error = %v, want ErrRateLimited", err)
+	}
+}
@@ -12,7 +12,15 @@ import (
 	"github.com/google/uuid"
 )
 
-func (s *Store) CreateLocalFeedPost(ctx context.Context, userID string, text *string, imageURLs []string, expiresAtMs int64, isPinned bool, nowMs int64) (LocalFeedPostRow, []LocalFeedPostImageRow, error) {
+// MaxLocalFeedPostImages caps how many images one local feed post may carry.
+const MaxLocalFeedPostImages = 9
+
+// MaxLocalFeedPostRadiusM is the largest visibility radius a post may request.
+const MaxLocalFeedPostRadiusM = 50000
+
+// CreateLocalFeedPost stores a post visible within radiusM of the author's home base.
+// A nil radiusM falls back to the home base's visibility radius.
+func (s *Store) CreateLocalFeedPost(ctx context.Context, userID string, text *string, imageURLs []string, radiusM *int, expiresAtMs int64, isPinned bool, nowMs int64) (LocalFeedPostRow, []LocalFeedPostImageRow, error) {
 	if s == nil || s.db == nil {
 		return LocalFeedPostRow{}, nil, fmt.Errorf("db not initialized")
 	}
@@ -22,17 +30,22 @@ func (s *Store) CreateLocalFeedPost(ctx context.Context, userID string, text *st
 	if expiresAtMs <= nowMs {
 		return LocalFeedPostRow{}, nil, fmt.Errorf("invalid expiresAtMs")
 	}
+	if len(imageURLs) > MaxLocalFeedPostImages {
+		return LocalFeedPostRow{}, nil, fmt.Errorf("too many images")
+	}
 
 	const defaultVisibilityRadiusM = 1100
-	radiusM := defaultVisibilityRadiusM
-	if hb, err := s.GetHomeBase(ctx, userID); err == nil {
+	postRadiusM := defaultVisibilityRadiusM
+	if radiusM != nil {
+		postRadiusM = *radiusM
+	} else if hb, err := s.GetHomeBase(ctx, userID); err == nil {
 		if hb.VisibilityRadiusM > 0 {
-			radiusM = hb.VisibilityRadiusM
+			postRadiusM = hb.VisibilityRadiusM
 		}
 	} else if !errors.Is(err, ErrNotFound) {
 		return LocalFeedPostRow{}, nil, err
 	}
-	if radiusM <= 0 {
+	if postRadiusM <= 0 || postRadiusM > MaxLocalFeedPostRadiusM {
 		return LocalFeedPostRow{}, nil, fmt.Errorf("invalid visibility radius")
 	}
 
@@ -49,7 +62,7 @@ func (s *Store) CreateLocalFeedPost(ctx context.Context, userID string, text *st
 		ID:          postID,
 		UserID:      userID,
 		Text:        normalizedText,
-		RadiusM:     radiusM,
+		RadiusM:     postRadiusM,
 		ExpiresAtMs: expiresAtMs,
 		IsPinned:    isPinned,
 		CreatedAtMs: nowMs,
@@ -125,18 +138,127 @@ func (s *Store) DeleteLocalFeedPost(ctx context.Context, userID, postID string)
 	return nil
 }
 
+// PurgeExpiredLocalFeedPosts deletes up to limit posts whose expiry is at or before nowMs
+// and returns how many were removed. Their images go with them via ON DELETE CASCADE.
+func (s *Store) PurgeExpiredLocalFeedPosts(ctx context.Context, nowMs int64, limit int) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+
+	q := `DELETE FROM local_feed_posts
+		WHERE id IN (
+			SELECT id FROM local_feed_posts
+			WHERE expires_at_ms <= ?
+			ORDER BY expires_at_ms ASC
+			LIMIT ?
+		);`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), nowMs, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateLocalFeedPost edits the text and/or pinned flag of one of userID's posts.
+// Nil fields are left unchanged; expired posts can no longer be edited.
+func (s *Store) UpdateLocalFeedPost(ctx context.Context, userID, postID string, text *string, isPinned *bool, nowMs int64) (LocalFeedPostRow, []LocalFeedPostImageRow, error) {
+	if s == nil || s.db == nil {
+		return LocalFeedPostRow{}, nil, fmt.Errorf("db not initialized")
+	}
+	if userID == "" || postID == "" {
+		return LocalFeedPostRow{}, nil, fmt.Errorf("missing ids")
+	}
+
+	selectQ := `SELECT id, user_id, text, radius_m, expires_at_ms, is_pinned, created_at_ms, updated_at_ms
+		FROM local_feed_posts WHERE id = ? AND user_id = ?;`
+	var (
+		post      LocalFeedPostRow
+		curText   sql.NullString
+		curPinned int
+	)
+	if err := s.db.QueryRowContext(ctx, s.rebind(selectQ), postID, userID).Scan(
+		&post.ID, &post.UserID, &curText, &post.RadiusM, &post.ExpiresAtMs, &curPinned, &post.CreatedAtMs, &post.UpdatedAtMs,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return LocalFeedPostRow{}, nil, fmt.Errorf("%w: local feed post", ErrNotFound)
+		}
+		return LocalFeedPostRow{}, nil, err
+	}
+	if post.ExpiresAtMs <= nowMs {
+		return LocalFeedPostRow{}, nil, ErrInvalidState
+	}
+	if curText.Valid {
+		post.Text = &curText.String
+	}
+	post.IsPinned = curPinned != 0
+
+	if text != nil {
+		t := strings.TrimSpace(*text)
+		post.Text = &t
+	}
+	if isPinned != nil {
+		post.IsPinned = *isPinned
+	}
+	post.UpdatedAtMs = nowMs
+
+	pinnedInt := 0
+	if post.IsPinned {
+		pinnedInt = 1
+	}
+	updateQ := `UPDATE local_feed_posts SET text = ?, is_pinned = ?, updated_at_ms = ? WHERE id = ? AND user_id = ?;`
+	if _, err := s.db.ExecContext(ctx, s.rebind(updateQ), post.Text, pinnedInt, post.UpdatedAtMs, postID, userID); err != nil {
+		return LocalFeedPostRow{}, nil, err
+	}
+
+	imgQ := `SELECT id, post_id, url, sort_order, created_at_ms
+		FROM local_feed_post_images
+		WHERE post_id = ?
+		ORDER BY sort_order ASC;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(imgQ), postID)
+	if err != nil {
+		return LocalFeedPostRow{}, nil, err
+	}
+	defer rows.Close()
+
+	var images []LocalFeedPostImageRow
+	for rows.Next() {
+		var img LocalFeedPostImageRow
+		if err := rows.Scan(&img.ID, &img.PostID, &img.URL, &img.SortOrder, &img.CreatedAtMs); err != nil {
+			return LocalFeedPostRow{}, nil, err
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return LocalFeedPostRow{}, nil, err
+	}
+	return post, images, nil
+}
+
 type LocalFeedPostWithImages struct {
 	Post   LocalFeedPostRow
 	Images []LocalFeedPostImageRow
 }
 
-func (s *Store) ListLocalFeedPostsForSource(ctx context.Context, sourceUserID string, atLatE7, atLngE7 *int64, nowMs int64, limit int) ([]LocalFeedPostWithImages, error) {
+// LocalFeedListOptions controls paging for ListLocalFeedPostsForSource.
+// Cursor is the ID of the last post from the previous page. IncludeExpired drops the
+// expires_at_ms filter; callers must only set it when the viewer is the source user.
+type LocalFeedListOptions struct {
+	Limit          int
+	Cursor         string
+	IncludeExpired bool
+}
+
+func (s *Store) ListLocalFeedPostsForSource(ctx context.Context, sourceUserID string, atLatE7, atLngE7 *int64, nowMs int64, opts LocalFeedListOptions) ([]LocalFeedPostWithImages, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("db not initialized")
+		return nil, "", fmt.Errorf("db not initialized")
 	}
 	if sourceUserID == "" {
-		return nil, fmt.Errorf("missing sourceUserID")
+		return nil, "", fmt.Errorf("missing sourceUserID")
 	}
+	limit := opts.Limit
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
@@ -145,24 +267,54 @@ func (s *Store) ListLocalFeedPostsForSource(ctx context.Context, sourceUserID st
 	if atLatE7 != nil && atLngE7 != nil {
 		row, err := s.GetHomeBase(ctx, sourceUserID)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		hb = &row
 	}
 
+	where := []string{"user_id = ?"}
+	args := []any{sourceUserID}
+	if !opts.IncludeExpired {
+		where = append(where, "expires_at_ms > ?")
+		args = append(args, nowMs)
+	}
+
+	if cursor := strings.TrimSpace(opts.Cursor); cursor != "" {
+		var (
+			cursorPinned    int
+			cursorCreatedAt int64
+		)
+		cursorQ := `SELECT is_pinned, created_at_ms FROM local_feed_posts WHERE id = ? AND user_id = ?;`
+		if err := s.db.QueryRowContext(ctx, s.rebind(cursorQ), cursor, sourceUserID).Scan(&cursorPinned, &cursorCreatedAt); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, "", ErrInvalidCursor
+			}
+			return nil, "", err
+		}
+		// Keyset over the same (is_pinned, created_at_ms, id) tuple used for ordering.
+		where = append(where, `(is_pinned < ? OR (is_pinned = ? AND (created_at_ms < ? OR (created_at_ms = ? AND id < ?))))`)
+		args = append(args, cursorPinned, cursorPinned, cursorCreatedAt, cursorCreatedAt, cursor)
+	}
+
 	q := `SELECT id, user_id, text, radius_m, expires_at_ms, is_pinned, created_at_ms, updated_at_ms
 		FROM local_feed_posts
-		WHERE user_id = ? AND expires_at_ms > ?
-		ORDER BY is_pinned DESC, created_at_ms DESC
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY is_pinned DESC, created_at_ms DESC, id DESC
 		LIMIT ?;`
+	args = append(args, limit+1)
 
-	rows, err := s.db.QueryContext(ctx, s.rebind(q), sourceUserID, nowMs, limit)
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
-	var posts []LocalFeedPostRow
+	var (
+		posts      []LocalFeedPostRow
+		scanned    int
+		lastID     string
+		nextCursor string
+	)
 	for rows.Next() {
 		var (
 			p      LocalFeedPostRow
@@ -170,8 +322,15 @@ func (s *Store) ListLocalFeedPostsForSource(ctx context.Context, sourceUserID st
 			pinned int
 		)
 		if err := rows.Scan(&p.ID, &p.UserID, &text, &p.RadiusM, &p.ExpiresAtMs, &pinned, &p.CreatedAtMs, &p.UpdatedAtMs); err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		scanned++
+		if scanned > limit {
+			// The extra row only signals that another page exists.
+			nextCursor = lastID
+			continue
+		}
+		lastID = p.ID
 		if text.Valid {
 			p.Text = &text.String
 		}
@@ -186,14 +345,105 @@ func (s *Store) ListLocalFeedPostsForSource(ctx context.Context, sourceUserID st
 
 		posts = append(posts, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(posts) == 0 {
+		return nil, nextCursor, nil
+	}
+
+	out, err := s.attachLocalFeedPostImages(ctx, posts)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, nextCursor, nil
+}
+
+// ListNearbyLocalFeedPosts returns unexpired posts by other users whose home base lies
+// within radiusM of the viewer and whose own radius_m also reaches the viewer, newest first.
+func (s *Store) ListNearbyLocalFeedPosts(ctx context.Context, viewerID string, viewerLatE7, viewerLngE7 int64, radiusM int, nowMs int64, limit int) ([]LocalFeedPostWithImages, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if viewerID == "" {
+		return nil, fmt.Errorf("missing viewerID")
+	}
+	if radiusM <= 0 || radiusM > MaxLocalFeedPostRadiusM {
+		radiusM = MaxLocalFeedPostRadiusM
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	// Coarse bounding box on the home base so the index narrows candidates; the exact
+	// distance checks happen below.
+	const metersPerDegreeLat = 111320.0
+	latDeltaE7 := int64(float64(radiusM) / metersPerDegreeLat * 1e7)
+	where := []string{
+		"p.user_id <> ?",
+		"p.expires_at_ms > ?",
+		"hb.lat_e7 >= ?",
+		"hb.lat_e7 <= ?",
+	}
+	args := []any{viewerID, nowMs, viewerLatE7 - latDeltaE7, viewerLatE7 + latDeltaE7}
+	if cosLat := math.Cos((float64(viewerLatE7) / 1e7) * math.Pi / 180.0); cosLat > 0.01 {
+		lngDeltaE7 := int64(float64(latDeltaE7) / cosLat)
+		where = append(where, "hb.lng_e7 >= ?", "hb.lng_e7 <= ?")
+		args = append(args, viewerLngE7-lngDeltaE7, viewerLngE7+lngDeltaE7)
+	}
+
+	q := `SELECT p.id, p.user_id, p.text, p.radius_m, p.expires_at_ms, p.is_pinned, p.created_at_ms, p.updated_at_ms,
+			hb.lat_e7, hb.lng_e7
+		FROM local_feed_posts p
+		JOIN home_bases hb ON hb.user_id = p.user_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY p.created_at_ms DESC, p.id DESC;`
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []LocalFeedPostRow
+	for rows.Next() {
+		var (
+			p            LocalFeedPostRow
+			text         sql.NullString
+			pinned       int
+			hbLat, hbLng int64
+		)
+		if err := rows.Scan(&p.ID, &p.UserID, &text, &p.RadiusM, &p.ExpiresAtMs, &pinned, &p.CreatedAtMs, &p.UpdatedAtMs, &hbLat, &hbLng); err != nil {
+			return nil, err
+		}
+		dist := distanceMetersE7(hbLat, hbLng, viewerLatE7, viewerLngE7)
+		if dist > float64(radiusM) || dist > float64(p.RadiusM) {
+			continue
+		}
+		if text.Valid {
+			p.Text = &text.String
+		}
+		p.IsPinned = pinned != 0
+		posts = append(posts, p)
+		if len(posts) >= limit {
+			break
+		}
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	// Release the connection before loading images (sqlite runs on a single connection).
+	_ = rows.Close()
+
+	return s.attachLocalFeedPostImages(ctx, posts)
+}
+
+// attachLocalFeedPostImages loads the images of posts in one query, preserving post order.
+func (s *Store) attachLocalFeedPostImages(ctx context.Context, posts []LocalFeedPostRow) ([]LocalFeedPostWithImages, error) {
 	if len(posts) == 0 {
 		return nil, nil
 	}
 
-	// Load images for posts.
 	postIDs := make([]any, 0, len(posts))
 	for _, p := range posts {
 		postIDs = append(postIDs, p.ID)
@@ -247,6 +497,7 @@ func (s *Store) ListLocalFeedPins(ctx context.Context, minLatE7, maxLatE7, minLn
 			hb.lng_e7,
 			COALESCE(mp.nickname_override, u.display_name) AS display_name,
 			COALESCE(mp.avatar_url_override, u.avatar_url) AS avatar_url,
+			hb.area_label,
 			hb.updated_at_ms
 		FROM home_bases hb
 		JOIN users u ON u.id = hb.user_id
@@ -268,15 +519,19 @@ func (s *Store) ListLocalFeedPins(ctx context.Context, minLatE7, maxLatE7, minLn
 	var out []LocalFeedPinRow
 	for rows.Next() {
 		var (
-			p      LocalFeedPinRow
-			avatar sql.NullString
+			p         LocalFeedPinRow
+			avatar    sql.NullString
+			areaLabel sql.NullString
 		)
-		if err := rows.Scan(&p.UserID, &p.LatE7, &p.LngE7, &p.DisplayName, &avatar, &p.UpdatedAtMs); err != nil {
+		if err := rows.Scan(&p.UserID, &p.LatE7, &p.LngE7, &p.DisplayName, &avatar, &areaLabel, &p.UpdatedAtMs); err != nil {
 			return nil, err
 		}
 		if avatar.Valid {
 			p.AvatarURL = &avatar.String
 		}
+		if areaLabel.Valid {
+			p.AreaLabel = &areaLabel.String
+		}
 		out = append(out, p)
 	}
 	if err := rows.Err(); err != nil {
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"testing"
@@ -33,13 +34,13 @@ func TestLocalFeed_PostVisibilityByRadius(t *testing.T) {
 
 	text := "hello"
 	expiresAt := now + 24*60*60*1000
-	if _, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, nil, expiresAt, false, now); err != nil {
+	if _, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, nil, nil, expiresAt, false, now); err != nil {
 		t.Fatalf("CreateLocalFeedPost() error = %v", err)
 	}
 
 	nearLat := int64(310000000)
 	nearLng := int64(1210000000)
-	near, err := store.ListLocalFeedPostsForSource(ctx, u.ID, &nearLat, &nearLng, now, 50)
+	near, _, err := store.ListLocalFeedPostsForSource(ctx, u.ID, &nearLat, &nearLng, now, LocalFeedListOptions{})
 	if err != nil {
 		t.Fatalf("ListLocalFeedPostsForSource(near) error = %v", err)
 	}
@@ -49,7 +50,7 @@ func TestLocalFeed_PostVisibilityByRadius(t *testing.T) {
 
 	farLat := int64(0)
 	farLng := int64(0)
-	far, err := store.ListLocalFeedPostsForSource(ctx, u.ID, &farLat, &farLng, now, 50)
+	far, _, err := store.ListLocalFeedPostsForSource(ctx, u.ID, &farLat, &farLng, now, LocalFeedListOptions{})
 	if err != nil {
 		t.Fatalf("ListLocalFeedPostsForSource(far) error = %v", err)
 	}
@@ -58,6 +59,67 @@ func TestLocalFeed_PostVisibilityByRadius(t *testing.T) {
 	}
 }
 
+func TestLocalFeed_ListForSource_PagingAndExpired(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	u, err := store.CreateUser(ctx, "source", "hash", "SourceUser", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	// One short-lived post that expires before "later", then three long-lived posts.
+	text := "post"
+	if _, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, nil, nil, now+1000, false, now); err != nil {
+		t.Fatalf("CreateLocalFeedPost(short) error = %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, nil, nil, now+24*60*60*1000, false, now+int64(i)); err != nil {
+			t.Fatalf("CreateLocalFeedPost(%d) error = %v", i, err)
+		}
+	}
+	later := now + 60*1000
+
+	page1, cursor, err := store.ListLocalFeedPostsForSource(ctx, u.ID, nil, nil, later, LocalFeedListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListLocalFeedPostsForSource(page1) error = %v", err)
+	}
+	if len(page1) != 2 || cursor == "" {
+		t.Fatalf("page1 = %d posts, cursor %q; want 2 posts and a cursor", len(page1), cursor)
+	}
+	if page1[0].Post.CreatedAtMs < page1[1].Post.CreatedAtMs {
+		t.Fatalf("page1 not ordered newest first")
+	}
+
+	page2, cursor2, err := store.ListLocalFeedPostsForSource(ctx, u.ID, nil, nil, later, LocalFeedListOptions{Limit: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("ListLocalFeedPostsForSource(page2) error = %v", err)
+	}
+	if len(page2) != 1 || cursor2 != "" {
+		t.Fatalf("page2 = %d posts, cursor %q; want 1 post and no cursor", len(page2), cursor2)
+	}
+
+	all, _, err := store.ListLocalFeedPostsForSource(ctx, u.ID, nil, nil, later, LocalFeedListOptions{IncludeExpired: true})
+	if err != nil {
+		t.Fatalf("ListLocalFeedPostsForSource(includeExpired) error = %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("includeExpired posts = %d, want 4", len(all))
+	}
+
+	if _, _, err := store.ListLocalFeedPostsForSource(ctx, u.ID, nil, nil, later, LocalFeedListOptions{Cursor: "missing"}); err != ErrInvalidCursor {
+		t.Fatalf("ListLocalFeedPostsForSource(bad cursor) error = %v, want ErrInvalidCursor", err)
+	}
+}
+
 func TestLocalFeed_PinsUseMapProfileOverride(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
@@ -94,3 +156,258 @@ func TestLocalFeed_PinsUseMapProfileOverride(t *testing.T) {
 		t.Fatalf("pins[0].DisplayName = %q, want %q", pins[0].DisplayName, "MapNick")
 	}
 }
+
+func TestUpdateLocalFeedPost_OwnerOnlyAndNotExpired(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	owner, err := store.CreateUser(ctx, "owner", "hash", "Owner", now)
+	if err != nil {
+		t.Fatalf("CreateUser(owner) error = %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other", "hash", "Other", now)
+	if err != nil {
+		t.Fatalf("CreateUser(other) error = %v", err)
+	}
+
+	text := "first draft"
+	expiresAt := now + 60*60*1000
+	post, _, err := store.CreateLocalFeedPost(ctx, owner.ID, &text, []string{"/uploads/a.jpg"}, nil, expiresAt, false, now)
+	if err != nil {
+		t.Fatalf("CreateLocalFeedPost() error = %v", err)
+	}
+
+	edited := "  fixed typo  "
+	if _, _, err := store.UpdateLocalFeedPost(ctx, other.ID, post.ID, &edited, nil, now+1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("UpdateLocalFeedPost(other) error = %v, want ErrNotFound", err)
+	}
+
+	updated, images, err := store.UpdateLocalFeedPost(ctx, owner.ID, post.ID, &edited, nil, now+1)
+	if err != nil {
+		t.Fatalf("UpdateLocalFeedPost(text) error = %v", err)
+	}
+	if updated.Text == nil || *updated.Text != "fixed typo" || updated.IsPinned || updated.UpdatedAtMs != now+1 {
+		t.Fatalf("updated = %+v, want trimmed text, unpinned, updatedAtMs %d", updated, now+1)
+	}
+	if len(images) != 1 || images[0].URL != "/uploads/a.jpg" {
+		t.Fatalf("images = %+v, want the original image", images)
+	}
+
+	pinned := true
+	updated, _, err = store.UpdateLocalFeedPost(ctx, owner.ID, post.ID, nil, &pinned, now+2)
+	if err != nil {
+		t.Fatalf("UpdateLocalFeedPost(pin) error = %v", err)
+	}
+	if !updated.IsPinned || updated.Text == nil || *updated.Text != "fixed typo" {
+		t.Fatalf("updated = %+v, want pinned with text unchanged", updated)
+	}
+
+	if _, _, err := store.UpdateLocalFeedPost(ctx, owner.ID, post.ID, &edited, nil, expiresAt); err != ErrInvalidState {
+		t.Fatalf("UpdateLocalFeedPost(expired) error = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestPurgeExpiredLocalFeedPosts_RemovesPostsAndImages(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	u, err := store.CreateUser(ctx, "poster", "hash", "Poster", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	text := "short lived"
+	expired, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, []string{"/uploads/a.jpg", "/uploads/b.jpg"}, nil, now+1000, false, now)
+	if err != nil {
+		t.Fatalf("CreateLocalFeedPost(expired) error = %v", err)
+	}
+	live, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, []string{"/uploads/c.jpg"}, nil, now+60*60*1000, false, now)
+	if err != nil {
+		t.Fatalf("CreateLocalFeedPost(live) error = %v", err)
+	}
+
+	n, err := store.PurgeExpiredLocalFeedPosts(ctx, now+2000, 100)
+	if err != nil {
+		t.Fatalf("PurgeExpiredLocalFeedPosts() error = %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("purged = %d, want 1", n)
+	}
+
+	var posts, images int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM local_feed_posts WHERE id = ?;`, expired.ID).Scan(&posts); err != nil {
+		t.Fatalf("count posts error = %v", err)
+	}
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM local_feed_post_images WHERE post_id = ?;`, expired.ID).Scan(&images); err != nil {
+		t.Fatalf("count images error = %v", err)
+	}
+	if posts != 0 || images != 0 {
+		t.Fatalf("expired post rows = %d, images = %d, want both 0", posts, images)
+	}
+
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM local_feed_post_images WHERE post_id = ?;`, live.ID).Scan(&images); err != nil {
+		t.Fatalf("count live images error = %v", err)
+	}
+	if images != 1 {
+		t.Fatalf("live post images = %d, want 1", images)
+	}
+
+	n, err = store.PurgeExpiredLocalFeedPosts(ctx, now+2000, 100)
+	if err != nil || n != 0 {
+		t.Fatalf("second purge = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestLocalFeed_PinsIncludeAreaLabel(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	u, err := store.CreateUser(ctx, "labeluser", "hash", "Label", now)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	label := "徐汇区"
+	if err := store.SetHomeBaseAreaLabel(ctx, u.ID, &label); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("SetHomeBaseAreaLabel(no home base) error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := store.UpsertHomeBase(ctx, u.ID, 310000000, 1210000000, nil, now); err != nil {
+		t.Fatalf("UpsertHomeBase() error = %v", err)
+	}
+
+	listPins := func() []LocalFeedPinRow {
+		t.Helper()
+		pins, err := store.ListLocalFeedPins(ctx, 300000000, 320000000, 1200000000, 1220000000, 310000000, 1210000000, 10)
+		if err != nil {
+			t.Fatalf("ListLocalFeedPins() error = %v", err)
+		}
+		if len(pins) != 1 {
+			t.Fatalf("pins = %d, want 1", len(pins))
+		}
+		return pins
+	}
+
+	if pins := listPins(); pins[0].AreaLabel != nil {
+		t.Fatalf("pins[0].AreaLabel = %q, want nil", *pins[0].AreaLabel)
+	}
+
+	padded := "  " + label + "  "
+	if err := store.SetHomeBaseAreaLabel(ctx, u.ID, &padded); err != nil {
+		t.Fatalf("SetHomeBaseAreaLabel() error = %v", err)
+	}
+	if pins := listPins(); pins[0].AreaLabel == nil || *pins[0].AreaLabel != label {
+		t.Fatalf("pins[0].AreaLabel = %v, want %q", pins[0].AreaLabel, label)
+	}
+	hb, err := store.GetHomeBase(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("GetHomeBase() error = %v", err)
+	}
+	if hb.AreaLabel == nil || *hb.AreaLabel != label {
+		t.Fatalf("hb.AreaLabel = %v, want %q", hb.AreaLabel, label)
+	}
+
+	empty := ""
+	if err := store.SetHomeBaseAreaLabel(ctx, u.ID, &empty); err != nil {
+		t.Fatalf("SetHomeBaseAreaLabel(clear) error = %v", err)
+	}
+	if pins := listPins(); pins[0].AreaLabel != nil {
+		t.Fatalf("pins[0].AreaLabel = %q after clear, want nil", *pins[0].AreaLabel)
+	}
+}
+
+func TestListNearbyLocalFeedPosts_FiltersByDistanceAndPostRadius(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	const viewerLat, viewerLng = int64(310000000), int64(1210000000)
+
+	newPoster := func(username string, latE7 int64) UserRow {
+		t.Helper()
+		u, err := store.CreateUser(ctx, username, "hash", username, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", username, err)
+		}
+		if _, err := store.UpsertHomeBase(ctx, u.ID, latE7, viewerLng, nil, now); err != nil {
+			t.Fatalf("UpsertHomeBase(%s) error = %v", username, err)
+		}
+		return u
+	}
+	post := func(u UserRow, radiusM int, expiresAtMs int64) LocalFeedPostRow {
+		t.Helper()
+		text := "hello from " + u.Username
+		p, _, err := store.CreateLocalFeedPost(ctx, u.ID, &text, nil, &radiusM, expiresAtMs, false, now)
+		if err != nil {
+			t.Fatalf("CreateLocalFeedPost(%s) error = %v", u.Username, err)
+		}
+		return p
+	}
+
+	viewer := newPoster("viewer", viewerLat)
+	near := newPoster("near", viewerLat+50000) // ~550m away
+	far := newPoster("far", viewerLat+270000)  // ~3km away
+
+	expiresAt := now + 60*60*1000
+	post(viewer, 1100, expiresAt)
+	nearPost := post(near, 1100, expiresAt)
+	post(near, 1100, now+1000)
+	post(far, 1100, expiresAt)
+	farWidePost := post(far, 5000, expiresAt)
+
+	ids := func(posts []LocalFeedPostWithImages) map[string]bool {
+		out := make(map[string]bool, len(posts))
+		for _, p := range posts {
+			out[p.Post.ID] = true
+		}
+		return out
+	}
+
+	posts, err := store.ListNearbyLocalFeedPosts(ctx, viewer.ID, viewerLat, viewerLng, 10000, now+2000, 0)
+	if err != nil {
+		t.Fatalf("ListNearbyLocalFeedPosts() error = %v", err)
+	}
+	got := ids(posts)
+	if len(got) != 2 || !got[nearPost.ID] || !got[farWidePost.ID] {
+		t.Fatalf("nearby posts = %v, want only %s and %s", got, nearPost.ID, farWidePost.ID)
+	}
+
+	posts, err = store.ListNearbyLocalFeedPosts(ctx, viewer.ID, viewerLat, viewerLng, 2000, now+2000, 0)
+	if err != nil {
+		t.Fatalf("ListNearbyLocalFeedPosts(2km) error = %v", err)
+	}
+	got = ids(posts)
+	if len(got) != 1 || !got[nearPost.ID] {
+		t.Fatalf("nearby posts within 2km = %v, want only %s", got, nearPost.ID)
+	}
+}
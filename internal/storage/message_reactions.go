@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxReactionsPerUserPerMessage caps how many distinct emojis one user can leave on a
+	// single message.
+	MaxReactionsPerUserPerMessage = 3
+	// MaxReactionEmojiLen bounds a reaction in runes. It leaves room for ZWJ sequences and
+	// skin-tone modifiers without letting clients store arbitrary text.
+	MaxReactionEmojiLen = 16
+)
+
+// NormalizeReactionEmoji trims emoji and reports whether it is acceptable as a reaction.
+func NormalizeReactionEmoji(emoji string) (string, bool) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > MaxReactionEmojiLen {
+		return "", false
+	}
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", false
+		}
+	}
+	return emoji, true
+}
+
+// checkReactableMessage verifies messageID belongs to sessionID, has not been unsent, and
+// that userID is a participant of an active session.
+func (s *Store) checkReactableMessage(ctx context.Context, sessionID, messageID, userID string) error {
+	q := `SELECT deleted_at_ms FROM messages WHERE id = ? AND session_id = ?;`
+	var deletedAt sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), messageID, sessionID).Scan(&deletedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: message", ErrNotFound)
+		}
+		return err
+	}
+
+	isParticipant, err := s.IsSessionParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !isParticipant {
+		return ErrAccessDenied
+	}
+	session, err := s.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status == SessionStatusArchived {
+		return ErrSessionArchived
+	}
+	if deletedAt.Valid {
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// AddReaction records userID reacting to messageID with emoji and reports whether a new
+// reaction was stored. Repeating an existing reaction is a no-op. A user can hold at most
+// MaxReactionsPerUserPerMessage distinct emojis per message.
+func (s *Store) AddReaction(ctx context.Context, sessionID, messageID, userID, emoji string, nowMs int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	emoji, ok := NormalizeReactionEmoji(emoji)
+	if sessionID == "" || messageID == "" || userID == "" || !ok {
+		return false, fmt.Errorf("missing fields")
+	}
+	if err := s.checkReactableMessage(ctx, sessionID, messageID, userID); err != nil {
+		return false, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		count  int
+		exists int
+	)
+	countQ := `SELECT COUNT(*), COALESCE(SUM(CASE WHEN emoji = ? THEN 1 ELSE 0 END), 0)
+		FROM message_reactions WHERE message_id = ? AND user_id = ?;`
+	if err := tx.QueryRowContext(ctx, rebindQuery(s.driver, countQ), emoji, messageID, userID).Scan(&count, &exists); err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	if count >= MaxReactionsPerUserPerMessage {
+		return false, ErrReactionLimit
+	}
+
+	insertQ := `INSERT INTO message_reactions (message_id, user_id, emoji, created_at_ms)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(message_id, user_id, emoji) DO NOTHING;`
+	res, err := tx.ExecContext(ctx, rebindQuery(s.driver, insertQ), messageID, userID, emoji, nowMs)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// RemoveReaction withdraws userID's emoji reaction from messageID and reports whether a
+// reaction was removed.
+func (s *Store) RemoveReaction(ctx context.Context, sessionID, messageID, userID, emoji string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	emoji, ok := NormalizeReactionEmoji(emoji)
+	if sessionID == "" || messageID == "" || userID == "" || !ok {
+		return false, fmt.Errorf("missing fields")
+	}
+	if err := s.checkReactableMessage(ctx, sessionID, messageID, userID); err != nil {
+		return false, err
+	}
+
+	q := `DELETE FROM message_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), messageID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// ListMessageReactions returns the reactions on messageIDs grouped by message, oldest
+// first.
+func (s *Store) ListMessageReactions(ctx context.Context, messageIDs []string) (map[string][]MessageReactionRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	args := make([]any, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		args = append(args, id)
+	}
+	if len(args) == 0 {
+		return map[string][]MessageReactionRow{}, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
+	q := fmt.Sprintf(`SELECT message_id, user_id, emoji, created_at_ms
+		FROM message_reactions
+		WHERE message_id IN (%s)
+		ORDER BY created_at_ms ASC, emoji ASC;`, placeholders)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string][]MessageReactionRow, len(args))
+	for rows.Next() {
+		var row MessageReactionRow
+		if err := rows.Scan(&row.MessageID, &row.UserID, &row.Emoji, &row.CreatedAtMs); err != nil {
+			return nil, err
+		}
+		out[row.MessageID] = append(out[row.MessageID], row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
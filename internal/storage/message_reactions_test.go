@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestAddReaction_ParticipantsOnlyAndCapped(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	text := "hello"
+	msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now+1)
+	if err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+
+	if _, err := store.AddReaction(ctx, session.ID, msg.ID, carol.ID, "👍", now+2); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("AddReaction(non-participant) error = %v, want ErrAccessDenied", err)
+	}
+	if _, err := store.AddReaction(ctx, "other-session", msg.ID, bob.ID, "👍", now+2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("AddReaction(wrong session) error = %v, want ErrNotFound", err)
+	}
+
+	for i, emoji := range []string{"👍", "❤️", "😂"} {
+		added, err := store.AddReaction(ctx, session.ID, msg.ID, bob.ID, emoji, now+int64(3+i))
+		if err != nil || !added {
+			t.Fatalf("AddReaction(%q) = %v, %v; want true, nil", emoji, added, err)
+		}
+	}
+	if added, err := store.AddReaction(ctx, session.ID, msg.ID, bob.ID, "👍", now+10); err != nil || added {
+		t.Fatalf("AddReaction(repeat) = %v, %v; want false, nil", added, err)
+	}
+	if _, err := store.AddReaction(ctx, session.ID, msg.ID, bob.ID, "🎉", now+11); !errors.Is(err, ErrReactionLimit) {
+		t.Fatalf("AddReaction(over cap) error = %v, want ErrReactionLimit", err)
+	}
+	if _, err := store.AddReaction(ctx, session.ID, msg.ID, alice.ID, "👍", now+12); err != nil {
+		t.Fatalf("AddReaction(alice) error = %v", err)
+	}
+
+	removed, err := store.RemoveReaction(ctx, session.ID, msg.ID, bob.ID, "😂")
+	if err != nil || !removed {
+		t.Fatalf("RemoveReaction() = %v, %v; want true, nil", removed, err)
+	}
+	if _, err := store.AddReaction(ctx, session.ID, msg.ID, bob.ID, "🎉", now+13); err != nil {
+		t.Fatalf("AddReaction(after removal) error = %v", err)
+	}
+
+	byMessage, err := store.ListMessageReactions(ctx, []string{msg.ID})
+	if err != nil {
+		t.Fatalf("ListMessageReactions() error = %v", err)
+	}
+	counts := map[string]int{}
+	for _, row := range byMessage[msg.ID] {
+		counts[row.Emoji]++
+	}
+	if len(counts) != 3 || counts["👍"] != 2 || counts["❤️"] != 1 || counts["🎉"] != 1 {
+		t.Fatalf("reaction counts = %v, want 👍:2 ❤️:1 🎉:1", counts)
+	}
+
+	if _, err := store.DeleteMessage(ctx, session.ID, msg.ID, alice.ID, now+20); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if _, err := store.AddReaction(ctx, session.ID, msg.ID, bob.ID, "👀", now+21); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("AddReaction(deleted) error = %v, want ErrInvalidState", err)
+	}
+	byMessage, err = store.ListMessageReactions(ctx, []string{msg.ID})
+	if err != nil {
+		t.Fatalf("ListMessageReactions(after delete) error = %v", err)
+	}
+	if len(byMessage[msg.ID]) != 0 {
+		t.Fatalf("reactions after delete = %d, want 0", len(byMessage[msg.ID]))
+	}
+}
@@ -5,20 +5,39 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+// MaxMessageTextLen caps the text of a message, in characters (runes) rather than bytes.
+const MaxMessageTextLen = 4000
+
 type MessageMeta struct {
 	Name      string `json:"name,omitempty"`
 	SizeBytes int64  `json:"sizeBytes,omitempty"`
 	URL       string `json:"url,omitempty"`
 }
 
-func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limit int, beforeID string) ([]MessageRow, bool, error) {
+// listMessagesSelect reads messages as m together with a preview of the message each one
+// replies to. The preview columns are NULL when the quoted message no longer exists.
+const listMessagesSelect = `SELECT m.id, m.session_id, m.sender_id, m.type, m.text, m.meta_json, m.created_at_ms, m.edited_at_ms, m.deleted_at_ms,
+			m.reply_to_message_id, r.sender_id, r.type, r.text, r.deleted_at_ms
+			FROM messages m
+			LEFT JOIN messages r ON r.id = m.reply_to_message_id`
+
+// ListMessages returns up to limit messages in ascending order. By default it pages
+// backwards from the newest message (or from beforeID); with afterID it pages forwards over
+// messages strictly newer than afterID, for catching up after a reconnect. hasMore reports
+// whether more messages exist in the direction of travel. beforeID and afterID are exclusive.
+func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limit int, beforeID, afterID string) ([]MessageRow, bool, error) {
 	if s == nil || s.db == nil {
 		return nil, false, fmt.Errorf("db not initialized")
 	}
+	if beforeID != "" && afterID != "" {
+		return nil, false, fmt.Errorf("before and after are mutually exclusive")
+	}
 
 	isParticipant, err := s.IsSessionParticipant(ctx, sessionID, userID)
 	if err != nil {
@@ -35,7 +54,25 @@ func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limi
 	var q string
 	var args []any
 
-	if beforeID != "" {
+	forward := afterID != ""
+	if forward {
+		var afterCreatedAt int64
+		subQ := `SELECT created_at_ms FROM messages WHERE id = ? AND session_id = ?;`
+		if err := s.db.QueryRowContext(ctx, s.rebind(subQ), afterID, sessionID).Scan(&afterCreatedAt); err != nil {
+			if err == sql.ErrNoRows {
+				// The anchor may have been burned; the client should resync from the latest page.
+				return nil, false, ErrInvalidCursor
+			}
+			return nil, false, err
+		}
+
+		// Ties on created_at_ms are broken by id so no message is skipped or repeated.
+		q = listMessagesSelect + `
+			WHERE m.session_id = ? AND (m.created_at_ms > ? OR (m.created_at_ms = ? AND m.id > ?))
+			ORDER BY m.created_at_ms ASC, m.id ASC
+			LIMIT ?;`
+		args = []any{sessionID, afterCreatedAt, afterCreatedAt, afterID, limit + 1}
+	} else if beforeID != "" {
 		var beforeCreatedAt int64
 		subQ := `SELECT created_at_ms FROM messages WHERE id = ?;`
 		if err := s.db.QueryRowContext(ctx, s.rebind(subQ), beforeID).Scan(&beforeCreatedAt); err != nil {
@@ -45,17 +82,15 @@ func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limi
 			return nil, false, err
 		}
 
-		q = `SELECT id, session_id, sender_id, type, text, meta_json, created_at_ms
-			FROM messages
-			WHERE session_id = ? AND created_at_ms < ?
-			ORDER BY created_at_ms DESC
+		q = listMessagesSelect + `
+			WHERE m.session_id = ? AND m.created_at_ms < ?
+			ORDER BY m.created_at_ms DESC
 			LIMIT ?;`
 		args = []any{sessionID, beforeCreatedAt, limit + 1}
 	} else {
-		q = `SELECT id, session_id, sender_id, type, text, meta_json, created_at_ms
-			FROM messages
-			WHERE session_id = ?
-			ORDER BY created_at_ms DESC
+		q = listMessagesSelect + `
+			WHERE m.session_id = ?
+			ORDER BY m.created_at_ms DESC
 			LIMIT ?;`
 		args = []any{sessionID, limit + 1}
 	}
@@ -70,16 +105,36 @@ func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limi
 	for rows.Next() {
 		var text sql.NullString
 		var meta sql.NullString
+		var editedAt sql.NullInt64
+		var deletedAt sql.NullInt64
+		var replyID, replySender, replyType, replyText sql.NullString
+		var replyDeletedAt sql.NullInt64
 		var mrow MessageRow
-		if err := rows.Scan(&mrow.ID, &mrow.SessionID, &mrow.SenderID, &mrow.Type, &text, &meta, &mrow.CreatedAtMs); err != nil {
+		if err := rows.Scan(
+			&mrow.ID, &mrow.SessionID, &mrow.SenderID, &mrow.Type, &text, &meta, &mrow.CreatedAtMs, &editedAt, &deletedAt,
+			&replyID, &replySender, &replyType, &replyText, &replyDeletedAt,
+		); err != nil {
 			return nil, false, err
 		}
+		if replyID.Valid {
+			mrow.ReplyToMessageID = &replyID.String
+			if replySender.Valid {
+				mrow.ReplyTo = newReplyPreview(replyID.String, replySender.String, replyType.String, replyText, replyDeletedAt.Valid)
+			}
+		}
 		if text.Valid {
 			mrow.Text = &text.String
 		}
+		if editedAt.Valid {
+			mrow.EditedAtMs = &editedAt.Int64
+		}
 		if meta.Valid && meta.String != "" {
 			mrow.MetaJSON = []byte(meta.String)
 		}
+		if deletedAt.Valid {
+			mrow.DeletedAtMs = &deletedAt.Int64
+			mrow = tombstoneMessage(mrow)
+		}
 		messages = append(messages, mrow)
 	}
 	if err := rows.Err(); err != nil {
@@ -90,6 +145,9 @@ func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limi
 	if hasMore {
 		messages = messages[:limit]
 	}
+	if forward {
+		return messages, hasMore, nil
+	}
 
 	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
 		messages[i], messages[j] = messages[j], messages[i]
@@ -99,6 +157,24 @@ func (s *Store) ListMessages(ctx context.Context, sessionID, userID string, limi
 }
 
 func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType string, text *string, meta *MessageMeta, nowMs int64) (MessageRow, error) {
+	return s.CreateReplyMessage(ctx, sessionID, senderID, msgType, text, meta, "", nowMs)
+}
+
+// CreateReplyMessage is CreateMessage with an optional quoted message. replyToID must name
+// an existing, undeleted, non-burn message in the same session; otherwise
+// ErrReplyTargetInvalid is returned. An empty replyToID sends a plain message.
+func (s *Store) CreateReplyMessage(ctx context.Context, sessionID, senderID, msgType string, text *string, meta *MessageMeta, replyToID string, nowMs int64) (MessageRow, error) {
+	return s.createMessage(ctx, sessionID, senderID, msgType, text, meta, replyToID, true, nowMs)
+}
+
+// CreateSystemMessage records a system notice (a member leaving, a call summary, ...)
+// attributed to senderID. Unlike CreateMessage, senderID need not still be an active
+// participant: the notice is often about them having just left.
+func (s *Store) CreateSystemMessage(ctx context.Context, sessionID, senderID, text string, nowMs int64) (MessageRow, error) {
+	return s.createMessage(ctx, sessionID, senderID, MessageTypeSystem, &text, nil, "", false, nowMs)
+}
+
+func (s *Store) createMessage(ctx context.Context, sessionID, senderID, msgType string, text *string, meta *MessageMeta, replyToID string, requireParticipant bool, nowMs int64) (MessageRow, error) {
 	if s == nil || s.db == nil {
 		return MessageRow{}, fmt.Errorf("db not initialized")
 	}
@@ -108,12 +184,14 @@ func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType
 		return MessageRow{}, err
 	}
 
-	isParticipant, err := s.IsSessionParticipant(ctx, sessionID, senderID)
-	if err != nil {
-		return MessageRow{}, err
-	}
-	if !isParticipant {
-		return MessageRow{}, ErrAccessDenied
+	if requireParticipant {
+		isParticipant, err := s.IsSessionParticipant(ctx, sessionID, senderID)
+		if err != nil {
+			return MessageRow{}, err
+		}
+		if !isParticipant {
+			return MessageRow{}, ErrAccessDenied
+		}
 	}
 
 	// Activity group chats auto-archive after endAtMs:
@@ -135,6 +213,24 @@ func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType
 	if session.Status == SessionStatusArchived {
 		return MessageRow{}, ErrSessionArchived
 	}
+	// Server-posted notices (call summaries and the like, which come through
+	// CreateSystemMessage) are not something the peer can block.
+	if session.Kind == SessionKindDirect && requireParticipant {
+		if err := s.checkNotBlocked(ctx, s.GetPeerUserID(session, senderID), senderID); err != nil {
+			return MessageRow{}, err
+		}
+	}
+	if text != nil && utf8.RuneCountInString(*text) > MaxMessageTextLen {
+		return MessageRow{}, fmt.Errorf("message text too long")
+	}
+
+	var replyTo *MessageReplyPreview
+	if replyToID != "" {
+		replyTo, err = s.getReplyTarget(ctx, sessionID, replyToID)
+		if err != nil {
+			return MessageRow{}, err
+		}
+	}
 
 	metaJSON, err := marshalMeta(meta)
 	if err != nil {
@@ -150,8 +246,13 @@ func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	insertQ := `INSERT INTO messages (id, session_id, sender_id, type, text, meta_json, created_at_ms)
-		VALUES (?, ?, ?, ?, ?, ?, ?);`
+	insertQ := `INSERT INTO messages (id, session_id, sender_id, type, text, meta_json, created_at_ms, reply_to_message_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	var replyVal any
+	if replyTo != nil {
+		replyVal = replyTo.ID
+	}
 
 	var textVal any
 	if text != nil {
@@ -164,7 +265,7 @@ func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType
 	}
 
 	if _, err := tx.ExecContext(ctx, s.rebind(insertQ),
-		messageID, sessionID, senderID, msgType, textVal, metaVal, nowMs,
+		messageID, sessionID, senderID, msgType, textVal, metaVal, nowMs, replyVal,
 	); err != nil {
 		return MessageRow{}, err
 	}
@@ -186,10 +287,209 @@ func (s *Store) CreateMessage(ctx context.Context, sessionID, senderID, msgType
 		Text:        text,
 		MetaJSON:    metaJSON,
 		CreatedAtMs: nowMs,
+		ReplyTo:     replyTo,
+	}
+	if replyTo != nil {
+		msg.ReplyToMessageID = &replyTo.ID
 	}
 	return msg, nil
 }
 
+// getReplyTarget loads the preview of messageID for quoting inside sessionID. Burn and
+// unsent messages cannot be quoted.
+func (s *Store) getReplyTarget(ctx context.Context, sessionID, messageID string) (*MessageReplyPreview, error) {
+	q := `SELECT session_id, sender_id, type, text, deleted_at_ms FROM messages WHERE id = ?;`
+	var (
+		targetSessionID string
+		senderID        string
+		msgType         string
+		text            sql.NullString
+		deletedAt       sql.NullInt64
+	)
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), messageID).Scan(&targetSessionID, &senderID, &msgType, &text, &deletedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: message not found", ErrReplyTargetInvalid)
+		}
+		return nil, err
+	}
+	if targetSessionID != sessionID {
+		return nil, fmt.Errorf("%w: message belongs to another session", ErrReplyTargetInvalid)
+	}
+	if msgType == MessageTypeBurn || deletedAt.Valid {
+		return nil, fmt.Errorf("%w: message cannot be quoted", ErrReplyTargetInvalid)
+	}
+	return newReplyPreview(messageID, senderID, msgType, text, false), nil
+}
+
+// newReplyPreview builds the quote shown above a reply. Quoted messages that were unsent
+// later are reported as type "deleted" without text.
+func newReplyPreview(id, senderID, msgType string, text sql.NullString, deleted bool) *MessageReplyPreview {
+	preview := &MessageReplyPreview{ID: id, SenderID: senderID, Type: msgType}
+	if deleted {
+		preview.Type = MessageTypeDeleted
+		return preview
+	}
+	if text.Valid {
+		preview.Text = &text.String
+	}
+	return preview
+}
+
+// getOwnMessageForUpdate loads messageID from sessionID for a sender-side change. The
+// caller must be the sender and still a participant, and the session must not be archived.
+func (s *Store) getOwnMessageForUpdate(ctx context.Context, sessionID, messageID, userID string) (MessageRow, error) {
+	q := `SELECT id, session_id, sender_id, type, text, meta_json, created_at_ms, edited_at_ms, deleted_at_ms
+		FROM messages WHERE id = ? AND session_id = ?;`
+	var (
+		msg       MessageRow
+		text      sql.NullString
+		meta      sql.NullString
+		editedAt  sql.NullInt64
+		deletedAt sql.NullInt64
+	)
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), messageID, sessionID).Scan(
+		&msg.ID, &msg.SessionID, &msg.SenderID, &msg.Type, &text, &meta, &msg.CreatedAtMs, &editedAt, &deletedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return MessageRow{}, fmt.Errorf("%w: message", ErrNotFound)
+		}
+		return MessageRow{}, err
+	}
+	if msg.SenderID != userID {
+		return MessageRow{}, ErrAccessDenied
+	}
+	if text.Valid {
+		msg.Text = &text.String
+	}
+	if meta.Valid && meta.String != "" {
+		msg.MetaJSON = []byte(meta.String)
+	}
+	if editedAt.Valid {
+		msg.EditedAtMs = &editedAt.Int64
+	}
+	if deletedAt.Valid {
+		msg.DeletedAtMs = &deletedAt.Int64
+	}
+
+	isParticipant, err := s.IsSessionParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return MessageRow{}, err
+	}
+	if !isParticipant {
+		return MessageRow{}, ErrAccessDenied
+	}
+	session, err := s.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return MessageRow{}, err
+	}
+	if session.Status == SessionStatusArchived {
+		return MessageRow{}, ErrSessionArchived
+	}
+	return msg, nil
+}
+
+// EditMessage replaces the text of one of userID's own text messages in sessionID and
+// stamps edited_at_ms. Image, file, system, burn and deleted messages cannot be edited.
+func (s *Store) EditMessage(ctx context.Context, sessionID, messageID, userID, newText string, nowMs int64) (MessageRow, error) {
+	if s == nil || s.db == nil {
+		return MessageRow{}, fmt.Errorf("db not initialized")
+	}
+	newText = strings.TrimSpace(newText)
+	if sessionID == "" || messageID == "" || userID == "" || newText == "" {
+		return MessageRow{}, fmt.Errorf("missing fields")
+	}
+	if utf8.RuneCountInString(newText) > MaxMessageTextLen {
+		return MessageRow{}, fmt.Errorf("message text too long")
+	}
+
+	msg, err := s.getOwnMessageForUpdate(ctx, sessionID, messageID, userID)
+	if err != nil {
+		return MessageRow{}, err
+	}
+	if msg.Type != MessageTypeText || msg.DeletedAtMs != nil {
+		return MessageRow{}, ErrInvalidState
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return MessageRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	updateQ := `UPDATE messages SET text = ?, edited_at_ms = ? WHERE id = ?;`
+	if _, err := tx.ExecContext(ctx, s.rebind(updateQ), newText, nowMs, messageID); err != nil {
+		return MessageRow{}, err
+	}
+	// Keep the session preview in sync when the edited message is the latest one.
+	previewQ := `UPDATE sessions SET last_message_text = ? WHERE id = ? AND last_message_at_ms = ?;`
+	if _, err := tx.ExecContext(ctx, s.rebind(previewQ), newText, sessionID, msg.CreatedAtMs); err != nil {
+		return MessageRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return MessageRow{}, err
+	}
+
+	msg.Text = &newText
+	msg.EditedAtMs = &nowMs
+	return msg, nil
+}
+
+// DeleteMessage unsends one of userID's own messages. The row is kept as a tombstone
+// (content cleared, deleted_at_ms set) so history pages keep the same size and cursors
+// stay valid; ListMessages reports it with type "deleted". Deleting twice is a no-op.
+func (s *Store) DeleteMessage(ctx context.Context, sessionID, messageID, userID string, nowMs int64) (MessageRow, error) {
+	if s == nil || s.db == nil {
+		return MessageRow{}, fmt.Errorf("db not initialized")
+	}
+	if sessionID == "" || messageID == "" || userID == "" {
+		return MessageRow{}, fmt.Errorf("missing fields")
+	}
+
+	msg, err := s.getOwnMessageForUpdate(ctx, sessionID, messageID, userID)
+	if err != nil {
+		return MessageRow{}, err
+	}
+	if msg.DeletedAtMs == nil {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return MessageRow{}, err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		updateQ := `UPDATE messages SET text = NULL, meta_json = NULL, deleted_at_ms = ? WHERE id = ? AND deleted_at_ms IS NULL;`
+		if _, err := tx.ExecContext(ctx, s.rebind(updateQ), nowMs, messageID); err != nil {
+			return MessageRow{}, err
+		}
+		reactionsQ := `DELETE FROM message_reactions WHERE message_id = ?;`
+		if _, err := tx.ExecContext(ctx, s.rebind(reactionsQ), messageID); err != nil {
+			return MessageRow{}, err
+		}
+		previewQ := `UPDATE sessions SET last_message_text = ? WHERE id = ? AND last_message_at_ms = ?;`
+		if _, err := tx.ExecContext(ctx, s.rebind(previewQ), buildLastMessageText(MessageTypeDeleted, nil, nil), sessionID, msg.CreatedAtMs); err != nil {
+			return MessageRow{}, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return MessageRow{}, err
+		}
+		msg.DeletedAtMs = &nowMs
+	}
+
+	return tombstoneMessage(msg), nil
+}
+
+// tombstoneMessage strips the content of a deleted message and reports it as type "deleted".
+func tombstoneMessage(m MessageRow) MessageRow {
+	m.Type = MessageTypeDeleted
+	m.Text = nil
+	m.MetaJSON = nil
+	m.EditedAtMs = nil
+	m.ReplyToMessageID = nil
+	m.ReplyTo = nil
+	return m
+}
+
 func marshalMeta(meta *MessageMeta) ([]byte, error) {
 	if meta == nil {
 		return nil, nil
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEditMessage_SenderOnlyTextMessages(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	text := "helo"
+	msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now+1)
+	if err != nil {
+		t.Fatalf("CreateMessage(text) error = %v", err)
+	}
+	img, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeImage, nil, &MessageMeta{Name: "a.jpg", SizeBytes: 10}, now+2)
+	if err != nil {
+		t.Fatalf("CreateMessage(image) error = %v", err)
+	}
+
+	if _, err := store.EditMessage(ctx, session.ID, msg.ID, bob.ID, "hijacked", now+3); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("EditMessage(non-sender) error = %v, want ErrAccessDenied", err)
+	}
+	if _, err := store.EditMessage(ctx, session.ID, img.ID, alice.ID, "caption", now+3); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("EditMessage(image) error = %v, want ErrInvalidState", err)
+	}
+	if _, err := store.EditMessage(ctx, "other-session", msg.ID, alice.ID, "hello", now+3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("EditMessage(wrong session) error = %v, want ErrNotFound", err)
+	}
+
+	edited, err := store.EditMessage(ctx, session.ID, msg.ID, alice.ID, " hello ", now+4)
+	if err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if edited.Text == nil || *edited.Text != "hello" || edited.EditedAtMs == nil || *edited.EditedAtMs != now+4 {
+		t.Fatalf("edited = %+v, want text %q edited at %d", edited, "hello", now+4)
+	}
+
+	messages, _, err := store.ListMessages(ctx, session.ID, bob.ID, 50, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(messages))
+	}
+	if got := messages[0]; got.ID != msg.ID || got.Text == nil || *got.Text != "hello" || got.EditedAtMs == nil {
+		t.Fatalf("messages[0] = %+v, want edited text message", got)
+	}
+	if messages[1].EditedAtMs != nil {
+		t.Fatalf("messages[1].EditedAtMs = %v, want nil", *messages[1].EditedAtMs)
+	}
+}
+
+func TestDeleteMessage_SenderOnlyLeavesTombstone(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	var sent []MessageRow
+	for i, text := range []string{"one", "oops", "three"} {
+		text := text
+		msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now+int64(i)+1)
+		if err != nil {
+			t.Fatalf("CreateMessage(%q) error = %v", text, err)
+		}
+		sent = append(sent, msg)
+	}
+	target := sent[1]
+
+	if _, err := store.DeleteMessage(ctx, session.ID, target.ID, bob.ID, now+10); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("DeleteMessage(non-sender) error = %v, want ErrAccessDenied", err)
+	}
+
+	deleted, err := store.DeleteMessage(ctx, session.ID, target.ID, alice.ID, now+10)
+	if err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if deleted.Type != MessageTypeDeleted || deleted.Text != nil || deleted.DeletedAtMs == nil || *deleted.DeletedAtMs != now+10 {
+		t.Fatalf("deleted = %+v, want tombstone deleted at %d", deleted, now+10)
+	}
+	again, err := store.DeleteMessage(ctx, session.ID, target.ID, alice.ID, now+20)
+	if err != nil || again.DeletedAtMs == nil || *again.DeletedAtMs != now+10 {
+		t.Fatalf("DeleteMessage(again) = %+v, %v, want original tombstone", again, err)
+	}
+	if _, err := store.EditMessage(ctx, session.ID, target.ID, alice.ID, "revived", now+30); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("EditMessage(deleted) error = %v, want ErrInvalidState", err)
+	}
+
+	// The tombstone keeps its slot so pagination over history is unchanged.
+	page, hasMore, err := store.ListMessages(ctx, session.ID, bob.ID, 2, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	if !hasMore || len(page) != 2 || page[0].ID != target.ID || page[1].ID != sent[2].ID {
+		t.Fatalf("page = %+v (hasMore=%v), want [oops-tombstone, three] with more", page, hasMore)
+	}
+	if page[0].Type != MessageTypeDeleted || page[0].Text != nil || page[0].DeletedAtMs == nil {
+		t.Fatalf("page[0] = %+v, want tombstone", page[0])
+	}
+	older, _, err := store.ListMessages(ctx, session.ID, bob.ID, 2, target.ID, "")
+	if err != nil {
+		t.Fatalf("ListMessages(before tombstone) error = %v", err)
+	}
+	if len(older) != 1 || older[0].ID != sent[0].ID {
+		t.Fatalf("older = %+v, want only the first message", older)
+	}
+}
+
+func TestListMessages_AfterCursorPagesForward(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	// Two messages share a timestamp so the id tie-break is exercised.
+	var sent []MessageRow
+	for i, atMs := range []int64{now + 1, now + 2, now + 2, now + 3, now + 4} {
+		text := string(rune('a' + i))
+		msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, atMs)
+		if err != nil {
+			t.Fatalf("CreateMessage(%d) error = %v", i, err)
+		}
+		sent = append(sent, msg)
+	}
+	// Forward paging orders by (created_at_ms, id).
+	all := append([]MessageRow(nil), sent...)
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAtMs != all[j].CreatedAtMs {
+			return all[i].CreatedAtMs < all[j].CreatedAtMs
+		}
+		return all[i].ID < all[j].ID
+	})
+
+	var (
+		got     []string
+		afterID = all[0].ID
+	)
+	for {
+		page, hasMore, err := store.ListMessages(ctx, session.ID, bob.ID, 2, "", afterID)
+		if err != nil {
+			t.Fatalf("ListMessages(after %s) error = %v", afterID, err)
+		}
+		for _, m := range page {
+			got = append(got, m.ID)
+		}
+		if !hasMore {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	if len(got) != len(all)-1 {
+		t.Fatalf("forward pages returned %d messages, want %d", len(got), len(all)-1)
+	}
+	for i, id := range got {
+		if id != all[i+1].ID {
+			t.Fatalf("forward page order = %v, want ascending order of %v", got, all[1:])
+		}
+	}
+
+	page, hasMore, err := store.ListMessages(ctx, session.ID, bob.ID, 2, "", all[len(all)-1].ID)
+	if err != nil || len(page) != 0 || hasMore {
+		t.Fatalf("ListMessages(after newest) = %d messages, hasMore=%v, err=%v, want empty", len(page), hasMore, err)
+	}
+	if _, _, err := store.ListMessages(ctx, session.ID, bob.ID, 2, "", "missing"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("ListMessages(after missing) error = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := store.ListMessages(ctx, session.ID, bob.ID, 2, sent[1].ID, sent[0].ID); err == nil {
+		t.Fatalf("ListMessages(before and after) error = nil, want error")
+	}
+}
+
+func TestCreateMessage_TextLengthBoundaryCountsRunes(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	// Multibyte text at exactly the limit is accepted even though it is far over 4000 bytes.
+	atLimit := strings.Repeat("你", MaxMessageTextLen)
+	msg, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &atLimit, nil, now+1)
+	if err != nil {
+		t.Fatalf("CreateMessage(at limit) error = %v", err)
+	}
+
+	overLimit := atLimit + "好"
+	if _, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &overLimit, nil, now+2); err == nil {
+		t.Fatalf("CreateMessage(over limit) error = nil, want error")
+	}
+	if _, err := store.EditMessage(ctx, session.ID, msg.ID, alice.ID, overLimit, now+3); err == nil {
+		t.Fatalf("EditMessage(over limit) error = nil, want error")
+	}
+	if _, err := store.EditMessage(ctx, session.ID, msg.ID, alice.ID, atLimit, now+3); err != nil {
+		t.Fatalf("EditMessage(at limit) error = %v", err)
+	}
+}
+
+func TestCreateReplyMessage_QuotesWithinSessionOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession(alice, bob) error = %v", err)
+	}
+	other, _, err := store.CreateSession(ctx, alice.ID, carol.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession(alice, carol) error = %v", err)
+	}
+
+	text := "lunch at noon?"
+	original, err := store.CreateMessage(ctx, session.ID, alice.ID, MessageTypeText, &text, nil, now+1)
+	if err != nil {
+		t.Fatalf("CreateMessage(original) error = %v", err)
+	}
+	elsewhere, err := store.CreateMessage(ctx, other.ID, alice.ID, MessageTypeText, &text, nil, now+2)
+	if err != nil {
+		t.Fatalf("CreateMessage(elsewhere) error = %v", err)
+	}
+	burn, _, err := store.CreateBurnMessage(ctx, session.ID, alice.ID, []byte(`{"text":"secret"}`), 10_000, now+3)
+	if err != nil {
+		t.Fatalf("CreateBurnMessage() error = %v", err)
+	}
+
+	replyText := "sure"
+	for name, target := range map[string]string{
+		"other session": elsewhere.ID,
+		"burn":          burn.ID,
+		"missing":       "nope",
+	} {
+		if _, err := store.CreateReplyMessage(ctx, session.ID, bob.ID, MessageTypeText, &replyText, nil, target, now+4); !errors.Is(err, ErrReplyTargetInvalid) {
+			t.Fatalf("CreateReplyMessage(%s) error = %v, want ErrReplyTargetInvalid", name, err)
+		}
+	}
+
+	reply, err := store.CreateReplyMessage(ctx, session.ID, bob.ID, MessageTypeText, &replyText, nil, original.ID, now+5)
+	if err != nil {
+		t.Fatalf("CreateReplyMessage() error = %v", err)
+	}
+	if reply.ReplyTo == nil || reply.ReplyTo.ID != original.ID || reply.ReplyTo.SenderID != alice.ID || reply.ReplyTo.Text == nil || *reply.ReplyTo.Text != text {
+		t.Fatalf("reply.ReplyTo = %+v, want preview of original", reply.ReplyTo)
+	}
+
+	if _, err := store.DeleteMessage(ctx, session.ID, original.ID, alice.ID, now+6); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	messages, _, err := store.ListMessages(ctx, session.ID, bob.ID, 50, "", "")
+	if err != nil {
+		t.Fatalf("ListMessages() error = %v", err)
+	}
+	got := messages[len(messages)-1]
+	if got.ID != reply.ID || got.ReplyToMessageID == nil || *got.ReplyToMessageID != original.ID {
+		t.Fatalf("last message = %+v, want reply to %s", got, original.ID)
+	}
+	if got.ReplyTo == nil || got.ReplyTo.Type != MessageTypeDeleted || got.ReplyTo.Text != nil {
+		t.Fatalf("ReplyTo after delete = %+v, want deleted preview without text", got.ReplyTo)
+	}
+}
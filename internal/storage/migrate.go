@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// schemaMigrationTimeout bounds schema setup, including waiting for another instance that is
+// migrating the same Postgres database.
+const schemaMigrationTimeout = time.Minute
+
+// schemaAdvisoryLockID is the Postgres advisory lock key held while the schema is applied.
+const schemaAdvisoryLockID int64 = 0x4c42_5343_4845_4d41 // "LBSCHEMA"
+
+// schemaDB is what the schema setup runs on: the pool, or the single connection holding the
+// migration lock.
+type schemaDB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// migrateSchema applies the schema and column migrations for driver. The statements are
+// idempotent, but concurrent CREATE TABLE IF NOT EXISTS can still fail on Postgres when
+// several instances start together, so there they run under a session advisory lock. They run
+// on the connection holding the lock, which also keeps startup working with a pool of one.
+func migrateSchema(ctx context.Context, db *sql.DB, driver string) error {
+	if driver != "pgx" {
+		return initSchema(ctx, db, driver)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, schemaAdvisoryLockID); err != nil {
+		return err
+	}
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_, _ = conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1);`, schemaAdvisoryLockID)
+	}()
+
+	return initSchema(ctx, conn, driver)
+}
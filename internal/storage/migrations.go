@@ -4,10 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
-func applyMigrations(ctx context.Context, db *sql.DB, driver string) error {
+func applyMigrations(ctx context.Context, db schemaDB, driver string) error {
 	if err := ensureColumn(ctx, db, driver, "sessions", "source", "TEXT NOT NULL DEFAULT 'wechat_code'"); err != nil {
 		return err
 	}
@@ -24,6 +25,9 @@ func applyMigrations(ctx context.Context, db *sql.DB, driver string) error {
 	if err := ensureColumn(ctx, db, driver, "session_requests", "last_opened_at_ms", "BIGINT NOT NULL DEFAULT 0"); err != nil {
 		return err
 	}
+	if err := ensureColumn(ctx, db, driver, "session_requests", "seen_at_ms", "BIGINT"); err != nil {
+		return err
+	}
 
 	if err := ensureColumn(ctx, db, driver, "session_invites", "expires_at_ms", "BIGINT"); err != nil {
 		return err
@@ -37,6 +41,12 @@ func applyMigrations(ctx context.Context, db *sql.DB, driver string) error {
 	if err := ensureColumn(ctx, db, driver, "session_invites", "geo_fence_radius_m", "INTEGER"); err != nil {
 		return err
 	}
+	if err := ensureColumn(ctx, db, driver, "session_invites", "settings_update_ymd", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "session_invites", "settings_update_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
 
 	if err := ensureColumn(ctx, db, driver, "activity_invites", "expires_at_ms", "BIGINT"); err != nil {
 		return err
@@ -50,6 +60,50 @@ func applyMigrations(ctx context.Context, db *sql.DB, driver string) error {
 	if err := ensureColumn(ctx, db, driver, "activity_invites", "geo_fence_radius_m", "INTEGER"); err != nil {
 		return err
 	}
+	if err := ensureColumn(ctx, db, driver, "activity_invites", "settings_update_ymd", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "activity_invites", "settings_update_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "activity_invites", "max_uses", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "activity_invites", "used_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(ctx, db, driver, "messages", "edited_at_ms", "BIGINT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "messages", "deleted_at_ms", "BIGINT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "messages", "reply_to_message_id", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(ctx, db, driver, "calls", "accepted_at_ms", "BIGINT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "calls", "ended_at_ms", "BIGINT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, driver, "calls", "session_id", "TEXT"); err != nil {
+		return err
+	}
+	// Group calls have no callee.
+	if err := ensureColumnNullable(ctx, db, driver, "calls", "callee_id"); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(ctx, db, driver, "burn_messages", "session_kind", "TEXT NOT NULL DEFAULT 'direct'"); err != nil {
+		return err
+	}
+	// Group burn messages have no single recipient.
+	if err := ensureColumnNullable(ctx, db, driver, "burn_messages", "recipient_id"); err != nil {
+		return err
+	}
 
 	if err := ensureColumn(ctx, db, driver, "home_bases", "daily_update_count", "INTEGER NOT NULL DEFAULT 1"); err != nil {
 		return err
@@ -57,21 +111,80 @@ func applyMigrations(ctx context.Context, db *sql.DB, driver string) error {
 	if err := ensureColumn(ctx, db, driver, "home_bases", "visibility_radius_m", "INTEGER NOT NULL DEFAULT 1100"); err != nil {
 		return err
 	}
+	if err := ensureColumn(ctx, db, driver, "home_bases", "area_label", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := ensureColumn(ctx, db, driver, "wechat_bindings", "phone_number", "TEXT"); err != nil {
+		return err
+	}
 
 	stmts := []string{
 		`CREATE INDEX IF NOT EXISTS idx_sessions_source_updated_at_ms ON sessions(source, updated_at_ms);`,
 		`CREATE INDEX IF NOT EXISTS idx_session_requests_requester_created_at_ms ON session_requests(requester_id, created_at_ms);`,
 		`CREATE INDEX IF NOT EXISTS idx_session_requests_requester_source_last_opened_at_ms ON session_requests(requester_id, source, last_opened_at_ms);`,
+		`CREATE INDEX IF NOT EXISTS idx_calls_session_id_status ON calls(session_id, status);`,
 	}
 	for _, stmt := range stmts {
 		if _, err := db.ExecContext(ctx, stmt); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	return ensureActiveCallPairIndex(ctx, db, driver)
+}
+
+// ensureActiveCallPairIndex lets at most one direct call per pair of users ring or stay
+// connected, whichever side placed it. Databases from before the index may already hold
+// duplicates; all but the newest are ended first so the index can be built.
+func ensureActiveCallPairIndex(ctx context.Context, db schemaDB, driver string) error {
+	const name = "idx_calls_active_pair"
+	exists, err := indexExists(ctx, db, driver, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	endDuplicates := `UPDATE calls SET status = 'ended', ended_at_ms = updated_at_ms
+		WHERE session_id IS NULL AND status IN ('inviting', 'accepted') AND EXISTS (
+			SELECT 1 FROM calls newer
+			WHERE newer.session_id IS NULL AND newer.status IN ('inviting', 'accepted')
+				AND ((newer.caller_id = calls.caller_id AND newer.callee_id = calls.callee_id)
+					OR (newer.caller_id = calls.callee_id AND newer.callee_id = calls.caller_id))
+				AND (newer.created_at_ms > calls.created_at_ms
+					OR (newer.created_at_ms = calls.created_at_ms AND newer.id > calls.id))
+		);`
+	if _, err := db.ExecContext(ctx, endDuplicates); err != nil {
+		return err
+	}
+
+	low, high := "min(caller_id, callee_id)", "max(caller_id, callee_id)"
+	if driver == "pgx" {
+		low, high = "LEAST(caller_id, callee_id)", "GREATEST(caller_id, callee_id)"
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %s ON calls(%s, %s)
+		WHERE session_id IS NULL AND status IN ('inviting', 'accepted');`, name, low, high))
+	return err
+}
+
+func indexExists(ctx context.Context, db schemaDB, driver, name string) (bool, error) {
+	q := `SELECT 1 FROM pg_indexes WHERE schemaname = current_schema() AND indexname = $1;`
+	if driver == "sqlite" {
+		q = `SELECT 1 FROM sqlite_master WHERE type = 'index' AND name = ?;`
+	}
+	var one int
+	if err := db.QueryRowContext(ctx, q, name).Scan(&one); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
-func ensureColumn(ctx context.Context, db *sql.DB, driver, table, column, definition string) error {
+func ensureColumn(ctx context.Context, db schemaDB, driver, table, column, definition string) error {
 	if !isSafeIdentifier(table) || !isSafeIdentifier(column) {
 		return fmt.Errorf("unsafe identifier: table=%q column=%q", table, column)
 	}
@@ -95,7 +208,133 @@ func ensureColumn(ctx context.Context, db *sql.DB, driver, table, column, defini
 	return nil
 }
 
-func columnExists(ctx context.Context, db *sql.DB, driver, table, column string) (bool, error) {
+// ensureColumnNullable drops a NOT NULL constraint that older schemas put on column. SQLite
+// cannot alter a column in place, so there the table is rebuilt from its stored definition.
+func ensureColumnNullable(ctx context.Context, db schemaDB, driver, table, column string) error {
+	if !isSafeIdentifier(table) || !isSafeIdentifier(column) {
+		return fmt.Errorf("unsafe identifier: table=%q column=%q", table, column)
+	}
+
+	notNull, err := columnNotNull(ctx, db, driver, table, column)
+	if err != nil {
+		return err
+	}
+	if !notNull {
+		return nil
+	}
+
+	if driver == "sqlite" {
+		return dropNotNullSQLite(ctx, db, table, column)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, column))
+	return err
+}
+
+func columnNotNull(ctx context.Context, db schemaDB, driver, table, column string) (bool, error) {
+	if driver == "sqlite" {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s);", table))
+		if err != nil {
+			return false, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				cid     int
+				name    string
+				typ     string
+				notnull int
+				dflt    sql.NullString
+				pk      int
+			)
+			if err := rows.Scan(&cid, &name, &typ, &notnull, &dflt, &pk); err != nil {
+				return false, err
+			}
+			if name == column {
+				return notnull != 0, nil
+			}
+		}
+		return false, rows.Err()
+	}
+
+	const q = `SELECT is_nullable
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		AND table_name = $1
+		AND column_name = $2;`
+	var nullable string
+	if err := db.QueryRowContext(ctx, q, table, column).Scan(&nullable); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return nullable == "NO", nil
+}
+
+// dropNotNullSQLite follows SQLite's recipe for schema changes ALTER TABLE cannot make: copy
+// the rows into a table created from the relaxed definition, drop the old one and rename the
+// copy into place. Foreign keys are off meanwhile so dropping the old table does not cascade
+// into the rows that reference it.
+func dropNotNullSQLite(ctx context.Context, db schemaDB, table, column string) error {
+	var createSQL string
+	if err := db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?;`, table).Scan(&createSQL); err != nil {
+		return err
+	}
+	columnDef := regexp.MustCompile(`(?i)(\b` + column + `\s+\w+)\s+NOT\s+NULL`)
+	if !columnDef.MatchString(createSQL) {
+		return fmt.Errorf("relax %s.%s: column definition not found", table, column)
+	}
+	relaxed := columnDef.ReplaceAllString(createSQL, "$1")
+	tableName := regexp.MustCompile(`(?i)^CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?"?` + table + `"?`)
+	tmp := table + "_relaxed"
+	relaxed = tableName.ReplaceAllString(relaxed, "CREATE TABLE "+tmp)
+
+	var indexes []string
+	rows, err := db.QueryContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL;`, table)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		indexes = append(indexes, stmt)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = OFF;"); err != nil {
+		return err
+	}
+	defer func() { _, _ = db.ExecContext(context.WithoutCancel(ctx), "PRAGMA foreign_keys = ON;") }()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmts := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s;", tmp),
+		relaxed,
+		fmt.Sprintf("INSERT INTO %s SELECT * FROM %s;", tmp, table),
+		fmt.Sprintf("DROP TABLE %s;", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", tmp, table),
+	}
+	stmts = append(stmts, indexes...)
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("relax %s.%s: %w", table, column, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func columnExists(ctx context.Context, db schemaDB, driver, table, column string) (bool, error) {
 	switch driver {
 	case "sqlite":
 		return columnExistsSQLite(ctx, db, table, column)
@@ -107,7 +346,7 @@ func columnExists(ctx context.Context, db *sql.DB, driver, table, column string)
 	}
 }
 
-func columnExistsSQLite(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+func columnExistsSQLite(ctx context.Context, db schemaDB, table, column string) (bool, error) {
 	q := fmt.Sprintf("PRAGMA table_info(%s);", table)
 	rows, err := db.QueryContext(ctx, q)
 	if err != nil {
@@ -137,7 +376,7 @@ func columnExistsSQLite(ctx context.Context, db *sql.DB, table, column string) (
 	return false, nil
 }
 
-func columnExistsPostgres(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+func columnExistsPostgres(ctx context.Context, db schemaDB, table, column string) (bool, error) {
 	const q = `SELECT 1
 		FROM information_schema.columns
 		WHERE table_schema = current_schema()
@@ -65,6 +65,43 @@ func (s *Store) ListRelationshipGroups(ctx context.Context, userID string) ([]Re
 	return out, nil
 }
 
+// CountSessionsPerGroup returns, for each of userID's relationship groups that has sessions
+// assigned, how many there are and the latest updated_at_ms among them. Groups without
+// sessions are absent from the map.
+func (s *Store) CountSessionsPerGroup(ctx context.Context, userID string) (map[string]RelationshipGroupStats, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+	if userID == "" {
+		return nil, fmt.Errorf("missing userID")
+	}
+
+	q := `SELECT m.group_id, COUNT(*), MAX(s.updated_at_ms)
+		FROM session_user_meta m
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.user_id = ? AND m.group_id IS NOT NULL
+		GROUP BY m.group_id;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]RelationshipGroupStats{}
+	for rows.Next() {
+		var groupID string
+		var stats RelationshipGroupStats
+		if err := rows.Scan(&groupID, &stats.SessionCount, &stats.LastActivityAtMs); err != nil {
+			return nil, err
+		}
+		out[groupID] = stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (s *Store) CreateRelationshipGroup(ctx context.Context, userID, name string, nowMs int64) (RelationshipGroupRow, bool, error) {
 	if s == nil || s.db == nil {
 		return RelationshipGroupRow{}, false, fmt.Errorf("db not initialized")
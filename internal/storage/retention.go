@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// PurgeFinishedActivityReminders deletes reminders that are no longer pending and were last touched before beforeMs.
+// Pending reminders are never purged, regardless of age.
+func (s *Store) PurgeFinishedActivityReminders(ctx context.Context, beforeMs int64) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+
+	q := `DELETE FROM activity_reminders WHERE status <> ? AND updated_at_ms < ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), ActivityReminderStatusPending, beforeMs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeEndedAnnouncements deletes announcements whose window closed before beforeMs.
+// Open-ended announcements are kept until an end time is set.
+func (s *Store) PurgeEndedAnnouncements(ctx context.Context, beforeMs int64) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+
+	q := `DELETE FROM announcements WHERE ends_at_ms IS NOT NULL AND ends_at_ms < ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), beforeMs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeIdempotencyKeys deletes idempotency keys created before beforeMs.
+func (s *Store) PurgeIdempotencyKeys(ctx context.Context, beforeMs int64) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+
+	q := `DELETE FROM idempotency_keys WHERE created_at_ms < ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), beforeMs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
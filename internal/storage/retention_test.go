@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestPurgeFinishedActivityReminders_KeepsPending(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	const nowMs = int64(1_700_000_000_000)
+	creator, err := store.CreateUser(ctx, "creator", "hash", "Creator", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	member, err := store.CreateUser(ctx, "member", "hash", "Member", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	activity, _, err := store.CreateActivity(ctx, creator.ID, "Hike", nil, nil, nil, nowMs)
+	if err != nil {
+		t.Fatalf("CreateActivity() error = %v", err)
+	}
+
+	if _, err := store.UpsertActivityReminder(ctx, activity.ID, creator.ID, nowMs+1000, nowMs); err != nil {
+		t.Fatalf("UpsertActivityReminder(creator) error = %v", err)
+	}
+	if _, err := store.UpsertActivityReminder(ctx, activity.ID, member.ID, nowMs+1000, nowMs); err != nil {
+		t.Fatalf("UpsertActivityReminder(member) error = %v", err)
+	}
+	if err := store.MarkActivityReminderSent(ctx, activity.ID, creator.ID, nowMs+1000); err != nil {
+		t.Fatalf("MarkActivityReminderSent() error = %v", err)
+	}
+
+	purged, err := store.PurgeFinishedActivityReminders(ctx, nowMs+10_000)
+	if err != nil {
+		t.Fatalf("PurgeFinishedActivityReminders() error = %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("purged = %d, want 1", purged)
+	}
+
+	if _, err := store.GetActivityReminder(ctx, activity.ID, member.ID); err != nil {
+		t.Fatalf("GetActivityReminder(pending) error = %v, want nil", err)
+	}
+}
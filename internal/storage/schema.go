@@ -2,10 +2,9 @@ package storage
 
 import (
 	"context"
-	"database/sql"
 )
 
-func initSchema(ctx context.Context, db *sql.DB, driver string) error {
+func initSchema(ctx context.Context, db schemaDB, driver string) error {
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS users (
 			id TEXT PRIMARY KEY,
@@ -57,16 +56,30 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 			text TEXT,
 			meta_json TEXT,
 			created_at_ms BIGINT NOT NULL,
+			edited_at_ms BIGINT,
+			deleted_at_ms BIGINT,
+			reply_to_message_id TEXT,
 			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE,
 			FOREIGN KEY(sender_id) REFERENCES users(id)
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_session_created_at_ms ON messages(session_id, created_at_ms);`,
 
+		`CREATE TABLE IF NOT EXISTS message_reactions (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			emoji TEXT NOT NULL,
+			created_at_ms BIGINT NOT NULL,
+			PRIMARY KEY(message_id, user_id, emoji),
+			FOREIGN KEY(message_id) REFERENCES messages(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS burn_messages (
 			message_id TEXT PRIMARY KEY,
 			session_id TEXT NOT NULL,
 			sender_id TEXT NOT NULL,
-			recipient_id TEXT NOT NULL,
+			session_kind TEXT NOT NULL DEFAULT 'direct',
+			recipient_id TEXT,
 			burn_after_ms BIGINT NOT NULL,
 			opened_at_ms BIGINT,
 			burn_at_ms BIGINT,
@@ -80,21 +93,36 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 		`CREATE INDEX IF NOT EXISTS idx_burn_messages_session_created_at_ms ON burn_messages(session_id, created_at_ms);`,
 		`CREATE INDEX IF NOT EXISTS idx_burn_messages_burn_at_ms ON burn_messages(burn_at_ms);`,
 
+		`CREATE TABLE IF NOT EXISTS burn_message_recipients (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			opened_at_ms BIGINT,
+			burn_at_ms BIGINT,
+			PRIMARY KEY(message_id, user_id),
+			FOREIGN KEY(message_id) REFERENCES burn_messages(message_id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS calls (
 			id TEXT PRIMARY KEY,
 			group_id TEXT NOT NULL,
 			caller_id TEXT NOT NULL,
-			callee_id TEXT NOT NULL,
+			callee_id TEXT,
 			media_type TEXT NOT NULL,
 			status TEXT NOT NULL,
 			created_at_ms BIGINT NOT NULL,
 			updated_at_ms BIGINT NOT NULL,
+			accepted_at_ms BIGINT,
+			ended_at_ms BIGINT,
+			session_id TEXT,
 			FOREIGN KEY(caller_id) REFERENCES users(id),
 			FOREIGN KEY(callee_id) REFERENCES users(id)
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_calls_caller ON calls(caller_id, updated_at_ms);`,
 		`CREATE INDEX IF NOT EXISTS idx_calls_callee ON calls(callee_id, updated_at_ms);`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_calls_group_id ON calls(group_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_calls_caller_created_at_ms ON calls(caller_id, created_at_ms);`,
+		`CREATE INDEX IF NOT EXISTS idx_calls_callee_created_at_ms ON calls(callee_id, created_at_ms);`,
 
 		`CREATE TABLE IF NOT EXISTS wechat_bindings (
 			user_id TEXT PRIMARY KEY,
@@ -106,6 +134,13 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 		);`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_wechat_bindings_openid ON wechat_bindings(openid);`,
 
+		`CREATE TABLE IF NOT EXISTS wechat_tokens (
+			app_id TEXT PRIMARY KEY,
+			access_token TEXT NOT NULL,
+			expires_at_ms BIGINT NOT NULL,
+			updated_at_ms BIGINT NOT NULL
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS session_requests (
 			id TEXT PRIMARY KEY,
 			requester_id TEXT NOT NULL,
@@ -127,6 +162,8 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 			geo_fence_lat_e7 BIGINT,
 			geo_fence_lng_e7 BIGINT,
 			geo_fence_radius_m INTEGER,
+			settings_update_ymd INTEGER NOT NULL DEFAULT 0,
+			settings_update_count INTEGER NOT NULL DEFAULT 0,
 			created_at_ms BIGINT NOT NULL,
 			updated_at_ms BIGINT NOT NULL,
 			FOREIGN KEY(inviter_id) REFERENCES users(id) ON DELETE CASCADE
@@ -140,6 +177,7 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 			last_updated_ymd INTEGER NOT NULL,
 			daily_update_count INTEGER NOT NULL DEFAULT 1,
 			visibility_radius_m INTEGER NOT NULL DEFAULT 1100,
+			area_label TEXT,
 			created_at_ms BIGINT NOT NULL,
 			updated_at_ms BIGINT NOT NULL,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -216,6 +254,15 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 		`CREATE INDEX IF NOT EXISTS idx_session_user_meta_user_updated_at_ms ON session_user_meta(user_id, updated_at_ms);`,
 		`CREATE INDEX IF NOT EXISTS idx_session_user_meta_user_group ON session_user_meta(user_id, group_id);`,
 
+		`CREATE TABLE IF NOT EXISTS session_read_marks (
+			session_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			last_read_at_ms BIGINT NOT NULL,
+			PRIMARY KEY(session_id, user_id),
+			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS session_participants (
 			session_id TEXT NOT NULL,
 			user_id TEXT NOT NULL,
@@ -230,6 +277,16 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 		`CREATE INDEX IF NOT EXISTS idx_session_participants_session_role ON session_participants(session_id, role);`,
 		`CREATE INDEX IF NOT EXISTS idx_session_participants_user_status_updated_at_ms ON session_participants(user_id, status, updated_at_ms);`,
 
+		`CREATE TABLE IF NOT EXISTS group_sessions (
+			session_id TEXT PRIMARY KEY,
+			creator_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			created_at_ms BIGINT NOT NULL,
+			updated_at_ms BIGINT NOT NULL,
+			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY(creator_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS activities (
 			id TEXT PRIMARY KEY,
 			session_id TEXT NOT NULL UNIQUE,
@@ -253,6 +310,10 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 				geo_fence_lat_e7 BIGINT,
 				geo_fence_lng_e7 BIGINT,
 				geo_fence_radius_m INTEGER,
+				max_uses INTEGER,
+				used_count INTEGER NOT NULL DEFAULT 0,
+				settings_update_ymd INTEGER NOT NULL DEFAULT 0,
+				settings_update_count INTEGER NOT NULL DEFAULT 0,
 				created_at_ms BIGINT NOT NULL,
 				updated_at_ms BIGINT NOT NULL,
 				FOREIGN KEY(activity_id) REFERENCES activities(id) ON DELETE CASCADE
@@ -273,6 +334,69 @@ func initSchema(ctx context.Context, db *sql.DB, driver string) error {
 				FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 			);`,
 		`CREATE INDEX IF NOT EXISTS idx_activity_reminders_status_remind_at_ms ON activity_reminders(status, remind_at_ms);`,
+
+		`CREATE TABLE IF NOT EXISTS announcements (
+			id TEXT PRIMARY KEY,
+			created_by TEXT NOT NULL,
+			title TEXT NOT NULL,
+			body TEXT NOT NULL,
+			starts_at_ms BIGINT NOT NULL,
+			ends_at_ms BIGINT,
+			dismissible INTEGER NOT NULL DEFAULT 1,
+			created_at_ms BIGINT NOT NULL,
+			FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_announcements_starts_at_ms ON announcements(starts_at_ms);`,
+
+		`CREATE TABLE IF NOT EXISTS uploads (
+			name TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			size_bytes BIGINT NOT NULL,
+			created_at_ms BIGINT NOT NULL,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS upload_usage (
+			user_id TEXT PRIMARY KEY,
+			used_bytes BIGINT NOT NULL DEFAULT 0,
+			quota_bytes BIGINT,
+			updated_at_ms BIGINT NOT NULL,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS card_views (
+			target_id TEXT NOT NULL,
+			viewer_id TEXT NOT NULL,
+			view_ymd INTEGER NOT NULL,
+			viewed_at_ms BIGINT NOT NULL,
+			PRIMARY KEY(target_id, viewer_id, view_ymd),
+			FOREIGN KEY(target_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(viewer_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_card_views_target_viewed_at_ms ON card_views(target_id, viewed_at_ms);`,
+
+		`CREATE TABLE IF NOT EXISTS blocks (
+			blocker_id TEXT NOT NULL,
+			blocked_id TEXT NOT NULL,
+			created_at_ms BIGINT NOT NULL,
+			PRIMARY KEY(blocker_id, blocked_id),
+			FOREIGN KEY(blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY(blocked_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_blocks_blocker_created_at_ms ON blocks(blocker_id, created_at_ms);`,
+
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			user_id TEXT NOT NULL,
+			idem_key TEXT NOT NULL,
+			request_path TEXT NOT NULL,
+			status_code INTEGER NOT NULL,
+			response_body TEXT NOT NULL,
+			created_at_ms BIGINT NOT NULL,
+			PRIMARY KEY(user_id, idem_key),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at_ms ON idempotency_keys(created_at_ms);`,
 	}
 
 	for _, stmt := range stmts {
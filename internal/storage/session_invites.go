@@ -126,6 +126,23 @@ func (s *Store) ResolveSessionInvite(ctx context.Context, code string) (SessionI
 	return row, nil
 }
 
+// Invite settings updates are counted per reset-TZ day, like home base moves.
+// The per-invite cap also bounds how often one activity's invite can change under its members;
+// the per-creator cap stops a user from churning settings across many activities.
+const (
+	maxInviteSettingsUpdatesPerDay             = 20
+	maxActivityInviteSettingsUpdatesPerCreator = 60
+)
+
+// inviteSettingsCountSet and inviteSettingsCountWhere make an invite settings UPDATE bump
+// the per-day counter and match only while today's count is under the cap, so the check and
+// the increment are one statement. Both take todayYMD; the WHERE part also takes the cap. An
+// UPDATE that matches no row means the cap was reached.
+const (
+	inviteSettingsCountSet   = `settings_update_count = CASE WHEN settings_update_ymd = ? THEN settings_update_count + 1 ELSE 1 END, settings_update_ymd = ?`
+	inviteSettingsCountWhere = ` AND (settings_update_ymd <> ? OR settings_update_count < ?)`
+)
+
 func (s *Store) UpdateSessionInviteSettings(ctx context.Context, inviterID string, expiresAtMs *int64, geoFence *GeoFence, nowMs int64) (SessionInviteRow, error) {
 	if s == nil || s.db == nil {
 		return SessionInviteRow{}, fmt.Errorf("db not initialized")
@@ -140,6 +157,8 @@ func (s *Store) UpdateSessionInviteSettings(ctx context.Context, inviterID strin
 		return SessionInviteRow{}, err
 	}
 
+	todayYMD := ymdInResetTZ(nowMs)
+
 	var exp any
 	if expiresAtMs != nil && *expiresAtMs > 0 {
 		exp = *expiresAtMs
@@ -155,11 +174,16 @@ func (s *Store) UpdateSessionInviteSettings(ctx context.Context, inviterID strin
 	}
 
 	q := `UPDATE session_invites
-		SET expires_at_ms = ?, geo_fence_lat_e7 = ?, geo_fence_lng_e7 = ?, geo_fence_radius_m = ?, updated_at_ms = ?
-		WHERE inviter_id = ?;`
-	if _, err := s.db.ExecContext(ctx, s.rebind(q), exp, lat, lng, rad, nowMs, inviterID); err != nil {
+		SET expires_at_ms = ?, geo_fence_lat_e7 = ?, geo_fence_lng_e7 = ?, geo_fence_radius_m = ?,
+			` + inviteSettingsCountSet + `, updated_at_ms = ?
+		WHERE inviter_id = ?` + inviteSettingsCountWhere + `;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), exp, lat, lng, rad, todayYMD, todayYMD, nowMs, inviterID, todayYMD, maxInviteSettingsUpdatesPerDay)
+	if err != nil {
 		return SessionInviteRow{}, err
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return SessionInviteRow{}, ErrRateLimited
+	}
 
 	row, _, err := s.GetOrCreateSessionInvite(ctx, inviterID, nowMs)
 	return row, err
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// unreadCountExpr counts messages in sessions.id that the user bound to its two
+// placeholders (sender exclusion, then read mark) has not read yet. Unsent messages are
+// skipped. It walks idx_messages_session_created_at_ms from the read mark onwards.
+const unreadCountExpr = `(SELECT COUNT(*) FROM messages m
+			WHERE m.session_id = sessions.id
+			AND m.sender_id <> ?
+			AND m.deleted_at_ms IS NULL
+			AND m.created_at_ms > COALESCE(
+				(SELECT rm.last_read_at_ms FROM session_read_marks rm WHERE rm.session_id = sessions.id AND rm.user_id = ?),
+				0))`
+
+// MarkSessionRead records that userID has read sessionID up to nowMs and reports whether
+// the mark moved. Marks never move backwards, so a stale request from another device
+// cannot resurrect unread messages.
+func (s *Store) MarkSessionRead(ctx context.Context, sessionID, userID string, nowMs int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	if sessionID == "" || userID == "" {
+		return false, fmt.Errorf("missing ids")
+	}
+
+	if _, err := s.GetSessionByID(ctx, sessionID); err != nil {
+		return false, err
+	}
+	isParticipant, err := s.IsSessionParticipant(ctx, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !isParticipant {
+		return false, ErrAccessDenied
+	}
+
+	q := `INSERT INTO session_read_marks (session_id, user_id, last_read_at_ms)
+		VALUES (?, ?, ?)
+		ON CONFLICT(session_id, user_id) DO UPDATE SET
+			last_read_at_ms = excluded.last_read_at_ms
+		WHERE excluded.last_read_at_ms > session_read_marks.last_read_at_ms;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), sessionID, userID, nowMs)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// CountUnreadMessages returns how many messages in sessionID userID has not read yet.
+func (s *Store) CountUnreadMessages(ctx context.Context, sessionID, userID string) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT ` + unreadCountExpr + ` FROM sessions WHERE id = ?;`
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), userID, userID, sessionID).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -7,11 +7,55 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+// SessionRequestLimits throttles outgoing session requests. Every requester may open at most
+// MaxPerWindow requests (counting re-opens) within WindowMs, across all sources; a rejected
+// pair cannot be re-requested until RejectCooldownMs has passed.
+type SessionRequestLimits struct {
+	WindowMs         int64
+	MaxPerWindow     int
+	RejectCooldownMs int64
+}
+
+// DefaultSessionRequestLimits is used when no limits are configured.
+var DefaultSessionRequestLimits = SessionRequestLimits{
+	WindowMs:         10 * 60 * 1000,
+	MaxPerWindow:     20,
+	RejectCooldownMs: 3 * 24 * 60 * 60 * 1000,
+}
+
+// MaxVerificationMessageLen caps the greeting attached to a session request, in runes.
+const MaxVerificationMessageLen = 100
+
+// NormalizeVerificationMessage trims msg and reports whether it fits
+// MaxVerificationMessageLen. A missing or blank message normalizes to nil.
+func NormalizeVerificationMessage(msg *string) (*string, bool) {
+	if msg == nil {
+		return nil, true
+	}
+	v := strings.TrimSpace(*msg)
+	if v == "" {
+		return nil, true
+	}
+	if utf8.RuneCountInString(v) > MaxVerificationMessageLen {
+		return nil, false
+	}
+	return &v, true
+}
+
+// CreateSessionRequest opens a request from requesterID to addresseeID under the default limits.
 func (s *Store) CreateSessionRequest(ctx context.Context, requesterID, addresseeID, source string, verificationMessage *string, nowMs int64) (SessionRequestRow, bool, error) {
+	return s.CreateSessionRequestWithLimits(ctx, requesterID, addresseeID, source, verificationMessage, DefaultSessionRequestLimits, nowMs)
+}
+
+// CreateSessionRequestWithLimits is CreateSessionRequest with explicit throttling. It returns
+// ErrRateLimited past the sliding window (or the daily cap for map requests) and
+// ErrCooldownActive while a rejection of the same pair is still cooling down.
+func (s *Store) CreateSessionRequestWithLimits(ctx context.Context, requesterID, addresseeID, source string, verificationMessage *string, limits SessionRequestLimits, nowMs int64) (SessionRequestRow, bool, error) {
 	if s == nil || s.db == nil {
 		return SessionRequestRow{}, false, fmt.Errorf("db not initialized")
 	}
@@ -21,10 +65,28 @@ func (s *Store) CreateSessionRequest(ctx context.Context, requesterID, addressee
 	if requesterID == addresseeID {
 		return SessionRequestRow{}, false, ErrCannotChatSelf
 	}
+	if err := s.checkNotBlocked(ctx, addresseeID, requesterID); err != nil {
+		return SessionRequestRow{}, false, err
+	}
+	verificationMessage, ok := NormalizeVerificationMessage(verificationMessage)
+	if !ok {
+		return SessionRequestRow{}, false, fmt.Errorf("verification message too long")
+	}
 
 	source = normalizeSessionRequestSource(source)
 
-	// Rate limit only applies to map-based relationship requests.
+	if limits.MaxPerWindow > 0 && limits.WindowMs > 0 {
+		windowQ := `SELECT COUNT(*) FROM session_requests WHERE requester_id = ? AND last_opened_at_ms > ?;`
+		var n int
+		if err := s.db.QueryRowContext(ctx, s.rebind(windowQ), requesterID, nowMs-limits.WindowMs).Scan(&n); err != nil {
+			return SessionRequestRow{}, false, err
+		}
+		if n >= limits.MaxPerWindow {
+			return SessionRequestRow{}, false, ErrRateLimited
+		}
+	}
+
+	// The daily cap only applies to map-based relationship requests.
 	if source == SessionRequestSourceMap {
 		dayStartMs, dayEndMs := dayBoundsMsInResetTZ(nowMs)
 		countQ := `SELECT COUNT(*) FROM session_requests
@@ -85,13 +147,13 @@ func (s *Store) CreateSessionRequest(ctx context.Context, requesterID, addressee
 		case SessionRequestStatusAccepted:
 			return SessionRequestRow{}, false, ErrSessionExists
 		default:
-			if existing.Status == SessionRequestStatusRejected && nowMs-existing.UpdatedAtMs < 3*24*60*60*1000 {
+			if existing.Status == SessionRequestStatusRejected && nowMs-existing.UpdatedAtMs < limits.RejectCooldownMs {
 				return SessionRequestRow{}, false, ErrCooldownActive
 			}
 
 			// Re-open the request
 			updateQ := `UPDATE session_requests
-				SET status = ?, source = ?, verification_message = ?, updated_at_ms = ?, last_opened_at_ms = ?
+				SET status = ?, source = ?, verification_message = ?, updated_at_ms = ?, last_opened_at_ms = ?, seen_at_ms = NULL
 				WHERE id = ?;`
 			if _, err := s.db.ExecContext(ctx, s.rebind(updateQ), SessionRequestStatusPending, source, verificationMessage, nowMs, nowMs, existing.ID); err != nil {
 				return SessionRequestRow{}, false, err
@@ -101,6 +163,7 @@ func (s *Store) CreateSessionRequest(ctx context.Context, requesterID, addressee
 			existing.VerificationMessage = verificationMessage
 			existing.UpdatedAtMs = nowMs
 			existing.LastOpenedAtMs = nowMs
+			existing.SeenAtMs = nil
 			return existing, false, nil
 		}
 	}
@@ -122,11 +185,11 @@ func (s *Store) ListSessionRequests(ctx context.Context, userID, box, status str
 
 	switch box {
 	case "incoming":
-		q = `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms
+		q = `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms, seen_at_ms
 			FROM session_requests WHERE addressee_id = ?`
 		args = append(args, userID)
 	default:
-		q = `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms
+		q = `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms, seen_at_ms
 			FROM session_requests WHERE requester_id = ?`
 		args = append(args, userID)
 	}
@@ -145,8 +208,11 @@ func (s *Store) ListSessionRequests(ctx context.Context, userID, box, status str
 
 	var out []SessionRequestRow
 	for rows.Next() {
-		var r SessionRequestRow
-		if err := rows.Scan(&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs); err != nil {
+		var (
+			r      SessionRequestRow
+			seenAt sql.NullInt64
+		)
+		if err := rows.Scan(&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs, &seenAt); err != nil {
 			return nil, err
 		}
 		if r.Source == "" {
@@ -155,6 +221,9 @@ func (s *Store) ListSessionRequests(ctx context.Context, userID, box, status str
 		if r.LastOpenedAtMs == 0 {
 			r.LastOpenedAtMs = r.CreatedAtMs
 		}
+		if seenAt.Valid {
+			r.SeenAtMs = &seenAt.Int64
+		}
 		out = append(out, r)
 	}
 	if err := rows.Err(); err != nil {
@@ -163,6 +232,25 @@ func (s *Store) ListSessionRequests(ctx context.Context, userID, box, status str
 	return out, nil
 }
 
+// MarkAllSessionRequestsSeen stamps seen_at_ms on every pending incoming request the user hasn't seen yet.
+// This is deliberately separate from last_opened_at_ms, which tracks (re)opens for the map request rate limit.
+func (s *Store) MarkAllSessionRequestsSeen(ctx context.Context, userID string, nowMs int64) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, fmt.Errorf("db not initialized")
+	}
+	if userID == "" {
+		return 0, fmt.Errorf("missing userID")
+	}
+
+	q := `UPDATE session_requests SET seen_at_ms = ?
+		WHERE addressee_id = ? AND status = ? AND seen_at_ms IS NULL;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), nowMs, userID, SessionRequestStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (s *Store) AcceptSessionRequest(ctx context.Context, requestID, userID string, nowMs int64) (SessionRequestRow, *SessionRow, error) {
 	return s.mutateSessionRequest(ctx, requestID, userID, nowMs, "accept")
 }
@@ -304,11 +392,14 @@ func normalizeBox(box string) string {
 }
 
 func (s *Store) getSessionRequestByPair(ctx context.Context, requesterID, addresseeID string) (SessionRequestRow, error) {
-	q := `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms
+	q := `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms, seen_at_ms
 		FROM session_requests WHERE requester_id = ? AND addressee_id = ?;`
-	var r SessionRequestRow
+	var (
+		r      SessionRequestRow
+		seenAt sql.NullInt64
+	)
 	if err := s.db.QueryRowContext(ctx, s.rebind(q), requesterID, addresseeID).Scan(
-		&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs,
+		&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs, &seenAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return SessionRequestRow{}, fmt.Errorf("%w: session request", ErrNotFound)
@@ -321,15 +412,21 @@ func (s *Store) getSessionRequestByPair(ctx context.Context, requesterID, addres
 	if r.LastOpenedAtMs == 0 {
 		r.LastOpenedAtMs = r.CreatedAtMs
 	}
+	if seenAt.Valid {
+		r.SeenAtMs = &seenAt.Int64
+	}
 	return r, nil
 }
 
 func getSessionRequestByID(ctx context.Context, q sqlQueryer, driver, id string) (SessionRequestRow, error) {
-	query := rebindQuery(driver, `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms
+	query := rebindQuery(driver, `SELECT id, requester_id, addressee_id, status, source, verification_message, created_at_ms, updated_at_ms, last_opened_at_ms, seen_at_ms
 		FROM session_requests WHERE id = ?;`)
-	var r SessionRequestRow
+	var (
+		r      SessionRequestRow
+		seenAt sql.NullInt64
+	)
 	if err := q.QueryRowContext(ctx, query, id).Scan(
-		&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs,
+		&r.ID, &r.RequesterID, &r.AddresseeID, &r.Status, &r.Source, &r.VerificationMessage, &r.CreatedAtMs, &r.UpdatedAtMs, &r.LastOpenedAtMs, &seenAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return SessionRequestRow{}, fmt.Errorf("%w: session request", ErrNotFound)
@@ -342,6 +439,9 @@ func getSessionRequestByID(ctx context.Context, q sqlQueryer, driver, id string)
 	if r.LastOpenedAtMs == 0 {
 		r.LastOpenedAtMs = r.CreatedAtMs
 	}
+	if seenAt.Valid {
+		r.SeenAtMs = &seenAt.Int64
+	}
 	return r, nil
 }
 
@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 )
@@ -90,3 +92,107 @@ func TestCreateSessionRequest_CooldownAfterReject(t *testing.T) {
 		t.Fatalf("CreateSessionRequest(after cooldown) created = true, want false (re-open)")
 	}
 }
+
+func TestCreateSessionRequestWithLimits_SlidingWindowAndCooldown(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	base := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	limits := SessionRequestLimits{
+		WindowMs:         60 * 1000,
+		MaxPerWindow:     3,
+		RejectCooldownMs: 60 * 60 * 1000,
+	}
+
+	requester, err := store.CreateUser(ctx, "req", "hash", "Requester", base)
+	if err != nil {
+		t.Fatalf("CreateUser(requester) error = %v", err)
+	}
+	addressees := make([]UserRow, 5)
+	for i := range addressees {
+		addressees[i], err = store.CreateUser(ctx, "w"+string(rune('a'+i)), "hash", "User", base)
+		if err != nil {
+			t.Fatalf("CreateUser(%d) error = %v", i, err)
+		}
+	}
+
+	// Invite-code requests are not covered by the map daily cap but still count here.
+	var first SessionRequestRow
+	for i := 0; i < 3; i++ {
+		req, _, err := store.CreateSessionRequestWithLimits(ctx, requester.ID, addressees[i].ID, SessionRequestSourceWeChatCode, nil, limits, base+int64(i)*1000)
+		if err != nil {
+			t.Fatalf("CreateSessionRequestWithLimits(%d) error = %v", i, err)
+		}
+		if i == 0 {
+			first = req
+		}
+	}
+	if _, _, err := store.CreateSessionRequestWithLimits(ctx, requester.ID, addressees[3].ID, SessionRequestSourceWeChatCode, nil, limits, base+5_000); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("CreateSessionRequestWithLimits(over window) error = %v, want ErrRateLimited", err)
+	}
+
+	// Once the first request slides out of the window another one fits.
+	if _, _, err := store.CreateSessionRequestWithLimits(ctx, requester.ID, addressees[3].ID, SessionRequestSourceWeChatCode, nil, limits, base+60_001); err != nil {
+		t.Fatalf("CreateSessionRequestWithLimits(after window) error = %v", err)
+	}
+
+	rejectAt := base + 61_000
+	if _, err := store.RejectSessionRequest(ctx, first.ID, addressees[0].ID, rejectAt); err != nil {
+		t.Fatalf("RejectSessionRequest() error = %v", err)
+	}
+	if _, _, err := store.CreateSessionRequestWithLimits(ctx, requester.ID, addressees[0].ID, SessionRequestSourceWeChatCode, nil, limits, rejectAt+30*60*1000); !errors.Is(err, ErrCooldownActive) {
+		t.Fatalf("CreateSessionRequestWithLimits(within cooldown) error = %v, want ErrCooldownActive", err)
+	}
+	if _, created, err := store.CreateSessionRequestWithLimits(ctx, requester.ID, addressees[0].ID, SessionRequestSourceWeChatCode, nil, limits, rejectAt+60*60*1000+1); err != nil || created {
+		t.Fatalf("CreateSessionRequestWithLimits(after cooldown) = %v, %v; want re-open", created, err)
+	}
+}
+
+func TestCreateSessionRequest_VerificationMessageRoundTrips(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	requester, err := store.CreateUser(ctx, "req", "hash", "Requester", now)
+	if err != nil {
+		t.Fatalf("CreateUser(requester) error = %v", err)
+	}
+	addressee, err := store.CreateUser(ctx, "addr", "hash", "Addressee", now)
+	if err != nil {
+		t.Fatalf("CreateUser(addressee) error = %v", err)
+	}
+
+	tooLong := strings.Repeat("嗨", MaxVerificationMessageLen+1)
+	if _, _, err := store.CreateSessionRequest(ctx, requester.ID, addressee.ID, SessionRequestSourceMap, &tooLong, now); err == nil {
+		t.Fatalf("CreateSessionRequest(too long) error = nil, want error")
+	}
+
+	greeting := "  你好，我是咖啡店遇到的那位  "
+	if _, _, err := store.CreateSessionRequest(ctx, requester.ID, addressee.ID, SessionRequestSourceMap, &greeting, now); err != nil {
+		t.Fatalf("CreateSessionRequest() error = %v", err)
+	}
+
+	incoming, err := store.ListSessionRequests(ctx, addressee.ID, "incoming", SessionRequestStatusPending)
+	if err != nil {
+		t.Fatalf("ListSessionRequests() error = %v", err)
+	}
+	if len(incoming) != 1 {
+		t.Fatalf("incoming len = %d, want 1", len(incoming))
+	}
+	if got := incoming[0].VerificationMessage; got == nil || *got != "你好，我是咖啡店遇到的那位" {
+		t.Fatalf("VerificationMessage = %v, want trimmed greeting", got)
+	}
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestMarkAllSessionRequestsSeen_OnlyPendingIncoming(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	me, err := store.CreateUser(ctx, "me", "hash", "Me", now)
+	if err != nil {
+		t.Fatalf("CreateUser(me) error = %v", err)
+	}
+	var requesters []UserRow
+	for _, name := range []string{"r1", "r2", "r3"} {
+		u, err := store.CreateUser(ctx, name, "hash", name, now)
+		if err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", name, err)
+		}
+		requesters = append(requesters, u)
+	}
+
+	var reqIDs []string
+	for _, u := range requesters {
+		req, _, err := store.CreateSessionRequest(ctx, u.ID, me.ID, SessionRequestSourceWeChatCode, nil, now)
+		if err != nil {
+			t.Fatalf("CreateSessionRequest() error = %v", err)
+		}
+		reqIDs = append(reqIDs, req.ID)
+	}
+	if _, err := store.RejectSessionRequest(ctx, reqIDs[2], me.ID, now+500); err != nil {
+		t.Fatalf("RejectSessionRequest() error = %v", err)
+	}
+
+	n, err := store.MarkAllSessionRequestsSeen(ctx, me.ID, now+1000)
+	if err != nil {
+		t.Fatalf("MarkAllSessionRequestsSeen() error = %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("updated = %d, want 2", n)
+	}
+
+	// Already-seen requests are not stamped again.
+	n, err = store.MarkAllSessionRequestsSeen(ctx, me.ID, now+2000)
+	if err != nil {
+		t.Fatalf("MarkAllSessionRequestsSeen(again) error = %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("updated(again) = %d, want 0", n)
+	}
+
+	reqs, err := store.ListSessionRequests(ctx, me.ID, "incoming", SessionRequestStatusPending)
+	if err != nil {
+		t.Fatalf("ListSessionRequests() error = %v", err)
+	}
+	for _, r := range reqs {
+		if r.SeenAtMs == nil || *r.SeenAtMs != now+1000 {
+			t.Fatalf("request %s SeenAtMs = %v, want %d", r.ID, r.SeenAtMs, now+1000)
+		}
+		if r.LastOpenedAtMs != now {
+			t.Fatalf("request %s LastOpenedAtMs = %d, want %d", r.ID, r.LastOpenedAtMs, now)
+		}
+	}
+}
@@ -61,6 +61,79 @@ func (s *Store) GetSessionUserMeta(ctx context.Context, sessionID, userID string
 	return row, nil
 }
 
+// ListSessionRelationshipsForUser returns userID's note, group and tags for each of
+// sessionIDs in one query, keyed by session ID. Sessions without meta are absent from the map.
+func (s *Store) ListSessionRelationshipsForUser(ctx context.Context, userID string, sessionIDs []string) (map[string]SessionUserMetaRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	args := make([]any, 0, len(sessionIDs)+1)
+	args = append(args, userID)
+	for _, id := range sessionIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		args = append(args, id)
+	}
+	if len(args) == 1 {
+		return map[string]SessionUserMetaRow{}, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)-1), ",")
+	q := fmt.Sprintf(`SELECT
+			m.session_id,
+			m.user_id,
+			m.note,
+			m.group_id,
+			g.name,
+			m.tags_json,
+			m.created_at_ms,
+			m.updated_at_ms
+		FROM session_user_meta m
+		LEFT JOIN relationship_groups g ON g.id = m.group_id
+		WHERE m.user_id = ? AND m.session_id IN (%s);`, placeholders)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]SessionUserMetaRow, len(args)-1)
+	for rows.Next() {
+		var (
+			row       SessionUserMetaRow
+			note      sql.NullString
+			groupID   sql.NullString
+			groupName sql.NullString
+		)
+		if err := rows.Scan(
+			&row.SessionID, &row.UserID, &note, &groupID, &groupName, &row.TagsJSON, &row.CreatedAtMs, &row.UpdatedAtMs,
+		); err != nil {
+			return nil, err
+		}
+		if note.Valid {
+			row.Note = &note.String
+		}
+		if groupID.Valid {
+			row.GroupID = &groupID.String
+		}
+		if groupName.Valid {
+			row.GroupName = &groupName.String
+		}
+		if strings.TrimSpace(row.TagsJSON) == "" {
+			row.TagsJSON = "[]"
+		}
+		out[row.SessionID] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (s *Store) UpsertSessionUserMeta(ctx context.Context, sessionID, userID string, note *string, groupID *string, tags []string, nowMs int64) (SessionUserMetaRow, error) {
 	if s == nil || s.db == nil {
 		return SessionUserMetaRow{}, fmt.Errorf("db not initialized")
@@ -155,17 +228,24 @@ func normalizeNullableID(id *string) *string {
 	return &v
 }
 
+// NormalizeTag trims a relationship tag and cuts it to the stored length. Tags compare
+// case-insensitively.
+func NormalizeTag(t string) string {
+	t = strings.TrimSpace(t)
+	if len(t) > 20 {
+		t = t[:20]
+	}
+	return t
+}
+
 func normalizeTagsJSON(tags []string) (string, error) {
 	normalized := make([]string, 0, len(tags))
 	seen := make(map[string]struct{}, len(tags))
 	for _, t := range tags {
-		t = strings.TrimSpace(t)
+		t = NormalizeTag(t)
 		if t == "" {
 			continue
 		}
-		if len(t) > 20 {
-			t = t[:20]
-		}
 		key := strings.ToLower(t)
 		if _, ok := seen[key]; ok {
 			continue
@@ -196,3 +276,61 @@ func ParseTagsJSON(raw string) []string {
 	}
 	return out
 }
+
+// tagLikePattern matches tags_json rows containing tag as a whole array element. Both sides
+// are lower-cased by the caller's query, so the match is case-insensitive like dedupe.
+func tagLikePattern(tag string) (string, error) {
+	b, err := json.Marshal(strings.ToLower(tag))
+	if err != nil {
+		return "", err
+	}
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(string(b))
+	return "%" + escaped + "%", nil
+}
+
+// ListUserTags returns the distinct tags userID put on their sessions with how many sessions
+// carry each, most used first. Tags differing only in case are counted together under the
+// spelling used most recently.
+func (s *Store) ListUserTags(ctx context.Context, userID string) ([]UserTagRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT tags_json FROM session_user_meta
+		WHERE user_id = ? AND tags_json IS NOT NULL AND tags_json <> '' AND tags_json <> '[]'
+		ORDER BY updated_at_ms DESC;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UserTagRow
+	index := map[string]int{}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		for _, tag := range ParseTagsJSON(raw) {
+			key := strings.ToLower(tag)
+			if i, ok := index[key]; ok {
+				out[i].SessionCount++
+				continue
+			}
+			index[key] = len(out)
+			out = append(out, UserTagRow{Tag: tag, SessionCount: 1})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].SessionCount != out[j].SessionCount {
+			return out[i].SessionCount > out[j].SessionCount
+		}
+		return strings.ToLower(out[i].Tag) < strings.ToLower(out[j].Tag)
+	})
+	return out, nil
+}
@@ -144,23 +144,86 @@ func (s *Store) GetSessionByID(ctx context.Context, sessionID string) (SessionRo
 }
 
 func (s *Store) ListSessionsForUser(ctx context.Context, userID, status string) ([]SessionRow, error) {
+	sessions, _, err := s.ListSessionsForUserPage(ctx, userID, status, SessionListOptions{})
+	return sessions, err
+}
+
+// SessionListOptions pages through a user's sessions. Limit <= 0 returns every session.
+// Cursor is the opaque nextCursor of the previous page. A non-empty GroupID keeps only the
+// sessions the user assigned to that relationship group; callers check it is the user's.
+// A non-empty Tag keeps only the sessions the user tagged with it, ignoring case.
+type SessionListOptions struct {
+	Limit   int
+	Cursor  string
+	GroupID string
+	Tag     string
+}
+
+// ListSessionsForUserPage lists the user's direct sessions and the ad-hoc group chats they
+// are an active member of, newest-updated first. Activity chats are listed with their
+// activities instead. Ties on updated_at_ms are broken by id so the order is total, and the
+// cursor carries both keys because updated_at_ms moves whenever a session gets a new message.
+func (s *Store) ListSessionsForUserPage(ctx context.Context, userID, status string, opts SessionListOptions) ([]SessionRow, string, error) {
 	if s == nil || s.db == nil {
-		return nil, fmt.Errorf("db not initialized")
+		return nil, "", fmt.Errorf("db not initialized")
 	}
 
-	q := `SELECT id, participants_hash, user1_id, user2_id, source, kind, status, last_message_text, last_message_at_ms, created_at_ms, updated_at_ms, hidden_by_users, reactivated_at_ms
+	q := `SELECT id, participants_hash, user1_id, user2_id, source, kind, status, last_message_text, last_message_at_ms, created_at_ms, updated_at_ms, hidden_by_users, reactivated_at_ms,
+			` + unreadCountExpr + ` AS unread_count
 		FROM sessions
-		WHERE kind = ? AND (user1_id = ? OR user2_id = ?) AND status = ?
-		AND (hidden_by_users IS NULL OR hidden_by_users NOT LIKE '%' || ? || '%')
-		ORDER BY updated_at_ms DESC;`
+		WHERE status = ?
+		AND (
+			(kind = ? AND (user1_id = ? OR user2_id = ?))
+			OR (kind = ? AND source = ? AND EXISTS (
+				SELECT 1 FROM session_participants sp
+				WHERE sp.session_id = sessions.id AND sp.user_id = ? AND sp.status = ?))
+		)
+		AND (hidden_by_users IS NULL OR hidden_by_users NOT LIKE '%' || ? || '%')`
+	args := []any{
+		userID, userID, status,
+		SessionKindDirect, userID, userID,
+		SessionKindGroup, SessionSourceGroup, userID, SessionParticipantStatusActive,
+		userID,
+	}
+
+	if groupID := strings.TrimSpace(opts.GroupID); groupID != "" {
+		q += ` AND EXISTS (
+			SELECT 1 FROM session_user_meta m
+			WHERE m.session_id = sessions.id AND m.user_id = ? AND m.group_id = ?)`
+		args = append(args, userID, groupID)
+	}
+	if tag := NormalizeTag(opts.Tag); tag != "" {
+		pattern, err := tagLikePattern(tag)
+		if err != nil {
+			return nil, "", err
+		}
+		q += ` AND EXISTS (
+			SELECT 1 FROM session_user_meta m
+			WHERE m.session_id = sessions.id AND m.user_id = ? AND LOWER(m.tags_json) LIKE ? ESCAPE '!')`
+		args = append(args, userID, pattern)
+	}
+	if cursor := strings.TrimSpace(opts.Cursor); cursor != "" {
+		cursorUpdatedAt, cursorID, ok := parseUpdatedAtCursor(cursor)
+		if !ok {
+			return nil, "", ErrInvalidCursor
+		}
+		q += ` AND (updated_at_ms < ? OR (updated_at_ms = ? AND id < ?))`
+		args = append(args, cursorUpdatedAt, cursorUpdatedAt, cursorID)
+	}
+	q += ` ORDER BY updated_at_ms DESC, id DESC`
+	if opts.Limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, opts.Limit+1)
+	}
 
-	rows, err := s.db.QueryContext(ctx, s.rebind(q), SessionKindDirect, userID, userID, status, userID)
+	rows, err := s.db.QueryContext(ctx, s.rebind(q+";"), args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	var sessions []SessionRow
+	hasMore := false
 	for rows.Next() {
 		var session SessionRow
 		var lastText sql.NullString
@@ -170,9 +233,14 @@ func (s *Store) ListSessionsForUser(ctx context.Context, userID, status string)
 		if err := rows.Scan(
 			&session.ID, &session.ParticipantsHash, &session.User1ID, &session.User2ID,
 			&session.Source, &session.Kind, &session.Status, &lastText, &lastAtMs, &session.CreatedAtMs, &session.UpdatedAtMs,
-			&hiddenBy, &reactivatedAt,
+			&hiddenBy, &reactivatedAt, &session.UnreadCount,
 		); err != nil {
-			return nil, err
+			return nil, "", err
+		}
+		if opts.Limit > 0 && len(sessions) == opts.Limit {
+			// The extra row only signals that another page exists.
+			hasMore = true
+			continue
 		}
 		if lastText.Valid {
 			session.LastMessageText = &lastText.String
@@ -189,10 +257,15 @@ func (s *Store) ListSessionsForUser(ctx context.Context, userID, status string)
 		sessions = append(sessions, session)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return sessions, nil
+	var nextCursor string
+	if hasMore {
+		last := sessions[len(sessions)-1]
+		nextCursor = formatUpdatedAtCursor(last.UpdatedAtMs, last.ID)
+	}
+	return sessions, nextCursor, nil
 }
 
 func (s *Store) ArchiveSession(ctx context.Context, sessionID, userID string, nowMs int64) (SessionRow, error) {
@@ -238,9 +311,9 @@ func (s *Store) IsSessionParticipant(ctx context.Context, sessionID, userID stri
 	switch kind {
 	case SessionKindGroup:
 		const memberQ = `SELECT 1 FROM session_participants
-			WHERE session_id = ? AND user_id = ? AND status = 'active';`
+			WHERE session_id = ? AND user_id = ? AND status = ?;`
 		var one int
-		if err := s.db.QueryRowContext(ctx, s.rebind(memberQ), sessionID, userID).Scan(&one); err != nil {
+		if err := s.db.QueryRowContext(ctx, s.rebind(memberQ), sessionID, userID, SessionParticipantStatusActive).Scan(&one); err != nil {
 			if err == sql.ErrNoRows {
 				return false, nil
 			}
@@ -248,18 +321,140 @@ func (s *Store) IsSessionParticipant(ctx context.Context, sessionID, userID stri
 		}
 		return true, nil
 	default:
-		// Default to direct session semantics.
+		// Default to direct session semantics. Group sessions never fall through here:
+		// user1/user2 on a group row are not a membership record.
 		return user1ID == userID || user2ID == userID, nil
 	}
 }
 
+// ListActiveSessionParticipantIDs returns the users currently in a group session.
+func (s *Store) ListActiveSessionParticipantIDs(ctx context.Context, sessionID string) ([]string, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	const q = `SELECT user_id FROM session_participants
+		WHERE session_id = ? AND status = ?
+		ORDER BY created_at_ms ASC;`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), sessionID, SessionParticipantStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		out = append(out, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ParticipantUserIDs returns everyone who should see sessionID's events: both sides of a
+// direct session, or the active members of a group session.
+func (s *Store) ParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error) {
+	session, err := s.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Kind == SessionKindGroup {
+		return s.ListActiveSessionParticipantIDs(ctx, sessionID)
+	}
+	return []string{session.User1ID, session.User2ID}, nil
+}
+
+// ListContactUserIDs returns the peers of userID's active direct sessions.
+func (s *Store) ListContactUserIDs(ctx context.Context, userID string) ([]string, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT DISTINCT CASE WHEN user1_id = ? THEN user2_id ELSE user1_id END
+		FROM sessions
+		WHERE kind = ? AND status = ? AND (user1_id = ? OR user2_id = ?);`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), userID, SessionKindDirect, SessionStatusActive, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPresenceContactUserIDs returns the contacts that may see userID's online status: the
+// peers of userID's active direct sessions, minus anyone on either side of a block.
+func (s *Store) ListPresenceContactUserIDs(ctx context.Context, userID string) ([]string, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	q := `SELECT DISTINCT c.peer_id
+		FROM (
+			SELECT CASE WHEN user1_id = ? THEN user2_id ELSE user1_id END AS peer_id
+			FROM sessions
+			WHERE kind = ? AND status = ? AND (user1_id = ? OR user2_id = ?)
+		) c
+		WHERE NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = ? AND b.blocked_id = c.peer_id) OR (b.blocker_id = c.peer_id AND b.blocked_id = ?)
+		);`
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), userID, SessionKindDirect, SessionStatusActive, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPeerUserID returns the other user of a direct session. Group sessions have no single
+// peer, so it returns "" for them.
 func (s *Store) GetPeerUserID(session SessionRow, currentUserID string) string {
+	if session.Kind == SessionKindGroup {
+		return ""
+	}
 	if session.User1ID == currentUserID {
 		return session.User2ID
 	}
 	return session.User1ID
 }
 
+// GetSessionByParticipants returns the direct session between two users, in either order.
+func (s *Store) GetSessionByParticipants(ctx context.Context, user1ID, user2ID string) (SessionRow, error) {
+	if s == nil || s.db == nil {
+		return SessionRow{}, fmt.Errorf("db not initialized")
+	}
+	return s.getSessionByParticipants(ctx, user1ID, user2ID)
+}
+
 func (s *Store) getSessionByParticipants(ctx context.Context, user1ID, user2ID string) (SessionRow, error) {
 	hash := computeParticipantsHash(user1ID, user2ID)
 	return s.getSessionByHash(ctx, hash)
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"os"
 	"testing"
@@ -90,3 +92,159 @@ func TestSessionReactivateAndHide(t *testing.T) {
 
 	t.Log("All tests passed!")
 }
+
+func TestListSessionsForUserPage_EqualTimestampsPageDeterministically(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	store, err := Open(context.Background(), "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	me, err := store.CreateUser(ctx, "me", "hash", "Me", nowMs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{}
+	for _, name := range []string{"p1", "p2", "p3", "p4", "p5"} {
+		peer, err := store.CreateUser(ctx, name, "hash", name, nowMs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every session shares the same updated_at_ms.
+		session, _, err := store.CreateSession(ctx, me.ID, peer.ID, nowMs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[session.ID] = true
+	}
+
+	seen := map[string]bool{}
+	var order []string
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		sessions, next, err := store.ListSessionsForUserPage(ctx, me.ID, SessionStatusActive, SessionListOptions{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListSessionsForUserPage() error = %v", err)
+		}
+		for _, s := range sessions {
+			if seen[s.ID] {
+				t.Fatalf("session %s returned twice", s.ID)
+			}
+			seen[s.ID] = true
+			order = append(order, s.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("paged %d sessions, want %d", len(seen), len(want))
+	}
+
+	all, err := store.ListSessionsForUser(ctx, me.ID, SessionStatusActive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range all {
+		if s.ID != order[i] {
+			t.Fatalf("unpaged order %d = %s, paged = %s", i, s.ID, order[i])
+		}
+	}
+
+	if _, _, err := store.ListSessionsForUserPage(ctx, me.ID, SessionStatusActive, SessionListOptions{Limit: 2, Cursor: "garbage"}); err != ErrInvalidCursor {
+		t.Fatalf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestListSessionsForUserPage_UnreadCountFollowsReadMark(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	carol, err := store.CreateUser(ctx, "carol", "hash", "Carol", now)
+	if err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+	session, _, err := store.CreateSession(ctx, alice.ID, bob.ID, now)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	send := func(senderID, text string, atMs int64) MessageRow {
+		t.Helper()
+		msg, err := store.CreateMessage(ctx, session.ID, senderID, MessageTypeText, &text, nil, atMs)
+		if err != nil {
+			t.Fatalf("CreateMessage(%q) error = %v", text, err)
+		}
+		return msg
+	}
+	unreadFor := func(userID string) int {
+		t.Helper()
+		sessions, _, err := store.ListSessionsForUserPage(ctx, userID, SessionStatusActive, SessionListOptions{})
+		if err != nil {
+			t.Fatalf("ListSessionsForUserPage() error = %v", err)
+		}
+		if len(sessions) != 1 {
+			t.Fatalf("sessions = %d, want 1", len(sessions))
+		}
+		return sessions[0].UnreadCount
+	}
+
+	send(alice.ID, "hi", now+1)
+	unsent := send(alice.ID, "oops", now+2)
+	send(alice.ID, "there", now+3)
+	send(bob.ID, "hello", now+4)
+
+	if _, err := store.DeleteMessage(ctx, session.ID, unsent.ID, alice.ID, now+5); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if got := unreadFor(bob.ID); got != 2 {
+		t.Fatalf("bob unread = %d, want 2 (own and unsent messages excluded)", got)
+	}
+	if got := unreadFor(alice.ID); got != 1 {
+		t.Fatalf("alice unread = %d, want 1", got)
+	}
+
+	if advanced, err := store.MarkSessionRead(ctx, session.ID, bob.ID, now+10); err != nil || !advanced {
+		t.Fatalf("MarkSessionRead() = %v, %v, want advanced", advanced, err)
+	}
+	if got := unreadFor(bob.ID); got != 0 {
+		t.Fatalf("bob unread after read = %d, want 0", got)
+	}
+
+	// An older mark must not move the read position backwards.
+	if advanced, err := store.MarkSessionRead(ctx, session.ID, bob.ID, now); err != nil || advanced {
+		t.Fatalf("MarkSessionRead(stale) = %v, %v, want not advanced", advanced, err)
+	}
+	send(alice.ID, "new", now+11)
+	if got := unreadFor(bob.ID); got != 1 {
+		t.Fatalf("bob unread after new message = %d, want 1", got)
+	}
+	if n, err := store.CountUnreadMessages(ctx, session.ID, bob.ID); err != nil || n != 1 {
+		t.Fatalf("CountUnreadMessages() = %d, %v, want 1", n, err)
+	}
+
+	if _, err := store.MarkSessionRead(ctx, session.ID, carol.ID, now+12); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("MarkSessionRead(outsider) error = %v, want ErrAccessDenied", err)
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -53,3 +54,29 @@ func rebindToPostgres(query string) string {
 
 	return b.String()
 }
+
+// escapeLikePattern escapes LIKE wildcards so user input matches literally.
+// Queries using it must declare ESCAPE '\'.
+func escapeLikePattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// formatUpdatedAtCursor builds the keyset cursor for lists ordered by (updated_at_ms DESC, id DESC).
+// Both keys are needed: updated_at_ms alone is not unique, and looking the row up by id would
+// see a newer timestamp if it changed between pages.
+func formatUpdatedAtCursor(updatedAtMs int64, id string) string {
+	return fmt.Sprintf("%d:%s", updatedAtMs, id)
+}
+
+func parseUpdatedAtCursor(cursor string) (int64, string, bool) {
+	rawUpdatedAt, id, ok := strings.Cut(cursor, ":")
+	if !ok || id == "" {
+		return 0, "", false
+	}
+	updatedAt, err := strconv.ParseInt(rawUpdatedAt, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return updatedAt, id, true
+}
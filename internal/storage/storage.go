@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -20,7 +21,46 @@ type Store struct {
 	logger *slog.Logger
 }
 
+// Connection pool defaults for server databases (Postgres). SQLite always uses one connection.
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 30 * time.Minute
+	DefaultConnMaxIdleTime = 5 * time.Minute
+)
+
+// OpenOptions tunes OpenWithOptions. The zero value matches Open.
+type OpenOptions struct {
+	// ObserveQuery, when set, is called with the duration of every database call.
+	ObserveQuery QueryObserver
+
+	// Pool limits; zero uses the Default* values. They are ignored for SQLite, which the store
+	// drives through a single connection (an in-memory database exists per connection, and
+	// writes would otherwise contend for the file lock).
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// PoolStats is a snapshot of the connection pool, for diagnostics.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections"`
+	OpenConnections    int   `json:"openConnections"`
+	InUse              int   `json:"inUse"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"waitCount"`
+	WaitDurationMs     int64 `json:"waitDurationMs"`
+	MaxIdleClosed      int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64 `json:"maxLifetimeClosed"`
+}
+
 func Open(ctx context.Context, databaseURL string, logger *slog.Logger) (*Store, error) {
+	return OpenWithOptions(ctx, databaseURL, logger, OpenOptions{})
+}
+
+func OpenWithOptions(ctx context.Context, databaseURL string, logger *slog.Logger, opts OpenOptions) (*Store, error) {
 	if strings.TrimSpace(databaseURL) == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
@@ -38,6 +78,17 @@ func Open(ctx context.Context, databaseURL string, logger *slog.Logger) (*Store,
 	if err != nil {
 		return nil, err
 	}
+	if opts.ObserveQuery != nil {
+		var connector driver.Connector = dsnConnector{dsn: dsn, drv: db.Driver()}
+		if dc, ok := db.Driver().(driver.DriverContext); ok {
+			if connector, err = dc.OpenConnector(dsn); err != nil {
+				_ = db.Close()
+				return nil, err
+			}
+		}
+		_ = db.Close()
+		db = sql.OpenDB(instrumentedConnector{Connector: connector, observe: opts.ObserveQuery})
+	}
 
 	store := &Store{
 		db:     db,
@@ -45,11 +96,7 @@ func Open(ctx context.Context, databaseURL string, logger *slog.Logger) (*Store,
 		logger: logger,
 	}
 
-	switch driverName {
-	case "sqlite":
-		db.SetMaxOpenConns(1)
-		db.SetMaxIdleConns(1)
-	}
+	applyPoolSettings(db, driverName, opts)
 
 	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -63,7 +110,9 @@ func Open(ctx context.Context, databaseURL string, logger *slog.Logger) (*Store,
 		return nil, err
 	}
 
-	if err := initSchema(pingCtx, db, driverName); err != nil {
+	migrateCtx, cancelMigrate := context.WithTimeout(ctx, schemaMigrationTimeout)
+	defer cancelMigrate()
+	if err := migrateSchema(migrateCtx, db, driverName); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
@@ -71,6 +120,65 @@ func Open(ctx context.Context, databaseURL string, logger *slog.Logger) (*Store,
 	return store, nil
 }
 
+func applyPoolSettings(db *sql.DB, driver string, opts OpenOptions) {
+	if driver == "sqlite" {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		return
+	}
+
+	maxOpen := opts.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = DefaultMaxOpenConns
+	}
+	maxIdle := opts.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = DefaultMaxIdleConns
+	}
+	lifetime := opts.ConnMaxLifetime
+	if lifetime <= 0 {
+		lifetime = DefaultConnMaxLifetime
+	}
+	idleTime := opts.ConnMaxIdleTime
+	if idleTime <= 0 {
+		idleTime = DefaultConnMaxIdleTime
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(min(maxIdle, maxOpen))
+	db.SetConnMaxLifetime(lifetime)
+	db.SetConnMaxIdleTime(idleTime)
+}
+
+// PoolStats reports the current connection pool usage.
+func (s *Store) PoolStats() PoolStats {
+	if s == nil || s.db == nil {
+		return PoolStats{}
+	}
+	st := s.db.Stats()
+	return PoolStats{
+		MaxOpenConnections: st.MaxOpenConnections,
+		OpenConnections:    st.OpenConnections,
+		InUse:              st.InUse,
+		Idle:               st.Idle,
+		WaitCount:          st.WaitCount,
+		WaitDurationMs:     st.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      st.MaxIdleClosed,
+		MaxIdleTimeClosed:  st.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  st.MaxLifetimeClosed,
+	}
+}
+
+// Migrate brings the database behind databaseURL up to the current schema and closes it. It is
+// for deploy steps that prepare the database before any API instance starts; Open runs the
+// same migrations, so calling it is optional.
+func Migrate(ctx context.Context, databaseURL string, logger *slog.Logger) error {
+	store, err := Open(ctx, databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	return store.Close()
+}
+
 func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
@@ -123,6 +231,10 @@ func driverAndDSN(u *url.URL, raw string) (driver string, dsn string, _ error) {
 		return "sqlite", dsn, nil
 	case "postgres", "postgresql":
 		return "pgx", raw, nil
+	case "mysql":
+		// The schema and upserts (ON CONFLICT, CREATE INDEX IF NOT EXISTS, TEXT keys) are written
+		// for SQLite and Postgres; MySQL needs its own dialect before it can be offered.
+		return "", "", fmt.Errorf("DATABASE_URL scheme mysql is not supported (use sqlite: or postgres://)")
 	default:
 		return "", "", fmt.Errorf("unsupported DATABASE_URL scheme %q (expected sqlite:// or postgres://)", u.Scheme)
 	}
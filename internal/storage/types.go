@@ -12,6 +12,7 @@ const (
 	SessionSourceMap        = "map"
 	SessionSourceActivity   = "activity"
 	SessionSourceManual     = "manual"
+	SessionSourceGroup      = "group"
 )
 
 const (
@@ -25,6 +26,8 @@ const (
 	MessageTypeFile   = "file"
 	MessageTypeSystem = "system"
 	MessageTypeBurn   = "burn"
+	// MessageTypeDeleted is reported for unsent messages; it is never stored as a type.
+	MessageTypeDeleted = "deleted"
 )
 
 const (
@@ -54,26 +57,31 @@ const (
 )
 
 var (
-	ErrNotFound          = errors.New("not found")
-	ErrUsernameExists    = errors.New("username exists")
-	ErrCannotChatSelf    = errors.New("cannot chat self")
-	ErrSessionExists     = errors.New("session exists")
-	ErrSessionNotFound   = errors.New("session not found")
-	ErrAccessDenied      = errors.New("access denied")
-	ErrTokenInvalid      = errors.New("token invalid")
-	ErrTokenExpired      = errors.New("token expired")
-	ErrInvalidState      = errors.New("invalid state")
-	ErrWeChatNotBound    = errors.New("wechat not bound")
-	ErrRequestExists     = errors.New("session request exists")
-	ErrInviteInvalid     = errors.New("session invite invalid")
-	ErrInviteExpired     = errors.New("invite expired")
-	ErrGeoFenceRequired  = errors.New("geo-fence location required")
-	ErrGeoFenceForbidden = errors.New("geo-fence forbidden")
-	ErrSessionArchived   = errors.New("session archived")
-	ErrRateLimited       = errors.New("rate limited")
-	ErrCooldownActive    = errors.New("cooldown active")
-	ErrHomeBaseLimited   = errors.New("home base update limited")
-	ErrGroupExists       = errors.New("relationship group exists")
+	ErrNotFound           = errors.New("not found")
+	ErrUsernameExists     = errors.New("username exists")
+	ErrCannotChatSelf     = errors.New("cannot chat self")
+	ErrSessionExists      = errors.New("session exists")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrAccessDenied       = errors.New("access denied")
+	ErrTokenInvalid       = errors.New("token invalid")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrInvalidState       = errors.New("invalid state")
+	ErrWeChatNotBound     = errors.New("wechat not bound")
+	ErrRequestExists      = errors.New("session request exists")
+	ErrInviteInvalid      = errors.New("session invite invalid")
+	ErrInviteExpired      = errors.New("invite expired")
+	ErrGeoFenceRequired   = errors.New("geo-fence location required")
+	ErrGeoFenceForbidden  = errors.New("geo-fence forbidden")
+	ErrSessionArchived    = errors.New("session archived")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrCooldownActive     = errors.New("cooldown active")
+	ErrHomeBaseLimited    = errors.New("home base update limited")
+	ErrGroupExists        = errors.New("relationship group exists")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrQuotaExceeded      = errors.New("quota exceeded")
+	ErrReactionLimit      = errors.New("reaction limit reached")
+	ErrReplyTargetInvalid = errors.New("reply target invalid")
+	ErrBlocked            = errors.New("blocked")
 )
 
 type UserRow struct {
@@ -108,6 +116,17 @@ type SessionRow struct {
 	UpdatedAtMs      int64
 	HiddenByUsers    *string
 	ReactivatedAtMs  *int64
+	// UnreadCount is only filled in by ListSessionsForUserPage, for the listing user.
+	UnreadCount int
+}
+
+type GroupSessionRow struct {
+	SessionID   string
+	CreatorID   string
+	Title       string
+	MemberCount int
+	CreatedAtMs int64
+	UpdatedAtMs int64
 }
 
 type MessageRow struct {
@@ -118,18 +137,79 @@ type MessageRow struct {
 	Text        *string
 	MetaJSON    []byte
 	CreatedAtMs int64
+	EditedAtMs  *int64
+	DeletedAtMs *int64
+
+	ReplyToMessageID *string
+	// ReplyTo previews the quoted message. It is nil when the quoted message is gone.
+	ReplyTo *MessageReplyPreview
+}
+
+type MessageReplyPreview struct {
+	ID       string
+	SenderID string
+	Type     string
+	Text     *string
+}
+
+type MessageReactionRow struct {
+	MessageID   string
+	UserID      string
+	Emoji       string
+	CreatedAtMs int64
 }
 
+// BurnMessageRow is a burn-after-reading message. In direct sessions RecipientID is the peer.
+// Group burn messages leave RecipientID empty and track each member in Recipients;
+// OpenedAtMs is then the first open and BurnAtMs the scheduled purge of the whole message.
 type BurnMessageRow struct {
 	MessageID   string
 	SessionID   string
 	SenderID    string
+	SessionKind string
 	RecipientID string
 	BurnAfterMs int64
 	OpenedAtMs  *int64
 	BurnAtMs    *int64
 	CreatedAtMs int64
 	UpdatedAtMs int64
+
+	Recipients []BurnRecipientRow
+}
+
+// IsGroup reports whether the message was sent to a group session.
+func (r BurnMessageRow) IsGroup() bool {
+	return r.SessionKind == SessionKindGroup
+}
+
+// AudienceIDs lists everyone who should hear about the message: the sender and every
+// recipient.
+func (r BurnMessageRow) AudienceIDs() []string {
+	if !r.IsGroup() {
+		return []string{r.SenderID, r.RecipientID}
+	}
+	ids := make([]string, 0, len(r.Recipients)+1)
+	ids = append(ids, r.SenderID)
+	for _, rec := range r.Recipients {
+		ids = append(ids, rec.UserID)
+	}
+	return ids
+}
+
+// Recipient returns userID's own countdown on a group burn message.
+func (r BurnMessageRow) Recipient(userID string) (BurnRecipientRow, bool) {
+	for _, rec := range r.Recipients {
+		if rec.UserID == userID {
+			return rec, true
+		}
+	}
+	return BurnRecipientRow{}, false
+}
+
+type BurnRecipientRow struct {
+	UserID     string
+	OpenedAtMs *int64
+	BurnAtMs   *int64
 }
 
 type CallRow struct {
@@ -141,6 +221,25 @@ type CallRow struct {
 	Status      string
 	CreatedAtMs int64
 	UpdatedAtMs int64
+	// AcceptedAtMs and EndedAtMs are set when the callee accepts and when the call ends.
+	AcceptedAtMs *int64
+	EndedAtMs    *int64
+	// SessionID is the activity chat of a group call and empty for direct calls. A group
+	// call's CallerID is its initiator and its CalleeID is empty.
+	SessionID string
+}
+
+// IsGroup reports whether the call is an activity group call.
+func (c CallRow) IsGroup() bool {
+	return c.SessionID != ""
+}
+
+// DurationMs is how long an accepted call lasted; 0 until it has ended.
+func (c CallRow) DurationMs() int64 {
+	if c.AcceptedAtMs == nil || c.EndedAtMs == nil || *c.EndedAtMs < *c.AcceptedAtMs {
+		return 0
+	}
+	return *c.EndedAtMs - *c.AcceptedAtMs
 }
 
 type WeChatBindingRow struct {
@@ -148,6 +247,14 @@ type WeChatBindingRow struct {
 	OpenID      string
 	SessionKey  string
 	UnionID     *string
+	PhoneNumber *string
+	UpdatedAtMs int64
+}
+
+type WeChatTokenRow struct {
+	AppID       string
+	AccessToken string
+	ExpiresAtMs int64
 	UpdatedAtMs int64
 }
 
@@ -161,6 +268,7 @@ type SessionRequestRow struct {
 	CreatedAtMs         int64
 	UpdatedAtMs         int64
 	LastOpenedAtMs      int64
+	SeenAtMs            *int64
 }
 
 type GeoFence struct {
@@ -183,6 +291,7 @@ type HomeBaseRow struct {
 	LatE7             int64
 	LngE7             int64
 	VisibilityRadiusM int
+	AreaLabel         *string
 	LastUpdatedYMD    int
 	DailyUpdateCount  int
 	CreatedAtMs       int64
@@ -206,6 +315,18 @@ type RelationshipGroupRow struct {
 	UpdatedAtMs int64
 }
 
+// RelationshipGroupStats summarizes the sessions assigned to a relationship group.
+type RelationshipGroupStats struct {
+	SessionCount     int
+	LastActivityAtMs int64
+}
+
+// UserTagRow is one of a user's relationship tags and the number of sessions carrying it.
+type UserTagRow struct {
+	Tag          string
+	SessionCount int
+}
+
 type SessionUserMetaRow struct {
 	SessionID   string
 	UserID      string
@@ -243,12 +364,21 @@ type ActivityInviteRow struct {
 	ActivityID  string
 	ExpiresAtMs *int64
 	GeoFence    *GeoFence
+	MaxUses     *int
+	UsedCount   int
 	CreatedAtMs int64
 	UpdatedAtMs int64
 }
 
+type ActivityInvitePreview struct {
+	Activity      ActivityRow
+	Session       SessionRow
+	AlreadyMember bool
+}
+
 const (
 	ActivityReminderStatusPending  = "pending"
+	ActivityReminderStatusSending  = "sending"
 	ActivityReminderStatusSent     = "sent"
 	ActivityReminderStatusFailed   = "failed"
 	ActivityReminderStatusCanceled = "canceled"
@@ -290,5 +420,35 @@ type LocalFeedPinRow struct {
 	LngE7       int64
 	DisplayName string
 	AvatarURL   *string
+	AreaLabel   *string
 	UpdatedAtMs int64
 }
+
+type AnnouncementRow struct {
+	ID          string
+	CreatedBy   string
+	Title       string
+	Body        string
+	StartsAtMs  int64
+	EndsAtMs    *int64
+	Dismissible bool
+	CreatedAtMs int64
+}
+
+type BlockedUserRow struct {
+	UserID      string
+	DisplayName string
+	AvatarURL   *string
+	CreatedAtMs int64
+}
+
+// IdempotencyKeyRow is a stored response for a client-supplied Idempotency-Key. StatusCode
+// is 0 while the original request is still being handled.
+type IdempotencyKeyRow struct {
+	UserID       string
+	Key          string
+	RequestPath  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAtMs  int64
+}
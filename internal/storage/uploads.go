@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UploadUsageRow tracks how many bytes of stored uploads a user owns. QuotaBytes is an
+// admin override; nil means the server default applies.
+type UploadUsageRow struct {
+	UserID      string
+	UsedBytes   int64
+	QuotaBytes  *int64
+	UpdatedAtMs int64
+}
+
+// LimitBytes returns the quota that applies to the user. Zero or less means unlimited.
+func (u UploadUsageRow) LimitBytes(defaultQuotaBytes int64) int64 {
+	if u.QuotaBytes != nil {
+		return *u.QuotaBytes
+	}
+	return defaultQuotaBytes
+}
+
+func (s *Store) GetUploadUsage(ctx context.Context, userID string) (UploadUsageRow, error) {
+	if s == nil || s.db == nil {
+		return UploadUsageRow{}, fmt.Errorf("db not initialized")
+	}
+	return getUploadUsage(ctx, s.db, s.driver, userID)
+}
+
+func getUploadUsage(ctx context.Context, q sqlQueryer, driver, userID string) (UploadUsageRow, error) {
+	row := UploadUsageRow{UserID: userID}
+	var quota sql.NullInt64
+	err := q.QueryRowContext(ctx, rebindQuery(driver, `SELECT used_bytes, quota_bytes, updated_at_ms FROM upload_usage WHERE user_id = ?;`), userID).
+		Scan(&row.UsedBytes, &quota, &row.UpdatedAtMs)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return row, nil
+		}
+		return UploadUsageRow{}, err
+	}
+	if quota.Valid {
+		row.QuotaBytes = &quota.Int64
+	}
+	return row, nil
+}
+
+// RecordUpload charges sizeBytes to userID and records the stored file name in one transaction.
+// When the charge would exceed the user's quota it returns ErrQuotaExceeded together with the
+// current usage so callers can report it.
+func (s *Store) RecordUpload(ctx context.Context, userID, name string, sizeBytes, defaultQuotaBytes, nowMs int64) (UploadUsageRow, error) {
+	if s == nil || s.db == nil {
+		return UploadUsageRow{}, fmt.Errorf("db not initialized")
+	}
+	if userID == "" || name == "" {
+		return UploadUsageRow{}, fmt.Errorf("missing required fields")
+	}
+	if sizeBytes < 0 {
+		return UploadUsageRow{}, fmt.Errorf("invalid size")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return UploadUsageRow{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ensureQ := `INSERT INTO upload_usage (user_id, used_bytes, quota_bytes, updated_at_ms)
+		VALUES (?, 0, NULL, ?)
+		ON CONFLICT(user_id) DO NOTHING;`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, ensureQ), userID, nowMs); err != nil {
+		return UploadUsageRow{}, err
+	}
+
+	// The quota check lives in the UPDATE so concurrent uploads cannot both squeeze under it.
+	chargeQ := `UPDATE upload_usage SET used_bytes = used_bytes + ?, updated_at_ms = ?
+		WHERE user_id = ? AND (COALESCE(quota_bytes, ?) <= 0 OR used_bytes + ? <= COALESCE(quota_bytes, ?));`
+	res, err := tx.ExecContext(txCtx, rebindQuery(s.driver, chargeQ),
+		sizeBytes, nowMs, userID, defaultQuotaBytes, sizeBytes, defaultQuotaBytes,
+	)
+	if err != nil {
+		return UploadUsageRow{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		usage, err := getUploadUsage(txCtx, tx, s.driver, userID)
+		if err != nil {
+			return UploadUsageRow{}, err
+		}
+		return usage, ErrQuotaExceeded
+	}
+
+	insertQ := `INSERT INTO uploads (name, user_id, size_bytes, created_at_ms) VALUES (?, ?, ?, ?);`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, insertQ), name, userID, sizeBytes, nowMs); err != nil {
+		return UploadUsageRow{}, err
+	}
+
+	usage, err := getUploadUsage(txCtx, tx, s.driver, userID)
+	if err != nil {
+		return UploadUsageRow{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return UploadUsageRow{}, err
+	}
+	return usage, nil
+}
+
+// ReleaseUpload drops the record for a stored file and credits its size back to the owner.
+// Cleanup jobs and failed writes call it once the file is gone.
+func (s *Store) ReleaseUpload(ctx context.Context, name string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(txCtx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		userID    string
+		sizeBytes int64
+	)
+	if err := tx.QueryRowContext(txCtx, rebindQuery(s.driver, `SELECT user_id, size_bytes FROM uploads WHERE name = ?;`), name).
+		Scan(&userID, &sizeBytes); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: upload", ErrNotFound)
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, `DELETE FROM uploads WHERE name = ?;`), name); err != nil {
+		return err
+	}
+
+	creditQ := `UPDATE upload_usage
+		SET used_bytes = CASE WHEN used_bytes > ? THEN used_bytes - ? ELSE 0 END, updated_at_ms = ?
+		WHERE user_id = ?;`
+	if _, err := tx.ExecContext(txCtx, rebindQuery(s.driver, creditQ), sizeBytes, sizeBytes, nowMs, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetUploadQuota sets or clears (quotaBytes == nil) a user's quota override.
+func (s *Store) SetUploadQuota(ctx context.Context, userID string, quotaBytes *int64, nowMs int64) (UploadUsageRow, error) {
+	if s == nil || s.db == nil {
+		return UploadUsageRow{}, fmt.Errorf("db not initialized")
+	}
+	if userID == "" {
+		return UploadUsageRow{}, fmt.Errorf("missing user id")
+	}
+	if quotaBytes != nil && *quotaBytes < 0 {
+		return UploadUsageRow{}, fmt.Errorf("invalid quota")
+	}
+
+	var quota any
+	if quotaBytes != nil {
+		quota = *quotaBytes
+	}
+	q := `INSERT INTO upload_usage (user_id, used_bytes, quota_bytes, updated_at_ms)
+		VALUES (?, 0, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			quota_bytes = excluded.quota_bytes,
+			updated_at_ms = excluded.updated_at_ms;`
+	if _, err := s.db.ExecContext(ctx, s.rebind(q), userID, quota, nowMs); err != nil {
+		return UploadUsageRow{}, err
+	}
+	return s.GetUploadUsage(ctx, userID)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestUploadUsage_RecordAndRelease(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	nowMs := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+	u, err := store.CreateUser(ctx, "uploader", "hash", "Uploader", nowMs)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if _, err := store.RecordUpload(ctx, u.ID, "a.png", 60, 100, nowMs); err != nil {
+		t.Fatalf("RecordUpload(a) error = %v", err)
+	}
+	usage, err := store.RecordUpload(ctx, u.ID, "b.png", 50, 100, nowMs)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("RecordUpload(b) error = %v, want ErrQuotaExceeded", err)
+	}
+	if usage.UsedBytes != 60 || usage.LimitBytes(100) != 100 {
+		t.Fatalf("usage = %+v, want used 60 limit 100", usage)
+	}
+
+	// The rejected upload left no record behind.
+	if err := store.ReleaseUpload(ctx, "b.png", nowMs); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReleaseUpload(b) error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.ReleaseUpload(ctx, "a.png", nowMs); err != nil {
+		t.Fatalf("ReleaseUpload(a) error = %v", err)
+	}
+	if usage, err = store.GetUploadUsage(ctx, u.ID); err != nil || usage.UsedBytes != 0 {
+		t.Fatalf("usage after release = %+v, %v; want 0 used", usage, err)
+	}
+
+	if _, err := store.RecordUpload(ctx, u.ID, "b.png", 50, 100, nowMs); err != nil {
+		t.Fatalf("RecordUpload(b) after release error = %v", err)
+	}
+
+	// A zero override lifts the cap for this user only.
+	zero := int64(0)
+	if _, err := store.SetUploadQuota(ctx, u.ID, &zero, nowMs); err != nil {
+		t.Fatalf("SetUploadQuota() error = %v", err)
+	}
+	if _, err := store.RecordUpload(ctx, u.ID, "c.png", 500, 100, nowMs); err != nil {
+		t.Fatalf("RecordUpload(c) with unlimited override error = %v", err)
+	}
+}
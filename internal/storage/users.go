@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -64,6 +65,58 @@ func (s *Store) GetUserByID(ctx context.Context, userID string) (UserRow, error)
 	return user, nil
 }
 
+// GetUsersByIDs loads several users in one query, keyed by id. Unknown ids are absent
+// from the result.
+func (s *Store) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]UserRow, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("db not initialized")
+	}
+
+	args := make([]any, 0, len(userIDs))
+	seen := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		args = append(args, id)
+	}
+	if len(args) == 0 {
+		return map[string]UserRow{}, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)), ",")
+	q := fmt.Sprintf(`SELECT id, username, password_hash, display_name, avatar_url, created_at_ms, updated_at_ms
+		FROM users WHERE id IN (%s);`, placeholders)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]UserRow, len(args))
+	for rows.Next() {
+		var user UserRow
+		var avatar sql.NullString
+		if err := rows.Scan(
+			&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName,
+			&avatar, &user.CreatedAtMs, &user.UpdatedAtMs,
+		); err != nil {
+			return nil, err
+		}
+		if avatar.Valid {
+			user.AvatarURL = &avatar.String
+		}
+		out[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (UserRow, error) {
 	if s == nil || s.db == nil {
 		return UserRow{}, fmt.Errorf("db not initialized")
@@ -90,19 +143,44 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (UserRow
 	return user, nil
 }
 
+const (
+	// MaxUserSearchQueryLen caps the search prefix, in runes.
+	MaxUserSearchQueryLen = 32
+	// MaxUserSearchLimit caps how many users one search returns.
+	MaxUserSearchLimit = 50
+)
+
+// SearchUsers returns users whose username or display name starts with query, ignoring case.
+// Exact username matches come first, then shorter usernames. PasswordHash is never loaded,
+// and deleted accounts (see DeleteUser) are left out. limit may exceed MaxUserSearchLimit by
+// one, so a caller that drops a row (such as the searching user) can still fill a full page.
 func (s *Store) SearchUsers(ctx context.Context, query string, limit int) ([]UserRow, error) {
 	if s == nil || s.db == nil {
 		return nil, fmt.Errorf("db not initialized")
 	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(query) > MaxUserSearchQueryLen {
+		query = string([]rune(query)[:MaxUserSearchQueryLen])
+	}
 	if limit <= 0 {
 		limit = 20
 	}
+	if limit > MaxUserSearchLimit+1 {
+		limit = MaxUserSearchLimit + 1
+	}
 
-	q := `SELECT id, username, password_hash, display_name, avatar_url, created_at_ms, updated_at_ms
-		FROM users WHERE username LIKE ? OR display_name LIKE ? LIMIT ?;`
+	q := `SELECT id, username, display_name, avatar_url, created_at_ms, updated_at_ms
+		FROM users
+		WHERE (LOWER(username) LIKE ? ESCAPE '\' OR LOWER(display_name) LIKE ? ESCAPE '\')
+			AND password_hash <> ''
+		ORDER BY CASE WHEN LOWER(username) = ? THEN 0 ELSE 1 END, LENGTH(username), username
+		LIMIT ?;`
 
-	pattern := "%" + query + "%"
-	rows, err := s.db.QueryContext(ctx, s.rebind(q), pattern, pattern, limit)
+	pattern := escapeLikePattern(query) + "%"
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), pattern, pattern, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +191,7 @@ func (s *Store) SearchUsers(ctx context.Context, query string, limit int) ([]Use
 		var user UserRow
 		var avatar sql.NullString
 		if err := rows.Scan(
-			&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName,
+			&user.ID, &user.Username, &user.DisplayName,
 			&avatar, &user.CreatedAtMs, &user.UpdatedAtMs,
 		); err != nil {
 			return nil, err
@@ -148,6 +226,46 @@ func (s *Store) UpdateUserDisplayName(ctx context.Context, userID, displayName s
 	return s.GetUserByID(ctx, userID)
 }
 
+func (s *Store) UpdateUserPasswordHash(ctx context.Context, userID, passwordHash string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if userID == "" || passwordHash == "" {
+		return fmt.Errorf("missing required fields")
+	}
+
+	q := `UPDATE users SET password_hash = ?, updated_at_ms = ? WHERE id = ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), passwordHash, nowMs, userID)
+	if err != nil {
+		return err
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("%w: user", ErrNotFound)
+	}
+	return nil
+}
+
+// ReplaceUserPasswordHash swaps the stored hash for newHash only if it still equals oldHash,
+// so a background rehash cannot overwrite a password changed in the meantime. It reports
+// whether the hash was replaced.
+func (s *Store) ReplaceUserPasswordHash(ctx context.Context, userID, oldHash, newHash string, nowMs int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	if userID == "" || oldHash == "" || newHash == "" {
+		return false, fmt.Errorf("missing required fields")
+	}
+
+	q := `UPDATE users SET password_hash = ?, updated_at_ms = ? WHERE id = ? AND password_hash = ?;`
+	result, err := s.db.ExecContext(ctx, s.rebind(q), newHash, nowMs, userID, oldHash)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
 func (s *Store) UpdateUserAvatarURL(ctx context.Context, userID string, avatarURL *string, nowMs int64) (UserRow, error) {
 	if s == nil || s.db == nil {
 		return UserRow{}, fmt.Errorf("db not initialized")
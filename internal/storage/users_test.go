@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestGetUsersByIDs_ReturnsKnownUsersOnly(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "hash", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+	bob, err := store.CreateUser(ctx, "bob", "hash", "Bob", now)
+	if err != nil {
+		t.Fatalf("CreateUser(bob) error = %v", err)
+	}
+	if _, err := store.CreateUser(ctx, "carol", "hash", "Carol", now); err != nil {
+		t.Fatalf("CreateUser(carol) error = %v", err)
+	}
+
+	users, err := store.GetUsersByIDs(ctx, []string{alice.ID, bob.ID, alice.ID, "missing", " "})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs() error = %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("GetUsersByIDs() len = %d, want 2", len(users))
+	}
+	if users[alice.ID].DisplayName != "Alice" || users[bob.ID].DisplayName != "Bob" {
+		t.Fatalf("GetUsersByIDs() = %+v, want alice and bob", users)
+	}
+	if _, ok := users["missing"]; ok {
+		t.Fatalf("GetUsersByIDs() returned unknown id")
+	}
+
+	empty, err := store.GetUsersByIDs(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("GetUsersByIDs(nil) = %v, %v; want empty map", empty, err)
+	}
+}
+
+func TestSearchUsers_PrefixMatchesNarrowResults(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	for _, u := range []struct{ username, displayName string }{
+		{"alexander", "Alexander"},
+		{"alex", "Alex"},
+		{"albert", "Albert"},
+		{"bob", "Alfred"},
+		{"malex", "Malex"},
+		{"al_x", "Underscore"},
+	} {
+		if _, err := store.CreateUser(ctx, u.username, "hash", u.displayName, now); err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", u.username, err)
+		}
+	}
+
+	usernames := func(query string, limit int) []string {
+		t.Helper()
+		users, err := store.SearchUsers(ctx, query, limit)
+		if err != nil {
+			t.Fatalf("SearchUsers(%q) error = %v", query, err)
+		}
+		out := make([]string, 0, len(users))
+		for _, u := range users {
+			if u.PasswordHash != "" {
+				t.Fatalf("SearchUsers(%q) returned a password hash for %s", query, u.Username)
+			}
+			out = append(out, u.Username)
+		}
+		return out
+	}
+
+	if got := usernames("al", 20); len(got) != 5 {
+		t.Fatalf("SearchUsers(al) = %v, want 5 users", got)
+	}
+	got := usernames("ALEX", 20)
+	if len(got) != 2 || got[0] != "alex" || got[1] != "alexander" {
+		t.Fatalf("SearchUsers(ALEX) = %v, want [alex alexander]", got)
+	}
+	if got := usernames("al_", 20); len(got) != 1 || got[0] != "al_x" {
+		t.Fatalf("SearchUsers(al_) = %v, want [al_x]", got)
+	}
+	if got := usernames("al", 2); len(got) != 2 {
+		t.Fatalf("SearchUsers(al, 2) = %v, want 2 users", got)
+	}
+}
+
+func TestReplaceUserPasswordHash_OnlyFromExpectedHash(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Date(2026, 1, 11, 10, 0, 0, 0, time.FixedZone("CST", 8*60*60)).UnixMilli()
+
+	alice, err := store.CreateUser(ctx, "alice", "old", "Alice", now)
+	if err != nil {
+		t.Fatalf("CreateUser(alice) error = %v", err)
+	}
+
+	// A password change lands between the login's compare and the rehash write.
+	if err := store.UpdateUserPasswordHash(ctx, alice.ID, "changed", now+1); err != nil {
+		t.Fatalf("UpdateUserPasswordHash() error = %v", err)
+	}
+	replaced, err := store.ReplaceUserPasswordHash(ctx, alice.ID, "old", "rehashed", now+2)
+	if err != nil || replaced {
+		t.Fatalf("ReplaceUserPasswordHash(stale) = %v, %v; want false, nil", replaced, err)
+	}
+	got, err := store.GetUserByID(ctx, alice.ID)
+	if err != nil || got.PasswordHash != "changed" {
+		t.Fatalf("password hash = %q, %v; want the changed hash kept", got.PasswordHash, err)
+	}
+
+	replaced, err = store.ReplaceUserPasswordHash(ctx, alice.ID, "changed", "rehashed", now+3)
+	if err != nil || !replaced {
+		t.Fatalf("ReplaceUserPasswordHash(current) = %v, %v; want true, nil", replaced, err)
+	}
+}
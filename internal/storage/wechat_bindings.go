@@ -6,6 +6,8 @@ import (
 	"fmt"
 )
 
+// UpsertWeChatBinding links userID to a WeChat account. A verified phone number is kept only
+// while the user stays bound to the same openid.
 func (s *Store) UpsertWeChatBinding(ctx context.Context, userID, openID, sessionKey string, unionID *string, nowMs int64) (WeChatBindingRow, error) {
 	if s == nil || s.db == nil {
 		return WeChatBindingRow{}, fmt.Errorf("db not initialized")
@@ -25,6 +27,7 @@ func (s *Store) UpsertWeChatBinding(ctx context.Context, userID, openID, session
 			openid = excluded.openid,
 			session_key = excluded.session_key,
 			unionid = excluded.unionid,
+			phone_number = CASE WHEN wechat_bindings.openid = excluded.openid THEN wechat_bindings.phone_number ELSE NULL END,
 			updated_at_ms = excluded.updated_at_ms;`
 
 	if _, err := s.db.ExecContext(ctx, s.rebind(q), userID, openID, sessionKey, union, nowMs); err != nil {
@@ -48,11 +51,11 @@ func (s *Store) GetWeChatBindingByUserID(ctx context.Context, userID string) (We
 		return WeChatBindingRow{}, fmt.Errorf("missing userID")
 	}
 
-	q := `SELECT user_id, openid, session_key, unionid, updated_at_ms FROM wechat_bindings WHERE user_id = ?;`
+	q := `SELECT user_id, openid, session_key, unionid, phone_number, updated_at_ms FROM wechat_bindings WHERE user_id = ?;`
 
 	var row WeChatBindingRow
-	var union sql.NullString
-	if err := s.db.QueryRowContext(ctx, s.rebind(q), userID).Scan(&row.UserID, &row.OpenID, &row.SessionKey, &union, &row.UpdatedAtMs); err != nil {
+	var union, phone sql.NullString
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), userID).Scan(&row.UserID, &row.OpenID, &row.SessionKey, &union, &phone, &row.UpdatedAtMs); err != nil {
 		if err == sql.ErrNoRows {
 			return WeChatBindingRow{}, fmt.Errorf("%w: wechat binding", ErrNotFound)
 		}
@@ -61,5 +64,100 @@ func (s *Store) GetWeChatBindingByUserID(ctx context.Context, userID string) (We
 	if union.Valid {
 		row.UnionID = &union.String
 	}
+	if phone.Valid {
+		row.PhoneNumber = &phone.String
+	}
+	return row, nil
+}
+
+// SetWeChatBindingPhone records the phone number the user verified through WeChat.
+func (s *Store) SetWeChatBindingPhone(ctx context.Context, userID, phoneNumber string, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if userID == "" || phoneNumber == "" {
+		return fmt.Errorf("missing required fields")
+	}
+
+	q := `UPDATE wechat_bindings SET phone_number = ?, updated_at_ms = ? WHERE user_id = ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), phoneNumber, nowMs, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: wechat binding", ErrNotFound)
+	}
+	return nil
+}
+
+// UpsertWeChatAccessToken stores the current access token for appID, replacing any previous one.
+func (s *Store) UpsertWeChatAccessToken(ctx context.Context, appID, accessToken string, expiresAtMs, nowMs int64) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("db not initialized")
+	}
+	if appID == "" || accessToken == "" {
+		return fmt.Errorf("missing required fields")
+	}
+
+	q := `INSERT INTO wechat_tokens (app_id, access_token, expires_at_ms, updated_at_ms)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(app_id) DO UPDATE SET
+			access_token = excluded.access_token,
+			expires_at_ms = excluded.expires_at_ms,
+			updated_at_ms = excluded.updated_at_ms;`
+
+	_, err := s.db.ExecContext(ctx, s.rebind(q), appID, accessToken, expiresAtMs, nowMs)
+	return err
+}
+
+// ClaimWeChatTokenRefresh takes the cross-instance refresh lease for appID's access token and
+// reports whether it was granted. The lease is the row's updated_at_ms: a claim succeeds only
+// when the row has not been claimed or saved within the last leaseMs, so one instance at a
+// time asks WeChat for a new token. A missing row is created with an empty token.
+func (s *Store) ClaimWeChatTokenRefresh(ctx context.Context, appID string, nowMs, leaseMs int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, fmt.Errorf("db not initialized")
+	}
+	if appID == "" {
+		return false, fmt.Errorf("missing appID")
+	}
+
+	q := `INSERT INTO wechat_tokens (app_id, access_token, expires_at_ms, updated_at_ms)
+		VALUES (?, '', 0, ?)
+		ON CONFLICT(app_id) DO UPDATE SET updated_at_ms = excluded.updated_at_ms
+		WHERE wechat_tokens.updated_at_ms <= ?;`
+	res, err := s.db.ExecContext(ctx, s.rebind(q), appID, nowMs, nowMs-leaseMs)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetWeChatAccessToken returns the stored access token for appID, which may have expired.
+func (s *Store) GetWeChatAccessToken(ctx context.Context, appID string) (WeChatTokenRow, error) {
+	if s == nil || s.db == nil {
+		return WeChatTokenRow{}, fmt.Errorf("db not initialized")
+	}
+	if appID == "" {
+		return WeChatTokenRow{}, fmt.Errorf("missing appID")
+	}
+
+	q := `SELECT app_id, access_token, expires_at_ms, updated_at_ms FROM wechat_tokens WHERE app_id = ?;`
+
+	var row WeChatTokenRow
+	if err := s.db.QueryRowContext(ctx, s.rebind(q), appID).Scan(&row.AppID, &row.AccessToken, &row.ExpiresAtMs, &row.UpdatedAtMs); err != nil {
+		if err == sql.ErrNoRows {
+			return WeChatTokenRow{}, fmt.Errorf("%w: wechat token", ErrNotFound)
+		}
+		return WeChatTokenRow{}, err
+	}
 	return row, nil
 }
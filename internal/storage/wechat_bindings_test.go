@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestClaimWeChatTokenRefresh_OneClaimPerLease(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	store, err := Open(ctx, "sqlite::memory:", logger)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	const lease = int64(30_000)
+	now := int64(1_700_000_000_000)
+
+	claims := []struct {
+		atMs int64
+		want bool
+	}{
+		{now, true},              // no row yet
+		{now + 1_000, false},     // within the first claim's lease
+		{now + lease, true},      // lease lapsed without a saved token
+		{now + lease + 1, false}, // within the second claim's lease
+	}
+	for _, c := range claims {
+		got, err := store.ClaimWeChatTokenRefresh(ctx, "wx-test", c.atMs, lease)
+		if err != nil {
+			t.Fatalf("ClaimWeChatTokenRefresh(%d) error = %v", c.atMs, err)
+		}
+		if got != c.want {
+			t.Fatalf("ClaimWeChatTokenRefresh(%d) = %v, want %v", c.atMs, got, c.want)
+		}
+	}
+
+	// Saving a token renews the lease, so instances that lost the claim read it instead.
+	if err := store.UpsertWeChatAccessToken(ctx, "wx-test", "token-1", now+7_200_000, now+2*lease); err != nil {
+		t.Fatalf("UpsertWeChatAccessToken() error = %v", err)
+	}
+	if got, err := store.ClaimWeChatTokenRefresh(ctx, "wx-test", now+2*lease+1, lease); err != nil || got {
+		t.Fatalf("ClaimWeChatTokenRefresh(after save) = %v, %v, want false", got, err)
+	}
+	row, err := store.GetWeChatAccessToken(ctx, "wx-test")
+	if err != nil || row.AccessToken != "token-1" {
+		t.Fatalf("GetWeChatAccessToken() = %+v, %v, want token-1", row, err)
+	}
+}
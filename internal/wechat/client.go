@@ -21,9 +21,41 @@ type Client struct {
 	appSecret  string
 	httpClient *http.Client
 
+	tokens TokenStore
+
 	mu           sync.Mutex
 	accessToken  string
 	accessExpiry time.Time
+	// accessLoadedAt is when accessToken was last taken from or saved to tokens.
+	accessLoadedAt time.Time
+	// rejectedToken is the last token WeChat refused; it is never reused, even if tokens
+	// still holds it.
+	rejectedToken string
+
+	// refreshMu serializes token refreshes so concurrent callers share one token request.
+	refreshMu sync.Mutex
+}
+
+const (
+	// tokenRecheckInterval bounds how long an in-memory token is trusted before tokens is
+	// consulted again, so a token refreshed by another instance is picked up.
+	tokenRecheckInterval = time.Minute
+	// tokenRefreshLease is how long a claimed refresh keeps other instances waiting.
+	tokenRefreshLease = 30 * time.Second
+	// tokenRefreshPollInterval is how often an instance waiting on another's refresh
+	// re-reads tokens.
+	tokenRefreshPollInterval = 250 * time.Millisecond
+)
+
+// TokenStore persists access tokens so a restarted process (or another instance sharing the
+// store) can reuse a token instead of requesting a new one; WeChat limits daily issuance, and
+// each new token invalidates the previous one. LoadAccessToken returns an empty token when
+// none is stored. ClaimAccessTokenRefresh grants one instance at a time the right to request
+// a new token for lease; other instances wait for it to be saved.
+type TokenStore interface {
+	LoadAccessToken(ctx context.Context, appID string) (token string, expiresAt time.Time, err error)
+	SaveAccessToken(ctx context.Context, appID, token string, expiresAt time.Time) error
+	ClaimAccessTokenRefresh(ctx context.Context, appID string, lease time.Duration) (bool, error)
 }
 
 func NewClient(logger *slog.Logger, appID, appSecret string) *Client {
@@ -40,6 +72,16 @@ func NewClient(logger *slog.Logger, appID, appSecret string) *Client {
 	}
 }
 
+// NewClientWithTokenStore is NewClient with access tokens also persisted in tokens. The
+// in-memory token is still checked first but only trusted for tokenRecheckInterval; tokens is
+// consulted before asking WeChat, refreshes are claimed through it so instances sharing it do
+// not invalidate each other's tokens, and it is updated after every refresh.
+func NewClientWithTokenStore(logger *slog.Logger, appID, appSecret string, tokens TokenStore) *Client {
+	c := NewClient(logger, appID, appSecret)
+	c.tokens = tokens
+	return c
+}
+
 type CodeSession struct {
 	OpenID     string  `json:"openid"`
 	SessionKey string  `json:"session_key"`
@@ -102,20 +144,77 @@ type tokenResponse struct {
 	ErrMsg      string `json:"errmsg"`
 }
 
+// GetAccessToken returns a usable access token, refreshing it when needed.
 func (c *Client) GetAccessToken(ctx context.Context) (string, error) {
 	if stringsTrim(c.appID) == "" || stringsTrim(c.appSecret) == "" {
 		return "", fmt.Errorf("wechat app credentials not configured")
 	}
 
-	now := time.Now()
-	c.mu.Lock()
-	if c.accessToken != "" && now.Before(c.accessExpiry.Add(-30*time.Second)) {
-		tok := c.accessToken
-		c.mu.Unlock()
+	if tok, ok := c.cachedAccessToken(); ok {
 		return tok, nil
 	}
-	c.mu.Unlock()
 
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Another caller may have refreshed while we waited.
+	if tok, ok := c.cachedAccessToken(); ok {
+		return tok, nil
+	}
+	if c.tokens == nil {
+		return c.refreshAccessToken(ctx)
+	}
+
+	for {
+		if tok, ok := c.loadStoredAccessToken(ctx); ok {
+			return tok, nil
+		}
+		claimed, err := c.tokens.ClaimAccessTokenRefresh(ctx, c.appID, tokenRefreshLease)
+		if err != nil {
+			c.logger.Warn("claim access token refresh failed", "error", err)
+			return c.refreshAccessToken(ctx)
+		}
+		if claimed {
+			// Another instance may have saved a token between the load and the claim.
+			if tok, ok := c.loadStoredAccessToken(ctx); ok {
+				return tok, nil
+			}
+			return c.refreshAccessToken(ctx)
+		}
+
+		// Another instance holds the lease; wait for its token or for the lease to lapse.
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(tokenRefreshPollInterval):
+		}
+	}
+}
+
+// loadStoredAccessToken adopts the token in c.tokens when it is usable and not known to be
+// rejected.
+func (c *Client) loadStoredAccessToken(ctx context.Context) (string, bool) {
+	tok, expiresAt, err := c.tokens.LoadAccessToken(ctx, c.appID)
+	if err != nil {
+		c.logger.Warn("load persisted access token failed", "error", err)
+		return "", false
+	}
+	if tok == "" || !tokenUsable(expiresAt, time.Now()) {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tok == c.rejectedToken {
+		return "", false
+	}
+	c.accessToken = tok
+	c.accessExpiry = expiresAt
+	c.accessLoadedAt = time.Now()
+	return tok, true
+}
+
+// refreshAccessToken asks WeChat for a new token and records it.
+func (c *Client) refreshAccessToken(ctx context.Context) (string, error) {
 	u, _ := url.Parse("https://api.weixin.qq.com/cgi-bin/token")
 	q := u.Query()
 	q.Set("grant_type", "client_credential")
@@ -150,12 +249,59 @@ func (c *Client) GetAccessToken(ctx context.Context) (string, error) {
 		return "", errors.New("wechat token response missing access_token/expires_in")
 	}
 
+	expiresAt := time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	c.setAccessToken(tr.AccessToken, expiresAt)
+	if c.tokens != nil {
+		if err := c.tokens.SaveAccessToken(ctx, c.appID, tr.AccessToken, expiresAt); err != nil {
+			c.logger.Warn("persist access token failed", "error", err)
+		}
+	}
+
+	return tr.AccessToken, nil
+}
+
+func (c *Client) cachedAccessToken() (string, bool) {
 	c.mu.Lock()
-	c.accessToken = tr.AccessToken
-	c.accessExpiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.accessToken == "" || !tokenUsable(c.accessExpiry, now) {
+		return "", false
+	}
+	if c.tokens != nil && now.Sub(c.accessLoadedAt) >= tokenRecheckInterval {
+		return "", false
+	}
+	return c.accessToken, true
+}
+
+func (c *Client) setAccessToken(token string, expiresAt time.Time) {
+	c.mu.Lock()
+	c.accessToken = token
+	c.accessExpiry = expiresAt
+	c.accessLoadedAt = time.Now()
 	c.mu.Unlock()
+}
 
-	return tr.AccessToken, nil
+// invalidateAccessToken forgets token after WeChat rejected it, so the next GetAccessToken
+// fetches another instead of serving it until it expires.
+func (c *Client) invalidateAccessToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejectedToken = token
+	if c.accessToken == token {
+		c.accessToken = ""
+		c.accessExpiry = time.Time{}
+	}
+}
+
+// isAccessTokenError reports whether errcode means the access token itself was rejected:
+// 40001 (invalid credential) or 42001 (access token expired).
+func isAccessTokenError(errcode int) bool {
+	return errcode == 40001 || errcode == 42001
+}
+
+// tokenUsable leaves a 30s margin so a token is not used right as it expires.
+func tokenUsable(expiresAt, now time.Time) bool {
+	return now.Before(expiresAt.Add(-30 * time.Second))
 }
 
 type subscribeSendResponse struct {
@@ -213,6 +359,9 @@ func (c *Client) SendSubscribeMessage(ctx context.Context, accessToken string, r
 		return fmt.Errorf("decode wechat subscribe response: %w", err)
 	}
 	if sr.ErrCode != 0 {
+		if isAccessTokenError(sr.ErrCode) {
+			c.invalidateAccessToken(accessToken)
+		}
 		return fmt.Errorf("wechat subscribe send errcode=%d errmsg=%q", sr.ErrCode, sr.ErrMsg)
 	}
 	return nil
@@ -273,6 +422,9 @@ func (c *Client) GetWxaCodeUnlimit(ctx context.Context, accessToken string, req
 	if len(body) > 0 && body[0] == '{' {
 		var er wxaCodeErrorResponse
 		if err := json.Unmarshal(body, &er); err == nil && er.ErrCode != 0 {
+			if isAccessTokenError(er.ErrCode) {
+				c.invalidateAccessToken(accessToken)
+			}
 			return nil, fmt.Errorf("wechat getwxacodeunlimit errcode=%d errmsg=%q", er.ErrCode, er.ErrMsg)
 		}
 	}
@@ -0,0 +1,310 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenTransport answers every token request with a new access token and counts them.
+// Subscribe messages are answered with subscribeBody.
+type tokenTransport struct {
+	mu            sync.Mutex
+	calls         int
+	subscribeBody string
+}
+
+func (rt *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body string
+	switch req.URL.Path {
+	case "/cgi-bin/token":
+		rt.mu.Lock()
+		rt.calls++
+		body = fmt.Sprintf(`{"access_token":"token-%d","expires_in":7200}`, rt.calls)
+		rt.mu.Unlock()
+	case "/cgi-bin/message/subscribe/send":
+		body = rt.subscribeBody
+	default:
+		return nil, fmt.Errorf("unexpected request to %s", req.URL.Path)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func (rt *tokenTransport) count() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.calls
+}
+
+type memoryTokenStore struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	// updatedAt mirrors the wechat_tokens lease column.
+	updatedAt time.Time
+}
+
+func (s *memoryTokenStore) LoadAccessToken(ctx context.Context, appID string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, s.expiresAt, nil
+}
+
+func (s *memoryTokenStore) SaveAccessToken(ctx context.Context, appID, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	s.expiresAt = expiresAt
+	s.updatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryTokenStore) ClaimAccessTokenRefresh(ctx context.Context, appID string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.updatedAt) < lease {
+		return false, nil
+	}
+	s.updatedAt = now
+	return true, nil
+}
+
+func newTestClient(rt http.RoundTripper, tokens TokenStore) *Client {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	c := NewClientWithTokenStore(logger, "wx-test", "secret", tokens)
+	c.httpClient = &http.Client{Transport: rt}
+	return c
+}
+
+func TestGetAccessToken_ReusesPersistedTokenAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	rt := &tokenTransport{}
+	tokens := &memoryTokenStore{}
+
+	first := newTestClient(rt, tokens)
+	tok, err := first.GetAccessToken(ctx)
+	if err != nil {
+		t.Fatalf("GetAccessToken() error = %v", err)
+	}
+	if tok != "token-1" {
+		t.Fatalf("token = %q, want %q", tok, "token-1")
+	}
+	if _, err := first.GetAccessToken(ctx); err != nil {
+		t.Fatalf("GetAccessToken() again error = %v", err)
+	}
+	if got := rt.count(); got != 1 {
+		t.Fatalf("token requests = %d, want 1", got)
+	}
+
+	// A new client has an empty in-memory cache, as after a restart.
+	restarted := newTestClient(rt, tokens)
+	tok, err = restarted.GetAccessToken(ctx)
+	if err != nil {
+		t.Fatalf("GetAccessToken() after restart error = %v", err)
+	}
+	if tok != "token-1" {
+		t.Fatalf("token after restart = %q, want %q", tok, "token-1")
+	}
+	if got := rt.count(); got != 1 {
+		t.Fatalf("token requests after restart = %d, want 1", got)
+	}
+}
+
+func TestGetAccessToken_RefreshesExpiredPersistedToken(t *testing.T) {
+	ctx := context.Background()
+	rt := &tokenTransport{}
+	tokens := &memoryTokenStore{token: "stale", expiresAt: time.Now().Add(10 * time.Second)}
+
+	c := newTestClient(rt, tokens)
+	tok, err := c.GetAccessToken(ctx)
+	if err != nil {
+		t.Fatalf("GetAccessToken() error = %v", err)
+	}
+	if tok != "token-1" {
+		t.Fatalf("token = %q, want %q", tok, "token-1")
+	}
+	if tokens.token != "token-1" {
+		t.Fatalf("persisted token = %q, want %q", tokens.token, "token-1")
+	}
+}
+
+func TestGetAccessToken_ConcurrentCallersShareOneRefresh(t *testing.T) {
+	ctx := context.Background()
+	rt := &tokenTransport{}
+	c := newTestClient(rt, &memoryTokenStore{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetAccessToken(ctx); err != nil {
+				t.Errorf("GetAccessToken() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := rt.count(); got != 1 {
+		t.Fatalf("token requests = %d, want 1", got)
+	}
+}
+
+func TestGetAccessToken_InstancesSharingStoreRefreshOnce(t *testing.T) {
+	ctx := context.Background()
+	rt := &tokenTransport{}
+	tokens := &memoryTokenStore{}
+
+	var wg sync.WaitGroup
+	got := make([]string, 4)
+	for i := range got {
+		c := newTestClient(rt, tokens)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tok, err := c.GetAccessToken(ctx)
+			if err != nil {
+				t.Errorf("GetAccessToken() error = %v", err)
+			}
+			got[i] = tok
+		}(i)
+	}
+	wg.Wait()
+	if n := rt.count(); n != 1 {
+		t.Fatalf("token requests = %d, want 1", n)
+	}
+	for _, tok := range got {
+		if tok != "token-1" {
+			t.Fatalf("tokens = %v, want all token-1", got)
+		}
+	}
+}
+
+func TestGetAccessToken_DropsTokenWeChatRejected(t *testing.T) {
+	ctx := context.Background()
+	rt := &tokenTransport{subscribeBody: `{"errcode":40001,"errmsg":"invalid credential"}`}
+	tokens := &memoryTokenStore{token: "dead", expiresAt: time.Now().Add(time.Hour)}
+
+	c := newTestClient(rt, tokens)
+	tok, err := c.GetAccessToken(ctx)
+	if err != nil || tok != "dead" {
+		t.Fatalf("GetAccessToken() = %q, %v, want the stored token", tok, err)
+	}
+	req := SubscribeSendRequest{ToUser: "openid", TemplateID: "tmpl"}
+	if err := c.SendSubscribeMessage(ctx, tok, req); err == nil {
+		t.Fatalf("SendSubscribeMessage() error = nil, want errcode 40001")
+	}
+
+	tok, err = c.GetAccessToken(ctx)
+	if err != nil {
+		t.Fatalf("GetAccessToken() after rejection error = %v", err)
+	}
+	if tok != "token-1" || tokens.token != "token-1" {
+		t.Fatalf("token = %q, persisted = %q, want a fresh token-1", tok, tokens.token)
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q) error = %v", s, err)
+	}
+	return b
+}
+
+// NIST SP 800-38A F.2.2, CBC-AES128.Decrypt.
+func TestCBCDecrypt_NISTVectors(t *testing.T) {
+	key := mustHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	iv := mustHex(t, "000102030405060708090a0b0c0d0e0f")
+	ciphertext := mustHex(t, "7649abac8119b246cee98e9b12e9197d"+
+		"5086cb9b507219ee95db113a917678b2"+
+		"73bed6b8e3c1743b7116e69e22229516"+
+		"3ff1caa1681fac09120eca307586e1a7")
+	want := mustHex(t, "6bc1bee22e409f96e93d7e117393172a"+
+		"ae2d8a571e03ac9c9eb76fac45af8e51"+
+		"30c81c46a35ce411e5fbc1191a0a52ef"+
+		"f69f2445df4f9b17ad2b417be66c3710")
+
+	got, err := cbcDecrypt(key, iv, ciphertext)
+	if err != nil {
+		t.Fatalf("cbcDecrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("cbcDecrypt() = %x, want %x", got, want)
+	}
+}
+
+// encryptForTest produces the base64 values wx.getPhoneNumber returns for payload.
+func encryptForTest(t *testing.T, key, iv []byte, payload string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher() error = %v", err)
+	}
+	n := aes.BlockSize - len(payload)%aes.BlockSize
+	plain := append([]byte(payload), bytes.Repeat([]byte{byte(n)}, n)...)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+func TestDecryptPhoneNumber(t *testing.T) {
+	key := mustHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	iv := mustHex(t, "000102030405060708090a0b0c0d0e0f")
+	sessionKey := base64.StdEncoding.EncodeToString(key)
+	ivB64 := base64.StdEncoding.EncodeToString(iv)
+	c := NewClient(slog.New(slog.NewJSONHandler(io.Discard, nil)), "wx-test", "secret")
+
+	payload := `{"phoneNumber":"+86 13800138000","purePhoneNumber":"13800138000","countryCode":"86","watermark":{"appid":"wx-test","timestamp":1700000000}}`
+	pn, err := c.DecryptPhoneNumber(sessionKey, encryptForTest(t, key, iv, payload), ivB64)
+	if err != nil {
+		t.Fatalf("DecryptPhoneNumber() error = %v", err)
+	}
+	if pn.PurePhoneNumber != "13800138000" || pn.CountryCode != "86" || pn.PhoneNumber != "+86 13800138000" {
+		t.Fatalf("DecryptPhoneNumber() = %+v", pn)
+	}
+
+	otherApp := `{"phoneNumber":"13800138000","purePhoneNumber":"13800138000","countryCode":"86","watermark":{"appid":"wx-other","timestamp":1700000000}}`
+	if _, err := c.DecryptPhoneNumber(sessionKey, encryptForTest(t, key, iv, otherApp), ivB64); !errors.Is(err, ErrWatermarkMismatch) {
+		t.Fatalf("DecryptPhoneNumber(other app) error = %v, want ErrWatermarkMismatch", err)
+	}
+
+	// The first NIST block decrypts to plaintext ending in 0x2a, which is not valid padding.
+	badPadding := base64.StdEncoding.EncodeToString(mustHex(t, "7649abac8119b246cee98e9b12e9197d"))
+	wrongKey := base64.StdEncoding.EncodeToString(mustHex(t, "000102030405060708090a0b0c0d0e0f"))
+	cases := []struct {
+		name                      string
+		sessionKey, data, ivInput string
+	}{
+		{"bad padding", sessionKey, badPadding, ivB64},
+		{"wrong session key", wrongKey, encryptForTest(t, key, iv, payload), ivB64},
+		{"not base64", sessionKey, "%%%", ivB64},
+		{"short iv", sessionKey, encryptForTest(t, key, iv, payload), "AAEC"},
+		{"partial block", sessionKey, base64.StdEncoding.EncodeToString([]byte("short")), ivB64},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := c.DecryptPhoneNumber(tc.sessionKey, tc.data, tc.ivInput); !errors.Is(err, ErrDecryptFailed) {
+				t.Fatalf("DecryptPhoneNumber() error = %v, want ErrDecryptFailed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package wechat
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDecryptFailed means encrypted data could not be decrypted with the session key,
+	// usually because the session key is stale or the payload was tampered with.
+	ErrDecryptFailed = errors.New("wechat: decrypt encrypted data failed")
+	// ErrWatermarkMismatch means the decrypted data was issued for a different app.
+	ErrWatermarkMismatch = errors.New("wechat: watermark appid mismatch")
+)
+
+type PhoneNumber struct {
+	PhoneNumber     string `json:"phoneNumber"`
+	PurePhoneNumber string `json:"purePhoneNumber"`
+	CountryCode     string `json:"countryCode"`
+	Watermark       struct {
+		AppID     string `json:"appid"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"watermark"`
+}
+
+// DecryptPhoneNumber decrypts the encryptedData and iv returned by wx.getPhoneNumber with the
+// user's session_key (all base64) and checks that the payload was issued for this app.
+func (c *Client) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (PhoneNumber, error) {
+	plain, err := decryptData(sessionKey, encryptedData, iv)
+	if err != nil {
+		return PhoneNumber{}, err
+	}
+
+	var pn PhoneNumber
+	if err := json.Unmarshal(plain, &pn); err != nil {
+		return PhoneNumber{}, fmt.Errorf("%w: decode payload: %v", ErrDecryptFailed, err)
+	}
+	if pn.Watermark.AppID != c.appID {
+		return PhoneNumber{}, ErrWatermarkMismatch
+	}
+	if pn.PhoneNumber == "" {
+		return PhoneNumber{}, fmt.Errorf("%w: payload missing phoneNumber", ErrDecryptFailed)
+	}
+	return pn, nil
+}
+
+// decryptData implements WeChat's open-data scheme: AES-128-CBC with PKCS#7 padding, where the
+// key, iv and ciphertext are base64-encoded.
+func decryptData(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid session key encoding", ErrDecryptFailed)
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid iv encoding", ErrDecryptFailed)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid encrypted data encoding", ErrDecryptFailed)
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("%w: session key must be 16 bytes", ErrDecryptFailed)
+	}
+
+	plain, err := cbcDecrypt(key, ivBytes, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return pkcs7Unpad(plain, aes.BlockSize)
+}
+
+func cbcDecrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptFailed, err)
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("%w: iv must be %d bytes", ErrDecryptFailed, block.BlockSize())
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("%w: ciphertext is not a whole number of blocks", ErrDecryptFailed)
+	}
+
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+	return plain, nil
+}
+
+// pkcs7Unpad strips PKCS#7 padding, rejecting pad bytes that are out of range or inconsistent.
+func pkcs7Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrDecryptFailed)
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > blockSize {
+		return nil, fmt.Errorf("%w: invalid padding", ErrDecryptFailed)
+	}
+	for _, p := range b[len(b)-n:] {
+		if int(p) != n {
+			return nil, fmt.Errorf("%w: invalid padding", ErrDecryptFailed)
+		}
+	}
+	return b[:len(b)-n], nil
+}
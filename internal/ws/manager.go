@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -22,6 +24,18 @@ const (
 
 const sendBuffer = 128
 
+// defaultSendTimeout applies when ManagerOptions.SendTimeout is unset.
+const defaultSendTimeout = 200 * time.Millisecond
+
+// typingRelayInterval is the minimum gap between two typing relays from one connection.
+const typingRelayInterval = time.Second
+
+// maxSignalBytes bounds the sdp and candidate blobs of one call.signal message.
+const maxSignalBytes = 16 << 10
+
+// drainPollInterval is how often Drain checks whether every client has left.
+const drainPollInterval = 50 * time.Millisecond
+
 type Envelope struct {
 	Type      string `json:"type"`
 	SessionID string `json:"sessionId"`
@@ -43,42 +57,188 @@ type TokenValidator interface {
 	ValidateToken(ctx context.Context, token string) (userID string, err error)
 }
 
+// CallStore looks up calls for media and signaling relay. isGroup marks activity group calls,
+// which have no callee.
 type CallStore interface {
-	GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, err error)
+	GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, isGroup bool, err error)
+}
+
+// SessionParticipantStore resolves who may receive session-scoped client events such as typing.
+type SessionParticipantStore interface {
+	ParticipantUserIDs(ctx context.Context, sessionID string) ([]string, error)
+}
+
+// PresenceAudience resolves who is told when a user comes online or goes offline.
+type PresenceAudience interface {
+	PresenceAudienceUserIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// outboundFrame is a queued write; messageType is websocket.TextMessage or BinaryMessage.
+type outboundFrame struct {
+	messageType int
+	data        []byte
+}
+
+func textFrame(b []byte) outboundFrame {
+	return outboundFrame{messageType: websocket.TextMessage, data: b}
 }
 
 type client struct {
-	conn      *websocket.Conn
-	userID    string
-	send      chan []byte
+	conn   *websocket.Conn
+	userID string
+	// token is the auth token the connection was opened with.
+	token string
+	// send is never closed, so queuing can race with close(); done signals the shutdown.
+	send      chan outboundFrame
+	done      chan struct{}
 	closeOnce sync.Once
+	limiter   *inboundLimiter
+	// lastTypingAt is only touched by the connection's read loop.
+	lastTypingAt time.Time
 }
 
 func (c *client) close() {
 	c.closeOnce.Do(func() {
-		close(c.send)
+		close(c.done)
 		_ = c.conn.Close()
 	})
 }
 
+type ManagerOptions struct {
+	// MaxInboundPerSec caps how many data frames a single connection may send per second.
+	// Zero disables the limit. Ping/pong control frames are handled by the websocket
+	// library before they reach the read loop, so they never count against it; app-level
+	// {"type":"ping"} messages are data frames and do.
+	MaxInboundPerSec int
+	// DisconnectOnRateLimit closes the connection on the first excess frame instead of dropping it.
+	DisconnectOnRateLimit bool
+	// Sessions routes typing indicators to session participants. Nil disables typing relay.
+	Sessions SessionParticipantStore
+	// MaxConnectionsPerUser caps how many sockets one user may hold open. When a new
+	// connection would exceed it, the user's oldest connection is closed. Zero disables it.
+	MaxConnectionsPerUser int
+	// Presence receives presence.online/presence.offline events for a user. Nil disables
+	// presence events; IsOnline and OnlineUserIDs work either way.
+	Presence PresenceAudience
+	// SendTimeout is how long one fan-out of an event waits in total for room in full send
+	// buffers before the clients still full are dropped as too slow. Zero uses
+	// defaultSendTimeout.
+	SendTimeout time.Duration
+}
+
+type Stats struct {
+	Connections          int   `json:"connections"`
+	RateLimitedMessages  int64 `json:"rateLimitedMessages"`
+	RateLimitDisconnects int64 `json:"rateLimitDisconnects"`
+	EvictedConnections   int64 `json:"evictedConnections"`
+	// SentEvents counts server events queued to connections by Broadcast and SendToUser(s).
+	SentEvents int64 `json:"sentEvents"`
+}
+
 type Manager struct {
 	logger         *slog.Logger
 	tokenValidator TokenValidator
 	callStore      CallStore
+	opts           ManagerOptions
 
 	mu      sync.Mutex
 	clients map[*client]struct{}
+	// userClients lists each user's connections oldest first; guarded by mu.
+	userClients map[string][]*client
+	// presenceSeq numbers online/offline transitions and presenceLatest holds each user's
+	// latest one; both guarded by mu. publishPresence drops events that are no longer the
+	// latest so a racing connect and disconnect cannot arrive out of order.
+	presenceSeq    uint64
+	presenceLatest map[string]uint64
+	// presenceMu serializes the final check-and-send of presence events.
+	presenceMu sync.Mutex
+
+	rateLimitedMessages  atomic.Int64
+	rateLimitDisconnects atomic.Int64
+	evictedConnections   atomic.Int64
+	sentEvents           atomic.Int64
+
+	// draining is set by Drain; new connections are refused from then on.
+	draining atomic.Bool
 }
 
 func NewManager(logger *slog.Logger, tokenValidator TokenValidator, callStore CallStore) *Manager {
+	return NewManagerWithOptions(logger, tokenValidator, callStore, ManagerOptions{})
+}
+
+func NewManagerWithOptions(logger *slog.Logger, tokenValidator TokenValidator, callStore CallStore, opts ManagerOptions) *Manager {
+	if opts.MaxInboundPerSec < 0 {
+		opts.MaxInboundPerSec = 0
+	}
+	if opts.MaxConnectionsPerUser < 0 {
+		opts.MaxConnectionsPerUser = 0
+	}
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = defaultSendTimeout
+	}
 	return &Manager{
 		logger:         logger.With("component", "ws"),
 		tokenValidator: tokenValidator,
 		callStore:      callStore,
+		opts:           opts,
 		clients:        make(map[*client]struct{}),
+		userClients:    make(map[string][]*client),
+		presenceLatest: make(map[string]uint64),
+	}
+}
+
+func (m *Manager) Stats() Stats {
+	m.mu.Lock()
+	conns := len(m.clients)
+	m.mu.Unlock()
+
+	return Stats{
+		Connections:          conns,
+		RateLimitedMessages:  m.rateLimitedMessages.Load(),
+		RateLimitDisconnects: m.rateLimitDisconnects.Load(),
+		EvictedConnections:   m.evictedConnections.Load(),
+		SentEvents:           m.sentEvents.Load(),
 	}
 }
 
+// OnlineStatus reports, for each of userIDs, whether the user has at least one open connection.
+// It takes the lock once for the whole batch so list endpoints can call it cheaply.
+func (m *Manager) OnlineStatus(userIDs []string) map[string]bool {
+	out := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		out[id] = false
+	}
+	if len(userIDs) == 0 {
+		return out
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := range out {
+		out[id] = len(m.userClients[id]) > 0
+	}
+	return out
+}
+
+// IsOnline reports whether userID has at least one open connection.
+func (m *Manager) IsOnline(userID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.userClients[userID]) > 0
+}
+
+// OnlineUserIDs returns every user with at least one open connection, sorted.
+func (m *Manager) OnlineUserIDs() []string {
+	m.mu.Lock()
+	out := make([]string, 0, len(m.userClients))
+	for id := range m.userClients {
+		out = append(out, id)
+	}
+	m.mu.Unlock()
+	sort.Strings(out)
+	return out
+}
+
 func (m *Manager) Handler() http.Handler {
 	return http.HandlerFunc(m.handle)
 }
@@ -95,6 +255,62 @@ func (m *Manager) CloseAll() {
 	}
 }
 
+// Drain prepares for shutdown without cutting clients off mid-call: it stops accepting new
+// connections, sends every client a server.draining event (payload graceMs) so it can wrap up
+// and reconnect to another instance, and waits until all clients have disconnected, grace has
+// elapsed or ctx is done. Whatever is still connected then is force-closed with CloseAll.
+func (m *Manager) Drain(ctx context.Context, grace time.Duration) {
+	m.draining.Store(true)
+	m.Broadcast(Envelope{
+		Type:    "server.draining",
+		Payload: map[string]any{"graceMs": grace.Milliseconds()},
+	})
+
+	if grace > 0 {
+		deadline := time.NewTimer(grace)
+		defer deadline.Stop()
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+	wait:
+		for m.Stats().Connections > 0 {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-deadline.C:
+				break wait
+			case <-ticker.C:
+			}
+		}
+	}
+
+	m.CloseAll()
+}
+
+// CloseUser disconnects every connection of userID, e.g. after the account was deleted.
+func (m *Manager) CloseUser(userID, reason string) {
+	m.CloseUserExcept(userID, "", reason)
+}
+
+// CloseUserExcept disconnects the connections of userID that were not opened with keepToken,
+// e.g. after a password change revoked the user's other tokens. An empty keepToken closes
+// them all.
+func (m *Manager) CloseUserExcept(userID, keepToken, reason string) {
+	clients := m.snapshotClients()
+	for _, c := range clients {
+		if c.userID != userID || (keepToken != "" && c.token == keepToken) {
+			continue
+		}
+		_ = c.conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason),
+			time.Now().Add(writeWait),
+		)
+		c.close()
+	}
+}
+
+// Broadcast sends env to every connected client. It is meant for server-wide notices such
+// as announcements; session events go through SendToUsers so non-members never see them.
 func (m *Manager) Broadcast(env Envelope) {
 	b, err := encodeJSON(env)
 	if err != nil {
@@ -102,16 +318,7 @@ func (m *Manager) Broadcast(env Envelope) {
 		return
 	}
 
-	clients := m.snapshotClients()
-	for _, c := range clients {
-		select {
-		case c.send <- b:
-		default:
-			m.logger.Warn("ws slow client dropped")
-			m.untrack(c)
-			c.close()
-		}
-	}
+	m.fanOut(m.snapshotClients(), textFrame(b))
 }
 
 func (m *Manager) SendToUser(userID string, env Envelope) {
@@ -121,19 +328,13 @@ func (m *Manager) SendToUser(userID string, env Envelope) {
 		return
 	}
 
-	clients := m.snapshotClients()
-	for _, c := range clients {
-		if c.userID != userID {
-			continue
-		}
-		select {
-		case c.send <- b:
-		default:
-			m.logger.Warn("ws slow client dropped", "userID", userID)
-			m.untrack(c)
-			c.close()
+	var targets []*client
+	for _, c := range m.snapshotClients() {
+		if c.userID == userID {
+			targets = append(targets, c)
 		}
 	}
+	m.fanOut(targets, textFrame(b))
 }
 
 func (m *Manager) SendToUsers(userIDs []string, env Envelope) {
@@ -152,16 +353,60 @@ func (m *Manager) SendToUsers(userIDs []string, env Envelope) {
 		userSet[id] = struct{}{}
 	}
 
-	clients := m.snapshotClients()
+	var targets []*client
+	for _, c := range m.snapshotClients() {
+		if _, ok := userSet[c.userID]; ok {
+			targets = append(targets, c)
+		}
+	}
+	m.fanOut(targets, textFrame(b))
+}
+
+// fanOut queues a server event for each of clients. Clients with room get it at once; those
+// whose send buffer is full then share one SendTimeout deadline for their write pumps to make
+// room, so a reader that is only briefly slow keeps its connection while any number of stuck
+// ones delay the caller by at most SendTimeout. Clients still full at the deadline are dropped.
+func (m *Manager) fanOut(clients []*client, frame outboundFrame) {
+	var full []*client
 	for _, c := range clients {
-		if _, ok := userSet[c.userID]; !ok {
-			continue
+		select {
+		case c.send <- frame:
+			m.sentEvents.Add(1)
+		case <-c.done:
+		default:
+			full = append(full, c)
+		}
+	}
+	if len(full) == 0 {
+		return
+	}
+
+	timer := time.NewTimer(m.opts.SendTimeout)
+	defer timer.Stop()
+	expired := false
+	for _, c := range full {
+		if !expired {
+			select {
+			case c.send <- frame:
+				m.sentEvents.Add(1)
+				continue
+			case <-c.done:
+				continue
+			case <-timer.C:
+				expired = true
+			}
 		}
 		select {
-		case c.send <- b:
+		case c.send <- frame:
+			m.sentEvents.Add(1)
+		case <-c.done:
 		default:
 			m.logger.Warn("ws slow client dropped", "userID", c.userID)
-			m.untrack(c)
+			// fanOut may be running under presenceMu on behalf of publishPresence, so a
+			// resulting presence.offline is published from its own goroutine.
+			if seq, wentOffline := m.forget(c); wentOffline {
+				go m.publishPresence(c.userID, false, seq)
+			}
 			c.close()
 		}
 	}
@@ -180,6 +425,10 @@ func (m *Manager) handle(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	if m.draining.Load() {
+		http.Error(w, "server draining", http.StatusServiceUnavailable)
+		return
+	}
 
 	token := extractToken(r)
 	if token == "" {
@@ -200,11 +449,23 @@ func (m *Manager) handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	c := &client{
-		conn:   conn,
-		userID: userID,
-		send:   make(chan []byte, sendBuffer),
+		conn:    conn,
+		userID:  userID,
+		token:   token,
+		send:    make(chan outboundFrame, sendBuffer),
+		done:    make(chan struct{}),
+		limiter: newInboundLimiter(m.opts.MaxInboundPerSec, time.Now()),
+	}
+	for _, old := range m.track(c) {
+		m.evictedConnections.Add(1)
+		m.logger.Info("ws evicting oldest connection", "userID", userID)
+		_ = old.conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many connections"),
+			time.Now().Add(writeWait),
+		)
+		old.close()
 	}
-	m.track(c)
 	defer m.untrack(c)
 	defer c.close()
 
@@ -220,11 +481,29 @@ func (m *Manager) handle(w http.ResponseWriter, r *http.Request) {
 	go m.writePump(c, r.RemoteAddr)
 
 	for {
-		_, msg, err := conn.ReadMessage()
+		msgType, msg, err := conn.ReadMessage()
 		if err != nil {
 			m.logger.Info("ws disconnected", "remoteAddr", r.RemoteAddr, "userID", userID, "error", err)
 			return
 		}
+		if !c.limiter.allow(time.Now()) {
+			m.rateLimitedMessages.Add(1)
+			if m.opts.DisconnectOnRateLimit {
+				m.rateLimitDisconnects.Add(1)
+				m.logger.Warn("ws client exceeded inbound rate limit, disconnecting", "remoteAddr", r.RemoteAddr, "userID", userID)
+				_ = conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded"),
+					time.Now().Add(writeWait),
+				)
+				return
+			}
+			continue
+		}
+		if msgType == websocket.BinaryMessage {
+			m.relayBinaryFrame(c, msg)
+			continue
+		}
 		m.handleClientMessage(c, msg)
 	}
 }
@@ -248,12 +527,11 @@ func (m *Manager) writePump(c *client, remoteAddr string) {
 
 	for {
 		select {
-		case msg, ok := <-c.send:
-			if !ok {
-				return
-			}
+		case <-c.done:
+			return
+		case msg := <-c.send:
 			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			if err := c.conn.WriteMessage(msg.messageType, msg.data); err != nil {
 				m.logger.Info("ws write failed", "remoteAddr", remoteAddr, "error", err)
 				c.close()
 				return
@@ -279,16 +557,122 @@ func (m *Manager) snapshotClients() []*client {
 	return clients
 }
 
-func (m *Manager) track(c *client) {
+// track registers c and returns the connections of the same user that must be evicted to
+// stay within MaxConnectionsPerUser, oldest first. Evicted clients are already untracked;
+// the caller closes them outside the lock. The user's first connection publishes
+// presence.online.
+func (m *Manager) track(c *client) []*client {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.clients[c] = struct{}{}
+	wentOnline := len(m.userClients[c.userID]) == 0
+	conns := append(m.userClients[c.userID], c)
+
+	var evicted []*client
+	if limit := m.opts.MaxConnectionsPerUser; limit > 0 && len(conns) > limit {
+		evicted = append(evicted, conns[:len(conns)-limit]...)
+		conns = append([]*client(nil), conns[len(conns)-limit:]...)
+		for _, old := range evicted {
+			delete(m.clients, old)
+		}
+	}
+	m.userClients[c.userID] = conns
+	var seq uint64
+	if wentOnline {
+		seq = m.nextPresenceSeqLocked(c.userID)
+	}
+	m.mu.Unlock()
+
+	if wentOnline {
+		m.publishPresence(c.userID, true, seq)
+	}
+	return evicted
 }
 
+// untrack forgets c. Removing the user's last connection publishes presence.offline.
 func (m *Manager) untrack(c *client) {
+	if seq, wentOffline := m.forget(c); wentOffline {
+		m.publishPresence(c.userID, false, seq)
+	}
+}
+
+// forget removes c from the connection maps and reports whether it was the user's last
+// connection, along with the presence transition the caller must publish in that case.
+func (m *Manager) forget(c *client) (uint64, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.clients, c)
+
+	conns := m.userClients[c.userID]
+	removed := false
+	for i, other := range conns {
+		if other == c {
+			conns = append(conns[:i:i], conns[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	wentOffline := removed && len(conns) == 0
+	if len(conns) == 0 {
+		delete(m.userClients, c.userID)
+	} else {
+		m.userClients[c.userID] = conns
+	}
+	var seq uint64
+	if wentOffline {
+		seq = m.nextPresenceSeqLocked(c.userID)
+	}
+	return seq, wentOffline
+}
+
+// nextPresenceSeqLocked records a new presence transition for userID. m.mu must be held.
+// Nothing is recorded when presence events are disabled.
+func (m *Manager) nextPresenceSeqLocked(userID string) uint64 {
+	if m.opts.Presence == nil {
+		return 0
+	}
+	m.presenceSeq++
+	m.presenceLatest[userID] = m.presenceSeq
+	return m.presenceSeq
+}
+
+// publishPresence tells userID's presence audience that the user came online or went
+// offline. seq is the transition being published; if a newer transition for the user has
+// been recorded by the time the audience is resolved, the event is dropped and the newer
+// one is sent instead.
+func (m *Manager) publishPresence(userID string, online bool, seq uint64) {
+	if m.opts.Presence == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	audience, err := m.opts.Presence.PresenceAudienceUserIDs(ctx, userID)
+	if err != nil {
+		m.logger.Warn("ws presence audience lookup failed", "error", err, "userID", userID)
+	}
+
+	m.presenceMu.Lock()
+	defer m.presenceMu.Unlock()
+	m.mu.Lock()
+	latest := m.presenceLatest[userID] == seq
+	if latest && !online {
+		delete(m.presenceLatest, userID)
+	}
+	m.mu.Unlock()
+	if !latest || len(audience) == 0 {
+		return
+	}
+
+	envType := "presence.offline"
+	if online {
+		envType = "presence.online"
+	}
+	m.SendToUsers(audience, Envelope{
+		Type: envType,
+		Payload: map[string]any{
+			"userId": userID,
+			"atMs":   time.Now().UnixMilli(),
+		},
+	})
 }
 
 func encodeJSON(v any) ([]byte, error) {
@@ -302,11 +686,15 @@ func encodeJSON(v any) ([]byte, error) {
 }
 
 type clientMessage struct {
-	Type     string `json:"type"`
-	CallID   string `json:"callId"`
-	Data     string `json:"data"`
-	Seq      int64  `json:"seq,omitempty"`
-	SentAtMs int64  `json:"sentAtMs,omitempty"`
+	Type      string `json:"type"`
+	SessionID string `json:"sessionId"`
+	CallID    string `json:"callId"`
+	Data      string `json:"data"`
+	Seq       int64  `json:"seq,omitempty"`
+	SentAtMs  int64  `json:"sentAtMs,omitempty"`
+	// SDP and Candidate are opaque WebRTC signaling blobs carried by call.signal.
+	SDP       json.RawMessage `json:"sdp,omitempty"`
+	Candidate json.RawMessage `json:"candidate,omitempty"`
 }
 
 func (m *Manager) handleClientMessage(c *client, msg []byte) {
@@ -315,29 +703,29 @@ func (m *Manager) handleClientMessage(c *client, msg []byte) {
 		return
 	}
 
-	if cm.Type != "audio.frame" && cm.Type != "video.frame" {
+	if cm.Type == "typing" {
+		m.relayTyping(c, cm.SessionID, time.Now())
 		return
 	}
-
-	if cm.CallID == "" || cm.Data == "" {
+	if cm.Type == "ping" {
+		m.replyPong(c, cm.SentAtMs, time.Now())
+		return
+	}
+	if cm.Type == "call.signal" {
+		m.relaySignal(c, cm)
 		return
 	}
 
-	callerID, calleeID, status, err := m.callStore.GetCallByID(context.Background(), cm.CallID)
-	if err != nil {
+	if cm.Type != "audio.frame" && cm.Type != "video.frame" {
 		return
 	}
 
-	if status != "accepted" {
+	if cm.CallID == "" || cm.Data == "" {
 		return
 	}
 
-	var peerID string
-	if c.userID == callerID {
-		peerID = calleeID
-	} else if c.userID == calleeID {
-		peerID = callerID
-	} else {
+	peerID, ok := m.callPeerID(c, cm.CallID)
+	if !ok {
 		return
 	}
 
@@ -361,15 +749,150 @@ func (m *Manager) handleClientMessage(c *client, msg []byte) {
 	if err != nil {
 		return
 	}
+	m.relayMediaFrame(peerID, textFrame(b))
+}
+
+// relayBinaryFrame forwards a binary audio frame without base64. A frame starts with a
+// one-byte call id length followed by the call id; the remaining bytes are raw audio. The
+// frame reaches the peer unchanged so it can tell which call the audio belongs to.
+func (m *Manager) relayBinaryFrame(c *client, frame []byte) {
+	if len(frame) < 2 {
+		return
+	}
+	idLen := int(frame[0])
+	if idLen == 0 || len(frame) <= 1+idLen {
+		return
+	}
+	callID := string(frame[1 : 1+idLen])
+
+	peerID, ok := m.callPeerID(c, callID)
+	if !ok {
+		return
+	}
+	m.relayMediaFrame(peerID, outboundFrame{messageType: websocket.BinaryMessage, data: frame})
+}
+
+// relaySignal forwards WebRTC offer/answer and ICE candidates to the peer of an accepted
+// call so clients can negotiate direct media. The blobs are passed through unparsed.
+func (m *Manager) relaySignal(c *client, cm clientMessage) {
+	if cm.CallID == "" || (len(cm.SDP) == 0 && len(cm.Candidate) == 0) {
+		return
+	}
+	if len(cm.SDP)+len(cm.Candidate) > maxSignalBytes {
+		return
+	}
 
+	peerID, ok := m.callPeerID(c, cm.CallID)
+	if !ok {
+		return
+	}
+
+	payload := map[string]any{
+		"callId":     cm.CallID,
+		"fromUserId": c.userID,
+	}
+	if len(cm.SDP) > 0 {
+		payload["sdp"] = cm.SDP
+	}
+	if len(cm.Candidate) > 0 {
+		payload["candidate"] = cm.Candidate
+	}
+	// Unlike media frames, a lost signal stalls negotiation, so it takes the regular
+	// delivery path instead of being dropped on a full buffer.
+	m.SendToUsers([]string{peerID}, Envelope{
+		Type:    "call.signal",
+		Payload: payload,
+	})
+}
+
+// callPeerID returns the other party of an accepted call that c takes part in.
+func (m *Manager) callPeerID(c *client, callID string) (string, bool) {
+	callerID, calleeID, status, isGroup, err := m.callStore.GetCallByID(context.Background(), callID)
+	// Group calls carry media over WeChat VoIP and have no single peer.
+	if err != nil || status != "accepted" || isGroup {
+		return "", false
+	}
+	switch c.userID {
+	case callerID:
+		return calleeID, true
+	case calleeID:
+		return callerID, true
+	default:
+		return "", false
+	}
+}
+
+// relayMediaFrame queues a media frame for every connection of peerID. Media is lossy by
+// nature, so frames for a full buffer are dropped rather than disconnecting the peer.
+func (m *Manager) relayMediaFrame(peerID string, frame outboundFrame) {
 	clients := m.snapshotClients()
 	for _, peer := range clients {
 		if peer.userID != peerID {
 			continue
 		}
 		select {
-		case peer.send <- b:
+		case peer.send <- frame:
 		default:
 		}
 	}
 }
+
+// replyPong answers an application-level ping on the same socket so the client can measure
+// round-trip time. It is never relayed to other users.
+func (m *Manager) replyPong(c *client, sentAtMs int64, now time.Time) {
+	b, err := encodeJSON(Envelope{
+		Type: "pong",
+		Payload: map[string]any{
+			"sentAtMs":   sentAtMs,
+			"serverAtMs": now.UnixMilli(),
+		},
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- textFrame(b):
+	default:
+	}
+}
+
+// relayTyping forwards a typing indicator from c to the other participants of sessionID.
+// Relays are throttled per connection, and senders outside the session are ignored. The
+// throttle applies to every frame, relayed or not, so frames for foreign sessions cannot
+// drive unthrottled participant lookups.
+func (m *Manager) relayTyping(c *client, sessionID string, now time.Time) {
+	if m.opts.Sessions == nil || sessionID == "" {
+		return
+	}
+	if !c.lastTypingAt.IsZero() && now.Sub(c.lastTypingAt) < typingRelayInterval {
+		return
+	}
+	c.lastTypingAt = now
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	participants, err := m.opts.Sessions.ParticipantUserIDs(ctx, sessionID)
+	if err != nil {
+		return
+	}
+	recipients := make([]string, 0, len(participants))
+	isParticipant := false
+	for _, id := range participants {
+		if id == c.userID {
+			isParticipant = true
+			continue
+		}
+		recipients = append(recipients, id)
+	}
+	if !isParticipant || len(recipients) == 0 {
+		return
+	}
+
+	m.SendToUsers(recipients, Envelope{
+		Type:      "typing",
+		SessionID: sessionID,
+		Payload: map[string]any{
+			"userId": c.userID,
+		},
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,8 +29,8 @@ func (staticValidator) ValidateToken(ctx context.Context, token string) (string,
 
 type staticCallStore struct{}
 
-func (staticCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, err error) {
-	return "", "", "", errors.New("not found")
+func (staticCallStore) GetCallByID(ctx context.Context, callID string) (callerID, calleeID, status string, isGroup bool, err error) {
+	return "", "", "", false, errors.New("not found")
 }
 
 func TestManager_Broadcast(t *testing.T) {
@@ -93,39 +96,43 @@ func (m *mockTokenValidator) ValidateToken(_ context.Context, token string) (str
 	return "", errors.New("invalid token")
 }
 
+type mockCall struct {
+	callerID string
+	calleeID string
+	status   string
+	isGroup  bool
+}
+
 type mockCallStore struct {
 	mu    sync.Mutex
-	calls map[string]struct {
-		callerID string
-		calleeID string
-		status   string
-	}
+	calls map[string]mockCall
 }
 
-func (m *mockCallStore) GetCallByID(_ context.Context, callID string) (callerID, calleeID, status string, err error) {
+func (m *mockCallStore) GetCallByID(_ context.Context, callID string) (callerID, calleeID, status string, isGroup bool, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if call, ok := m.calls[callID]; ok {
-		return call.callerID, call.calleeID, call.status, nil
+		return call.callerID, call.calleeID, call.status, call.isGroup, nil
 	}
-	return "", "", "", errors.New("call not found")
+	return "", "", "", false, errors.New("call not found")
 }
 
 func (m *mockCallStore) SetCall(callID, callerID, calleeID, status string) {
+	m.setCall(callID, mockCall{callerID: callerID, calleeID: calleeID, status: status})
+}
+
+// SetGroupCall records an activity group call, which has an initiator but no callee.
+func (m *mockCallStore) SetGroupCall(callID, initiatorID, status string) {
+	m.setCall(callID, mockCall{callerID: initiatorID, status: status, isGroup: true})
+}
+
+func (m *mockCallStore) setCall(callID string, call mockCall) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.calls == nil {
-		m.calls = make(map[string]struct {
-			callerID string
-			calleeID string
-			status   string
-		})
+		m.calls = make(map[string]mockCall)
 	}
-	m.calls[callID] = struct {
-		callerID string
-		calleeID string
-		status   string
-	}{callerID, calleeID, status}
+	m.calls[callID] = call
 }
 
 func setupTestManager() (*Manager, *mockTokenValidator, *mockCallStore) {
@@ -302,6 +309,40 @@ func TestAudioFrameRelay_CallNotAccepted(t *testing.T) {
 	}
 }
 
+func TestAudioFrameRelay_GroupCallNotRelayed(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	// Group calls carry media over WeChat VoIP, whichever members take part.
+	cs.SetGroupCall("call1", "userA", "accepted")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	msg := `{"type":"audio.frame","callId":"call1","data":"dGVzdA=="}`
+	for _, conn := range []*websocket.Conn{connA, connB} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	for _, conn := range []*websocket.Conn{connA, connB} {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, data, err := conn.ReadMessage(); err == nil {
+			t.Fatalf("expected timeout, got %s", data)
+		}
+	}
+}
+
 func TestAudioFrameRelay_NotParticipant(t *testing.T) {
 	m, tv, cs := setupTestManager()
 
@@ -516,3 +557,799 @@ func TestMixedFrameRelay(t *testing.T) {
 		}
 	}
 }
+
+func TestInboundRateLimit_DropsExcessFrames(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenA": "userA", "tokenB": "userB"}}
+	cs := &mockCallStore{}
+	cs.SetCall("call1", "userA", "userB", "accepted")
+	m := NewManagerWithOptions(logger, tv, cs, ManagerOptions{MaxInboundPerSec: 5})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	const frameCount = 20
+	for i := 0; i < frameCount; i++ {
+		msg := `{"type":"audio.frame","callId":"call1","data":"dGVzdA=="}`
+		if err := connA.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("write failed at frame %d: %v", i, err)
+		}
+	}
+
+	count := 0
+	for {
+		connB.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		if _, _, err := connB.ReadMessage(); err != nil {
+			break
+		}
+		count++
+	}
+
+	if count == 0 || count >= frameCount {
+		t.Fatalf("relayed %d frames, want between 1 and %d", count, frameCount-1)
+	}
+	if got := m.Stats().RateLimitedMessages; got == 0 {
+		t.Fatalf("RateLimitedMessages = 0, want > 0")
+	}
+}
+
+func TestInboundRateLimit_Disconnect(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenA": "userA"}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{MaxInboundPerSec: 2, DisconnectOnRateLimit: true})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := connA.WriteMessage(websocket.TextMessage, []byte(`{"type":"noop"}`)); err != nil {
+			break
+		}
+	}
+
+	connA.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := connA.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("ReadMessage() error = %v, want policy violation close", err)
+	}
+	if got := m.Stats().RateLimitDisconnects; got != 1 {
+		t.Fatalf("RateLimitDisconnects = %d, want 1", got)
+	}
+}
+
+func TestInboundRateLimit_ThrottlesPingFlood(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenA": "userA"}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{MaxInboundPerSec: 2})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	const pings = 20
+	for i := 0; i < pings; i++ {
+		if err := connA.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping","sentAtMs":1}`)); err != nil {
+			t.Fatalf("write failed at ping %d: %v", i, err)
+		}
+	}
+
+	pongs := 0
+	for {
+		connA.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		_, data, err := connA.ReadMessage()
+		if err != nil {
+			break
+		}
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil || env.Type != "pong" {
+			t.Fatalf("envelope = %s, want pong", data)
+		}
+		pongs++
+	}
+
+	if pongs == 0 || pongs >= pings {
+		t.Fatalf("answered %d pings, want between 1 and %d", pongs, pings-1)
+	}
+	if got := m.Stats().RateLimitedMessages; got == 0 {
+		t.Fatalf("RateLimitedMessages = 0, want > 0")
+	}
+}
+
+func TestMaxConnectionsPerUser_EvictsOldest(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenA": "userA", "tokenB": "userB"}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{MaxConnectionsPerUser: 2})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	conns := make([]*websocket.Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		c := connectWS(t, server, "tokenA")
+		defer c.Close()
+		conns = append(conns, c)
+		// Let the server register each socket so "oldest" is well defined.
+		time.Sleep(50 * time.Millisecond)
+	}
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	conns[0].SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conns[0].ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("oldest ReadMessage() error = %v, want policy violation close", err)
+	}
+
+	m.SendToUser("userA", Envelope{Type: "ping.test"})
+	for i, c := range conns[1:] {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := c.ReadMessage(); err != nil {
+			t.Fatalf("conns[%d] ReadMessage() error = %v, want delivered event", i+1, err)
+		}
+	}
+
+	stats := m.Stats()
+	if stats.Connections != 3 || stats.EvictedConnections != 1 {
+		t.Fatalf("stats = %+v, want 3 connections and 1 eviction", stats)
+	}
+}
+
+func TestOnlineStatus(t *testing.T) {
+	m, tv, _ := setupTestManager()
+	tv.tokens["tokenA"] = "userA"
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	status := m.OnlineStatus([]string{"userA", "userB"})
+	if !status["userA"] {
+		t.Errorf("userA online = false, want true")
+	}
+	if online, ok := status["userB"]; !ok || online {
+		t.Errorf("userB online = %v (present=%v), want false", online, ok)
+	}
+}
+
+type mockSessionStore struct {
+	participants map[string][]string
+	lookups      atomic.Int64
+}
+
+func (m *mockSessionStore) ParticipantUserIDs(_ context.Context, sessionID string) ([]string, error) {
+	m.lookups.Add(1)
+	ids, ok := m.participants[sessionID]
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+	return ids, nil
+}
+
+func TestTypingRelay_ParticipantsOnlyAndThrottled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{
+		"tokenA": "userA",
+		"tokenB": "userB",
+		"tokenC": "userC",
+	}}
+	sessions := &mockSessionStore{participants: map[string][]string{
+		"s1": {"userA", "userB"},
+	}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{Sessions: sessions})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+	connC := connectWS(t, server, "tokenC")
+	defer connC.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	typing := `{"type":"typing","sessionId":"s1"}`
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(typing)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := connB.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if env.Type != "typing" || env.SessionID != "s1" {
+		t.Fatalf("envelope = %+v, want typing for s1", env)
+	}
+	if payload, ok := env.Payload.(map[string]any); !ok || payload["userId"] != "userA" {
+		t.Fatalf("payload = %v, want userId userA", env.Payload)
+	}
+
+	// A second indicator within the throttle window and one from a non-participant are dropped.
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(typing)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := connC.WriteMessage(websocket.TextMessage, []byte(typing)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := connB.ReadMessage(); err == nil {
+		t.Error("expected no further typing relay to userB")
+	}
+	connA.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := connA.ReadMessage(); err == nil {
+		t.Error("expected no typing relay to userA")
+	}
+	connC.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := connC.ReadMessage(); err == nil {
+		t.Error("expected no typing relay to non-participant userC")
+	}
+}
+
+func TestTypingRelay_ForeignSessionFramesAreThrottled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenC": "userC"}}
+	sessions := &mockSessionStore{participants: map[string][]string{
+		"s1": {"userA", "userB"},
+	}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{Sessions: sessions})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connC := connectWS(t, server, "tokenC")
+	defer connC.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := connC.WriteMessage(websocket.TextMessage, []byte(`{"type":"typing","sessionId":"s1"}`)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if n := sessions.lookups.Load(); n != 1 {
+		t.Fatalf("participant lookups = %d, want 1", n)
+	}
+}
+
+type mockPresenceAudience struct {
+	audience map[string][]string
+}
+
+func (m *mockPresenceAudience) PresenceAudienceUserIDs(_ context.Context, userID string) ([]string, error) {
+	return m.audience[userID], nil
+}
+
+func TestPresence_OnlineOfflineTransitions(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	tv := &mockTokenValidator{tokens: map[string]string{"tokenA": "userA", "tokenB": "userB"}}
+	presence := &mockPresenceAudience{audience: map[string][]string{"userA": {"userB"}}}
+	m := NewManagerWithOptions(logger, tv, &mockCallStore{}, ManagerOptions{Presence: presence})
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	readPresence := func() (string, string) {
+		t.Helper()
+		connB.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, msg, err := connB.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		var env struct {
+			Type    string `json:"type"`
+			Payload struct {
+				UserID string `json:"userId"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(msg, &env); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		return env.Type, env.Payload.UserID
+	}
+
+	connA1 := connectWS(t, server, "tokenA")
+	if typ, who := readPresence(); typ != "presence.online" || who != "userA" {
+		t.Fatalf("event = %s/%s, want presence.online/userA", typ, who)
+	}
+	if !m.IsOnline("userA") {
+		t.Fatalf("IsOnline(userA) = false, want true")
+	}
+	if got := m.OnlineUserIDs(); len(got) != 2 || got[0] != "userA" || got[1] != "userB" {
+		t.Fatalf("OnlineUserIDs() = %v, want [userA userB]", got)
+	}
+
+	// A second socket and closing one of two must not flip presence.
+	connA2 := connectWS(t, server, "tokenA")
+	time.Sleep(50 * time.Millisecond)
+	connA1.Close()
+	time.Sleep(50 * time.Millisecond)
+	if !m.IsOnline("userA") {
+		t.Fatalf("IsOnline(userA) = false with one socket left, want true")
+	}
+
+	connA2.Close()
+	if typ, who := readPresence(); typ != "presence.offline" || who != "userA" {
+		t.Fatalf("event = %s/%s, want presence.offline/userA", typ, who)
+	}
+	if m.IsOnline("userA") {
+		t.Fatalf("IsOnline(userA) = true after last disconnect, want false")
+	}
+}
+
+func TestPresence_SlowAudienceDroppedDuringPresenceBroadcast(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	presence := &mockPresenceAudience{audience: map[string][]string{"userA": {"userB"}, "userB": {"userA"}}}
+	m := NewManagerWithOptions(logger, staticValidator{}, staticCallStore{}, ManagerOptions{
+		Presence:    presence,
+		SendTimeout: 50 * time.Millisecond,
+	})
+
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	defer server.Close()
+	peer := connectWS(t, server, "")
+	defer peer.Close()
+
+	// userB's only connection is stuck, so userA's presence.online drops it, which in turn
+	// publishes userB's presence.offline from inside that broadcast.
+	slow := &client{conn: <-conns, userID: "userB", send: make(chan outboundFrame, 1), done: make(chan struct{})}
+	m.track(slow)
+	slow.send <- textFrame([]byte("backlog"))
+
+	a := &client{userID: "userA", send: make(chan outboundFrame, 4), done: make(chan struct{})}
+	tracked := make(chan struct{})
+	go func() {
+		m.track(a)
+		close(tracked)
+	}()
+	select {
+	case <-tracked:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("track() did not return; presence broadcast deadlocked")
+	}
+
+	select {
+	case frame := <-a.send:
+		if !strings.Contains(string(frame.data), "presence.offline") || !strings.Contains(string(frame.data), "userB") {
+			t.Fatalf("frame = %s, want presence.offline for userB", frame.data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("presence.offline for the dropped client was not published")
+	}
+	if m.IsOnline("userB") {
+		t.Fatalf("IsOnline(userB) = true after the slow client was dropped, want false")
+	}
+}
+
+func TestPing_RepliesPongToSenderOnly(t *testing.T) {
+	m, tv, _ := setupTestManager()
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenA2"] = "userA"
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+	other := connectWS(t, server, "tokenA2")
+	defer other.Close()
+
+	before := time.Now().UnixMilli()
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping","sentAtMs":12345}`)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	connA.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := connA.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	var env struct {
+		Type    string `json:"type"`
+		Payload struct {
+			SentAtMs   int64 `json:"sentAtMs"`
+			ServerAtMs int64 `json:"serverAtMs"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(msg, &env); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if env.Type != "pong" || env.Payload.SentAtMs != 12345 || env.Payload.ServerAtMs < before {
+		t.Fatalf("pong = %s, want echo of sentAtMs and server time", msg)
+	}
+
+	other.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, msg, err := other.ReadMessage(); err == nil {
+		t.Fatalf("unexpected event on other socket: %s", msg)
+	}
+}
+
+func TestBinaryAudioFrameRelay(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	tv.tokens["tokenC"] = "userC"
+	cs.SetCall("call1", "userA", "userB", "accepted")
+	cs.SetCall("call2", "userA", "userB", "ringing")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+	connC := connectWS(t, server, "tokenC")
+	defer connC.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	frame := func(callID string, audio []byte) []byte {
+		return append(append([]byte{byte(len(callID))}, callID...), audio...)
+	}
+	audio := []byte{0x00, 0xff, 0x10, 0x7f}
+
+	// Frames for a call that is not accepted, or from a non-participant, are dropped.
+	if err := connA.WriteMessage(websocket.BinaryMessage, frame("call2", audio)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := connC.WriteMessage(websocket.BinaryMessage, frame("call1", audio)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := connA.WriteMessage(websocket.BinaryMessage, frame("call1", audio)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msgType, data, err := connB.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if msgType != websocket.BinaryMessage {
+		t.Fatalf("msgType = %d, want binary", msgType)
+	}
+	if string(data) != string(frame("call1", audio)) {
+		t.Fatalf("frame = %x, want %x", data, frame("call1", audio))
+	}
+
+	connB.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, data, err := connB.ReadMessage(); err == nil {
+		t.Fatalf("unexpected extra frame: %x", data)
+	}
+}
+
+func TestCallSignalRelay_Success(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	cs.SetCall("call1", "userA", "userB", "accepted")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	msg := `{"type":"call.signal","callId":"call1","sdp":{"type":"offer","sdp":"v=0"},"candidate":"candidate:1 1 udp 2122260223 10.0.0.1 54321 typ host"}`
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := connB.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if env.Type != "call.signal" {
+		t.Errorf("expected type call.signal, got %s", env.Type)
+	}
+
+	payload, ok := env.Payload.(map[string]interface{})
+	if !ok {
+		t.Fatalf("payload is not map")
+	}
+	if payload["callId"] != "call1" || payload["fromUserId"] != "userA" {
+		t.Errorf("expected callId call1 from userA, got %v", payload)
+	}
+	sdp, ok := payload["sdp"].(map[string]interface{})
+	if !ok || sdp["type"] != "offer" || sdp["sdp"] != "v=0" {
+		t.Errorf("expected sdp to pass through unchanged, got %v", payload["sdp"])
+	}
+	if payload["candidate"] != "candidate:1 1 udp 2122260223 10.0.0.1 54321 typ host" {
+		t.Errorf("expected candidate to pass through unchanged, got %v", payload["candidate"])
+	}
+}
+
+func TestCallSignalRelay_CallNotAccepted(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	cs.SetCall("call1", "userA", "userB", "inviting")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	msg := `{"type":"call.signal","callId":"call1","sdp":{"type":"offer","sdp":"v=0"}}`
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := connB.ReadMessage()
+	if err == nil {
+		t.Error("expected timeout, got message")
+	}
+}
+
+func TestCallSignalRelay_NotParticipant(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	tv.tokens["tokenC"] = "userC"
+	cs.SetCall("call1", "userA", "userB", "accepted")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connC := connectWS(t, server, "tokenC")
+	defer connC.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	msg := `{"type":"call.signal","callId":"call1","candidate":"candidate:1"}`
+	if err := connC.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := connB.ReadMessage()
+	if err == nil {
+		t.Error("expected timeout, got message")
+	}
+}
+
+func TestCallSignalRelay_DropsOversizedBlob(t *testing.T) {
+	m, tv, cs := setupTestManager()
+
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+	cs.SetCall("call1", "userA", "userB", "accepted")
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	msg := `{"type":"call.signal","callId":"call1","sdp":"` + strings.Repeat("a", maxSignalBytes) + `"}`
+	if err := connA.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	connB.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := connB.ReadMessage()
+	if err == nil {
+		t.Error("expected timeout, got message")
+	}
+}
+
+func TestDrain_NotifiesClientsBeforeClosing(t *testing.T) {
+	m, tv, _ := setupTestManager()
+	tv.tokens["tokenA"] = "userA"
+	tv.tokens["tokenB"] = "userB"
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	connA := connectWS(t, server, "tokenA")
+	defer connA.Close()
+	connB := connectWS(t, server, "tokenB")
+	defer connB.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		m.Drain(context.Background(), 500*time.Millisecond)
+		close(done)
+	}()
+
+	for name, c := range map[string]*websocket.Conn{"A": connA, "B": connB} {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("conn%s ReadMessage() error = %v, want server.draining", name, err)
+		}
+		var env Envelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if env.Type != "server.draining" {
+			t.Fatalf("conn%s type = %q, want server.draining", name, env.Type)
+		}
+	}
+
+	// New connections are refused while draining.
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?token=tokenA"
+	if _, res, err := websocket.DefaultDialer.Dial(url, nil); err == nil || res == nil || res.StatusCode != 503 {
+		t.Fatalf("Dial() during drain err = %v, want 503", err)
+	}
+
+	// A leaves on its own; B stays and is closed once the grace period ends.
+	connA.Close()
+	connB.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := connB.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("connB ReadMessage() error = %v, want normal close after grace", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Drain() did not return after the grace period")
+	}
+}
+
+func TestDrain_ReturnsEarlyWhenClientsLeave(t *testing.T) {
+	m, tv, _ := setupTestManager()
+	tv.tokens["tokenA"] = "userA"
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	conn := connectWS(t, server, "tokenA")
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		m.Drain(context.Background(), 10*time.Second)
+		close(done)
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage() error = %v, want server.draining", err)
+	}
+	conn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Drain() still waiting after every client left")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("Drain() took %s, want early return", elapsed)
+	}
+}
+
+func TestSendToUser_WaitsForBrieflySlowReader(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	m := NewManagerWithOptions(logger, staticValidator{}, staticCallStore{}, ManagerOptions{SendTimeout: time.Second})
+
+	// A client whose send buffer is full and whose writer only catches up after a short pause.
+	c := &client{userID: "slow", send: make(chan outboundFrame, 1), done: make(chan struct{})}
+	m.track(c)
+	c.send <- textFrame([]byte("backlog"))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		<-c.send
+	}()
+
+	m.SendToUser("slow", Envelope{Type: "message.created", SessionID: "s1"})
+
+	select {
+	case frame := <-c.send:
+		if !strings.Contains(string(frame.data), "message.created") {
+			t.Fatalf("queued frame = %s, want message.created", frame.data)
+		}
+	default:
+		t.Fatalf("event was not queued for the briefly slow client")
+	}
+	if stats := m.Stats(); stats.Connections != 1 || stats.SentEvents != 1 {
+		t.Fatalf("stats = %+v, want the client kept and 1 sent event", stats)
+	}
+}
+
+func TestSendToUsers_StuckClientsShareOneTimeout(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	const sendTimeout = 200 * time.Millisecond
+	m := NewManagerWithOptions(logger, staticValidator{}, staticCallStore{}, ManagerOptions{SendTimeout: sendTimeout})
+
+	// Server-side connections for the stuck clients, so dropping them can close a real socket.
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	defer server.Close()
+
+	const stuck = 5
+	userIDs := make([]string, 0, stuck)
+	for i := 0; i < stuck; i++ {
+		peer := connectWS(t, server, "")
+		defer peer.Close()
+		c := &client{
+			conn:   <-conns,
+			userID: fmt.Sprintf("stuck-%d", i),
+			send:   make(chan outboundFrame, 1),
+			done:   make(chan struct{}),
+		}
+		m.track(c)
+		c.send <- textFrame([]byte("backlog"))
+		userIDs = append(userIDs, c.userID)
+	}
+
+	start := time.Now()
+	m.SendToUsers(userIDs, Envelope{Type: "message.created", SessionID: "s1"})
+	if elapsed := time.Since(start); elapsed > 2*sendTimeout {
+		t.Fatalf("SendToUsers() took %s with %d stuck clients, want about %s", elapsed, stuck, sendTimeout)
+	}
+	if stats := m.Stats(); stats.Connections != 0 {
+		t.Fatalf("connections = %d, want every stuck client dropped", stats.Connections)
+	}
+}
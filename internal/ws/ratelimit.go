@@ -0,0 +1,42 @@
+package ws
+
+import "time"
+
+// inboundLimiter is a token bucket guarding a single connection's read loop.
+// It is only touched by the goroutine reading from that connection, so it needs no locking.
+type inboundLimiter struct {
+	ratePerSec float64
+	burst      float64
+	tokens     float64
+	last       time.Time
+}
+
+func newInboundLimiter(perSec int, now time.Time) *inboundLimiter {
+	if perSec <= 0 {
+		return nil
+	}
+	return &inboundLimiter{
+		ratePerSec: float64(perSec),
+		burst:      float64(perSec),
+		tokens:     float64(perSec),
+		last:       now,
+	}
+}
+
+func (l *inboundLimiter) allow(now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.ratePerSec
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}